@@ -5,6 +5,7 @@
 package bucket
 
 import (
+	resource "k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -22,6 +23,8 @@ type ConfigSpec struct {
 	// Selects a specific BucketClass by storage pool name.
 	// +kubebuilder:default:=""
 	StoragePool string `json:"storagePool,omitempty"`
+	// Capacity the bucket reserves against the tenant's `buckets.storage` quota. It is not a limit on the bucket itself. Required when the tenant limits `buckets.storage`.
+	Size resource.Quantity `json:"size,omitempty"`
 	// Users configuration map.
 	// +kubebuilder:default:={}
 	Users map[string]User `json:"users,omitempty"`
@@ -53,6 +53,7 @@ func (in *Config) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigSpec) DeepCopyInto(out *ConfigSpec) {
 	*out = *in
+	out.Size = in.Size.DeepCopy()
 	if in.Users != nil {
 		in, out := &in.Users, &out.Users
 		*out = make(map[string]User, len(*in))
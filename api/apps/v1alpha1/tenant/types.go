@@ -43,4 +43,10 @@ type ConfigSpec struct {
 	// Define resource quotas for the tenant.
 	// +kubebuilder:default:={}
 	ResourceQuotas map[string]resource.Quantity `json:"resourceQuotas,omitempty"`
+	// Lifecycle state of the tenant and its sub-tenants. `suspended` scales workloads to zero and suspends releases while keeping data; `archived` takes final backups of applications with a backup plan, deletes those and keeps the rest suspended; `deleting` suspends the tenant and deletes it after the platform's grace period unless set back to `active` first.
+	// +kubebuilder:default:="active"
+	Lifecycle Lifecycle `json:"lifecycle"`
 }
+
+// +kubebuilder:validation:Enum="active";"suspended";"archived";"deleting"
+type Lifecycle string
@@ -0,0 +1,138 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DomainClaimPhase is the verification state of a DomainClaim.
+// +kubebuilder:validation:Enum=Pending;Verified;Failed
+type DomainClaimPhase string
+
+const (
+	// DomainClaimPhasePending means the TXT challenge is published in
+	// status but has not been found in DNS yet. The controller keeps
+	// checking.
+	DomainClaimPhasePending DomainClaimPhase = "Pending"
+	// DomainClaimPhaseVerified means the challenge was found; routes in
+	// the claim's namespace may attach hostnames under Spec.Domain.
+	DomainClaimPhaseVerified DomainClaimPhase = "Verified"
+	// DomainClaimPhaseFailed means the claim cannot be verified as
+	// written, e.g. another namespace already holds the domain.
+	DomainClaimPhaseFailed DomainClaimPhase = "Failed"
+)
+
+// DomainClaimVerifiedCondition is the condition type reporting the
+// outcome of the last verification attempt.
+const DomainClaimVerifiedCondition = "Verified"
+
+// DomainClaimSpec defines the desired state of DomainClaim.
+type DomainClaimSpec struct {
+	// Domain is the customer-supplied domain the tenant wants to
+	// publish. A verified claim covers the domain itself and every
+	// hostname below it. Changing it restarts verification with a new
+	// challenge.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)+$`
+	// +kubebuilder:validation:MaxLength=253
+	Domain string `json:"domain"`
+}
+
+// DomainClaimChallenge is the TXT record the tenant publishes to prove
+// control of the domain.
+type DomainClaimChallenge struct {
+	// RecordName is the fully qualified name of the TXT record, always
+	// `_cozystack-challenge.<domain>`.
+	RecordName string `json:"recordName"`
+
+	// RecordValue is the exact TXT value to publish. It is unique per
+	// challenge and never reused after the domain changes.
+	RecordValue string `json:"recordValue"`
+}
+
+// DomainClaimStatus defines the observed state of DomainClaim.
+type DomainClaimStatus struct {
+	// ObservedGeneration mirrors the .metadata.generation the status
+	// was computed for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase is the verification state.
+	// +optional
+	Phase DomainClaimPhase `json:"phase,omitempty"`
+
+	// Challenge is the TXT record to publish for Spec.Domain.
+	// +optional
+	Challenge *DomainClaimChallenge `json:"challenge,omitempty"`
+
+	// Domain is the domain Challenge and Phase refer to; a spec edit
+	// is picked up when it no longer matches Spec.Domain.
+	// +optional
+	Domain string `json:"domain,omitempty"`
+
+	// VerifiedAt is when the challenge was first found. An older
+	// verification wins when claims of two namespaces overlap.
+	// +optional
+	VerifiedAt *metav1.Time `json:"verifiedAt,omitempty"`
+
+	// LastCheckTime is when DNS was last queried for the challenge.
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+
+	// Conditions carries the Verified condition.
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Namespaced,shortName=dc
+// +kubebuilder:printcolumn:name="Domain",type="string",JSONPath=".spec.domain"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Record",type="string",JSONPath=".status.challenge.recordName",priority=1
+// +kubebuilder:printcolumn:name="Value",type="string",JSONPath=".status.challenge.recordValue",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// DomainClaim asks the platform to let routes in its namespace publish
+// hostnames under a customer-supplied domain. The cozystack-controller
+// issues a TXT-record challenge in status and marks the claim Verified
+// once the record resolves; until then the TenantGateway controller
+// refuses to attach the domain's hostnames.
+type DomainClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DomainClaimSpec   `json:"spec,omitempty"`
+	Status DomainClaimStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// DomainClaimList contains a list of DomainClaim.
+type DomainClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DomainClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DomainClaim{}, &DomainClaimList{})
+}
@@ -18,6 +18,8 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -58,12 +60,128 @@ const (
 	IssuerNameLetsEncryptStage IssuerName = "letsencrypt-stage"
 )
 
+// IssuerConfig replaces the built-in Let's Encrypt Issuer with a
+// private ACME server, an existing cert-manager issuer, or a CA
+// Secret. Exactly one block must be set. A platform-wide private CA
+// belongs in a CA ClusterIssuer named by IssuerRef, which keeps its
+// key pair out of tenant namespaces; CA is for a tenant signing with
+// a CA of its own.
+// +kubebuilder:validation:XValidation:rule="(has(self.acme) ? 1 : 0) + (has(self.issuerRef) ? 1 : 0) + (has(self.ca) ? 1 : 0) == 1",message="exactly one of acme, issuerRef or ca must be set"
+type IssuerConfig struct {
+	// ACME points the per-tenant Issuer at an arbitrary ACME directory
+	// (step-ca, ZeroSSL, an internal Boulder, ...). The solver is still
+	// selected by CertMode.
+	// +optional
+	ACME *ACMEIssuerConfig `json:"acme,omitempty"`
+
+	// IssuerRef makes every Certificate reference an existing
+	// cert-manager Issuer or ClusterIssuer. No per-tenant Issuer is
+	// rendered.
+	// +optional
+	IssuerRef *IssuerReference `json:"issuerRef,omitempty"`
+
+	// CA renders the per-tenant Issuer as a cert-manager CA Issuer
+	// signing with the key pair in the referenced Secret. No challenge
+	// is solved.
+	// +optional
+	CA *CAIssuerConfig `json:"ca,omitempty"`
+}
+
+// ACMEIssuerConfig configures a custom ACME server.
+type ACMEIssuerConfig struct {
+	// Server is the ACME directory URL, e.g.
+	// https://ca.internal:9000/acme/acme/directory.
+	// +kubebuilder:validation:Pattern=`^https://`
+	Server string `json:"server"`
+
+	// Email is the ACME account contact address.
+	// +optional
+	Email string `json:"email,omitempty"`
+
+	// CABundle is a PEM bundle used to verify the ACME server's TLS
+	// certificate. Defaults to the cert-manager container trust store.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+
+	// SkipTLSVerify disables TLS verification of the ACME server.
+	// Only meant for test environments; prefer CABundle.
+	// +optional
+	SkipTLSVerify bool `json:"skipTLSVerify,omitempty"`
+
+	// ExternalAccountBinding binds the ACME account to an account at
+	// the CA. Required by ZeroSSL, Sectigo and most commercial ACME
+	// endpoints.
+	// +optional
+	ExternalAccountBinding *ExternalAccountBinding `json:"externalAccountBinding,omitempty"`
+}
+
+// ExternalAccountBinding carries the EAB credentials issued by the CA.
+type ExternalAccountBinding struct {
+	// KeyID is the EAB key identifier.
+	// +kubebuilder:validation:MinLength=1
+	KeyID string `json:"keyID"`
+
+	// KeySecretRef references the Secret key holding the base64url
+	// encoded HMAC key. The Secret must live in the TenantGateway's
+	// namespace.
+	KeySecretRef corev1.SecretKeySelector `json:"keySecretRef"`
+}
+
+// IssuerReferenceKind is the kind of an existing cert-manager issuer.
+// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+type IssuerReferenceKind string
+
+const (
+	IssuerReferenceKindIssuer        IssuerReferenceKind = "Issuer"
+	IssuerReferenceKindClusterIssuer IssuerReferenceKind = "ClusterIssuer"
+)
+
+// IssuerReference names an existing cert-manager issuer.
+type IssuerReference struct {
+	// Kind is Issuer (in the TenantGateway's namespace) or
+	// ClusterIssuer.
+	// +kubebuilder:default=ClusterIssuer
+	Kind IssuerReferenceKind `json:"kind,omitempty"`
+
+	// Name of the issuer.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// CAIssuerConfig configures a cert-manager CA Issuer.
+type CAIssuerConfig struct {
+	// SecretRef names a kubernetes.io/tls Secret in the TenantGateway's
+	// namespace holding the signing certificate and key.
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+}
+
 // DNS01Provider names a supported cert-manager DNS-01 solver.
-// +kubebuilder:validation:Enum=cloudflare;route53;digitalocean;rfc2136
+// +kubebuilder:validation:Enum=cloudflare;route53;digitalocean;rfc2136;clouddns;azuredns;powerdns;hetzner;webhook
 type DNS01Provider string
 
+const (
+	DNS01ProviderCloudflare   DNS01Provider = "cloudflare"
+	DNS01ProviderRoute53      DNS01Provider = "route53"
+	DNS01ProviderDigitalOcean DNS01Provider = "digitalocean"
+	DNS01ProviderRFC2136      DNS01Provider = "rfc2136"
+	DNS01ProviderCloudDNS     DNS01Provider = "clouddns"
+	DNS01ProviderAzureDNS     DNS01Provider = "azuredns"
+
+	// DNS01ProviderPowerDNS and DNS01ProviderHetzner have no in-tree
+	// cert-manager solver; both render a webhook solver and expect the
+	// operator to have installed the matching cert-manager webhook.
+	DNS01ProviderPowerDNS DNS01Provider = "powerdns"
+	DNS01ProviderHetzner  DNS01Provider = "hetzner"
+
+	// DNS01ProviderWebhook renders an arbitrary cert-manager webhook
+	// solver from Spec.DNS01.Webhook.
+	DNS01ProviderWebhook DNS01Provider = "webhook"
+)
+
 // DNS01Config configures the DNS-01 solver when CertMode=dns01. Only
 // the field corresponding to Provider is read; others are ignored.
+// The same block supplies the credentials used for record publishing
+// (Spec.DNSRecords), in which case it is read regardless of CertMode.
 type DNS01Config struct {
 	// Provider selects which DNS-01 solver block to render.
 	// +kubebuilder:default=cloudflare
@@ -84,6 +202,26 @@ type DNS01Config struct {
 	// RFC2136 config. Required when Provider=rfc2136.
 	// +optional
 	RFC2136 *RFC2136DNS01 `json:"rfc2136,omitempty"`
+
+	// CloudDNS config. Required when Provider=clouddns.
+	// +optional
+	CloudDNS *CloudDNSDNS01 `json:"clouddns,omitempty"`
+
+	// AzureDNS config. Required when Provider=azuredns.
+	// +optional
+	AzureDNS *AzureDNSDNS01 `json:"azuredns,omitempty"`
+
+	// PowerDNS config. Required when Provider=powerdns.
+	// +optional
+	PowerDNS *PowerDNSDNS01 `json:"powerdns,omitempty"`
+
+	// Hetzner config. Required when Provider=hetzner.
+	// +optional
+	Hetzner *HetznerDNS01 `json:"hetzner,omitempty"`
+
+	// Webhook config. Required when Provider=webhook.
+	// +optional
+	Webhook *WebhookDNS01 `json:"webhook,omitempty"`
 }
 
 // CloudflareDNS01 configures the cloudflare solver.
@@ -142,7 +280,388 @@ type RFC2136DNS01 struct {
 	TSIGSecretSecretRef corev1.SecretKeySelector `json:"tsigSecretSecretRef"`
 }
 
+// CloudDNSDNS01 configures the Google Cloud DNS solver.
+type CloudDNSDNS01 struct {
+	// Project is the GCP project hosting the managed zone.
+	// +required
+	Project string `json:"project"`
+
+	// HostedZoneName pins the managed zone name. Optional; cert-manager
+	// discovers the zone from the challenge FQDN when empty.
+	// +optional
+	HostedZoneName string `json:"hostedZoneName,omitempty"`
+
+	// ServiceAccountSecretRef references a Secret holding a service
+	// account JSON key with dns.admin on the project. Optional when
+	// running with GKE workload identity.
+	// +optional
+	ServiceAccountSecretRef *corev1.SecretKeySelector `json:"serviceAccountSecretRef,omitempty"`
+}
+
+// AzureDNSDNS01 configures the Azure DNS solver.
+type AzureDNSDNS01 struct {
+	// SubscriptionID is the Azure subscription holding the DNS zone.
+	// +required
+	SubscriptionID string `json:"subscriptionID"`
+
+	// ResourceGroupName is the resource group holding the DNS zone.
+	// +required
+	ResourceGroupName string `json:"resourceGroupName"`
+
+	// HostedZoneName is the DNS zone name. Optional; cert-manager
+	// discovers the zone from the challenge FQDN when empty.
+	// +optional
+	HostedZoneName string `json:"hostedZoneName,omitempty"`
+
+	// Environment is the Azure cloud environment. Default
+	// AzurePublicCloud.
+	// +kubebuilder:validation:Enum=AzurePublicCloud;AzureChinaCloud;AzureGermanCloud;AzureUSGovernmentCloud
+	// +optional
+	Environment string `json:"environment,omitempty"`
+
+	// TenantID is the Entra ID tenant of the service principal.
+	// Optional when running with managed identity.
+	// +optional
+	TenantID string `json:"tenantID,omitempty"`
+
+	// ClientID is the service principal application ID. Optional when
+	// running with managed identity.
+	// +optional
+	ClientID string `json:"clientID,omitempty"`
+
+	// ClientSecretSecretRef references a Secret holding the service
+	// principal secret. Optional when running with managed identity.
+	// +optional
+	ClientSecretSecretRef *corev1.SecretKeySelector `json:"clientSecretSecretRef,omitempty"`
+}
+
+// PowerDNSDNS01 configures a PowerDNS authoritative server reached
+// through its HTTP API. The solver is rendered as a cert-manager
+// webhook solver; the operator installs the webhook
+// (cert-manager-webhook-pdns or compatible) separately.
+type PowerDNSDNS01 struct {
+	// Host is the base URL of the PowerDNS API, e.g.
+	// https://pdns.example.com:8081.
+	// +required
+	Host string `json:"host"`
+
+	// ServerID is the PowerDNS server ID. Default localhost.
+	// +kubebuilder:default=localhost
+	// +optional
+	ServerID string `json:"serverID,omitempty"`
+
+	// APIKeySecretRef references a Secret holding the PowerDNS API key.
+	// +required
+	APIKeySecretRef corev1.SecretKeySelector `json:"apiKeySecretRef"`
+
+	// GroupName is the API group the cert-manager webhook registers.
+	// Default acme.zacharyseguin.ca (the upstream pdns webhook chart).
+	// +optional
+	GroupName string `json:"groupName,omitempty"`
+}
+
+// HetznerDNS01 configures the Hetzner DNS API. The solver is rendered
+// as a cert-manager webhook solver; the operator installs the webhook
+// (cert-manager-webhook-hetzner or compatible) separately.
+// +kubebuilder:validation:XValidation:rule="self.apiKeySecretRef.key == 'api-key'",message="the hetzner webhook reads the token from the api-key key of the Secret"
+type HetznerDNS01 struct {
+	// ZoneName is the Hetzner DNS zone holding the tenant apex.
+	// +required
+	ZoneName string `json:"zoneName"`
+
+	// APIKeySecretRef references a Secret holding the Hetzner DNS API
+	// token. The webhook only reads the api-key key, so Key must be
+	// api-key; record publishing reads the same key.
+	// +required
+	APIKeySecretRef corev1.SecretKeySelector `json:"apiKeySecretRef"`
+
+	// APIURL overrides the Hetzner DNS API endpoint. Default
+	// https://dns.hetzner.com/api/v1.
+	// +optional
+	APIURL string `json:"apiURL,omitempty"`
+
+	// GroupName is the API group the cert-manager webhook registers.
+	// The upstream chart has no usable default, so it is required.
+	// +required
+	GroupName string `json:"groupName"`
+}
+
+// WebhookDNS01 configures an arbitrary cert-manager webhook solver.
+type WebhookDNS01 struct {
+	// GroupName is the API group the cert-manager webhook registers.
+	// +required
+	GroupName string `json:"groupName"`
+
+	// SolverName is the solver name the webhook registers.
+	// +required
+	SolverName string `json:"solverName"`
+
+	// Config is passed verbatim to the webhook solver.
+	// +optional
+	Config *apiextensionsv1.JSON `json:"config,omitempty"`
+
+	// RecordsEndpoint is the URL the controller calls to publish and
+	// withdraw listener records when Spec.DNSRecords is enabled. The
+	// controller GETs the current record (hostname, type and owner in
+	// the query; 404 when absent) and PUTs or DELETEs a JSON record
+	// document against it only when something changed. Every request
+	// carries the owning TenantGateway as owner; the endpoint must not
+	// overwrite or delete records of another owner. Required for record
+	// publishing with this provider; ignored otherwise.
+	// +optional
+	RecordsEndpoint string `json:"recordsEndpoint,omitempty"`
+
+	// RecordsTokenSecretRef references a Secret holding a bearer token
+	// sent with every RecordsEndpoint request.
+	// +optional
+	RecordsTokenSecretRef *corev1.SecretKeySelector `json:"recordsTokenSecretRef,omitempty"`
+}
+
+// DNSRecordsConfig enables external-DNS style publishing of A/AAAA
+// records for every listener hostname on the tenant Gateway. Records
+// are written through the provider and credentials in Spec.DNS01.
+// Only cloudflare, digitalocean, hetzner, powerdns and webhook have a
+// records API; with any other provider every listener reports
+// DNSRecord=Unsupported and nothing is written. Each record set is claimed by a TXT ownership record
+// ("_cozystack-<type>.<hostname>"); record sets without one, or owned
+// by another TenantGateway, are left untouched and reported Failed.
+type DNSRecordsConfig struct {
+	// Enabled turns record publishing on. Records previously published
+	// are withdrawn when it is switched off.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// TTL of the published records, in seconds. Default 300.
+	// +kubebuilder:default=300
+	// +kubebuilder:validation:Minimum=30
+	// +optional
+	TTL int32 `json:"ttl,omitempty"`
+
+	// Targets overrides the record targets. Default: the addresses the
+	// Gateway controller reports in Gateway.Status.Addresses. Set this
+	// when the Gateway sits behind NAT or an external load balancer.
+	// +optional
+	Targets []string `json:"targets,omitempty"`
+}
+
+// DNSRecordState is the publishing state of a listener's DNS record.
+// +kubebuilder:validation:Enum=Published;Pending;Failed;Unsupported
+type DNSRecordState string
+
+const (
+	DNSRecordStatePublished DNSRecordState = "Published"
+	DNSRecordStatePending   DNSRecordState = "Pending"
+	DNSRecordStateFailed    DNSRecordState = "Failed"
+	// DNSRecordStateUnsupported means Spec.DNS01 names a provider the
+	// controller cannot publish records through. Not retried.
+	DNSRecordStateUnsupported DNSRecordState = "Unsupported"
+)
+
+// TrafficPolicy attaches ingress-style protections to the HTTPRoutes
+// it targets on this tenant's Gateway. The controller translates it
+// into the policy resources of the Gateway implementation behind
+// Spec.GatewayClassName and reports the attachment on each targeted
+// route's parent status.
+// +kubebuilder:validation:XValidation:rule="has(self.hostnames) || has(self.routes)",message="a policy needs at least one of hostnames or routes"
+type TrafficPolicy struct {
+	// Name identifies the policy in route status and in the names of
+	// the rendered policy resources.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=40
+	Name string `json:"name"`
+
+	// Hostnames targets every attached HTTPRoute declaring one of these
+	// hostnames. A leading wildcard label (*.example.org) matches any
+	// hostname under it.
+	// +optional
+	Hostnames []string `json:"hostnames,omitempty"`
+
+	// Routes targets attached HTTPRoutes by namespace and name.
+	// +optional
+	Routes []PolicyRouteRef `json:"routes,omitempty"`
+
+	// RateLimit caps the request rate per Gateway replica.
+	// +optional
+	RateLimit *RateLimitPolicy `json:"rateLimit,omitempty"`
+
+	// IPAllowList restricts access to clients from these CIDRs. Empty
+	// allows every source not in IPDenyList.
+	// +optional
+	IPAllowList []string `json:"ipAllowList,omitempty"`
+
+	// IPDenyList rejects clients from these CIDRs. Evaluated before
+	// IPAllowList.
+	// +optional
+	IPDenyList []string `json:"ipDenyList,omitempty"`
+
+	// BasicAuth requires HTTP basic authentication.
+	// +optional
+	BasicAuth *BasicAuthPolicy `json:"basicAuth,omitempty"`
+
+	// ForwardAuth delegates the authentication decision to an external
+	// HTTP service, typically an OIDC proxy such as oauth2-proxy.
+	// +optional
+	ForwardAuth *ForwardAuthPolicy `json:"forwardAuth,omitempty"`
+
+	// MaxRequestBodySize rejects requests with a larger body.
+	// +optional
+	MaxRequestBodySize *resource.Quantity `json:"maxRequestBodySize,omitempty"`
+}
+
+// PolicyRouteRef names an HTTPRoute a TrafficPolicy targets.
+type PolicyRouteRef struct {
+	// Namespace of the HTTPRoute.
+	Namespace string `json:"namespace"`
+
+	// Name of the HTTPRoute.
+	Name string `json:"name"`
+}
+
+// RateLimitUnit is the window a RateLimitPolicy counts requests in.
+// +kubebuilder:validation:Enum=Second;Minute;Hour
+type RateLimitUnit string
+
+const (
+	RateLimitUnitSecond RateLimitUnit = "Second"
+	RateLimitUnitMinute RateLimitUnit = "Minute"
+	RateLimitUnitHour   RateLimitUnit = "Hour"
+)
+
+// RateLimitPolicy is a local (per Gateway replica) request rate limit.
+type RateLimitPolicy struct {
+	// Requests is the number of requests allowed per Unit.
+	// +kubebuilder:validation:Minimum=1
+	Requests int32 `json:"requests"`
+
+	// Unit is the counting window. Default Minute.
+	// +kubebuilder:default=Minute
+	// +optional
+	Unit RateLimitUnit `json:"unit,omitempty"`
+}
+
+// BasicAuthPolicy configures HTTP basic authentication.
+type BasicAuthPolicy struct {
+	// SecretRef names a Secret in each targeted route's namespace
+	// holding an htpasswd file (SHA hashes) under the .htpasswd key.
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+}
+
+// ForwardAuthPolicy configures external authorization over HTTP.
+// Requests are forwarded to the service first; a 2xx response lets
+// them through, anything else is returned to the client.
+type ForwardAuthPolicy struct {
+	// ServiceName is the authorization Service.
+	ServiceName string `json:"serviceName"`
+
+	// ServiceNamespace is the authorization Service namespace. It must
+	// be the TenantGateway namespace or one inheriting its Gateway;
+	// any other namespace is refused. Default: the targeted route's
+	// namespace. A ReferenceGrant is required when it differs.
+	// +optional
+	ServiceNamespace string `json:"serviceNamespace,omitempty"`
+
+	// Port of the authorization Service.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+
+	// Path prefix the authorization request is sent to, e.g.
+	// /oauth2/auth for oauth2-proxy.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// HeadersToBackend lists authorization response headers copied onto
+	// the upstream request, e.g. X-Auth-Request-User.
+	// +optional
+	HeadersToBackend []string `json:"headersToBackend,omitempty"`
+}
+
+// ClientValidationState is the client-certificate verification state
+// of a listener.
+// +kubebuilder:validation:Enum=Enforced;Pending;Invalid;Unsupported
+type ClientValidationState string
+
+const (
+	ClientValidationStateEnforced    ClientValidationState = "Enforced"
+	ClientValidationStatePending     ClientValidationState = "Pending"
+	ClientValidationStateInvalid     ClientValidationState = "Invalid"
+	ClientValidationStateUnsupported ClientValidationState = "Unsupported"
+)
+
+// ClientValidation requires clients of the listed hostnames to present
+// a certificate signed by the referenced CA bundle (mutual TLS).
+type ClientValidation struct {
+	// Name identifies the entry in listener status and in the names of
+	// the rendered resources.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=40
+	Name string `json:"name"`
+
+	// Hostnames are the listener hostnames to verify clients on. Each
+	// hostname gets its own listener; in dns01 and existingSecret mode
+	// the controller adds one next to the wildcard listener.
+	// +kubebuilder:validation:MinItems=1
+	Hostnames []string `json:"hostnames"`
+
+	// CABundle is the source of the trusted client CA certificates.
+	CABundle ClientCABundleSource `json:"caBundle"`
+
+	// Optional accepts connections that present no certificate or one
+	// that fails verification, leaving authorization to the backend.
+	// +optional
+	Optional bool `json:"optional,omitempty"`
+
+	// ForwardSubject passes the subject of the verified client
+	// certificate to the backend in the X-Forwarded-Client-Cert
+	// header. A header sent by the client is always dropped.
+	// +optional
+	ForwardSubject bool `json:"forwardSubject,omitempty"`
+}
+
+// ClientCABundleSource selects where the client CA bundle comes from.
+// Exactly one source must be set. The source Secret may live in the
+// TenantGateway namespace or in any namespace attached to it.
+// +kubebuilder:validation:XValidation:rule="has(self.tenantSecret) != has(self.tenantCA)",message="exactly one of tenantSecret or tenantCA must be set"
+type ClientCABundleSource struct {
+	// TenantSecret reads the bundle from a Secret exposed to the
+	// tenant through the tenantsecrets API.
+	// +optional
+	TenantSecret *TenantSecretCARef `json:"tenantSecret,omitempty"`
+
+	// TenantCA reads the bundle from the "<release>.tenant-ca"
+	// projection the platform publishes for a managed application.
+	// +optional
+	TenantCA *TenantCARef `json:"tenantCA,omitempty"`
+}
+
+// TenantSecretCARef references a key of a TenantSecret.
+type TenantSecretCARef struct {
+	// Namespace of the Secret. Default: the TenantGateway namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the Secret.
+	Name string `json:"name"`
+
+	// Key holding the PEM bundle.
+	// +kubebuilder:default=ca.crt
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// TenantCARef references the CA projection of a managed application.
+type TenantCARef struct {
+	// Namespace of the application. Default: the TenantGateway
+	// namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Release is the Helm release name of the application.
+	Release string `json:"release"`
+}
+
 // TenantGatewaySpec describes the desired state of a per-tenant Gateway.
+// +kubebuilder:validation:XValidation:rule="!has(self.dnsRecords) || !has(self.dnsRecords.enabled) || !self.dnsRecords.enabled || has(self.dns01)",message="dnsRecords publishes through dns01, which must be set"
 type TenantGatewaySpec struct {
 	// Apex is the tenant's apex hostname. The Gateway listeners are
 	// constrained to this apex and its subdomains.
@@ -161,6 +680,12 @@ type TenantGatewaySpec struct {
 	// +kubebuilder:default=letsencrypt-prod
 	IssuerName IssuerName `json:"issuerName,omitempty"`
 
+	// Issuer overrides IssuerName with a custom ACME server, an
+	// existing cert-manager issuer, or a CA Secret. Ignored when
+	// CertMode=existingSecret.
+	// +optional
+	Issuer *IssuerConfig `json:"issuer,omitempty"`
+
 	// DNS01 configures the DNS-01 solver when CertMode=dns01. Ignored
 	// otherwise. Required (provider + matching config block) when
 	// CertMode=dns01.
@@ -176,6 +701,38 @@ type TenantGatewaySpec struct {
 	// +optional
 	WildcardSecretRef *corev1.LocalObjectReference `json:"wildcardSecretRef,omitempty"`
 
+	// DNSRecords enables publishing DNS records for every listener
+	// hostname through the provider configured in Spec.DNS01. Works in
+	// every CertMode; Spec.DNS01 must be set to a provider that can
+	// publish records when enabled.
+	// +optional
+	DNSRecords *DNSRecordsConfig `json:"dnsRecords,omitempty"`
+
+	// Policies attaches rate limits, source-IP lists, authentication
+	// and request-size limits to routes on this Gateway. They are
+	// enforced through Envoy Gateway policy resources, so they require
+	// a GatewayClass of Envoy Gateway, such as the envoy-gateway class
+	// of the optional cozystack.envoy-gateway package; under any other
+	// class nothing is rendered and the PoliciesEnforced condition is
+	// False.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Policies []TrafficPolicy `json:"policies,omitempty"`
+
+	// ClientValidation enables client-certificate verification on the
+	// listeners serving the given hostnames. An entry covering every
+	// HTTPS listener is rendered as the standard Gateway
+	// spec.tls.frontend when the GatewayClass advertises
+	// GatewayFrontendClientCertificateValidation; otherwise Envoy
+	// Gateway classes get a ClientTrafficPolicy. A listener whose
+	// verification is not in force, Unsupported included, accepts no
+	// routes.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	ClientValidation []ClientValidation `json:"clientValidation,omitempty"`
+
 	// AttachedNamespaces lists namespace names that are allowed to
 	// attach HTTPRoute or TLSRoute to this tenant's Gateway. The
 	// publishing tenant namespace is implicit. Selector is by built-in
@@ -190,7 +747,8 @@ type TenantGatewaySpec struct {
 	TLSPassthroughServices []string `json:"tlsPassthroughServices,omitempty"`
 
 	// GatewayClassName names the GatewayClass to attach the rendered
-	// Gateway to. Default cilium.
+	// Gateway to. Default cilium, which cannot enforce Policies or
+	// ClientValidation; use envoy-gateway for those.
 	// +kubebuilder:default=cilium
 	// +optional
 	GatewayClassName string `json:"gatewayClassName,omitempty"`
@@ -218,6 +776,43 @@ type TenantGatewayListenerStatus struct {
 	// Reason is a short machine-readable reason when Ready=false.
 	// +optional
 	Reason string `json:"reason,omitempty"`
+
+	// CertificateMessage carries the message of the Certificate's
+	// Ready condition while it is not ready, e.g. an ACME order or
+	// signing error.
+	// +optional
+	CertificateMessage string `json:"certificateMessage,omitempty"`
+
+	// DNSRecord reports the publishing state of this listener's DNS
+	// record. Empty when Spec.DNSRecords is disabled or the listener
+	// has no hostname.
+	// +optional
+	DNSRecord DNSRecordState `json:"dnsRecord,omitempty"`
+
+	// DNSRecordMessage carries the provider error when DNSRecord is
+	// Failed, or the reason when it is Unsupported.
+	// +optional
+	DNSRecordMessage string `json:"dnsRecordMessage,omitempty"`
+
+	// ClientValidation reports client-certificate verification on this
+	// listener. Empty when no Spec.ClientValidation entry targets it.
+	// +optional
+	ClientValidation ClientValidationState `json:"clientValidation,omitempty"`
+
+	// ClientValidationMessage explains a Pending, Invalid or
+	// Unsupported ClientValidation.
+	// +optional
+	ClientValidationMessage string `json:"clientValidationMessage,omitempty"`
+}
+
+// PublishedDNSRecord records a DNS record the controller wrote, so it
+// can be withdrawn once the listener hostname goes away.
+type PublishedDNSRecord struct {
+	// Hostname is the record owner name.
+	Hostname string `json:"hostname"`
+
+	// Type is the record type (A or AAAA).
+	Type string `json:"type"`
 }
 
 // TenantGatewayStatus reports the observed state of the tenant's Gateway.
@@ -228,7 +823,7 @@ type TenantGatewayStatus struct {
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 
 	// Conditions describes the current state of the TenantGateway.
-	// Standard condition types: Ready, Programmed.
+	// Standard condition types: Ready, Programmed, PoliciesEnforced.
 	// +optional
 	// +patchMergeKey=type
 	// +patchStrategy=merge
@@ -241,6 +836,12 @@ type TenantGatewayStatus struct {
 	// +listType=map
 	// +listMapKey=name
 	Listeners []TenantGatewayListenerStatus `json:"listeners,omitempty"`
+
+	// PublishedDNSRecords lists the DNS records currently published
+	// for this Gateway. The controller withdraws entries that no
+	// longer match a listener hostname.
+	// +optional
+	PublishedDNSRecords []PublishedDNSRecord `json:"publishedDNSRecords,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -21,11 +21,155 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACMEIssuerConfig) DeepCopyInto(out *ACMEIssuerConfig) {
+	*out = *in
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExternalAccountBinding != nil {
+		in, out := &in.ExternalAccountBinding, &out.ExternalAccountBinding
+		*out = new(ExternalAccountBinding)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACMEIssuerConfig.
+func (in *ACMEIssuerConfig) DeepCopy() *ACMEIssuerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ACMEIssuerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AzureDNSDNS01) DeepCopyInto(out *AzureDNSDNS01) {
+	*out = *in
+	if in.ClientSecretSecretRef != nil {
+		in, out := &in.ClientSecretSecretRef, &out.ClientSecretSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureDNSDNS01.
+func (in *AzureDNSDNS01) DeepCopy() *AzureDNSDNS01 {
+	if in == nil {
+		return nil
+	}
+	out := new(AzureDNSDNS01)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BasicAuthPolicy) DeepCopyInto(out *BasicAuthPolicy) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BasicAuthPolicy.
+func (in *BasicAuthPolicy) DeepCopy() *BasicAuthPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(BasicAuthPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CAIssuerConfig) DeepCopyInto(out *CAIssuerConfig) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CAIssuerConfig.
+func (in *CAIssuerConfig) DeepCopy() *CAIssuerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CAIssuerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientCABundleSource) DeepCopyInto(out *ClientCABundleSource) {
+	*out = *in
+	if in.TenantSecret != nil {
+		in, out := &in.TenantSecret, &out.TenantSecret
+		*out = new(TenantSecretCARef)
+		**out = **in
+	}
+	if in.TenantCA != nil {
+		in, out := &in.TenantCA, &out.TenantCA
+		*out = new(TenantCARef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientCABundleSource.
+func (in *ClientCABundleSource) DeepCopy() *ClientCABundleSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientCABundleSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientValidation) DeepCopyInto(out *ClientValidation) {
+	*out = *in
+	if in.Hostnames != nil {
+		in, out := &in.Hostnames, &out.Hostnames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.CABundle.DeepCopyInto(&out.CABundle)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientValidation.
+func (in *ClientValidation) DeepCopy() *ClientValidation {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientValidation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudDNSDNS01) DeepCopyInto(out *CloudDNSDNS01) {
+	*out = *in
+	if in.ServiceAccountSecretRef != nil {
+		in, out := &in.ServiceAccountSecretRef, &out.ServiceAccountSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudDNSDNS01.
+func (in *CloudDNSDNS01) DeepCopy() *CloudDNSDNS01 {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudDNSDNS01)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CloudflareDNS01) DeepCopyInto(out *CloudflareDNS01) {
 	*out = *in
@@ -65,6 +209,31 @@ func (in *DNS01Config) DeepCopyInto(out *DNS01Config) {
 		*out = new(RFC2136DNS01)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CloudDNS != nil {
+		in, out := &in.CloudDNS, &out.CloudDNS
+		*out = new(CloudDNSDNS01)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AzureDNS != nil {
+		in, out := &in.AzureDNS, &out.AzureDNS
+		*out = new(AzureDNSDNS01)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PowerDNS != nil {
+		in, out := &in.PowerDNS, &out.PowerDNS
+		*out = new(PowerDNSDNS01)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hetzner != nil {
+		in, out := &in.Hetzner, &out.Hetzner
+		*out = new(HetznerDNS01)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(WebhookDNS01)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNS01Config.
@@ -77,6 +246,26 @@ func (in *DNS01Config) DeepCopy() *DNS01Config {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSRecordsConfig) DeepCopyInto(out *DNSRecordsConfig) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSRecordsConfig.
+func (in *DNSRecordsConfig) DeepCopy() *DNSRecordsConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSRecordsConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DigitalOceanDNS01) DeepCopyInto(out *DigitalOceanDNS01) {
 	*out = *in
@@ -93,6 +282,273 @@ func (in *DigitalOceanDNS01) DeepCopy() *DigitalOceanDNS01 {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainClaim) DeepCopyInto(out *DomainClaim) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainClaim.
+func (in *DomainClaim) DeepCopy() *DomainClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DomainClaim) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainClaimChallenge) DeepCopyInto(out *DomainClaimChallenge) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainClaimChallenge.
+func (in *DomainClaimChallenge) DeepCopy() *DomainClaimChallenge {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainClaimChallenge)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainClaimList) DeepCopyInto(out *DomainClaimList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DomainClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainClaimList.
+func (in *DomainClaimList) DeepCopy() *DomainClaimList {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainClaimList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DomainClaimList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainClaimSpec) DeepCopyInto(out *DomainClaimSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainClaimSpec.
+func (in *DomainClaimSpec) DeepCopy() *DomainClaimSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainClaimSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainClaimStatus) DeepCopyInto(out *DomainClaimStatus) {
+	*out = *in
+	if in.Challenge != nil {
+		in, out := &in.Challenge, &out.Challenge
+		*out = new(DomainClaimChallenge)
+		**out = **in
+	}
+	if in.VerifiedAt != nil {
+		in, out := &in.VerifiedAt, &out.VerifiedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainClaimStatus.
+func (in *DomainClaimStatus) DeepCopy() *DomainClaimStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainClaimStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalAccountBinding) DeepCopyInto(out *ExternalAccountBinding) {
+	*out = *in
+	in.KeySecretRef.DeepCopyInto(&out.KeySecretRef)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalAccountBinding.
+func (in *ExternalAccountBinding) DeepCopy() *ExternalAccountBinding {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalAccountBinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForwardAuthPolicy) DeepCopyInto(out *ForwardAuthPolicy) {
+	*out = *in
+	if in.HeadersToBackend != nil {
+		in, out := &in.HeadersToBackend, &out.HeadersToBackend
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForwardAuthPolicy.
+func (in *ForwardAuthPolicy) DeepCopy() *ForwardAuthPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ForwardAuthPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HetznerDNS01) DeepCopyInto(out *HetznerDNS01) {
+	*out = *in
+	in.APIKeySecretRef.DeepCopyInto(&out.APIKeySecretRef)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HetznerDNS01.
+func (in *HetznerDNS01) DeepCopy() *HetznerDNS01 {
+	if in == nil {
+		return nil
+	}
+	out := new(HetznerDNS01)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IssuerConfig) DeepCopyInto(out *IssuerConfig) {
+	*out = *in
+	if in.ACME != nil {
+		in, out := &in.ACME, &out.ACME
+		*out = new(ACMEIssuerConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IssuerRef != nil {
+		in, out := &in.IssuerRef, &out.IssuerRef
+		*out = new(IssuerReference)
+		**out = **in
+	}
+	if in.CA != nil {
+		in, out := &in.CA, &out.CA
+		*out = new(CAIssuerConfig)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IssuerConfig.
+func (in *IssuerConfig) DeepCopy() *IssuerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(IssuerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IssuerReference) DeepCopyInto(out *IssuerReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IssuerReference.
+func (in *IssuerReference) DeepCopy() *IssuerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(IssuerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyRouteRef) DeepCopyInto(out *PolicyRouteRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyRouteRef.
+func (in *PolicyRouteRef) DeepCopy() *PolicyRouteRef {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyRouteRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerDNSDNS01) DeepCopyInto(out *PowerDNSDNS01) {
+	*out = *in
+	in.APIKeySecretRef.DeepCopyInto(&out.APIKeySecretRef)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerDNSDNS01.
+func (in *PowerDNSDNS01) DeepCopy() *PowerDNSDNS01 {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerDNSDNS01)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PublishedDNSRecord) DeepCopyInto(out *PublishedDNSRecord) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublishedDNSRecord.
+func (in *PublishedDNSRecord) DeepCopy() *PublishedDNSRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(PublishedDNSRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RFC2136DNS01) DeepCopyInto(out *RFC2136DNS01) {
 	*out = *in
@@ -109,12 +565,27 @@ func (in *RFC2136DNS01) DeepCopy() *RFC2136DNS01 {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimitPolicy) DeepCopyInto(out *RateLimitPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitPolicy.
+func (in *RateLimitPolicy) DeepCopy() *RateLimitPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimitPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Route53DNS01) DeepCopyInto(out *Route53DNS01) {
 	*out = *in
 	if in.SecretAccessKeySecretRef != nil {
 		in, out := &in.SecretAccessKeySecretRef, &out.SecretAccessKeySecretRef
-		*out = new(v1.SecretKeySelector)
+		*out = new(corev1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
 	}
 }
@@ -129,6 +600,21 @@ func (in *Route53DNS01) DeepCopy() *Route53DNS01 {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantCARef) DeepCopyInto(out *TenantCARef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantCARef.
+func (in *TenantCARef) DeepCopy() *TenantCARef {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantCARef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TenantGateway) DeepCopyInto(out *TenantGateway) {
 	*out = *in
@@ -206,6 +692,11 @@ func (in *TenantGatewayListenerStatus) DeepCopy() *TenantGatewayListenerStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TenantGatewaySpec) DeepCopyInto(out *TenantGatewaySpec) {
 	*out = *in
+	if in.Issuer != nil {
+		in, out := &in.Issuer, &out.Issuer
+		*out = new(IssuerConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.DNS01 != nil {
 		in, out := &in.DNS01, &out.DNS01
 		*out = new(DNS01Config)
@@ -213,9 +704,28 @@ func (in *TenantGatewaySpec) DeepCopyInto(out *TenantGatewaySpec) {
 	}
 	if in.WildcardSecretRef != nil {
 		in, out := &in.WildcardSecretRef, &out.WildcardSecretRef
-		*out = new(v1.LocalObjectReference)
+		*out = new(corev1.LocalObjectReference)
 		**out = **in
 	}
+	if in.DNSRecords != nil {
+		in, out := &in.DNSRecords, &out.DNSRecords
+		*out = new(DNSRecordsConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]TrafficPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ClientValidation != nil {
+		in, out := &in.ClientValidation, &out.ClientValidation
+		*out = make([]ClientValidation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.AttachedNamespaces != nil {
 		in, out := &in.AttachedNamespaces, &out.AttachedNamespaces
 		*out = make([]string, len(*in))
@@ -243,7 +753,7 @@ func (in *TenantGatewayStatus) DeepCopyInto(out *TenantGatewayStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]metav1.Condition, len(*in))
+		*out = make([]v1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -253,6 +763,11 @@ func (in *TenantGatewayStatus) DeepCopyInto(out *TenantGatewayStatus) {
 		*out = make([]TenantGatewayListenerStatus, len(*in))
 		copy(*out, *in)
 	}
+	if in.PublishedDNSRecords != nil {
+		in, out := &in.PublishedDNSRecords, &out.PublishedDNSRecords
+		*out = make([]PublishedDNSRecord, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantGatewayStatus.
@@ -264,3 +779,98 @@ func (in *TenantGatewayStatus) DeepCopy() *TenantGatewayStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantSecretCARef) DeepCopyInto(out *TenantSecretCARef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantSecretCARef.
+func (in *TenantSecretCARef) DeepCopy() *TenantSecretCARef {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantSecretCARef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficPolicy) DeepCopyInto(out *TrafficPolicy) {
+	*out = *in
+	if in.Hostnames != nil {
+		in, out := &in.Hostnames, &out.Hostnames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]PolicyRouteRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(RateLimitPolicy)
+		**out = **in
+	}
+	if in.IPAllowList != nil {
+		in, out := &in.IPAllowList, &out.IPAllowList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IPDenyList != nil {
+		in, out := &in.IPDenyList, &out.IPDenyList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BasicAuth != nil {
+		in, out := &in.BasicAuth, &out.BasicAuth
+		*out = new(BasicAuthPolicy)
+		**out = **in
+	}
+	if in.ForwardAuth != nil {
+		in, out := &in.ForwardAuth, &out.ForwardAuth
+		*out = new(ForwardAuthPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxRequestBodySize != nil {
+		in, out := &in.MaxRequestBodySize, &out.MaxRequestBodySize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficPolicy.
+func (in *TrafficPolicy) DeepCopy() *TrafficPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookDNS01) DeepCopyInto(out *WebhookDNS01) {
+	*out = *in
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = new(apiextensionsv1.JSON)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RecordsTokenSecretRef != nil {
+		in, out := &in.RecordsTokenSecretRef, &out.RecordsTokenSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookDNS01.
+func (in *WebhookDNS01) DeepCopy() *WebhookDNS01 {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookDNS01)
+	in.DeepCopyInto(out)
+	return out
+}
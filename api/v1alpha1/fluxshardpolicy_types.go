@@ -0,0 +1,215 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// FluxShardPolicyName is the name of the one FluxShardPolicy the
+// flux-shard-operator reads.
+const FluxShardPolicyName = "default"
+
+// FluxShardPolicySpec configures how tenant HelmReleases are sharded over
+// helm-controller instances.
+type FluxShardPolicySpec struct {
+	// ShardCount is the number of helm-controller shards: "auto" sizes it
+	// from the tenant HelmRelease count, an integer pins it.
+	// +kubebuilder:default=auto
+	// +kubebuilder:validation:XValidation:rule="type(self) == string ? self == 'auto' : self >= 1",message="shardCount must be auto or a positive integer"
+	// +optional
+	ShardCount intstr.IntOrString `json:"shardCount,omitempty"`
+
+	// Concurrent is the --concurrent of each shard helm-controller.
+	// +kubebuilder:default=5
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Concurrent int32 `json:"concurrent,omitempty"`
+
+	// RebalanceThresholdPercent is the load spread, (max-min)/average in
+	// percent, above which tenants are moved between shards. 0 disables
+	// rebalancing.
+	// +kubebuilder:default=25
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RebalanceThresholdPercent int32 `json:"rebalanceThresholdPercent,omitempty"`
+
+	// PinnedTenants keep tenants on a given shard, out of rebalancing. A pin
+	// to a shard beyond the shard count is ignored.
+	// +listType=map
+	// +listMapKey=tenant
+	// +optional
+	PinnedTenants []FluxShardPin `json:"pinnedTenants,omitempty"`
+
+	// Resources of every shard helm-controller. Unset values inherit the
+	// flux-aio helm-controller's; source- and kustomize-controller shards
+	// always inherit theirs.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeAffinity of every shard pod.
+	// +optional
+	NodeAffinity *corev1.NodeAffinity `json:"nodeAffinity,omitempty"`
+
+	// SourceController also runs a source-controller per shard. Sources in
+	// tenant namespaces (HelmCharts of HelmReleases with a chart template,
+	// repositories a tenant chart renders) are then served from the shard of
+	// their tenant instead of the shared source-controller.
+	// +optional
+	SourceController bool `json:"sourceController,omitempty"`
+
+	// KustomizeController also runs a kustomize-controller per shard for the
+	// Kustomizations in tenant namespaces.
+	// +optional
+	KustomizeController bool `json:"kustomizeController,omitempty"`
+
+	// Shards override resources and node affinity of individual shards,
+	// e.g. one a heavy tenant is pinned to.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Shards []FluxShardOverride `json:"shards,omitempty"`
+}
+
+// FluxShardPin pins a tenant to a shard.
+type FluxShardPin struct {
+	// Tenant is the tenant namespace, e.g. tenant-bigone.
+	// +kubebuilder:validation:Pattern=`^tenant-[a-z0-9-]+$`
+	// +required
+	Tenant string `json:"tenant"`
+
+	// Shard is the shard name, e.g. shard3.
+	// +kubebuilder:validation:Pattern=`^shard(0|[1-9][0-9]*)$`
+	// +required
+	Shard string `json:"shard"`
+}
+
+// FluxShardOverride adjusts a single shard.
+type FluxShardOverride struct {
+	// Name is the shard name, e.g. shard3.
+	// +kubebuilder:validation:Pattern=`^shard(0|[1-9][0-9]*)$`
+	// +required
+	Name string `json:"name"`
+
+	// Resources are merged over spec.resources for this shard's
+	// helm-controller.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeAffinity replaces spec.nodeAffinity for this shard.
+	// +optional
+	NodeAffinity *corev1.NodeAffinity `json:"nodeAffinity,omitempty"`
+}
+
+// FluxShardPolicyStatus is maintained by the flux-shard-operator.
+type FluxShardPolicyStatus struct {
+	// ObservedGeneration is the generation the status was computed from.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ShardCount is the number of shards in use.
+	// +optional
+	ShardCount int32 `json:"shardCount,omitempty"`
+
+	// RecommendedShardCount is the autosizing recommendation before
+	// hysteresis.
+	// +optional
+	RecommendedShardCount int32 `json:"recommendedShardCount,omitempty"`
+
+	// Shards reports every shard.
+	// +optional
+	Shards []FluxShardStatus `json:"shards,omitempty"`
+
+	// PendingMoves are the reassignments not carried out yet, because moves
+	// are paced or the target shard is not ready.
+	// +optional
+	PendingMoves []FluxShardMove `json:"pendingMoves,omitempty"`
+
+	// Message explains a policy the operator could not fully apply.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// FluxShardStatus reports one shard.
+type FluxShardStatus struct {
+	// Name is the shard name.
+	Name string `json:"name"`
+
+	// Ready is true when every controller of the shard has a ready replica.
+	Ready bool `json:"ready"`
+
+	// Tenants are the tenant namespaces assigned to the shard.
+	// +optional
+	Tenants []string `json:"tenants,omitempty"`
+
+	// HelmReleases is the number of HelmReleases of those tenants.
+	// +optional
+	HelmReleases int32 `json:"helmReleases,omitempty"`
+
+	// Load is the summed placement weight of those tenants, in milliseconds
+	// of reconcile time per minute.
+	// +optional
+	Load int64 `json:"load,omitempty"`
+}
+
+// FluxShardMove is a pending tenant reassignment.
+type FluxShardMove struct {
+	// Tenant is the tenant namespace.
+	Tenant string `json:"tenant"`
+
+	// From is the current shard; empty for a tenant not assigned yet.
+	// +optional
+	From string `json:"from,omitempty"`
+
+	// To is the shard the tenant moves to.
+	To string `json:"to"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:validation:XValidation:rule="self.metadata.name == 'default'",message="the FluxShardPolicy must be named default"
+// +kubebuilder:printcolumn:name="Shards",type="integer",JSONPath=".status.shardCount"
+// +kubebuilder:printcolumn:name="Recommended",type="integer",JSONPath=".status.recommendedShardCount"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// FluxShardPolicy configures the flux-shard-operator: how many
+// helm-controller shards run, how they are sized and scheduled, and which
+// tenants are pinned. There is one, named default; edits take effect on the
+// next sync without restarting the operator.
+type FluxShardPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FluxShardPolicySpec   `json:"spec,omitempty"`
+	Status FluxShardPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// FluxShardPolicyList contains a list of FluxShardPolicy
+type FluxShardPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FluxShardPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FluxShardPolicy{}, &FluxShardPolicyList{})
+}
@@ -26,6 +26,7 @@ import (
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Variant",type="string",JSONPath=".spec.variant",description="Selected variant"
 // +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status",description="Ready status"
+// +kubebuilder:printcolumn:name="Rollout",type="string",JSONPath=".status.rollout.phase",description="Rollout phase",priority=1
 // +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].message",description="Ready message"
 
 // Package is the Schema for the packages API
@@ -66,6 +67,52 @@ type PackageSpec struct {
 	// Allows overriding values and enabling/disabling specific components from the PackageSource
 	// +optional
 	Components map[string]PackageComponent `json:"components,omitempty"`
+
+	// Rollout stages the upgrade of the Package's components when its
+	// source revision changes. Without it every component is upgraded at
+	// once.
+	// +optional
+	Rollout *RolloutPolicy `json:"rollout,omitempty"`
+}
+
+// RolloutPolicy upgrades the components of a Package in waves
+type RolloutPolicy struct {
+	// Waves are upgraded one after the other. Components not listed in any
+	// wave form a last wave of their own.
+	// +required
+	// +kubebuilder:validation:MinItems=1
+	Waves []RolloutWave `json:"waves"`
+
+	// Pause is how long a wave must stay healthy before the next one
+	// starts, or, for the last wave, before the rollout succeeds
+	// +optional
+	Pause *metav1.Duration `json:"pause,omitempty"`
+
+	// HealthTimeout is how long a wave may take to become healthy before
+	// the rollout fails. A component is healthy when its HelmRelease is
+	// Ready for the new artifact, which includes its healthCheckExprs.
+	// Defaults to 15m.
+	// +optional
+	HealthTimeout *metav1.Duration `json:"healthTimeout,omitempty"`
+
+	// AutoRevert reverts the whole Package to its last-known-good source
+	// revision when a wave fails. When false, a failed rollout stops and
+	// the waves not reached yet stay on the previous revision. Defaults to
+	// true.
+	// +optional
+	AutoRevert *bool `json:"autoRevert,omitempty"`
+}
+
+// RolloutWave is a set of components upgraded together
+type RolloutWave struct {
+	// Name identifies the wave in the rollout status
+	// +required
+	Name string `json:"name"`
+
+	// Components are the names of the components in the wave
+	// +required
+	// +kubebuilder:validation:MinItems=1
+	Components []string `json:"components"`
 }
 
 // PackageComponent defines overrides for a specific component
@@ -91,6 +138,101 @@ type PackageStatus struct {
 	// Key is the dependency package name, value indicates if the dependency is ready
 	// +optional
 	Dependencies map[string]DependencyStatus `json:"dependencies,omitempty"`
+
+	// Conflicts lists the dependency constraints that cannot be satisfied
+	// together with the installed packages, including those of Packages
+	// depending on this one that an upgrade of it would break. While any is
+	// listed, no HelmRelease of the Package is created or updated.
+	// +optional
+	Conflicts []DependencyConflict `json:"conflicts,omitempty"`
+
+	// Applied is what the HelmReleases of the Package were last reconciled
+	// to. Packages depending on it are checked against it, so an upgrade
+	// held back for breaking their constraints does not block them.
+	// +optional
+	Applied *AppliedPackage `json:"applied,omitempty"`
+
+	// Rollout is the progress of the staged rollout, when the Package has a
+	// rollout policy
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+}
+
+// RolloutPhase is the state of a staged rollout
+// +kubebuilder:validation:Enum=Progressing;Succeeded;Failed;RolledBack
+type RolloutPhase string
+
+const (
+	RolloutProgressing RolloutPhase = "Progressing"
+	RolloutSucceeded   RolloutPhase = "Succeeded"
+	RolloutFailed      RolloutPhase = "Failed"
+	RolloutRolledBack  RolloutPhase = "RolledBack"
+)
+
+// RolloutStatus records the progress of a staged rollout
+type RolloutStatus struct {
+	// Phase is the state of the rollout
+	Phase RolloutPhase `json:"phase"`
+
+	// Revision is the source revision being rolled out, or installed once
+	// the rollout succeeded
+	// +optional
+	Revision string `json:"revision,omitempty"`
+
+	// LastKnownGoodRevision is the last source revision every wave was
+	// healthy with; a failed rollout reverts to it
+	// +optional
+	LastKnownGoodRevision string `json:"lastKnownGoodRevision,omitempty"`
+
+	// FailedRevision is the revision whose rollout failed. The Package stays
+	// on the last-known-good revision until the source moves past it.
+	// +optional
+	FailedRevision string `json:"failedRevision,omitempty"`
+
+	// Wave is the index of the wave being upgraded
+	// +optional
+	Wave int32 `json:"wave,omitempty"`
+
+	// WaveStartedAt is when the current wave started upgrading
+	// +optional
+	WaveStartedAt *metav1.Time `json:"waveStartedAt,omitempty"`
+
+	// WaveHealthyAt is when the current wave became healthy; the next wave
+	// starts once the pause has passed since
+	// +optional
+	WaveHealthyAt *metav1.Time `json:"waveHealthyAt,omitempty"`
+
+	// Message describes the progress or the failure
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// AppliedPackage is the version and variant of a Package that its
+// HelmReleases were reconciled to
+type AppliedPackage struct {
+	// Version is the version of the PackageSource
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Variant is the name of the variant
+	Variant string `json:"variant"`
+
+	// Revision is the source revision the charts were generated from, when
+	// they follow the source rather than a rollout
+	// +optional
+	Revision string `json:"revision,omitempty"`
+}
+
+// DependencyConflict is a dependency constraint that cannot be satisfied
+type DependencyConflict struct {
+	// Package is the constrained package
+	Package string `json:"package"`
+
+	// RequiredBy is the package whose variant declares the constraint
+	RequiredBy string `json:"requiredBy"`
+
+	// Message explains why the constraint cannot be satisfied
+	Message string `json:"message"`
 }
 
 // DependencyStatus represents the readiness status of a dependency
@@ -18,14 +18,18 @@ package v1alpha1
 
 import (
 	"github.com/fluxcd/pkg/apis/kustomize"
+	fluxmeta "github.com/fluxcd/pkg/apis/meta"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Cluster,shortName={pks}
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".spec.version",description="Package version"
 // +kubebuilder:printcolumn:name="Variants",type="string",JSONPath=".status.variants",description="Package variants (comma-separated)"
 // +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status",description="Ready status"
+// +kubebuilder:printcolumn:name="Verified",type="string",JSONPath=".status.conditions[?(@.type=='Verified')].status",description="Verification status",priority=1
 // +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].message",description="Ready message"
 
 // PackageSource is the Schema for the packagesources API
@@ -52,6 +56,11 @@ func init() {
 
 // PackageSourceSpec defines the desired state of PackageSource
 type PackageSourceSpec struct {
+	// Version is the semantic version of the package, e.g. "1.4.2". Other
+	// packages constrain it in their variants' Constraints.
+	// +optional
+	Version string `json:"version,omitempty"`
+
 	// SourceRef is the source reference for the package source charts
 	// +optional
 	SourceRef *PackageSourceRef `json:"sourceRef,omitempty"`
@@ -60,6 +69,11 @@ type PackageSourceSpec struct {
 	// Each variant defines components, applications, dependencies, and libraries for a specific configuration
 	// +optional
 	Variants []Variant `json:"variants,omitempty"`
+
+	// Verify is what the source artifact must prove before any variant is
+	// installed from it. A variant's own Verify replaces it.
+	// +optional
+	Verify *ArtifactVerification `json:"verify,omitempty"`
 }
 
 // Variant defines a single variant configuration
@@ -73,6 +87,13 @@ type Variant struct {
 	// +optional
 	DependsOn []string `json:"dependsOn,omitempty"`
 
+	// Constraints restrict the version and variant of other packages. A
+	// package in DependsOn must satisfy its constraint before this variant
+	// is installed; any other constrained package conflicts with this
+	// variant when it is installed without satisfying it.
+	// +optional
+	Constraints []DependencyConstraint `json:"constraints,omitempty"`
+
 	// Libraries is a list of Helm library charts used by components in this variant
 	// +optional
 	Libraries []Library `json:"libraries,omitempty"`
@@ -80,6 +101,99 @@ type Variant struct {
 	// Components is a list of Helm releases to be installed as part of this variant
 	// +optional
 	Components []Component `json:"components,omitempty"`
+
+	// Verify replaces the PackageSource's Verify for this variant
+	// +optional
+	Verify *ArtifactVerification `json:"verify,omitempty"`
+}
+
+// DependencyConstraint restricts the package a variant depends on or
+// conflicts with
+type DependencyConstraint struct {
+	// Name is the name of the constrained package, e.g. "cozystack.networking"
+	// +required
+	Name string `json:"name"`
+
+	// Version is a semantic version constraint the package's version must
+	// satisfy, e.g. ">= 1.4" or "~1.4.0 || ^2"
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Variants lists the variants of the package that are compatible; empty
+	// allows any
+	// +optional
+	Variants []string `json:"variants,omitempty"`
+}
+
+// ArtifactVerification declares the signatures a source artifact must
+// carry and the origin it must come from. A variant whose artifact fails it
+// is blocked: its components get no chart artifacts until the source
+// satisfies it.
+type ArtifactVerification struct {
+	// Signature requires the artifact to be signed. The Flux source
+	// controller checks the signatures, so the referenced source must declare
+	// the same verification in its spec.verify; the PackageSource controller
+	// checks that it does and that it succeeded.
+	// +optional
+	Signature *SignaturePolicy `json:"signature,omitempty"`
+
+	// Origin restricts the repository and revision the artifact comes from.
+	// +optional
+	Origin *OriginPolicy `json:"origin,omitempty"`
+}
+
+// SignaturePolicy declares who must have signed an artifact
+type SignaturePolicy struct {
+	// Provider is the signing technology: cosign or notation for an
+	// OCIRepository, git for PGP-signed commits and tags of a GitRepository
+	// +kubebuilder:validation:Enum=cosign;notation;git
+	// +required
+	Provider string `json:"provider"`
+
+	// SecretRef is the Secret in the namespace of the source holding the
+	// trusted public keys; the source must verify with the same Secret
+	// +optional
+	SecretRef *fluxmeta.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// MatchOIDCIdentity lists the keyless (Fulcio) identities allowed to sign
+	// with cosign. The source must match on a subset of them.
+	// +optional
+	MatchOIDCIdentity []OIDCIdentity `json:"matchOIDCIdentity,omitempty"`
+
+	// GitMode is which Git objects must be signed: HEAD, Tag or TagAndHEAD.
+	// Defaults to HEAD.
+	// +kubebuilder:validation:Enum=HEAD;Tag;TagAndHEAD
+	// +optional
+	GitMode string `json:"gitMode,omitempty"`
+}
+
+// OIDCIdentity is a keyless signing identity, as Go regular expressions
+// matched against the issuer and subject of the Fulcio certificate
+type OIDCIdentity struct {
+	// +required
+	Issuer string `json:"issuer"`
+	// +required
+	Subject string `json:"subject"`
+}
+
+// OriginPolicy restricts where an artifact comes from. For an OCI artifact
+// it matches the org.opencontainers.image.source and .revision annotations
+// the publisher set, which are only as trustworthy as the signature
+// covering them; no build attestation is read. For a GitRepository it
+// matches the URL the source fetches from and the revision it fetched.
+// Patterns are Go regular expressions that must match the whole value.
+type OriginPolicy struct {
+	// SourceURI must match the repository: the
+	// org.opencontainers.image.source annotation of an OCI artifact, or the
+	// URL of a GitRepository
+	// +optional
+	SourceURI string `json:"sourceURI,omitempty"`
+
+	// Revision must match the revision: the
+	// org.opencontainers.image.revision annotation of an OCI artifact, or
+	// the revision of a GitRepository artifact, e.g. v1.2.0@sha1:<commit>
+	// +optional
+	Revision string `json:"revision,omitempty"`
 }
 
 // Library defines a Helm library chart
@@ -186,6 +300,13 @@ type Component struct {
 	// ValuesFiles is a list of values file names to use
 	// +optional
 	ValuesFiles []string `json:"valuesFiles,omitempty"`
+
+	// ValuesSchema is the OpenAPI v3 schema of the values a Package may
+	// override for this component. A Package whose values do not match it
+	// is not installed. When unset, the values.schema.json the chart ships
+	// is used.
+	// +optional
+	ValuesSchema *apiextensionsv1.JSON `json:"valuesSchema,omitempty"`
 }
 
 // PackageSourceStatus defines the observed state of PackageSource
@@ -198,4 +319,60 @@ type PackageSourceStatus struct {
 	// Conditions represents the latest available observations of a PackageSource's state
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Verification is the verification state of every variant with a
+	// verification policy
+	// +optional
+	Verification []VariantVerification `json:"verification,omitempty"`
+
+	// VerifiedRevision is the last source revision that passed the
+	// verification of every variant with a policy. While a newer revision
+	// awaits verification, the ArtifactGenerator reads this one.
+	// +optional
+	VerifiedRevision string `json:"verifiedRevision,omitempty"`
+
+	// ValuesSchemas reference the values.schema.json files the component
+	// charts ship, as read from ValuesSchemasRevision of the source. Each is
+	// kept in a ConfigMap of its own: together they would outgrow the
+	// PackageSource
+	// +optional
+	ValuesSchemas []ChartValuesSchema `json:"valuesSchemas,omitempty"`
+
+	// ValuesSchemasRevision is the source revision ValuesSchemas were read
+	// from
+	// +optional
+	ValuesSchemasRevision string `json:"valuesSchemasRevision,omitempty"`
+}
+
+// ChartValuesSchema references the values schema a chart ships
+type ChartValuesSchema struct {
+	// Path is the path of the chart directory, as components give it
+	Path string `json:"path"`
+
+	// ConfigMapName is the ConfigMap in cozy-system holding the content of
+	// the chart's values.schema.json, under the values.schema.json key
+	ConfigMapName string `json:"configMapName"`
+}
+
+// VariantVerification is the verification state of one variant
+type VariantVerification struct {
+	// Variant is the name of the variant
+	Variant string `json:"variant"`
+
+	// Status is True when the artifact satisfies the variant's policy, False
+	// when it fails it and the variant is blocked, and Unknown while the
+	// source has not been verified yet
+	Status metav1.ConditionStatus `json:"status"`
+
+	// Reason is a CamelCase reason for the status
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message explains a False or Unknown status
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Revision is the source revision the status was determined for
+	// +optional
+	Revision string `json:"revision,omitempty"`
 }
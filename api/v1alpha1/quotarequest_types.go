@@ -0,0 +1,134 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// QuotaRequestSpec asks for a temporary raise of a tenant's quota.
+type QuotaRequestSpec struct {
+	// Resources are added to the tenant's declared budget while the request
+	// is active. Keys are those of the tenant's tenant-quota ResourceQuota
+	// (e.g. requests.cpu, limits.memory, requests.storage); a resource the
+	// tenant does not limit cannot be raised.
+	// +required
+	Resources corev1.ResourceList `json:"resources"`
+
+	// Duration is how long the raise lasts once approved, e.g. 72h.
+	// +required
+	Duration metav1.Duration `json:"duration"`
+
+	// Reason explains the request to the approver.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Decision is set by an approver: a user allowed to approve
+	// quotarequests in the parent tenant's namespace, or cluster-wide for
+	// tenant-root. It cannot be changed once set, and neither can resources
+	// and duration.
+	// +optional
+	Decision *QuotaRequestDecision `json:"decision,omitempty"`
+}
+
+// QuotaRequestDecision records who approved or denied a QuotaRequest.
+type QuotaRequestDecision struct {
+	// Approved grants the request; false denies it.
+	// +required
+	Approved bool `json:"approved"`
+
+	// By is the username of the approver. Admission refuses a decision
+	// whose by is not the user submitting it.
+	// +required
+	By string `json:"by"`
+
+	// Comment is the approver's note to the requester.
+	// +optional
+	Comment string `json:"comment,omitempty"`
+}
+
+// QuotaRequestPhase is the lifecycle state of a QuotaRequest.
+// +kubebuilder:validation:Enum=Pending;Active;Expired;Denied;Invalid
+type QuotaRequestPhase string
+
+const (
+	// QuotaRequestPending awaits a decision.
+	QuotaRequestPending QuotaRequestPhase = "Pending"
+	// QuotaRequestActive is approved and raises the tenant's budget until
+	// status.expiresAt.
+	QuotaRequestActive QuotaRequestPhase = "Active"
+	// QuotaRequestExpired was active and no longer raises the budget.
+	QuotaRequestExpired QuotaRequestPhase = "Expired"
+	// QuotaRequestDenied was refused by the approver.
+	QuotaRequestDenied QuotaRequestPhase = "Denied"
+	// QuotaRequestInvalid cannot be applied, or was approved for more than
+	// the parent tenant's pool has left; see status.message. An approved
+	// request that turned Invalid is not retried.
+	QuotaRequestInvalid QuotaRequestPhase = "Invalid"
+)
+
+// QuotaRequestStatus is maintained by the tenant quota controller.
+type QuotaRequestStatus struct {
+	// +optional
+	Phase QuotaRequestPhase `json:"phase,omitempty"`
+
+	// Message explains an Invalid phase.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// ApprovedAt is when the controller first saw the approval; the raise
+	// runs from here for spec.duration.
+	// +optional
+	ApprovedAt *metav1.Time `json:"approvedAt,omitempty"`
+
+	// ExpiresAt is when the raise ends.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Approver",type="string",JSONPath=".spec.decision.by"
+// +kubebuilder:printcolumn:name="Expires",type="date",JSONPath=".status.expiresAt"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// QuotaRequest is a tenant's request, filed in its own namespace, to raise
+// its quota for a limited time. The parent tenant (or a platform admin)
+// approves it by setting spec.decision; the tenant quota controller then
+// overlays spec.resources on the tenant's declared budget until it expires.
+type QuotaRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   QuotaRequestSpec   `json:"spec,omitempty"`
+	Status QuotaRequestStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// QuotaRequestList contains a list of QuotaRequest
+type QuotaRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []QuotaRequest `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&QuotaRequest{}, &QuotaRequestList{})
+}
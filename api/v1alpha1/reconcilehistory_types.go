@@ -0,0 +1,121 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Outcomes of a ReconcileAttempt.
+const (
+	ReconcileInProgress = "InProgress"
+	ReconcileSucceeded  = "Succeeded"
+	ReconcileFailed     = "Failed"
+)
+
+// ReconcileAttempt is one helm-controller reconcile attempt of a HelmRelease,
+// as observed from its status.
+type ReconcileAttempt struct {
+	// StartTime is when the attempt began: when the HelmRelease turned
+	// Reconciling or, if that was not observed, the completion time minus
+	// the duration helm-controller reports for its last release action.
+	// +required
+	StartTime metav1.Time `json:"startTime"`
+
+	// CompletionTime is when the outcome was observed; unset while the
+	// attempt is in progress.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Outcome is InProgress, Succeeded or Failed.
+	// +kubebuilder:validation:Enum=InProgress;Succeeded;Failed
+	// +required
+	Outcome string `json:"outcome"`
+
+	// Reason is the reason of the Ready condition, e.g. UpgradeFailed.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is the message of the Ready condition, which carries the
+	// failure on a failed attempt.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Action is the Helm action attempted: install or upgrade.
+	// +optional
+	Action string `json:"action,omitempty"`
+
+	// ChartVersion is the chart version attempted.
+	// +optional
+	ChartVersion string `json:"chartVersion,omitempty"`
+
+	// Generation is the HelmRelease generation attempted.
+	// +optional
+	Generation int64 `json:"generation,omitempty"`
+
+	// Shard is the sharding.fluxcd.io/key of the HelmRelease at the time,
+	// naming the helm-controller shard that handled it; empty for the
+	// flux-aio helm-controller.
+	// +optional
+	Shard string `json:"shard,omitempty"`
+}
+
+// ReconcileHistoryStatus holds the recorded attempts.
+type ReconcileHistoryStatus struct {
+	// InProgress is the attempt under way, if any.
+	// +optional
+	InProgress *ReconcileAttempt `json:"inProgress,omitempty"`
+
+	// Attempts are the finished attempts, newest first.
+	// +optional
+	Attempts []ReconcileAttempt `json:"attempts,omitempty"`
+
+	// ObservedState fingerprints the HelmRelease status the last attempt was
+	// recorded from, so an unchanged status is not recorded twice.
+	// +optional
+	ObservedState string `json:"observedState,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Outcome",type="string",JSONPath=".status.attempts[0].outcome"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.attempts[0].reason"
+// +kubebuilder:printcolumn:name="Completed",type="date",JSONPath=".status.attempts[0].completionTime"
+
+// ReconcileHistory records the recent reconcile attempts of one Application
+// HelmRelease. It is written by the reconcile history controller, named
+// after the HelmRelease and owned by it; the core.cozystack.io
+// reconciliations view serves it to tenants.
+type ReconcileHistory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status ReconcileHistoryStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReconcileHistoryList contains a list of ReconcileHistory
+type ReconcileHistoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReconcileHistory `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReconcileHistory{}, &ReconcileHistoryList{})
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TenantMigrationVolumeStrategy is how application data follows a tenant to
+// its new namespaces.
+// +kubebuilder:validation:Enum=Rebind;Backup
+type TenantMigrationVolumeStrategy string
+
+const (
+	// TenantMigrationRebind stops each application, releases its
+	// PersistentVolumes and binds them to claims of the same name in the
+	// new namespace. Data never leaves the volumes.
+	TenantMigrationRebind TenantMigrationVolumeStrategy = "Rebind"
+	// TenantMigrationBackup takes a final backup of each application
+	// through its backup Plan and restores it into the recreated
+	// application. Every application in the subtree needs a Plan.
+	TenantMigrationBackup TenantMigrationVolumeStrategy = "Backup"
+)
+
+// TenantMigrationSpec names the tenant to move and where to.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+type TenantMigrationSpec struct {
+	// Tenant is the namespace of the tenant to move, e.g. tenant-foo-bar.
+	// Its sub-tenants move with it.
+	// +required
+	Tenant string `json:"tenant"`
+
+	// NewParent is the namespace of the tenant to move it under, e.g.
+	// tenant-baz.
+	// +required
+	NewParent string `json:"newParent"`
+
+	// NewName renames the tenant; it defaults to its current name.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]+$`
+	// +optional
+	NewName string `json:"newName,omitempty"`
+
+	// VolumeStrategy is how application data is moved.
+	// +kubebuilder:default=Rebind
+	// +optional
+	VolumeStrategy TenantMigrationVolumeStrategy `json:"volumeStrategy,omitempty"`
+}
+
+// TenantMigrationPhase is the progress of a TenantMigration.
+// +kubebuilder:validation:Enum=Pending;Provisioning;Moving;Finalizing;Succeeded;Failed
+type TenantMigrationPhase string
+
+const (
+	// TenantMigrationPending has not been validated yet.
+	TenantMigrationPending TenantMigrationPhase = "Pending"
+	// TenantMigrationProvisioning is creating the new tenant namespaces and
+	// copying their configuration.
+	TenantMigrationProvisioning TenantMigrationPhase = "Provisioning"
+	// TenantMigrationMoving is moving applications one at a time.
+	TenantMigrationMoving TenantMigrationPhase = "Moving"
+	// TenantMigrationFinalizing is deleting the old tenant.
+	TenantMigrationFinalizing TenantMigrationPhase = "Finalizing"
+	// TenantMigrationSucceeded has moved the whole subtree.
+	TenantMigrationSucceeded TenantMigrationPhase = "Succeeded"
+	// TenantMigrationFailed stopped; see status.message. Applications
+	// already moved stay in their new namespaces and the rest where they
+	// were.
+	TenantMigrationFailed TenantMigrationPhase = "Failed"
+)
+
+// TenantMigrationNamespace maps one namespace of the subtree.
+type TenantMigrationNamespace struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// TenantMigrationApplicationPhase is the progress of one application.
+// +kubebuilder:validation:Enum=Pending;BackingUp;Stopping;Releasing;Recreating;Restoring;Moved;Failed
+type TenantMigrationApplicationPhase string
+
+const (
+	TenantMigrationApplicationPending    TenantMigrationApplicationPhase = "Pending"
+	TenantMigrationApplicationBackingUp  TenantMigrationApplicationPhase = "BackingUp"
+	TenantMigrationApplicationStopping   TenantMigrationApplicationPhase = "Stopping"
+	TenantMigrationApplicationReleasing  TenantMigrationApplicationPhase = "Releasing"
+	TenantMigrationApplicationRecreating TenantMigrationApplicationPhase = "Recreating"
+	TenantMigrationApplicationRestoring  TenantMigrationApplicationPhase = "Restoring"
+	TenantMigrationApplicationMoved      TenantMigrationApplicationPhase = "Moved"
+	TenantMigrationApplicationFailed     TenantMigrationApplicationPhase = "Failed"
+)
+
+// TenantMigrationApplication is the progress of one application of the
+// subtree. Tenants and the services the tenant chart deploys itself are
+// recreated with their namespaces and not listed.
+type TenantMigrationApplication struct {
+	// Namespace is the application's source namespace.
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+
+	// +optional
+	Phase TenantMigrationApplicationPhase `json:"phase,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Backup is the final backup the application is restored from.
+	// +optional
+	Backup string `json:"backup,omitempty"`
+
+	// Volumes are the claims being rebound.
+	// +optional
+	Volumes []TenantMigrationVolume `json:"volumes,omitempty"`
+}
+
+// TenantMigrationVolume records a claim being moved to the new namespace,
+// so it can be recreated after the original is deleted.
+type TenantMigrationVolume struct {
+	Claim  string `json:"claim"`
+	Volume string `json:"volume"`
+
+	// ReclaimPolicy is the volume's policy before it was set to Retain for
+	// the move; it is put back once the new claim is bound.
+	ReclaimPolicy string `json:"reclaimPolicy"`
+
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// TenantMigrationStatus is maintained by the tenant migration controller.
+type TenantMigrationStatus struct {
+	// +optional
+	Phase TenantMigrationPhase `json:"phase,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Destination is the namespace the tenant moves to.
+	// +optional
+	Destination string `json:"destination,omitempty"`
+
+	// Namespaces maps every namespace of the subtree, parents first.
+	// +optional
+	Namespaces []TenantMigrationNamespace `json:"namespaces,omitempty"`
+
+	// +optional
+	Applications []TenantMigrationApplication `json:"applications,omitempty"`
+
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Tenant",type="string",JSONPath=".spec.tenant"
+// +kubebuilder:printcolumn:name="Destination",type="string",JSONPath=".status.destination"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// TenantMigration moves a tenant and its sub-tenants under a different
+// parent, optionally renaming it. Tenant namespaces are named after their
+// position in the tree, so the controller recreates the subtree under its
+// new names, moves the applications over one at a time and then deletes the
+// old tenant. New applications are refused in the old namespaces while the
+// migration runs.
+type TenantMigration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TenantMigrationSpec   `json:"spec,omitempty"`
+	Status TenantMigrationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TenantMigrationList contains a list of TenantMigration
+type TenantMigrationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TenantMigration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TenantMigration{}, &TenantMigrationList{})
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// UsageRollupStatus holds the usage metered in one namespace over one UTC day.
+type UsageRollupStatus struct {
+	// Date is the UTC day covered, as YYYY-MM-DD.
+	// +required
+	Date string `json:"date"`
+
+	// Hours holds one entry per hour of the day in which anything was sampled.
+	// +optional
+	Hours []UsageHour `json:"hours,omitempty"`
+
+	// LastSampleTime is the latest sample credited to this rollup. A sampler
+	// that restarts, or takes over the leader lease, resumes from it instead
+	// of crediting time that was already credited.
+	// +optional
+	LastSampleTime *metav1.Time `json:"lastSampleTime,omitempty"`
+}
+
+// UsageHour is the usage integrated over one hour.
+type UsageHour struct {
+	// Start is the beginning of the hour.
+	// +required
+	Start metav1.Time `json:"start"`
+
+	// SampledSeconds is how much of the hour the samples cover. Less than
+	// 3600 means the metering controller was not running for part of it.
+	// +optional
+	SampledSeconds int64 `json:"sampledSeconds,omitempty"`
+
+	// Applications holds the usage attributed to each Application, plus an
+	// entry with empty kind and name for Workloads that carry no Application
+	// labels.
+	// +optional
+	Applications []ApplicationUsage `json:"applications,omitempty"`
+}
+
+// ApplicationUsage is the usage of one Application within a UsageHour.
+type ApplicationUsage struct {
+	// Kind is the Application kind, e.g. Postgres.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name is the Application name.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Usage maps a Workload resource name to the amount held integrated over
+	// time, in units of the resource times hours (e.g. 2 cpu for 30 minutes
+	// is 1).
+	// +optional
+	Usage map[string]resource.Quantity `json:"usage,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Date",type="string",JSONPath=".status.date"
+
+// UsageRollup is the metering record of one namespace for one UTC day,
+// written by the metering controller from the namespace's Workloads. It is
+// named usage-<YYYY-MM-DD>.
+type UsageRollup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status UsageRollupStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// UsageRollupList contains a list of UsageRollup
+type UsageRollupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []UsageRollup `json:"items"`
+}
+
+// Price is the hourly price of one unit of a resource.
+type Price struct {
+	// Resource is a Workload resource name such as "cpu" or
+	// "s3-storage-bytes". A leading "*" matches any prefix, so
+	// "*.storageclass.storage.k8s.io/requests.storage" prices storage in
+	// every StorageClass. An exact match wins over a pattern, and a longer
+	// pattern over a shorter one.
+	// +required
+	Resource string `json:"resource"`
+
+	// PerHour is the price of holding Unit of the resource for one hour.
+	// +required
+	PerHour resource.Quantity `json:"perHour"`
+
+	// Unit is the amount of the resource PerHour is charged for, e.g. 1Gi
+	// for storage. Defaults to 1.
+	// +optional
+	Unit *resource.Quantity `json:"unit,omitempty"`
+}
+
+// PriceListSpec defines the prices applied to usage reports.
+type PriceListSpec struct {
+	// Currency is shown next to every cost, e.g. EUR.
+	// +optional
+	Currency string `json:"currency,omitempty"`
+
+	// Prices lists the priced resources. Usage of a resource without a price
+	// is reported without a cost.
+	// +optional
+	Prices []Price `json:"prices,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Currency",type="string",JSONPath=".spec.currency"
+
+// PriceList prices metered usage. Usage reports use the PriceList named
+// "default"; without one they carry quantities only.
+type PriceList struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PriceListSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PriceListList contains a list of PriceList
+type PriceListList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PriceList `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&UsageRollup{}, &UsageRollupList{}, &PriceList{}, &PriceListList{})
+}
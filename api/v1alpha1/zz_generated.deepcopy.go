@@ -23,6 +23,8 @@ package v1alpha1
 import (
 	"github.com/fluxcd/helm-controller/api/v2"
 	"github.com/fluxcd/pkg/apis/kustomize"
+	"github.com/fluxcd/pkg/apis/meta"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -253,6 +255,83 @@ func (in *ApplicationDefinitionSpec) DeepCopy() *ApplicationDefinitionSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationUsage) DeepCopyInto(out *ApplicationUsage) {
+	*out = *in
+	if in.Usage != nil {
+		in, out := &in.Usage, &out.Usage
+		*out = make(map[string]resource.Quantity, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationUsage.
+func (in *ApplicationUsage) DeepCopy() *ApplicationUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppliedPackage) DeepCopyInto(out *AppliedPackage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppliedPackage.
+func (in *AppliedPackage) DeepCopy() *AppliedPackage {
+	if in == nil {
+		return nil
+	}
+	out := new(AppliedPackage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArtifactVerification) DeepCopyInto(out *ArtifactVerification) {
+	*out = *in
+	if in.Signature != nil {
+		in, out := &in.Signature, &out.Signature
+		*out = new(SignaturePolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Origin != nil {
+		in, out := &in.Origin, &out.Origin
+		*out = new(OriginPolicy)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactVerification.
+func (in *ArtifactVerification) DeepCopy() *ArtifactVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartValuesSchema) DeepCopyInto(out *ChartValuesSchema) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartValuesSchema.
+func (in *ChartValuesSchema) DeepCopy() *ChartValuesSchema {
+	if in == nil {
+		return nil
+	}
+	out := new(ChartValuesSchema)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Component) DeepCopyInto(out *Component) {
 	*out = *in
@@ -271,6 +350,11 @@ func (in *Component) DeepCopyInto(out *Component) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ValuesSchema != nil {
+		in, out := &in.ValuesSchema, &out.ValuesSchema
+		*out = new(v1.JSON)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Component.
@@ -308,6 +392,41 @@ func (in *ComponentInstall) DeepCopy() *ComponentInstall {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DependencyConflict) DeepCopyInto(out *DependencyConflict) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DependencyConflict.
+func (in *DependencyConflict) DeepCopy() *DependencyConflict {
+	if in == nil {
+		return nil
+	}
+	out := new(DependencyConflict)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DependencyConstraint) DeepCopyInto(out *DependencyConstraint) {
+	*out = *in
+	if in.Variants != nil {
+		in, out := &in.Variants, &out.Variants
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DependencyConstraint.
+func (in *DependencyConstraint) DeepCopy() *DependencyConstraint {
+	if in == nil {
+		return nil
+	}
+	out := new(DependencyConstraint)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DependencyStatus) DeepCopyInto(out *DependencyStatus) {
 	*out = *in
@@ -323,6 +442,197 @@ func (in *DependencyStatus) DeepCopy() *DependencyStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluxShardMove) DeepCopyInto(out *FluxShardMove) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluxShardMove.
+func (in *FluxShardMove) DeepCopy() *FluxShardMove {
+	if in == nil {
+		return nil
+	}
+	out := new(FluxShardMove)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluxShardOverride) DeepCopyInto(out *FluxShardOverride) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.NodeAffinity != nil {
+		in, out := &in.NodeAffinity, &out.NodeAffinity
+		*out = new(corev1.NodeAffinity)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluxShardOverride.
+func (in *FluxShardOverride) DeepCopy() *FluxShardOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(FluxShardOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluxShardPin) DeepCopyInto(out *FluxShardPin) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluxShardPin.
+func (in *FluxShardPin) DeepCopy() *FluxShardPin {
+	if in == nil {
+		return nil
+	}
+	out := new(FluxShardPin)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluxShardPolicy) DeepCopyInto(out *FluxShardPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluxShardPolicy.
+func (in *FluxShardPolicy) DeepCopy() *FluxShardPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(FluxShardPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FluxShardPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluxShardPolicyList) DeepCopyInto(out *FluxShardPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FluxShardPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluxShardPolicyList.
+func (in *FluxShardPolicyList) DeepCopy() *FluxShardPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(FluxShardPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FluxShardPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluxShardPolicySpec) DeepCopyInto(out *FluxShardPolicySpec) {
+	*out = *in
+	out.ShardCount = in.ShardCount
+	if in.PinnedTenants != nil {
+		in, out := &in.PinnedTenants, &out.PinnedTenants
+		*out = make([]FluxShardPin, len(*in))
+		copy(*out, *in)
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.NodeAffinity != nil {
+		in, out := &in.NodeAffinity, &out.NodeAffinity
+		*out = new(corev1.NodeAffinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Shards != nil {
+		in, out := &in.Shards, &out.Shards
+		*out = make([]FluxShardOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluxShardPolicySpec.
+func (in *FluxShardPolicySpec) DeepCopy() *FluxShardPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FluxShardPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluxShardPolicyStatus) DeepCopyInto(out *FluxShardPolicyStatus) {
+	*out = *in
+	if in.Shards != nil {
+		in, out := &in.Shards, &out.Shards
+		*out = make([]FluxShardStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PendingMoves != nil {
+		in, out := &in.PendingMoves, &out.PendingMoves
+		*out = make([]FluxShardMove, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluxShardPolicyStatus.
+func (in *FluxShardPolicyStatus) DeepCopy() *FluxShardPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FluxShardPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluxShardStatus) DeepCopyInto(out *FluxShardStatus) {
+	*out = *in
+	if in.Tenants != nil {
+		in, out := &in.Tenants, &out.Tenants
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluxShardStatus.
+func (in *FluxShardStatus) DeepCopy() *FluxShardStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FluxShardStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Library) DeepCopyInto(out *Library) {
 	*out = *in
@@ -338,6 +648,36 @@ func (in *Library) DeepCopy() *Library {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCIdentity) DeepCopyInto(out *OIDCIdentity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCIdentity.
+func (in *OIDCIdentity) DeepCopy() *OIDCIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OriginPolicy) DeepCopyInto(out *OriginPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OriginPolicy.
+func (in *OriginPolicy) DeepCopy() *OriginPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(OriginPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Package) DeepCopyInto(out *Package) {
 	*out = *in
@@ -511,6 +851,11 @@ func (in *PackageSourceSpec) DeepCopyInto(out *PackageSourceSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Verify != nil {
+		in, out := &in.Verify, &out.Verify
+		*out = new(ArtifactVerification)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageSourceSpec.
@@ -533,6 +878,16 @@ func (in *PackageSourceStatus) DeepCopyInto(out *PackageSourceStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = make([]VariantVerification, len(*in))
+		copy(*out, *in)
+	}
+	if in.ValuesSchemas != nil {
+		in, out := &in.ValuesSchemas, &out.ValuesSchemas
+		*out = make([]ChartValuesSchema, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageSourceStatus.
@@ -560,6 +915,11 @@ func (in *PackageSpec) DeepCopyInto(out *PackageSpec) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageSpec.
@@ -589,6 +949,21 @@ func (in *PackageStatus) DeepCopyInto(out *PackageStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.Conflicts != nil {
+		in, out := &in.Conflicts, &out.Conflicts
+		*out = make([]DependencyConflict, len(*in))
+		copy(*out, *in)
+	}
+	if in.Applied != nil {
+		in, out := &in.Applied, &out.Applied
+		*out = new(AppliedPackage)
+		**out = **in
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageStatus.
@@ -602,10 +977,421 @@ func (in *PackageStatus) DeepCopy() *PackageStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in Selector) DeepCopyInto(out *Selector) {
-	{
-		in := &in
-		*out = make(Selector, len(*in))
+func (in *Price) DeepCopyInto(out *Price) {
+	*out = *in
+	out.PerHour = in.PerHour.DeepCopy()
+	if in.Unit != nil {
+		in, out := &in.Unit, &out.Unit
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Price.
+func (in *Price) DeepCopy() *Price {
+	if in == nil {
+		return nil
+	}
+	out := new(Price)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PriceList) DeepCopyInto(out *PriceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PriceList.
+func (in *PriceList) DeepCopy() *PriceList {
+	if in == nil {
+		return nil
+	}
+	out := new(PriceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PriceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PriceListList) DeepCopyInto(out *PriceListList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PriceList, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PriceListList.
+func (in *PriceListList) DeepCopy() *PriceListList {
+	if in == nil {
+		return nil
+	}
+	out := new(PriceListList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PriceListList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PriceListSpec) DeepCopyInto(out *PriceListSpec) {
+	*out = *in
+	if in.Prices != nil {
+		in, out := &in.Prices, &out.Prices
+		*out = make([]Price, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PriceListSpec.
+func (in *PriceListSpec) DeepCopy() *PriceListSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PriceListSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuotaRequest) DeepCopyInto(out *QuotaRequest) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuotaRequest.
+func (in *QuotaRequest) DeepCopy() *QuotaRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(QuotaRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *QuotaRequest) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuotaRequestDecision) DeepCopyInto(out *QuotaRequestDecision) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuotaRequestDecision.
+func (in *QuotaRequestDecision) DeepCopy() *QuotaRequestDecision {
+	if in == nil {
+		return nil
+	}
+	out := new(QuotaRequestDecision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuotaRequestList) DeepCopyInto(out *QuotaRequestList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]QuotaRequest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuotaRequestList.
+func (in *QuotaRequestList) DeepCopy() *QuotaRequestList {
+	if in == nil {
+		return nil
+	}
+	out := new(QuotaRequestList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *QuotaRequestList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuotaRequestSpec) DeepCopyInto(out *QuotaRequestSpec) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	out.Duration = in.Duration
+	if in.Decision != nil {
+		in, out := &in.Decision, &out.Decision
+		*out = new(QuotaRequestDecision)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuotaRequestSpec.
+func (in *QuotaRequestSpec) DeepCopy() *QuotaRequestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(QuotaRequestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuotaRequestStatus) DeepCopyInto(out *QuotaRequestStatus) {
+	*out = *in
+	if in.ApprovedAt != nil {
+		in, out := &in.ApprovedAt, &out.ApprovedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuotaRequestStatus.
+func (in *QuotaRequestStatus) DeepCopy() *QuotaRequestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(QuotaRequestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReconcileAttempt) DeepCopyInto(out *ReconcileAttempt) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReconcileAttempt.
+func (in *ReconcileAttempt) DeepCopy() *ReconcileAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(ReconcileAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReconcileHistory) DeepCopyInto(out *ReconcileHistory) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReconcileHistory.
+func (in *ReconcileHistory) DeepCopy() *ReconcileHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(ReconcileHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReconcileHistory) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReconcileHistoryList) DeepCopyInto(out *ReconcileHistoryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ReconcileHistory, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReconcileHistoryList.
+func (in *ReconcileHistoryList) DeepCopy() *ReconcileHistoryList {
+	if in == nil {
+		return nil
+	}
+	out := new(ReconcileHistoryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReconcileHistoryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReconcileHistoryStatus) DeepCopyInto(out *ReconcileHistoryStatus) {
+	*out = *in
+	if in.InProgress != nil {
+		in, out := &in.InProgress, &out.InProgress
+		*out = new(ReconcileAttempt)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Attempts != nil {
+		in, out := &in.Attempts, &out.Attempts
+		*out = make([]ReconcileAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReconcileHistoryStatus.
+func (in *ReconcileHistoryStatus) DeepCopy() *ReconcileHistoryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReconcileHistoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutPolicy) DeepCopyInto(out *RolloutPolicy) {
+	*out = *in
+	if in.Waves != nil {
+		in, out := &in.Waves, &out.Waves
+		*out = make([]RolloutWave, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Pause != nil {
+		in, out := &in.Pause, &out.Pause
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.HealthTimeout != nil {
+		in, out := &in.HealthTimeout, &out.HealthTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.AutoRevert != nil {
+		in, out := &in.AutoRevert, &out.AutoRevert
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutPolicy.
+func (in *RolloutPolicy) DeepCopy() *RolloutPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+	*out = *in
+	if in.WaveStartedAt != nil {
+		in, out := &in.WaveStartedAt, &out.WaveStartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.WaveHealthyAt != nil {
+		in, out := &in.WaveHealthyAt, &out.WaveHealthyAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
+func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutWave) DeepCopyInto(out *RolloutWave) {
+	*out = *in
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutWave.
+func (in *RolloutWave) DeepCopy() *RolloutWave {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutWave)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in Selector) DeepCopyInto(out *Selector) {
+	{
+		in := &in
+		*out = make(Selector, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
 		}
@@ -622,6 +1408,306 @@ func (in Selector) DeepCopy() Selector {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SignaturePolicy) DeepCopyInto(out *SignaturePolicy) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(meta.LocalObjectReference)
+		**out = **in
+	}
+	if in.MatchOIDCIdentity != nil {
+		in, out := &in.MatchOIDCIdentity, &out.MatchOIDCIdentity
+		*out = make([]OIDCIdentity, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SignaturePolicy.
+func (in *SignaturePolicy) DeepCopy() *SignaturePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(SignaturePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantMigration) DeepCopyInto(out *TenantMigration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantMigration.
+func (in *TenantMigration) DeepCopy() *TenantMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TenantMigration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantMigrationApplication) DeepCopyInto(out *TenantMigrationApplication) {
+	*out = *in
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]TenantMigrationVolume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantMigrationApplication.
+func (in *TenantMigrationApplication) DeepCopy() *TenantMigrationApplication {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantMigrationApplication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantMigrationList) DeepCopyInto(out *TenantMigrationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TenantMigration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantMigrationList.
+func (in *TenantMigrationList) DeepCopy() *TenantMigrationList {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantMigrationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TenantMigrationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantMigrationNamespace) DeepCopyInto(out *TenantMigrationNamespace) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantMigrationNamespace.
+func (in *TenantMigrationNamespace) DeepCopy() *TenantMigrationNamespace {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantMigrationNamespace)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantMigrationSpec) DeepCopyInto(out *TenantMigrationSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantMigrationSpec.
+func (in *TenantMigrationSpec) DeepCopy() *TenantMigrationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantMigrationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantMigrationStatus) DeepCopyInto(out *TenantMigrationStatus) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]TenantMigrationNamespace, len(*in))
+		copy(*out, *in)
+	}
+	if in.Applications != nil {
+		in, out := &in.Applications, &out.Applications
+		*out = make([]TenantMigrationApplication, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StartedAt != nil {
+		in, out := &in.StartedAt, &out.StartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantMigrationStatus.
+func (in *TenantMigrationStatus) DeepCopy() *TenantMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantMigrationVolume) DeepCopyInto(out *TenantMigrationVolume) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantMigrationVolume.
+func (in *TenantMigrationVolume) DeepCopy() *TenantMigrationVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantMigrationVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UsageHour) DeepCopyInto(out *UsageHour) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	if in.Applications != nil {
+		in, out := &in.Applications, &out.Applications
+		*out = make([]ApplicationUsage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UsageHour.
+func (in *UsageHour) DeepCopy() *UsageHour {
+	if in == nil {
+		return nil
+	}
+	out := new(UsageHour)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UsageRollup) DeepCopyInto(out *UsageRollup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UsageRollup.
+func (in *UsageRollup) DeepCopy() *UsageRollup {
+	if in == nil {
+		return nil
+	}
+	out := new(UsageRollup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UsageRollup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UsageRollupList) DeepCopyInto(out *UsageRollupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]UsageRollup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UsageRollupList.
+func (in *UsageRollupList) DeepCopy() *UsageRollupList {
+	if in == nil {
+		return nil
+	}
+	out := new(UsageRollupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UsageRollupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UsageRollupStatus) DeepCopyInto(out *UsageRollupStatus) {
+	*out = *in
+	if in.Hours != nil {
+		in, out := &in.Hours, &out.Hours
+		*out = make([]UsageHour, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastSampleTime != nil {
+		in, out := &in.LastSampleTime, &out.LastSampleTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UsageRollupStatus.
+func (in *UsageRollupStatus) DeepCopy() *UsageRollupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UsageRollupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Variant) DeepCopyInto(out *Variant) {
 	*out = *in
@@ -630,6 +1716,13 @@ func (in *Variant) DeepCopyInto(out *Variant) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Constraints != nil {
+		in, out := &in.Constraints, &out.Constraints
+		*out = make([]DependencyConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Libraries != nil {
 		in, out := &in.Libraries, &out.Libraries
 		*out = make([]Library, len(*in))
@@ -642,6 +1735,11 @@ func (in *Variant) DeepCopyInto(out *Variant) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Verify != nil {
+		in, out := &in.Verify, &out.Verify
+		*out = new(ArtifactVerification)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Variant.
@@ -654,6 +1752,21 @@ func (in *Variant) DeepCopy() *Variant {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VariantVerification) DeepCopyInto(out *VariantVerification) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VariantVerification.
+func (in *VariantVerification) DeepCopy() *VariantVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(VariantVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Workload) DeepCopyInto(out *Workload) {
 	*out = *in
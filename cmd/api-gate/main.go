@@ -21,18 +21,42 @@ limitations under the License.
 //
 // Usage:
 //
-//	api-gate --base <dir> --head <dir>
+//	api-gate --base <dir> --head <dir> [--format markdown|text|json|sarif] [--policy <file>]
+//	api-gate --since <tag> [--head <dir>] [--format markdown|text|json|sarif]
+//	api-gate --instances <file|dir>[,...] [--head <dir>] [--format markdown|text|json|sarif]
 //
 // Both flags point at a full repository checkout (the merge base and the PR
 // head). Exit code 0 means "not sizeable"; exit code 2 means "sizeable"
-// (findings are printed to stdout as a Markdown report); any other non-zero
-// exit is an operational error.
+// (findings are printed to stdout as a report in the chosen format); any other
+// non-zero exit is an operational error.
+//
+// A removal announced in the deprecation policy (api/deprecations.yaml) whose
+// removal date has been reached is reported as a planned removal and is not
+// sizeable. The policy is read from the base checkout unless --policy is
+// given, so the announcement has to be merged before the removal: a PR cannot
+// approve its own removal by adding a policy entry.
+//
+// With --since, api-gate instead prints the API changes between the release
+// tagged <tag> in the --head repository (default: the current directory) and
+// that checkout, including added served versions and newly announced
+// deprecations, for the upgrade notes. It exits 0 whatever the changes are.
+//
+// With --instances, api-gate validates live Applications exported from a
+// cluster against the ApplicationDefinition schemas of the --head checkout
+// (default: the current directory) and reports the instances that would fail
+// to update after upgrading to it, exiting 2 when there are any. Values the
+// head schemas no longer declare are reported too, without failing. Export
+// the instances through the aggregated API, e.g.:
+//
+//	kubectl get $(kubectl api-resources --api-group=apps.cozystack.io -o name | paste -sd, -) -A -o yaml > apps.yaml
 package main
 
 import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/cozystack/cozystack/internal/apigate"
 )
@@ -44,9 +68,45 @@ const exitSizeable = 2
 func main() {
 	baseDir := flag.String("base", "", "path to the base (merge-base) repository checkout")
 	headDir := flag.String("head", "", "path to the head (PR) repository checkout")
-	format := flag.String("format", "markdown", "report format: markdown or text")
+	format := flag.String("format", "markdown", "report format: "+strings.Join(apigate.Formats, ", "))
+	policyFile := flag.String("policy", "", "deprecation policy file (default: "+apigate.PolicyFile+" in the base checkout)")
+	since := flag.String("since", "", "print the API changes since this git tag or revision of the head repository instead of gating")
+	instances := flag.String("instances", "", "comma-separated files or directories of exported Applications to validate against the head schemas instead of gating")
 	flag.Parse()
 
+	if *instances != "" {
+		if *baseDir != "" || *policyFile != "" || *since != "" {
+			fail("--instances cannot be combined with --base, --policy or --since")
+		}
+		if *headDir == "" {
+			*headDir = "."
+		}
+		report, incompatible, err := validateInstances(strings.Split(*instances, ","), *headDir, *format)
+		if err != nil {
+			fail("%v", err)
+		}
+		fmt.Print(report)
+		if incompatible {
+			os.Exit(exitSizeable)
+		}
+		return
+	}
+
+	if *since != "" {
+		if *baseDir != "" || *policyFile != "" {
+			fail("--since cannot be combined with --base or --policy")
+		}
+		if *headDir == "" {
+			*headDir = "."
+		}
+		report, err := changelog(*since, *headDir, *format)
+		if err != nil {
+			fail("%v", err)
+		}
+		fmt.Print(report)
+		return
+	}
+
 	if *baseDir == "" || *headDir == "" {
 		fmt.Fprintln(os.Stderr, "api-gate: both --base and --head are required")
 		flag.Usage()
@@ -55,21 +115,88 @@ func main() {
 
 	base, err := apigate.LoadSnapshot(*baseDir)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "api-gate: loading base snapshot: %v\n", err)
-		os.Exit(1)
+		fail("loading base snapshot: %v", err)
 	}
 	head, err := apigate.LoadSnapshot(*headDir)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "api-gate: loading head snapshot: %v\n", err)
-		os.Exit(1)
+		fail("loading head snapshot: %v", err)
+	}
+	var policy apigate.Policy
+	if *policyFile != "" {
+		data, err := os.ReadFile(*policyFile)
+		if err != nil {
+			fail("reading deprecation policy: %v", err)
+		}
+		if policy, err = apigate.ParsePolicy(data); err != nil {
+			fail("%s: %v", *policyFile, err)
+		}
+	} else if policy, err = apigate.LoadPolicy(*baseDir); err != nil {
+		fail("loading base deprecation policy: %v", err)
 	}
 
-	findings := apigate.Classify(base, head)
-	if len(findings) == 0 {
-		fmt.Println("No sizeable API changes detected.")
-		return
+	findings := apigate.ApplyPolicy(apigate.Classify(base, head), policy, time.Now())
+	report, err := apigate.Report(findings, *format)
+	if err != nil {
+		fail("%v", err)
+	}
+	fmt.Print(report)
+	if apigate.Sizeable(findings) {
+		os.Exit(exitSizeable)
+	}
+}
+
+// changelog renders the API changes between the since revision of the repo
+// at headDir and its checkout.
+func changelog(since, headDir, format string) (string, error) {
+	baseDir, err := os.MkdirTemp("", "api-gate-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(baseDir)
+	if err := apigate.ExtractRevision(headDir, since, baseDir); err != nil {
+		return "", err
+	}
+
+	base, err := apigate.LoadSnapshot(baseDir)
+	if err != nil {
+		return "", fmt.Errorf("loading %s snapshot: %w", since, err)
 	}
+	basePolicy, err := apigate.LoadPolicy(baseDir)
+	if err != nil {
+		return "", fmt.Errorf("loading %s deprecation policy: %w", since, err)
+	}
+	head, err := apigate.LoadSnapshot(headDir)
+	if err != nil {
+		return "", fmt.Errorf("loading head snapshot: %w", err)
+	}
+	headPolicy, err := apigate.LoadPolicy(headDir)
+	if err != nil {
+		return "", fmt.Errorf("loading head deprecation policy: %w", err)
+	}
+	return apigate.ChangelogReport(apigate.Changelog(base, head, basePolicy, headPolicy, time.Now()), since, format)
+}
+
+// validateInstances renders the check of the Applications exported to paths
+// against the schemas of the checkout at headDir, and whether any of them
+// is incompatible.
+func validateInstances(paths []string, headDir, format string) (string, bool, error) {
+	instances, err := apigate.LoadInstances(paths)
+	if err != nil {
+		return "", false, fmt.Errorf("loading instances: %w", err)
+	}
+	if len(instances) == 0 {
+		return "", false, fmt.Errorf("no apps.cozystack.io objects found in %s", strings.Join(paths, ", "))
+	}
+	head, err := apigate.LoadSnapshot(headDir)
+	if err != nil {
+		return "", false, fmt.Errorf("loading head snapshot: %w", err)
+	}
+	findings := apigate.ValidateInstances(head, instances)
+	report, err := apigate.InstancesReport(findings, len(instances), format)
+	return report, apigate.Sizeable(findings), err
+}
 
-	fmt.Print(apigate.Report(findings, *format))
-	os.Exit(exitSizeable)
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "api-gate: "+format+"\n", args...)
+	os.Exit(1)
 }
@@ -0,0 +1,212 @@
+/*
+Copyright 2025 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// listPageSize bounds every LIST so a large cluster is fetched in pages
+// instead of one response the apiserver has to buffer whole.
+const listPageSize = 500
+
+// requestTimeout bounds a single apiserver request.
+const requestTimeout = 60 * time.Second
+
+// Client-side rate limits. client-go's defaults (5 QPS) throttle discovery
+// plus a dozen LISTs for seconds; the sequential fetch already keeps the
+// load down, like kubectl, which does not throttle that hard either.
+const (
+	clientQPS   = 50
+	clientBurst = 100
+)
+
+// cluster is the apiserver check-readiness reads from.
+type cluster struct {
+	discovery discovery.DiscoveryInterface
+	dynamic   dynamic.Interface
+
+	// served maps "resource.group" (just "resource" for the core group) to
+	// the preferred version, filled in once by the first discovery. nil
+	// when discovery failed: every kind is then assumed to be installed at
+	// its catalog version.
+	served     map[string]string
+	discovered bool
+	mu         sync.Mutex
+}
+
+func newCluster() (*cluster, error) {
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{})
+	rc, err := loader.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	rc.Timeout = requestTimeout
+	rc.QPS, rc.Burst = clientQPS, clientBurst
+	rc.UserAgent = "check-readiness/" + Version
+	disc, err := discovery.NewDiscoveryClientForConfig(rc)
+	if err != nil {
+		return nil, err
+	}
+	dyn, err := dynamic.NewForConfig(rc)
+	if err != nil {
+		return nil, err
+	}
+	return &cluster{discovery: disc, dynamic: dyn}, nil
+}
+
+// discover fills served on the first call. Groups that fail discovery are
+// missing from the result, the rest is still used; when discovery fails as
+// a whole served stays nil.
+func (c *cluster) discover() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.discovered {
+		return
+	}
+	c.discovered = true
+	lists, err := c.discovery.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return
+	}
+	if err != nil && len(lists) == 0 {
+		return
+	}
+	c.served = map[string]string{}
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, res := range list.APIResources {
+			if strings.Contains(res.Name, "/") {
+				continue
+			}
+			key := res.Name
+			if gv.Group != "" {
+				key += "." + gv.Group
+			}
+			c.served[key] = gv.Version
+		}
+	}
+}
+
+// installed reports whether the apiserver serves e.
+func (c *cluster) installed(e entry) bool {
+	c.discover()
+	if c.served == nil {
+		return true
+	}
+	_, ok := c.served[e.kind]
+	return ok
+}
+
+// resourceFor returns the resource to list for e.
+func (c *cluster) resourceFor(e entry) schema.GroupVersionResource {
+	name, group, _ := strings.Cut(e.kind, ".")
+	version := e.version
+	if v, ok := c.served[e.kind]; ok {
+		version = v
+	}
+	return schema.GroupVersionResource{Group: group, Version: version, Resource: name}
+}
+
+// list returns every object of e in scope, following continue tokens. A
+// kind the apiserver does not know is reported as empty, which is what
+// happens when discovery was unavailable and its CRD is not installed.
+func (cfg *config) list(e entry) ([]unstructured.Unstructured, error) {
+	nri := cfg.cluster.dynamic.Resource(cfg.cluster.resourceFor(e))
+	var ri dynamic.ResourceInterface = nri
+	if e.scope == "namespaced" && cfg.namespace != "" {
+		ri = nri.Namespace(cfg.namespace)
+	}
+	var items []unstructured.Unstructured
+	opts := metav1.ListOptions{LabelSelector: cfg.selector, Limit: listPageSize}
+	for {
+		page, err := ri.List(context.Background(), opts)
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		if page.GetContinue() == "" {
+			return items, nil
+		}
+		opts.Continue = page.GetContinue()
+	}
+}
+
+// runOnce fetches every kind of the catalog and classifies what it finds.
+func (cfg *config) runOnce() *report {
+	entries := cfg.entries()
+	rep := &report{kinds: make([]kindResult, len(entries))}
+	items := make([][]unstructured.Unstructured, len(entries))
+
+	fetch := func(idx int) {
+		k := &rep.kinds[idx]
+		items[idx], k.err = cfg.list(k.entry)
+	}
+	for idx, e := range entries {
+		rep.kinds[idx] = kindResult{entry: e, installed: cfg.cluster.installed(e)}
+	}
+
+	// Phase 1: fetch.
+	if cfg.parallel {
+		var wg sync.WaitGroup
+		for idx := range entries {
+			if !rep.kinds[idx].installed {
+				continue
+			}
+			wg.Add(1)
+			go func(idx int) {
+				defer wg.Done()
+				fetch(idx)
+			}(idx)
+		}
+		wg.Wait()
+	} else {
+		for idx := range entries {
+			if rep.kinds[idx].installed {
+				fetch(idx)
+			}
+		}
+	}
+
+	// Phase 2: classify, then resolve dependencies across the whole report.
+	for idx := range rep.kinds {
+		k := &rep.kinds[idx]
+		for i := range items[idx] {
+			k.resources = append(k.resources, classify(k.entry, &items[idx][i]))
+		}
+	}
+	rep.resolveDependencies(cfg.namespace, cfg.selector)
+	return rep
+}
@@ -15,18 +15,16 @@ limitations under the License.
 */
 
 // Command check-readiness checks readiness of cozystack / Flux / Kubernetes
-// resources. It shells out to kubectl (resolved from PATH) and reports only
-// non-ready resources, parsed from status conditions rather than column
-// heuristics.
+// resources. It talks to the apiserver with client-go, finds the served kinds
+// through discovery and reports only non-ready resources, parsed from status
+// conditions rather than column heuristics.
 package main
 
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -43,6 +41,8 @@ the apiserver is already under heavy reconciler load, so we avoid bursts of N
 concurrent LIST requests. Use --parallel for one-shot interactive runs against a
 healthy cluster (3-4x faster wall-clock).
 
+Connects using $KUBECONFIG, ~/.kube/config or the in-cluster service account.
+
 Usage:
   check-readiness [OPTIONS]
 
@@ -53,53 +53,73 @@ Options:
       --parallel            Fire all fetches concurrently (faster, more apiserver load)
       --core                Check only essential cozystack/Flux kinds (5 instead of 14)
   -v, --verbose             Show condition reason/message for not-ready rows
+      --deps                Show the not-ready dependencies each row is waiting for
+  -o, --output FORMAT       Output format: text, json or junit (default: text)
   -n, --namespace NS        Scope to a single namespace (cluster-scoped kinds ignore this)
-  -l, --selector SELECTOR   kubectl label selector to apply to every query
+  -l, --selector SELECTOR   Label selector to apply to every query
       --no-color            Disable color output (auto-disabled on non-TTY)
   -h, --help                Show this help
 
+Exit status:
+  0  Everything is ready
+  1  Some resources are not ready yet (or --wait timed out)
+  2  Usage error
+  3  Some resources failed permanently (Stalled, PVC Lost, invalid Package);
+     --wait stops waiting as soon as one does
+
 `
 
+// Exit codes.
+const (
+	exitReady    = 0
+	exitNotReady = 1
+	exitUsage    = 2
+	exitFailed   = 3
+)
+
 // entry is a resource catalog item.
 type entry struct {
 	kind            string
 	scope           string // "namespaced" | "cluster"
 	condType        string // condition .type, or "_phase" for PVCs
 	supportsSuspend bool
+	version         string // version to list when discovery is unavailable
 }
 
 var coreKinds = []entry{
-	{"packages.cozystack.io", "cluster", "Ready", false},
-	{"artifactgenerators.source.extensions.fluxcd.io", "namespaced", "Ready", true},
-	{"externalartifacts.source.toolkit.fluxcd.io", "namespaced", "Ready", false},
-	{"helmreleases.helm.toolkit.fluxcd.io", "namespaced", "Ready", true},
-	{"kustomizations.kustomize.toolkit.fluxcd.io", "namespaced", "Ready", true},
+	{"packages.cozystack.io", "cluster", "Ready", false, "v1alpha1"},
+	{"artifactgenerators.source.extensions.fluxcd.io", "namespaced", "Ready", true, "v1beta1"},
+	{"externalartifacts.source.toolkit.fluxcd.io", "namespaced", "Ready", false, "v1"},
+	{"helmreleases.helm.toolkit.fluxcd.io", "namespaced", "Ready", true, "v2"},
+	{"kustomizations.kustomize.toolkit.fluxcd.io", "namespaced", "Ready", true, "v1"},
 }
 
 var fluxExtraKinds = []entry{
-	{"gitrepositories.source.toolkit.fluxcd.io", "namespaced", "Ready", true},
-	{"ocirepositories.source.toolkit.fluxcd.io", "namespaced", "Ready", true},
-	{"helmrepositories.source.toolkit.fluxcd.io", "namespaced", "Ready", true},
-	{"helmcharts.source.toolkit.fluxcd.io", "namespaced", "Ready", true},
-	{"buckets.source.toolkit.fluxcd.io", "namespaced", "Ready", true},
+	{"gitrepositories.source.toolkit.fluxcd.io", "namespaced", "Ready", true, "v1"},
+	{"ocirepositories.source.toolkit.fluxcd.io", "namespaced", "Ready", true, "v1"},
+	{"helmrepositories.source.toolkit.fluxcd.io", "namespaced", "Ready", true, "v1"},
+	{"helmcharts.source.toolkit.fluxcd.io", "namespaced", "Ready", true, "v1"},
+	{"buckets.source.toolkit.fluxcd.io", "namespaced", "Ready", true, "v1"},
 }
 
 var clusterKinds = []entry{
-	{"nodes", "cluster", "Ready", false},
-	{"apiservices.apiregistration.k8s.io", "cluster", "Available", false},
-	{"customresourcedefinitions.apiextensions.k8s.io", "cluster", "Established", false},
+	{"nodes", "cluster", "Ready", false, "v1"},
+	{"apiservices.apiregistration.k8s.io", "cluster", "Available", false, "v1"},
+	{"customresourcedefinitions.apiextensions.k8s.io", "cluster", "Established", false, "v1"},
 }
 
-var pvcKind = entry{"persistentvolumeclaims", "namespaced", "_phase", false}
+var pvcKind = entry{"persistentvolumeclaims", "namespaced", "_phase", false, "v1"}
 
 type config struct {
-	kubectl []string
+	cluster *cluster
 
 	watch     bool
 	interval  int
 	waitMode  bool
 	timeoutS  int
 	verbose   bool
+	deps      bool
+	output    string
 	namespace string
 	selector  string
 	useColor  bool
@@ -108,12 +128,6 @@ type config struct {
 
 	// colors
 	red, green, yellow, cyan, dim, bold, reset string
-
-	// cached api-resources
-	apiCache  string
-	apiCached bool
-
-	foundIssues bool
 }
 
 func main() {
@@ -122,14 +136,11 @@ func main() {
 
 func run(args []string) int {
 	cfg := &config{
-		kubectl:  []string{"kubectl"},
 		interval: 10,
 		timeoutS: 0,
+		output:   "text",
 		useColor: true,
 	}
-	if kc := os.Getenv("KUBECONFIG"); kc != "" {
-		cfg.kubectl = append(cfg.kubectl, "--kubeconfig="+kc)
-	}
 
 	timeoutRaw := "30m"
 
@@ -150,24 +161,34 @@ func run(args []string) int {
 		case "--timeout":
 			if i+1 >= len(args) {
 				fmt.Fprintln(os.Stderr, "--timeout requires a value")
-				return 2
+				return exitUsage
 			}
 			timeoutRaw = args[i+1]
 			i += 2
 		case "-v", "--verbose":
 			cfg.verbose = true
 			i++
+		case "--deps":
+			cfg.deps = true
+			i++
+		case "-o", "--output":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "--output requires a value")
+				return exitUsage
+			}
+			cfg.output = args[i+1]
+			i += 2
 		case "-n", "--namespace":
 			if i+1 >= len(args) {
 				fmt.Fprintln(os.Stderr, "--namespace requires a value")
-				return 2
+				return exitUsage
 			}
 			cfg.namespace = args[i+1]
 			i += 2
 		case "-l", "--selector":
 			if i+1 >= len(args) {
 				fmt.Fprintln(os.Stderr, "--selector requires a value")
-				return 2
+				return exitUsage
 			}
 			cfg.selector = args[i+1]
 			i += 2
@@ -182,14 +203,14 @@ func run(args []string) int {
 			i++
 		case "-h", "--help":
 			fmt.Fprint(os.Stdout, usageText)
-			return 0
+			return exitReady
 		case "--version":
 			fmt.Fprintln(os.Stdout, Version)
-			return 0
+			return exitReady
 		default:
 			fmt.Fprintf(os.Stderr, "Unknown argument: %s\n", a)
 			fmt.Fprint(os.Stderr, usageText)
-			return 2
+			return exitUsage
 		}
 	}
 
@@ -201,6 +222,9 @@ func run(args []string) int {
 	} else {
 		cfg.useColor = false
 	}
+	if cfg.output != "text" {
+		cfg.useColor = false
+	}
 
 	cfg.setColors()
 
@@ -208,23 +232,43 @@ func run(args []string) int {
 	sec, ok := parseTimeout(timeoutRaw)
 	if !ok {
 		fmt.Fprintf(os.Stderr, "Invalid --timeout value: %s (expected e.g. 30m, 1h, 600s)\n", timeoutRaw)
-		return 2
+		return exitUsage
 	}
 	cfg.timeoutS = sec
 
+	switch cfg.output {
+	case "text", "json", "junit":
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid --output value: %s (expected text, json or junit)\n", cfg.output)
+		return exitUsage
+	}
+	if cfg.watch && !cfg.waitMode && cfg.output != "text" {
+		fmt.Fprintf(os.Stderr, "--output %s cannot be combined with --watch\n", cfg.output)
+		return exitUsage
+	}
+
+	c, err := newCluster()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot load Kubernetes client configuration: %v\n", err)
+		return exitUsage
+	}
+	cfg.cluster = c
+
 	if cfg.waitMode {
 		return cfg.runWait()
 	}
 	if cfg.watch {
 		cfg.runWatch()
-		return 0
+		return exitReady
 	}
-	out := cfg.runOnce()
-	fmt.Print(out)
-	if cfg.foundIssues {
-		return 1
+	rep := cfg.runOnce()
+	out, err := cfg.render(rep)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot render %s output: %v\n", cfg.output, err)
+		return exitNotReady
 	}
-	return 0
+	fmt.Print(out)
+	return rep.exitCode()
 }
 
 func (cfg *config) setColors() {
@@ -281,20 +325,43 @@ func parseTimeout(raw string) (int, bool) {
 	return 0, false
 }
 
+// render formats rep in the configured output format.
+func (cfg *config) render(rep *report) (string, error) {
+	switch cfg.output {
+	case "json":
+		return rep.json()
+	case "junit":
+		return rep.junit()
+	}
+	return cfg.text(rep), nil
+}
+
 func (cfg *config) runWait() int {
 	start := time.Now()
 	for {
-		output := cfg.runOnce()
+		rep := cfg.runOnce()
 		elapsed := int(time.Since(start).Seconds())
-		if strings.Contains(output, "All resources are ready.") {
-			fmt.Print(output)
-			fmt.Printf("%sReady after %ds.%s\n", cfg.green, elapsed, cfg.reset)
-			return 0
+		code := rep.exitCode()
+		if code == exitReady || code == exitFailed || elapsed >= cfg.timeoutS {
+			out, err := cfg.render(rep)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Cannot render %s output: %v\n", cfg.output, err)
+				return exitNotReady
+			}
+			fmt.Print(out)
 		}
-		if elapsed >= cfg.timeoutS {
-			fmt.Print(output)
+		switch {
+		case code == exitReady:
+			if cfg.output == "text" {
+				fmt.Printf("%sReady after %ds.%s\n", cfg.green, elapsed, cfg.reset)
+			}
+			return exitReady
+		case code == exitFailed:
+			fmt.Fprintf(os.Stderr, "%s%sFailed after %ds — some resources will not become ready without intervention.%s\n", cfg.red, cfg.bold, elapsed, cfg.reset)
+			return exitFailed
+		case elapsed >= cfg.timeoutS:
 			fmt.Fprintf(os.Stderr, "%s%sTimeout after %ds — resources still not ready.%s\n", cfg.red, cfg.bold, cfg.timeoutS, cfg.reset)
-			return 1
+			return exitNotReady
 		}
 		time.Sleep(time.Duration(cfg.interval) * time.Second)
 	}
@@ -302,7 +369,7 @@ func (cfg *config) runWait() int {
 
 func (cfg *config) runWatch() {
 	for {
-		output := cfg.runOnce()
+		output := cfg.text(cfg.runOnce())
 		// Smooth clear is cosmetic and untested; just reprint.
 		fmt.Printf("%sLast updated: %s  (refreshing every %ds, Ctrl+C to stop)%s\n", cfg.bold, time.Now().Format(time.UnixDate), cfg.interval, cfg.reset)
 		fmt.Println()
@@ -311,101 +378,15 @@ func (cfg *config) runWatch() {
 	}
 }
 
-// buildScopeArgs mirrors build_kubectl_scope_args.
-func (cfg *config) buildScopeArgs(scope string) []string {
-	var out []string
-	if scope == "namespaced" {
-		if cfg.namespace != "" {
-			out = append(out, "-n", cfg.namespace)
-		} else {
-			out = append(out, "-A")
-		}
-	}
-	if cfg.selector != "" {
-		out = append(out, "-l", cfg.selector)
-	}
-	return out
-}
-
-func (cfg *config) ensureAPIResources() {
-	if cfg.apiCached {
-		return
-	}
-	args := append(append([]string{}, cfg.kubectl[1:]...), "api-resources", "--no-headers")
-	cmd := exec.Command(cfg.kubectl[0], args...)
-	out, err := cmd.Output()
-	if err != nil {
-		cfg.apiCache = ""
-	} else {
-		cfg.apiCache = string(out)
-	}
-	cfg.apiCached = true
-}
-
-// kindExists checks the cached api-resources output.
-func (cfg *config) kindExists(kind string) bool {
-	cfg.ensureAPIResources()
-	if cfg.apiCache == "" {
-		return true
-	}
-	name := kind
-	group := ""
-	if idx := strings.IndexByte(kind, '.'); idx >= 0 {
-		name = kind[:idx]
-		group = kind[idx+1:]
-	}
-	for _, line := range strings.Split(cfg.apiCache, "\n") {
-		fields := strings.Fields(line)
-		if len(fields) < 3 {
-			continue
-		}
-		if fields[0] != name {
-			continue
-		}
-		apiv := fields[len(fields)-3]
-		grp := ""
-		if parts := strings.SplitN(apiv, "/", 2); len(parts) == 2 {
-			grp = parts[0]
-		}
-		if group == "" || group == grp {
-			return true
-		}
-	}
-	return false
-}
-
-// fetchRows runs kubectl get and returns raw jsonpath output.
-func (cfg *config) fetchRows(e entry) string {
-	scopeArgs := cfg.buildScopeArgs(e.scope)
-
-	var jsonpath string
-	if e.condType == "_phase" {
-		jsonpath = `{range .items[*]}{.metadata.namespace}{"\x1f"}{.metadata.name}{"\x1f"}{.status.phase}{"\x1f"}{""}{"\x1f"}{""}{"\x1f"}{"false"}{"\n"}{end}`
-	} else {
-		suspendField := `{"false"}`
-		if e.supportsSuspend {
-			suspendField = `{.spec.suspend}`
-		}
-		var b strings.Builder
-		b.WriteString(`{range .items[*]}`)
-		b.WriteString(`{.metadata.namespace}{"\x1f"}`)
-		b.WriteString(`{.metadata.name}{"\x1f"}`)
-		b.WriteString(`{.status.conditions[?(@.type=="` + e.condType + `")].status}{"\x1f"}`)
-		b.WriteString(`{.status.conditions[?(@.type=="` + e.condType + `")].reason}{"\x1f"}`)
-		b.WriteString(`{.status.conditions[?(@.type=="` + e.condType + `")].message}{"\x1f"}`)
-		b.WriteString(suspendField + `{"\n"}`)
-		b.WriteString(`{end}`)
-		jsonpath = b.String()
+// entries returns the catalog to check, in display order.
+func (cfg *config) entries() []entry {
+	entries := append([]entry{}, coreKinds...)
+	if !cfg.coreOnly {
+		entries = append(entries, fluxExtraKinds...)
+		entries = append(entries, clusterKinds...)
+		entries = append(entries, pvcKind)
 	}
-
-	args := append([]string{}, cfg.kubectl[1:]...)
-	args = append(args, "get", e.kind)
-	args = append(args, scopeArgs...)
-	args = append(args, "-o", "jsonpath="+jsonpath)
-
-	cmd := exec.Command(cfg.kubectl[0], args...)
-	out, _ := cmd.Output() // ignore errors, like `|| true`
-	return string(out)
+	return entries
 }
 
 // formatExtra mirrors format_extra.
@@ -438,155 +419,89 @@ func cutChars(s string, n int) string {
 	return s[:n]
 }
 
-// processRows mirrors process_rows; appends rendered output to b.
-func (cfg *config) processRows(e entry, rows string, b *strings.Builder) {
-	if rows == "" {
-		return
+// label is the status column shown for r; empty when r is ready.
+func label(r *resource) string {
+	switch r.State {
+	case stateSuspended:
+		return "SUSPENDED"
+	case stateFailed:
+		return "FAILED"
+	case stateNotReady:
+		if r.Status == "" {
+			return "Unknown"
+		}
+		return r.Status
 	}
+	return ""
+}
+
+// processRows mirrors process_rows; appends rendered output to b.
+func (cfg *config) processRows(k *kindResult, b *strings.Builder) {
 	headerPrinted := false
 	notReady := 0
-	total := 0
 
-	for _, line := range strings.Split(rows, "\n") {
-		if line == "" {
+	for i := range k.resources {
+		r := &k.resources[i]
+		lbl := label(r)
+		if lbl == "" {
 			continue
 		}
-		fields := strings.Split(line, "\x1f")
-		// ns name status reason message suspended
-		var ns, name, status, reason, message, suspended string
-		if len(fields) > 0 {
-			ns = fields[0]
-		}
-		if len(fields) > 1 {
-			name = fields[1]
-		}
-		if len(fields) > 2 {
-			status = fields[2]
-		}
-		if len(fields) > 3 {
-			reason = fields[3]
-		}
-		if len(fields) > 4 {
-			message = fields[4]
-		}
-		if len(fields) > 5 {
-			suspended = fields[5]
-		}
-
-		if name == "" {
-			continue
-		}
-		total++
-
-		label := ""
-		color := ""
-		if suspended == "true" {
-			label = "SUSPENDED"
+		color := cfg.red
+		switch r.State {
+		case stateSuspended:
 			color = cfg.cyan
-		} else if e.condType == "_phase" {
-			if status != "Bound" {
-				label = status
-				color = cfg.red
-			}
-		} else {
-			if status != "True" {
-				if status == "" {
-					label = "Unknown"
-				} else {
-					label = status
-				}
-				color = cfg.red
-			}
-		}
-
-		if label == "" {
-			continue
+		case stateFailed:
+			color = cfg.red + cfg.bold
 		}
 
 		if !headerPrinted {
-			fmt.Fprintf(b, "%s%s=== %s (not ready) ===%s\n", cfg.bold, cfg.yellow, e.kind, cfg.reset)
+			fmt.Fprintf(b, "%s%s=== %s (not ready) ===%s\n", cfg.bold, cfg.yellow, k.kind, cfg.reset)
 			headerPrinted = true
 		}
 		notReady++
 
 		var prefix string
-		if ns != "" {
-			prefix = fmt.Sprintf("%-30s %-50s %s", ns, name, label)
+		if r.Namespace != "" {
+			prefix = fmt.Sprintf("%-30s %-50s %s", r.Namespace, r.Name, lbl)
 		} else {
-			prefix = fmt.Sprintf("%-50s %s", name, label)
+			prefix = fmt.Sprintf("%-50s %s", r.Name, lbl)
 		}
 		fmt.Fprintf(b, "%s%s%s\n", color, prefix, cfg.reset)
 
 		if cfg.verbose {
-			extra := formatExtra(reason, message)
+			extra := formatExtra(r.Reason, r.Message)
 			if extra != "" {
 				fmt.Fprintf(b, "  %s%s%s\n", cfg.dim, extra, cfg.reset)
 			}
 		}
-
-		if label != "SUSPENDED" {
-			cfg.foundIssues = true
+		if cfg.deps && r.State != stateSuspended && len(r.BlockedBy) > 0 {
+			fmt.Fprintf(b, "  %swaiting for: %s%s\n", cfg.dim, formatBlockers(r.BlockedBy), cfg.reset)
 		}
 	}
 
 	if headerPrinted && cfg.verbose {
-		fmt.Fprintf(b, "%s  -> %d/%d not ready%s\n", cfg.dim, notReady, total, cfg.reset)
+		fmt.Fprintf(b, "%s  -> %d/%d not ready%s\n", cfg.dim, notReady, len(k.resources), cfg.reset)
 	}
 }
 
-func (cfg *config) runOnce() string {
-	cfg.foundIssues = false
-	cfg.ensureAPIResources()
-
-	entries := append([]entry{}, coreKinds...)
-	if !cfg.coreOnly {
-		entries = append(entries, fluxExtraKinds...)
-		entries = append(entries, clusterKinds...)
-		entries = append(entries, pvcKind)
-	}
-
-	rowsByIdx := make([]string, len(entries))
-
-	// Phase 1: fetch.
-	if cfg.parallel {
-		var wg sync.WaitGroup
-		for idx := range entries {
-			e := entries[idx]
-			if !cfg.kindExists(e.kind) {
-				continue
-			}
-			wg.Add(1)
-			go func(idx int, e entry) {
-				defer wg.Done()
-				rowsByIdx[idx] = cfg.fetchRows(e)
-			}(idx, e)
-		}
-		wg.Wait()
-	} else {
-		for idx := range entries {
-			e := entries[idx]
-			if !cfg.kindExists(e.kind) {
-				continue
-			}
-			rowsByIdx[idx] = cfg.fetchRows(e)
-		}
-	}
-
+// text renders rep the way the human-readable output always looked.
+func (cfg *config) text(rep *report) string {
 	var b strings.Builder
-
-	// Phase 2: process in catalog order.
-	for idx := range entries {
-		e := entries[idx]
-		if !cfg.kindExists(e.kind) {
-			fmt.Fprintf(&b, "%s--- %s: CRD not installed, skipping%s\n", cfg.dim, e.kind, cfg.reset)
+	for i := range rep.kinds {
+		k := &rep.kinds[i]
+		if !k.installed {
+			fmt.Fprintf(&b, "%s--- %s: CRD not installed, skipping%s\n", cfg.dim, k.kind, cfg.reset)
+			continue
+		}
+		if k.err != nil {
+			fmt.Fprintf(&b, "%s%s=== %s (not ready) ===%s\n", cfg.bold, cfg.yellow, k.kind, cfg.reset)
+			fmt.Fprintf(&b, "%scannot list: %v%s\n", cfg.red, k.err, cfg.reset)
 			continue
 		}
-		cfg.processRows(e, rowsByIdx[idx], &b)
+		cfg.processRows(k, &b)
 	}
-
-	if !cfg.foundIssues {
+	if rep.exitCode() == exitReady {
 		fmt.Fprintf(&b, "%s%sAll resources are ready.%s\n", cfg.green, cfg.bold, cfg.reset)
 	}
-
 	return b.String()
 }
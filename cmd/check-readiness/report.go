@@ -0,0 +1,377 @@
+/*
+Copyright 2025 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Resource states.
+const (
+	stateReady     = "Ready"
+	stateNotReady  = "NotReady"
+	stateFailed    = "Failed"
+	stateSuspended = "Suspended"
+
+	// stateMissing is the state of a dependency that does not exist.
+	stateMissing = "Missing"
+)
+
+// failedPackageReasons are the Ready reasons of a Package the operator
+// cannot get past without someone changing the Package or its source.
+var failedPackageReasons = map[string]bool{
+	"VariantNotFound":      true,
+	"InvalidConfiguration": true,
+	"InvalidValues":        true,
+	"InvalidRollout":       true,
+	"DependencyConflict":   true,
+}
+
+// resource is one object of a checked kind.
+type resource struct {
+	Namespace string       `json:"namespace,omitempty"`
+	Name      string       `json:"name"`
+	State     string       `json:"state"`
+	Status    string       `json:"status,omitempty"` // condition status, or phase for PVCs
+	Reason    string       `json:"reason,omitempty"`
+	Message   string       `json:"message,omitempty"`
+	BlockedBy []dependency `json:"blockedBy,omitempty"`
+
+	dependsOn []dependency
+}
+
+// dependency is an object of the same kind a resource waits for.
+type dependency struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	State     string `json:"state"`
+}
+
+func (d dependency) key() string {
+	if d.Namespace == "" {
+		return d.Name
+	}
+	return d.Namespace + "/" + d.Name
+}
+
+// kindResult is what was found for one catalog entry.
+type kindResult struct {
+	entry
+	installed bool
+	err       error
+	resources []resource
+}
+
+// report is the result of one pass over the catalog.
+type report struct {
+	kinds []kindResult
+}
+
+// classify turns obj into a resource of kind e.
+func classify(e entry, obj *unstructured.Unstructured) resource {
+	r := resource{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+
+	if e.condType == "_phase" {
+		r.Status, _, _ = unstructured.NestedString(obj.Object, "status", "phase")
+		switch r.Status {
+		case "Bound":
+			r.State = stateReady
+		case "Lost":
+			r.State = stateFailed
+		default:
+			r.State = stateNotReady
+		}
+		return r
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	cond := findCondition(conditions, e.condType)
+	stalled := findCondition(conditions, "Stalled")
+	r.Status, r.Reason, r.Message = cond["status"], cond["reason"], cond["message"]
+	switch {
+	case r.Status == "True":
+		r.State = stateReady
+	case stalled["status"] == "True":
+		r.State = stateFailed
+		if cond == nil {
+			r.Reason, r.Message = stalled["reason"], stalled["message"]
+		}
+	case e.kind == "packages.cozystack.io" && failedPackageReasons[r.Reason]:
+		r.State = stateFailed
+	default:
+		r.State = stateNotReady
+	}
+	if e.supportsSuspend {
+		if suspended, _, _ := unstructured.NestedBool(obj.Object, "spec", "suspend"); suspended {
+			r.State = stateSuspended
+		}
+	}
+	r.dependsOn = dependsOn(e, obj)
+	return r
+}
+
+// findCondition returns the string fields of the condition of type condType,
+// nil when there is none.
+func findCondition(conditions []interface{}, condType string) map[string]string {
+	for _, c := range conditions {
+		m, ok := c.(map[string]interface{})
+		if !ok || m["type"] != condType {
+			continue
+		}
+		out := map[string]string{}
+		for _, field := range []string{"status", "reason", "message"} {
+			if v, ok := m[field].(string); ok {
+				out[field] = v
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// dependsOn lists the objects of the same kind obj waits for: spec.dependsOn
+// of Flux HelmReleases and Kustomizations, and the dependencies the operator
+// records in the status of a Package.
+func dependsOn(e entry, obj *unstructured.Unstructured) []dependency {
+	var deps []dependency
+	switch e.kind {
+	case "helmreleases.helm.toolkit.fluxcd.io", "kustomizations.kustomize.toolkit.fluxcd.io":
+		refs, _, _ := unstructured.NestedSlice(obj.Object, "spec", "dependsOn")
+		for _, ref := range refs {
+			m, ok := ref.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := m["name"].(string)
+			namespace, _ := m["namespace"].(string)
+			if namespace == "" {
+				namespace = obj.GetNamespace()
+			}
+			if name != "" {
+				deps = append(deps, dependency{Namespace: namespace, Name: name})
+			}
+		}
+	case "packages.cozystack.io":
+		status, _, _ := unstructured.NestedMap(obj.Object, "status", "dependencies")
+		for name := range status {
+			deps = append(deps, dependency{Name: name})
+		}
+		sort.Slice(deps, func(i, j int) bool { return deps[i].Name < deps[j].Name })
+	}
+	return deps
+}
+
+// resolveDependencies fills BlockedBy with the dependencies that are not
+// ready. A dependency that was not found is only reported missing when the
+// query could have returned it.
+func (rep *report) resolveDependencies(namespace, selector string) {
+	for ki := range rep.kinds {
+		k := &rep.kinds[ki]
+		byKey := map[string]*resource{}
+		for i := range k.resources {
+			r := &k.resources[i]
+			byKey[dependency{Namespace: r.Namespace, Name: r.Name}.key()] = r
+		}
+		for i := range k.resources {
+			r := &k.resources[i]
+			for _, dep := range r.dependsOn {
+				if found, ok := byKey[dep.key()]; ok {
+					if found.State == stateReady {
+						continue
+					}
+					dep.State = found.State
+				} else {
+					if selector != "" || namespace != "" && dep.Namespace != "" && dep.Namespace != namespace {
+						continue
+					}
+					dep.State = stateMissing
+				}
+				r.BlockedBy = append(r.BlockedBy, dep)
+			}
+		}
+	}
+}
+
+// exitCode is the process exit status rep calls for.
+func (rep *report) exitCode() int {
+	code := exitReady
+	for _, k := range rep.kinds {
+		if k.err != nil {
+			code = exitNotReady
+		}
+		for _, r := range k.resources {
+			switch r.State {
+			case stateFailed:
+				return exitFailed
+			case stateNotReady:
+				code = exitNotReady
+			}
+		}
+	}
+	return code
+}
+
+// formatBlockers renders the dependencies a resource is waiting for.
+func formatBlockers(deps []dependency) string {
+	parts := make([]string, 0, len(deps))
+	for _, d := range deps {
+		parts = append(parts, fmt.Sprintf("%s (%s)", d.key(), d.State))
+	}
+	return strings.Join(parts, ", ")
+}
+
+type jsonReport struct {
+	State string     `json:"state"`
+	Kinds []jsonKind `json:"kinds"`
+}
+
+type jsonKind struct {
+	Kind      string     `json:"kind"`
+	Installed bool       `json:"installed"`
+	Error     string     `json:"error,omitempty"`
+	Resources []resource `json:"resources"`
+}
+
+// json renders rep for machines: every resource found, ready or not.
+func (rep *report) json() (string, error) {
+	out := jsonReport{State: stateReady, Kinds: []jsonKind{}}
+	switch rep.exitCode() {
+	case exitFailed:
+		out.State = stateFailed
+	case exitNotReady:
+		out.State = stateNotReady
+	}
+	for _, k := range rep.kinds {
+		jk := jsonKind{Kind: k.kind, Installed: k.installed, Resources: k.resources}
+		if jk.Resources == nil {
+			jk.Resources = []resource{}
+		}
+		if k.err != nil {
+			jk.Error = k.err.Error()
+		}
+		out.Kinds = append(out.Kinds, jk)
+	}
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b) + "\n", nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// junit renders rep as a JUnit report: a suite per kind and a test case per
+// resource. Not-ready resources are failures, permanently failed ones carry
+// the Failed type, suspended resources and missing kinds are skipped.
+func (rep *report) junit() (string, error) {
+	out := junitTestSuites{Name: "check-readiness"}
+	for _, k := range rep.kinds {
+		suite := junitTestSuite{Name: k.kind}
+		switch {
+		case !k.installed:
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name: k.kind, ClassName: k.kind,
+				Skipped: &junitSkipped{Message: "CRD not installed"},
+			})
+		case k.err != nil:
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name: k.kind, ClassName: k.kind,
+				Error: &junitProblem{Message: "cannot list", Type: "ListError", Text: k.err.Error()},
+			})
+		}
+		for _, r := range k.resources {
+			tc := junitTestCase{Name: dependency{Namespace: r.Namespace, Name: r.Name}.key(), ClassName: k.kind}
+			switch r.State {
+			case stateSuspended:
+				tc.Skipped = &junitSkipped{Message: "suspended"}
+			case stateNotReady, stateFailed:
+				text := formatExtra(r.Reason, r.Message)
+				if len(r.BlockedBy) > 0 {
+					text = strings.TrimPrefix(text+"\nwaiting for: "+formatBlockers(r.BlockedBy), "\n")
+				}
+				status := r.Status
+				if status == "" {
+					status = "Unknown"
+				}
+				tc.Failure = &junitProblem{Message: status, Type: r.State, Text: text}
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		for _, tc := range suite.Cases {
+			suite.Tests++
+			switch {
+			case tc.Failure != nil:
+				suite.Failures++
+			case tc.Error != nil:
+				suite.Errors++
+			case tc.Skipped != nil:
+				suite.Skipped++
+			}
+		}
+		out.Tests += suite.Tests
+		out.Failures += suite.Failures
+		out.Errors += suite.Errors
+		out.Skipped += suite.Skipped
+		out.Suites = append(out.Suites, suite)
+	}
+	b, err := xml.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(b) + "\n", nil
+}
@@ -0,0 +1,366 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	"github.com/cozystack/cozystack/internal/bundle"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+var bundleExportCmdFlags struct {
+	files         []string
+	sourceDir     string
+	output        string
+	images        []string
+	excludeImages []string
+	kubeconfig    string
+}
+
+var bundleImportCmdFlags struct {
+	registry   string
+	prefix     string
+	plainHTTP  bool
+	username   string
+	password   string
+	dryRun     bool
+	kubeconfig string
+}
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Move PackageSources and their images into an air-gapped cluster",
+	Long: `Move PackageSources and their images into an air-gapped cluster.
+
+"cozypkg bundle export" packs the charts, libraries and values files of
+PackageSources, and every container image their values files reference, into a
+single tarball holding an OCI image layout and a checksum manifest.
+"cozypkg bundle import" verifies the tarball, pushes its content into a local
+registry and points the PackageSources at it.`,
+}
+
+var bundleExportCmd = &cobra.Command{
+	Use:   "export [packagesource]...",
+	Short: "Write PackageSources and the images they use to a bundle tarball",
+	Long: `Write PackageSources and the images they use to a bundle tarball.
+
+PackageSources are read from -f files or directories and, by name, from the
+cluster; with neither, every PackageSource in the cluster is exported. Their
+files are taken from --source-dir, a local copy of what their sourceRef points
+at: the root of the Git checkout for a GitRepository, or of the unpacked
+artifact for an OCIRepository. Images are pulled with the credentials in the
+Docker config file (DOCKER_CONFIG or ~/.docker/config.json), anonymously for
+registries it has none for.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		sources, err := exportSources(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		out, err := os.Create(bundleExportCmdFlags.output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", bundleExportCmdFlags.output, err)
+		}
+		defer out.Close()
+		creds := dockerCredentials()
+		m, err := bundle.Export(ctx, out, bundle.ExportOptions{
+			SourceDir:      bundleExportCmdFlags.sourceDir,
+			PackageSources: sources,
+			Images:         bundleExportCmdFlags.images,
+			ExcludeImages:  bundleExportCmdFlags.excludeImages,
+			Registry: func(host string) *bundle.Registry {
+				reg := bundle.NewRegistry(host)
+				reg.Username, reg.Password = creds(host)
+				return reg
+			},
+			Log: os.Stderr,
+		})
+		if err != nil {
+			_ = os.Remove(bundleExportCmdFlags.output)
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "✓ Wrote %s: %d PackageSource(s), %d image(s)\n", bundleExportCmdFlags.output, len(m.PackageSources), len(m.Images))
+		return nil
+	},
+}
+
+var bundleImportCmd = &cobra.Command{
+	Use:   "import <bundle>",
+	Short: "Push a bundle into a registry and point its PackageSources at it",
+	Long: `Push a bundle into a registry and point its PackageSources at it.
+
+The bundle is verified against its checksum manifest before anything is pushed.
+Images keep their repository path under --registry, so it can be configured as
+a mirror of their original registries on the nodes. Each PackageSource gets an
+OCIRepository in cozy-system pinned to its artifact's digest and is annotated
+with that digest; the PackageSource controller refuses to build from a source
+serving any other artifact. PackageSources missing from the cluster are created
+from the bundle. With --dry-run the OCIRepositories and PackageSources are
+printed instead of written.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		dir, err := os.MkdirTemp("", "cozypkg-bundle-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		b, err := bundle.Open(f, dir)
+		if err != nil {
+			return fmt.Errorf("failed to verify %s: %w", args[0], err)
+		}
+		fmt.Fprintf(os.Stderr, "✓ Verified %s: %d PackageSource(s), %d image(s)\n", args[0], len(b.Manifest.PackageSources), len(b.Manifest.Images))
+
+		reg := &bundle.Registry{
+			Host:      bundleImportCmdFlags.registry,
+			PlainHTTP: bundleImportCmdFlags.plainHTTP,
+			Username:  bundleImportCmdFlags.username,
+			Password:  bundleImportCmdFlags.password,
+		}
+		if reg.Username == "" {
+			reg.Username, reg.Password = dockerCredentials()(reg.Host)
+		}
+		pushed, images, err := b.Push(ctx, reg, bundleImportCmdFlags.prefix, os.Stderr)
+		if err != nil {
+			return err
+		}
+
+		var k8sClient client.Client
+		if !bundleImportCmdFlags.dryRun {
+			if k8sClient, _, err = newPlanClient(bundleImportCmdFlags.kubeconfig); err != nil {
+				return err
+			}
+		}
+		for i, src := range pushed {
+			if err := importSource(ctx, k8sClient, b.Manifest.PackageSources[i].PackageSource, src); err != nil {
+				return err
+			}
+		}
+
+		registries := map[string]bool{}
+		for _, image := range images {
+			if ref, err := bundle.ParseReference(image.Reference); err == nil {
+				registries[ref.Registry] = true
+			}
+		}
+		if len(registries) > 0 {
+			mirror := reg.Host
+			if bundleImportCmdFlags.prefix != "" {
+				mirror += "/" + bundleImportCmdFlags.prefix
+			}
+			fmt.Fprintf(os.Stderr, "Configure %s as the registry mirror of: %s\n", mirror, strings.Join(sortedNames(registries), ", "))
+		}
+		return nil
+	},
+}
+
+// exportSources reads the PackageSources to export from -f files and, by
+// name or all of them, from the cluster.
+func exportSources(ctx context.Context, args []string) ([]*cozyv1alpha1.PackageSource, error) {
+	sources := map[string]*cozyv1alpha1.PackageSource{}
+	for _, path := range bundleExportCmdFlags.files {
+		if err := readManifests(path, sources, map[string]*cozyv1alpha1.Package{}); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+	if len(args) > 0 || len(bundleExportCmdFlags.files) == 0 {
+		k8sClient, _, err := newPlanClient(bundleExportCmdFlags.kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 0 {
+			list := &cozyv1alpha1.PackageSourceList{}
+			if err := k8sClient.List(ctx, list); err != nil {
+				return nil, fmt.Errorf("failed to list PackageSources: %w", err)
+			}
+			for i := range list.Items {
+				sources[list.Items[i].Name] = &list.Items[i]
+			}
+		}
+		for _, name := range args {
+			ps := &cozyv1alpha1.PackageSource{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: name}, ps); err != nil {
+				return nil, fmt.Errorf("failed to get PackageSource %s: %w", name, err)
+			}
+			sources[name] = ps
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no PackageSources to export")
+	}
+	result := make([]*cozyv1alpha1.PackageSource, 0, len(sources))
+	for _, ps := range sources {
+		result = append(result, ps)
+	}
+	return result, nil
+}
+
+// importSource points the PackageSource at its pushed artifact, creating it
+// from the bundled copy when the cluster has none. With a nil client the
+// objects are printed instead.
+func importSource(ctx context.Context, k8sClient client.Client, bundled *cozyv1alpha1.PackageSource, src bundle.PushedSource) error {
+	current := &cozyv1alpha1.PackageSource{}
+	exists := false
+	if k8sClient != nil {
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: src.Name}, current)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get PackageSource %s: %w", src.Name, err)
+		}
+		exists = err == nil
+	}
+	if !exists {
+		current = bundled
+	}
+	ps, repo := bundle.RewriteSource(current, src, bundleImportCmdFlags.plainHTTP)
+
+	if k8sClient == nil {
+		for _, obj := range []interface{}{repo, ps} {
+			data, err := sigsyaml.Marshal(obj)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("---\n%s", data)
+		}
+		return nil
+	}
+
+	existing := &sourcev1.OCIRepository{}
+	err := k8sClient.Get(ctx, client.ObjectKeyFromObject(repo), existing)
+	switch {
+	case apierrors.IsNotFound(err):
+		err = k8sClient.Create(ctx, repo)
+	case err == nil:
+		existing.Spec = repo.Spec
+		if existing.Labels == nil {
+			existing.Labels = map[string]string{}
+		}
+		for k, v := range repo.Labels {
+			existing.Labels[k] = v
+		}
+		err = k8sClient.Update(ctx, existing)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write OCIRepository %s/%s: %w", repo.Namespace, repo.Name, err)
+	}
+	fmt.Fprintf(os.Stderr, "✓ Pinned OCIRepository %s/%s to %s\n", repo.Namespace, repo.Name, src.Digest)
+
+	if exists {
+		err = k8sClient.Update(ctx, ps)
+	} else {
+		err = k8sClient.Create(ctx, ps)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write PackageSource %s: %w", ps.Name, err)
+	}
+	fmt.Fprintf(os.Stderr, "✓ Pointed PackageSource %s at %s\n", ps.Name, src.URL)
+	return nil
+}
+
+// dockerCredentials returns a lookup of the username and password the
+// Docker config file holds for a registry host.
+func dockerCredentials() func(host string) (string, string) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".docker")
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "config.json")); err == nil {
+		_ = json.Unmarshal(data, &config)
+	}
+	return func(host string) (string, string) {
+		keys := []string{host, "https://" + host}
+		if host == "docker.io" || host == "registry-1.docker.io" {
+			keys = append(keys, "https://index.docker.io/v1/")
+		}
+		for _, key := range keys {
+			entry, ok := config.Auths[key]
+			if !ok {
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				continue
+			}
+			if user, pass, ok := strings.Cut(string(decoded), ":"); ok {
+				return user, pass
+			}
+		}
+		return "", ""
+	}
+}
+
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	rootCmd.AddCommand(bundleCmd)
+	bundleCmd.AddCommand(bundleExportCmd)
+	bundleCmd.AddCommand(bundleImportCmd)
+
+	bundleExportCmd.Flags().StringArrayVarP(&bundleExportCmdFlags.files, "file", "f", []string{}, "Read PackageSource manifests from file or directory (can be specified multiple times)")
+	bundleExportCmd.Flags().StringVar(&bundleExportCmdFlags.sourceDir, "source-dir", "", "Local copy of the source the PackageSources point at (required)")
+	bundleExportCmd.Flags().StringVarP(&bundleExportCmdFlags.output, "output", "o", "", "Bundle tarball to write (required)")
+	bundleExportCmd.Flags().StringArrayVar(&bundleExportCmdFlags.images, "image", []string{}, "Also export this image (can be specified multiple times)")
+	bundleExportCmd.Flags().StringArrayVar(&bundleExportCmdFlags.excludeImages, "exclude-image", []string{}, "Do not export images matching this glob pattern (can be specified multiple times)")
+	bundleExportCmd.Flags().StringVar(&bundleExportCmdFlags.kubeconfig, "kubeconfig", "", "Path to kubeconfig file (defaults to ~/.kube/config or KUBECONFIG env var)")
+	_ = bundleExportCmd.MarkFlagRequired("source-dir")
+	_ = bundleExportCmd.MarkFlagRequired("output")
+
+	bundleImportCmd.Flags().StringVar(&bundleImportCmdFlags.registry, "registry", "", "Registry host to push to, e.g. registry.local:5000 (required)")
+	bundleImportCmd.Flags().StringVar(&bundleImportCmdFlags.prefix, "prefix", "", "Repository path prefix to push under")
+	bundleImportCmd.Flags().BoolVar(&bundleImportCmdFlags.plainHTTP, "plain-http", false, "Talk HTTP to the registry; also marks the OCIRepositories insecure")
+	bundleImportCmd.Flags().StringVar(&bundleImportCmdFlags.username, "username", "", "Registry username (defaults to the Docker config file)")
+	bundleImportCmd.Flags().StringVar(&bundleImportCmdFlags.password, "password", "", "Registry password")
+	bundleImportCmd.Flags().BoolVar(&bundleImportCmdFlags.dryRun, "dry-run", false, "Push, but print the OCIRepositories and PackageSources instead of writing them")
+	bundleImportCmd.Flags().StringVar(&bundleImportCmdFlags.kubeconfig, "kubeconfig", "", "Path to kubeconfig file (defaults to ~/.kube/config or KUBECONFIG env var)")
+	_ = bundleImportCmd.MarkFlagRequired("registry")
+}
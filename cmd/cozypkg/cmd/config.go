@@ -0,0 +1,393 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	"github.com/cozystack/cozystack/internal/operator"
+	"github.com/spf13/cobra"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+var configCmdFlags struct {
+	kubeconfig string
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Get and set the component values of a Package",
+	Long: `Get and set the component values of a Package.
+
+A path names a component of the Package's variant followed by the keys of a
+value, separated by dots: "cilium.mtu" is the mtu value of the cilium
+component. Values are checked against the values schema the component
+publishes in its PackageSource, and paths and values are completed from it.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <package> [path]",
+	Short: "Print the values a Package sets, or one of them",
+	Long: `Print the values a Package sets for its components as YAML, or the value at path.
+
+A value the Package does not set is printed from the default of the
+component's values schema, when it has one.`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeConfigArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		k8sClient, _, err := newPlanClient(configCmdFlags.kubeconfig)
+		if err != nil {
+			return err
+		}
+		pkg, ps, variant, err := getPackageVariant(ctx, k8sClient, args[0])
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			values := map[string]interface{}{}
+			for name := range pkg.Spec.Components {
+				v, err := componentValues(pkg, name)
+				if err != nil {
+					return err
+				}
+				if len(v) > 0 {
+					values[name] = v
+				}
+			}
+			return printYAML(values)
+		}
+
+		component, keys, err := splitValuePath(variant, args[1])
+		if err != nil {
+			return err
+		}
+		values, err := componentValues(pkg, component.Name)
+		if err != nil {
+			return err
+		}
+		if value, ok := lookupValue(values, keys); ok {
+			return printYAML(value)
+		}
+		schema, err := operator.LoadComponentValuesSchema(ctx, k8sClient, ps, component)
+		if err != nil {
+			return err
+		}
+		if schema != nil {
+			if props := schema.Lookup(keys); props != nil && props.Default != nil {
+				var value interface{}
+				if err := json.Unmarshal(props.Default.Raw, &value); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "%s is not set, showing the schema default\n", args[1])
+				return printYAML(value)
+			}
+		}
+		return fmt.Errorf("%s is not set in Package %s", args[1], pkg.Name)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <package> <path> <value>",
+	Short: "Set a value of a Package",
+	Long: `Set the value at path in the values of a Package.
+
+The value is parsed as the type the component's values schema gives the path:
+as is for a string, as a number or boolean for those, and as YAML otherwise.
+The Package is written only if its values then match the schema.`,
+	Args:              cobra.ExactArgs(3),
+	ValidArgsFunction: completeConfigArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		k8sClient, _, err := newPlanClient(configCmdFlags.kubeconfig)
+		if err != nil {
+			return err
+		}
+		pkg, ps, variant, err := getPackageVariant(ctx, k8sClient, args[0])
+		if err != nil {
+			return err
+		}
+		component, keys, err := splitValuePath(variant, args[1])
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("path %s names a component, not one of its values", args[1])
+		}
+		schema, err := operator.LoadComponentValuesSchema(ctx, k8sClient, ps, component)
+		if err != nil {
+			return err
+		}
+		var props *apiextensionsv1.JSONSchemaProps
+		if schema != nil {
+			if props = schema.Lookup(keys); props == nil {
+				return fmt.Errorf("component %s has no value %s", component.Name, strings.Join(keys, "."))
+			}
+		}
+		value, err := parseValue(args[2], props)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", args[1], err)
+		}
+
+		values, err := componentValues(pkg, component.Name)
+		if err != nil {
+			return err
+		}
+		if values == nil {
+			values = map[string]interface{}{}
+		}
+		if err := setValue(values, keys, value); err != nil {
+			return fmt.Errorf("cannot set %s: %w", args[1], err)
+		}
+		raw, err := json.Marshal(values)
+		if err != nil {
+			return err
+		}
+		if pkg.Spec.Components == nil {
+			pkg.Spec.Components = map[string]cozyv1alpha1.PackageComponent{}
+		}
+		override := pkg.Spec.Components[component.Name]
+		override.Values = &apiextensionsv1.JSON{Raw: raw}
+		pkg.Spec.Components[component.Name] = override
+
+		if errs := operator.ValidatePackageValues(ctx, k8sClient, pkg, ps, variant); len(errs) > 0 {
+			return errs.ToAggregate()
+		}
+		if err := k8sClient.Update(ctx, pkg); err != nil {
+			return fmt.Errorf("failed to update Package %s: %w", pkg.Name, err)
+		}
+		fmt.Fprintf(os.Stderr, "✓ Set %s in Package %s\n", args[1], pkg.Name)
+		return nil
+	},
+}
+
+// getPackageVariant gets the Package name, its PackageSource and the
+// variant of it the Package installs.
+func getPackageVariant(ctx context.Context, k8sClient client.Client, name string) (*cozyv1alpha1.Package, *cozyv1alpha1.PackageSource, *cozyv1alpha1.Variant, error) {
+	pkg := &cozyv1alpha1.Package{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: name}, pkg); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get Package %s: %w", name, err)
+	}
+	ps := &cozyv1alpha1.PackageSource{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: name}, ps); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get PackageSource %s: %w", name, err)
+	}
+	variantName := variantOrDefault(pkg.Spec.Variant)
+	for i := range ps.Spec.Variants {
+		if ps.Spec.Variants[i].Name == variantName {
+			return pkg, ps, &ps.Spec.Variants[i], nil
+		}
+	}
+	return nil, nil, nil, fmt.Errorf("variant %s not found in PackageSource %s", variantName, name)
+}
+
+// splitValuePath splits path into the component of variant it starts with
+// and the keys of the value in the component's values.
+func splitValuePath(variant *cozyv1alpha1.Variant, path string) (*cozyv1alpha1.Component, []string, error) {
+	parts := strings.Split(path, ".")
+	for i := range variant.Components {
+		if variant.Components[i].Name == parts[0] {
+			for _, key := range parts[1:] {
+				if key == "" {
+					return nil, nil, fmt.Errorf("path %s has an empty key", path)
+				}
+			}
+			return &variant.Components[i], parts[1:], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("variant %s has no component %s", variant.Name, parts[0])
+}
+
+// componentValues decodes the values pkg sets for a component; nil when it
+// sets none.
+func componentValues(pkg *cozyv1alpha1.Package, component string) (map[string]interface{}, error) {
+	override, ok := pkg.Spec.Components[component]
+	if !ok || override.Values == nil || len(override.Values.Raw) == 0 {
+		return nil, nil
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(override.Values.Raw, &values); err != nil {
+		return nil, fmt.Errorf("values of component %s are not an object: %w", component, err)
+	}
+	return values, nil
+}
+
+func lookupValue(values map[string]interface{}, keys []string) (interface{}, bool) {
+	var value interface{} = values
+	for _, key := range keys {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func setValue(values map[string]interface{}, keys []string, value interface{}) error {
+	obj := values
+	for i, key := range keys[:len(keys)-1] {
+		next, ok := obj[key]
+		if !ok {
+			child := map[string]interface{}{}
+			obj[key] = child
+			obj = child
+			continue
+		}
+		if obj, ok = next.(map[string]interface{}); !ok {
+			return fmt.Errorf("%s is not an object", strings.Join(keys[:i+1], "."))
+		}
+	}
+	obj[keys[len(keys)-1]] = value
+	return nil
+}
+
+// parseValue parses s as the type props gives the value; as YAML when props
+// is nil or does not name a scalar type.
+func parseValue(s string, props *apiextensionsv1.JSONSchemaProps) (interface{}, error) {
+	if props != nil {
+		switch props.Type {
+		case "string":
+			return s, nil
+		case "integer":
+			return strconv.ParseInt(s, 10, 64)
+		case "number":
+			return strconv.ParseFloat(s, 64)
+		case "boolean":
+			return strconv.ParseBool(s)
+		}
+	}
+	var value interface{}
+	if err := sigsyaml.Unmarshal([]byte(s), &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func printYAML(value interface{}) error {
+	data, err := sigsyaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// completeConfigArgs completes Package names, then value paths from the
+// components' values schemas, then, for set, the values the schema allows.
+func completeConfigArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	ctx := context.Background()
+	k8sClient, _, err := newPlanClient(configCmdFlags.kubeconfig)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	if len(args) == 0 {
+		var packages cozyv1alpha1.PackageList
+		if err := k8sClient.List(ctx, &packages); err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		var names []string
+		for _, pkg := range packages.Items {
+			names = append(names, pkg.Name)
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	_, ps, variant, err := getPackageVariant(ctx, k8sClient, args[0])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	if len(args) == 1 {
+		prefix, _, nested := cutLast(toComplete, ".")
+		if !nested {
+			var names []string
+			for _, component := range variant.Components {
+				names = append(names, component.Name+".")
+			}
+			return names, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+		}
+		component, keys, err := splitValuePath(variant, prefix)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		schema, err := operator.LoadComponentValuesSchema(ctx, k8sClient, ps, component)
+		if err != nil || schema == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var paths []string
+		for _, key := range schema.Keys(keys) {
+			path := prefix + "." + key
+			if len(schema.Keys(append(keys[:len(keys):len(keys)], key))) > 0 {
+				path += "."
+			}
+			paths = append(paths, path)
+		}
+		return paths, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+
+	if len(args) == 2 && cmd.Name() == "set" {
+		component, keys, err := splitValuePath(variant, args[1])
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		schema, err := operator.LoadComponentValuesSchema(ctx, k8sClient, ps, component)
+		if err != nil || schema == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		props := schema.Lookup(keys)
+		if props == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var values []string
+		for _, v := range props.Enum {
+			values = append(values, strings.Trim(string(v.Raw), `"`))
+		}
+		if props.Type == "boolean" {
+			values = append(values, "true", "false")
+		}
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// cutLast cuts s around the last sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.PersistentFlags().StringVar(&configCmdFlags.kubeconfig, "kubeconfig", "", "Path to kubeconfig file (defaults to ~/.kube/config or KUBECONFIG env var)")
+}
@@ -20,9 +20,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	"github.com/cozystack/cozystack/internal/operator"
 	"github.com/emicklei/dot"
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -51,6 +53,10 @@ Pipe the output through the "dot" program (part of graphviz package) to render t
 
     cozypkg dot | dot -Tpng > graph.png
 
+Version and variant constraints label the package edges; a dotted edge is a
+constraint on a package that is not a dependency, and an orange edge is a
+constraint the package currently fails.
+
 By default, shows dependencies for all PackageSource resources.
 Use --installed to show only installed Package resources.
 Specify packages as arguments or use -f flag to read from files.`,
@@ -95,12 +101,12 @@ Specify packages as arguments or use -f flag to read from files.`,
 
 		// packagesOnly is inverse of components flag (if components=false, then packagesOnly=true)
 		packagesOnly := !dotCmdFlags.components
-		graph, allNodes, edgeVariants, packageNames, err := buildGraphFromCluster(ctx, dotCmdFlags.kubeconfig, packagesOnly, dotCmdFlags.installed, packageName, selectedPackages)
+		graph, allNodes, edgeVariants, edgeConstraints, packageNames, err := buildGraphFromCluster(ctx, dotCmdFlags.kubeconfig, packagesOnly, dotCmdFlags.installed, packageName, selectedPackages)
 		if err != nil {
 			return fmt.Errorf("error getting PackageSource dependencies: %w", err)
 		}
 
-		dotGraph := generateDOTGraph(graph, allNodes, packagesOnly, edgeVariants, packageNames)
+		dotGraph := generateDOTGraph(graph, allNodes, packagesOnly, edgeVariants, edgeConstraints, packageNames)
 		dotGraph.Write(os.Stdout)
 
 		return nil
@@ -124,9 +130,20 @@ func init() {
 	utilruntime.Must(cozyv1alpha1.AddToScheme(dependenciesScheme))
 }
 
+// constraintEdge describes the version and variant constraints behind a
+// package edge.
+type constraintEdge struct {
+	// labels are the distinct constraints, one per line of the edge label
+	labels []string
+	// dependency is set when the source also depends on the target
+	dependency bool
+	// unsatisfied is set when the target fails any of the constraints
+	unsatisfied bool
+}
+
 // buildGraphFromCluster builds a dependency graph from PackageSource resources in the cluster.
-// Returns: graph, allNodes, edgeVariants (map[edgeKey]variants), packageNames, error
-func buildGraphFromCluster(ctx context.Context, kubeconfig string, packagesOnly bool, installedOnly bool, packageName string, selectedPackages []string) (map[string][]string, map[string]bool, map[string][]string, map[string]bool, error) {
+// Returns: graph, allNodes, edgeVariants (map[edgeKey]variants), edgeConstraints (map[edgeKey]constraints), packageNames, error
+func buildGraphFromCluster(ctx context.Context, kubeconfig string, packagesOnly bool, installedOnly bool, packageName string, selectedPackages []string) (map[string][]string, map[string]bool, map[string][]string, map[string]*constraintEdge, map[string]bool, error) {
 	// Create Kubernetes client config
 	var config *rest.Config
 	var err error
@@ -135,45 +152,50 @@ func buildGraphFromCluster(ctx context.Context, kubeconfig string, packagesOnly
 		// Load kubeconfig from explicit path
 		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
 		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("failed to load kubeconfig from %s: %w", kubeconfig, err)
+			return nil, nil, nil, nil, nil, fmt.Errorf("failed to load kubeconfig from %s: %w", kubeconfig, err)
 		}
 	} else {
 		// Use default kubeconfig loading (from env var or ~/.kube/config)
 		config, err = ctrl.GetConfig()
 		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("failed to get kubeconfig: %w", err)
+			return nil, nil, nil, nil, nil, fmt.Errorf("failed to get kubeconfig: %w", err)
 		}
 	}
 
 	k8sClient, err := client.New(config, client.Options{Scheme: dependenciesScheme})
 	if err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("failed to create k8s client: %w", err)
+		return nil, nil, nil, nil, nil, fmt.Errorf("failed to create k8s client: %w", err)
 	}
 
-	// Get installed Packages if needed
+	// Get installed Packages, for --installed and the variants constraints are checked against
 	installedPackages := make(map[string]bool)
-	if installedOnly {
-		var packageList cozyv1alpha1.PackageList
-		if err := k8sClient.List(ctx, &packageList); err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("failed to list Packages: %w", err)
-		}
-		for _, pkg := range packageList.Items {
-			installedPackages[pkg.Name] = true
+	installedVariants := make(map[string]string)
+	var packageList cozyv1alpha1.PackageList
+	if err := k8sClient.List(ctx, &packageList); err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("failed to list Packages: %w", err)
+	}
+	for _, pkg := range packageList.Items {
+		installedPackages[pkg.Name] = true
+		installedVariants[pkg.Name] = pkg.Spec.Variant
+		if installedVariants[pkg.Name] == "" {
+			installedVariants[pkg.Name] = "default"
 		}
 	}
 
 	// List all PackageSource resources
 	var packageSourceList cozyv1alpha1.PackageSourceList
 	if err := k8sClient.List(ctx, &packageSourceList); err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("failed to list PackageSources: %w", err)
+		return nil, nil, nil, nil, nil, fmt.Errorf("failed to list PackageSources: %w", err)
 	}
 
 	// Build map of existing packages and components
 	packageNames := make(map[string]bool)
+	packageSources := make(map[string]*cozyv1alpha1.PackageSource)
 	allExistingComponents := make(map[string]bool) // "package.component" -> true
-	for _, ps := range packageSourceList.Items {
+	for i, ps := range packageSourceList.Items {
 		if ps.Name != "" {
 			packageNames[ps.Name] = true
+			packageSources[ps.Name] = &packageSourceList.Items[i]
 			for _, variant := range ps.Spec.Variants {
 				for _, component := range variant.Components {
 					if component.Install != nil {
@@ -187,9 +209,10 @@ func buildGraphFromCluster(ctx context.Context, kubeconfig string, packagesOnly
 
 	graph := make(map[string][]string)
 	allNodes := make(map[string]bool)
-	edgeVariants := make(map[string][]string)      // key: "source->target", value: list of variant names
-	existingEdges := make(map[string]bool)         // key: "source->target" to avoid duplicates
-	componentHasLocalDeps := make(map[string]bool) // componentName -> has local component dependencies
+	edgeVariants := make(map[string][]string)           // key: "source->target", value: list of variant names
+	edgeConstraints := make(map[string]*constraintEdge) // key: "source->target", value: version and variant constraints
+	existingEdges := make(map[string]bool)              // key: "source->target" to avoid duplicates
+	componentHasLocalDeps := make(map[string]bool)      // componentName -> has local component dependencies
 
 	// Process each PackageSource
 	for _, ps := range packageSourceList.Items {
@@ -263,6 +286,37 @@ func buildGraphFromCluster(ctx context.Context, kubeconfig string, packagesOnly
 				// If package doesn't exist, don't add to allNodes - it will be shown as missing (red)
 			}
 
+			// Version and variant constraints (package-level)
+			for _, constraint := range variant.Constraints {
+				if installedOnly && !installedPackages[constraint.Name] {
+					continue
+				}
+
+				edgeKey := fmt.Sprintf("%s->%s", psName, constraint.Name)
+				if !existingEdges[edgeKey] {
+					graph[psName] = append(graph[psName], constraint.Name)
+					existingEdges[edgeKey] = true
+				}
+				edge := edgeConstraints[edgeKey]
+				if edge == nil {
+					edge = &constraintEdge{}
+					edgeConstraints[edgeKey] = edge
+				}
+				for _, dep := range variant.DependsOn {
+					edge.dependency = edge.dependency || dep == constraint.Name
+				}
+				if label := constraintLabel(constraint); label != "" && !slices.Contains(edge.labels, label) {
+					edge.labels = append(edge.labels, label)
+				}
+
+				if target, ok := packageSources[constraint.Name]; ok {
+					allNodes[constraint.Name] = true
+					if err := operator.CheckConstraint(constraint, target, installedVariants[constraint.Name]); err != nil {
+						edge.unsatisfied = true
+					}
+				}
+			}
+
 			// Component-level dependencies
 			if !packagesOnly {
 				for _, component := range variant.Components {
@@ -430,11 +484,23 @@ func buildGraphFromCluster(ctx context.Context, kubeconfig string, packagesOnly
 		}
 	}
 
-	return graph, allNodes, edgeVariants, packageNames, nil
+	return graph, allNodes, edgeVariants, edgeConstraints, packageNames, nil
+}
+
+// constraintLabel renders a constraint for an edge label, e.g. ">= 1.4 [cilium]".
+func constraintLabel(constraint cozyv1alpha1.DependencyConstraint) string {
+	var parts []string
+	if constraint.Version != "" {
+		parts = append(parts, constraint.Version)
+	}
+	if len(constraint.Variants) > 0 {
+		parts = append(parts, "["+strings.Join(constraint.Variants, ",")+"]")
+	}
+	return strings.Join(parts, " ")
 }
 
 // generateDOTGraph generates a DOT graph from the dependency graph.
-func generateDOTGraph(graph map[string][]string, allNodes map[string]bool, packagesOnly bool, edgeVariants map[string][]string, packageNames map[string]bool) *dot.Graph {
+func generateDOTGraph(graph map[string][]string, allNodes map[string]bool, packagesOnly bool, edgeVariants map[string][]string, edgeConstraints map[string]*constraintEdge, packageNames map[string]bool) *dot.Graph {
 	g := dot.NewGraph(dot.Directed)
 	g.Attr("rankdir", "RL")
 	g.Attr("nodesep", "0.5")
@@ -552,9 +618,22 @@ func generateDOTGraph(graph map[string][]string, allNodes map[string]bool, packa
 			} else {
 				// Check if this edge has variant information (dependency not in all variants)
 				edgeKey := fmt.Sprintf("%s->%s", source, target)
+				var label []string
 				if variants, hasVariants := edgeVariants[edgeKey]; hasVariants {
 					// Add label with variant names
-					edge.Attr("label", strings.Join(variants, ","))
+					label = append(label, strings.Join(variants, ","))
+				}
+				if constraint, hasConstraint := edgeConstraints[edgeKey]; hasConstraint {
+					label = append(label, constraint.labels...)
+					if !constraint.dependency {
+						edge.Attr("style", "dotted")
+					}
+					if constraint.unsatisfied {
+						edge.Attr("color", "orange")
+					}
+				}
+				if len(label) > 0 {
+					edge.Attr("label", strings.Join(label, "\n"))
 				}
 			}
 		}
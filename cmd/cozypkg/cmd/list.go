@@ -95,7 +95,7 @@ func listPackageSources(ctx context.Context, k8sClient client.Client, showCompon
 	defer w.Flush()
 
 	// Print header
-	fmt.Fprintln(w, "NAME\tVARIANTS\tREADY\tSTATUS")
+	fmt.Fprintln(w, "NAME\tVARIANTS\tREADY\tVERIFIED\tSTATUS")
 
 	// Print rows
 	for _, ps := range psList.Items {
@@ -123,15 +123,24 @@ func listPackageSources(ctx context.Context, k8sClient client.Client, showCompon
 			}
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", ps.Name, variantsStr, ready, status)
+		// Get Verified condition; absent when no variant has a policy
+		verified := "-"
+		for _, condition := range ps.Status.Conditions {
+			if condition.Type == "Verified" {
+				verified = string(condition.Status)
+				break
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", ps.Name, variantsStr, ready, verified, status)
 
 		// Show components if requested
 		if showComponents {
 			for _, variant := range ps.Spec.Variants {
 				for _, component := range variant.Components {
-					fmt.Fprintf(w, "  %s\t%s\t\t\n", 
+					fmt.Fprintf(w, "  %s\t%s\t\t%s\t\n", 
 						fmt.Sprintf("%s.%s", ps.Name, component.Name), 
-						variant.Name)
+						variant.Name, variantVerification(&ps, variant.Name))
 				}
 			}
 		}
@@ -146,17 +155,14 @@ func listPackages(ctx context.Context, k8sClient client.Client, showComponents b
 		return fmt.Errorf("failed to list Packages: %w", err)
 	}
 
-	// Fetch all PackageSource resources once if components are requested
-	var psMap map[string]*cozyv1alpha1.PackageSource
-	if showComponents {
-		var psList cozyv1alpha1.PackageSourceList
-		if err := k8sClient.List(ctx, &psList); err != nil {
-			return fmt.Errorf("failed to list PackageSources: %w", err)
-		}
-		psMap = make(map[string]*cozyv1alpha1.PackageSource)
-		for i := range psList.Items {
-			psMap[psList.Items[i].Name] = &psList.Items[i]
-		}
+	// Fetch all PackageSource resources once for verification state and components
+	var psList cozyv1alpha1.PackageSourceList
+	if err := k8sClient.List(ctx, &psList); err != nil {
+		return fmt.Errorf("failed to list PackageSources: %w", err)
+	}
+	psMap := make(map[string]*cozyv1alpha1.PackageSource)
+	for i := range psList.Items {
+		psMap[psList.Items[i].Name] = &psList.Items[i]
 	}
 
 	// Use tabwriter for better column alignment
@@ -164,7 +170,7 @@ func listPackages(ctx context.Context, k8sClient client.Client, showComponents b
 	defer w.Flush()
 
 	// Print header
-	fmt.Fprintln(w, "NAME\tVARIANT\tREADY\tSTATUS")
+	fmt.Fprintln(w, "NAME\tVARIANT\tREADY\tVERIFIED\tSTATUS")
 
 	// Print rows
 	for _, pkg := range pkgList.Items {
@@ -187,7 +193,12 @@ func listPackages(ctx context.Context, k8sClient client.Client, showComponents b
 			}
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", pkg.Name, variant, ready, status)
+		verified := "-"
+		if ps, exists := psMap[pkg.Name]; exists {
+			verified = variantVerification(ps, variant)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", pkg.Name, variant, ready, verified, status)
 
 		// Show components if requested
 		if showComponents {
@@ -197,7 +208,7 @@ func listPackages(ctx context.Context, k8sClient client.Client, showComponents b
 				for _, v := range ps.Spec.Variants {
 					if v.Name == variant {
 						for _, component := range v.Components {
-							fmt.Fprintf(w, "  %s\t%s\t\t\n", 
+							fmt.Fprintf(w, "  %s\t%s\t\t\t\n", 
 								fmt.Sprintf("%s.%s", pkg.Name, component.Name), 
 								variant)
 						}
@@ -211,6 +222,21 @@ func listPackages(ctx context.Context, k8sClient client.Client, showComponents b
 	return nil
 }
 
+// variantVerification returns the verification state of a variant of ps:
+// its status, followed by the reason when it is not verified, or "-" when the
+// variant has no verification policy.
+func variantVerification(ps *cozyv1alpha1.PackageSource, variant string) string {
+	for _, v := range ps.Status.Verification {
+		if v.Variant == variant {
+			if v.Status == "True" || v.Reason == "" {
+				return string(v.Status)
+			}
+			return fmt.Sprintf("%s (%s)", v.Status, v.Reason)
+		}
+	}
+	return "-"
+}
+
 func init() {
 	rootCmd.AddCommand(listCmd)
 	listCmd.Flags().BoolVarP(&listCmdFlags.installed, "installed", "i", false, "list installed Package resources instead of PackageSource resources")
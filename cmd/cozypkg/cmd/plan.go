@@ -0,0 +1,500 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	"github.com/cozystack/cozystack/internal/operator"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+var planCmdFlags struct {
+	files      []string
+	variant    string
+	output     string
+	kubeconfig string
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan [package]...",
+	Short: "Show which HelmReleases a Package or PackageSource change will create, update or delete",
+	Long: `Show which HelmReleases a Package or PackageSource change will create, update or delete.
+
+The change is read from -f files or directories holding Package and PackageSource
+manifests, and from --variant. Each Package is resolved the way the Package
+controller resolves it: the variant is looked up in the PackageSource, component
+overrides from the Package are applied and dependsOn is built from the dependency
+graph. The resulting HelmReleases are compared with the cluster, which is left
+untouched. Packages named as arguments are planned against the cluster as-is.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		k8sClient, scheme, err := newPlanClient(planCmdFlags.kubeconfig)
+		if err != nil {
+			return err
+		}
+		changes, err := loadChanges(ctx, k8sClient, args, planCmdFlags.files, planCmdFlags.variant)
+		if err != nil {
+			return err
+		}
+		plans, err := planChanges(ctx, k8sClient, scheme, changes)
+		if err != nil {
+			return err
+		}
+		return printPlans(os.Stdout, changes, plans, planCmdFlags.output)
+	},
+}
+
+// packageChange is the Package and PackageSource one package is planned
+// with, and whether they differ from the cluster.
+type packageChange struct {
+	name          string
+	pkg           *cozyv1alpha1.Package
+	packageSource *cozyv1alpha1.PackageSource
+	// installed is false when the Package does not exist yet.
+	installed bool
+	// pkgChanged and sourceChanged are set when the Package or the
+	// PackageSource from files and flags differs from the cluster and must
+	// be written.
+	pkgChanged    bool
+	sourceChanged bool
+}
+
+func newPlanClient(kubeconfig string) (client.Client, *runtime.Scheme, error) {
+	var config *rest.Config
+	var err error
+
+	if kubeconfig != "" {
+		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load kubeconfig from %s: %w", kubeconfig, err)
+		}
+	} else {
+		config, err = ctrl.GetConfig()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get kubeconfig: %w", err)
+		}
+	}
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(cozyv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(helmv2.AddToScheme(scheme))
+	utilruntime.Must(sourcev1.AddToScheme(scheme))
+
+	k8sClient, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	return k8sClient, scheme, nil
+}
+
+// loadChanges resolves the Package and PackageSource of every package named
+// in args or defined in files, in dependency order.
+func loadChanges(ctx context.Context, k8sClient client.Client, args, files []string, variant string) ([]*packageChange, error) {
+	sources := map[string]*cozyv1alpha1.PackageSource{}
+	packages := map[string]*cozyv1alpha1.Package{}
+	for _, path := range files {
+		if err := readManifests(path, sources, packages); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	names := map[string]bool{}
+	for _, arg := range args {
+		names[arg] = true
+	}
+	for name := range sources {
+		names[name] = true
+	}
+	for name := range packages {
+		names[name] = true
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no packages specified")
+	}
+	if variant != "" && len(names) != 1 {
+		return nil, fmt.Errorf("--variant needs exactly one package, got %d", len(names))
+	}
+
+	changes := map[string]*packageChange{}
+	tree := map[string][]string{}
+	for name := range names {
+		ch := &packageChange{name: name}
+
+		current := &cozyv1alpha1.PackageSource{}
+		if err := k8sClient.Get(ctx, client.ObjectKey{Name: name}, current); err == nil {
+			ch.packageSource = current
+		} else if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get PackageSource %s: %w", name, err)
+		}
+		// A PackageSource from a file replaces the spec and adds its labels
+		// and annotations; those set in the cluster by others stay.
+		if fromFile, ok := sources[name]; ok {
+			if ch.packageSource == nil {
+				fromFile.ResourceVersion = ""
+				ch.packageSource = fromFile
+				ch.sourceChanged = true
+			} else {
+				updated := mergeManifest(ch.packageSource, fromFile).(*cozyv1alpha1.PackageSource)
+				updated.Spec = fromFile.Spec
+				ch.sourceChanged = !equality.Semantic.DeepEqual(updated, ch.packageSource)
+				ch.packageSource = updated
+			}
+		}
+		if ch.packageSource == nil {
+			return nil, fmt.Errorf("PackageSource %s not found", name)
+		}
+
+		pkg := &cozyv1alpha1.Package{}
+		if err := k8sClient.Get(ctx, client.ObjectKey{Name: name}, pkg); err == nil {
+			ch.installed = true
+		} else if apierrors.IsNotFound(err) {
+			pkg = &cozyv1alpha1.Package{}
+			pkg.Name = name
+		} else {
+			return nil, fmt.Errorf("failed to get Package %s: %w", name, err)
+		}
+		// A Package from a file is merged the same way; the UID stays, so
+		// ownerReferences compare equal.
+		updated := pkg.DeepCopy()
+		if fromFile, ok := packages[name]; ok {
+			updated = mergeManifest(pkg, fromFile).(*cozyv1alpha1.Package)
+			updated.Spec = fromFile.Spec
+		}
+		if variant != "" {
+			updated.Spec.Variant = variant
+		}
+		ch.pkgChanged = !ch.installed || !equality.Semantic.DeepEqual(updated, pkg)
+		ch.pkg = updated
+
+		changes[name] = ch
+		tree[name] = nil
+		for _, v := range ch.packageSource.Spec.Variants {
+			if v.Name != variantOrDefault(pkg.Spec.Variant) {
+				continue
+			}
+			for _, dep := range v.DependsOn {
+				if names[dep] {
+					tree[name] = append(tree[name], dep)
+				}
+			}
+		}
+	}
+
+	order, err := topologicalSort(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sort dependencies: %w", err)
+	}
+	var out []*packageChange
+	for _, name := range order {
+		out = append(out, changes[name])
+	}
+	return out, nil
+}
+
+// mergeManifest copies current and adds the labels and annotations of
+// fromFile.
+func mergeManifest(current, fromFile client.Object) client.Object {
+	out := current.DeepCopyObject().(client.Object)
+	for _, m := range []struct {
+		get func() map[string]string
+		set func(map[string]string)
+		add map[string]string
+	}{
+		{out.GetLabels, out.SetLabels, fromFile.GetLabels()},
+		{out.GetAnnotations, out.SetAnnotations, fromFile.GetAnnotations()},
+	} {
+		if len(m.add) == 0 {
+			continue
+		}
+		merged := map[string]string{}
+		for k, v := range m.get() {
+			merged[k] = v
+		}
+		for k, v := range m.add {
+			merged[k] = v
+		}
+		m.set(merged)
+	}
+	return out
+}
+
+func variantOrDefault(variant string) string {
+	if variant == "" {
+		return "default"
+	}
+	return variant
+}
+
+// readManifests collects the Packages and PackageSources defined in a file,
+// or in the YAML files of a directory.
+func readManifests(path string, sources map[string]*cozyv1alpha1.PackageSource, packages map[string]*cozyv1alpha1.Package) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !strings.HasSuffix(p, ".yaml") && !strings.HasSuffix(p, ".yml") {
+				return nil
+			}
+			if err := readManifests(p, sources, packages); err != nil {
+				return fmt.Errorf("failed to read %s: %w", p, err)
+			}
+			return nil
+		})
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+	for _, doc := range strings.Split(string(data), "\n---") {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		if _, _, err := decoder.Decode([]byte(doc), nil, obj); err != nil {
+			continue
+		}
+		switch obj.GetKind() {
+		case "PackageSource":
+			ps := &cozyv1alpha1.PackageSource{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, ps); err != nil {
+				return fmt.Errorf("failed to convert PackageSource %s: %w", obj.GetName(), err)
+			}
+			sources[ps.Name] = ps
+		case "Package":
+			pkg := &cozyv1alpha1.Package{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pkg); err != nil {
+				return fmt.Errorf("failed to convert Package %s: %w", obj.GetName(), err)
+			}
+			packages[pkg.Name] = pkg
+		}
+	}
+	return nil
+}
+
+// planChanges plans every change with the HelmRelease settings the
+// cozystack-operator in the cluster runs with.
+func planChanges(ctx context.Context, k8sClient client.Client, scheme *runtime.Scheme, changes []*packageChange) ([]*operator.PackagePlan, error) {
+	r, err := operatorReconciler(ctx, k8sClient, scheme)
+	if err != nil {
+		return nil, err
+	}
+	var plans []*operator.PackagePlan
+	for _, ch := range changes {
+		plan, err := r.Plan(ctx, ch.pkg, ch.packageSource)
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan Package %s: %w", ch.name, err)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// operatorReconciler returns a PackageReconciler configured like the
+// cozystack-operator Deployment, so operator-wide settings such as
+// intervals and timeouts do not show up as changes. The defaults match the
+// operator's own flag defaults.
+func operatorReconciler(ctx context.Context, k8sClient client.Client, scheme *runtime.Scheme) (*operator.PackageReconciler, error) {
+	r := &operator.PackageReconciler{
+		Client:                    k8sClient,
+		Scheme:                    scheme,
+		HelmReleaseInterval:       5 * time.Minute,
+		HelmReleaseRetryInterval:  30 * time.Second,
+		HelmReleaseInstallTimeout: 10 * time.Minute,
+		HelmReleaseUpgradeTimeout: 10 * time.Minute,
+		HelmReleaseMaxHistory:     5,
+	}
+
+	deploy := &appsv1.Deployment{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "cozy-system", Name: "cozystack-operator"}, deploy); err != nil {
+		if apierrors.IsNotFound(err) {
+			fmt.Fprintf(os.Stderr, "warning: cozystack-operator Deployment not found, assuming default HelmRelease settings\n")
+			return r, nil
+		}
+		return nil, fmt.Errorf("failed to get cozystack-operator Deployment: %w", err)
+	}
+	durations := map[string]*time.Duration{
+		"--helmrelease-interval":        &r.HelmReleaseInterval,
+		"--helmrelease-retry-interval":  &r.HelmReleaseRetryInterval,
+		"--helmrelease-install-timeout": &r.HelmReleaseInstallTimeout,
+		"--helmrelease-upgrade-timeout": &r.HelmReleaseUpgradeTimeout,
+	}
+	for _, c := range deploy.Spec.Template.Spec.Containers {
+		for _, arg := range c.Args {
+			flag, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				continue
+			}
+			if d, ok := durations[flag]; ok {
+				parsed, err := time.ParseDuration(value)
+				if err != nil {
+					return nil, fmt.Errorf("cozystack-operator has an invalid %s: %w", flag, err)
+				}
+				*d = parsed
+			}
+			if flag == "--helmrelease-max-history" {
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("cozystack-operator has an invalid %s: %w", flag, err)
+				}
+				r.HelmReleaseMaxHistory = n
+			}
+		}
+	}
+	return r, nil
+}
+
+// printPlans writes the plans as text, or the desired HelmReleases as YAML.
+func printPlans(w io.Writer, changes []*packageChange, plans []*operator.PackagePlan, output string) error {
+	switch output {
+	case "yaml":
+		for _, plan := range plans {
+			for _, rel := range plan.Releases {
+				if rel.Desired == nil {
+					continue
+				}
+				hr := rel.Desired.DeepCopy()
+				hr.APIVersion = helmv2.GroupVersion.String()
+				hr.Kind = helmv2.HelmReleaseKind
+				hr.ManagedFields = nil
+				hr.ResourceVersion = ""
+				hr.Status = helmv2.HelmReleaseStatus{}
+				data, err := sigsyaml.Marshal(hr)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(w, "---\n%s", data)
+			}
+		}
+		return nil
+	case "", "text":
+	default:
+		return fmt.Errorf("unknown output format %q, want text or yaml", output)
+	}
+
+	planned := map[string]bool{}
+	var creates, updates, deletes int
+	for i, plan := range plans {
+		ch := changes[i]
+		state := "installed"
+		if !ch.installed {
+			state = "not installed"
+		}
+		fmt.Fprintf(w, "Package %s (variant: %s, %s)\n", plan.Package, plan.Variant, state)
+		if ch.sourceChanged {
+			fmt.Fprintf(w, "  ~ PackageSource %s\n", ch.name)
+		}
+		if ch.pkgChanged {
+			fmt.Fprintf(w, "  ~ Package %s\n", ch.name)
+		}
+		if len(plan.InvalidValues) > 0 {
+			for _, msg := range plan.InvalidValues {
+				fmt.Fprintf(w, "  ! invalid values: %s\n", msg)
+			}
+			fmt.Fprintln(w)
+			planned[plan.Package] = true
+			continue
+		}
+		if len(plan.Conflicts) > 0 {
+			for _, c := range plan.Conflicts {
+				fmt.Fprintf(w, "  ! conflict on %s: %s\n", c.Package, c.Message)
+			}
+			fmt.Fprintln(w)
+			planned[plan.Package] = true
+			continue
+		}
+		if len(plan.Blocked) > 0 {
+			var deps []string
+			for _, dep := range plan.Blocked {
+				if planned[dep] {
+					dep += " (planned above)"
+				}
+				deps = append(deps, dep)
+			}
+			fmt.Fprintf(w, "  ! blocked until these dependencies are ready: %s\n\n", strings.Join(deps, ", "))
+			planned[plan.Package] = true
+			continue
+		}
+		for _, rel := range plan.Releases {
+			fmt.Fprintf(w, "  %s %-9s HelmRelease %s/%s\n", planSymbol(rel.Action), rel.Action, rel.Namespace, rel.Name)
+			if rel.Diff != "" {
+				for _, line := range strings.Split(strings.TrimSuffix(rel.Diff, "\n"), "\n") {
+					fmt.Fprintf(w, "      %s\n", line)
+				}
+			}
+		}
+		c, u, d := plan.Changes()
+		creates, updates, deletes = creates+c, updates+u, deletes+d
+		fmt.Fprintln(w)
+		planned[plan.Package] = true
+	}
+	fmt.Fprintf(w, "Plan: %d to create, %d to update, %d to delete.\n", creates, updates, deletes)
+	return nil
+}
+
+func planSymbol(action operator.PlanAction) string {
+	switch action {
+	case operator.PlanCreate:
+		return "+"
+	case operator.PlanUpdate:
+		return "~"
+	case operator.PlanDelete:
+		return "-"
+	}
+	return "="
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	planCmd.Flags().StringArrayVarP(&planCmdFlags.files, "file", "f", []string{}, "Read Package and PackageSource manifests from file or directory (can be specified multiple times)")
+	planCmd.Flags().StringVar(&planCmdFlags.variant, "variant", "", "Switch the package to this variant")
+	planCmd.Flags().StringVarP(&planCmdFlags.output, "output", "o", "text", "Output format: text, or yaml for the resulting HelmReleases")
+	planCmd.Flags().StringVar(&planCmdFlags.kubeconfig, "kubeconfig", "", "Path to kubeconfig file (defaults to ~/.kube/config or KUBECONFIG env var)")
+}
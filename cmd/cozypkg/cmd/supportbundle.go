@@ -0,0 +1,171 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cozystack/cozystack/internal/supportbundle"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// supportBundleExecTimeout bounds each command run in an ovn-central pod.
+const supportBundleExecTimeout = 30 * time.Second
+
+var supportBundleCmdFlags struct {
+	output     string
+	since      time.Duration
+	logLines   int64
+	noOVN      bool
+	kubeconfig string
+}
+
+var supportBundleCmd = &cobra.Command{
+	Use:   "support-bundle",
+	Short: "Collect a redacted diagnostics bundle of the platform",
+	Long: `Collect a redacted diagnostics bundle of the platform.
+
+The bundle is a gzipped tarball to attach to an issue. It holds the status of
+Packages, PackageSources, ApplicationDefinitions, HelmReleases and
+WorkloadMonitors, the flux-shard placement of tenants, the consensus of the
+OVN Raft clusters as kubeovn-plunger computes it, failed BackupJobs and
+RestoreJobs, recent Events and the logs of the Cozystack controllers and Flux.
+index.yaml at its root lists every file, and what could not be collected and
+why.
+
+Secret and TenantSecret values are replaced with REDACTED, keeping their
+keys, and so is any string under a key naming a credential (password, token,
+secret...) in other objects. Credentials recognisable in Events and logs are
+scrubbed too; review the bundle before sharing it all the same.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		var config *rest.Config
+		var err error
+		if supportBundleCmdFlags.kubeconfig != "" {
+			config, err = clientcmd.BuildConfigFromFlags("", supportBundleCmdFlags.kubeconfig)
+			if err != nil {
+				return fmt.Errorf("failed to load kubeconfig from %s: %w", supportBundleCmdFlags.kubeconfig, err)
+			}
+		} else {
+			config, err = ctrl.GetConfig()
+			if err != nil {
+				return fmt.Errorf("failed to get kubeconfig: %w", err)
+			}
+		}
+		dyn, err := dynamic.NewForConfig(config)
+		if err != nil {
+			return fmt.Errorf("failed to create dynamic client: %w", err)
+		}
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return fmt.Errorf("failed to create clientset: %w", err)
+		}
+
+		output := supportBundleCmdFlags.output
+		if output == "" {
+			output = fmt.Sprintf("cozystack-support-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+		}
+		out, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", output, err)
+		}
+		defer out.Close()
+
+		opts := supportbundle.Options{
+			Dynamic:   dyn,
+			Clientset: clientset,
+			Since:     supportBundleCmdFlags.since,
+			LogLines:  supportBundleCmdFlags.logLines,
+			Version:   Version,
+			Log:       os.Stderr,
+		}
+		if !supportBundleCmdFlags.noOVN {
+			opts.Exec = podExec(config, clientset)
+		}
+		index, err := supportbundle.Collect(ctx, out, opts)
+		if err != nil {
+			_ = os.Remove(output)
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		failed := 0
+		for _, f := range index.Files {
+			if f.Error != "" {
+				failed++
+			}
+		}
+		fmt.Fprintf(os.Stderr, "✓ Wrote %s: %d file(s)", output, len(index.Files)-failed)
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, ", %d could not be collected (see index.yaml)", failed)
+		}
+		fmt.Fprintln(os.Stderr)
+		return nil
+	},
+}
+
+// podExec runs commands in pods over the exec subresource.
+func podExec(config *rest.Config, clientset kubernetes.Interface) supportbundle.ExecFunc {
+	return func(ctx context.Context, namespace, pod, container string, command []string) (string, error) {
+		req := clientset.CoreV1().RESTClient().Post().
+			Resource("pods").
+			Namespace(namespace).
+			Name(pod).
+			SubResource("exec").
+			VersionedParams(&corev1.PodExecOptions{
+				Container: container,
+				Command:   command,
+				Stdout:    true,
+				Stderr:    true,
+			}, scheme.ParameterCodec)
+		exec, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+		if err != nil {
+			return "", fmt.Errorf("spdy executor: %w", err)
+		}
+		ctx, cancel := context.WithTimeout(ctx, supportBundleExecTimeout)
+		defer cancel()
+		var stdout, stderr bytes.Buffer
+		if err := exec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr}); err != nil {
+			return "", fmt.Errorf("%s: %w: %s", command[0], err, stderr.String())
+		}
+		return stdout.String(), nil
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(supportBundleCmd)
+	supportBundleCmd.Flags().StringVarP(&supportBundleCmdFlags.output, "output", "o", "", "Bundle tarball to write (default cozystack-support-<timestamp>.tar.gz)")
+	supportBundleCmd.Flags().DurationVar(&supportBundleCmdFlags.since, "since", time.Hour, "How far back Events and logs go")
+	supportBundleCmd.Flags().Int64Var(&supportBundleCmdFlags.logLines, "log-lines", 2000, "Lines kept of each container log")
+	supportBundleCmd.Flags().BoolVar(&supportBundleCmdFlags.noOVN, "no-ovn", false, "Skip the OVN consensus check, which runs commands in the ovn-central pods")
+	supportBundleCmd.Flags().StringVar(&supportBundleCmdFlags.kubeconfig, "kubeconfig", "", "Path to kubeconfig file (defaults to ~/.kube/config or KUBECONFIG env var)")
+}
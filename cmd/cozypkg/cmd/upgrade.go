@@ -0,0 +1,142 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var upgradeCmdFlags struct {
+	files      []string
+	variant    string
+	yes        bool
+	kubeconfig string
+}
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade [package]...",
+	Short: "Plan a Package or PackageSource change and apply it after confirmation",
+	Long: `Plan a Package or PackageSource change and apply it after confirmation.
+
+Prints the same plan as "cozypkg plan", then writes the PackageSources and
+Packages from -f files and the --variant switch to the cluster. The Package
+controller then creates, updates and deletes the HelmReleases as planned.
+PackageSources are written before Packages, dependencies before dependents.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		k8sClient, scheme, err := newPlanClient(upgradeCmdFlags.kubeconfig)
+		if err != nil {
+			return err
+		}
+		changes, err := loadChanges(ctx, k8sClient, args, upgradeCmdFlags.files, upgradeCmdFlags.variant)
+		if err != nil {
+			return err
+		}
+		plans, err := planChanges(ctx, k8sClient, scheme, changes)
+		if err != nil {
+			return err
+		}
+		if err := printPlans(os.Stdout, changes, plans, "text"); err != nil {
+			return err
+		}
+
+		pending := false
+		for _, ch := range changes {
+			pending = pending || ch.pkgChanged || ch.sourceChanged
+		}
+		if !pending {
+			fmt.Fprintln(os.Stderr, "Nothing to apply: no Package or PackageSource differs from the cluster.")
+			return nil
+		}
+		if !upgradeCmdFlags.yes {
+			ok, err := confirm("Apply these changes?")
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("upgrade cancelled")
+			}
+		}
+		return applyChanges(ctx, k8sClient, changes)
+	},
+}
+
+// applyChanges writes the changed PackageSources, then the changed
+// Packages.
+func applyChanges(ctx context.Context, k8sClient client.Client, changes []*packageChange) error {
+	for _, ch := range changes {
+		if !ch.sourceChanged {
+			continue
+		}
+		ps := ch.packageSource
+		if ps.ResourceVersion == "" {
+			if err := k8sClient.Create(ctx, ps); err != nil {
+				return fmt.Errorf("failed to create PackageSource %s: %w", ps.Name, err)
+			}
+			fmt.Fprintf(os.Stderr, "✓ Added PackageSource %s\n", ps.Name)
+			continue
+		}
+		if err := k8sClient.Update(ctx, ps); err != nil {
+			return fmt.Errorf("failed to update PackageSource %s: %w", ps.Name, err)
+		}
+		fmt.Fprintf(os.Stderr, "✓ Updated PackageSource %s\n", ps.Name)
+	}
+	for _, ch := range changes {
+		if !ch.pkgChanged {
+			continue
+		}
+		if !ch.installed {
+			if err := k8sClient.Create(ctx, ch.pkg); err != nil {
+				return fmt.Errorf("failed to create Package %s: %w", ch.name, err)
+			}
+			fmt.Fprintf(os.Stderr, "✓ Added Package %s\n", ch.name)
+			continue
+		}
+		if err := k8sClient.Update(ctx, ch.pkg); err != nil {
+			return fmt.Errorf("failed to update Package %s: %w", ch.name, err)
+		}
+		fmt.Fprintf(os.Stderr, "✓ Updated Package %s\n", ch.name)
+	}
+	return nil
+}
+
+// confirm asks a yes/no question on stderr; anything but y or yes is no.
+func confirm(question string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	input, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, fmt.Errorf("failed to read input: %w", err)
+	}
+	input = strings.ToLower(strings.TrimSpace(input))
+	return input == "y" || input == "yes", nil
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+	upgradeCmd.Flags().StringArrayVarP(&upgradeCmdFlags.files, "file", "f", []string{}, "Read Package and PackageSource manifests from file or directory (can be specified multiple times)")
+	upgradeCmd.Flags().StringVar(&upgradeCmdFlags.variant, "variant", "", "Switch the package to this variant")
+	upgradeCmd.Flags().BoolVarP(&upgradeCmdFlags.yes, "yes", "y", false, "Apply without asking for confirmation")
+	upgradeCmd.Flags().StringVar(&upgradeCmdFlags.kubeconfig, "kubeconfig", "", "Path to kubeconfig file (defaults to ~/.kube/config or KUBECONFIG env var)")
+}
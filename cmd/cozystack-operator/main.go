@@ -185,6 +185,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	valuesSchemaSelector, err := labels.Parse(operator.LabelValuesSchema)
+	if err != nil {
+		setupLog.Error(err, "could not parse values schema label selector")
+		os.Exit(1)
+	}
+
 	// Initialize the controller manager
 	mgr, err := ctrl.NewManager(config, ctrl.Options{
 		Scheme: scheme,
@@ -199,6 +205,12 @@ func main() {
 				&corev1.Namespace{}: {
 					Label: targetNSSelector,
 				},
+
+				// Cache only the ConfigMaps holding chart values schemas
+				&corev1.ConfigMap{}: {
+					Namespaces: map[string]cache.Config{"cozy-system": {}},
+					Label:      valuesSchemaSelector,
+				},
 			},
 		},
 		Metrics: metricsserver.Options{
@@ -297,8 +309,9 @@ func main() {
 
 	// Setup PackageSource reconciler
 	if err := (&operator.PackageSourceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		APIReader: mgr.GetAPIReader(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "PackageSource")
 		os.Exit(1)
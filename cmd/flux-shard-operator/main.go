@@ -32,7 +32,6 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/selection"
@@ -47,6 +46,7 @@ import (
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
 	fso "github.com/cozystack/cozystack/internal/fluxshardoperator"
 )
 
@@ -57,6 +57,7 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(cozyv1alpha1.AddToScheme(scheme))
 }
 
 func main() {
@@ -66,11 +67,6 @@ func main() {
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var fluxNamespace string
-	var shardCount string
-	var shardConcurrent int
-	var rebalanceThreshold float64
-	var pinnedTenants string
-	var shardCPURequest, shardCPULimit, shardMemoryRequest, shardMemoryLimit string
 	var tlsOpts []func(*tls.Config)
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
@@ -85,24 +81,6 @@ func main() {
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
 	flag.StringVar(&fluxNamespace, "flux-namespace", "cozy-fluxcd",
 		"Namespace of the flux-aio Deployment and the shard Deployments.")
-	flag.StringVar(&shardCount, "shard-count", fso.ShardCountAuto,
-		"Number of helm-controller shards to provision and distribute tenants over: "+
-			"\"auto\" (default) sizes from the tenant HelmRelease count with hysteresis, "+
-			"or a positive integer pins it explicitly.")
-	flag.IntVar(&shardConcurrent, "shard-concurrent", 5,
-		"Value of --concurrent for each shard helm-controller.")
-	flag.Float64Var(&rebalanceThreshold, "rebalance-threshold", 0.25,
-		"Load spread ratio (maxLoad-minLoad)/avgLoad above which tenants are rebalanced between shards.")
-	flag.StringVar(&pinnedTenants, "pinned-tenants", "",
-		"Comma-separated tenant pins, e.g. tenant-bigone=shard3,tenant-other=shard0.")
-	flag.StringVar(&shardCPURequest, "shard-cpu-request", "",
-		"CPU request for shard helm-controllers; empty inherits the flux-aio value.")
-	flag.StringVar(&shardCPULimit, "shard-cpu-limit", "",
-		"CPU limit for shard helm-controllers; empty inherits the flux-aio value.")
-	flag.StringVar(&shardMemoryRequest, "shard-memory-request", "",
-		"Memory request for shard helm-controllers; empty inherits the flux-aio value.")
-	flag.StringVar(&shardMemoryLimit, "shard-memory-limit", "",
-		"Memory limit for shard helm-controllers; empty inherits the flux-aio value.")
 	opts := zap.Options{
 		Development: false,
 	}
@@ -111,30 +89,9 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	pinned, err := fso.ParsePinnedTenants(pinnedTenants)
-	if err != nil {
-		setupLog.Error(err, "invalid --pinned-tenants")
-		os.Exit(1)
-	}
-	explicitShards, autoShards, err := fso.ParseShardCount(shardCount)
-	if err != nil {
-		setupLog.Error(err, "invalid --shard-count")
-		os.Exit(1)
-	}
-	shardResources, err := parseShardResources(shardCPURequest, shardCPULimit, shardMemoryRequest, shardMemoryLimit)
-	if err != nil {
-		setupLog.Error(err, "invalid shard resources")
-		os.Exit(1)
-	}
-	cfg := &fso.Config{
-		FluxNamespace:      fluxNamespace,
-		ShardCount:         explicitShards,
-		AutoShardCount:     autoShards,
-		ShardConcurrent:    shardConcurrent,
-		RebalanceThreshold: rebalanceThreshold,
-		PinnedTenants:      pinned,
-		ShardResources:     shardResources,
-	}
+	// Sharding settings are read from the FluxShardPolicy on every sync;
+	// these defaults apply while none exists.
+	cfg := fso.DefaultConfig(fluxNamespace)
 
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
@@ -192,6 +149,8 @@ func main() {
 				fso.NamespaceMeta():   {},
 				// Full Deployments, but only from the flux namespace.
 				&appsv1.Deployment{}: {Namespaces: map[string]cache.Config{fluxNamespace: {}}},
+				// Shard pods, to scrape their helm-controller metrics.
+				&corev1.Pod{}: {Namespaces: map[string]cache.Config{fluxNamespace: {}}},
 			},
 		},
 	})
@@ -210,7 +169,7 @@ func main() {
 		setupLog.Error(err, "unable to setup controller", "controller", "ShardSet")
 		os.Exit(1)
 	}
-	shardWebhook := &fso.ShardWebhook{Reader: mgr.GetClient()}
+	shardWebhook := &fso.ShardWebhook{Reader: mgr.GetClient(), Config: cfg}
 	if err := shardWebhook.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to setup webhook", "webhook", "ShardWebhook")
 		os.Exit(1)
@@ -225,43 +184,9 @@ func main() {
 		os.Exit(1)
 	}
 
-	setupLog.Info("starting manager",
-		"fluxNamespace", fluxNamespace, "shardCount", shardCount, "shardConcurrent", shardConcurrent)
+	setupLog.Info("starting manager", "fluxNamespace", fluxNamespace)
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
-
-// parseShardResources builds the shard helm-controller resource requirements
-// from flags; empty flags inherit the corresponding flux-aio values.
-func parseShardResources(cpuReq, cpuLim, memReq, memLim string) (corev1.ResourceRequirements, error) {
-	res := corev1.ResourceRequirements{}
-	set := func(list *corev1.ResourceList, name corev1.ResourceName, value string) error {
-		if value == "" {
-			return nil
-		}
-		q, err := resource.ParseQuantity(value)
-		if err != nil {
-			return err
-		}
-		if *list == nil {
-			*list = corev1.ResourceList{}
-		}
-		(*list)[name] = q
-		return nil
-	}
-	if err := set(&res.Requests, corev1.ResourceCPU, cpuReq); err != nil {
-		return res, err
-	}
-	if err := set(&res.Limits, corev1.ResourceCPU, cpuLim); err != nil {
-		return res, err
-	}
-	if err := set(&res.Requests, corev1.ResourceMemory, memReq); err != nil {
-		return res, err
-	}
-	if err := set(&res.Limits, corev1.ResourceMemory, memLim); err != nil {
-		return res, err
-	}
-	return res, nil
-}
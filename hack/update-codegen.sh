@@ -52,6 +52,7 @@ COZY_CONTROLLER_CRDDIR=packages/system/cozystack-controller/definitions
 COZY_RD_CRDDIR=packages/system/application-definition-crd/definition
 BACKUPS_CORE_CRDDIR=packages/system/backup-controller/definitions
 BACKUPSTRATEGY_CRDDIR=packages/system/backupstrategy-controller/definitions
+FLUX_SHARD_CRDDIR=packages/system/flux-shard-operator/crds
 
 trap 'rm -rf ${TMPDIR}' EXIT
 
@@ -102,6 +103,8 @@ mv ${TMPDIR}/cozystack.io_applicationdefinitions.yaml \
 mv ${TMPDIR}/backups.cozystack.io*.yaml ${BACKUPS_CORE_CRDDIR}/
 mv ${TMPDIR}/strategy.backups.cozystack.io*.yaml ${BACKUPSTRATEGY_CRDDIR}/
 
+mv ${TMPDIR}/cozystack.io_fluxshardpolicies.yaml ${FLUX_SHARD_CRDDIR}/cozystack.io_fluxshardpolicies.yaml
+
 mv ${TMPDIR}/*.yaml ${COZY_CONTROLLER_CRDDIR}/
 
 # Tidy dependencies for standalone api/apps/v1alpha1 submodule
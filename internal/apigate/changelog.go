@@ -0,0 +1,177 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigate
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Changelog lists the API changes between two releases for upgrade notes:
+// everything Classify reports, with removals matched against the head
+// policy, plus the served versions added to existing resources and the
+// deprecations head announces that base did not.
+func Changelog(base, head Snapshot, basePolicy, headPolicy Policy, now time.Time) []Finding {
+	findings := ApplyPolicy(Classify(base, head), headPolicy, now)
+
+	for _, res := range sortedResources(head) {
+		prev, ok := base[res.key()]
+		if !ok {
+			continue
+		}
+		for _, v := range sortedKeys(res.Versions) {
+			if _, existed := prev.Versions[v]; existed {
+				continue
+			}
+			findings = append(findings, Finding{
+				Category: NewVersion,
+				Group:    res.Group,
+				Kind:     res.Kind,
+				Plural:   res.Plural,
+				Source:   res.Source,
+				Origin:   res.Origin,
+				Version:  v,
+				Detail:   fmt.Sprintf("served version %q is added", v),
+			})
+		}
+	}
+
+	announced := map[Deprecation]bool{}
+	for _, d := range basePolicy.Deprecations {
+		announced[d] = true
+	}
+	for _, d := range headPolicy.Deprecations {
+		if announced[d] {
+			continue
+		}
+		f := Finding{
+			Category: Deprecated,
+			Group:    d.Group,
+			Plural:   d.Resource,
+			Origin:   PolicyFile,
+			Version:  d.Version,
+			Field:    d.Field,
+			Detail:   fmt.Sprintf("%s is deprecated and will be removed from %s", deprecatedSubject(d), d.Removal),
+		}
+		if res, ok := head[resourceKey{Group: d.Group, Plural: d.Resource}]; ok {
+			f.Kind = res.Kind
+			f.Source = res.Source
+		}
+		if d.Note != "" {
+			f.Detail += ": " + d.Note
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// deprecatedSubject names what a deprecation removes, for its changelog
+// entry.
+func deprecatedSubject(d Deprecation) string {
+	switch {
+	case d.Field != "" && d.Version != "":
+		return fmt.Sprintf("field %s of version %s", d.Field, d.Version)
+	case d.Field != "":
+		return "field " + d.Field
+	case d.Version != "":
+		return "served version " + d.Version
+	}
+	return "the resource"
+}
+
+// ExtractRevision writes the files LoadSnapshot and LoadPolicy read from the
+// tree of rev in the git repository at repo into dst, an existing empty
+// directory, to load a release the working tree is no longer at.
+func ExtractRevision(repo, rev, dst string) error {
+	cmd := exec.Command("git", "-C", repo, "archive", "--format=tar", rev+"^{tree}")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("git archive %s: %w", rev, err)
+	}
+	extractErr := extractTar(out, dst, snapshotInput)
+	// Drain what extractTar left unread so git does not block on a full pipe.
+	_, _ = io.Copy(io.Discard, out)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("git archive %s: %w: %s", rev, err, strings.TrimSpace(stderr.String()))
+	}
+	return extractErr
+}
+
+// snapshotInput reports whether a repo-relative path is read by LoadSnapshot
+// or LoadPolicy.
+func snapshotInput(name string) bool {
+	if name == apiserverGoFile || name == PolicyFile {
+		return true
+	}
+	for _, root := range crdRoots {
+		if strings.HasPrefix(name, root+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// extractTar writes the regular files of a tar stream that keep selects
+// below dst. Links are skipped: the API sources are plain files, and
+// skipping them keeps a crafted archive from writing outside dst.
+func extractTar(r io.Reader, dst string, keep func(name string) bool) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg || !keep(hdr.Name) {
+			continue
+		}
+		name := filepath.FromSlash(hdr.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("archive entry %q escapes the destination", hdr.Name)
+		}
+		path := filepath.Join(dst, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigate
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChangelog(t *testing.T) {
+	obj := Schema{"type": "object"}
+	pgBase := res("apps.cozystack.io", "Postgres", "postgreses", SourceCozyRD, obj)
+	pgHead := res("apps.cozystack.io", "Postgres", "postgreses", SourceCozyRD, obj)
+	pgHead.Versions["v1beta1"] = obj
+	redis := res("apps.cozystack.io", "Redis", "redises", SourceCozyRD, obj)
+
+	old := Deprecation{Group: "apps.cozystack.io", Resource: "redises", Removal: "2026-01-01"}
+	announced := Deprecation{Group: "apps.cozystack.io", Resource: "postgreses", Version: "v1alpha1", Removal: "2027-01-01", Note: "use v1beta1"}
+
+	got := Changelog(snap(pgBase), snap(pgHead, redis),
+		Policy{Deprecations: []Deprecation{old}},
+		Policy{Deprecations: []Deprecation{old, announced}},
+		time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+
+	for _, want := range []struct {
+		category Category
+		detail   string
+	}{
+		{NewResource, "resource redises (Redis) is added"},
+		{NewVersion, `served version "v1beta1" is added`},
+		{Deprecated, "served version v1alpha1 is deprecated and will be removed from 2027-01-01: use v1beta1"},
+	} {
+		group := findingsOf(got, want.category)
+		if len(group) != 1 || !strings.Contains(group[0].Detail, want.detail) {
+			t.Errorf("expected one %s finding containing %q, got %v", want.category, want.detail, got)
+		}
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 findings, got %v", got)
+	}
+	if d := findingsOf(got, Deprecated)[0]; d.Kind != "Postgres" || d.Origin != PolicyFile {
+		t.Errorf("deprecation finding not attributed to the resource and policy: %+v", d)
+	}
+
+	md, err := ChangelogReport(got, "v1.6.0", "markdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"### API changes since v1.6.0", "#### New served version", "#### Newly announced deprecation"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown changelog misses %q:\n%s", want, md)
+		}
+	}
+	if empty, _ := ChangelogReport(nil, "v1.6.0", "text"); empty != "No API changes since v1.6.0.\n" {
+		t.Errorf("empty changelog = %q", empty)
+	}
+}
+
+func TestReportFormats(t *testing.T) {
+	findings := []Finding{
+		{Category: Breaking, Group: "apps.cozystack.io", Kind: "Postgres", Plural: "postgreses", Source: SourceCRD,
+			Origin: "packages/a.yaml, packages/b.yaml", Version: "v1alpha1", Field: "spec.legacy", Detail: `[v1alpha1] spec: field "legacy" was removed`},
+		{Category: PlannedRemoval, Group: "apps.cozystack.io", Kind: "Redis", Plural: "redises", Source: SourceCozyRD,
+			Origin: "packages/c.yaml", Detail: "resource redises (Redis) is removed"},
+	}
+
+	out, err := Report(findings, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		Sizeable bool             `json:"sizeable"`
+		Findings []map[string]any `json:"findings"`
+	}
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("json report does not parse: %v\n%s", err, out)
+	}
+	if !report.Sizeable || len(report.Findings) != 2 || report.Findings[0]["field"] != "spec.legacy" || report.Findings[1]["category"] != "planned-removal" {
+		t.Errorf("unexpected json report:\n%s", out)
+	}
+	if out, _ := Report(nil, "json"); !strings.Contains(out, `"findings": []`) {
+		t.Errorf("json report of no findings must carry an empty list:\n%s", out)
+	}
+
+	out, err = Report(findings, "sarif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sarif sarifLog
+	if err := json.Unmarshal([]byte(out), &sarif); err != nil {
+		t.Fatalf("sarif report does not parse: %v\n%s", err, out)
+	}
+	results := sarif.Runs[0].Results
+	if sarif.Version != "2.1.0" || len(results) != 2 {
+		t.Fatalf("unexpected sarif report:\n%s", out)
+	}
+	if results[0].Level != "error" || results[1].Level != "note" {
+		t.Errorf("sizeable findings must be errors and the rest notes: %+v", results)
+	}
+	if uri := results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "packages/a.yaml" {
+		t.Errorf("sarif location = %q, want the first origin", uri)
+	}
+
+	md, err := Report(findings[1:], "markdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(md, "No sizeable API changes detected.\n\n#### Planned removal") {
+		t.Errorf("planned removals alone must not read as sizeable:\n%s", md)
+	}
+	if _, err := Report(findings, "yaml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestExtractTar(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct{ name, body string }{
+		{"packages/system/foo-rd/cozyrds/foo.yaml", "kind: ApplicationDefinition\n"},
+		{PolicyFile, "deprecations: []\n"},
+		{"docs/release.md", "unrelated\n"},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := t.TempDir()
+	if err := extractTar(bytes.NewReader(buf.Bytes()), dst, snapshotInput); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"packages/system/foo-rd/cozyrds/foo.yaml": true,
+		PolicyFile:        true,
+		"docs/release.md": false,
+	} {
+		_, err := os.Stat(filepath.Join(dst, filepath.FromSlash(name)))
+		if got := err == nil; got != want {
+			t.Errorf("%s extracted = %v, want %v", name, got, want)
+		}
+	}
+
+	buf.Reset()
+	tw = tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "packages/../../escape.yaml", Mode: 0o644, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractTar(bytes.NewReader(buf.Bytes()), dst, snapshotInput); err == nil {
+		t.Error("expected an error for an entry escaping the destination")
+	}
+}
@@ -121,7 +121,7 @@ func Classify(base, head Snapshot) []Finding {
 		if !ok {
 			continue
 		}
-		for _, detail := range diffResource(prev, res) {
+		for _, c := range diffResource(prev, res) {
 			findings = append(findings, Finding{
 				Category: Breaking,
 				Group:    res.Group,
@@ -129,7 +129,11 @@ func Classify(base, head Snapshot) []Finding {
 				Plural:   res.Plural,
 				Source:   res.Source,
 				Origin:   res.Origin,
-				Detail:   detail,
+				Version:  c.version,
+				Field:    c.removedField,
+				Detail:   c.detail,
+
+				versionRemoved: c.versionRemoved,
 			})
 		}
 	}
@@ -137,25 +141,31 @@ func Classify(base, head Snapshot) []Finding {
 	return findings
 }
 
-// diffResource returns human-readable descriptions of every breaking change
-// between the base and head form of one resource: a removed served version, or
-// a breaking schema change within a shared version. New versions are additive
-// and ignored.
-func diffResource(base, head Resource) []string {
-	var out []string
-	versions := make([]string, 0, len(base.Versions))
-	for v := range base.Versions {
-		versions = append(versions, v)
-	}
-	sort.Strings(versions)
-	for _, v := range versions {
+// versionChange is one breaking change to a served version of a resource.
+type versionChange struct {
+	version        string
+	versionRemoved bool
+	schemaChange
+}
+
+// diffResource returns every breaking change between the base and head form
+// of one resource: a removed served version, or a breaking schema change
+// within a shared version. New versions are additive and ignored.
+func diffResource(base, head Resource) []versionChange {
+	var out []versionChange
+	for _, v := range sortedKeys(base.Versions) {
 		headSchema, ok := head.Versions[v]
 		if !ok {
-			out = append(out, fmt.Sprintf("served version %q was removed", v))
+			out = append(out, versionChange{
+				version:        v,
+				versionRemoved: true,
+				schemaChange:   schemaChange{detail: fmt.Sprintf("served version %q was removed", v)},
+			})
 			continue
 		}
-		for _, c := range diffSchema("spec", base.Versions[v], headSchema) {
-			out = append(out, fmt.Sprintf("[%s] %s", v, c))
+		for _, c := range diffSchemaChanges("spec", base.Versions[v], headSchema) {
+			c.detail = fmt.Sprintf("[%s] %s", v, c.detail)
+			out = append(out, versionChange{version: v, schemaChange: c})
 		}
 	}
 	return out
@@ -0,0 +1,303 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	structuraldefaulting "k8s.io/apiextensions-apiserver/pkg/apiserver/schema/defaulting"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+// Instance is a live Application exported from a cluster, as kubectl prints
+// it from the apps.cozystack.io aggregated API.
+type Instance struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Spec      map[string]any
+	// Origin is the file the instance was read from.
+	Origin string
+}
+
+func (i Instance) String() string {
+	return i.Namespace + "/" + i.Name
+}
+
+// LoadInstances reads the Applications in paths: YAML or JSON files, or
+// directories searched for them, each holding objects or Lists such as
+// `kubectl get -o yaml` prints. Objects outside apps.cozystack.io are
+// ignored, so a dump of a whole namespace can be passed as is.
+func LoadInstances(paths []string) ([]Instance, error) {
+	var instances []Instance
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			// A file named on the command line is read whatever its extension.
+			if ext := filepath.Ext(path); path != root && ext != ".yaml" && ext != ".yml" && ext != ".json" {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			found, err := parseInstances(path, data)
+			if err != nil {
+				return err
+			}
+			instances = append(instances, found...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return instances, nil
+}
+
+// exportedObject is the part of an exported object, or of a List of them,
+// that instance validation reads.
+type exportedObject struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+	} `json:"metadata"`
+	Spec  map[string]any   `json:"spec"`
+	Items []exportedObject `json:"items"`
+}
+
+func parseInstances(origin string, data []byte) ([]Instance, error) {
+	var out []Instance
+	var collect func(obj exportedObject)
+	collect = func(obj exportedObject) {
+		if strings.HasSuffix(obj.Kind, "List") {
+			for _, item := range obj.Items {
+				collect(item)
+			}
+			return
+		}
+		if group, _, _ := strings.Cut(obj.APIVersion, "/"); group != appsGroup {
+			return
+		}
+		out = append(out, Instance{
+			Kind:      obj.Kind,
+			Namespace: obj.Metadata.Namespace,
+			Name:      obj.Metadata.Name,
+			Spec:      obj.Spec,
+			Origin:    origin,
+		})
+	}
+	for _, doc := range splitYAMLDocs(data) {
+		if len(strings.TrimSpace(string(doc))) == 0 {
+			continue
+		}
+		var obj exportedObject
+		if err := yaml.Unmarshal(doc, &obj); err != nil {
+			return nil, fmt.Errorf("%s: %w", origin, err)
+		}
+		collect(obj)
+	}
+	return out, nil
+}
+
+// instanceSchema is the head schema of one Application kind, compiled for
+// validating instances.
+type instanceSchema struct {
+	schema    Schema
+	validator validation.SchemaValidator
+	// structural is nil when the schema is not structural, in which case
+	// instances are validated without defaults.
+	structural *structuralschema.Structural
+}
+
+func compileInstanceSchema(s Schema) (*instanceSchema, error) {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	props := &apiextensionsv1.JSONSchemaProps{}
+	if err := json.Unmarshal(raw, props); err != nil {
+		return nil, err
+	}
+	internal := &apiextensions.JSONSchemaProps{}
+	if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(props, internal, nil); err != nil {
+		return nil, err
+	}
+	validator, _, err := validation.NewSchemaValidator(internal)
+	if err != nil {
+		return nil, err
+	}
+	compiled := &instanceSchema{schema: s, validator: validator}
+	if structural, err := structuralschema.NewStructural(internal); err == nil {
+		compiled.structural = structural
+	}
+	return compiled, nil
+}
+
+// ValidateInstances checks live Applications against the head
+// ApplicationDefinition schemas and reports each instance that would fail
+// to update after the upgrade, as an IncompatibleInstance finding per
+// violation. The spec is defaulted first, as the aggregated API does on
+// update, so a new required field with a default is not reported. Values
+// the head schema no longer declares are reported as DroppedValue: they are
+// accepted but no longer do anything.
+func ValidateInstances(head Snapshot, instances []Instance) []Finding {
+	kinds := map[string]Resource{}
+	for _, res := range head {
+		if res.Source == SourceCozyRD {
+			kinds[res.Kind] = res
+		}
+	}
+	compiled := map[string]*instanceSchema{}
+
+	var findings []Finding
+	for _, inst := range sortedInstances(instances) {
+		res, ok := kinds[inst.Kind]
+		if !ok {
+			findings = append(findings, Finding{
+				Category: IncompatibleInstance,
+				Group:    appsGroup,
+				Kind:     inst.Kind,
+				Origin:   inst.Origin,
+				Instance: inst.String(),
+				Detail:   fmt.Sprintf("%s: kind %s is not served by any head ApplicationDefinition", inst, inst.Kind),
+			})
+			continue
+		}
+		finding := func(category Category, fld, detail string) Finding {
+			return Finding{
+				Category: category,
+				Group:    res.Group,
+				Kind:     res.Kind,
+				Plural:   res.Plural,
+				Source:   res.Source,
+				Origin:   res.Origin,
+				Instance: inst.String(),
+				Field:    fld,
+				Detail:   fmt.Sprintf("%s: %s", inst, detail),
+			}
+		}
+		schema, ok := res.Versions["v1alpha1"]
+		if !ok {
+			continue
+		}
+		if _, done := compiled[res.Kind]; !done {
+			c, err := compileInstanceSchema(schema)
+			if err != nil {
+				findings = append(findings, finding(IncompatibleInstance, "", fmt.Sprintf("head schema of %s cannot be compiled: %v", res.Kind, err)))
+			}
+			compiled[res.Kind] = c
+		}
+		c := compiled[res.Kind]
+		if c == nil {
+			continue
+		}
+
+		spec := cloneSpec(inst.Spec)
+		if c.structural != nil {
+			structuraldefaulting.Default(spec, c.structural)
+		}
+		errs := validation.ValidateCustomResource(field.NewPath("spec"), spec, c.validator)
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+		for _, err := range errs {
+			findings = append(findings, finding(IncompatibleInstance, err.Field, err.Error()))
+		}
+		for _, fld := range undeclaredFields("spec", spec, c.schema) {
+			findings = append(findings, finding(DroppedValue, fld, fld+": not declared by the head schema; the value is ignored"))
+		}
+	}
+	return findings
+}
+
+// undeclaredFields returns the paths of the values in v that schema s does
+// not declare, where it declares properties and neither allows other keys
+// with additionalProperties nor preserves unknown fields.
+func undeclaredFields(path string, v any, s Schema) []string {
+	var out []string
+	switch v := v.(type) {
+	case map[string]any:
+		props := schemaProps(s)
+		_, hasAdditional := s["additionalProperties"]
+		open := len(props) == 0 || hasAdditional || schemaBool(s, "x-kubernetes-preserve-unknown-fields")
+		additional, _ := s["additionalProperties"].(map[string]any)
+		for _, key := range sortedKeys(v) {
+			child, ok := props[key]
+			switch {
+			case ok:
+				out = append(out, undeclaredFields(childPath(path, key), v[key], child)...)
+			case additional != nil:
+				out = append(out, undeclaredFields(childPath(path, key), v[key], Schema(additional))...)
+			case !open:
+				out = append(out, childPath(path, key))
+			}
+		}
+	case []any:
+		items, ok := s["items"].(map[string]any)
+		if !ok {
+			return nil
+		}
+		for i, item := range v {
+			out = append(out, undeclaredFields(fmt.Sprintf("%s[%d]", path, i), item, Schema(items))...)
+		}
+	}
+	return out
+}
+
+// cloneSpec deep-copies a spec so defaulting does not alter the instance.
+func cloneSpec(spec map[string]any) map[string]any {
+	out := map[string]any{}
+	if spec == nil {
+		return out
+	}
+	raw, err := json.Marshal(spec)
+	if err == nil {
+		_ = json.Unmarshal(raw, &out)
+	}
+	return out
+}
+
+func sortedInstances(instances []Instance) []Instance {
+	out := append([]Instance(nil), instances...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
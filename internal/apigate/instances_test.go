@@ -0,0 +1,113 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const exportedApps = `apiVersion: v1
+kind: List
+items:
+- apiVersion: apps.cozystack.io/v1alpha1
+  kind: Postgres
+  metadata: {namespace: tenant-root, name: valid}
+  spec: {replicas: 2, users: {app: {readonly: true}}}
+  status: {ready: true}
+- apiVersion: apps.cozystack.io/v1alpha1
+  kind: Postgres
+  metadata: {namespace: tenant-root, name: invalid}
+  spec: {replicas: "two", size: tiny, legacy: true, users: {app: {readonly: "yes"}}}
+- apiVersion: v1
+  kind: ConfigMap
+  metadata: {namespace: tenant-root, name: unrelated}
+---
+apiVersion: apps.cozystack.io/v1alpha1
+kind: Widget
+metadata: {namespace: tenant-foo, name: orphan}
+spec: {}
+`
+
+func TestValidateInstances(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "apps.yaml"), []byte(exportedApps), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not yaml: ["), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	instances, err := LoadInstances([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 3 {
+		t.Fatalf("expected the 3 apps.cozystack.io objects, got %v", instances)
+	}
+
+	// tier is newly required but defaulted, so existing instances still
+	// update.
+	pg := res(appsGroup, "Postgres", "postgreses", SourceCozyRD, mustSchema(t, `{
+		"type": "object",
+		"required": ["tier"],
+		"properties": {
+			"replicas": {"type": "integer"},
+			"size": {"type": "string", "enum": ["small", "large"]},
+			"tier": {"type": "string", "default": "standard"},
+			"users": {"type": "object", "additionalProperties": {
+				"type": "object", "properties": {"readonly": {"type": "boolean"}}
+			}}
+		}
+	}`))
+	got := ValidateInstances(snap(pg), instances)
+
+	byInstance := map[string][]string{}
+	for _, f := range got {
+		byInstance[f.Instance] = append(byInstance[f.Instance], string(f.Category)+" "+f.Field)
+	}
+	if v := byInstance["tenant-root/valid"]; len(v) != 0 {
+		t.Errorf("valid instance reported: %v", v)
+	}
+	want := []string{
+		"incompatible-instance spec.replicas",
+		"incompatible-instance spec.size",
+		"incompatible-instance spec.users.app.readonly",
+		"dropped-value spec.legacy",
+	}
+	if v := strings.Join(byInstance["tenant-root/invalid"], "\n"); v != strings.Join(want, "\n") {
+		t.Errorf("invalid instance findings:\n%s\nwant:\n%s", v, strings.Join(want, "\n"))
+	}
+	if v := byInstance["tenant-foo/orphan"]; len(v) != 1 || v[0] != "incompatible-instance " {
+		t.Errorf("instance of a kind head no longer serves: %v", v)
+	}
+	if !Sizeable(got) {
+		t.Error("incompatible instances must fail the check")
+	}
+	if Sizeable(findingsOf(got, DroppedValue)) {
+		t.Error("dropped values alone must not fail the check")
+	}
+	if _, ok := instances[0].Spec["tier"]; ok {
+		t.Error("defaulting must not alter the loaded instance")
+	}
+
+	report, err := InstancesReport(nil, 3, "text")
+	if err != nil || report != "All 3 Application instance(s) validate against the head schemas.\n" {
+		t.Errorf("report of no findings = %q, %v", report, err)
+	}
+}
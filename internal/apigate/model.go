@@ -22,8 +22,9 @@ limitations under the License.
 // that define the served API surface, with no network or LLM calls.
 package apigate
 
-// Category classifies why a change is sizeable. The three values mirror the
-// gate's contract exactly.
+// Category classifies an API change. Every category is sizeable except
+// PlannedRemoval, the changelog-only NewVersion and Deprecated, and
+// DroppedValue.
 type Category string
 
 const (
@@ -44,8 +45,37 @@ const (
 	// gone from head — the most disruptive single-resource change, since every
 	// stored object and client of that resource breaks.
 	RemovedResource Category = "removed-resource"
+	// PlannedRemoval is a removed served version, field or resource that the
+	// deprecation policy announced and whose removal date has been reached.
+	// It is reported but not sizeable.
+	PlannedRemoval Category = "planned-removal"
+	// NewVersion is a served version added to an existing resource. Additive,
+	// so only the changelog reports it.
+	NewVersion Category = "new-version"
+	// Deprecated is a removal newly announced in the deprecation policy,
+	// reported only by the changelog.
+	Deprecated Category = "deprecation"
+	// IncompatibleInstance is a live Application whose spec the head
+	// schema of its kind rejects, so it would fail to update after the
+	// upgrade.
+	IncompatibleInstance Category = "incompatible-instance"
+	// DroppedValue is a value set on a live Application that the head
+	// schema of its kind no longer declares. It is still accepted, but
+	// silently does nothing.
+	DroppedValue Category = "dropped-value"
 )
 
+// Sizeable reports whether any finding requires an API owner review.
+func Sizeable(findings []Finding) bool {
+	for _, f := range findings {
+		switch f.Category {
+		case NewGroup, NewResource, RemovedGroup, RemovedResource, Breaking, IncompatibleInstance:
+			return true
+		}
+	}
+	return false
+}
+
 // Source identifies which checked-in artifact a resource was parsed from.
 // It is carried through to findings so the report points reviewers at the
 // right file family.
@@ -119,13 +149,25 @@ func (s Snapshot) groups() map[string]struct{} {
 // Finding is one reason a change is sizeable, ready to render into the CI
 // report.
 type Finding struct {
-	Category Category
-	Group    string
-	Kind     string
-	Plural   string
-	Source   Source
-	Origin   string
+	Category Category `json:"category"`
+	Group    string   `json:"group"`
+	Kind     string   `json:"kind,omitempty"`
+	Plural   string   `json:"plural,omitempty"`
+	Source   Source   `json:"source,omitempty"`
+	Origin   string   `json:"origin"`
+	// Instance is the namespace/name of the live Application an
+	// IncompatibleInstance or DroppedValue finding is about.
+	Instance string `json:"instance,omitempty"`
+	// Version is the served version a Breaking finding applies to.
+	Version string `json:"version,omitempty"`
+	// Field is the schema path of the removed field when a Breaking finding
+	// is a field removal, and the path of the offending value in an
+	// instance finding.
+	Field string `json:"field,omitempty"`
 	// Detail is a human-readable explanation. For Breaking findings it names
 	// the schema path and the nature of the break.
-	Detail string
+	Detail string `json:"detail"`
+
+	// versionRemoved marks a Breaking finding for the removal of Version.
+	versionRemoved bool
 }
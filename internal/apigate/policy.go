@@ -0,0 +1,160 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// PolicyFile is the repo-relative path of the deprecation policy.
+const PolicyFile = "api/deprecations.yaml"
+
+// policyDateLayout is the layout of Deprecation.Removal.
+const policyDateLayout = "2006-01-02"
+
+// Policy is the checked-in deprecation policy: removals announced ahead of
+// time, which the gate lets through without an API owner review once their
+// date has come.
+type Policy struct {
+	Deprecations []Deprecation `json:"deprecations"`
+}
+
+// Deprecation announces the removal of a resource, one of its served
+// versions, or a field of its schema. Version and Field narrow the entry:
+// with neither it covers removing the whole resource, with Version alone
+// removing that served version, and with Field removing that field (from
+// every version unless Version is set too).
+type Deprecation struct {
+	Group string `json:"group"`
+	// Resource is the plural resource name, as in the gate's report.
+	Resource string `json:"resource"`
+	Version  string `json:"version,omitempty"`
+	// Field is the schema path the gate reports, e.g. spec.backup.s3Bucket.
+	Field string `json:"field,omitempty"`
+	// Removal is the date (YYYY-MM-DD) from which the removal may ship.
+	Removal string `json:"removal"`
+	// Announced is the release that announced the deprecation.
+	Announced string `json:"announced,omitempty"`
+	// Note tells users what to migrate to; it goes into the changelog.
+	Note string `json:"note,omitempty"`
+}
+
+// LoadPolicy reads the deprecation policy of the checkout rooted at dir. A
+// checkout without one has an empty policy.
+func LoadPolicy(dir string) (Policy, error) {
+	path := filepath.Join(dir, filepath.FromSlash(PolicyFile))
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Policy{}, nil
+	}
+	if err != nil {
+		return Policy{}, err
+	}
+	policy, err := ParsePolicy(data)
+	if err != nil {
+		return Policy{}, fmt.Errorf("%s: %w", PolicyFile, err)
+	}
+	return policy, nil
+}
+
+// ParsePolicy parses and validates a deprecation policy. Unknown fields are
+// rejected so that a misspelt key cannot widen an entry unnoticed.
+func ParsePolicy(data []byte) (Policy, error) {
+	var policy Policy
+	if err := sigsyaml.UnmarshalStrict(data, &policy); err != nil {
+		return Policy{}, err
+	}
+	for i, d := range policy.Deprecations {
+		if d.Group == "" || d.Resource == "" {
+			return Policy{}, fmt.Errorf("deprecations[%d]: group and resource are required", i)
+		}
+		if _, err := time.Parse(policyDateLayout, d.Removal); err != nil {
+			return Policy{}, fmt.Errorf("deprecations[%d] (%s): removal must be a YYYY-MM-DD date: %w", i, d, err)
+		}
+	}
+	return policy, nil
+}
+
+// String identifies what the entry deprecates.
+func (d Deprecation) String() string {
+	s := d.Group + "/" + d.Resource
+	if d.Version != "" {
+		s += " " + d.Version
+	}
+	if d.Field != "" {
+		s += " " + d.Field
+	}
+	return s
+}
+
+// due reports whether the removal date has been reached on now's date.
+func (d Deprecation) due(now time.Time) bool {
+	removal, err := time.Parse(policyDateLayout, d.Removal)
+	if err != nil {
+		return false
+	}
+	return !now.UTC().Before(removal)
+}
+
+// covers reports whether the entry announces the removal f reports.
+// Removing a whole group is never covered: it takes an API owner's review
+// even when each of its resources was announced.
+func (d Deprecation) covers(f Finding) bool {
+	if d.Group != f.Group || d.Resource != f.Plural {
+		return false
+	}
+	switch {
+	case f.Category == RemovedResource:
+		return d.Version == "" && d.Field == ""
+	case f.Category != Breaking:
+		return false
+	case f.Field != "":
+		return d.Field == f.Field && (d.Version == "" || d.Version == f.Version)
+	case f.versionRemoved:
+		return d.Field == "" && d.Version == f.Version
+	}
+	return false
+}
+
+// ApplyPolicy matches removals among findings against the policy. A removal
+// announced by an entry whose date has been reached becomes a PlannedRemoval;
+// one announced for a later date stays sizeable, its detail saying when it
+// will be allowed. Other findings are returned as they are.
+func ApplyPolicy(findings []Finding, policy Policy, now time.Time) []Finding {
+	out := make([]Finding, 0, len(findings))
+	for _, f := range findings {
+		for _, d := range policy.Deprecations {
+			if !d.covers(f) {
+				continue
+			}
+			if d.due(now) {
+				f.Category = PlannedRemoval
+				f.Detail += fmt.Sprintf(" (deprecation policy: removal planned from %s)", d.Removal)
+			} else {
+				f.Detail += fmt.Sprintf(" (deprecation policy: removal not allowed before %s)", d.Removal)
+			}
+			break
+		}
+		out = append(out, f)
+	}
+	return out
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigate
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			policy: `deprecations:
+- group: apps.cozystack.io
+  resource: postgreses
+  field: spec.legacy
+  removal: "2026-12-01"
+  announced: v1.6.0
+  note: use spec.modern instead
+`,
+		},
+		{
+			name:    "unknown key",
+			policy:  "deprecations:\n- group: apps.cozystack.io\n  resource: postgreses\n  versions: v1alpha1\n  removal: \"2026-12-01\"\n",
+			wantErr: "unknown field",
+		},
+		{
+			name:    "missing resource",
+			policy:  "deprecations:\n- group: apps.cozystack.io\n  removal: \"2026-12-01\"\n",
+			wantErr: "group and resource are required",
+		},
+		{
+			name:    "bad date",
+			policy:  "deprecations:\n- group: apps.cozystack.io\n  resource: postgreses\n  removal: next year\n",
+			wantErr: "YYYY-MM-DD",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParsePolicy([]byte(tc.policy))
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestApplyPolicy(t *testing.T) {
+	withVersions := func(r Resource, versions map[string]Schema) Resource {
+		r.Versions = versions
+		return r
+	}
+	obj := Schema{"type": "object"}
+	legacy := Schema{"type": "object", "properties": map[string]any{"legacy": map[string]any{"type": "string"}}}
+	pgBase := withVersions(res("apps.cozystack.io", "Postgres", "postgreses", SourceCRD, nil),
+		map[string]Schema{"v1alpha1": legacy, "v1beta1": legacy})
+	other := res("apps.cozystack.io", "Redis", "redises", SourceCozyRD, obj)
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	policy := func(d Deprecation) Policy { return Policy{Deprecations: []Deprecation{d}} }
+
+	tests := []struct {
+		name         string
+		base, head   Snapshot
+		policy       Policy
+		wantCategory Category
+		wantDetail   string
+	}{
+		{
+			name:         "due version removal is planned",
+			base:         snap(pgBase),
+			head:         snap(withVersions(pgBase, map[string]Schema{"v1beta1": legacy})),
+			policy:       policy(Deprecation{Group: "apps.cozystack.io", Resource: "postgreses", Version: "v1alpha1", Removal: "2026-10-19"}),
+			wantCategory: PlannedRemoval,
+			wantDetail:   "removal planned from 2026-10-19",
+		},
+		{
+			name:         "removal before its date stays breaking",
+			base:         snap(pgBase),
+			head:         snap(withVersions(pgBase, map[string]Schema{"v1beta1": legacy})),
+			policy:       policy(Deprecation{Group: "apps.cozystack.io", Resource: "postgreses", Version: "v1alpha1", Removal: "2026-10-20"}),
+			wantCategory: Breaking,
+			wantDetail:   "removal not allowed before 2026-10-20",
+		},
+		{
+			name:         "entry for another version does not match",
+			base:         snap(pgBase),
+			head:         snap(withVersions(pgBase, map[string]Schema{"v1beta1": legacy})),
+			policy:       policy(Deprecation{Group: "apps.cozystack.io", Resource: "postgreses", Version: "v1beta1", Removal: "2026-01-01"}),
+			wantCategory: Breaking,
+		},
+		{
+			name:         "field entry covers the field in every version",
+			base:         snap(withVersions(pgBase, map[string]Schema{"v1alpha1": legacy})),
+			head:         snap(withVersions(pgBase, map[string]Schema{"v1alpha1": obj})),
+			policy:       policy(Deprecation{Group: "apps.cozystack.io", Resource: "postgreses", Field: "spec.legacy", Removal: "2026-01-01"}),
+			wantCategory: PlannedRemoval,
+		},
+		{
+			name:         "field entry does not cover other breaking changes",
+			base:         snap(withVersions(pgBase, map[string]Schema{"v1alpha1": legacy})),
+			head:         snap(withVersions(pgBase, map[string]Schema{"v1alpha1": {"type": "object", "properties": map[string]any{"legacy": map[string]any{"type": "integer"}}}})),
+			policy:       policy(Deprecation{Group: "apps.cozystack.io", Resource: "postgreses", Field: "spec.legacy", Removal: "2026-01-01"}),
+			wantCategory: Breaking,
+		},
+		{
+			name:         "version entry does not cover a schema change within it",
+			base:         snap(withVersions(pgBase, map[string]Schema{"v1alpha1": legacy})),
+			head:         snap(withVersions(pgBase, map[string]Schema{"v1alpha1": obj})),
+			policy:       policy(Deprecation{Group: "apps.cozystack.io", Resource: "postgreses", Version: "v1alpha1", Removal: "2026-01-01"}),
+			wantCategory: Breaking,
+		},
+		{
+			name:         "resource removal",
+			base:         snap(pgBase, other),
+			head:         snap(other),
+			policy:       policy(Deprecation{Group: "apps.cozystack.io", Resource: "postgreses", Removal: "2026-01-01"}),
+			wantCategory: PlannedRemoval,
+		},
+		{
+			name:         "group removal is never covered",
+			base:         snap(pgBase),
+			head:         snap(res("other.cozystack.io", "Widget", "widgets", SourceCRD, obj)),
+			policy:       policy(Deprecation{Group: "apps.cozystack.io", Resource: "postgreses", Removal: "2026-01-01"}),
+			wantCategory: RemovedGroup,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ApplyPolicy(Classify(tc.base, tc.head), tc.policy, now)
+			var matched []Finding
+			for _, f := range got {
+				if f.Group == "apps.cozystack.io" {
+					matched = append(matched, f)
+				}
+			}
+			if len(matched) != 1 {
+				t.Fatalf("expected 1 finding for apps.cozystack.io, got %v", got)
+			}
+			if matched[0].Category != tc.wantCategory {
+				t.Fatalf("expected category %s, got %v", tc.wantCategory, matched[0])
+			}
+			if !strings.Contains(matched[0].Detail, tc.wantDetail) {
+				t.Fatalf("expected detail containing %q, got %q", tc.wantDetail, matched[0].Detail)
+			}
+			if Sizeable(matched) != (tc.wantCategory != PlannedRemoval) {
+				t.Fatalf("Sizeable(%v) = %v", matched, Sizeable(matched))
+			}
+		})
+	}
+}
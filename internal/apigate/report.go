@@ -17,6 +17,7 @@ limitations under the License.
 package apigate
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -28,26 +29,88 @@ var categoryTitles = map[Category]string{
 	RemovedGroup:    "Removed API group",
 	RemovedResource: "Removed resource",
 	Breaking:        "Breaking change to existing API",
+	PlannedRemoval:  "Planned removal (deprecation policy)",
+	NewVersion:      "New served version",
+	Deprecated:      "Newly announced deprecation",
+
+	IncompatibleInstance: "Application failing the head schema",
+	DroppedValue:         "Application value no longer declared",
 }
 
 // categoryOrder is the stable order categories are rendered in.
-var categoryOrder = []Category{NewGroup, NewResource, RemovedGroup, RemovedResource, Breaking}
+var categoryOrder = []Category{
+	NewGroup, NewResource, RemovedGroup, RemovedResource, Breaking, PlannedRemoval, NewVersion, Deprecated,
+	IncompatibleInstance, DroppedValue,
+}
 
-// Report renders findings for CI consumption. format "markdown" (default)
-// produces a PR-comment-friendly summary; any other value produces plain text.
-func Report(findings []Finding, format string) string {
-	if len(findings) == 0 {
-		return "No sizeable API changes detected.\n"
-	}
-	var b strings.Builder
-	md := format != "text"
+// Formats are the report formats Report and ChangelogReport accept.
+var Formats = []string{"markdown", "text", "json", "sarif"}
 
-	if md {
-		b.WriteString("### Sizeable API change detected\n\n")
-		b.WriteString("This change touches the API surface in a way that requires review from a designated API owner.\n\n")
-	} else {
-		b.WriteString("Sizeable API change detected:\n\n")
+// Report renders the gate's findings for CI consumption. format "markdown"
+// produces a PR-comment-friendly summary and "text" plain text; "json" and
+// "sarif" (2.1.0, for code scanning) are meant for tooling.
+func Report(findings []Finding, format string) (string, error) {
+	return render(findings, format, func(md bool) string {
+		switch {
+		case len(findings) == 0:
+			return "No sizeable API changes detected.\n"
+		case !Sizeable(findings):
+			return "No sizeable API changes detected.\n\n"
+		case md:
+			return "### Sizeable API change detected\n\n" +
+				"This change touches the API surface in a way that requires review from a designated API owner.\n\n"
+		default:
+			return "Sizeable API change detected:\n\n"
+		}
+	})
+}
+
+// ChangelogReport renders the findings of Changelog as upgrade notes for
+// the releases after since, in the same formats as Report.
+func ChangelogReport(findings []Finding, since, format string) (string, error) {
+	return render(findings, format, func(md bool) string {
+		switch {
+		case len(findings) == 0:
+			return fmt.Sprintf("No API changes since %s.\n", since)
+		case md:
+			return fmt.Sprintf("### API changes since %s\n\n", since)
+		default:
+			return fmt.Sprintf("API changes since %s:\n\n", since)
+		}
+	})
+}
+
+// InstancesReport renders the findings of ValidateInstances for the count
+// instances checked, in the same formats as Report.
+func InstancesReport(findings []Finding, count int, format string) (string, error) {
+	return render(findings, format, func(md bool) string {
+		switch {
+		case len(findings) == 0:
+			return fmt.Sprintf("All %d Application instance(s) validate against the head schemas.\n", count)
+		case !Sizeable(findings):
+			return fmt.Sprintf("All %d Application instance(s) validate against the head schemas.\n\n", count)
+		case md:
+			return fmt.Sprintf("### Application instances incompatible with the head schemas\n\n"+
+				"Checked %d instance(s); those below would fail to update after the upgrade.\n\n", count)
+		default:
+			return fmt.Sprintf("Application instances incompatible with the head schemas (checked %d):\n\n", count)
+		}
+	})
+}
+
+func render(findings []Finding, format string, heading func(md bool) string) (string, error) {
+	switch format {
+	case "json":
+		return reportJSON(findings)
+	case "sarif":
+		return reportSARIF(findings)
+	case "markdown", "text":
+	default:
+		return "", fmt.Errorf("unknown report format %q (want one of %s)", format, strings.Join(Formats, ", "))
 	}
+	md := format == "markdown"
+	var b strings.Builder
+	b.WriteString(heading(md))
 
 	for _, cat := range categoryOrder {
 		group := findingsOf(findings, cat)
@@ -60,7 +123,10 @@ func Report(findings []Finding, format string) string {
 			fmt.Fprintf(&b, "%s:\n", categoryTitles[cat])
 		}
 		for _, f := range group {
-			line := fmt.Sprintf("%s %s — %s (%s)", identity(f, md), f.Detail, f.Origin, f.Source)
+			line := fmt.Sprintf("%s %s — %s", identity(f, md), f.Detail, f.Origin)
+			if f.Source != "" {
+				line += fmt.Sprintf(" (%s)", f.Source)
+			}
 			if md {
 				fmt.Fprintf(&b, "- %s\n", line)
 			} else {
@@ -69,7 +135,106 @@ func Report(findings []Finding, format string) string {
 		}
 		b.WriteString("\n")
 	}
-	return b.String()
+	return b.String(), nil
+}
+
+// jsonReport is the "json" report format.
+type jsonReport struct {
+	Sizeable bool      `json:"sizeable"`
+	Findings []Finding `json:"findings"`
+}
+
+func reportJSON(findings []Finding) (string, error) {
+	if findings == nil {
+		findings = []Finding{}
+	}
+	out, err := json.MarshalIndent(jsonReport{Sizeable: Sizeable(findings), Findings: findings}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out) + "\n", nil
+}
+
+// The subset of SARIF 2.1.0 the "sarif" report format uses: one rule per
+// category and one result per finding, located at the file the resource was
+// parsed from.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations,omitempty"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+		} `json:"physicalLocation"`
+	}
+)
+
+func reportSARIF(findings []Finding) (string, error) {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "api-gate"}},
+		Results: []sarifResult{},
+	}
+	for _, cat := range categoryOrder {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID: string(cat), ShortDescription: sarifMessage{Text: categoryTitles[cat]},
+		})
+	}
+	for _, f := range findings {
+		level := "note"
+		if Sizeable([]Finding{f}) {
+			level = "error"
+		}
+		result := sarifResult{
+			RuleID:  string(f.Category),
+			Level:   level,
+			Message: sarifMessage{Text: identity(f, false) + " " + f.Detail},
+		}
+		// APIService-backed groups merge several files into Origin; the
+		// first is where the group is declared.
+		if origin, _, _ := strings.Cut(f.Origin, ", "); origin != "" {
+			var loc sarifLocation
+			loc.PhysicalLocation.ArtifactLocation.URI = origin
+			result.Locations = []sarifLocation{loc}
+		}
+		run.Results = append(run.Results, result)
+	}
+	out, err := json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out) + "\n", nil
 }
 
 // identity renders the resource identity, emphasized only in Markdown mode so
@@ -33,13 +33,31 @@ import (
 // pattern), the change is treated as breaking rather than risk waving through
 // a real incompatibility.
 func diffSchema(path string, base, head Schema) []string {
-	var out []string
+	changes := diffSchemaChanges(path, base, head)
+	out := make([]string, len(changes))
+	for i, c := range changes {
+		out[i] = c.detail
+	}
+	return out
+}
+
+// schemaChange is one breaking difference found by diffNode.
+type schemaChange struct {
+	detail string
+	// removedField is the path of the removed field when the change is a
+	// field removal, which a deprecation policy entry can announce.
+	removedField string
+}
+
+// diffSchemaChanges is diffSchema keeping which changes remove a field.
+func diffSchemaChanges(path string, base, head Schema) []schemaChange {
+	var out []schemaChange
 	diffNode(path, base, head, &out)
-	sort.Strings(out)
+	sort.Slice(out, func(i, j int) bool { return out[i].detail < out[j].detail })
 	return out
 }
 
-func diffNode(path string, base, head Schema, out *[]string) {
+func diffNode(path string, base, head Schema, out *[]schemaChange) {
 	// A nil head means the schema at this path was removed entirely. Dropping a
 	// constraint is a widening, never a break, so there is nothing to report.
 	// Callers avoid handing us a nil head for genuine field/version removals
@@ -56,10 +74,10 @@ func diffNode(path string, base, head Schema, out *[]string) {
 	bt, ht := schemaTypes(base), schemaTypes(head)
 	switch {
 	case len(bt) == 0 && len(ht) > 0:
-		*out = append(*out, fmt.Sprintf("%s: type constraint added, now only accepts %s", path, strings.Join(sortedKeys(ht), "/")))
+		*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: type constraint added, now only accepts %s", path, strings.Join(sortedKeys(ht), "/"))})
 	case len(bt) > 0 && len(ht) > 0:
 		if removed := setDiff(bt, ht); len(removed) > 0 {
-			*out = append(*out, fmt.Sprintf("%s: type narrowed, no longer accepts %s", path, strings.Join(removed, "/")))
+			*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: type narrowed, no longer accepts %s", path, strings.Join(removed, "/"))})
 		}
 	}
 
@@ -69,10 +87,10 @@ func diffNode(path string, base, head Schema, out *[]string) {
 	he, hasHeadEnum := schemaEnum(head)
 	switch {
 	case !hasBaseEnum && hasHeadEnum:
-		*out = append(*out, fmt.Sprintf("%s: enum constraint added (previously unrestricted)", path))
+		*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: enum constraint added (previously unrestricted)", path)})
 	case hasBaseEnum && hasHeadEnum:
 		if removed := setDiff(be, he); len(removed) > 0 {
-			*out = append(*out, fmt.Sprintf("%s: enum value(s) removed: %s", path, strings.Join(removed, ", ")))
+			*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: enum value(s) removed: %s", path, strings.Join(removed, ", "))})
 		}
 	}
 
@@ -82,9 +100,9 @@ func diffNode(path string, base, head Schema, out *[]string) {
 	hp := schemaString(head, "pattern")
 	if hp != "" && hp != bp {
 		if bp == "" {
-			*out = append(*out, fmt.Sprintf("%s: pattern constraint added (%q)", path, hp))
+			*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: pattern constraint added (%q)", path, hp)})
 		} else {
-			*out = append(*out, fmt.Sprintf("%s: pattern changed (%q -> %q)", path, bp, hp))
+			*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: pattern changed (%q -> %q)", path, bp, hp)})
 		}
 	}
 
@@ -103,7 +121,7 @@ func diffNode(path string, base, head Schema, out *[]string) {
 	baseReq, headReq := schemaRequired(base), schemaRequired(head)
 	for _, name := range sortedKeys(headReq) {
 		if !baseReq[name] {
-			*out = append(*out, fmt.Sprintf("%s: field %q is now required", path, name))
+			*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: field %q is now required", path, name)})
 		}
 	}
 
@@ -112,14 +130,14 @@ func diffNode(path string, base, head Schema, out *[]string) {
 	// rule is a relaxation and is safe, mirroring how pattern/enum are treated.
 	baseRules, headRules := schemaValidationRules(base), schemaValidationRules(head)
 	for _, rule := range setDiff(headRules, baseRules) {
-		*out = append(*out, fmt.Sprintf("%s: validation rule added (%q)", path, rule))
+		*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: validation rule added (%q)", path, rule)})
 	}
 
 	// nullable: Kubernetes expresses "may be null" via nullable:true rather than
 	// a "null" entry in the type set. Dropping it rejects previously-valid null
 	// values, so a true -> false/absent transition is breaking.
 	if schemaBool(base, "nullable") && !schemaBool(head, "nullable") {
-		*out = append(*out, fmt.Sprintf("%s: no longer nullable (null values rejected)", path))
+		*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: no longer nullable (null values rejected)", path)})
 	}
 
 	// Composition keywords (oneOf/allOf/not): introducing one where base had
@@ -131,7 +149,7 @@ func diffNode(path string, base, head Schema, out *[]string) {
 			continue
 		}
 		if _, inHead := head[kw]; inHead {
-			*out = append(*out, fmt.Sprintf("%s: %s composition constraint added", path, kw))
+			*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: %s composition constraint added", path, kw)})
 		}
 	}
 
@@ -142,7 +160,7 @@ func diffNode(path string, base, head Schema, out *[]string) {
 		child := childPath(path, name)
 		hp, ok := headProps[name]
 		if !ok {
-			*out = append(*out, fmt.Sprintf("%s: field %q was removed", path, name))
+			*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: field %q was removed", path, name), removedField: child})
 			continue
 		}
 		diffNode(child, baseProps[name], hp, out)
@@ -159,7 +177,7 @@ func diffNode(path string, base, head Schema, out *[]string) {
 	//   - open -> true/absent:  relaxation, safe.
 	baseOpenAP := base["additionalProperties"] != false
 	if baseOpenAP && head["additionalProperties"] == false {
-		*out = append(*out, fmt.Sprintf("%s: additionalProperties restricted to false (undeclared fields no longer accepted)", path))
+		*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: additionalProperties restricted to false (undeclared fields no longer accepted)", path)})
 	} else if baseOpenAP {
 		if b, h, ok := childSchemas(base, head, "additionalProperties"); ok {
 			diffNode(path+"{}", b, h, out)
@@ -177,31 +195,31 @@ func diffNode(path string, base, head Schema, out *[]string) {
 
 // diffLowerBound flags a lower-bound (minimum/minLength/minItems) that was
 // added or raised — both reject values the base accepted.
-func diffLowerBound(path, key string, base, head Schema, out *[]string) {
+func diffLowerBound(path, key string, base, head Schema, out *[]schemaChange) {
 	bv, hasB := schemaNumber(base, key)
 	hv, hasH := schemaNumber(head, key)
 	if !hasH {
 		return
 	}
 	if !hasB {
-		*out = append(*out, fmt.Sprintf("%s: %s constraint added (%s)", path, key, formatNum(hv)))
+		*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: %s constraint added (%s)", path, key, formatNum(hv))})
 	} else if hv > bv {
-		*out = append(*out, fmt.Sprintf("%s: %s raised (%s -> %s)", path, key, formatNum(bv), formatNum(hv)))
+		*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: %s raised (%s -> %s)", path, key, formatNum(bv), formatNum(hv))})
 	}
 }
 
 // diffUpperBound flags an upper-bound (maximum/maxLength/maxItems) that was
 // added or lowered.
-func diffUpperBound(path, key string, base, head Schema, out *[]string) {
+func diffUpperBound(path, key string, base, head Schema, out *[]schemaChange) {
 	bv, hasB := schemaNumber(base, key)
 	hv, hasH := schemaNumber(head, key)
 	if !hasH {
 		return
 	}
 	if !hasB {
-		*out = append(*out, fmt.Sprintf("%s: %s constraint added (%s)", path, key, formatNum(hv)))
+		*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: %s constraint added (%s)", path, key, formatNum(hv))})
 	} else if hv < bv {
-		*out = append(*out, fmt.Sprintf("%s: %s lowered (%s -> %s)", path, key, formatNum(bv), formatNum(hv)))
+		*out = append(*out, schemaChange{detail: fmt.Sprintf("%s: %s lowered (%s -> %s)", path, key, formatNum(bv), formatNum(hv))})
 	}
 }
 
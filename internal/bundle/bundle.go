@@ -0,0 +1,734 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bundle moves PackageSources into air-gapped clusters.
+//
+// A bundle is a tar archive of an OCI image layout. It holds one Flux OCI
+// artifact per PackageSource with the charts, libraries and values files
+// its variants use, every container image those values files reference,
+// and a checksum manifest (cozystack-bundle.json) listing the digest of
+// each artifact, each file in it and each image. Import pushes all of it
+// into a local registry and points the PackageSources at the pushed
+// artifacts, pinned by digest.
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	"github.com/cozystack/cozystack/internal/operator"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ManifestFile is the checksum manifest at the root of the layout.
+	ManifestFile = "cozystack-bundle.json"
+
+	// ManifestVersion is the version of the checksum manifest format.
+	ManifestVersion = 1
+
+	// AnnotationRefName names an image in index.json by its original
+	// reference.
+	AnnotationRefName = "org.opencontainers.image.ref.name"
+	// AnnotationPackageSource names the PackageSource an artifact in
+	// index.json belongs to.
+	AnnotationPackageSource = "io.cozystack.bundle.packagesource"
+
+	// Media types of a Flux OCI artifact, as written by flux push artifact.
+	fluxConfigMediaType  = "application/vnd.cncf.flux.config.v1+json"
+	fluxContentMediaType = "application/vnd.cncf.flux.content.v1.tar+gzip"
+
+	// sourceRepository is where import pushes the PackageSource artifacts,
+	// under the registry prefix.
+	sourceRepository = "cozystack/packagesources"
+)
+
+// Manifest is the checksum manifest of a bundle.
+type Manifest struct {
+	Version        int           `json:"version"`
+	PackageSources []SourceEntry `json:"packageSources"`
+	Images         []ImageEntry  `json:"images"`
+}
+
+// SourceEntry is the artifact of one PackageSource.
+type SourceEntry struct {
+	// Name is the name of the PackageSource.
+	Name string `json:"name"`
+	// Digest is the digest of the artifact's OCI manifest. Import pins the
+	// OCIRepository to it and the PackageSource controller checks the
+	// fetched artifact against it.
+	Digest string `json:"digest"`
+	// Files maps the path of every file in the artifact to its sha256
+	// digest.
+	Files map[string]string `json:"files"`
+	// PackageSource is the PackageSource as exported, without cluster
+	// metadata or status.
+	PackageSource *cozyv1alpha1.PackageSource `json:"packageSource"`
+}
+
+// ImageEntry is one container image.
+type ImageEntry struct {
+	// Reference is the image as the values files name it.
+	Reference string `json:"reference"`
+	// Digest is the digest of its manifest or index.
+	Digest    string `json:"digest"`
+	MediaType string `json:"mediaType"`
+}
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	// SourceDir is a local copy of what the PackageSources' sourceRef points
+	// at: the checkout of the GitRepository or the unpacked OCIRepository.
+	SourceDir string
+	// PackageSources are exported in name order.
+	PackageSources []*cozyv1alpha1.PackageSource
+	// Images are exported in addition to those the values files reference.
+	Images []string
+	// ExcludeImages are path.Match patterns of image references that are
+	// not exported.
+	ExcludeImages []string
+	// Registry returns the client that images on host are pulled with;
+	// defaults to an anonymous NewRegistry.
+	Registry func(host string) *Registry
+	// Log, when set, receives a line per exported artifact and image.
+	Log io.Writer
+}
+
+func (o *ExportOptions) logf(format string, args ...interface{}) {
+	if o.Log != nil {
+		fmt.Fprintf(o.Log, format+"\n", args...)
+	}
+}
+
+// Export writes the bundle of opts.PackageSources to w and returns its
+// checksum manifest.
+func Export(ctx context.Context, w io.Writer, opts ExportOptions) (*Manifest, error) {
+	if opts.Registry == nil {
+		opts.Registry = NewRegistry
+	}
+	sources := append([]*cozyv1alpha1.PackageSource(nil), opts.PackageSources...)
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name < sources[j].Name })
+
+	lw := newLayoutWriter(w)
+	m := &Manifest{Version: ManifestVersion}
+	images := map[string]bool{}
+	for _, image := range opts.Images {
+		images[image] = true
+	}
+
+	for _, ps := range sources {
+		files, err := sourceFiles(opts.SourceDir, ps)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := writeSourceArtifact(lw, ps, files)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export PackageSource %s: %w", ps.Name, err)
+		}
+		m.PackageSources = append(m.PackageSources, *entry)
+		opts.logf("✓ Exported PackageSource %s (%d files)", ps.Name, len(entry.Files))
+
+		for _, rel := range sortedKeys(files) {
+			if !isValuesFile(rel) {
+				continue
+			}
+			data, err := os.ReadFile(files[rel])
+			if err != nil {
+				return nil, err
+			}
+			for _, image := range ImagesInValues(data) {
+				images[image] = true
+			}
+		}
+	}
+
+	cache := map[string]*Registry{}
+	for _, image := range sortedKeys(images) {
+		if excluded(image, opts.ExcludeImages) {
+			continue
+		}
+		ref, err := ParseReference(image)
+		if err != nil {
+			return nil, err
+		}
+		reg := cache[ref.Registry]
+		if reg == nil {
+			reg = opts.Registry(ref.Registry)
+			cache[ref.Registry] = reg
+		}
+		desc, err := copyImage(ctx, lw, reg, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export image %s: %w", image, err)
+		}
+		desc.Annotations = map[string]string{AnnotationRefName: image}
+		lw.addManifest(desc)
+		m.Images = append(m.Images, ImageEntry{Reference: image, Digest: desc.Digest, MediaType: desc.MediaType})
+		opts.logf("✓ Exported image %s", image)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := lw.close(map[string][]byte{ManifestFile: data}); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return m, nil
+}
+
+// sourceFiles maps every file the variants of ps use, relative to the
+// source's base path, to its location under dir.
+func sourceFiles(dir string, ps *cozyv1alpha1.PackageSource) (map[string]string, error) {
+	if ps.Spec.SourceRef == nil {
+		return nil, fmt.Errorf("PackageSource %s has no sourceRef", ps.Name)
+	}
+	base := filepath.Join(dir, filepath.FromSlash(operator.SourceBasePath(ps.Spec.SourceRef)))
+
+	var roots []string
+	for _, variant := range ps.Spec.Variants {
+		for _, lib := range variant.Libraries {
+			roots = append(roots, lib.Path)
+		}
+		for _, component := range variant.Components {
+			if component.Path != "" {
+				roots = append(roots, component.Path)
+			}
+		}
+	}
+
+	files := map[string]string{}
+	for _, root := range roots {
+		root = strings.Trim(path.Clean("/"+root), "/")
+		if root == "" {
+			return nil, fmt.Errorf("PackageSource %s: refusing to export the whole source", ps.Name)
+		}
+		rootDir := filepath.Join(base, filepath.FromSlash(root))
+		if _, err := os.Stat(rootDir); err != nil {
+			return nil, fmt.Errorf("PackageSource %s: %w", ps.Name, err)
+		}
+		err := filepath.WalkDir(rootDir, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := os.Stat(p)
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(base, p)
+			if err != nil {
+				return err
+			}
+			files[filepath.ToSlash(rel)] = p
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("PackageSource %s: %w", ps.Name, err)
+		}
+	}
+	return files, nil
+}
+
+// writeSourceArtifact packs files into a Flux OCI artifact in the layout.
+// The archive is reproducible: entries are sorted and carry no timestamps
+// or owners, so exporting the same files again yields the same digest.
+func writeSourceArtifact(lw *layoutWriter, ps *cozyv1alpha1.PackageSource, files map[string]string) (*SourceEntry, error) {
+	entry := &SourceEntry{Name: ps.Name, Files: map[string]string{}, PackageSource: exportedPackageSource(ps)}
+
+	var layer bytes.Buffer
+	gz := gzip.NewWriter(&layer)
+	tw := tar.NewWriter(gz)
+	for _, rel := range sortedKeys(files) {
+		data, err := os.ReadFile(files[rel])
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		entry.Files[rel] = "sha256:" + hex.EncodeToString(sum[:])
+		if err := tw.WriteHeader(&tar.Header{Name: rel, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg, Format: tar.FormatPAX}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	config, err := lw.writeBytes(fluxConfigMediaType, []byte("{}"))
+	if err != nil {
+		return nil, err
+	}
+	content, err := lw.writeBytes(fluxContentMediaType, layer.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(imageManifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeOCIManifest,
+		Config:        config,
+		Layers:        []Descriptor{content},
+		Annotations:   map[string]string{"org.opencontainers.image.title": ps.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+	desc, err := lw.writeBytes(MediaTypeOCIManifest, data)
+	if err != nil {
+		return nil, err
+	}
+	desc.Annotations = map[string]string{AnnotationPackageSource: ps.Name}
+	lw.addManifest(desc)
+	entry.Digest = desc.Digest
+	return entry, nil
+}
+
+// exportedPackageSource strips what only makes sense in the cluster it was
+// read from.
+func exportedPackageSource(ps *cozyv1alpha1.PackageSource) *cozyv1alpha1.PackageSource {
+	return &cozyv1alpha1.PackageSource{
+		TypeMeta: metav1.TypeMeta{APIVersion: cozyv1alpha1.GroupVersion.String(), Kind: "PackageSource"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ps.Name,
+			Labels:      ps.Labels,
+			Annotations: ps.Annotations,
+		},
+		Spec: *ps.Spec.DeepCopy(),
+	}
+}
+
+func isValuesFile(rel string) bool {
+	name := path.Base(rel)
+	return strings.HasPrefix(name, "values") && (strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml"))
+}
+
+func excluded(image string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, image); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ImagesInValues returns the image references in a Helm values file: the
+// string values of image keys, and maps holding a repository and a tag,
+// with registry and digest keys when set. References without a tag or a
+// digest and templated ones are skipped.
+func ImagesInValues(data []byte) []string {
+	var values interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil
+	}
+	found := map[string]bool{}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if image, ok := v["image"].(string); ok {
+				found[image] = true
+			}
+			if repo, ok := v["repository"].(string); ok {
+				image := repo
+				if registry, ok := v["registry"].(string); ok && registry != "" {
+					image = strings.TrimSuffix(registry, "/") + "/" + repo
+				}
+				if tag := scalarString(v["tag"]); tag != "" {
+					image += ":" + tag
+				}
+				if digest, ok := v["digest"].(string); ok && digest != "" {
+					image += "@" + digest
+				}
+				found[image] = true
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(values)
+
+	var images []string
+	for image := range found {
+		if strings.Contains(image, "{{") {
+			continue
+		}
+		ref, err := ParseReference(image)
+		if err != nil || ref.Tag == "" && ref.Digest == "" {
+			continue
+		}
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images
+}
+
+func scalarString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// copyImage writes the image ref with all its platforms into the layout
+// and returns the descriptor of its manifest or index.
+func copyImage(ctx context.Context, lw *layoutWriter, reg *Registry, ref Reference) (Descriptor, error) {
+	data, mediaType, err := reg.Manifest(ctx, ref.Repository, ref.Identifier())
+	if err != nil {
+		return Descriptor{}, err
+	}
+	desc := Descriptor{MediaType: mediaType, Digest: digestOf(data), Size: int64(len(data))}
+	if ref.Digest != "" && desc.Digest != ref.Digest {
+		return Descriptor{}, fmt.Errorf("registry returned manifest %s, want %s", desc.Digest, ref.Digest)
+	}
+	return desc, copyManifest(ctx, lw, reg, ref.Repository, desc, data)
+}
+
+func copyManifest(ctx context.Context, lw *layoutWriter, reg *Registry, repo string, desc Descriptor, data []byte) error {
+	if lw.hasBlob(desc.Digest) {
+		return nil
+	}
+	if isIndex(desc.MediaType) {
+		var idx index
+		if err := json.Unmarshal(data, &idx); err != nil {
+			return fmt.Errorf("failed to decode index %s: %w", desc.Digest, err)
+		}
+		for _, child := range idx.Manifests {
+			if lw.hasBlob(child.Digest) {
+				continue
+			}
+			childData, _, err := reg.Manifest(ctx, repo, child.Digest)
+			if err != nil {
+				return err
+			}
+			if got := digestOf(childData); got != child.Digest {
+				return fmt.Errorf("registry returned manifest %s, want %s", got, child.Digest)
+			}
+			if err := copyManifest(ctx, lw, reg, repo, child, childData); err != nil {
+				return err
+			}
+		}
+	} else {
+		var im imageManifest
+		if err := json.Unmarshal(data, &im); err != nil {
+			return fmt.Errorf("failed to decode manifest %s: %w", desc.Digest, err)
+		}
+		for _, blob := range append([]Descriptor{im.Config}, im.Layers...) {
+			if lw.hasBlob(blob.Digest) {
+				continue
+			}
+			rc, err := reg.Blob(ctx, repo, blob.Digest)
+			if err != nil {
+				return err
+			}
+			err = lw.writeBlob(blob, rc)
+			_ = rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return lw.writeBlob(desc, bytes.NewReader(data))
+}
+
+// Bundle is a bundle unpacked and verified by Open.
+type Bundle struct {
+	Manifest *Manifest
+	layout   *layout
+	sources  map[string]Descriptor
+	images   map[string]Descriptor
+}
+
+// Open unpacks the bundle r into dir and verifies it: every blob against
+// its digest, every artifact and image against the checksum manifest, and
+// every file in an artifact against its listed digest.
+func Open(r io.Reader, dir string) (*Bundle, error) {
+	l, err := extractLayout(r, dir)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("bundle has no %s: %w", ManifestFile, err)
+	}
+	b := &Bundle{Manifest: &Manifest{}, layout: l, sources: map[string]Descriptor{}, images: map[string]Descriptor{}}
+	if err := json.Unmarshal(data, b.Manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", ManifestFile, err)
+	}
+	if b.Manifest.Version != ManifestVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.Manifest.Version)
+	}
+	for _, desc := range l.index.Manifests {
+		if name := desc.Annotations[AnnotationPackageSource]; name != "" {
+			b.sources[name] = desc
+		} else if ref := desc.Annotations[AnnotationRefName]; ref != "" {
+			b.images[ref] = desc
+		}
+	}
+
+	for _, entry := range b.Manifest.PackageSources {
+		desc, ok := b.sources[entry.Name]
+		if !ok || desc.Digest != entry.Digest {
+			return nil, fmt.Errorf("PackageSource %s: artifact %s is not in the bundle", entry.Name, entry.Digest)
+		}
+		if err := b.verifySource(desc, entry); err != nil {
+			return nil, fmt.Errorf("PackageSource %s: %w", entry.Name, err)
+		}
+	}
+	for _, entry := range b.Manifest.Images {
+		desc, ok := b.images[entry.Reference]
+		if !ok || desc.Digest != entry.Digest {
+			return nil, fmt.Errorf("image %s: manifest %s is not in the bundle", entry.Reference, entry.Digest)
+		}
+		if err := b.walk(desc, func(Descriptor) error { return nil }); err != nil {
+			return nil, fmt.Errorf("image %s: %w", entry.Reference, err)
+		}
+	}
+	return b, nil
+}
+
+// verifySource checks the files in a PackageSource artifact against the
+// checksum manifest.
+func (b *Bundle) verifySource(desc Descriptor, entry SourceEntry) error {
+	data, err := b.layout.blob(desc)
+	if err != nil {
+		return err
+	}
+	var im imageManifest
+	if err := json.Unmarshal(data, &im); err != nil || len(im.Layers) != 1 {
+		return fmt.Errorf("artifact %s is not a Flux artifact", desc.Digest)
+	}
+	f, err := os.Open(b.layout.blobFile(im.Layers[0].Digest))
+	if err != nil {
+		return fmt.Errorf("bundle is missing blob %s: %w", im.Layers[0].Digest, err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		h := sha256.New()
+		if _, err := io.Copy(h, tr); err != nil {
+			return err
+		}
+		want, ok := entry.Files[hdr.Name]
+		if !ok {
+			return fmt.Errorf("file %s is not in the checksum manifest", hdr.Name)
+		}
+		if got := "sha256:" + hex.EncodeToString(h.Sum(nil)); got != want {
+			return fmt.Errorf("file %s has digest %s, want %s", hdr.Name, got, want)
+		}
+		seen[hdr.Name] = true
+	}
+	for name := range entry.Files {
+		if !seen[name] {
+			return fmt.Errorf("file %s is missing from the artifact", name)
+		}
+	}
+	return nil
+}
+
+// walk calls fn for every blob desc references and then for desc itself,
+// children before parents, which is the order a registry accepts them in.
+func (b *Bundle) walk(desc Descriptor, fn func(Descriptor) error) error {
+	data, err := b.layout.blob(desc)
+	if err != nil {
+		return err
+	}
+	if isIndex(desc.MediaType) {
+		var idx index
+		if err := json.Unmarshal(data, &idx); err != nil {
+			return fmt.Errorf("failed to decode index %s: %w", desc.Digest, err)
+		}
+		for _, child := range idx.Manifests {
+			if err := b.walk(child, fn); err != nil {
+				return err
+			}
+		}
+	} else {
+		var im imageManifest
+		if err := json.Unmarshal(data, &im); err != nil {
+			return fmt.Errorf("failed to decode manifest %s: %w", desc.Digest, err)
+		}
+		for _, blob := range append([]Descriptor{im.Config}, im.Layers...) {
+			if _, err := os.Stat(b.layout.blobFile(blob.Digest)); err != nil {
+				return fmt.Errorf("bundle is missing blob %s", blob.Digest)
+			}
+			if err := fn(blob); err != nil {
+				return err
+			}
+		}
+	}
+	return fn(desc)
+}
+
+// PushedSource is where Push put the artifact of a PackageSource.
+type PushedSource struct {
+	Name string
+	// URL is the oci:// URL of the repository.
+	URL string
+	// Digest is the digest of the artifact's manifest.
+	Digest string
+}
+
+// PushedImage is where Push put an image.
+type PushedImage struct {
+	Reference string
+	Mirror    string
+}
+
+// Push uploads every artifact and image of the bundle to reg. Images keep
+// their repository path, so the registry can serve as a mirror for their
+// original registries; prefix, when set, goes in front of every repository.
+func (b *Bundle) Push(ctx context.Context, reg *Registry, prefix string, log io.Writer) ([]PushedSource, []PushedImage, error) {
+	push := func(repo string, desc Descriptor, tag string) error {
+		return b.walk(desc, func(d Descriptor) error {
+			if !isIndex(d.MediaType) && !isManifestMediaType(d.MediaType) {
+				return reg.PushBlob(ctx, repo, d, func() (io.ReadCloser, error) { return os.Open(b.layout.blobFile(d.Digest)) })
+			}
+			data, err := b.layout.blob(d)
+			if err != nil {
+				return err
+			}
+			if err := reg.PushManifest(ctx, repo, d.Digest, d.MediaType, data); err != nil {
+				return err
+			}
+			if d.Digest == desc.Digest && tag != "" {
+				return reg.PushManifest(ctx, repo, tag, d.MediaType, data)
+			}
+			return nil
+		})
+	}
+
+	var sources []PushedSource
+	for _, entry := range b.Manifest.PackageSources {
+		repo := path.Join(prefix, sourceRepository, entry.Name)
+		tag := strings.TrimPrefix(entry.Digest, "sha256:")[:12]
+		if err := push(repo, b.sources[entry.Name], tag); err != nil {
+			return nil, nil, fmt.Errorf("failed to push PackageSource %s: %w", entry.Name, err)
+		}
+		sources = append(sources, PushedSource{Name: entry.Name, URL: "oci://" + reg.Host + "/" + repo, Digest: entry.Digest})
+		if log != nil {
+			fmt.Fprintf(log, "✓ Pushed PackageSource %s to %s/%s:%s\n", entry.Name, reg.Host, repo, tag)
+		}
+	}
+
+	var images []PushedImage
+	for _, entry := range b.Manifest.Images {
+		ref, err := ParseReference(entry.Reference)
+		if err != nil {
+			return nil, nil, err
+		}
+		repo := path.Join(prefix, ref.Repository)
+		if err := push(repo, b.images[entry.Reference], ref.Tag); err != nil {
+			return nil, nil, fmt.Errorf("failed to push image %s: %w", entry.Reference, err)
+		}
+		mirror := Reference{Registry: reg.Host, Repository: repo, Tag: ref.Tag, Digest: entry.Digest}
+		images = append(images, PushedImage{Reference: entry.Reference, Mirror: mirror.String()})
+		if log != nil {
+			fmt.Fprintf(log, "✓ Pushed image %s\n", mirror)
+		}
+	}
+	return sources, images, nil
+}
+
+func isManifestMediaType(mediaType string) bool {
+	return mediaType == MediaTypeOCIManifest || mediaType == MediaTypeDockerManifest
+}
+
+// RewriteSource returns an OCIRepository for the pushed artifact, pinned by
+// digest, and ps pointing at it. ps is not modified. The PackageSource
+// carries the digest in operator.AnnotationBundleDigest so its controller
+// refuses to build from any other artifact.
+func RewriteSource(ps *cozyv1alpha1.PackageSource, pushed PushedSource, plainHTTP bool) (*cozyv1alpha1.PackageSource, *sourcev1.OCIRepository) {
+	repo := &sourcev1.OCIRepository{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: sourcev1.GroupVersion.String(),
+			Kind:       sourcev1.OCIRepositoryKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ps.Name,
+			Namespace: "cozy-system",
+			Labels:    map[string]string{"cozystack.io/packagesource": ps.Name},
+		},
+		Spec: sourcev1.OCIRepositorySpec{
+			URL:       pushed.URL,
+			Reference: &sourcev1.OCIRepositoryRef{Digest: pushed.Digest},
+			Interval:  metav1.Duration{Duration: 10 * time.Minute},
+			Insecure:  plainHTTP,
+		},
+	}
+
+	out := ps.DeepCopy()
+	out.Spec.SourceRef = &cozyv1alpha1.PackageSourceRef{
+		Kind:      sourcev1.OCIRepositoryKind,
+		Name:      repo.Name,
+		Namespace: repo.Namespace,
+	}
+	if out.Annotations == nil {
+		out.Annotations = map[string]string{}
+	}
+	out.Annotations[operator.AnnotationBundleDigest] = pushed.Digest
+	return out, repo
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,386 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	"github.com/cozystack/cozystack/internal/operator"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeRegistry is an in-memory OCI distribution API behind a Bearer token
+// challenge.
+type fakeRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string]fakeManifest
+	uploads   int
+	srv       *httptest.Server
+}
+
+type fakeManifest struct {
+	mediaType string
+	data      []byte
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	t.Helper()
+	f := &fakeRegistry{blobs: map[string][]byte{}, manifests: map[string]fakeManifest{}}
+	f.srv = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeRegistry) host() string { return strings.TrimPrefix(f.srv.URL, "http://") }
+
+func (f *fakeRegistry) client() *Registry { return &Registry{Host: f.host(), PlainHTTP: true} }
+
+func (f *fakeRegistry) putManifest(repo, ref, mediaType string, data []byte) {
+	f.manifests[repo+":"+ref] = fakeManifest{mediaType, data}
+	f.manifests[repo+":"+digestOf(data)] = fakeManifest{mediaType, data}
+}
+
+func (f *fakeRegistry) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/token" {
+		_, _ = io.WriteString(w, `{"token":"t"}`)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer t" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake"`, f.srv.URL))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/upload/") && r.Method == http.MethodPut {
+		data, _ := io.ReadAll(r.Body)
+		if digestOf(data) != r.URL.Query().Get("digest") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.blobs[digestOf(data)] = data
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	p := strings.TrimPrefix(r.URL.Path, "/v2/")
+	switch {
+	case strings.HasSuffix(p, "/blobs/uploads/") && r.Method == http.MethodPost:
+		f.uploads++
+		w.Header().Set("Location", fmt.Sprintf("/upload/%d", f.uploads))
+		w.WriteHeader(http.StatusAccepted)
+	case strings.Contains(p, "/blobs/"):
+		data, ok := f.blobs[p[strings.LastIndex(p, "/")+1:]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case strings.Contains(p, "/manifests/"):
+		i := strings.LastIndex(p, "/manifests/")
+		key := p[:i] + ":" + p[i+len("/manifests/"):]
+		if r.Method == http.MethodPut {
+			data, _ := io.ReadAll(r.Body)
+			f.manifests[key] = fakeManifest{r.Header.Get("Content-Type"), data}
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		m, ok := f.manifests[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", m.mediaType)
+		_, _ = w.Write(m.data)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// seedImage stores a one-platform image as repo:tag and returns the
+// digest of its index.
+func (f *fakeRegistry) seedImage(t *testing.T, repo, tag string) string {
+	t.Helper()
+	config, layer := []byte(`{"architecture":"amd64"}`), []byte("layer of "+repo)
+	f.blobs[digestOf(config)] = config
+	f.blobs[digestOf(layer)] = layer
+	manifest, _ := json.Marshal(imageManifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeOCIManifest,
+		Config:        Descriptor{MediaType: "application/vnd.oci.image.config.v1+json", Digest: digestOf(config), Size: int64(len(config))},
+		Layers:        []Descriptor{{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: digestOf(layer), Size: int64(len(layer))}},
+	})
+	f.putManifest(repo, digestOf(manifest), MediaTypeOCIManifest, manifest)
+	idx, _ := json.Marshal(index{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeOCIIndex,
+		Manifests:     []Descriptor{{MediaType: MediaTypeOCIManifest, Digest: digestOf(manifest), Size: int64(len(manifest))}},
+	})
+	f.putManifest(repo, tag, MediaTypeOCIIndex, idx)
+	return digestOf(idx)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// exportFixture exports a PackageSource with one component and one library
+// from a Git checkout whose charts reference an image in upstream.
+func exportFixture(t *testing.T, upstream *fakeRegistry) ([]byte, *cozyv1alpha1.PackageSource) {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "packages/system/app/Chart.yaml"), "name: app\n")
+	writeFile(t, filepath.Join(dir, "packages/system/app/values.yaml"), "image: "+upstream.host()+"/org/app:v1\n")
+	writeFile(t, filepath.Join(dir, "packages/system/app/templates/deploy.yaml"), "image: {{ .Values.image }}\n")
+	writeFile(t, filepath.Join(dir, "packages/library/common/Chart.yaml"), "name: common\n")
+	writeFile(t, filepath.Join(dir, "packages/system/unused/Chart.yaml"), "name: unused\n")
+
+	ps := &cozyv1alpha1.PackageSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack.app", ResourceVersion: "42"},
+		Spec: cozyv1alpha1.PackageSourceSpec{
+			SourceRef: &cozyv1alpha1.PackageSourceRef{Kind: "GitRepository", Name: "platform", Namespace: "cozy-system"},
+			Variants: []cozyv1alpha1.Variant{{
+				Name:       "default",
+				Libraries:  []cozyv1alpha1.Library{{Name: "common", Path: "library/common"}},
+				Components: []cozyv1alpha1.Component{{Name: "app", Path: "system/app", Libraries: []string{"common"}}},
+			}},
+		},
+	}
+	var buf bytes.Buffer
+	if _, err := Export(context.Background(), &buf, ExportOptions{
+		SourceDir:      dir,
+		PackageSources: []*cozyv1alpha1.PackageSource{ps},
+		Registry:       func(string) *Registry { return upstream.client() },
+	}); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	return buf.Bytes(), ps
+}
+
+func TestExportPushRoundTrip(t *testing.T) {
+	upstream := newFakeRegistry(t)
+	imageDigest := upstream.seedImage(t, "org/app", "v1")
+	data, ps := exportFixture(t, upstream)
+
+	b, err := Open(bytes.NewReader(data), t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if len(b.Manifest.PackageSources) != 1 || len(b.Manifest.Images) != 1 {
+		t.Fatalf("manifest = %+v, want one PackageSource and one image", b.Manifest)
+	}
+	files := b.Manifest.PackageSources[0].Files
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	wantFiles := []string{"library/common/Chart.yaml", "system/app/Chart.yaml", "system/app/templates/deploy.yaml", "system/app/values.yaml"}
+	if len(names) != len(wantFiles) {
+		t.Errorf("files = %v, want %v", names, wantFiles)
+	}
+	for _, name := range wantFiles {
+		if files[name] == "" {
+			t.Errorf("file %s missing from the checksum manifest", name)
+		}
+	}
+	if got := b.Manifest.Images[0].Digest; got != imageDigest {
+		t.Errorf("image digest = %s, want %s", got, imageDigest)
+	}
+	if exported := b.Manifest.PackageSources[0].PackageSource; exported.ResourceVersion != "" {
+		t.Errorf("exported PackageSource kept resourceVersion %q", exported.ResourceVersion)
+	}
+
+	local := newFakeRegistry(t)
+	sources, images, err := b.Push(context.Background(), local.client(), "mirror", nil)
+	if err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if len(images) != 1 || images[0].Mirror != local.host()+"/mirror/org/app:v1@"+imageDigest {
+		t.Errorf("images = %+v", images)
+	}
+	if m, ok := local.manifests["mirror/org/app:v1"]; !ok || digestOf(m.data) != imageDigest {
+		t.Error("image was not pushed under its tag")
+	}
+	// The image config and layer, and the artifact config and layer.
+	if len(local.blobs) != 4 {
+		t.Errorf("local registry holds %d blobs, want 4", len(local.blobs))
+	}
+	if len(sources) != 1 || sources[0].URL != "oci://"+local.host()+"/mirror/cozystack/packagesources/cozystack.app" {
+		t.Fatalf("sources = %+v", sources)
+	}
+	if _, ok := local.manifests["mirror/cozystack/packagesources/cozystack.app:"+sources[0].Digest]; !ok {
+		t.Error("artifact was not pushed by digest")
+	}
+
+	rewritten, repo := RewriteSource(ps, sources[0], true)
+	if rewritten.Spec.SourceRef.Kind != "OCIRepository" || rewritten.Spec.SourceRef.Name != repo.Name || rewritten.Spec.SourceRef.Path != "" {
+		t.Errorf("sourceRef = %+v", rewritten.Spec.SourceRef)
+	}
+	if rewritten.Annotations[operator.AnnotationBundleDigest] != sources[0].Digest || repo.Spec.Reference.Digest != sources[0].Digest {
+		t.Error("rewritten source is not pinned to the artifact digest")
+	}
+	if ps.Spec.SourceRef.Kind != "GitRepository" {
+		t.Error("RewriteSource modified its argument")
+	}
+	// Component paths keep resolving against the artifact root.
+	if got := operator.SourceBasePath(rewritten.Spec.SourceRef); got != "" {
+		t.Errorf("base path = %q, want the artifact root", got)
+	}
+
+	// Exporting the same files again yields the same artifact.
+	again, _ := exportFixture(t, upstream)
+	b2, err := Open(bytes.NewReader(again), t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if b2.Manifest.PackageSources[0].Digest != sources[0].Digest {
+		t.Error("export is not reproducible")
+	}
+}
+
+// rewriteEntry returns the bundle archive data with the entry name passed
+// through edit.
+func rewriteEntry(t *testing.T, data []byte, name string, edit func([]byte) []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	tr, tw := tar.NewReader(bytes.NewReader(data)), tar.NewWriter(&out)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(tr)
+		if hdr.Name == name {
+			content = edit(content)
+			hdr.Size = int64(len(content))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = tw.Write(content)
+	}
+	_ = tw.Close()
+	return out.Bytes()
+}
+
+func TestOpenRejectsTampering(t *testing.T) {
+	upstream := newFakeRegistry(t)
+	upstream.seedImage(t, "org/app", "v1")
+	data, _ := exportFixture(t, upstream)
+
+	tampered := rewriteEntry(t, data, ManifestFile, func(b []byte) []byte {
+		var m Manifest
+		_ = json.Unmarshal(b, &m)
+		m.PackageSources[0].Files["system/app/values.yaml"] = digestOf([]byte("something else"))
+		out, _ := json.Marshal(m)
+		return out
+	})
+	if _, err := Open(bytes.NewReader(tampered), t.TempDir()); err == nil || !strings.Contains(err.Error(), "system/app/values.yaml") {
+		t.Errorf("Open of a bundle with a wrong file checksum: err = %v", err)
+	}
+
+	var blob string
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatal("bundle has no blobs")
+		}
+		if strings.HasPrefix(hdr.Name, "blobs/") {
+			blob = hdr.Name
+			break
+		}
+	}
+	corrupted := rewriteEntry(t, data, blob, func(b []byte) []byte { return append(b, '!') })
+	if _, err := Open(bytes.NewReader(corrupted), t.TempDir()); err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Errorf("Open of a bundle with a corrupted blob: err = %v", err)
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	cases := map[string]Reference{
+		"nginx":                                  {Registry: "docker.io", Repository: "library/nginx"},
+		"grafana/grafana:11.6.15":                {Registry: "docker.io", Repository: "grafana/grafana", Tag: "11.6.15"},
+		"ghcr.io/cozystack/cozystack/api:v1":     {Registry: "ghcr.io", Repository: "cozystack/cozystack/api", Tag: "v1"},
+		"localhost:5000/app@" + digest:           {Registry: "localhost:5000", Repository: "app", Digest: digest},
+		"quay.io/keycloak/keycloak:26@" + digest: {Registry: "quay.io", Repository: "keycloak/keycloak", Tag: "26", Digest: digest},
+	}
+	for in, want := range cases {
+		got, err := ParseReference(in)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseReference(%q) = %+v, %v; want %+v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "ghcr.io/Upper/case:v1", "app@sha256:short", "app:bad tag"} {
+		if _, err := ParseReference(in); err == nil {
+			t.Errorf("ParseReference(%q) succeeded", in)
+		}
+	}
+}
+
+func TestImagesInValues(t *testing.T) {
+	values := `
+image: ghcr.io/cozystack/cozystack/api:v1.6.0
+untagged:
+  image: ghcr.io/cozystack/cozystack/untagged
+templated:
+  image: "{{ .Values.registry }}/app:v1"
+cilium:
+  image:
+    repository: quay.io/cilium/cilium
+    tag: v1.18.0
+coredns:
+  registry: registry.k8s.io
+  repository: coredns/coredns
+  tag: 1.12
+sidecars:
+- image: quay.io/kubevirt/velero-plugin:v0.9.0
+`
+	want := []string{
+		"ghcr.io/cozystack/cozystack/api:v1.6.0",
+		"quay.io/cilium/cilium:v1.18.0",
+		"quay.io/kubevirt/velero-plugin:v0.9.0",
+		"registry.k8s.io/coredns/coredns:1.12",
+	}
+	if got := ImagesInValues([]byte(values)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ImagesInValues = %v, want %v", got, want)
+	}
+}
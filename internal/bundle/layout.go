@@ -0,0 +1,268 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Descriptor points at a blob of an OCI image layout or registry.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// index is an OCI image index; the index.json of a layout is one.
+type index struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Manifests     []Descriptor `json:"manifests"`
+}
+
+// imageManifest is an OCI image manifest, or a Docker v2 one.
+type imageManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+func isIndex(mediaType string) bool {
+	return mediaType == MediaTypeOCIIndex || mediaType == MediaTypeDockerList
+}
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func blobPath(digest string) string {
+	return "blobs/sha256/" + strings.TrimPrefix(digest, "sha256:")
+}
+
+const (
+	layoutFile    = "oci-layout"
+	indexFile     = "index.json"
+	layoutVersion = `{"imageLayoutVersion":"1.0.0"}`
+)
+
+// layoutWriter streams an OCI image layout into a tar archive. Blobs are
+// written once however often they are referenced.
+type layoutWriter struct {
+	tw       *tar.Writer
+	written  map[string]bool
+	manifest []Descriptor
+}
+
+func newLayoutWriter(w io.Writer) *layoutWriter {
+	return &layoutWriter{tw: tar.NewWriter(w), written: map[string]bool{}}
+}
+
+func (w *layoutWriter) writeFile(name string, size int64, r io.Reader) error {
+	if err := w.tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: size, Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := io.Copy(w.tw, r)
+	return err
+}
+
+// hasBlob reports whether the blob digest is already in the layout.
+func (w *layoutWriter) hasBlob(digest string) bool {
+	return w.written[digest]
+}
+
+// writeBlob copies the blob desc from r, failing when the content does not
+// match the descriptor.
+func (w *layoutWriter) writeBlob(desc Descriptor, r io.Reader) error {
+	if w.written[desc.Digest] {
+		return nil
+	}
+	if !digestPattern.MatchString(desc.Digest) {
+		return fmt.Errorf("unsupported blob digest %q", desc.Digest)
+	}
+	v := newVerifier(desc)
+	if err := w.writeFile(blobPath(desc.Digest), desc.Size, io.TeeReader(io.LimitReader(r, desc.Size), v)); err != nil {
+		return fmt.Errorf("failed to write blob %s: %w", desc.Digest, err)
+	}
+	if err := v.verify(); err != nil {
+		return err
+	}
+	w.written[desc.Digest] = true
+	return nil
+}
+
+// writeBytes writes data as a blob and returns its descriptor.
+func (w *layoutWriter) writeBytes(mediaType string, data []byte) (Descriptor, error) {
+	desc := Descriptor{MediaType: mediaType, Digest: digestOf(data), Size: int64(len(data))}
+	return desc, w.writeBlob(desc, strings.NewReader(string(data)))
+}
+
+// addManifest lists desc in index.json.
+func (w *layoutWriter) addManifest(desc Descriptor) {
+	w.manifest = append(w.manifest, desc)
+}
+
+// close writes oci-layout, index.json and the extra top-level files, then
+// ends the archive.
+func (w *layoutWriter) close(extra map[string][]byte) error {
+	idx, err := json.MarshalIndent(index{SchemaVersion: 2, MediaType: MediaTypeOCIIndex, Manifests: w.manifest}, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := []struct {
+		name string
+		data []byte
+	}{{layoutFile, []byte(layoutVersion)}, {indexFile, idx}}
+	for _, name := range sortedKeys(extra) {
+		files = append(files, struct {
+			name string
+			data []byte
+		}{name, extra[name]})
+	}
+	for _, f := range files {
+		if err := w.writeFile(f.name, int64(len(f.data)), strings.NewReader(string(f.data))); err != nil {
+			return err
+		}
+	}
+	return w.tw.Close()
+}
+
+// verifier hashes what is written to it and checks it against a descriptor.
+type verifier struct {
+	desc Descriptor
+	h    hash.Hash
+	n    int64
+}
+
+func newVerifier(desc Descriptor) *verifier {
+	return &verifier{desc: desc, h: sha256.New()}
+}
+
+func (v *verifier) Write(p []byte) (int, error) {
+	v.n += int64(len(p))
+	return v.h.Write(p)
+}
+
+func (v *verifier) verify() error {
+	if v.n != v.desc.Size {
+		return fmt.Errorf("blob %s: got %d bytes, want %d", v.desc.Digest, v.n, v.desc.Size)
+	}
+	if got := "sha256:" + hex.EncodeToString(v.h.Sum(nil)); got != v.desc.Digest {
+		return fmt.Errorf("blob %s: content has digest %s", v.desc.Digest, got)
+	}
+	return nil
+}
+
+// layout is an OCI image layout unpacked into a directory.
+type layout struct {
+	dir   string
+	index index
+}
+
+// extractLayout unpacks the layout archive r into dir, checking every blob
+// against the digest it is named by. Only the layout files, blobs and the
+// bundle manifest are accepted.
+func extractLayout(r io.Reader, dir string) (*layout, error) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		name := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(hdr.Name)), "./")
+		switch {
+		case hdr.Typeflag == tar.TypeDir:
+			continue
+		case hdr.Typeflag != tar.TypeReg:
+			return nil, fmt.Errorf("bundle entry %s: unsupported type %q", hdr.Name, hdr.Typeflag)
+		case name == layoutFile || name == indexFile || name == ManifestFile:
+		case strings.HasPrefix(name, "blobs/sha256/") && digestPattern.MatchString("sha256:"+strings.TrimPrefix(name, "blobs/sha256/")):
+		default:
+			return nil, fmt.Errorf("unexpected bundle entry %s", hdr.Name)
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return nil, err
+		}
+		f, err := os.Create(target)
+		if err != nil {
+			return nil, err
+		}
+		var out io.Writer = f
+		var v *verifier
+		if strings.HasPrefix(name, "blobs/") {
+			v = newVerifier(Descriptor{Digest: "sha256:" + filepath.Base(name), Size: hdr.Size})
+			out = io.MultiWriter(f, v)
+		}
+		_, err = io.Copy(out, tr)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+		if v != nil {
+			if err := v.verify(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	l := &layout{dir: dir}
+	data, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if err != nil {
+		return nil, fmt.Errorf("bundle has no %s: %w", indexFile, err)
+	}
+	if err := json.Unmarshal(data, &l.index); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", indexFile, err)
+	}
+	return l, nil
+}
+
+// blob reads a small blob such as a manifest, checking it against desc.
+func (l *layout) blob(desc Descriptor) ([]byte, error) {
+	data, err := os.ReadFile(l.blobFile(desc.Digest))
+	if err != nil {
+		return nil, fmt.Errorf("bundle is missing blob %s: %w", desc.Digest, err)
+	}
+	if int64(len(data)) != desc.Size {
+		return nil, fmt.Errorf("blob %s: got %d bytes, want %d", desc.Digest, len(data), desc.Size)
+	}
+	return data, nil
+}
+
+func (l *layout) blobFile(digest string) string {
+	return filepath.Join(l.dir, filepath.FromSlash(blobPath(digest)))
+}
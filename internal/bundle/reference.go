@@ -0,0 +1,108 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const dockerHub = "docker.io"
+
+var (
+	digestPattern     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+	repositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	tagPattern        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+)
+
+// Reference is a parsed container image reference.
+type Reference struct {
+	// Registry is the registry host, with docker.io for Docker Hub.
+	Registry string
+	// Repository is the path within the registry, with library/ added to
+	// official Docker Hub images.
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses an image reference the way container runtimes do:
+// the first path component is the registry when it contains a dot or a
+// port or is localhost, and Docker Hub otherwise.
+func ParseReference(s string) (Reference, error) {
+	var ref Reference
+	name := s
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+		if !digestPattern.MatchString(ref.Digest) {
+			return Reference{}, fmt.Errorf("invalid image reference %q: unsupported digest %q", s, ref.Digest)
+		}
+	}
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i:], "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+		if !tagPattern.MatchString(ref.Tag) {
+			return Reference{}, fmt.Errorf("invalid image reference %q: invalid tag %q", s, ref.Tag)
+		}
+	}
+
+	ref.Registry = dockerHub
+	if i := strings.Index(name, "/"); i >= 0 {
+		host := name[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.Registry = host
+			name = name[i+1:]
+		}
+	}
+	if ref.Registry == "index.docker.io" {
+		ref.Registry = dockerHub
+	}
+	if ref.Registry == dockerHub && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	if !repositoryPattern.MatchString(name) {
+		return Reference{}, fmt.Errorf("invalid image reference %q: invalid repository %q", s, name)
+	}
+	ref.Repository = name
+	return ref, nil
+}
+
+// String renders the reference in full, registry included.
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Identifier is the digest if the reference has one and the tag otherwise,
+// defaulting to latest; it is what a manifest is fetched by.
+func (r Reference) Identifier() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	if r.Tag != "" {
+		return r.Tag
+	}
+	return "latest"
+}
@@ -0,0 +1,338 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Manifest media types a registry may answer with.
+const (
+	MediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+var manifestAccept = strings.Join([]string{MediaTypeOCIIndex, MediaTypeOCIManifest, MediaTypeDockerList, MediaTypeDockerManifest}, ", ")
+
+// Registry is a client for the OCI distribution API of one registry host.
+// It answers Basic and Bearer token challenges with Username and Password,
+// or anonymously when they are empty.
+type Registry struct {
+	// Host is the registry host and optional port, e.g. registry.local:5000.
+	Host string
+	// PlainHTTP talks HTTP instead of HTTPS.
+	PlainHTTP bool
+	Username  string
+	Password  string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+
+	mu sync.Mutex
+	// auth caches the Authorization header per token scope.
+	auth map[string]string
+}
+
+// NewRegistry returns a client for host, mapping docker.io to the Docker
+// Hub API endpoint.
+func NewRegistry(host string) *Registry {
+	if host == dockerHub {
+		host = "registry-1.docker.io"
+	}
+	return &Registry{Host: host}
+}
+
+func (g *Registry) baseURL() string {
+	if g.PlainHTTP {
+		return "http://" + g.Host
+	}
+	return "https://" + g.Host
+}
+
+// do sends the request newReq builds, answering one authentication
+// challenge for scope. newReq is called again for the retry, so it must
+// return a fresh body each time.
+func (g *Registry) do(ctx context.Context, scope string, newReq func() (*http.Request, error)) (*http.Response, error) {
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	send := func() (*http.Response, error) {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		g.mu.Lock()
+		authz := g.auth[scope]
+		g.mu.Unlock()
+		if authz != "" {
+			req.Header.Set("Authorization", authz)
+		}
+		return client.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	_ = resp.Body.Close()
+	authz, err := g.authorize(ctx, client, challenge, scope)
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	if g.auth == nil {
+		g.auth = map[string]string{}
+	}
+	g.auth[scope] = authz
+	g.mu.Unlock()
+	return send()
+}
+
+// authorize answers a WWW-Authenticate challenge with an Authorization
+// header value.
+func (g *Registry) authorize(ctx context.Context, client *http.Client, challenge, scope string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if g.Username == "" {
+			return "", fmt.Errorf("registry %s requires credentials", g.Host)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "", nil)
+		req.SetBasicAuth(g.Username, g.Password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("registry %s: unsupported authentication challenge %q", g.Host, challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("registry %s: invalid token realm %q", g.Host, params["realm"])
+	}
+	q := realm.Query()
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	q.Set("scope", scope)
+	realm.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if g.Username != "" {
+		req.SetBasicAuth(g.Username, g.Password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get a token for %s: %w", g.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get a token for %s: %s", g.Host, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode the token for %s: %w", g.Host, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// parseChallenge splits `Bearer realm="…",service="…"` into the scheme and
+// its parameters.
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, ", "), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return scheme, params
+}
+
+func pullScope(repo string) string { return "repository:" + repo + ":pull" }
+func pushScope(repo string) string { return "repository:" + repo + ":pull,push" }
+
+func responseError(resp *http.Response, what string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		return fmt.Errorf("%s: %s", what, resp.Status)
+	}
+	return fmt.Errorf("%s: %s: %s", what, resp.Status, msg)
+}
+
+// Manifest fetches the manifest or index repo:reference and its media type.
+func (g *Registry) Manifest(ctx context.Context, repo, reference string) ([]byte, string, error) {
+	resp, err := g.do(ctx, pullScope(repo), func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v2/%s/manifests/%s", g.baseURL(), repo, reference), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", manifestAccept)
+		return req, nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get manifest %s/%s:%s: %w", g.Host, repo, reference, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", responseError(resp, fmt.Sprintf("failed to get manifest %s/%s:%s", g.Host, repo, reference))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	mediaType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	if mediaType == "" || mediaType == "application/json" {
+		var probe struct {
+			MediaType string `json:"mediaType"`
+		}
+		_ = json.Unmarshal(data, &probe)
+		mediaType = probe.MediaType
+	}
+	return data, mediaType, nil
+}
+
+// Blob opens the blob repo@digest.
+func (g *Registry) Blob(ctx context.Context, repo, digest string) (io.ReadCloser, error) {
+	resp, err := g.do(ctx, pullScope(repo), func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v2/%s/blobs/%s", g.baseURL(), repo, digest), nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob %s/%s@%s: %w", g.Host, repo, digest, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp, fmt.Sprintf("failed to get blob %s/%s@%s", g.Host, repo, digest))
+	}
+	return resp.Body, nil
+}
+
+// HasBlob reports whether repo already holds the blob digest.
+func (g *Registry) HasBlob(ctx context.Context, repo, digest string) (bool, error) {
+	resp, err := g.do(ctx, pushScope(repo), func() (*http.Request, error) {
+		return http.NewRequest(http.MethodHead, fmt.Sprintf("%s/v2/%s/blobs/%s", g.baseURL(), repo, digest), nil)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check blob %s/%s@%s: %w", g.Host, repo, digest, err)
+	}
+	_ = resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to check blob %s/%s@%s: %s", g.Host, repo, digest, resp.Status)
+}
+
+// PushBlob uploads the blob described by desc in one request unless repo
+// already holds it. open is called for every attempt.
+func (g *Registry) PushBlob(ctx context.Context, repo string, desc Descriptor, open func() (io.ReadCloser, error)) error {
+	exists, err := g.HasBlob(ctx, repo, desc.Digest)
+	if err != nil || exists {
+		return err
+	}
+
+	resp, err := g.do(ctx, pushScope(repo), func() (*http.Request, error) {
+		return http.NewRequest(http.MethodPost, fmt.Sprintf("%s/v2/%s/blobs/uploads/", g.baseURL(), repo), nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start upload to %s/%s: %w", g.Host, repo, err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to start upload to %s/%s: %s", g.Host, repo, resp.Status)
+	}
+	location, err := url.Parse(g.baseURL() + "/")
+	if err == nil {
+		location, err = location.Parse(resp.Header.Get("Location"))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start upload to %s/%s: invalid Location: %w", g.Host, repo, err)
+	}
+	q := location.Query()
+	q.Set("digest", desc.Digest)
+	location.RawQuery = q.Encode()
+
+	resp, err = g.do(ctx, pushScope(repo), func() (*http.Request, error) {
+		body, err := open()
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest(http.MethodPut, location.String(), body)
+		if err != nil {
+			_ = body.Close()
+			return nil, err
+		}
+		req.ContentLength = desc.Size
+		req.Header.Set("Content-Type", "application/octet-stream")
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload blob %s to %s/%s: %w", desc.Digest, g.Host, repo, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return responseError(resp, fmt.Sprintf("failed to upload blob %s to %s/%s", desc.Digest, g.Host, repo))
+	}
+	return nil
+}
+
+// PushManifest uploads a manifest or index as repo:reference.
+func (g *Registry) PushManifest(ctx context.Context, repo, reference, mediaType string, data []byte) error {
+	resp, err := g.do(ctx, pushScope(repo), func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/v2/%s/manifests/%s", g.baseURL(), repo, reference), bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", mediaType)
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to push manifest %s/%s:%s: %w", g.Host, repo, reference, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return responseError(resp, fmt.Sprintf("failed to push manifest %s/%s:%s", g.Host, repo, reference))
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	"github.com/cozystack/cozystack/internal/controller/tenantquota"
 	helmv2 "github.com/fluxcd/helm-controller/api/v2"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -96,14 +97,19 @@ func (r *ApplicationDefinitionHelmReconciler) updateHelmReleasesForAppDef(ctx co
 	return nil
 }
 
-// expectedValuesFrom returns the expected valuesFrom configuration for HelmReleases
-func expectedValuesFrom() []helmv2.ValuesReference {
-	return []helmv2.ValuesReference{
+// expectedValuesFrom returns the expected valuesFrom configuration for HelmReleases.
+// Tenant releases also read the quota raises of their approved QuotaRequests.
+func expectedValuesFrom(hr *helmv2.HelmRelease, applicationKind string) []helmv2.ValuesReference {
+	refs := []helmv2.ValuesReference{
 		{
 			Kind: "Secret",
 			Name: "cozystack-values",
 		},
 	}
+	if applicationKind == "Tenant" {
+		refs = append(refs, tenantquota.BurstValuesReference(hr.Name))
+	}
+	return refs
 }
 
 // valuesFromEqual compares two ValuesReference slices
@@ -156,7 +162,7 @@ func (r *ApplicationDefinitionHelmReconciler) updateHelmReleaseChart(ctx context
 	}
 
 	// Check and update valuesFrom configuration
-	expected := expectedValuesFrom()
+	expected := expectedValuesFrom(hr, appDef.Spec.Application.Kind)
 	if !valuesFromEqual(hrCopy.Spec.ValuesFrom, expected) {
 		logger.V(4).Info("Updating HelmRelease valuesFrom", "name", hr.Name, "namespace", hr.Namespace)
 		hrCopy.Spec.ValuesFrom = expected
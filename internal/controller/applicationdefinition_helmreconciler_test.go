@@ -192,6 +192,67 @@ func TestAppDefHelm_ValuesFromMismatchPatches(t *testing.T) {
 	}
 }
 
+// TestAppDefHelm_TenantValuesFromIncludesQuotaRaises pins that Tenant
+// releases also read the optional values Secret carrying the raises of
+// their approved QuotaRequests.
+func TestAppDefHelm_TenantValuesFromIncludesQuotaRaises(t *testing.T) {
+	scheme := newAppDefHelmScheme(t)
+
+	appDef := &cozyv1alpha1.ApplicationDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "tenant"},
+		Spec: cozyv1alpha1.ApplicationDefinitionSpec{
+			Application: cozyv1alpha1.ApplicationDefinitionApplication{Kind: "Tenant"},
+			Release: cozyv1alpha1.ApplicationDefinitionRelease{
+				ChartRef: &helmv2.CrossNamespaceSourceReference{
+					Kind:      "ExternalArtifact",
+					Name:      "tenant",
+					Namespace: "cozy-system",
+				},
+			},
+		},
+	}
+	hr := &helmv2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tenant-foo",
+			Namespace: "tenant-root",
+			Labels: map[string]string{
+				"apps.cozystack.io/application.kind":  "Tenant",
+				"apps.cozystack.io/application.group": "apps.cozystack.io",
+			},
+		},
+		Spec: helmv2.HelmReleaseSpec{
+			ChartRef: appDef.Spec.Release.ChartRef.DeepCopy(),
+			ValuesFrom: []helmv2.ValuesReference{
+				{Kind: "Secret", Name: "cozystack-values"},
+			},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(appDef, hr).
+		Build()
+
+	r := &ApplicationDefinitionHelmReconciler{Client: fakeClient, Scheme: scheme}
+	if _, err := r.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "tenant"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := &helmv2.HelmRelease{}
+	if err := fakeClient.Get(context.TODO(), types.NamespacedName{Name: "tenant-foo", Namespace: "tenant-root"}, got); err != nil {
+		t.Fatalf("get HR: %v", err)
+	}
+	want := []helmv2.ValuesReference{
+		{Kind: "Secret", Name: "cozystack-values"},
+		{Kind: "Secret", Name: "tenant-foo-quota-burst", Optional: true},
+	}
+	if !valuesFromEqual(got.Spec.ValuesFrom, want) {
+		t.Fatalf("valuesFrom = %+v, want %+v", got.Spec.ValuesFrom, want)
+	}
+}
+
 // TestAppDefHelm_LabelsApplied pins the label propagation: when the
 // ApplicationDefinition declares Release.Labels, those are merged into
 // HR labels.
@@ -351,7 +351,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, err
 	}
 
-	target := release + projectionSuffix
+	target := ProjectionName(release)
 
 	// Every CACert projection resolves to the single, release-derived canonical
 	// name, so more than one would overwrite the others on each pass — silently,
@@ -946,6 +946,14 @@ func (r *Reconciler) upsertProjection(ctx context.Context, tp *internalv1alpha1.
 // read-modify-write that never writes Type, so it could not self-DoS on an adopted
 // non-Opaque Secret even without this check.
 func isOurProjection(s *corev1.Secret, tp *internalv1alpha1.TenantProjection) bool {
+	return IsProjectionOf(s, tp.Name)
+}
+
+// IsProjectionOf reports whether s is owned by the TenantProjection sentinel
+// named sentinel, by the same name-matched owner reference isOurProjection
+// adopts on. Consumers that read a projection by name — the TenantGateway
+// client-certificate validation, for one — use it to refuse a look-alike Secret.
+func IsProjectionOf(s *corev1.Secret, sentinel string) bool {
 	if s.Type != corev1.SecretTypeOpaque {
 		return false
 	}
@@ -953,13 +961,19 @@ func isOurProjection(s *corev1.Secret, tp *internalv1alpha1.TenantProjection) bo
 		ref := &s.OwnerReferences[i]
 		if ref.Kind == "TenantProjection" &&
 			ref.APIVersion == internalv1alpha1.GroupVersion.String() &&
-			ref.Name == tp.Name {
+			ref.Name == sentinel {
 			return true
 		}
 	}
 	return false
 }
 
+// ProjectionName returns the canonical "<release>.tenant-ca" name of the
+// projection published for release.
+func ProjectionName(release string) string {
+	return release + projectionSuffix
+}
+
 // selectorsDigest digests the ApplicationDefinition's spec.secrets — the
 // selectors the lineage webhook uses to decide whether the projection is visible
 // to the tenant. It is the drift signal that forces a re-admission when tenant
@@ -0,0 +1,232 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package domainclaim verifies that a tenant controls a customer-supplied
+// domain before the tenant Gateway publishes hostnames under it.
+//
+// A DomainClaim names the domain. The controller answers with a TXT-record
+// challenge in status (`_cozystack-challenge.<domain>` carrying a random
+// value), then polls DNS until the record shows up and marks the claim
+// Verified. The TenantGateway controller reads verified claims when it
+// decides which routes may attach a hostname outside the platform apexes.
+//
+// Verification is sticky: once Verified, a claim stays Verified until its
+// domain changes or it is deleted, so removing the TXT record after the fact
+// does not take a live site down. A domain another namespace has already
+// verified cannot be claimed again; the newcomer is marked Failed.
+package domainclaim
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+)
+
+const (
+	// challengeRecordPrefix is the label prepended to the claimed domain
+	// to form the TXT record name.
+	challengeRecordPrefix = "_cozystack-challenge."
+	// challengeValuePrefix marks the TXT value as ours, so an unrelated
+	// record at the same name is never mistaken for an answer.
+	challengeValuePrefix = "cozystack-domain-verification="
+
+	// defaultCheckInterval is how often a Pending or Failed claim is
+	// re-checked.
+	defaultCheckInterval = time.Minute
+)
+
+// +kubebuilder:rbac:groups=gateway.cozystack.io,resources=domainclaims,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.cozystack.io,resources=domainclaims/status,verbs=get;update;patch
+
+// Reconciler issues and checks DomainClaim challenges.
+type Reconciler struct {
+	client.Client
+	// Resolver answers the TXT lookups. Nil uses the system resolver.
+	Resolver Resolver
+	// CheckInterval overrides defaultCheckInterval.
+	CheckInterval time.Duration
+	// now stubs the clock in tests.
+	now func() time.Time
+}
+
+// Reconcile brings one DomainClaim's status up to date. Status writes do
+// not bump metadata.generation, so they never re-trigger the watch; the
+// polling cadence comes from RequeueAfter alone.
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	dc := &gatewayv1alpha1.DomainClaim{}
+	if err := r.Get(ctx, req.NamespacedName, dc); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	before := dc.Status.DeepCopy()
+	now := metav1.NewTime(r.clock())
+	domain := strings.ToLower(dc.Spec.Domain)
+
+	// A new domain invalidates everything learned about the old one,
+	// including a verification: the challenge value is regenerated so a
+	// record left behind for the previous domain cannot answer it.
+	if dc.Status.Challenge == nil || dc.Status.Domain != domain {
+		value, err := newChallengeValue()
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		dc.Status = gatewayv1alpha1.DomainClaimStatus{
+			Domain: domain,
+			Phase:  gatewayv1alpha1.DomainClaimPhasePending,
+			Challenge: &gatewayv1alpha1.DomainClaimChallenge{
+				RecordName:  challengeRecordPrefix + domain,
+				RecordValue: value,
+			},
+		}
+	}
+	dc.Status.ObservedGeneration = dc.Generation
+
+	if dc.Status.Phase != gatewayv1alpha1.DomainClaimPhaseVerified {
+		holder, err := r.verifiedElsewhere(ctx, dc, domain)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if holder != "" {
+			r.setPhase(dc, gatewayv1alpha1.DomainClaimPhaseFailed, metav1.ConditionFalse, "DomainClaimed",
+				fmt.Sprintf("%s is already verified by DomainClaim %s", domain, holder))
+		} else {
+			r.check(ctx, dc, now)
+		}
+	}
+
+	if !equality.Semantic.DeepEqual(before, &dc.Status) {
+		if err := r.Status().Update(ctx, dc); err != nil {
+			return ctrl.Result{}, fmt.Errorf("update DomainClaim status: %w", err)
+		}
+	}
+	if dc.Status.Phase == gatewayv1alpha1.DomainClaimPhaseVerified {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: r.checkInterval()}, nil
+}
+
+// check queries DNS for the challenge and records the outcome.
+func (r *Reconciler) check(ctx context.Context, dc *gatewayv1alpha1.DomainClaim, now metav1.Time) {
+	challenge := dc.Status.Challenge
+	dc.Status.LastCheckTime = &now
+	records, err := r.resolver().LookupTXT(ctx, challenge.RecordName)
+	switch {
+	case err != nil && !isNotFound(err):
+		log.FromContext(ctx).V(1).Info("TXT lookup failed", "name", challenge.RecordName, "error", err.Error())
+		r.setPhase(dc, gatewayv1alpha1.DomainClaimPhasePending, metav1.ConditionFalse, "LookupFailed",
+			fmt.Sprintf("looking up TXT %s: %v", challenge.RecordName, err))
+		return
+	case err == nil && containsValue(records, challenge.RecordValue):
+		dc.Status.VerifiedAt = &now
+		r.setPhase(dc, gatewayv1alpha1.DomainClaimPhaseVerified, metav1.ConditionTrue, "RecordFound",
+			fmt.Sprintf("TXT %s found", challenge.RecordName))
+		return
+	}
+	r.setPhase(dc, gatewayv1alpha1.DomainClaimPhasePending, metav1.ConditionFalse, "RecordNotFound",
+		fmt.Sprintf("publish TXT %s with value %q", challenge.RecordName, challenge.RecordValue))
+}
+
+func (r *Reconciler) setPhase(dc *gatewayv1alpha1.DomainClaim, phase gatewayv1alpha1.DomainClaimPhase, status metav1.ConditionStatus, reason, message string) {
+	dc.Status.Phase = phase
+	apimeta.SetStatusCondition(&dc.Status.Conditions, metav1.Condition{
+		Type:               gatewayv1alpha1.DomainClaimVerifiedCondition,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: dc.Generation,
+	})
+}
+
+// verifiedElsewhere returns namespace/name of a claim in another
+// namespace that already verified domain, or "".
+func (r *Reconciler) verifiedElsewhere(ctx context.Context, dc *gatewayv1alpha1.DomainClaim, domain string) (string, error) {
+	list := &gatewayv1alpha1.DomainClaimList{}
+	if err := r.List(ctx, list); err != nil {
+		return "", fmt.Errorf("list DomainClaims: %w", err)
+	}
+	for i := range list.Items {
+		other := &list.Items[i]
+		if other.Namespace == dc.Namespace {
+			continue
+		}
+		if other.Status.Phase == gatewayv1alpha1.DomainClaimPhaseVerified && other.Status.Domain == domain {
+			return other.Namespace + "/" + other.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// containsValue matches the challenge against every TXT record at the
+// name; a zone may carry several challenges at once during a handover.
+func containsValue(records []string, want string) bool {
+	for _, rec := range records {
+		if strings.TrimSpace(rec) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func newChallengeValue() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate challenge: %w", err)
+	}
+	return challengeValuePrefix + hex.EncodeToString(buf), nil
+}
+
+func (r *Reconciler) resolver() Resolver {
+	if r.Resolver != nil {
+		return r.Resolver
+	}
+	return NewResolver(nil)
+}
+
+func (r *Reconciler) checkInterval() time.Duration {
+	if r.CheckInterval > 0 {
+		return r.CheckInterval
+	}
+	return defaultCheckInterval
+}
+
+func (r *Reconciler) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// SetupWithManager registers the reconciler. Only spec changes trigger
+// a reconcile; pending claims are polled through RequeueAfter.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("domainclaim").
+		For(&gatewayv1alpha1.DomainClaim{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
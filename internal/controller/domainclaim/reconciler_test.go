@@ -0,0 +1,190 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package domainclaim
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+)
+
+// fakeResolver serves TXT records from a map; a name mapped to a nil
+// slice fails the lookup with a transient error.
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	records, ok := f[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	if records == nil {
+		return nil, errors.New("i/o timeout")
+	}
+	return records, nil
+}
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	s := runtime.NewScheme()
+	if err := gatewayv1alpha1.AddToScheme(s); err != nil {
+		t.Fatalf("gateway scheme: %v", err)
+	}
+	return s
+}
+
+func domainClaim(ns, name, domain string) *gatewayv1alpha1.DomainClaim {
+	return &gatewayv1alpha1.DomainClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, Generation: 1},
+		Spec:       gatewayv1alpha1.DomainClaimSpec{Domain: domain},
+	}
+}
+
+func reconcileClaim(t *testing.T, r *Reconciler, ns, name string) ctrl.Result {
+	t.Helper()
+	res, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: ns, Name: name}})
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	return res
+}
+
+func getClaim(t *testing.T, c client.Client, ns, name string) *gatewayv1alpha1.DomainClaim {
+	t.Helper()
+	dc := &gatewayv1alpha1.DomainClaim{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: ns, Name: name}, dc); err != nil {
+		t.Fatalf("get DomainClaim: %v", err)
+	}
+	return dc
+}
+
+// TestReconcile_IssuesChallengeThenVerifies pins the happy path: the
+// first pass publishes a challenge and stays Pending with a requeue,
+// the pass after the record appears marks the claim Verified and stops
+// polling.
+func TestReconcile_IssuesChallengeThenVerifies(t *testing.T) {
+	s := newScheme(t)
+	dc := domainClaim("tenant-foo", "shop", "Shop.Example.com")
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(dc).WithStatusSubresource(dc).Build()
+	resolver := fakeResolver{}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := &Reconciler{Client: c, Resolver: resolver, now: func() time.Time { return now }}
+
+	if res := reconcileClaim(t, r, "tenant-foo", "shop"); res.RequeueAfter != defaultCheckInterval {
+		t.Errorf("RequeueAfter=%v, want %v while pending", res.RequeueAfter, defaultCheckInterval)
+	}
+	got := getClaim(t, c, "tenant-foo", "shop")
+	if got.Status.Phase != gatewayv1alpha1.DomainClaimPhasePending || got.Status.Challenge == nil {
+		t.Fatalf("status=%+v, want Pending with a challenge", got.Status)
+	}
+	if got.Status.Challenge.RecordName != "_cozystack-challenge.shop.example.com" {
+		t.Errorf("recordName=%q", got.Status.Challenge.RecordName)
+	}
+	if !strings.HasPrefix(got.Status.Challenge.RecordValue, challengeValuePrefix) {
+		t.Errorf("recordValue=%q, want %s prefix", got.Status.Challenge.RecordValue, challengeValuePrefix)
+	}
+	cond := apimeta.FindStatusCondition(got.Status.Conditions, gatewayv1alpha1.DomainClaimVerifiedCondition)
+	if cond == nil || cond.Reason != "RecordNotFound" || !strings.Contains(cond.Message, got.Status.Challenge.RecordValue) {
+		t.Errorf("Verified condition=%+v, want RecordNotFound naming the value", cond)
+	}
+
+	value := got.Status.Challenge.RecordValue
+	resolver[got.Status.Challenge.RecordName] = []string{"v=spf1 -all", value}
+	if res := reconcileClaim(t, r, "tenant-foo", "shop"); res.RequeueAfter != 0 {
+		t.Errorf("RequeueAfter=%v, want no polling once verified", res.RequeueAfter)
+	}
+	got = getClaim(t, c, "tenant-foo", "shop")
+	if got.Status.Phase != gatewayv1alpha1.DomainClaimPhaseVerified || got.Status.VerifiedAt == nil || !got.Status.VerifiedAt.Time.Equal(now) {
+		t.Fatalf("status=%+v, want Verified at %v", got.Status, now)
+	}
+	if got.Status.Challenge.RecordValue != value {
+		t.Error("challenge must not change across reconciles")
+	}
+
+	// Verification is sticky: the record going away does not revoke it.
+	delete(resolver, got.Status.Challenge.RecordName)
+	reconcileClaim(t, r, "tenant-foo", "shop")
+	if got := getClaim(t, c, "tenant-foo", "shop"); got.Status.Phase != gatewayv1alpha1.DomainClaimPhaseVerified {
+		t.Errorf("phase=%s after record removal, want Verified", got.Status.Phase)
+	}
+}
+
+// TestReconcile_DomainChangeRestartsVerification pins that a spec edit
+// drops the verification and issues a fresh challenge.
+func TestReconcile_DomainChangeRestartsVerification(t *testing.T) {
+	s := newScheme(t)
+	dc := domainClaim("tenant-foo", "shop", "shop.example.com")
+	dc.Status = gatewayv1alpha1.DomainClaimStatus{
+		Domain:     "shop.example.com",
+		Phase:      gatewayv1alpha1.DomainClaimPhaseVerified,
+		VerifiedAt: &metav1.Time{Time: time.Now()},
+		Challenge:  &gatewayv1alpha1.DomainClaimChallenge{RecordName: "_cozystack-challenge.shop.example.com", RecordValue: challengeValuePrefix + "old"},
+	}
+	dc.Spec.Domain = "store.example.com"
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(dc).WithStatusSubresource(dc).Build()
+	r := &Reconciler{Client: c, Resolver: fakeResolver{"_cozystack-challenge.store.example.com": {challengeValuePrefix + "old"}}}
+	reconcileClaim(t, r, "tenant-foo", "shop")
+
+	got := getClaim(t, c, "tenant-foo", "shop")
+	if got.Status.Phase != gatewayv1alpha1.DomainClaimPhasePending || got.Status.VerifiedAt != nil {
+		t.Errorf("status=%+v, want Pending without verifiedAt", got.Status)
+	}
+	if got.Status.Domain != "store.example.com" || got.Status.Challenge.RecordValue == challengeValuePrefix+"old" {
+		t.Errorf("challenge=%+v, want a fresh one for store.example.com", got.Status.Challenge)
+	}
+}
+
+// TestReconcile_DomainVerifiedElsewhereFails pins the cross-namespace
+// guard, and that a lookup error keeps a claim Pending with the reason.
+func TestReconcile_DomainVerifiedElsewhereFails(t *testing.T) {
+	s := newScheme(t)
+	holder := domainClaim("tenant-bar", "shop", "shop.example.com")
+	holder.Status = gatewayv1alpha1.DomainClaimStatus{Domain: "shop.example.com", Phase: gatewayv1alpha1.DomainClaimPhaseVerified}
+	latecomer := domainClaim("tenant-foo", "shop", "shop.example.com")
+	flaky := domainClaim("tenant-foo", "blog", "blog.example.com")
+	c := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(holder, latecomer, flaky).
+		WithStatusSubresource(holder, latecomer, flaky).
+		Build()
+	r := &Reconciler{Client: c, Resolver: fakeResolver{"_cozystack-challenge.blog.example.com": nil}}
+
+	reconcileClaim(t, r, "tenant-foo", "shop")
+	got := getClaim(t, c, "tenant-foo", "shop")
+	cond := apimeta.FindStatusCondition(got.Status.Conditions, gatewayv1alpha1.DomainClaimVerifiedCondition)
+	if got.Status.Phase != gatewayv1alpha1.DomainClaimPhaseFailed || cond == nil || cond.Reason != "DomainClaimed" || !strings.Contains(cond.Message, "tenant-bar/shop") {
+		t.Errorf("status=%+v, want Failed/DomainClaimed naming tenant-bar/shop", got.Status)
+	}
+
+	reconcileClaim(t, r, "tenant-foo", "blog")
+	got = getClaim(t, c, "tenant-foo", "blog")
+	cond = apimeta.FindStatusCondition(got.Status.Conditions, gatewayv1alpha1.DomainClaimVerifiedCondition)
+	if got.Status.Phase != gatewayv1alpha1.DomainClaimPhasePending || cond == nil || cond.Reason != "LookupFailed" {
+		t.Errorf("status=%+v, want Pending/LookupFailed", got.Status)
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package domainclaim
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+)
+
+// Resolver looks up the TXT records published under a name. *net.Resolver
+// satisfies it; tests substitute a map.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// NewResolver returns the system resolver, or one that sends every query
+// to the given nameservers (host:port) in turn. Pointing verification at
+// public recursive resolvers keeps a split-horizon cluster DNS from
+// confirming a record the outside world cannot see.
+func NewResolver(nameservers []string) Resolver {
+	if len(nameservers) == 0 {
+		return net.DefaultResolver
+	}
+	var next atomic.Uint32
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			server := nameservers[int(next.Add(1)-1)%len(nameservers)]
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// isNotFound reports whether err means the name has no TXT records, as
+// opposed to a lookup that could not complete.
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
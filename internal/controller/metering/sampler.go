@@ -0,0 +1,358 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metering integrates the resources recorded on Workloads over time
+// into per-namespace, per-hour UsageRollups that usage reports are built
+// from.
+//
+// Everything billable is already a Workload resource: pod requests (cpu,
+// memory, GPUs), PVC sizes (<class>.storageclass.storage.k8s.io/requests.storage),
+// external IPs (<pool>.ipaddresspool.metallb.io/requests.ipaddresses) and
+// bucket bytes (s3-storage-bytes). The sampler holds each sample for the
+// time since the previous one, so a rollup records resource-hours.
+package metering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+)
+
+const (
+	// RollupPrefix names the UsageRollup of a day: usage-<YYYY-MM-DD>.
+	RollupPrefix = "usage-"
+	// DateLayout is the UTC day format of rollup names and status.date.
+	DateLayout = "2006-01-02"
+
+	tenantNamespacePrefix = "tenant-"
+)
+
+// Sampler is a manager Runnable that samples Workloads every Interval and
+// folds the samples into UsageRollups.
+type Sampler struct {
+	// Client lists Workloads (cached) and writes UsageRollups.
+	Client client.Client
+	// Reader reads UsageRollups uncached: a year of rollups across every
+	// tenant is far too much to keep in an informer.
+	Reader client.Reader
+	// Interval is the sampling period.
+	Interval time.Duration
+	// Retention is how long rollups are kept; 0 keeps them forever.
+	Retention time.Duration
+
+	last       time.Time
+	lastPruned string
+}
+
+// Start implements manager.Runnable
+func (s *Sampler) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if err := s.Sample(ctx, now.UTC()); err != nil {
+				log.FromContext(ctx).Error(err, "usage sampling failed")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable; two
+// samplers would double every figure.
+func (s *Sampler) NeedLeaderElection() bool {
+	return true
+}
+
+// Sample takes one sample at now and credits it for the time since the
+// previous one. After a restart, or a takeover of the leader lease, the
+// previous sample is the LastSampleTime recorded on each namespace's
+// rollup, so time the last leader credited is not credited again; a
+// namespace never sampled before is credited one Interval. A gap longer
+// than two Intervals (the controller was down, or no replica held the
+// lease) is credited only two, so an outage shows up as SampledSeconds
+// short of an hour rather than as usage that may not have existed.
+func (s *Sampler) Sample(ctx context.Context, now time.Time) error {
+	workloads := &cozyv1alpha1.WorkloadList{}
+	if err := s.Client.List(ctx, workloads); err != nil {
+		return fmt.Errorf("list workloads: %w", err)
+	}
+	sample := Collect(workloads.Items)
+
+	namespaces := make([]string, 0, len(sample))
+	for ns := range sample {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	// A failed write is not retried: crediting the span again on the next
+	// tick would double-count every namespace that did succeed.
+	var errs []error
+	for _, ns := range namespaces {
+		last := s.last
+		if last.IsZero() {
+			var err error
+			if last, err = s.lastSampled(ctx, ns, now); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		for _, seg := range SplitHours(s.spanStart(last, now), now) {
+			if err := s.credit(ctx, ns, seg, now, sample[ns]); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	s.last = now
+
+	if today := now.Format(DateLayout); s.Retention > 0 && s.lastPruned != today {
+		if err := s.prune(ctx, now); err != nil {
+			errs = append(errs, err)
+		} else {
+			s.lastPruned = today
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// spanStart is where the span credited by a sample at now begins, given
+// the previous sample; zero means there was none.
+func (s *Sampler) spanStart(last, now time.Time) time.Time {
+	switch {
+	case last.IsZero():
+		return now.Add(-s.Interval)
+	case !last.Before(now):
+		return now
+	}
+	if earliest := now.Add(-2 * s.Interval); last.Before(earliest) {
+		return earliest
+	}
+	return last
+}
+
+// lastSampled reads the latest LastSampleTime recorded for a namespace in
+// the rollups a sample at now could still credit. It is zero when there
+// is none.
+func (s *Sampler) lastSampled(ctx context.Context, namespace string, now time.Time) (time.Time, error) {
+	var last time.Time
+	dates := []string{now.Add(-2 * s.Interval).Format(DateLayout)}
+	if today := now.Format(DateLayout); today != dates[0] {
+		dates = append(dates, today)
+	}
+	for _, date := range dates {
+		key := types.NamespacedName{Namespace: namespace, Name: RollupPrefix + date}
+		rollup := &cozyv1alpha1.UsageRollup{}
+		if err := s.Reader.Get(ctx, key, rollup); apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return time.Time{}, fmt.Errorf("get usage rollup %s: %w", key, err)
+		}
+		if t := rollup.Status.LastSampleTime; t != nil && t.After(last) {
+			last = t.Time
+		}
+	}
+	return last, nil
+}
+
+// AppKey identifies the Application a Workload belongs to. The zero value
+// collects Workloads without Application labels.
+type AppKey struct {
+	Kind string
+	Name string
+}
+
+// Collect sums the resources of tenant Workloads by namespace and
+// Application.
+func Collect(workloads []cozyv1alpha1.Workload) map[string]map[AppKey]map[string]resource.Quantity {
+	out := map[string]map[AppKey]map[string]resource.Quantity{}
+	for i := range workloads {
+		w := &workloads[i]
+		if !strings.HasPrefix(w.Namespace, tenantNamespacePrefix) || len(w.Status.Resources) == 0 {
+			continue
+		}
+		key := AppKey{
+			Kind: w.Labels[appsv1alpha1.ApplicationKindLabel],
+			Name: w.Labels[appsv1alpha1.ApplicationNameLabel],
+		}
+		if out[w.Namespace] == nil {
+			out[w.Namespace] = map[AppKey]map[string]resource.Quantity{}
+		}
+		sums := out[w.Namespace][key]
+		if sums == nil {
+			sums = map[string]resource.Quantity{}
+			out[w.Namespace][key] = sums
+		}
+		for name, q := range w.Status.Resources {
+			sum := sums[name]
+			sum.Add(q)
+			sums[name] = sum
+		}
+	}
+	return out
+}
+
+// Segment is the part of a sampling span that falls within one hour.
+type Segment struct {
+	Hour    time.Time
+	Seconds int64
+}
+
+// SplitHours cuts [from, to) at hour boundaries, so a span crossing the
+// hour, or midnight, is credited to each hour it covers.
+func SplitHours(from, to time.Time) []Segment {
+	var out []Segment
+	from, to = from.UTC(), to.UTC()
+	for from.Before(to) {
+		hour := from.Truncate(time.Hour)
+		end := hour.Add(time.Hour)
+		if to.Before(end) {
+			end = to
+		}
+		if secs := int64(end.Sub(from).Round(time.Second) / time.Second); secs > 0 {
+			out = append(out, Segment{Hour: hour, Seconds: secs})
+		}
+		from = end
+	}
+	return out
+}
+
+// ResourceHours is q held for seconds, in units of q times hours. Values
+// below a billion keep millis; larger ones (byte counts) are whole units.
+func ResourceHours(q resource.Quantity, seconds int64) resource.Quantity {
+	v := q.AsApproximateFloat64() * float64(seconds) / 3600
+	if math.Abs(v) < 1e9 {
+		return *resource.NewMilliQuantity(int64(math.Round(v*1000)), q.Format)
+	}
+	return *resource.NewQuantity(int64(math.Round(v)), q.Format)
+}
+
+// credit adds one segment of a namespace's sample, taken at now, to its
+// daily rollup.
+func (s *Sampler) credit(ctx context.Context, namespace string, seg Segment, now time.Time, apps map[AppKey]map[string]resource.Quantity) error {
+	date := seg.Hour.Format(DateLayout)
+	key := types.NamespacedName{Namespace: namespace, Name: RollupPrefix + date}
+	rollup := &cozyv1alpha1.UsageRollup{}
+	err := s.Reader.Get(ctx, key, rollup)
+	if apierrors.IsNotFound(err) {
+		rollup = &cozyv1alpha1.UsageRollup{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+		if err = s.Client.Create(ctx, rollup); err != nil {
+			return fmt.Errorf("create usage rollup %s: %w", key, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("get usage rollup %s: %w", key, err)
+	}
+
+	rollup.Status.Date = date
+	AddToRollup(&rollup.Status, seg, apps)
+	if last := rollup.Status.LastSampleTime; last == nil || last.Time.Before(now) {
+		rollup.Status.LastSampleTime = &metav1.Time{Time: now}
+	}
+	if err := s.Client.Status().Update(ctx, rollup); err != nil {
+		return fmt.Errorf("update usage rollup %s: %w", key, err)
+	}
+	return nil
+}
+
+// AddToRollup folds a segment into status, keeping hours and applications
+// sorted so rewrites of an unchanged rollup are stable.
+func AddToRollup(status *cozyv1alpha1.UsageRollupStatus, seg Segment, apps map[AppKey]map[string]resource.Quantity) {
+	var hour *cozyv1alpha1.UsageHour
+	for i := range status.Hours {
+		if status.Hours[i].Start.Time.Equal(seg.Hour) {
+			hour = &status.Hours[i]
+			break
+		}
+	}
+	if hour == nil {
+		status.Hours = append(status.Hours, cozyv1alpha1.UsageHour{Start: metav1.NewTime(seg.Hour)})
+		sort.Slice(status.Hours, func(i, j int) bool { return status.Hours[i].Start.Before(&status.Hours[j].Start) })
+		for i := range status.Hours {
+			if status.Hours[i].Start.Time.Equal(seg.Hour) {
+				hour = &status.Hours[i]
+			}
+		}
+	}
+	hour.SampledSeconds += seg.Seconds
+	if hour.SampledSeconds > 3600 {
+		hour.SampledSeconds = 3600
+	}
+
+	for key, resources := range apps {
+		var usage *cozyv1alpha1.ApplicationUsage
+		for i := range hour.Applications {
+			if hour.Applications[i].Kind == key.Kind && hour.Applications[i].Name == key.Name {
+				usage = &hour.Applications[i]
+				break
+			}
+		}
+		if usage == nil {
+			hour.Applications = append(hour.Applications, cozyv1alpha1.ApplicationUsage{Kind: key.Kind, Name: key.Name})
+			usage = &hour.Applications[len(hour.Applications)-1]
+		}
+		if usage.Usage == nil {
+			usage.Usage = map[string]resource.Quantity{}
+		}
+		for name, q := range resources {
+			sum := usage.Usage[name]
+			sum.Add(ResourceHours(q, seg.Seconds))
+			usage.Usage[name] = sum
+		}
+	}
+	sort.Slice(hour.Applications, func(i, j int) bool {
+		a, b := hour.Applications[i], hour.Applications[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+}
+
+// prune deletes rollups of days older than Retention.
+func (s *Sampler) prune(ctx context.Context, now time.Time) error {
+	cutoff := now.Add(-s.Retention).Format(DateLayout)
+	rollups := &metav1.PartialObjectMetadataList{}
+	rollups.SetGroupVersionKind(cozyv1alpha1.GroupVersion.WithKind("UsageRollupList"))
+	if err := s.Reader.List(ctx, rollups); err != nil {
+		return fmt.Errorf("list usage rollups: %w", err)
+	}
+	for i := range rollups.Items {
+		r := &rollups.Items[i]
+		date, ok := strings.CutPrefix(r.Name, RollupPrefix)
+		if !ok || date >= cutoff {
+			continue
+		}
+		r.SetGroupVersionKind(cozyv1alpha1.GroupVersion.WithKind("UsageRollup"))
+		if err := s.Client.Delete(ctx, r); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("delete usage rollup %s/%s: %w", r.Namespace, r.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metering
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+)
+
+func workload(ns, name, kind, app string, resources map[string]string) *cozyv1alpha1.Workload {
+	w := &cozyv1alpha1.Workload{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+	if kind != "" {
+		w.Labels = map[string]string{
+			appsv1alpha1.ApplicationKindLabel: kind,
+			appsv1alpha1.ApplicationNameLabel: app,
+		}
+	}
+	w.Status.Resources = map[string]resource.Quantity{}
+	for k, v := range resources {
+		w.Status.Resources[k] = resource.MustParse(v)
+	}
+	return w
+}
+
+func TestSplitHours(t *testing.T) {
+	from := time.Date(2026, 3, 1, 23, 55, 0, 0, time.UTC)
+	got := SplitHours(from, from.Add(10*time.Minute))
+	if len(got) != 2 {
+		t.Fatalf("segments = %+v, want two", got)
+	}
+	if got[0].Hour.Hour() != 23 || got[0].Seconds != 300 || got[1].Hour.Day() != 2 || got[1].Seconds != 300 {
+		t.Errorf("segments = %+v, want 300s before and after midnight", got)
+	}
+}
+
+func TestSample(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := cozyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("register scheme: %v", err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithStatusSubresource(&cozyv1alpha1.UsageRollup{}).
+		WithObjects(
+			workload("tenant-foo", "pod-db-0", "Postgres", "db", map[string]string{"cpu": "2", "memory": "4Gi"}),
+			workload("tenant-foo", "pvc-db-0", "Postgres", "db", map[string]string{"replicated.storageclass.storage.k8s.io/requests.storage": "10Gi"}),
+			workload("tenant-foo", "pod-debug", "", "", map[string]string{"cpu": "500m"}),
+			workload("cozy-system", "pod-controller", "", "", map[string]string{"cpu": "1"}),
+		).Build()
+	s := &Sampler{Client: c, Reader: c, Interval: 30 * time.Minute}
+	ctx := context.Background()
+
+	start := time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC)
+	for _, at := range []time.Time{start, start.Add(30 * time.Minute), start.Add(60 * time.Minute)} {
+		if err := s.Sample(ctx, at); err != nil {
+			t.Fatalf("Sample(%s): %v", at, err)
+		}
+	}
+
+	rollup := &cozyv1alpha1.UsageRollup{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "tenant-foo", Name: "usage-2026-03-01"}, rollup); err != nil {
+		t.Fatalf("get rollup: %v", err)
+	}
+	if len(rollup.Status.Hours) != 2 {
+		t.Fatalf("hours = %d, want 10:00 and 11:00", len(rollup.Status.Hours))
+	}
+	ten := rollup.Status.Hours[0]
+	if ten.SampledSeconds != 3600 {
+		t.Errorf("10:00 sampledSeconds = %d, want 3600", ten.SampledSeconds)
+	}
+	if len(ten.Applications) != 2 || ten.Applications[0].Kind != "" || ten.Applications[1].Name != "db" {
+		t.Fatalf("applications = %+v, want unlabelled then Postgres/db", ten.Applications)
+	}
+	db := ten.Applications[1].Usage
+	if q := db["cpu"]; q.Cmp(resource.MustParse("2")) != 0 {
+		t.Errorf("db cpu = %s, want 2 cpu-hours", q.String())
+	}
+	if q := db["replicated.storageclass.storage.k8s.io/requests.storage"]; q.Cmp(resource.MustParse("10Gi")) != 0 {
+		t.Errorf("db storage = %s, want 10Gi-hours", q.String())
+	}
+	if q := ten.Applications[0].Usage["cpu"]; q.Cmp(resource.MustParse("500m")) != 0 {
+		t.Errorf("unlabelled cpu = %s, want 500m cpu-hours", q.String())
+	}
+	if eleven := rollup.Status.Hours[1]; eleven.SampledSeconds != 1800 {
+		t.Errorf("11:00 sampledSeconds = %d, want 1800", eleven.SampledSeconds)
+	}
+
+	list := &cozyv1alpha1.UsageRollupList{}
+	if err := c.List(ctx, list); err != nil {
+		t.Fatalf("list rollups: %v", err)
+	}
+	if len(list.Items) != 1 {
+		t.Errorf("rollups = %d, want only tenant-foo's", len(list.Items))
+	}
+}
+
+func TestSample_CapsGaps(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := cozyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("register scheme: %v", err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithStatusSubresource(&cozyv1alpha1.UsageRollup{}).
+		WithObjects(workload("tenant-foo", "pod-vm", "VMInstance", "vm", map[string]string{"cpu": "1"})).
+		Build()
+	s := &Sampler{Client: c, Reader: c, Interval: 5 * time.Minute}
+	ctx := context.Background()
+
+	start := time.Date(2026, 3, 1, 10, 5, 0, 0, time.UTC)
+	for _, at := range []time.Time{start, start.Add(40 * time.Minute)} {
+		if err := s.Sample(ctx, at); err != nil {
+			t.Fatalf("Sample(%s): %v", at, err)
+		}
+	}
+	rollup := &cozyv1alpha1.UsageRollup{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "tenant-foo", Name: "usage-2026-03-01"}, rollup); err != nil {
+		t.Fatalf("get rollup: %v", err)
+	}
+	// 5m for the first sample plus 10m (two intervals) for the 40m gap.
+	if got := rollup.Status.Hours[0].SampledSeconds; got != 900 {
+		t.Errorf("sampledSeconds = %d, want 900", got)
+	}
+}
+
+func TestSample_ResumesAfterFailover(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := cozyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("register scheme: %v", err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithStatusSubresource(&cozyv1alpha1.UsageRollup{}).
+		WithObjects(workload("tenant-foo", "pod-vm", "VMInstance", "vm", map[string]string{"cpu": "1"})).
+		Build()
+	ctx := context.Background()
+
+	start := time.Date(2026, 3, 1, 10, 10, 0, 0, time.UTC)
+	leader := &Sampler{Client: c, Reader: c, Interval: 10 * time.Minute}
+	for _, at := range []time.Time{start, start.Add(10 * time.Minute)} {
+		if err := leader.Sample(ctx, at); err != nil {
+			t.Fatalf("Sample(%s): %v", at, err)
+		}
+	}
+	// The standby's ticker runs on its own schedule: its first tick after
+	// taking over the lease comes 3m after the old leader's last sample,
+	// and must credit those 3m rather than a whole Interval.
+	standby := &Sampler{Client: c, Reader: c, Interval: 10 * time.Minute}
+	if err := standby.Sample(ctx, start.Add(13*time.Minute)); err != nil {
+		t.Fatalf("Sample after failover: %v", err)
+	}
+
+	rollup := &cozyv1alpha1.UsageRollup{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "tenant-foo", Name: "usage-2026-03-01"}, rollup); err != nil {
+		t.Fatalf("get rollup: %v", err)
+	}
+	// 10m for the first sample, 10m for the second and 3m after failover.
+	if got := rollup.Status.Hours[0].SampledSeconds; got != 23*60 {
+		t.Errorf("sampledSeconds = %d, want %d", got, 23*60)
+	}
+	// 167m + 167m + 50m, each sample rounded to millis.
+	if q := rollup.Status.Hours[0].Applications[0].Usage["cpu"]; q.Cmp(resource.MustParse("384m")) != 0 {
+		t.Errorf("cpu = %s, want 384m cpu-hours", q.String())
+	}
+	if got := rollup.Status.LastSampleTime; got == nil || !got.Time.Equal(start.Add(13*time.Minute)) {
+		t.Errorf("lastSampleTime = %v, want %s", got, start.Add(13*time.Minute))
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcilehistory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/pkg/apis/meta"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=helm.toolkit.fluxcd.io,resources=helmreleases,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cozystack.io,resources=reconcilehistories,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=cozystack.io,resources=reconcilehistories/status,verbs=update
+
+// Reconciler keeps a ReconcileHistory next to every Application HelmRelease,
+// so tenants can see why an Application is stuck without access to
+// helm-controller logs or cluster-scoped events.
+type Reconciler struct {
+	client.Client
+	// Limit is how many finished attempts are kept; DefaultLimit if zero.
+	Limit int
+	// now stubs the clock in tests.
+	now func() time.Time
+}
+
+func (r *Reconciler) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+func (r *Reconciler) limit() int {
+	if r.Limit > 0 {
+		return r.Limit
+	}
+	return DefaultLimit
+}
+
+// Reconcile records the current status of one HelmRelease.
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	hr := &helmv2.HelmRelease{}
+	if err := r.Get(ctx, req.NamespacedName, hr); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !hr.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	history := &cozyv1alpha1.ReconcileHistory{}
+	err := r.Get(ctx, req.NamespacedName, history)
+	if apierrors.IsNotFound(err) {
+		history = &cozyv1alpha1.ReconcileHistory{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      hr.Name,
+				Namespace: hr.Namespace,
+				Labels: map[string]string{
+					appsv1alpha1.ApplicationKindLabel: hr.Labels[appsv1alpha1.ApplicationKindLabel],
+					appsv1alpha1.ApplicationNameLabel: hr.Labels[appsv1alpha1.ApplicationNameLabel],
+				},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion:         helmv2.GroupVersion.String(),
+					Kind:               helmv2.HelmReleaseKind,
+					Name:               hr.Name,
+					UID:                hr.UID,
+					BlockOwnerDeletion: ptr.To(false),
+				}},
+			},
+		}
+		if err := r.Create(ctx, history); err != nil {
+			return ctrl.Result{}, fmt.Errorf("creating ReconcileHistory: %w", err)
+		}
+	} else if err != nil {
+		return ctrl.Result{}, fmt.Errorf("getting ReconcileHistory: %w", err)
+	}
+
+	if !Record(&history.Status, hr, r.clock(), r.limit()) {
+		return ctrl.Result{}, nil
+	}
+	if err := r.Status().Update(ctx, history); err != nil {
+		return ctrl.Result{}, fmt.Errorf("updating ReconcileHistory: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager watches Application HelmReleases. Their status is
+// patched constantly; only changes of the conditions and counters an
+// attempt is reconstructed from are passed on.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("reconcilehistory").
+		For(&helmv2.HelmRelease{}, builder.WithPredicates(isApplication(), attemptChanged())).
+		Complete(r)
+}
+
+func isApplication() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetLabels()[appsv1alpha1.ApplicationKindLabel] != ""
+	})
+}
+
+func attemptChanged() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldHR, ok := e.ObjectOld.(*helmv2.HelmRelease)
+			if !ok {
+				return true
+			}
+			newHR, ok := e.ObjectNew.(*helmv2.HelmRelease)
+			if !ok {
+				return true
+			}
+			return attemptState(oldHR) != attemptState(newHR)
+		},
+		DeleteFunc: func(event.DeleteEvent) bool { return false },
+	}
+}
+
+// attemptState summarises what Record looks at.
+func attemptState(hr *helmv2.HelmRelease) string {
+	state := ""
+	for _, t := range []string{meta.ReadyCondition, meta.ReconcilingCondition} {
+		if c := apimeta.FindStatusCondition(hr.Status.Conditions, t); c != nil {
+			state += fmt.Sprintf("%s=%s/%s/%s/%s;", t, c.Status, c.Reason, c.Message, c.LastTransitionTime.UTC())
+		}
+	}
+	return state + fmt.Sprintf("%d/%s/%s/%d/%d/%d", hr.Status.LastAttemptedGeneration, hr.Status.LastAttemptedRevision,
+		hr.Status.LastAttemptedConfigDigest, hr.Status.Failures, hr.Status.InstallFailures, hr.Status.UpgradeFailures)
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcilehistory
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/pkg/apis/meta"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+)
+
+// ShardKeyLabel is the Flux sharding label naming the helm-controller shard
+// of a HelmRelease.
+const ShardKeyLabel = "sharding.fluxcd.io/key"
+
+// DefaultLimit is how many finished attempts are kept per HelmRelease.
+const DefaultLimit = 20
+
+// Record folds the current status of hr into history and reports whether
+// history changed. helm-controller exposes no per-attempt log, so attempts
+// are reconstructed from status transitions: an attempt starts when the
+// HelmRelease turns Reconciling and ends when its Ready condition settles on
+// an outcome not recorded yet. A no-op reconcile leaves the status alone and
+// is not recorded; a retry that fails the same way again bumps the failure
+// counters and is.
+func Record(history *cozyv1alpha1.ReconcileHistoryStatus, hr *helmv2.HelmRelease, now time.Time, limit int) bool {
+	ready := apimeta.FindStatusCondition(hr.Status.Conditions, meta.ReadyCondition)
+	reconciling := apimeta.FindStatusCondition(hr.Status.Conditions, meta.ReconcilingCondition)
+
+	if reconciling != nil && reconciling.Status == metav1.ConditionTrue ||
+		ready != nil && ready.Status == metav1.ConditionUnknown {
+		if history.InProgress != nil {
+			return false
+		}
+		start := metav1.NewTime(now)
+		if reconciling != nil && !reconciling.LastTransitionTime.IsZero() {
+			start = reconciling.LastTransitionTime
+		}
+		history.InProgress = attempt(hr, start, cozyv1alpha1.ReconcileInProgress, reconciling)
+		return true
+	}
+	if ready == nil {
+		return false
+	}
+
+	state := fingerprint(hr, ready)
+	if state == history.ObservedState && history.InProgress == nil {
+		return false
+	}
+
+	// A Ready transition carries its own timestamp; an outcome that leaves
+	// the condition status as it was is only known from when it is seen.
+	completed := metav1.NewTime(now)
+	if len(history.Attempts) == 0 || ready.LastTransitionTime.After(history.Attempts[0].CompletionTime.Time) {
+		if !ready.LastTransitionTime.IsZero() && !ready.LastTransitionTime.After(now) {
+			completed = ready.LastTransitionTime
+		}
+	}
+	start := completed
+	switch {
+	case history.InProgress != nil && !history.InProgress.StartTime.After(completed.Time):
+		start = history.InProgress.StartTime
+	case hr.Status.LastAttemptedReleaseActionDuration != nil:
+		start = metav1.NewTime(completed.Add(-hr.Status.LastAttemptedReleaseActionDuration.Duration))
+	}
+
+	outcome := cozyv1alpha1.ReconcileFailed
+	if ready.Status == metav1.ConditionTrue {
+		outcome = cozyv1alpha1.ReconcileSucceeded
+	}
+	done := attempt(hr, start, outcome, ready)
+	done.CompletionTime = &completed
+
+	history.Attempts = append([]cozyv1alpha1.ReconcileAttempt{*done}, history.Attempts...)
+	if limit > 0 && len(history.Attempts) > limit {
+		history.Attempts = history.Attempts[:limit]
+	}
+	history.InProgress = nil
+	history.ObservedState = state
+	return true
+}
+
+// attempt describes the attempt hr is at, explained by cond.
+func attempt(hr *helmv2.HelmRelease, start metav1.Time, outcome string, cond *metav1.Condition) *cozyv1alpha1.ReconcileAttempt {
+	a := &cozyv1alpha1.ReconcileAttempt{
+		StartTime:    start,
+		Outcome:      outcome,
+		Action:       string(hr.Status.LastAttemptedReleaseAction),
+		ChartVersion: chartVersion(hr),
+		Generation:   hr.Status.LastAttemptedGeneration,
+		Shard:        hr.Labels[ShardKeyLabel],
+	}
+	if a.Generation == 0 {
+		a.Generation = hr.Generation
+	}
+	if cond != nil {
+		a.Reason, a.Message = cond.Reason, cond.Message
+	}
+	return a
+}
+
+// chartVersion is the chart version last attempted, falling back to the one
+// last released.
+func chartVersion(hr *helmv2.HelmRelease) string {
+	if hr.Status.LastAttemptedRevision != "" {
+		return hr.Status.LastAttemptedRevision
+	}
+	if latest := hr.Status.History.Latest(); latest != nil {
+		return latest.ChartVersion
+	}
+	return ""
+}
+
+// fingerprint identifies an outcome: what was attempted, how it ended and
+// how often it failed.
+func fingerprint(hr *helmv2.HelmRelease, ready *metav1.Condition) string {
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%s\x00%s\x00%s\x00%d\x00%s\x00%s\x00%d\x00%d\x00%d",
+		ready.Status, ready.Reason, ready.Message,
+		hr.Status.LastAttemptedGeneration, hr.Status.LastAttemptedRevision, hr.Status.LastAttemptedConfigDigest,
+		hr.Status.Failures, hr.Status.InstallFailures, hr.Status.UpgradeFailures)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
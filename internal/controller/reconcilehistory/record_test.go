@@ -0,0 +1,165 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcilehistory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/pkg/apis/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+)
+
+var t0 = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func release(conds ...metav1.Condition) *helmv2.HelmRelease {
+	hr := &helmv2.HelmRelease{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "tenant-a",
+		Name:      "postgres-db",
+		UID:       "uid-1",
+		Labels: map[string]string{
+			appsv1alpha1.ApplicationKindLabel: "Postgres",
+			appsv1alpha1.ApplicationNameLabel: "db",
+			ShardKeyLabel:                     "shard2",
+		},
+	}}
+	hr.Status.Conditions = conds
+	hr.Status.LastAttemptedGeneration = 3
+	hr.Status.LastAttemptedRevision = "0.12.1"
+	hr.Status.LastAttemptedReleaseAction = helmv2.ReleaseActionUpgrade
+	return hr
+}
+
+func cond(t string, status metav1.ConditionStatus, reason, msg string, at time.Time) metav1.Condition {
+	return metav1.Condition{Type: t, Status: status, Reason: reason, Message: msg, LastTransitionTime: metav1.NewTime(at)}
+}
+
+func TestRecordInProgressThenSucceeded(t *testing.T) {
+	var h cozyv1alpha1.ReconcileHistoryStatus
+
+	running := release(cond(meta.ReconcilingCondition, metav1.ConditionTrue, "Progressing", "", t0),
+		cond(meta.ReadyCondition, metav1.ConditionUnknown, "Progressing", "", t0))
+	if !Record(&h, running, t0.Add(time.Second), 10) {
+		t.Fatal("start of an attempt not recorded")
+	}
+	if h.InProgress == nil || !h.InProgress.StartTime.Time.Equal(t0) || h.InProgress.Outcome != cozyv1alpha1.ReconcileInProgress {
+		t.Fatalf("in progress = %+v, want started at %v", h.InProgress, t0)
+	}
+	if Record(&h, running, t0.Add(2*time.Second), 10) {
+		t.Error("an attempt still in progress was recorded twice")
+	}
+
+	done := release(cond(meta.ReadyCondition, metav1.ConditionTrue, "UpgradeSucceeded", "upgraded", t0.Add(40*time.Second)))
+	if !Record(&h, done, t0.Add(41*time.Second), 10) {
+		t.Fatal("outcome not recorded")
+	}
+	if h.InProgress != nil || len(h.Attempts) != 1 {
+		t.Fatalf("history = %+v, want one finished attempt", h)
+	}
+	a := h.Attempts[0]
+	if a.Outcome != cozyv1alpha1.ReconcileSucceeded || a.Reason != "UpgradeSucceeded" || a.Action != "upgrade" ||
+		a.ChartVersion != "0.12.1" || a.Generation != 3 || a.Shard != "shard2" {
+		t.Errorf("attempt = %+v", a)
+	}
+	if !a.StartTime.Time.Equal(t0) || !a.CompletionTime.Time.Equal(t0.Add(40*time.Second)) {
+		t.Errorf("attempt ran %v-%v, want %v-%v", a.StartTime, a.CompletionTime, t0, t0.Add(40*time.Second))
+	}
+
+	if Record(&h, done, t0.Add(time.Hour), 10) {
+		t.Error("an unchanged status was recorded again")
+	}
+}
+
+func TestRecordRepeatedFailure(t *testing.T) {
+	var h cozyv1alpha1.ReconcileHistoryStatus
+
+	failed := release(cond(meta.ReadyCondition, metav1.ConditionFalse, "UpgradeFailed", "timed out", t0))
+	failed.Status.Failures = 1
+	failed.Status.LastAttemptedReleaseActionDuration = &metav1.Duration{Duration: 5 * time.Minute}
+	if !Record(&h, failed, t0.Add(time.Second), 10) {
+		t.Fatal("failure not recorded")
+	}
+	if got := h.Attempts[0].StartTime.Time; !got.Equal(t0.Add(-5 * time.Minute)) {
+		t.Errorf("start = %v, want derived from the action duration", got)
+	}
+
+	// The retry fails the same way: Ready keeps its transition time, only
+	// the counter moves.
+	again := failed.DeepCopy()
+	again.Status.Failures = 2
+	if !Record(&h, again, t0.Add(10*time.Minute), 10) {
+		t.Fatal("repeated failure not recorded")
+	}
+	if len(h.Attempts) != 2 || h.Attempts[0].Outcome != cozyv1alpha1.ReconcileFailed || h.Attempts[0].Message != "timed out" {
+		t.Fatalf("attempts = %+v, want two failures", h.Attempts)
+	}
+	if got := h.Attempts[0].CompletionTime.Time; !got.Equal(t0.Add(10 * time.Minute)) {
+		t.Errorf("completion = %v, want when the retry was observed", got)
+	}
+}
+
+func TestRecordLimit(t *testing.T) {
+	var h cozyv1alpha1.ReconcileHistoryStatus
+	for i := range 5 {
+		hr := release(cond(meta.ReadyCondition, metav1.ConditionFalse, "InstallFailed", "boom", t0))
+		hr.Status.Failures = int64(i + 1)
+		Record(&h, hr, t0.Add(time.Duration(i)*time.Minute), 3)
+	}
+	if len(h.Attempts) != 3 {
+		t.Fatalf("kept %d attempts, want 3", len(h.Attempts))
+	}
+	if !h.Attempts[0].CompletionTime.Time.Equal(t0.Add(4 * time.Minute)) {
+		t.Errorf("newest attempt completed %v, want the last one first", h.Attempts[0].CompletionTime)
+	}
+}
+
+func TestReconcileCreatesOwnedHistory(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = helmv2.AddToScheme(scheme)
+	_ = cozyv1alpha1.AddToScheme(scheme)
+	hr := release(cond(meta.ReadyCondition, metav1.ConditionTrue, "InstallSucceeded", "installed", t0))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hr).
+		WithStatusSubresource(&cozyv1alpha1.ReconcileHistory{}).Build()
+	r := &Reconciler{Client: c, now: func() time.Time { return t0.Add(time.Minute) }}
+
+	key := types.NamespacedName{Namespace: "tenant-a", Name: "postgres-db"}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	got := &cozyv1alpha1.ReconcileHistory{}
+	if err := c.Get(context.Background(), key, got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.OwnerReferences) != 1 || got.OwnerReferences[0].UID != "uid-1" {
+		t.Errorf("owner references = %+v, want the HelmRelease", got.OwnerReferences)
+	}
+	if got.Labels[appsv1alpha1.ApplicationNameLabel] != "db" {
+		t.Errorf("labels = %v", got.Labels)
+	}
+	if len(got.Status.Attempts) != 1 || got.Status.Attempts[0].Outcome != cozyv1alpha1.ReconcileSucceeded {
+		t.Errorf("status = %+v, want one successful attempt", got.Status)
+	}
+}
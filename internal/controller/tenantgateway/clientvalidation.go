@@ -0,0 +1,592 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantgateway
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+	"github.com/cozystack/cozystack/internal/controller/cacert"
+	corev1alpha1 "github.com/cozystack/cozystack/pkg/apis/core/v1alpha1"
+)
+
+// clientCAKey is the key of the CA bundle in the ConfigMap rendered
+// for each ClientValidation entry, and the default key read from a
+// TenantSecret source.
+const clientCAKey = "ca.crt"
+
+// clientValidationNameLabel marks the CA ConfigMaps rendered for
+// Spec.ClientValidation with the name of their entry.
+const clientValidationNameLabel = "gateway.cozystack.io/client-validation"
+
+// ConfigMapCacheByObject returns the cache scoping the cozystack-controller
+// manager must apply to corev1.ConfigMap. The only ConfigMaps this
+// controller reads or owns are the client CA bundles it renders, all
+// labelled managed-by cozystack-controller, so the informer behind
+// Owns(&corev1.ConfigMap{}) never holds the rest of the cluster's.
+func ConfigMapCacheByObject() cache.ByObject {
+	return cache.ByObject{
+		Label: labels.SelectorFromSet(labels.Set{cozystackManagedByLabel: cozystackManagedByValue}),
+	}
+}
+
+var envoyClientTrafficPolicyGVK = schema.GroupVersionKind{Group: "gateway.envoyproxy.io", Version: "v1alpha1", Kind: "ClientTrafficPolicy"}
+
+// clientValidationResult is the verification state of one listener,
+// keyed by listener name in the map reconcileClientValidation returns.
+type clientValidationResult struct {
+	state   gatewayv1alpha1.ClientValidationState
+	message string
+}
+
+// clientValidationObjectName names both the CA ConfigMap and the
+// ClientTrafficPolicy of one entry. Both live in the TenantGateway
+// namespace next to the Gateway they configure.
+func clientValidationObjectName(tgw *gatewayv1alpha1.TenantGateway, name string) string {
+	return tgw.Name + "-mtls-" + name
+}
+
+// clientValidationListenerHostnames returns the hostnames that need a
+// dedicated listener in dns01 / existingSecret mode: the wildcard
+// listener cannot carry per-hostname client validation, so every
+// hostname the wildcard certificate covers gets a listener of its own
+// that reuses it. Hostnames outside the certificate are left alone;
+// no listener can serve them, and so are TLS-passthrough hostnames,
+// whose listener already exists and never terminates TLS.
+func clientValidationListenerHostnames(tgw *gatewayv1alpha1.TenantGateway, childApexes []string) []string {
+	apexes := append([]string{tgw.Spec.Apex}, childApexes...)
+	seen := map[string]struct{}{}
+	for _, svc := range tgw.Spec.TLSPassthroughServices {
+		seen[strings.ToLower(svc+"."+tgw.Spec.Apex)] = struct{}{}
+	}
+	var out []string
+	for _, cv := range tgw.Spec.ClientValidation {
+		for _, h := range cv.Hostnames {
+			h = strings.ToLower(h)
+			label, parent, ok := strings.Cut(h, ".")
+			if !ok || label == "" || label == "*" {
+				continue
+			}
+			covered := false
+			for _, apex := range apexes {
+				if strings.EqualFold(parent, apex) {
+					covered = true
+					break
+				}
+			}
+			if _, dup := seen[h]; dup || !covered {
+				continue
+			}
+			seen[h] = struct{}{}
+			out = append(out, h)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Gateway API feature names a GatewayClass advertises in
+// status.supportedFeatures when it implements Gateway.spec.tls.frontend.
+// The vendored gateway-api predates their constants.
+const (
+	frontendValidationFeature                 gatewayv1.FeatureName = "GatewayFrontendClientCertificateValidation"
+	frontendValidationInsecureFallbackFeature gatewayv1.FeatureName = "GatewayFrontendClientCertificateValidationInsecureFallback"
+)
+
+// clientValidationSupport is what the GatewayClass of a TenantGateway
+// can do for Spec.ClientValidation.
+type clientValidationSupport struct {
+	// frontend: the class implements the standard
+	// Gateway.spec.tls.frontend; fallback: it also honours
+	// AllowInsecureFallback.
+	frontend, fallback bool
+	// envoy: the class is Envoy Gateway, which takes a
+	// ClientTrafficPolicy per listener.
+	envoy bool
+	// reason explains why neither applies.
+	reason string
+}
+
+// clientValidationSupportOf inspects the GatewayClass behind
+// Spec.GatewayClassName.
+func (r *Reconciler) clientValidationSupportOf(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway) (clientValidationSupport, error) {
+	className := tgw.Spec.GatewayClassName
+	if className == "" {
+		className = defaultGatewayClassName
+	}
+	gc := &gatewayv1.GatewayClass{}
+	if err := r.Get(ctx, types.NamespacedName{Name: className}, gc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return clientValidationSupport{reason: fmt.Sprintf("GatewayClass %q not found", className)}, nil
+		}
+		return clientValidationSupport{}, fmt.Errorf("get GatewayClass %s: %w", className, err)
+	}
+	var out clientValidationSupport
+	for _, f := range gc.Status.SupportedFeatures {
+		switch f.Name {
+		case frontendValidationFeature:
+			out.frontend = true
+		case frontendValidationInsecureFallbackFeature:
+			out.fallback = true
+		}
+	}
+	out.envoy = gc.Spec.ControllerName == envoyGatewayControllerName
+	if !out.frontend && !out.envoy {
+		out.reason = fmt.Sprintf("GatewayClass %q (controller %s) supports neither Gateway frontend TLS validation nor Envoy Gateway policies; enable the cozystack.envoy-gateway package and use the envoy-gateway class", className, gc.Spec.ControllerName)
+	}
+	return out, nil
+}
+
+// standardFrontendReason reports why cv cannot be enforced through
+// Gateway.spec.tls.frontend of gw, or "" when it can. The standard
+// field applies to every HTTPS listener on a port, so it only fits an
+// entry that covers all of them; X-Forwarded-Client-Cert handling has
+// no standard counterpart.
+func standardFrontendReason(gw *gatewayv1.Gateway, support clientValidationSupport, cv *gatewayv1alpha1.ClientValidation, sections []string) string {
+	switch {
+	case !support.frontend && support.reason != "":
+		return support.reason
+	case !support.frontend:
+		return "the GatewayClass does not advertise the GatewayFrontendClientCertificateValidation feature"
+	case cv.ForwardSubject:
+		return "forwardSubject needs Envoy Gateway; Gateway frontend TLS validation cannot strip or set X-Forwarded-Client-Cert"
+	case cv.Optional && !support.fallback:
+		return "optional needs the GatewayFrontendClientCertificateValidationInsecureFallback feature, which the GatewayClass does not advertise"
+	}
+	covered := map[string]struct{}{}
+	for _, s := range sections {
+		covered[s] = struct{}{}
+	}
+	for _, l := range gw.Spec.Listeners {
+		if l.Protocol != gatewayv1.HTTPSProtocolType {
+			continue
+		}
+		if _, ok := covered[string(l.Name)]; !ok {
+			return fmt.Sprintf("Gateway frontend TLS validation applies to every HTTPS listener on port %d, and listener %s is not covered by this entry; list all of them or use Envoy Gateway", l.Port, l.Name)
+		}
+	}
+	return ""
+}
+
+// renderFrontendValidation sets Gateway.spec.tls.frontend of gw to
+// verify clients against the CA ConfigMap of cv.
+func renderFrontendValidation(tgw *gatewayv1alpha1.TenantGateway, gw *gatewayv1.Gateway, cv *gatewayv1alpha1.ClientValidation) {
+	mode := gatewayv1.AllowValidOnly
+	if cv.Optional {
+		mode = gatewayv1.AllowInsecureFallback
+	}
+	gw.Spec.TLS = &gatewayv1.GatewayTLSConfig{
+		Frontend: &gatewayv1.FrontendTLSConfig{
+			Default: gatewayv1.TLSConfig{
+				Validation: &gatewayv1.FrontendTLSValidation{
+					CACertificateRefs: []gatewayv1.ObjectReference{{
+						Group: "",
+						Kind:  "ConfigMap",
+						Name:  gatewayv1.ObjectName(clientValidationObjectName(tgw, cv.Name)),
+					}},
+					Mode: mode,
+				},
+			},
+		},
+	}
+}
+
+// closeListener stops l from accepting routes. A hostname listed under
+// Spec.ClientValidation whose verification is not in force is refused
+// rather than served without it; the more specific listener still wins
+// the match, so the wildcard does not pick the hostname up.
+func closeListener(l *gatewayv1.Listener) {
+	allowed := &gatewayv1.AllowedRoutes{}
+	if l.AllowedRoutes != nil {
+		allowed = l.AllowedRoutes.DeepCopy()
+	}
+	from := gatewayv1.NamespacesFromNone
+	allowed.Namespaces = &gatewayv1.RouteNamespaces{From: &from}
+	l.AllowedRoutes = allowed
+}
+
+// reconcileClientValidation enforces Spec.ClientValidation on gw, the
+// Gateway about to be applied. Per entry it copies the client CA
+// bundle into a ConfigMap next to the Gateway, then prefers the
+// standard Gateway.spec.tls.frontend when the GatewayClass advertises
+// it and the entry covers every HTTPS listener, and falls back to an
+// Envoy Gateway ClientTrafficPolicy targeting the listeners of its
+// hostnames. The returned map carries the state of every targeted
+// listener for reconcileStatus.
+//
+// An entry whose CA source is missing or malformed keeps the objects
+// rendered from its last valid source, so a broken bundle never lifts
+// client verification that was already enforced. A listener whose
+// verification is not in force at all, Unsupported included, is
+// closed to routes. A hostname no listener serves yet is skipped until
+// a route publishes it.
+func (r *Reconciler) reconcileClientValidation(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway, gw *gatewayv1.Gateway) (map[string]clientValidationResult, error) {
+	rendered := &corev1.ConfigMapList{}
+	if err := r.List(ctx, rendered, client.InNamespace(tgw.Namespace), client.MatchingLabels{
+		cozystackManagedByLabel:      cozystackManagedByValue,
+		policyTenantGatewayNameLabel: tgw.Name,
+	}, client.HasLabels{clientValidationNameLabel}); err != nil {
+		return nil, fmt.Errorf("list client CA ConfigMaps: %w", err)
+	}
+	// Every rendered ClientTrafficPolicy has a CA ConfigMap next to
+	// it, so a TenantGateway with neither spec entries nor ConfigMaps
+	// has nothing to reconcile and never asks for the Envoy CRDs.
+	if len(tgw.Spec.ClientValidation) == 0 && len(rendered.Items) == 0 {
+		return nil, nil
+	}
+	renderedNames := map[string]struct{}{}
+	for i := range rendered.Items {
+		renderedNames[rendered.Items[i].Name] = struct{}{}
+	}
+
+	support, err := r.clientValidationSupportOf(ctx, tgw)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := r.allowedRouteNamespaces(ctx, tgw)
+	if err != nil {
+		return nil, err
+	}
+	listenerByHost := map[string]int{}
+	for i, l := range gw.Spec.Listeners {
+		if l.Hostname == nil || l.Protocol != gatewayv1.HTTPSProtocolType {
+			continue
+		}
+		listenerByHost[strings.ToLower(string(*l.Hostname))] = i
+	}
+
+	results := map[string]clientValidationResult{}
+	claimedBy := map[string]string{}
+	closed := map[string]struct{}{}
+	keep := map[policyObjectKey]struct{}{}
+	keepConfigMaps := map[string]struct{}{}
+	for i := range tgw.Spec.ClientValidation {
+		cv := &tgw.Spec.ClientValidation[i]
+		var sections []string
+		for _, h := range cv.Hostnames {
+			idx, ok := listenerByHost[strings.ToLower(h)]
+			if !ok {
+				continue
+			}
+			listener := string(gw.Spec.Listeners[idx].Name)
+			if owner, taken := claimedBy[listener]; taken {
+				results[listener] = clientValidationResult{
+					state:   gatewayv1alpha1.ClientValidationStateInvalid,
+					message: fmt.Sprintf("hostname %s is listed by clientValidation entries %s and %s; %s is enforced", h, owner, cv.Name, owner),
+				}
+				continue
+			}
+			claimedBy[listener] = cv.Name
+			sections = append(sections, listener)
+		}
+		if len(sections) == 0 {
+			continue
+		}
+
+		result := clientValidationResult{state: gatewayv1alpha1.ClientValidationStateEnforced}
+		objectName := clientValidationObjectName(tgw, cv.Name)
+		standardReason := standardFrontendReason(gw, support, cv, sections)
+		switch {
+		case standardReason != "" && !support.envoy:
+			result = clientValidationResult{state: gatewayv1alpha1.ClientValidationStateUnsupported, message: standardReason}
+		default:
+			bundle, state, msg, err := r.loadClientCABundle(ctx, tgw, cv, allowed)
+			if err != nil {
+				return nil, err
+			}
+			if state != "" {
+				result = clientValidationResult{state: state, message: msg}
+				if _, ok := renderedNames[objectName]; !ok {
+					break
+				}
+				keepConfigMaps[objectName] = struct{}{}
+				if standardReason == "" {
+					renderFrontendValidation(tgw, gw, cv)
+				} else {
+					keep[policyObjectKey{kind: envoyClientTrafficPolicyGVK.Kind, namespace: tgw.Namespace, name: objectName}] = struct{}{}
+				}
+				break
+			}
+			if err := r.applyClientCAConfigMap(ctx, tgw, cv.Name, bundle); err != nil {
+				return nil, err
+			}
+			keepConfigMaps[objectName] = struct{}{}
+			if standardReason == "" {
+				renderFrontendValidation(tgw, gw, cv)
+				break
+			}
+			ctp, err := r.renderClientTrafficPolicy(tgw, cv, sections)
+			if err != nil {
+				return nil, err
+			}
+			if err := r.applyPolicyObject(ctx, tgw, ctp); err != nil {
+				return nil, err
+			}
+			keep[policyObjectKey{kind: ctp.GetKind(), namespace: ctp.GetNamespace(), name: ctp.GetName()}] = struct{}{}
+		}
+		_, inForce := keepConfigMaps[objectName]
+		if !inForce {
+			result.message = strings.TrimPrefix(result.message+"; the listener accepts no routes until client validation is enforced", "; ")
+		}
+		for _, section := range sections {
+			if _, conflict := results[section]; conflict {
+				continue
+			}
+			results[section] = result
+			if !inForce {
+				closed[section] = struct{}{}
+			}
+		}
+	}
+	for i := range gw.Spec.Listeners {
+		if _, ok := closed[string(gw.Spec.Listeners[i].Name)]; ok {
+			closeListener(&gw.Spec.Listeners[i])
+		}
+	}
+
+	if err := r.gcPolicyObjects(ctx, tgw, []schema.GroupVersionKind{envoyClientTrafficPolicyGVK}, keep); err != nil {
+		return nil, err
+	}
+	for i := range rendered.Items {
+		cm := &rendered.Items[i]
+		if _, ok := keepConfigMaps[cm.Name]; ok || !ownedByTenantGateway(cm.OwnerReferences, tgw) {
+			continue
+		}
+		if err := r.Delete(ctx, cm); client.IgnoreNotFound(err) != nil {
+			return nil, fmt.Errorf("delete client CA ConfigMap %s: %w", cm.Name, err)
+		}
+		log.FromContext(ctx).V(1).Info("deleted client CA ConfigMap", "namespace", cm.Namespace, "name", cm.Name)
+	}
+	return results, nil
+}
+
+// loadClientCABundle reads and validates the CA bundle of cv. A
+// non-empty state reports why the bundle cannot be used: Pending while
+// the source Secret does not exist yet, Invalid for everything an
+// operator has to fix. The error return is reserved for API failures.
+func (r *Reconciler) loadClientCABundle(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway, cv *gatewayv1alpha1.ClientValidation, allowed map[string]struct{}) ([]byte, gatewayv1alpha1.ClientValidationState, string, error) {
+	var namespace, name, key string
+	switch src := cv.CABundle; {
+	case src.TenantSecret != nil:
+		namespace, name, key = src.TenantSecret.Namespace, src.TenantSecret.Name, src.TenantSecret.Key
+	case src.TenantCA != nil:
+		namespace, name, key = src.TenantCA.Namespace, cacert.ProjectionName(src.TenantCA.Release), clientCAKey
+	default:
+		return nil, gatewayv1alpha1.ClientValidationStateInvalid, "caBundle sets neither tenantSecret nor tenantCA", nil
+	}
+	if namespace == "" {
+		namespace = tgw.Namespace
+	}
+	if key == "" {
+		key = clientCAKey
+	}
+	// The bundle is copied into the Gateway namespace, so the source
+	// must come from a namespace that may publish through this
+	// Gateway anyway; anything else would let a tenant read CA
+	// material of a namespace it has no business with.
+	if _, ok := allowed[namespace]; !ok {
+		return nil, gatewayv1alpha1.ClientValidationStateInvalid, fmt.Sprintf("namespace %s is not attached to this Gateway", namespace), nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.secretReader().Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, gatewayv1alpha1.ClientValidationStatePending, fmt.Sprintf("Secret %s/%s not found", namespace, name), nil
+		}
+		return nil, "", "", fmt.Errorf("get client CA Secret %s/%s: %w", namespace, name, err)
+	}
+	switch {
+	case cv.CABundle.TenantCA != nil:
+		if secret.Labels[cacert.TenantCALabel] != "true" || !cacert.IsProjectionOf(secret, cv.CABundle.TenantCA.Release) {
+			return nil, gatewayv1alpha1.ClientValidationStateInvalid, fmt.Sprintf("Secret %s/%s is not a tenant-ca projection", namespace, name), nil
+		}
+	case secret.Labels[corev1alpha1.TenantResourceLabelKey] != corev1alpha1.TenantResourceLabelValue:
+		return nil, gatewayv1alpha1.ClientValidationStateInvalid, fmt.Sprintf("Secret %s/%s is not a TenantSecret", namespace, name), nil
+	}
+	bundle, ok := secret.Data[key]
+	if !ok {
+		return nil, gatewayv1alpha1.ClientValidationStateInvalid, fmt.Sprintf("Secret %s/%s has no key %q", namespace, name, key), nil
+	}
+	if err := validateCABundle(bundle); err != nil {
+		return nil, gatewayv1alpha1.ClientValidationStateInvalid, fmt.Sprintf("Secret %s/%s key %q: %v", namespace, name, key, err), nil
+	}
+	return bundle, "", "", nil
+}
+
+// validateCABundle accepts a PEM bundle made only of parseable
+// CERTIFICATE blocks, at least one of them. A private key pasted into
+// the bundle is rejected rather than copied next to the Gateway.
+func validateCABundle(bundle []byte) error {
+	rest := bundle
+	count := 0
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return fmt.Errorf("unexpected PEM block %q", block.Type)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return fmt.Errorf("parse certificate: %w", err)
+		}
+		count++
+	}
+	if count == 0 {
+		return fmt.Errorf("no PEM certificates found")
+	}
+	return nil
+}
+
+// applyClientCAConfigMap writes the validated bundle of one entry into
+// its ConfigMap, owned by the TenantGateway.
+func (r *Reconciler) applyClientCAConfigMap(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway, name string, bundle []byte) error {
+	labels := map[string]string{
+		cozystackManagedByLabel:      cozystackManagedByValue,
+		policyTenantGatewayNameLabel: tgw.Name,
+		clientValidationNameLabel:    name,
+	}
+	desired := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clientValidationObjectName(tgw, name),
+			Namespace: tgw.Namespace,
+			Labels:    labels,
+		},
+		Data: map[string]string{clientCAKey: string(bundle)},
+	}
+	if err := controllerutil.SetControllerReference(tgw, desired, r.Scheme); err != nil {
+		return fmt.Errorf("set controller reference on ConfigMap %s: %w", desired.Name, err)
+	}
+
+	existing := &corev1.ConfigMap{}
+	err := r.Get(ctx, client.ObjectKeyFromObject(desired), existing)
+	switch {
+	case apierrors.IsNotFound(err):
+		if err := r.Create(ctx, desired); err != nil {
+			return fmt.Errorf("create client CA ConfigMap %s: %w", desired.Name, err)
+		}
+		return nil
+	case err != nil:
+		return fmt.Errorf("get client CA ConfigMap %s: %w", desired.Name, err)
+	}
+	if !ownedByTenantGateway(existing.OwnerReferences, tgw) {
+		return fmt.Errorf("configmap %s/%s exists but is not owned by TenantGateway %s; refusing to take over", tgw.Namespace, desired.Name, tgw.Name)
+	}
+	if equality.Semantic.DeepEqual(existing.Data, desired.Data) && labelsEqual(existing.Labels, mergeLabels(existing.Labels, labels)) {
+		return nil
+	}
+	existing.Data = desired.Data
+	existing.Labels = mergeLabels(existing.Labels, labels)
+	if err := r.Update(ctx, existing); err != nil {
+		return fmt.Errorf("update client CA ConfigMap %s: %w", desired.Name, err)
+	}
+	return nil
+}
+
+// renderClientTrafficPolicy builds the Envoy Gateway
+// ClientTrafficPolicy enforcing cv on the given listeners of the
+// tenant Gateway. Without ForwardSubject the X-Forwarded-Client-Cert
+// header is left at Envoy's default, which strips any value the client
+// sent.
+func (r *Reconciler) renderClientTrafficPolicy(tgw *gatewayv1alpha1.TenantGateway, cv *gatewayv1alpha1.ClientValidation, sections []string) (*unstructured.Unstructured, error) {
+	targetRefs := make([]any, 0, len(sections))
+	for _, s := range sections {
+		targetRefs = append(targetRefs, map[string]any{
+			"group":       gatewayv1.GroupName,
+			"kind":        "Gateway",
+			"name":        tgw.Name,
+			"sectionName": s,
+		})
+	}
+	validation := map[string]any{
+		"caCertificateRefs": []any{
+			map[string]any{
+				"group": "",
+				"kind":  "ConfigMap",
+				"name":  clientValidationObjectName(tgw, cv.Name),
+			},
+		},
+	}
+	if cv.Optional {
+		validation["optional"] = true
+	}
+	spec := map[string]any{
+		"targetRefs": targetRefs,
+		"tls":        map[string]any{"clientValidation": validation},
+	}
+	if cv.ForwardSubject {
+		spec["headers"] = map[string]any{
+			"xForwardedClientCert": map[string]any{
+				"mode":             "SanitizeSet",
+				"certDetailsToAdd": []any{"Subject"},
+			},
+		}
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	obj.SetGroupVersionKind(envoyClientTrafficPolicyGVK)
+	obj.SetNamespace(tgw.Namespace)
+	obj.SetName(clientValidationObjectName(tgw, cv.Name))
+	obj.SetLabels(policyObjectLabels(tgw, cv.Name))
+	if err := controllerutil.SetControllerReference(tgw, obj, r.Scheme); err != nil {
+		return nil, fmt.Errorf("set controller reference on ClientTrafficPolicy %s: %w", obj.GetName(), err)
+	}
+	return obj, nil
+}
+
+// clientValidationReferencesSecret reports whether any entry of tgw
+// reads its CA bundle from the Secret namespace/name.
+func clientValidationReferencesSecret(tgw *gatewayv1alpha1.TenantGateway, namespace, name string) bool {
+	for _, cv := range tgw.Spec.ClientValidation {
+		var ns, n string
+		switch {
+		case cv.CABundle.TenantSecret != nil:
+			ns, n = cv.CABundle.TenantSecret.Namespace, cv.CABundle.TenantSecret.Name
+		case cv.CABundle.TenantCA != nil:
+			ns, n = cv.CABundle.TenantCA.Namespace, cacert.ProjectionName(cv.CABundle.TenantCA.Release)
+		default:
+			continue
+		}
+		if ns == "" {
+			ns = tgw.Namespace
+		}
+		if ns == namespace && n == name {
+			return true
+		}
+	}
+	return false
+}
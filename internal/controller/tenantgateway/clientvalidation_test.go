@@ -0,0 +1,600 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantgateway
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+	"github.com/cozystack/cozystack/internal/controller/cacert"
+	corev1alpha1 "github.com/cozystack/cozystack/pkg/apis/core/v1alpha1"
+)
+
+// newClientValidationScheme adds ClientTrafficPolicy to the policy
+// scheme as an unstructured type.
+func newClientValidationScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	s := newPolicyScheme(t)
+	gvk := envoyClientTrafficPolicyGVK
+	s.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+	s.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	return s
+}
+
+// testCAPEM returns a self-signed CA certificate in PEM form.
+func testCAPEM(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tenant-foo clients"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func clientCASecret(ns, name string, data []byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			Labels:    map[string]string{corev1alpha1.TenantResourceLabelKey: corev1alpha1.TenantResourceLabelValue},
+		},
+		Data: map[string][]byte{"ca.crt": data},
+	}
+}
+
+func clientValidationTenantGateway(className string, entries ...gatewayv1alpha1.ClientValidation) *gatewayv1alpha1.TenantGateway {
+	tgw := policyTenantGateway(className)
+	tgw.Spec.ClientValidation = entries
+	return tgw
+}
+
+// clientValidationStatus returns the status of the listener serving
+// hostname, failing the test when there is none.
+func clientValidationStatus(t *testing.T, c client.Client, hostname string) *gatewayv1alpha1.TenantGatewayListenerStatus {
+	t.Helper()
+	ls := listenerStatusFor(getTGW(t, c), hostname)
+	if ls == nil {
+		t.Fatalf("no listener status for %s", hostname)
+	}
+	return ls
+}
+
+func getClientTrafficPolicy(t *testing.T, c client.Client, name string) (*unstructured.Unstructured, bool) {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(envoyClientTrafficPolicyGVK)
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: "tenant-foo", Name: name}, obj)
+	if client.IgnoreNotFound(err) != nil {
+		t.Fatalf("get ClientTrafficPolicy %s: %v", name, err)
+	}
+	return obj, err == nil
+}
+
+// gatewayListenerFor returns the Gateway listener serving hostname.
+func gatewayListenerFor(t *testing.T, c client.Client, hostname string) *gatewayv1.Listener {
+	t.Helper()
+	gw := &gatewayv1.Gateway{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "tenant-foo", Name: "cozystack"}, gw); err != nil {
+		t.Fatalf("get Gateway: %v", err)
+	}
+	for i := range gw.Spec.Listeners {
+		if l := &gw.Spec.Listeners[i]; l.Hostname != nil && string(*l.Hostname) == hostname {
+			return l
+		}
+	}
+	t.Fatalf("no Gateway listener for %s", hostname)
+	return nil
+}
+
+func listenerClosed(l *gatewayv1.Listener) bool {
+	return l.AllowedRoutes != nil && l.AllowedRoutes.Namespaces != nil &&
+		l.AllowedRoutes.Namespaces.From != nil && *l.AllowedRoutes.Namespaces.From == gatewayv1.NamespacesFromNone
+}
+
+// frontendValidationClass is a non-Envoy GatewayClass advertising the
+// standard Gateway frontend TLS validation.
+func frontendValidationClass(name string, features ...gatewayv1.FeatureName) *gatewayv1.GatewayClass {
+	gc := gatewayClass(name, "io.cilium/gateway-controller")
+	for _, f := range append([]gatewayv1.FeatureName{frontendValidationFeature}, features...) {
+		gc.Status.SupportedFeatures = append(gc.Status.SupportedFeatures, gatewayv1.SupportedFeature{Name: f})
+	}
+	return gc
+}
+
+// TestReconcile_ClientValidationRendersClientTrafficPolicy pins the
+// Envoy Gateway translation: the CA bundle is copied into an owned
+// ConfigMap, a ClientTrafficPolicy targets the hostname's listener by
+// sectionName and forwards the verified subject, and the listener
+// reports Enforced. Dropping the entry removes both objects.
+func TestReconcile_ClientValidationRendersClientTrafficPolicy(t *testing.T) {
+	s := newClientValidationScheme(t)
+	ca := testCAPEM(t)
+	tgw := clientValidationTenantGateway("envoy", gatewayv1alpha1.ClientValidation{
+		Name:           "partners",
+		Hostnames:      []string{"api.foo.example.com"},
+		CABundle:       gatewayv1alpha1.ClientCABundleSource{TenantSecret: &gatewayv1alpha1.TenantSecretCARef{Name: "partner-ca"}},
+		ForwardSubject: true,
+	})
+	c := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(tgw, gatewayClass("envoy", envoyGatewayControllerName),
+			httpRouteAttached("api", "cozy-harbor", "api.foo.example.com"),
+			clientCASecret("tenant-foo", "partner-ca", ca)).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}, &gatewayv1.HTTPRoute{}).
+		Build()
+	r := &Reconciler{Client: c, Scheme: s}
+	reconcileTGW(t, r)
+
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "tenant-foo", Name: "cozystack-mtls-partners"}, cm); err != nil {
+		t.Fatalf("get CA ConfigMap: %v", err)
+	}
+	if cm.Data["ca.crt"] != string(ca) {
+		t.Errorf("ConfigMap ca.crt=%q, want the source bundle", cm.Data["ca.crt"])
+	}
+	if !ownedByTenantGateway(cm.OwnerReferences, getTGW(t, c)) {
+		t.Error("CA ConfigMap must be controller-owned by the TenantGateway")
+	}
+
+	ctp, ok := getClientTrafficPolicy(t, c, "cozystack-mtls-partners")
+	if !ok {
+		t.Fatal("expected ClientTrafficPolicy to be rendered")
+	}
+	want := map[string]any{
+		"targetRefs": []any{map[string]any{
+			"group":       gatewayv1.GroupName,
+			"kind":        "Gateway",
+			"name":        "cozystack",
+			"sectionName": perListenerName("api.foo.example.com"),
+		}},
+		"tls": map[string]any{"clientValidation": map[string]any{
+			"caCertificateRefs": []any{map[string]any{"group": "", "kind": "ConfigMap", "name": "cozystack-mtls-partners"}},
+		}},
+		"headers": map[string]any{"xForwardedClientCert": map[string]any{
+			"mode":             "SanitizeSet",
+			"certDetailsToAdd": []any{"Subject"},
+		}},
+	}
+	if !reflect.DeepEqual(ctp.Object["spec"], want) {
+		t.Errorf("ClientTrafficPolicy spec=%v\nwant %v", ctp.Object["spec"], want)
+	}
+	if l := clientValidationStatus(t, c, "api.foo.example.com"); l.ClientValidation != gatewayv1alpha1.ClientValidationStateEnforced || l.ClientValidationMessage != "" {
+		t.Errorf("listener clientValidation=%q (%q), want Enforced", l.ClientValidation, l.ClientValidationMessage)
+	}
+
+	updated := getTGW(t, c)
+	updated.Spec.ClientValidation = nil
+	if err := c.Update(context.TODO(), updated); err != nil {
+		t.Fatalf("drop clientValidation: %v", err)
+	}
+	reconcileTGW(t, r)
+	if _, ok := getClientTrafficPolicy(t, c, "cozystack-mtls-partners"); ok {
+		t.Error("ClientTrafficPolicy must be removed with its entry")
+	}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "tenant-foo", Name: "cozystack-mtls-partners"}, &corev1.ConfigMap{}); err == nil {
+		t.Error("CA ConfigMap must be removed with its entry")
+	}
+	if l := clientValidationStatus(t, c, "api.foo.example.com"); l.ClientValidation != "" {
+		t.Errorf("listener clientValidation=%q after removal, want empty", l.ClientValidation)
+	}
+}
+
+// TestReconcile_ClientValidationSourceErrors pins the listener status
+// for every CA source an operator has to fix, and that none of them
+// renders a policy.
+func TestReconcile_ClientValidationSourceErrors(t *testing.T) {
+	ca := testCAPEM(t)
+	unlabeled := clientCASecret("tenant-foo", "partner-ca", ca)
+	unlabeled.Labels = nil
+	withKey := clientCASecret("tenant-foo", "partner-ca", ca)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("secret")})
+	withKey.Data["ca.crt"] = append(append([]byte{}, ca...), keyPEM...)
+
+	cases := []struct {
+		name      string
+		ref       gatewayv1alpha1.TenantSecretCARef
+		secret    *corev1.Secret
+		wantState gatewayv1alpha1.ClientValidationState
+		wantMsg   string
+	}{
+		{"missing secret", gatewayv1alpha1.TenantSecretCARef{Name: "partner-ca"}, nil, gatewayv1alpha1.ClientValidationStatePending, "not found"},
+		{"not a TenantSecret", gatewayv1alpha1.TenantSecretCARef{Name: "partner-ca"}, unlabeled, gatewayv1alpha1.ClientValidationStateInvalid, "not a TenantSecret"},
+		{"missing key", gatewayv1alpha1.TenantSecretCARef{Name: "partner-ca", Key: "bundle.pem"}, clientCASecret("tenant-foo", "partner-ca", ca), gatewayv1alpha1.ClientValidationStateInvalid, `no key "bundle.pem"`},
+		{"garbage bundle", gatewayv1alpha1.TenantSecretCARef{Name: "partner-ca"}, clientCASecret("tenant-foo", "partner-ca", []byte("not pem")), gatewayv1alpha1.ClientValidationStateInvalid, "no PEM certificates"},
+		{"private key in bundle", gatewayv1alpha1.TenantSecretCARef{Name: "partner-ca"}, withKey, gatewayv1alpha1.ClientValidationStateInvalid, `unexpected PEM block "PRIVATE KEY"`},
+		{"foreign namespace", gatewayv1alpha1.TenantSecretCARef{Namespace: "tenant-bar", Name: "partner-ca"}, clientCASecret("tenant-bar", "partner-ca", ca), gatewayv1alpha1.ClientValidationStateInvalid, "not attached"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newClientValidationScheme(t)
+			ref := tc.ref
+			tgw := clientValidationTenantGateway("envoy", gatewayv1alpha1.ClientValidation{
+				Name:      "partners",
+				Hostnames: []string{"api.foo.example.com"},
+				CABundle:  gatewayv1alpha1.ClientCABundleSource{TenantSecret: &ref},
+			})
+			objs := []client.Object{tgw, gatewayClass("envoy", envoyGatewayControllerName), httpRouteAttached("api", "cozy-harbor", "api.foo.example.com")}
+			if tc.secret != nil {
+				objs = append(objs, tc.secret)
+			}
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).
+				WithStatusSubresource(tgw, &gatewayv1.Gateway{}, &gatewayv1.HTTPRoute{}).
+				Build()
+			reconcileTGW(t, &Reconciler{Client: c, Scheme: s})
+
+			l := clientValidationStatus(t, c, "api.foo.example.com")
+			if l.ClientValidation != tc.wantState || !strings.Contains(l.ClientValidationMessage, tc.wantMsg) {
+				t.Errorf("listener clientValidation=%q (%q), want %q containing %q", l.ClientValidation, l.ClientValidationMessage, tc.wantState, tc.wantMsg)
+			}
+			if _, ok := getClientTrafficPolicy(t, c, "cozystack-mtls-partners"); ok {
+				t.Error("no ClientTrafficPolicy may be rendered from an unusable CA source")
+			}
+			if !listenerClosed(gatewayListenerFor(t, c, "api.foo.example.com")) {
+				t.Error("a listener whose client validation never took effect must accept no routes")
+			}
+		})
+	}
+}
+
+// TestReconcile_ClientValidationKeepsLastGoodBundle pins fail-closed
+// behaviour: a bundle that turns invalid after being enforced leaves
+// the rendered ConfigMap and policy in place instead of lifting
+// client verification.
+func TestReconcile_ClientValidationKeepsLastGoodBundle(t *testing.T) {
+	s := newClientValidationScheme(t)
+	ca := testCAPEM(t)
+	tgw := clientValidationTenantGateway("envoy", gatewayv1alpha1.ClientValidation{
+		Name:      "partners",
+		Hostnames: []string{"api.foo.example.com"},
+		CABundle:  gatewayv1alpha1.ClientCABundleSource{TenantSecret: &gatewayv1alpha1.TenantSecretCARef{Name: "partner-ca"}},
+	})
+	secret := clientCASecret("tenant-foo", "partner-ca", ca)
+	c := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(tgw, secret, gatewayClass("envoy", envoyGatewayControllerName), httpRouteAttached("api", "cozy-harbor", "api.foo.example.com")).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}, &gatewayv1.HTTPRoute{}).
+		Build()
+	r := &Reconciler{Client: c, Scheme: s}
+	reconcileTGW(t, r)
+
+	secret.Data["ca.crt"] = []byte("truncated")
+	if err := c.Update(context.TODO(), secret); err != nil {
+		t.Fatalf("corrupt Secret: %v", err)
+	}
+	reconcileTGW(t, r)
+
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "tenant-foo", Name: "cozystack-mtls-partners"}, cm); err != nil {
+		t.Fatalf("CA ConfigMap must survive a broken source: %v", err)
+	}
+	if cm.Data["ca.crt"] != string(ca) {
+		t.Error("CA ConfigMap must keep the last valid bundle")
+	}
+	if _, ok := getClientTrafficPolicy(t, c, "cozystack-mtls-partners"); !ok {
+		t.Error("ClientTrafficPolicy must survive a broken source")
+	}
+	if l := clientValidationStatus(t, c, "api.foo.example.com"); l.ClientValidation != gatewayv1alpha1.ClientValidationStateInvalid {
+		t.Errorf("listener clientValidation=%q, want Invalid", l.ClientValidation)
+	}
+	if listenerClosed(gatewayListenerFor(t, c, "api.foo.example.com")) {
+		t.Error("a listener still verified by the last valid bundle must keep serving")
+	}
+}
+
+// TestReconcile_ClientValidationTenantCA pins the tenant-ca source:
+// the bundle comes from the cacert projection of the named release,
+// and a Secret squatting on the projection name is refused.
+func TestReconcile_ClientValidationTenantCA(t *testing.T) {
+	ca := testCAPEM(t)
+	projection := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cacert.ProjectionName("postgres"),
+			Namespace: "tenant-foo",
+			Labels:    map[string]string{cacert.TenantCALabel: "true"},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "internal.cozystack.io/v1alpha1",
+				Kind:       "TenantProjection",
+				Name:       "postgres",
+				UID:        "tp-uid",
+			}},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{"ca.crt": ca},
+	}
+	squatter := projection.DeepCopy()
+	squatter.OwnerReferences = nil
+
+	for name, tc := range map[string]struct {
+		secret *corev1.Secret
+		want   gatewayv1alpha1.ClientValidationState
+	}{
+		"projection": {projection, gatewayv1alpha1.ClientValidationStateEnforced},
+		"squatter":   {squatter, gatewayv1alpha1.ClientValidationStateInvalid},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newClientValidationScheme(t)
+			tgw := clientValidationTenantGateway("envoy", gatewayv1alpha1.ClientValidation{
+				Name:      "db-clients",
+				Hostnames: []string{"api.foo.example.com"},
+				CABundle:  gatewayv1alpha1.ClientCABundleSource{TenantCA: &gatewayv1alpha1.TenantCARef{Release: "postgres"}},
+			})
+			c := fake.NewClientBuilder().WithScheme(s).
+				WithObjects(tgw, tc.secret, gatewayClass("envoy", envoyGatewayControllerName), httpRouteAttached("api", "cozy-harbor", "api.foo.example.com")).
+				WithStatusSubresource(tgw, &gatewayv1.Gateway{}, &gatewayv1.HTTPRoute{}).
+				Build()
+			reconcileTGW(t, &Reconciler{Client: c, Scheme: s})
+			if l := clientValidationStatus(t, c, "api.foo.example.com"); l.ClientValidation != tc.want {
+				t.Errorf("listener clientValidation=%q (%q), want %q", l.ClientValidation, l.ClientValidationMessage, tc.want)
+			}
+		})
+	}
+}
+
+// TestReconcile_ClientValidationUnsupportedClass pins the Cilium path:
+// nothing is rendered and the listener says why.
+func TestReconcile_ClientValidationUnsupportedClass(t *testing.T) {
+	s := newClientValidationScheme(t)
+	tgw := clientValidationTenantGateway("cilium", gatewayv1alpha1.ClientValidation{
+		Name:      "partners",
+		Hostnames: []string{"api.foo.example.com"},
+		CABundle:  gatewayv1alpha1.ClientCABundleSource{TenantSecret: &gatewayv1alpha1.TenantSecretCARef{Name: "partner-ca"}},
+	})
+	c := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(tgw, gatewayClass("cilium", "io.cilium/gateway-controller"),
+			httpRouteAttached("api", "cozy-harbor", "api.foo.example.com"),
+			clientCASecret("tenant-foo", "partner-ca", testCAPEM(t))).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}, &gatewayv1.HTTPRoute{}).
+		Build()
+	reconcileTGW(t, &Reconciler{Client: c, Scheme: s})
+
+	if _, ok := getClientTrafficPolicy(t, c, "cozystack-mtls-partners"); ok {
+		t.Error("no ClientTrafficPolicy may be rendered for a Cilium class")
+	}
+	l := clientValidationStatus(t, c, "api.foo.example.com")
+	if l.ClientValidation != gatewayv1alpha1.ClientValidationStateUnsupported || !strings.Contains(l.ClientValidationMessage, "Envoy Gateway") {
+		t.Errorf("listener clientValidation=%q (%q), want Unsupported", l.ClientValidation, l.ClientValidationMessage)
+	}
+	if !listenerClosed(gatewayListenerFor(t, c, "api.foo.example.com")) {
+		t.Error("an Unsupported listener must accept no routes rather than serve without client validation")
+	}
+}
+
+// TestReconcile_ClientValidationStandardFrontend pins the standard
+// path: on a class advertising Gateway frontend TLS validation, an
+// entry covering every HTTPS listener renders Gateway.spec.tls.frontend
+// against the copied bundle and no implementation policy.
+func TestReconcile_ClientValidationStandardFrontend(t *testing.T) {
+	s := newClientValidationScheme(t)
+	tgw := clientValidationTenantGateway("cilium", gatewayv1alpha1.ClientValidation{
+		Name:      "partners",
+		Hostnames: []string{"api.foo.example.com"},
+		CABundle:  gatewayv1alpha1.ClientCABundleSource{TenantSecret: &gatewayv1alpha1.TenantSecretCARef{Name: "partner-ca"}},
+		Optional:  true,
+	})
+	c := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(tgw, frontendValidationClass("cilium", frontendValidationInsecureFallbackFeature),
+			httpRouteAttached("api", "cozy-harbor", "api.foo.example.com"),
+			clientCASecret("tenant-foo", "partner-ca", testCAPEM(t))).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}, &gatewayv1.HTTPRoute{}).
+		Build()
+	reconcileTGW(t, &Reconciler{Client: c, Scheme: s})
+
+	gw := &gatewayv1.Gateway{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "tenant-foo", Name: "cozystack"}, gw); err != nil {
+		t.Fatalf("get Gateway: %v", err)
+	}
+	want := &gatewayv1.GatewayTLSConfig{Frontend: &gatewayv1.FrontendTLSConfig{
+		Default: gatewayv1.TLSConfig{Validation: &gatewayv1.FrontendTLSValidation{
+			CACertificateRefs: []gatewayv1.ObjectReference{{Kind: "ConfigMap", Name: "cozystack-mtls-partners"}},
+			Mode:              gatewayv1.AllowInsecureFallback,
+		}},
+	}}
+	if !reflect.DeepEqual(gw.Spec.TLS, want) {
+		t.Errorf("Gateway spec.tls=%+v, want %+v", gw.Spec.TLS, want)
+	}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "tenant-foo", Name: "cozystack-mtls-partners"}, &corev1.ConfigMap{}); err != nil {
+		t.Errorf("CA ConfigMap must back the standard field: %v", err)
+	}
+	if _, ok := getClientTrafficPolicy(t, c, "cozystack-mtls-partners"); ok {
+		t.Error("no ClientTrafficPolicy may be rendered when the standard field applies")
+	}
+	if l := clientValidationStatus(t, c, "api.foo.example.com"); l.ClientValidation != gatewayv1alpha1.ClientValidationStateEnforced {
+		t.Errorf("listener clientValidation=%q (%q), want Enforced", l.ClientValidation, l.ClientValidationMessage)
+	}
+	if listenerClosed(gatewayListenerFor(t, c, "api.foo.example.com")) {
+		t.Error("an enforced listener must keep serving")
+	}
+}
+
+// TestReconcile_ClientValidationStandardFrontendPartial pins the
+// limit of the standard field: it applies per port, so an entry that
+// leaves another HTTPS listener out is Unsupported on a non-Envoy
+// class and only its own listener is closed.
+func TestReconcile_ClientValidationStandardFrontendPartial(t *testing.T) {
+	s := newClientValidationScheme(t)
+	tgw := clientValidationTenantGateway("cilium", gatewayv1alpha1.ClientValidation{
+		Name:      "partners",
+		Hostnames: []string{"api.foo.example.com"},
+		CABundle:  gatewayv1alpha1.ClientCABundleSource{TenantSecret: &gatewayv1alpha1.TenantSecretCARef{Name: "partner-ca"}},
+	})
+	c := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(tgw, frontendValidationClass("cilium"),
+			httpRouteAttached("api", "cozy-harbor", "api.foo.example.com"),
+			httpRouteAttached("harbor", "cozy-harbor", "harbor.foo.example.com"),
+			clientCASecret("tenant-foo", "partner-ca", testCAPEM(t))).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}, &gatewayv1.HTTPRoute{}).
+		Build()
+	reconcileTGW(t, &Reconciler{Client: c, Scheme: s})
+
+	gw := &gatewayv1.Gateway{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "tenant-foo", Name: "cozystack"}, gw); err != nil {
+		t.Fatalf("get Gateway: %v", err)
+	}
+	if gw.Spec.TLS != nil {
+		t.Errorf("Gateway spec.tls=%+v, want none for a partial entry", gw.Spec.TLS)
+	}
+	l := clientValidationStatus(t, c, "api.foo.example.com")
+	if l.ClientValidation != gatewayv1alpha1.ClientValidationStateUnsupported || !strings.Contains(l.ClientValidationMessage, "every HTTPS listener") {
+		t.Errorf("listener clientValidation=%q (%q), want Unsupported", l.ClientValidation, l.ClientValidationMessage)
+	}
+	if !listenerClosed(gatewayListenerFor(t, c, "api.foo.example.com")) {
+		t.Error("the unverifiable listener must accept no routes")
+	}
+	if listenerClosed(gatewayListenerFor(t, c, "harbor.foo.example.com")) {
+		t.Error("listeners outside clientValidation must be left alone")
+	}
+}
+
+// TestRenderGateway_ClientValidationSplitsWildcard pins the wildcard
+// modes: a verified hostname gets a listener of its own on the
+// wildcard certificate, while the apex and hostnames the certificate
+// cannot cover stay where they are.
+func TestRenderGateway_ClientValidationSplitsWildcard(t *testing.T) {
+	tgw := &gatewayv1alpha1.TenantGateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack", Namespace: "tenant-foo"},
+		Spec: gatewayv1alpha1.TenantGatewaySpec{
+			Apex:              "foo.example.com",
+			CertMode:          gatewayv1alpha1.CertModeExistingSecret,
+			WildcardSecretRef: &corev1.LocalObjectReference{Name: "wildcard-tls"},
+			ClientValidation: []gatewayv1alpha1.ClientValidation{{
+				Name:      "partners",
+				Hostnames: []string{"API.foo.example.com", "foo.example.com", "deep.api.foo.example.com", "x.bar.foo.example.com"},
+				CABundle:  gatewayv1alpha1.ClientCABundleSource{TenantSecret: &gatewayv1alpha1.TenantSecretCARef{Name: "partner-ca"}},
+			}},
+		},
+	}
+	r := &Reconciler{Scheme: newScheme(t)}
+	gw, err := r.renderGateway(tgw, nil, []string{"bar.foo.example.com"})
+	if err != nil {
+		t.Fatalf("renderGateway: %v", err)
+	}
+	byHost := map[string]gatewayv1.Listener{}
+	for _, l := range gw.Spec.Listeners {
+		if l.Hostname != nil {
+			byHost[string(*l.Hostname)] = l
+		}
+	}
+	for _, h := range []string{"api.foo.example.com", "x.bar.foo.example.com"} {
+		l, ok := byHost[h]
+		if !ok {
+			t.Errorf("expected a dedicated listener for %s, got %v", h, gw.Spec.Listeners)
+			continue
+		}
+		if string(l.Name) != perListenerName(h) || string(l.TLS.CertificateRefs[0].Name) != "wildcard-tls" {
+			t.Errorf("listener %s name=%s cert=%s, want %s on wildcard-tls", h, l.Name, l.TLS.CertificateRefs[0].Name, perListenerName(h))
+		}
+	}
+	if _, ok := byHost["deep.api.foo.example.com"]; ok {
+		t.Error("a hostname the wildcard certificate does not cover must not get a listener")
+	}
+	if l := byHost["foo.example.com"]; l.Name != "https-apex" {
+		t.Errorf("apex listener=%s, want https-apex untouched", l.Name)
+	}
+}
+
+// TestMapSecretToTenantGateways pins the Secret watch: only
+// TenantGateways that read their client CA bundle from the changed
+// Secret are requeued, whichever source kind names it.
+func TestMapSecretToTenantGateways(t *testing.T) {
+	s := newScheme(t)
+	bySecret := clientValidationTenantGateway("envoy", gatewayv1alpha1.ClientValidation{
+		Name:      "partners",
+		Hostnames: []string{"api.foo.example.com"},
+		CABundle:  gatewayv1alpha1.ClientCABundleSource{TenantSecret: &gatewayv1alpha1.TenantSecretCARef{Name: "partner-ca"}},
+	})
+	byCA := &gatewayv1alpha1.TenantGateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack", Namespace: "tenant-bar"},
+		Spec: gatewayv1alpha1.TenantGatewaySpec{
+			Apex: "bar.example.com",
+			ClientValidation: []gatewayv1alpha1.ClientValidation{{
+				Name:      "db",
+				Hostnames: []string{"db.bar.example.com"},
+				CABundle:  gatewayv1alpha1.ClientCABundleSource{TenantCA: &gatewayv1alpha1.TenantCARef{Namespace: "tenant-foo", Release: "postgres"}},
+			}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(bySecret, byCA).Build()
+	r := &Reconciler{Client: c, Scheme: s}
+
+	meta := func(ns, name string) *metav1.PartialObjectMetadata {
+		obj := secretMeta()
+		obj.Namespace, obj.Name = ns, name
+		return obj
+	}
+	if got := r.mapSecretToTenantGateways(context.TODO(), meta("tenant-foo", "partner-ca")); len(got) != 1 || got[0].Namespace != "tenant-foo" {
+		t.Errorf("partner-ca requeued %v, want tenant-foo/cozystack", got)
+	}
+	if got := r.mapSecretToTenantGateways(context.TODO(), meta("tenant-foo", cacert.ProjectionName("postgres"))); len(got) != 1 || got[0].Namespace != "tenant-bar" {
+		t.Errorf("projection requeued %v, want tenant-bar/cozystack", got)
+	}
+	if got := r.mapSecretToTenantGateways(context.TODO(), meta("tenant-bar", "partner-ca")); len(got) != 0 {
+		t.Errorf("unrelated Secret requeued %v", got)
+	}
+}
+
+// TestConfigMapCacheByObject_SelectsRenderedBundles pins the ConfigMap
+// cache scope: the CA bundles this controller renders are cached, any
+// other ConfigMap in the cluster is not.
+func TestConfigMapCacheByObject_SelectsRenderedBundles(t *testing.T) {
+	by := ConfigMapCacheByObject()
+	if by.Label == nil {
+		t.Fatal("the ConfigMap cache must be scoped by a label selector")
+	}
+	rendered := labels.Set{
+		cozystackManagedByLabel:      cozystackManagedByValue,
+		policyTenantGatewayNameLabel: "cozystack",
+		clientValidationNameLabel:    "partners",
+	}
+	if !by.Label.Matches(rendered) {
+		t.Errorf("selector %s must match a rendered CA ConfigMap", by.Label)
+	}
+	if by.Label.Matches(labels.Set{"app.kubernetes.io/name": "harbor"}) {
+		t.Errorf("selector %s must not match unrelated ConfigMaps", by.Label)
+	}
+}
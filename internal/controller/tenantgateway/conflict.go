@@ -107,8 +107,11 @@ func resolveHostnameOwners(claims map[string][]routeRef) (map[string]routeRef, m
 // ControllerName, one per (route, parentRef) tuple that attached to
 // this TenantGateway. Accepted=True for tuples not in losers,
 // Accepted=False with Reason=HostnameConflict for tuples that lost
-// at least one hostname race. Other controllers' entries (Cilium
-// etc.) are untouched.
+// at least one hostname race, and Accepted=False with
+// Reason=UnverifiedHostname for tuples that declared a hostname their
+// namespace holds no verified DomainClaim for. The latter wins over a
+// conflict: it is the one the route owner has to act on. Other
+// controllers' entries (Cilium etc.) are untouched.
 //
 // allRefs is the full set of (route, parentRef) tuples observed by
 // collectHostnameClaims — without it, multi-parentRef routes would
@@ -119,6 +122,7 @@ func (r *Reconciler) updateRouteStatuses(
 	tgw *gatewayv1alpha1.TenantGateway,
 	allRefs map[routeRef]struct{},
 	losers map[routeRef][]string,
+	unverified map[routeRef][]string,
 ) error {
 	logger := log.FromContext(ctx)
 
@@ -127,6 +131,19 @@ func (r *Reconciler) updateRouteStatuses(
 	// transitions; building Conditions here without it keeps the
 	// no-op reconcile no-op.
 	for ref := range allRefs {
+		if reasons, ok := unverified[ref]; ok {
+			if err := r.updateRouteParentStatus(ctx, ref, []metav1.Condition{
+				{
+					Type:    "Accepted",
+					Status:  metav1.ConditionFalse,
+					Reason:  "UnverifiedHostname",
+					Message: fmt.Sprintf("Hostname(s) not verified for namespace %s: %s", ref.namespace, strings.Join(reasons, "; ")),
+				},
+			}); err != nil {
+				logger.Error(err, "update unverified route status", "route", ref.namespace+"/"+ref.name)
+			}
+			continue
+		}
 		if hostnames, isLoser := losers[ref]; isLoser {
 			// A route can claim multiple hostnames; conflict status
 			// takes priority over the happy path.
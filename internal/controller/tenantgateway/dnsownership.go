@@ -0,0 +1,107 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantgateway
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+)
+
+// Every published record set is claimed by a TXT record next to it, in
+// the manner of the external-dns TXT registry: the publisher only
+// writes or deletes a record set whose ownership record names its own
+// TenantGateway, so records created by hand, by external-dns or by
+// another tenant are never touched.
+
+// dnsOwnershipHeritage tags the TXT values this controller writes.
+const dnsOwnershipHeritage = "heritage=cozystack"
+
+// dnsOwnershipOwnerKey precedes the owner in an ownership TXT value.
+const dnsOwnershipOwnerKey = "cozystack/owner="
+
+// errDNSRecordNotOwned is returned when a record set exists but is not
+// claimed by the TenantGateway publishing it.
+var errDNSRecordNotOwned = errors.New("DNS record is not owned by this TenantGateway")
+
+// dnsRecordOwner identifies tgw in ownership records.
+func dnsRecordOwner(tgw *gatewayv1alpha1.TenantGateway) string {
+	return tgw.Namespace + "/" + tgw.Name
+}
+
+// ownershipRecordName is the name of the TXT record claiming the
+// recordType set at hostname: "_cozystack-a.harbor.example.com". A TXT
+// record cannot sit below a wildcard label, so "*.example.com" is
+// claimed by "_cozystack-a-wildcard.example.com".
+func ownershipRecordName(hostname, recordType string) string {
+	hostname = strings.TrimSuffix(strings.ToLower(hostname), ".")
+	label := "_cozystack-" + strings.ToLower(recordType)
+	if rest, ok := strings.CutPrefix(hostname, "*."); ok {
+		return label + "-wildcard." + rest
+	}
+	return label + "." + hostname
+}
+
+// ownershipValue is the quoted TXT value claiming a record set for
+// owner.
+func ownershipValue(owner string) string {
+	return fmt.Sprintf("%q", dnsOwnershipHeritage+","+dnsOwnershipOwnerKey+owner)
+}
+
+// parseOwnershipValue returns the owner named by a TXT value, which
+// providers report with or without the surrounding quotes. ok is false
+// for values this controller did not write.
+func parseOwnershipValue(value string) (owner string, ok bool) {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	fields := strings.Split(value, ",")
+	if len(fields) != 2 || fields[0] != dnsOwnershipHeritage {
+		return "", false
+	}
+	return strings.CutPrefix(fields[1], dnsOwnershipOwnerKey)
+}
+
+// claimOwnership decides whether owner may write a record set given the
+// values of its ownership TXT record and whether the set has values.
+// claimed reports that owner's ownership record is already there.
+func claimOwnership(owner, hostname, recordType string, txt []string, hasValues bool) (claimed bool, err error) {
+	for _, value := range txt {
+		other, ok := parseOwnershipValue(value)
+		if !ok {
+			continue
+		}
+		if other != owner {
+			return false, fmt.Errorf("%w: %s %s is owned by %s", errDNSRecordNotOwned, hostname, recordType, other)
+		}
+		return true, nil
+	}
+	if hasValues {
+		return false, fmt.Errorf("%w: %s %s exists without an ownership record", errDNSRecordNotOwned, hostname, recordType)
+	}
+	return false, nil
+}
+
+// ownsRecord reports whether the ownership TXT values name owner.
+func ownsRecord(owner string, txt []string) bool {
+	for _, value := range txt {
+		if other, ok := parseOwnershipValue(value); ok && other == owner {
+			return true
+		}
+	}
+	return false
+}
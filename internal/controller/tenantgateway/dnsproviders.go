@@ -0,0 +1,849 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantgateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+)
+
+// dnsRecord is one owner name / record type pair with its full target
+// set. Providers converge the zone onto exactly these targets.
+type dnsRecord struct {
+	Hostname string
+	Type     string
+	Targets  []string
+	TTL      int32
+}
+
+// dnsRecordProvider publishes and withdraws listener records through
+// the DNS provider configured in TenantGateway.Spec.DNS01. Providers
+// only write record sets their TenantGateway owns; see dnsownership.go.
+type dnsRecordProvider interface {
+	UpsertRecord(ctx context.Context, rec dnsRecord) error
+	DeleteRecord(ctx context.Context, hostname, recordType string) error
+}
+
+// dnsProviderFactory builds a dnsRecordProvider for a TenantGateway.
+// Swapped out in tests so record publishing can run without a real
+// DNS API.
+type dnsProviderFactory func(ctx context.Context, c client.Reader, tgw *gatewayv1alpha1.TenantGateway) (dnsRecordProvider, error)
+
+// dnsProviderHTTPClient is shared by every provider client. The
+// timeout bounds how long a stuck provider API can hold a reconcile.
+var dnsProviderHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// errDNSRecordsUnsupported is returned by newDNSRecordProvider for a
+// dns01 provider the controller has no records API client for. It is
+// a configuration state, not a provider failure, so it is reported as
+// DNSRecord=Unsupported and never retried.
+var errDNSRecordsUnsupported = errors.New("cannot publish DNS records")
+
+const (
+	cloudflareAPIURL   = "https://api.cloudflare.com/client/v4"
+	digitalOceanAPIURL = "https://api.digitalocean.com/v2"
+)
+
+// newDNSRecordProvider is the production dnsProviderFactory. It reads
+// the provider credentials from Secrets in the TenantGateway's own
+// namespace — the same Secrets the cert-manager solver uses.
+func newDNSRecordProvider(ctx context.Context, c client.Reader, tgw *gatewayv1alpha1.TenantGateway) (dnsRecordProvider, error) {
+	cfg := tgw.Spec.DNS01
+	if cfg == nil {
+		return nil, fmt.Errorf("spec.dnsRecords requires spec.dns01 to be set")
+	}
+	switch cfg.Provider {
+	case gatewayv1alpha1.DNS01ProviderCloudflare:
+		if cfg.Cloudflare == nil {
+			return nil, fmt.Errorf("dns01.provider=cloudflare requires dns01.cloudflare to be set")
+		}
+		token, err := readSecretKey(ctx, c, tgw.Namespace, cfg.Cloudflare.APITokenSecretRef)
+		if err != nil {
+			return nil, err
+		}
+		return newZonedProvider(&cloudflareAPI{baseURL: cloudflareAPIURL, token: token}, dnsRecordOwner(tgw)), nil
+	case gatewayv1alpha1.DNS01ProviderDigitalOcean:
+		if cfg.DigitalOcean == nil {
+			return nil, fmt.Errorf("dns01.provider=digitalocean requires dns01.digitalocean to be set")
+		}
+		token, err := readSecretKey(ctx, c, tgw.Namespace, cfg.DigitalOcean.TokenSecretRef)
+		if err != nil {
+			return nil, err
+		}
+		return newZonedProvider(&digitalOceanAPI{baseURL: digitalOceanAPIURL, token: token}, dnsRecordOwner(tgw)), nil
+	case gatewayv1alpha1.DNS01ProviderHetzner:
+		if cfg.Hetzner == nil {
+			return nil, fmt.Errorf("dns01.provider=hetzner requires dns01.hetzner to be set")
+		}
+		// The same key the cert-manager webhook reads; see buildSolver.
+		ref := corev1.SecretKeySelector{LocalObjectReference: cfg.Hetzner.APIKeySecretRef.LocalObjectReference, Key: hetznerAPIKeySecretKey}
+		token, err := readSecretKey(ctx, c, tgw.Namespace, ref)
+		if err != nil {
+			return nil, err
+		}
+		return newZonedProvider(&hetznerAPI{baseURL: hetznerAPIURL(cfg.Hetzner), token: token, zoneName: cfg.Hetzner.ZoneName}, dnsRecordOwner(tgw)), nil
+	case gatewayv1alpha1.DNS01ProviderPowerDNS:
+		if cfg.PowerDNS == nil {
+			return nil, fmt.Errorf("dns01.provider=powerdns requires dns01.powerdns to be set")
+		}
+		key, err := readSecretKey(ctx, c, tgw.Namespace, cfg.PowerDNS.APIKeySecretRef)
+		if err != nil {
+			return nil, err
+		}
+		return &powerDNSProvider{
+			baseURL:  strings.TrimSuffix(cfg.PowerDNS.Host, "/"),
+			serverID: powerDNSServerID(cfg.PowerDNS),
+			apiKey:   key,
+			owner:    dnsRecordOwner(tgw),
+		}, nil
+	case gatewayv1alpha1.DNS01ProviderWebhook:
+		if cfg.Webhook == nil || cfg.Webhook.RecordsEndpoint == "" {
+			return nil, fmt.Errorf("dns01.provider=webhook requires dns01.webhook.recordsEndpoint for record publishing")
+		}
+		p := &webhookRecordProvider{endpoint: cfg.Webhook.RecordsEndpoint, owner: dnsRecordOwner(tgw)}
+		if cfg.Webhook.RecordsTokenSecretRef != nil {
+			token, err := readSecretKey(ctx, c, tgw.Namespace, *cfg.Webhook.RecordsTokenSecretRef)
+			if err != nil {
+				return nil, err
+			}
+			p.token = token
+		}
+		return p, nil
+	default:
+		return nil, fmt.Errorf("dns01.provider=%q %w (supported: cloudflare, digitalocean, hetzner, powerdns, webhook); publish the listener records outside the platform", cfg.Provider, errDNSRecordsUnsupported)
+	}
+}
+
+// readSecretKey returns the trimmed value under ref.Key of the Secret
+// ref.Name in namespace.
+func readSecretKey(ctx context.Context, c client.Reader, namespace string, ref corev1.SecretKeySelector) (string, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		return "", fmt.Errorf("get Secret %s/%s: %w", namespace, ref.Name, err)
+	}
+	val, ok := secret.Data[ref.Key]
+	if !ok || len(val) == 0 {
+		return "", fmt.Errorf("secret %s/%s has no key %q", namespace, ref.Name, ref.Key)
+	}
+	return strings.TrimSpace(string(val)), nil
+}
+
+// zoneCandidates lists the suffixes of hostname that could be the
+// authoritative zone, longest first, stopping at two labels. A
+// leading wildcard label is dropped first: "*.foo.example.com" yields
+// foo.example.com, example.com.
+func zoneCandidates(hostname string) []string {
+	hostname = strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(hostname), "*."), ".")
+	labels := strings.Split(hostname, ".")
+	var out []string
+	for i := 0; i <= len(labels)-2; i++ {
+		out = append(out, strings.Join(labels[i:], "."))
+	}
+	return out
+}
+
+// relativeName returns hostname relative to zone, using "@" for the
+// zone apex as the DigitalOcean and Hetzner APIs expect.
+func relativeName(hostname, zone string) string {
+	hostname = strings.TrimSuffix(strings.ToLower(hostname), ".")
+	if hostname == zone {
+		return "@"
+	}
+	return strings.TrimSuffix(hostname, "."+zone)
+}
+
+// remoteRecord is a single record value as reported by a provider
+// whose API stores one value per record object.
+type remoteRecord struct {
+	ID      string
+	Content string
+	TTL     int32
+}
+
+// zonedRecordAPI is the per-provider surface for APIs that model a
+// record set as individual value records inside a zone (Cloudflare,
+// DigitalOcean, Hetzner). zonedProvider layers zone discovery and
+// diffing on top.
+type zonedRecordAPI interface {
+	findZone(ctx context.Context, candidate string) (zoneID string, found bool, err error)
+	listRecords(ctx context.Context, zoneID, zone, hostname, recordType string) ([]remoteRecord, error)
+	createRecord(ctx context.Context, zoneID, zone string, rec dnsRecord, target string) error
+	updateRecord(ctx context.Context, zoneID, zone, id string, rec dnsRecord, target string) error
+	deleteRecord(ctx context.Context, zoneID, zone, id string) error
+}
+
+type zonedProvider struct {
+	api   zonedRecordAPI
+	owner string
+}
+
+func newZonedProvider(api zonedRecordAPI, owner string) *zonedProvider {
+	return &zonedProvider{api: api, owner: owner}
+}
+
+// resolveZone walks zoneCandidates until the provider recognises one.
+func (p *zonedProvider) resolveZone(ctx context.Context, hostname string) (zoneID, zone string, err error) {
+	for _, candidate := range zoneCandidates(hostname) {
+		id, found, err := p.api.findZone(ctx, candidate)
+		if err != nil {
+			return "", "", err
+		}
+		if found {
+			return id, candidate, nil
+		}
+	}
+	return "", "", fmt.Errorf("no zone found for %s", hostname)
+}
+
+// UpsertRecord converges the record set onto rec.Targets: missing
+// values are created, values with a stale TTL are updated in place
+// (providers refuse a second identical name/type/content record),
+// extra values are deleted, and nothing is written when the set
+// already matches.
+// Deletions run last so a target swap never leaves the name without
+// any address. A set owned by someone else is refused; an unclaimed
+// empty one is claimed before its first value is written.
+func (p *zonedProvider) UpsertRecord(ctx context.Context, rec dnsRecord) error {
+	zoneID, zone, err := p.resolveZone(ctx, rec.Hostname)
+	if err != nil {
+		return err
+	}
+	txtName := ownershipRecordName(rec.Hostname, rec.Type)
+	txt, err := p.api.listRecords(ctx, zoneID, zone, txtName, "TXT")
+	if err != nil {
+		return err
+	}
+	existing, err := p.api.listRecords(ctx, zoneID, zone, rec.Hostname, rec.Type)
+	if err != nil {
+		return err
+	}
+	claimed, err := claimOwnership(p.owner, rec.Hostname, rec.Type, remoteContents(txt), len(existing) > 0)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		claim := dnsRecord{Hostname: txtName, Type: "TXT", TTL: rec.TTL}
+		if err := p.api.createRecord(ctx, zoneID, zone, claim, ownershipValue(p.owner)); err != nil {
+			return err
+		}
+	}
+
+	want := make(map[string]struct{}, len(rec.Targets))
+	for _, t := range rec.Targets {
+		want[t] = struct{}{}
+	}
+	have := map[string]struct{}{}
+	var stale []string
+	var retuned []remoteRecord
+	for _, r := range existing {
+		if _, ok := want[r.Content]; ok {
+			if _, dup := have[r.Content]; !dup {
+				have[r.Content] = struct{}{}
+				if r.TTL != rec.TTL {
+					retuned = append(retuned, r)
+				}
+				continue
+			}
+		}
+		stale = append(stale, r.ID)
+	}
+	for _, r := range retuned {
+		if err := p.api.updateRecord(ctx, zoneID, zone, r.ID, rec, r.Content); err != nil {
+			return err
+		}
+	}
+	for _, t := range rec.Targets {
+		if _, ok := have[t]; ok {
+			continue
+		}
+		if err := p.api.createRecord(ctx, zoneID, zone, rec, t); err != nil {
+			return err
+		}
+	}
+	for _, id := range stale {
+		if err := p.api.deleteRecord(ctx, zoneID, zone, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteRecord removes the record set and its ownership record when
+// the TenantGateway owns them, and leaves anything else alone.
+func (p *zonedProvider) DeleteRecord(ctx context.Context, hostname, recordType string) error {
+	zoneID, zone, err := p.resolveZone(ctx, hostname)
+	if err != nil {
+		return err
+	}
+	txt, err := p.api.listRecords(ctx, zoneID, zone, ownershipRecordName(hostname, recordType), "TXT")
+	if err != nil {
+		return err
+	}
+	if !ownsRecord(p.owner, remoteContents(txt)) {
+		return nil
+	}
+	existing, err := p.api.listRecords(ctx, zoneID, zone, hostname, recordType)
+	if err != nil {
+		return err
+	}
+	for _, r := range existing {
+		if err := p.api.deleteRecord(ctx, zoneID, zone, r.ID); err != nil {
+			return err
+		}
+	}
+	// The claim goes last, so a failed pass is retried as ours.
+	for _, r := range txt {
+		if !ownsRecord(p.owner, []string{r.Content}) {
+			continue
+		}
+		if err := p.api.deleteRecord(ctx, zoneID, zone, r.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func remoteContents(records []remoteRecord) []string {
+	out := make([]string, 0, len(records))
+	for _, r := range records {
+		out = append(out, r.Content)
+	}
+	return out
+}
+
+// sameTargets reports whether a and b hold the same values, in any
+// order.
+func sameTargets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// doJSON issues an HTTP request with an optional JSON body and decodes
+// a JSON response into out when out is non-nil. Non-2xx responses are
+// returned as errors carrying a truncated response body; the status
+// code is returned either way so callers can special-case 404.
+func doJSON(ctx context.Context, method, rawURL string, headers map[string]string, body, out any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, reader)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := dnsProviderHTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("read %s %s response: %w", method, req.URL.Path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg := strings.TrimSpace(string(payload))
+		if len(msg) > 256 {
+			msg = msg[:256]
+		}
+		return resp.StatusCode, fmt.Errorf("%s %s: HTTP %d: %s", method, req.URL.Path, resp.StatusCode, msg)
+	}
+	if out != nil && len(payload) > 0 {
+		if err := json.Unmarshal(payload, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("decode %s %s response: %w", method, req.URL.Path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// cloudflareAPI implements zonedRecordAPI against the Cloudflare v4 API.
+type cloudflareAPI struct {
+	baseURL string
+	token   string
+}
+
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int32  `json:"ttl"`
+	Proxied bool   `json:"proxied"`
+}
+
+func (a *cloudflareAPI) headers() map[string]string {
+	return map[string]string{"Authorization": "Bearer " + a.token}
+}
+
+func (a *cloudflareAPI) findZone(ctx context.Context, candidate string) (string, bool, error) {
+	var resp struct {
+		Result []struct {
+			ID string `json:"id"`
+		} `json:"result"`
+	}
+	if _, err := doJSON(ctx, http.MethodGet, a.baseURL+"/zones?name="+url.QueryEscape(candidate), a.headers(), nil, &resp); err != nil {
+		return "", false, err
+	}
+	if len(resp.Result) == 0 {
+		return "", false, nil
+	}
+	return resp.Result[0].ID, true, nil
+}
+
+func (a *cloudflareAPI) listRecords(ctx context.Context, zoneID, _, hostname, recordType string) ([]remoteRecord, error) {
+	var resp struct {
+		Result []cloudflareRecord `json:"result"`
+	}
+	q := url.Values{"type": {recordType}, "name": {hostname}, "per_page": {"100"}}
+	if _, err := doJSON(ctx, http.MethodGet, a.baseURL+"/zones/"+zoneID+"/dns_records?"+q.Encode(), a.headers(), nil, &resp); err != nil {
+		return nil, err
+	}
+	out := make([]remoteRecord, 0, len(resp.Result))
+	for _, r := range resp.Result {
+		out = append(out, remoteRecord{ID: r.ID, Content: r.Content, TTL: r.TTL})
+	}
+	return out, nil
+}
+
+func (a *cloudflareAPI) createRecord(ctx context.Context, zoneID, _ string, rec dnsRecord, target string) error {
+	body := cloudflareRecord{Type: rec.Type, Name: rec.Hostname, Content: target, TTL: rec.TTL}
+	_, err := doJSON(ctx, http.MethodPost, a.baseURL+"/zones/"+zoneID+"/dns_records", a.headers(), body, nil)
+	return err
+}
+
+func (a *cloudflareAPI) updateRecord(ctx context.Context, zoneID, _, id string, rec dnsRecord, target string) error {
+	body := cloudflareRecord{Type: rec.Type, Name: rec.Hostname, Content: target, TTL: rec.TTL}
+	_, err := doJSON(ctx, http.MethodPut, a.baseURL+"/zones/"+zoneID+"/dns_records/"+id, a.headers(), body, nil)
+	return err
+}
+
+func (a *cloudflareAPI) deleteRecord(ctx context.Context, zoneID, _, id string) error {
+	_, err := doJSON(ctx, http.MethodDelete, a.baseURL+"/zones/"+zoneID+"/dns_records/"+id, a.headers(), nil, nil)
+	return err
+}
+
+// digitalOceanAPI implements zonedRecordAPI against the DigitalOcean
+// v2 domains API. DigitalOcean identifies zones by domain name, so the
+// zone ID and the zone name are the same string.
+type digitalOceanAPI struct {
+	baseURL string
+	token   string
+}
+
+type digitalOceanRecord struct {
+	ID   int64  `json:"id,omitempty"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Data string `json:"data"`
+	TTL  int32  `json:"ttl"`
+}
+
+func (a *digitalOceanAPI) headers() map[string]string {
+	return map[string]string{"Authorization": "Bearer " + a.token}
+}
+
+func (a *digitalOceanAPI) findZone(ctx context.Context, candidate string) (string, bool, error) {
+	status, err := doJSON(ctx, http.MethodGet, a.baseURL+"/domains/"+url.PathEscape(candidate), a.headers(), nil, nil)
+	if status == http.StatusNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return candidate, true, nil
+}
+
+func (a *digitalOceanAPI) listRecords(ctx context.Context, zoneID, _, hostname, recordType string) ([]remoteRecord, error) {
+	var resp struct {
+		Records []digitalOceanRecord `json:"domain_records"`
+	}
+	q := url.Values{"type": {recordType}, "name": {strings.ToLower(hostname)}, "per_page": {"200"}}
+	if _, err := doJSON(ctx, http.MethodGet, a.baseURL+"/domains/"+url.PathEscape(zoneID)+"/records?"+q.Encode(), a.headers(), nil, &resp); err != nil {
+		return nil, err
+	}
+	out := make([]remoteRecord, 0, len(resp.Records))
+	for _, r := range resp.Records {
+		out = append(out, remoteRecord{ID: fmt.Sprintf("%d", r.ID), Content: r.Data, TTL: r.TTL})
+	}
+	return out, nil
+}
+
+func (a *digitalOceanAPI) createRecord(ctx context.Context, zoneID, zone string, rec dnsRecord, target string) error {
+	body := digitalOceanRecord{Type: rec.Type, Name: relativeName(rec.Hostname, zone), Data: target, TTL: rec.TTL}
+	_, err := doJSON(ctx, http.MethodPost, a.baseURL+"/domains/"+url.PathEscape(zoneID)+"/records", a.headers(), body, nil)
+	return err
+}
+
+func (a *digitalOceanAPI) updateRecord(ctx context.Context, zoneID, zone, id string, rec dnsRecord, target string) error {
+	body := digitalOceanRecord{Type: rec.Type, Name: relativeName(rec.Hostname, zone), Data: target, TTL: rec.TTL}
+	_, err := doJSON(ctx, http.MethodPut, a.baseURL+"/domains/"+url.PathEscape(zoneID)+"/records/"+id, a.headers(), body, nil)
+	return err
+}
+
+func (a *digitalOceanAPI) deleteRecord(ctx context.Context, zoneID, _, id string) error {
+	_, err := doJSON(ctx, http.MethodDelete, a.baseURL+"/domains/"+url.PathEscape(zoneID)+"/records/"+id, a.headers(), nil, nil)
+	return err
+}
+
+// hetznerAPI implements zonedRecordAPI against the Hetzner DNS API.
+// When zoneName is set (it is required for the DNS-01 webhook) zone
+// discovery only accepts that zone.
+type hetznerAPI struct {
+	baseURL  string
+	token    string
+	zoneName string
+}
+
+type hetznerRecord struct {
+	ID     string `json:"id,omitempty"`
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	TTL    int32  `json:"ttl"`
+	ZoneID string `json:"zone_id"`
+}
+
+func (a *hetznerAPI) headers() map[string]string {
+	return map[string]string{"Auth-API-Token": a.token}
+}
+
+func (a *hetznerAPI) findZone(ctx context.Context, candidate string) (string, bool, error) {
+	if a.zoneName != "" && !strings.EqualFold(a.zoneName, candidate) {
+		return "", false, nil
+	}
+	var resp struct {
+		Zones []struct {
+			ID string `json:"id"`
+		} `json:"zones"`
+	}
+	status, err := doJSON(ctx, http.MethodGet, a.baseURL+"/zones?name="+url.QueryEscape(candidate), a.headers(), nil, &resp)
+	if status == http.StatusNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if len(resp.Zones) == 0 {
+		return "", false, nil
+	}
+	return resp.Zones[0].ID, true, nil
+}
+
+// hetznerRecordsPerPage is the page size for listing a zone's
+// records; the API has no name filter, so listRecords pages through
+// the whole zone.
+const hetznerRecordsPerPage = 100
+
+func (a *hetznerAPI) listRecords(ctx context.Context, zoneID, zone, hostname, recordType string) ([]remoteRecord, error) {
+	name := relativeName(hostname, zone)
+	var out []remoteRecord
+	for page := 1; ; page++ {
+		var resp struct {
+			Records []hetznerRecord `json:"records"`
+			Meta    struct {
+				Pagination struct {
+					LastPage int `json:"last_page"`
+				} `json:"pagination"`
+			} `json:"meta"`
+		}
+		q := url.Values{"zone_id": {zoneID}, "page": {fmt.Sprintf("%d", page)}, "per_page": {fmt.Sprintf("%d", hetznerRecordsPerPage)}}
+		if _, err := doJSON(ctx, http.MethodGet, a.baseURL+"/records?"+q.Encode(), a.headers(), nil, &resp); err != nil {
+			return nil, err
+		}
+		for _, r := range resp.Records {
+			if r.Type != recordType || r.Name != name {
+				continue
+			}
+			out = append(out, remoteRecord{ID: r.ID, Content: r.Value, TTL: r.TTL})
+		}
+		if page >= resp.Meta.Pagination.LastPage || len(resp.Records) == 0 {
+			return out, nil
+		}
+	}
+}
+
+func (a *hetznerAPI) createRecord(ctx context.Context, zoneID, zone string, rec dnsRecord, target string) error {
+	body := hetznerRecord{Type: rec.Type, Name: relativeName(rec.Hostname, zone), Value: target, TTL: rec.TTL, ZoneID: zoneID}
+	_, err := doJSON(ctx, http.MethodPost, a.baseURL+"/records", a.headers(), body, nil)
+	return err
+}
+
+func (a *hetznerAPI) updateRecord(ctx context.Context, zoneID, zone, id string, rec dnsRecord, target string) error {
+	body := hetznerRecord{Type: rec.Type, Name: relativeName(rec.Hostname, zone), Value: target, TTL: rec.TTL, ZoneID: zoneID}
+	_, err := doJSON(ctx, http.MethodPut, a.baseURL+"/records/"+url.PathEscape(id), a.headers(), body, nil)
+	return err
+}
+
+func (a *hetznerAPI) deleteRecord(ctx context.Context, _, _, id string) error {
+	_, err := doJSON(ctx, http.MethodDelete, a.baseURL+"/records/"+url.PathEscape(id), a.headers(), nil, nil)
+	return err
+}
+
+// powerDNSProvider writes whole RRsets through the PowerDNS
+// authoritative API, which replaces or deletes every value of a
+// name/type pair in a single PATCH. The ownership TXT RRset is written
+// in the same PATCH as the first values.
+type powerDNSProvider struct {
+	baseURL  string
+	serverID string
+	apiKey   string
+	owner    string
+}
+
+type powerDNSRRSet struct {
+	Name       string           `json:"name"`
+	Type       string           `json:"type"`
+	TTL        int32            `json:"ttl,omitempty"`
+	ChangeType string           `json:"changetype,omitempty"`
+	Records    []powerDNSRecord `json:"records,omitempty"`
+}
+
+type powerDNSRecord struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+func (p *powerDNSProvider) headers() map[string]string {
+	return map[string]string{"X-API-Key": p.apiKey}
+}
+
+func (p *powerDNSProvider) zoneURL(zone string) string {
+	return p.baseURL + "/api/v1/servers/" + url.PathEscape(p.serverID) + "/zones/" + url.PathEscape(zone+".")
+}
+
+func (p *powerDNSProvider) resolveZone(ctx context.Context, hostname string) (string, error) {
+	for _, candidate := range zoneCandidates(hostname) {
+		status, err := doJSON(ctx, http.MethodGet, p.zoneURL(candidate)+"?rrsets=false", p.headers(), nil, nil)
+		// PowerDNS answers 404 or 422 for an unknown zone depending
+		// on the version.
+		if status == http.StatusNotFound || status == http.StatusUnprocessableEntity {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return candidate, nil
+	}
+	return "", fmt.Errorf("no zone found for %s", hostname)
+}
+
+// rrset returns the values of the name/type RRset of zone, or nil when
+// it does not exist. Servers older than 4.8 ignore the filter and
+// return the whole zone, so the RRset is picked out here as well.
+func (p *powerDNSProvider) rrset(ctx context.Context, zone, name, recordType string) (*powerDNSRRSet, error) {
+	var resp struct {
+		RRSets []powerDNSRRSet `json:"rrsets"`
+	}
+	q := url.Values{"rrset_name": {name}, "rrset_type": {recordType}}
+	if _, err := doJSON(ctx, http.MethodGet, p.zoneURL(zone)+"?"+q.Encode(), p.headers(), nil, &resp); err != nil {
+		return nil, err
+	}
+	for i := range resp.RRSets {
+		if strings.EqualFold(resp.RRSets[i].Name, name) && resp.RRSets[i].Type == recordType {
+			return &resp.RRSets[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *powerDNSProvider) patch(ctx context.Context, zone string, rrsets ...powerDNSRRSet) error {
+	body := map[string][]powerDNSRRSet{"rrsets": rrsets}
+	_, err := doJSON(ctx, http.MethodPatch, p.zoneURL(zone), p.headers(), body, nil)
+	return err
+}
+
+func rrsetContents(rrset *powerDNSRRSet) []string {
+	if rrset == nil {
+		return nil
+	}
+	out := make([]string, 0, len(rrset.Records))
+	for _, r := range rrset.Records {
+		out = append(out, r.Content)
+	}
+	return out
+}
+
+func (p *powerDNSProvider) UpsertRecord(ctx context.Context, rec dnsRecord) error {
+	zone, err := p.resolveZone(ctx, rec.Hostname)
+	if err != nil {
+		return err
+	}
+	txtName := fqdn(ownershipRecordName(rec.Hostname, rec.Type))
+	txt, err := p.rrset(ctx, zone, txtName, "TXT")
+	if err != nil {
+		return err
+	}
+	current, err := p.rrset(ctx, zone, fqdn(rec.Hostname), rec.Type)
+	if err != nil {
+		return err
+	}
+	claimed, err := claimOwnership(p.owner, rec.Hostname, rec.Type, rrsetContents(txt), len(rrsetContents(current)) > 0)
+	if err != nil {
+		return err
+	}
+	if claimed && current != nil && current.TTL == rec.TTL && sameTargets(rrsetContents(current), rec.Targets) {
+		return nil
+	}
+
+	var rrsets []powerDNSRRSet
+	if !claimed {
+		rrsets = append(rrsets, powerDNSRRSet{
+			Name:       txtName,
+			Type:       "TXT",
+			TTL:        rec.TTL,
+			ChangeType: "REPLACE",
+			Records:    []powerDNSRecord{{Content: ownershipValue(p.owner)}},
+		})
+	}
+	records := make([]powerDNSRecord, 0, len(rec.Targets))
+	for _, t := range rec.Targets {
+		records = append(records, powerDNSRecord{Content: t})
+	}
+	rrsets = append(rrsets, powerDNSRRSet{
+		Name:       fqdn(rec.Hostname),
+		Type:       rec.Type,
+		TTL:        rec.TTL,
+		ChangeType: "REPLACE",
+		Records:    records,
+	})
+	return p.patch(ctx, zone, rrsets...)
+}
+
+func (p *powerDNSProvider) DeleteRecord(ctx context.Context, hostname, recordType string) error {
+	zone, err := p.resolveZone(ctx, hostname)
+	if err != nil {
+		return err
+	}
+	txtName := fqdn(ownershipRecordName(hostname, recordType))
+	txt, err := p.rrset(ctx, zone, txtName, "TXT")
+	if err != nil {
+		return err
+	}
+	if !ownsRecord(p.owner, rrsetContents(txt)) {
+		return nil
+	}
+	return p.patch(ctx, zone,
+		powerDNSRRSet{Name: fqdn(hostname), Type: recordType, ChangeType: "DELETE"},
+		powerDNSRRSet{Name: txtName, Type: "TXT", ChangeType: "DELETE"},
+	)
+}
+
+func fqdn(hostname string) string {
+	return strings.TrimSuffix(strings.ToLower(hostname), ".") + "."
+}
+
+// webhookRecordProvider forwards record changes to an operator-run
+// HTTP endpoint: GET with the name/type pair to read the current
+// record, PUT with the full record on upsert, DELETE with the name/type
+// pair on withdrawal. Every request names the owning TenantGateway; the
+// endpoint must not overwrite or delete records of another owner. Lets
+// operators bridge any DNS backend without the controller growing a
+// client for it.
+type webhookRecordProvider struct {
+	endpoint string
+	token    string
+	owner    string
+}
+
+type webhookRecordRequest struct {
+	Hostname string   `json:"hostname"`
+	Type     string   `json:"type"`
+	Targets  []string `json:"targets,omitempty"`
+	TTL      int32    `json:"ttl,omitempty"`
+	Owner    string   `json:"owner,omitempty"`
+}
+
+func (p *webhookRecordProvider) headers() map[string]string {
+	if p.token == "" {
+		return nil
+	}
+	return map[string]string{"Authorization": "Bearer " + p.token}
+}
+
+// current returns the record the endpoint holds for hostname and
+// recordType, or nil when it has none or cannot say (404 or 405).
+func (p *webhookRecordProvider) current(ctx context.Context, hostname, recordType string) (*webhookRecordRequest, error) {
+	u, err := url.Parse(p.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse records endpoint: %w", err)
+	}
+	q := u.Query()
+	q.Set("hostname", hostname)
+	q.Set("type", recordType)
+	q.Set("owner", p.owner)
+	u.RawQuery = q.Encode()
+	out := &webhookRecordRequest{}
+	status, err := doJSON(ctx, http.MethodGet, u.String(), p.headers(), nil, out)
+	if status == http.StatusNotFound || status == http.StatusMethodNotAllowed {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (p *webhookRecordProvider) UpsertRecord(ctx context.Context, rec dnsRecord) error {
+	current, err := p.current(ctx, rec.Hostname, rec.Type)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.Owner == p.owner && current.TTL == rec.TTL && sameTargets(current.Targets, rec.Targets) {
+		return nil
+	}
+	body := webhookRecordRequest{Hostname: rec.Hostname, Type: rec.Type, Targets: rec.Targets, TTL: rec.TTL, Owner: p.owner}
+	_, err = doJSON(ctx, http.MethodPut, p.endpoint, p.headers(), body, nil)
+	return err
+}
+
+func (p *webhookRecordProvider) DeleteRecord(ctx context.Context, hostname, recordType string) error {
+	status, err := doJSON(ctx, http.MethodDelete, p.endpoint, p.headers(), webhookRecordRequest{Hostname: hostname, Type: recordType, Owner: p.owner}, nil)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
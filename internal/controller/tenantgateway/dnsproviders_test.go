@@ -0,0 +1,435 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantgateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+)
+
+func TestZoneCandidates(t *testing.T) {
+	got := zoneCandidates("*.Harbor.Foo.example.com.")
+	want := []string{"harbor.foo.example.com", "foo.example.com", "example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("zoneCandidates=%v, want %v", got, want)
+	}
+	if got := relativeName("foo.example.com", "foo.example.com"); got != "@" {
+		t.Errorf("relativeName(apex)=%q, want @", got)
+	}
+	if got := relativeName("*.foo.example.com", "example.com"); got != "*.foo" {
+		t.Errorf("relativeName(wildcard)=%q, want *.foo", got)
+	}
+}
+
+const testDNSOwner = "tenant-foo/foo"
+
+// fakeCloudflare serves the subset of the Cloudflare v4 API the
+// publisher uses, backed by an in-memory record list for one zone.
+type fakeCloudflare struct {
+	mu      sync.Mutex
+	zone    string
+	nextID  int
+	writes  int
+	records map[string]cloudflareRecord
+}
+
+func (f *fakeCloudflare) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if req.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == "/zones":
+		var result []map[string]string
+		if req.URL.Query().Get("name") == f.zone {
+			result = append(result, map[string]string{"id": "zone1"})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "result": result})
+	case req.Method == http.MethodGet && req.URL.Path == "/zones/zone1/dns_records":
+		var result []cloudflareRecord
+		for _, r := range f.records {
+			if r.Name == req.URL.Query().Get("name") && r.Type == req.URL.Query().Get("type") {
+				result = append(result, r)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "result": result})
+	case req.Method == http.MethodPost && req.URL.Path == "/zones/zone1/dns_records":
+		var rec cloudflareRecord
+		_ = json.NewDecoder(req.Body).Decode(&rec)
+		for _, r := range f.records {
+			// Cloudflare refuses an identical record (error 81058).
+			if r.Name == rec.Name && r.Type == rec.Type && r.Content == rec.Content {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"success": false, "errors": []map[string]any{{"code": 81058, "message": "An identical record already exists."}}})
+				return
+			}
+		}
+		f.nextID++
+		f.writes++
+		rec.ID = "rec" + string(rune('0'+f.nextID))
+		f.records[rec.ID] = rec
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "result": rec})
+	case req.Method == http.MethodPut && strings.HasPrefix(req.URL.Path, "/zones/zone1/dns_records/"):
+		id := strings.TrimPrefix(req.URL.Path, "/zones/zone1/dns_records/")
+		if _, ok := f.records[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var rec cloudflareRecord
+		_ = json.NewDecoder(req.Body).Decode(&rec)
+		rec.ID = id
+		f.writes++
+		f.records[id] = rec
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "result": rec})
+	case req.Method == http.MethodDelete && strings.HasPrefix(req.URL.Path, "/zones/zone1/dns_records/"):
+		f.writes++
+		delete(f.records, strings.TrimPrefix(req.URL.Path, "/zones/zone1/dns_records/"))
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": true})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeCloudflare) contents(name, recordType string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []string
+	for _, r := range f.records {
+		if r.Name == name && r.Type == recordType {
+			out = append(out, r.Content)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (f *fakeCloudflare) writeCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.writes
+}
+
+// TestZonedProvider_CloudflareConverges pins the diffing contract of
+// list-based providers: missing targets are created, stale ones are
+// removed, an unchanged set is not written, and DeleteRecord clears
+// the whole set with its ownership record. Zone discovery walks up
+// from the hostname to the zone the account actually holds.
+func TestZonedProvider_CloudflareConverges(t *testing.T) {
+	api := &fakeCloudflare{zone: "example.com", records: map[string]cloudflareRecord{
+		"old":   {ID: "old", Type: "A", Name: "harbor.foo.example.com", Content: "192.0.2.1", TTL: 300},
+		"owner": {ID: "owner", Type: "TXT", Name: "_cozystack-a.harbor.foo.example.com", Content: ownershipValue(testDNSOwner), TTL: 300},
+	}}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	p := newZonedProvider(&cloudflareAPI{baseURL: srv.URL, token: "token"}, testDNSOwner)
+	ctx := context.Background()
+	rec := dnsRecord{Hostname: "harbor.foo.example.com", Type: "A", Targets: []string{"203.0.113.10", "203.0.113.11"}, TTL: 300}
+	if err := p.UpsertRecord(ctx, rec); err != nil {
+		t.Fatalf("UpsertRecord: %v", err)
+	}
+	if got, want := api.contents("harbor.foo.example.com", "A"), []string{"203.0.113.10", "203.0.113.11"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("records after upsert=%v, want %v", got, want)
+	}
+
+	writes := api.writeCount()
+	if err := p.UpsertRecord(ctx, rec); err != nil {
+		t.Fatalf("UpsertRecord again: %v", err)
+	}
+	if got := api.writeCount(); got != writes {
+		t.Errorf("unchanged upsert wrote %d time(s), want none", got-writes)
+	}
+
+	if err := p.DeleteRecord(ctx, "harbor.foo.example.com", "A"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+	if got := api.contents("harbor.foo.example.com", "A"); len(got) != 0 {
+		t.Errorf("records after delete=%v, want none", got)
+	}
+	if got := api.contents("_cozystack-a.harbor.foo.example.com", "TXT"); len(got) != 0 {
+		t.Errorf("ownership record after delete=%v, want none", got)
+	}
+}
+
+// TestZonedProvider_TTLChangeUpdatesInPlace pins that a TTL-only
+// change rewrites the existing record by ID: Cloudflare refuses a
+// second record with the same name, type and content, so
+// create-then-delete would fail on every pass.
+func TestZonedProvider_TTLChangeUpdatesInPlace(t *testing.T) {
+	api := &fakeCloudflare{zone: "example.com", records: map[string]cloudflareRecord{
+		"live":  {ID: "live", Type: "A", Name: "harbor.foo.example.com", Content: "203.0.113.10", TTL: 300},
+		"owner": {ID: "owner", Type: "TXT", Name: "_cozystack-a.harbor.foo.example.com", Content: ownershipValue(testDNSOwner), TTL: 300},
+	}}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	p := newZonedProvider(&cloudflareAPI{baseURL: srv.URL, token: "token"}, testDNSOwner)
+	rec := dnsRecord{Hostname: "harbor.foo.example.com", Type: "A", Targets: []string{"203.0.113.10"}, TTL: 120}
+	if err := p.UpsertRecord(context.Background(), rec); err != nil {
+		t.Fatalf("UpsertRecord: %v", err)
+	}
+	if got := api.writeCount(); got != 1 {
+		t.Errorf("TTL change wrote %d time(s), want 1", got)
+	}
+	if got := api.records["live"]; got.TTL != 120 || got.Content != "203.0.113.10" {
+		t.Errorf("record after TTL change=%+v, want TTL 120 on the same record", got)
+	}
+}
+
+// TestHetznerAPI_ListRecordsPaginates pins that records past the
+// first page are seen, so stale values there are cleaned up too.
+func TestHetznerAPI_ListRecordsPaginates(t *testing.T) {
+	pages := map[string][]hetznerRecord{
+		"1": {{ID: "a", Type: "A", Name: "www", Value: "192.0.2.1", TTL: 300}, {ID: "b", Type: "TXT", Name: "www", Value: "x", TTL: 300}},
+		"2": {{ID: "c", Type: "A", Name: "www", Value: "192.0.2.2", TTL: 300}, {ID: "d", Type: "A", Name: "shop", Value: "192.0.2.3", TTL: 300}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/records" || req.URL.Query().Get("zone_id") != "zone1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"records": pages[req.URL.Query().Get("page")],
+			"meta":    map[string]any{"pagination": map[string]any{"last_page": 2}},
+		})
+	}))
+	defer srv.Close()
+
+	api := &hetznerAPI{baseURL: srv.URL, token: "token"}
+	got, err := api.listRecords(context.Background(), "zone1", "example.com", "www.example.com", "A")
+	if err != nil {
+		t.Fatalf("listRecords: %v", err)
+	}
+	want := []remoteRecord{{ID: "a", Content: "192.0.2.1", TTL: 300}, {ID: "c", Content: "192.0.2.2", TTL: 300}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("listRecords=%+v, want %+v", got, want)
+	}
+}
+
+// TestZonedProvider_TouchesOnlyOwnedRecords pins the ownership
+// registry: a fresh name is claimed with a TXT record before its
+// values are written, while record sets claimed by someone else or by
+// nobody are neither overwritten nor deleted.
+func TestZonedProvider_TouchesOnlyOwnedRecords(t *testing.T) {
+	api := &fakeCloudflare{zone: "example.com", records: map[string]cloudflareRecord{
+		"manual":  {ID: "manual", Type: "A", Name: "www.example.com", Content: "192.0.2.1", TTL: 300},
+		"foreign": {ID: "foreign", Type: "A", Name: "shop.example.com", Content: "192.0.2.2", TTL: 300},
+		"claim":   {ID: "claim", Type: "TXT", Name: "_cozystack-a.shop.example.com", Content: ownershipValue("tenant-bar/bar"), TTL: 300},
+	}}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	p := newZonedProvider(&cloudflareAPI{baseURL: srv.URL, token: "token"}, testDNSOwner)
+	ctx := context.Background()
+
+	if err := p.UpsertRecord(ctx, dnsRecord{Hostname: "*.foo.example.com", Type: "A", Targets: []string{"203.0.113.10"}, TTL: 300}); err != nil {
+		t.Fatalf("UpsertRecord(fresh): %v", err)
+	}
+	if got, want := api.contents("_cozystack-a-wildcard.foo.example.com", "TXT"), []string{ownershipValue(testDNSOwner)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ownership record=%v, want %v", got, want)
+	}
+
+	for _, host := range []string{"www.example.com", "shop.example.com"} {
+		err := p.UpsertRecord(ctx, dnsRecord{Hostname: host, Type: "A", Targets: []string{"203.0.113.10"}, TTL: 300})
+		if !errors.Is(err, errDNSRecordNotOwned) {
+			t.Errorf("UpsertRecord(%s) err=%v, want errDNSRecordNotOwned", host, err)
+		}
+		if err := p.DeleteRecord(ctx, host, "A"); err != nil {
+			t.Errorf("DeleteRecord(%s): %v", host, err)
+		}
+	}
+	if got := api.contents("www.example.com", "A"); !reflect.DeepEqual(got, []string{"192.0.2.1"}) {
+		t.Errorf("unowned record touched: %v", got)
+	}
+	if got := api.contents("shop.example.com", "A"); !reflect.DeepEqual(got, []string{"192.0.2.2"}) {
+		t.Errorf("foreign record touched: %v", got)
+	}
+}
+
+func TestOwnershipValueRoundTrip(t *testing.T) {
+	for _, value := range []string{ownershipValue(testDNSOwner), strings.Trim(ownershipValue(testDNSOwner), `"`)} {
+		if owner, ok := parseOwnershipValue(value); !ok || owner != testDNSOwner {
+			t.Errorf("parseOwnershipValue(%s)=%q, %v", value, owner, ok)
+		}
+	}
+	if _, ok := parseOwnershipValue(`"heritage=external-dns,external-dns/owner=default"`); ok {
+		t.Error("external-dns ownership record taken for ours")
+	}
+}
+
+// TestPowerDNSProvider_ReplacesRRSet pins the PowerDNS wire format:
+// the zone is discovered with a GET per candidate, the current RRsets
+// are read, then a single PATCH claims the name and REPLACEs the whole
+// RRset with the trailing-dot FQDN. A matching RRset is not patched.
+func TestPowerDNSProvider_ReplacesRRSet(t *testing.T) {
+	var patches []map[string][]powerDNSRRSet
+	rrsets := map[string]powerDNSRRSet{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case req.Method == http.MethodGet && req.URL.Path == "/api/v1/servers/localhost/zones/example.com.":
+			q := req.URL.Query()
+			var found []powerDNSRRSet
+			if rrset, ok := rrsets[q.Get("rrset_name")+" "+q.Get("rrset_type")]; ok {
+				found = append(found, rrset)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"name": "example.com.", "rrsets": found})
+		case req.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+		case req.Method == http.MethodPatch && req.URL.Path == "/api/v1/servers/localhost/zones/example.com.":
+			var body map[string][]powerDNSRRSet
+			_ = json.NewDecoder(req.Body).Decode(&body)
+			patches = append(patches, body)
+			for _, rrset := range body["rrsets"] {
+				key := rrset.Name + " " + rrset.Type
+				if rrset.ChangeType == "DELETE" {
+					delete(rrsets, key)
+					continue
+				}
+				rrset.ChangeType = ""
+				rrsets[key] = rrset
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	p := &powerDNSProvider{baseURL: srv.URL, serverID: "localhost", apiKey: "secret", owner: testDNSOwner}
+	ctx := context.Background()
+	rec := dnsRecord{Hostname: "*.foo.example.com", Type: "A", Targets: []string{"203.0.113.10"}, TTL: 120}
+	for i := 0; i < 2; i++ {
+		if err := p.UpsertRecord(ctx, rec); err != nil {
+			t.Fatalf("UpsertRecord: %v", err)
+		}
+	}
+	if err := p.DeleteRecord(ctx, "*.foo.example.com", "A"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+	claim := powerDNSRRSet{Name: "_cozystack-a-wildcard.foo.example.com.", Type: "TXT", TTL: 120, ChangeType: "REPLACE", Records: []powerDNSRecord{{Content: ownershipValue(testDNSOwner)}}}
+	want := []map[string][]powerDNSRRSet{
+		{"rrsets": {claim, {Name: "*.foo.example.com.", Type: "A", TTL: 120, ChangeType: "REPLACE", Records: []powerDNSRecord{{Content: "203.0.113.10"}}}}},
+		{"rrsets": {{Name: "*.foo.example.com.", Type: "A", ChangeType: "DELETE"}, {Name: "_cozystack-a-wildcard.foo.example.com.", Type: "TXT", ChangeType: "DELETE"}}},
+	}
+	if !reflect.DeepEqual(patches, want) {
+		t.Errorf("PATCH bodies=%+v, want %+v", patches, want)
+	}
+}
+
+// TestWebhookRecordProvider_SendsBearerToken pins the generic webhook
+// contract: GET the current record, PUT the full record only when it
+// differs, DELETE with name/type, the owner on every request and the
+// optional bearer token on all of them.
+func TestWebhookRecordProvider_SendsBearerToken(t *testing.T) {
+	var got []string
+	var stored *webhookRecordRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			q := req.URL.Query()
+			got = append(got, req.Method+" "+req.Header.Get("Authorization")+" "+q.Get("hostname")+" "+q.Get("type")+" "+q.Get("owner"))
+			if stored == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(stored)
+			return
+		}
+		var body webhookRecordRequest
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		got = append(got, req.Method+" "+req.Header.Get("Authorization")+" "+body.Hostname+" "+body.Type+" "+body.Owner+" "+strings.Join(body.Targets, ","))
+		switch req.Method {
+		case http.MethodPut:
+			stored = &body
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p := &webhookRecordProvider{endpoint: srv.URL, token: "t0k", owner: testDNSOwner}
+	ctx := context.Background()
+	rec := dnsRecord{Hostname: "a.example.com", Type: "A", Targets: []string{"203.0.113.1"}, TTL: 300}
+	for i := 0; i < 2; i++ {
+		if err := p.UpsertRecord(ctx, rec); err != nil {
+			t.Fatalf("UpsertRecord: %v", err)
+		}
+	}
+	if err := p.DeleteRecord(ctx, "a.example.com", "A"); err != nil {
+		t.Fatalf("DeleteRecord must treat 404 as already gone: %v", err)
+	}
+	want := []string{
+		"GET Bearer t0k a.example.com A tenant-foo/foo",
+		"PUT Bearer t0k a.example.com A tenant-foo/foo 203.0.113.1",
+		"GET Bearer t0k a.example.com A tenant-foo/foo",
+		"DELETE Bearer t0k a.example.com A tenant-foo/foo ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("requests=%q, want %q", got, want)
+	}
+}
+
+// TestNewDNSRecordProvider_HetznerReadsWebhookKey pins that record
+// publishing reads the Hetzner token from the same Secret key as the
+// cert-manager webhook.
+func TestNewDNSRecordProvider_HetznerReadsWebhookKey(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "hetzner-token", Namespace: "tenant-foo"},
+		Data:       map[string][]byte{hetznerAPIKeySecretKey: []byte("tok\n")},
+	}
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(secret).Build()
+	tgw := &gatewayv1alpha1.TenantGateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "tenant-foo"},
+		Spec: gatewayv1alpha1.TenantGatewaySpec{DNS01: &gatewayv1alpha1.DNS01Config{
+			Provider: gatewayv1alpha1.DNS01ProviderHetzner,
+			Hetzner: &gatewayv1alpha1.HetznerDNS01{
+				ZoneName:        "example.com",
+				APIKeySecretRef: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "hetzner-token"}, Key: hetznerAPIKeySecretKey},
+			},
+		}},
+	}
+	p, err := newDNSRecordProvider(context.Background(), c, tgw)
+	if err != nil {
+		t.Fatalf("newDNSRecordProvider: %v", err)
+	}
+	zoned, ok := p.(*zonedProvider)
+	if !ok {
+		t.Fatalf("provider=%T, want *zonedProvider", p)
+	}
+	if api := zoned.api.(*hetznerAPI); api.token != "tok" || zoned.owner != testDNSOwner {
+		t.Errorf("hetzner token=%q owner=%q", api.token, zoned.owner)
+	}
+}
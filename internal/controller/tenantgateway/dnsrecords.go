@@ -0,0 +1,321 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantgateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+)
+
+// dnsRecordsFinalizer holds a TenantGateway until every record it
+// published has been withdrawn from the provider. Only added while
+// Spec.DNSRecords is enabled; a Gateway that never published records
+// deletes without waiting on the DNS API.
+const dnsRecordsFinalizer = "gateway.cozystack.io/dns-records"
+
+// dnsRecordRetryInterval is how soon a TenantGateway with a Failed
+// listener record is reconciled again. Provider errors are usually
+// transient (rate limits, API hiccups) and nothing else would wake
+// the reconciler once the Gateway has settled.
+const dnsRecordRetryInterval = 2 * time.Minute
+
+const defaultDNSRecordTTL int32 = 300
+
+// listenerDNSRecord is the per-hostname outcome of a publishing pass,
+// surfaced on TenantGatewayListenerStatus by reconcileStatus.
+type listenerDNSRecord struct {
+	State   gatewayv1alpha1.DNSRecordState
+	Message string
+}
+
+// dnsRecordsResult carries the publishing outcome from
+// reconcileDNSRecords to reconcileStatus, which persists both in a
+// single status write.
+type dnsRecordsResult struct {
+	listeners map[string]listenerDNSRecord
+	published []gatewayv1alpha1.PublishedDNSRecord
+}
+
+func dnsRecordsEnabled(tgw *gatewayv1alpha1.TenantGateway) bool {
+	return tgw.Spec.DNSRecords != nil && tgw.Spec.DNSRecords.Enabled
+}
+
+func (r *Reconciler) dnsProvider(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway) (dnsRecordProvider, error) {
+	if r.newDNSProvider != nil {
+		return r.newDNSProvider(ctx, r.secretReader(), tgw)
+	}
+	return newDNSRecordProvider(ctx, r.secretReader(), tgw)
+}
+
+// reconcileDNSRecords publishes A/AAAA records for every hostname the
+// rendered Gateway listens on and withdraws the records recorded in
+// Status.PublishedDNSRecords that no longer match a listener.
+//
+// Provider failures are not reconcile errors: they are reported per
+// listener (DNSRecord=Failed) so a flaky DNS API never blocks the
+// Gateway / Certificate work, and Reconcile requeues on its own. A
+// provider without a records API reports DNSRecord=Unsupported and is
+// not retried.
+// Returns nil when publishing is disabled and nothing is left to
+// withdraw, leaving listener DNS state empty.
+func (r *Reconciler) reconcileDNSRecords(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway) (*dnsRecordsResult, error) {
+	logger := log.FromContext(ctx)
+
+	if !dnsRecordsEnabled(tgw) {
+		if len(tgw.Status.PublishedDNSRecords) == 0 {
+			return nil, nil
+		}
+		provider, err := r.dnsProvider(ctx, tgw)
+		if err != nil {
+			// The provider config is gone along with the publishing
+			// switch; nothing can withdraw the records any more.
+			// Forget them rather than failing every future reconcile.
+			logger.Error(err, "cannot withdraw DNS records after publishing was disabled; forgetting them", "records", len(tgw.Status.PublishedDNSRecords))
+			return &dnsRecordsResult{}, nil
+		}
+		return &dnsRecordsResult{published: withdrawDNSRecords(ctx, provider, tgw.Status.PublishedDNSRecords, nil)}, nil
+	}
+
+	gw := &gatewayv1.Gateway{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: tgw.Namespace, Name: tgw.Name}, gw); err != nil {
+		return nil, fmt.Errorf("get Gateway for DNS records: %w", err)
+	}
+	hostnames := listenerHostnames(gw)
+	result := &dnsRecordsResult{listeners: make(map[string]listenerDNSRecord, len(hostnames))}
+
+	provider, err := r.dnsProvider(ctx, tgw)
+	if err != nil {
+		state := gatewayv1alpha1.DNSRecordStateFailed
+		if errors.Is(err, errDNSRecordsUnsupported) {
+			state = gatewayv1alpha1.DNSRecordStateUnsupported
+		}
+		for _, h := range hostnames {
+			result.listeners[h] = listenerDNSRecord{State: state, Message: err.Error()}
+		}
+		// Keep the bookkeeping: the records are still out there and
+		// will be withdrawn once the provider is reachable again.
+		result.published = tgw.Status.PublishedDNSRecords
+		return result, nil
+	}
+
+	targets := dnsRecordTargets(tgw, gw)
+	if len(targets) == 0 {
+		for _, h := range hostnames {
+			result.listeners[h] = listenerDNSRecord{
+				State:   gatewayv1alpha1.DNSRecordStatePending,
+				Message: "Gateway has no IP address yet",
+			}
+		}
+		result.published = tgw.Status.PublishedDNSRecords
+		return result, nil
+	}
+
+	ttl := tgw.Spec.DNSRecords.TTL
+	if ttl == 0 {
+		ttl = defaultDNSRecordTTL
+	}
+	desired := map[gatewayv1alpha1.PublishedDNSRecord]struct{}{}
+	for _, h := range hostnames {
+		state := listenerDNSRecord{State: gatewayv1alpha1.DNSRecordStatePublished}
+		for _, recordType := range []string{"A", "AAAA"} {
+			if len(targets[recordType]) == 0 {
+				continue
+			}
+			key := gatewayv1alpha1.PublishedDNSRecord{Hostname: h, Type: recordType}
+			desired[key] = struct{}{}
+			rec := dnsRecord{Hostname: h, Type: recordType, Targets: targets[recordType], TTL: ttl}
+			if err := provider.UpsertRecord(ctx, rec); err != nil {
+				logger.Error(err, "publish DNS record", "hostname", h, "type", recordType)
+				state = listenerDNSRecord{State: gatewayv1alpha1.DNSRecordStateFailed, Message: err.Error()}
+				continue
+			}
+			result.published = append(result.published, key)
+		}
+		result.listeners[h] = state
+	}
+
+	// Anything recorded as published but no longer desired is
+	// withdrawn. Failed withdrawals stay in the list for the next pass.
+	var stale []gatewayv1alpha1.PublishedDNSRecord
+	for _, rec := range tgw.Status.PublishedDNSRecords {
+		if _, keep := desired[rec]; keep {
+			// A desired record whose upsert failed this pass is still
+			// out there with its previous targets; keep tracking it.
+			if !containsPublishedRecord(result.published, rec) {
+				result.published = append(result.published, rec)
+			}
+			continue
+		}
+		stale = append(stale, rec)
+	}
+	result.published = withdrawDNSRecords(ctx, provider, stale, result.published)
+	sortPublishedDNSRecords(result.published)
+	return result, nil
+}
+
+// withdrawDNSRecords deletes every record in stale and returns keep
+// extended with the records whose deletion failed.
+func withdrawDNSRecords(ctx context.Context, provider dnsRecordProvider, stale, keep []gatewayv1alpha1.PublishedDNSRecord) []gatewayv1alpha1.PublishedDNSRecord {
+	logger := log.FromContext(ctx)
+	for _, rec := range stale {
+		if err := provider.DeleteRecord(ctx, rec.Hostname, rec.Type); err != nil {
+			logger.Error(err, "withdraw DNS record", "hostname", rec.Hostname, "type", rec.Type)
+			keep = append(keep, rec)
+			continue
+		}
+		logger.V(1).Info("withdrew DNS record", "hostname", rec.Hostname, "type", rec.Type)
+	}
+	sortPublishedDNSRecords(keep)
+	return keep
+}
+
+// finalizeDNSRecords withdraws every published record of a
+// TenantGateway being deleted, then releases the finalizer. A failed
+// withdrawal keeps the finalizer so the deletion retries.
+func (r *Reconciler) finalizeDNSRecords(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway) error {
+	if !controllerutil.ContainsFinalizer(tgw, dnsRecordsFinalizer) {
+		return nil
+	}
+	if len(tgw.Status.PublishedDNSRecords) > 0 {
+		provider, err := r.dnsProvider(ctx, tgw)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "cannot withdraw DNS records on deletion; releasing finalizer", "records", len(tgw.Status.PublishedDNSRecords))
+		} else if left := withdrawDNSRecords(ctx, provider, tgw.Status.PublishedDNSRecords, nil); len(left) > 0 {
+			tgw.Status.PublishedDNSRecords = left
+			if err := r.Status().Update(ctx, tgw); err != nil {
+				return fmt.Errorf("record remaining DNS records: %w", err)
+			}
+			return fmt.Errorf("%d DNS record(s) could not be withdrawn", len(left))
+		}
+	}
+	before := tgw.DeepCopy()
+	controllerutil.RemoveFinalizer(tgw, dnsRecordsFinalizer)
+	return r.Patch(ctx, tgw, client.MergeFrom(before))
+}
+
+// ensureDNSRecordsFinalizer adds the finalizer while publishing is on
+// and drops it once publishing is off and nothing is left to withdraw.
+func (r *Reconciler) ensureDNSRecordsFinalizer(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway) error {
+	want := dnsRecordsEnabled(tgw) || len(tgw.Status.PublishedDNSRecords) > 0
+	has := controllerutil.ContainsFinalizer(tgw, dnsRecordsFinalizer)
+	if want == has {
+		return nil
+	}
+	before := tgw.DeepCopy()
+	if want {
+		controllerutil.AddFinalizer(tgw, dnsRecordsFinalizer)
+	} else {
+		controllerutil.RemoveFinalizer(tgw, dnsRecordsFinalizer)
+	}
+	if err := r.Patch(ctx, tgw, client.MergeFrom(before)); err != nil {
+		return fmt.Errorf("patch DNS records finalizer: %w", err)
+	}
+	return nil
+}
+
+// listenerHostnames returns the deduplicated, sorted hostnames the
+// Gateway listens on. Listeners without a hostname (the port-80 http
+// listener) publish nothing.
+func listenerHostnames(gw *gatewayv1.Gateway) []string {
+	seen := map[string]struct{}{}
+	var out []string
+	for _, l := range gw.Spec.Listeners {
+		if l.Hostname == nil || *l.Hostname == "" {
+			continue
+		}
+		h := string(*l.Hostname)
+		if _, dup := seen[h]; dup {
+			continue
+		}
+		seen[h] = struct{}{}
+		out = append(out, h)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// dnsRecordTargets splits the record targets into A and AAAA sets.
+// Spec.DNSRecords.Targets wins; otherwise the Gateway's reported
+// addresses are used. Hostname-typed addresses are skipped — a CNAME
+// cannot sit on the tenant apex, so only IP targets are published.
+func dnsRecordTargets(tgw *gatewayv1alpha1.TenantGateway, gw *gatewayv1.Gateway) map[string][]string {
+	var raw []string
+	if len(tgw.Spec.DNSRecords.Targets) > 0 {
+		raw = tgw.Spec.DNSRecords.Targets
+	} else {
+		for _, a := range gw.Status.Addresses {
+			if a.Type != nil && *a.Type != gatewayv1.IPAddressType {
+				continue
+			}
+			raw = append(raw, a.Value)
+		}
+	}
+	out := map[string][]string{}
+	seen := map[string]struct{}{}
+	for _, v := range raw {
+		ip := net.ParseIP(v)
+		if ip == nil {
+			continue
+		}
+		canonical := ip.String()
+		if _, dup := seen[canonical]; dup {
+			continue
+		}
+		seen[canonical] = struct{}{}
+		if ip.To4() != nil {
+			out["A"] = append(out["A"], canonical)
+		} else {
+			out["AAAA"] = append(out["AAAA"], canonical)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	sort.Strings(out["A"])
+	sort.Strings(out["AAAA"])
+	return out
+}
+
+func containsPublishedRecord(list []gatewayv1alpha1.PublishedDNSRecord, rec gatewayv1alpha1.PublishedDNSRecord) bool {
+	for _, r := range list {
+		if r == rec {
+			return true
+		}
+	}
+	return false
+}
+
+func sortPublishedDNSRecords(list []gatewayv1alpha1.PublishedDNSRecord) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Hostname != list[j].Hostname {
+			return list[i].Hostname < list[j].Hostname
+		}
+		return list[i].Type < list[j].Type
+	})
+}
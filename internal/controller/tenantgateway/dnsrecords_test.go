@@ -0,0 +1,344 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantgateway
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+)
+
+// fakeDNSProvider records the zone content the reconciler converges
+// on, keyed by hostname/type.
+type fakeDNSProvider struct {
+	records map[gatewayv1alpha1.PublishedDNSRecord][]string
+	failFor string
+}
+
+func newFakeDNSProvider() *fakeDNSProvider {
+	return &fakeDNSProvider{records: map[gatewayv1alpha1.PublishedDNSRecord][]string{}}
+}
+
+func (p *fakeDNSProvider) UpsertRecord(_ context.Context, rec dnsRecord) error {
+	if rec.Hostname == p.failFor {
+		return errors.New("provider API unavailable")
+	}
+	p.records[gatewayv1alpha1.PublishedDNSRecord{Hostname: rec.Hostname, Type: rec.Type}] = rec.Targets
+	return nil
+}
+
+func (p *fakeDNSProvider) DeleteRecord(_ context.Context, hostname, recordType string) error {
+	delete(p.records, gatewayv1alpha1.PublishedDNSRecord{Hostname: hostname, Type: recordType})
+	return nil
+}
+
+func (p *fakeDNSProvider) factory() dnsProviderFactory {
+	return func(context.Context, client.Reader, *gatewayv1alpha1.TenantGateway) (dnsRecordProvider, error) {
+		return p, nil
+	}
+}
+
+func dnsRecordsTenantGateway() *gatewayv1alpha1.TenantGateway {
+	return &gatewayv1alpha1.TenantGateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack", Namespace: "tenant-foo"},
+		Spec: gatewayv1alpha1.TenantGatewaySpec{
+			Apex:               "foo.example.com",
+			CertMode:           gatewayv1alpha1.CertModeHTTP01,
+			GatewayClassName:   "cilium",
+			AttachedNamespaces: []string{"cozy-harbor"},
+			DNS01: &gatewayv1alpha1.DNS01Config{
+				Provider: gatewayv1alpha1.DNS01ProviderCloudflare,
+				Cloudflare: &gatewayv1alpha1.CloudflareDNS01{
+					APITokenSecretRef: corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "cf"},
+						Key:                  "api-token",
+					},
+				},
+			},
+			DNSRecords: &gatewayv1alpha1.DNSRecordsConfig{Enabled: true},
+		},
+	}
+}
+
+func reconcileTGW(t *testing.T, r *Reconciler) ctrl.Result {
+	t.Helper()
+	res, err := r.Reconcile(context.TODO(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: "cozystack", Namespace: "tenant-foo"},
+	})
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	return res
+}
+
+func setGatewayAddresses(t *testing.T, c client.Client, addrs ...string) {
+	t.Helper()
+	gw := &gatewayv1.Gateway{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "cozystack", Namespace: "tenant-foo"}, gw); err != nil {
+		t.Fatalf("get Gateway: %v", err)
+	}
+	ipType := gatewayv1.IPAddressType
+	gw.Status.Addresses = nil
+	for _, a := range addrs {
+		gw.Status.Addresses = append(gw.Status.Addresses, gatewayv1.GatewayStatusAddress{Type: &ipType, Value: a})
+	}
+	if err := c.Status().Update(context.TODO(), gw); err != nil {
+		t.Fatalf("update Gateway status: %v", err)
+	}
+}
+
+func getTGW(t *testing.T, c client.Client) *gatewayv1alpha1.TenantGateway {
+	t.Helper()
+	got := &gatewayv1alpha1.TenantGateway{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "cozystack", Namespace: "tenant-foo"}, got); err != nil {
+		t.Fatalf("get tgw: %v", err)
+	}
+	return got
+}
+
+func listenerStatusFor(tgw *gatewayv1alpha1.TenantGateway, hostname string) *gatewayv1alpha1.TenantGatewayListenerStatus {
+	for i := range tgw.Status.Listeners {
+		if tgw.Status.Listeners[i].Hostname == hostname {
+			return &tgw.Status.Listeners[i]
+		}
+	}
+	return nil
+}
+
+// TestReconcile_DNSRecordsPublishedPerListenerHostname pins the
+// publishing flow: once the Gateway reports addresses, every listener
+// hostname gets A/AAAA records with the Gateway's IPs, the listener
+// status reports Published, and the TenantGateway carries the
+// finalizer that withdraws them on deletion.
+func TestReconcile_DNSRecordsPublishedPerListenerHostname(t *testing.T) {
+	s := newScheme(t)
+	tgw := dnsRecordsTenantGateway()
+	route := httpRouteAttached("harbor", "cozy-harbor", "harbor.foo.example.com")
+	c := fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(tgw, route).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}).
+		Build()
+	provider := newFakeDNSProvider()
+	r := &Reconciler{Client: c, Scheme: s, newDNSProvider: provider.factory()}
+
+	reconcileTGW(t, r)
+	got := getTGW(t, c)
+	if ls := listenerStatusFor(got, "harbor.foo.example.com"); ls == nil || ls.DNSRecord != gatewayv1alpha1.DNSRecordStatePending {
+		t.Fatalf("expected Pending DNS record before the Gateway has an address, got %+v", ls)
+	}
+	if len(provider.records) != 0 {
+		t.Fatalf("expected no records without Gateway addresses, got %v", provider.records)
+	}
+	if !controllerutil.ContainsFinalizer(got, dnsRecordsFinalizer) {
+		t.Errorf("expected finalizer %s while publishing is enabled, got %v", dnsRecordsFinalizer, got.Finalizers)
+	}
+
+	setGatewayAddresses(t, c, "203.0.113.10", "2001:db8::10")
+	reconcileTGW(t, r)
+
+	want := map[gatewayv1alpha1.PublishedDNSRecord][]string{
+		{Hostname: "harbor.foo.example.com", Type: "A"}:    {"203.0.113.10"},
+		{Hostname: "harbor.foo.example.com", Type: "AAAA"}: {"2001:db8::10"},
+	}
+	if !reflect.DeepEqual(provider.records, want) {
+		t.Errorf("provider records=%v, want %v", provider.records, want)
+	}
+	got = getTGW(t, c)
+	if ls := listenerStatusFor(got, "harbor.foo.example.com"); ls == nil || ls.DNSRecord != gatewayv1alpha1.DNSRecordStatePublished {
+		t.Errorf("expected Published DNS record on harbor listener, got %+v", ls)
+	}
+	if ls := listenerStatusFor(got, ""); ls != nil && ls.DNSRecord != "" {
+		t.Errorf("hostname-less http listener must carry no DNS record state, got %+v", ls)
+	}
+	wantPublished := []gatewayv1alpha1.PublishedDNSRecord{
+		{Hostname: "harbor.foo.example.com", Type: "A"},
+		{Hostname: "harbor.foo.example.com", Type: "AAAA"},
+	}
+	if !reflect.DeepEqual(got.Status.PublishedDNSRecords, wantPublished) {
+		t.Errorf("Status.PublishedDNSRecords=%v, want %v", got.Status.PublishedDNSRecords, wantPublished)
+	}
+}
+
+// TestReconcile_DNSRecordsWithdrawnWhenRouteGoesAway pins the GC
+// half: a record published for a hostname that no longer backs a
+// listener is deleted from the provider and dropped from status.
+func TestReconcile_DNSRecordsWithdrawnWhenRouteGoesAway(t *testing.T) {
+	s := newScheme(t)
+	tgw := dnsRecordsTenantGateway()
+	tgw.Spec.DNSRecords.Targets = []string{"198.51.100.7"}
+	route := httpRouteAttached("harbor", "cozy-harbor", "harbor.foo.example.com")
+	c := fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(tgw, route).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}).
+		Build()
+	provider := newFakeDNSProvider()
+	r := &Reconciler{Client: c, Scheme: s, newDNSProvider: provider.factory()}
+
+	reconcileTGW(t, r)
+	if _, ok := provider.records[gatewayv1alpha1.PublishedDNSRecord{Hostname: "harbor.foo.example.com", Type: "A"}]; !ok {
+		t.Fatalf("expected harbor A record from spec targets, got %v", provider.records)
+	}
+
+	if err := c.Delete(context.TODO(), route); err != nil {
+		t.Fatalf("delete route: %v", err)
+	}
+	reconcileTGW(t, r)
+
+	if len(provider.records) != 0 {
+		t.Errorf("expected harbor record withdrawn, got %v", provider.records)
+	}
+	if got := getTGW(t, c); len(got.Status.PublishedDNSRecords) != 0 {
+		t.Errorf("expected empty Status.PublishedDNSRecords, got %v", got.Status.PublishedDNSRecords)
+	}
+}
+
+// TestReconcile_DNSRecordsProviderFailureRequeues pins the failure
+// contract: a provider error marks the listener Failed with the
+// error message and requeues, without failing the reconcile.
+func TestReconcile_DNSRecordsProviderFailureRequeues(t *testing.T) {
+	s := newScheme(t)
+	tgw := dnsRecordsTenantGateway()
+	tgw.Spec.DNSRecords.Targets = []string{"198.51.100.7"}
+	route := httpRouteAttached("harbor", "cozy-harbor", "harbor.foo.example.com")
+	c := fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(tgw, route).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}).
+		Build()
+	provider := newFakeDNSProvider()
+	provider.failFor = "harbor.foo.example.com"
+	r := &Reconciler{Client: c, Scheme: s, newDNSProvider: provider.factory()}
+
+	res := reconcileTGW(t, r)
+	if res.RequeueAfter != dnsRecordRetryInterval {
+		t.Errorf("RequeueAfter=%v, want %v", res.RequeueAfter, dnsRecordRetryInterval)
+	}
+	ls := listenerStatusFor(getTGW(t, c), "harbor.foo.example.com")
+	if ls == nil || ls.DNSRecord != gatewayv1alpha1.DNSRecordStateFailed || ls.DNSRecordMessage != "provider API unavailable" {
+		t.Errorf("expected Failed DNS record with provider message, got %+v", ls)
+	}
+}
+
+// TestReconcile_DNSRecordsUnsupportedProvider pins the status for a
+// dns01 provider without a records API: every listener reports
+// Unsupported with the reason, and the TenantGateway is not requeued
+// for a state no retry can change.
+func TestReconcile_DNSRecordsUnsupportedProvider(t *testing.T) {
+	s := newScheme(t)
+	tgw := dnsRecordsTenantGateway()
+	tgw.Spec.DNS01 = &gatewayv1alpha1.DNS01Config{
+		Provider: gatewayv1alpha1.DNS01ProviderRoute53,
+		Route53:  &gatewayv1alpha1.Route53DNS01{Region: "us-east-1"},
+	}
+	route := httpRouteAttached("harbor", "cozy-harbor", "harbor.foo.example.com")
+	c := fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(tgw, route).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}).
+		Build()
+	r := &Reconciler{Client: c, Scheme: s}
+
+	res := reconcileTGW(t, r)
+	if res.RequeueAfter != 0 {
+		t.Errorf("RequeueAfter=%v, want no requeue", res.RequeueAfter)
+	}
+	ls := listenerStatusFor(getTGW(t, c), "harbor.foo.example.com")
+	if ls == nil || ls.DNSRecord != gatewayv1alpha1.DNSRecordStateUnsupported || !strings.Contains(ls.DNSRecordMessage, `dns01.provider="route53" cannot publish DNS records`) {
+		t.Errorf("expected Unsupported DNS record for route53, got %+v", ls)
+	}
+}
+
+// TestReconcile_DNSRecordsWithdrawnOnDeletion pins the finalizer:
+// deleting the TenantGateway withdraws every published record before
+// the finalizer is released.
+func TestReconcile_DNSRecordsWithdrawnOnDeletion(t *testing.T) {
+	s := newScheme(t)
+	tgw := dnsRecordsTenantGateway()
+	tgw.Spec.DNSRecords.Targets = []string{"198.51.100.7"}
+	route := httpRouteAttached("harbor", "cozy-harbor", "harbor.foo.example.com")
+	c := fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(tgw, route).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}).
+		Build()
+	provider := newFakeDNSProvider()
+	r := &Reconciler{Client: c, Scheme: s, newDNSProvider: provider.factory()}
+
+	reconcileTGW(t, r)
+	if len(provider.records) == 0 {
+		t.Fatalf("expected records published before deletion")
+	}
+
+	if err := c.Delete(context.TODO(), getTGW(t, c)); err != nil {
+		t.Fatalf("delete tgw: %v", err)
+	}
+	reconcileTGW(t, r)
+
+	if len(provider.records) != 0 {
+		t.Errorf("expected every record withdrawn on deletion, got %v", provider.records)
+	}
+	got := &gatewayv1alpha1.TenantGateway{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: "cozystack", Namespace: "tenant-foo"}, got)
+	if err == nil {
+		t.Errorf("expected TenantGateway gone once the finalizer is released, still present with finalizers %v", got.Finalizers)
+	}
+}
+
+// TestDNSRecordTargets pins the target split: spec targets override
+// Gateway addresses, hostname addresses are skipped, IPv4 and IPv6
+// land in A and AAAA respectively.
+func TestDNSRecordTargets(t *testing.T) {
+	ipType := gatewayv1.IPAddressType
+	hostType := gatewayv1.HostnameAddressType
+	gw := &gatewayv1.Gateway{
+		Status: gatewayv1.GatewayStatus{
+			Addresses: []gatewayv1.GatewayStatusAddress{
+				{Type: &ipType, Value: "203.0.113.10"},
+				{Type: &hostType, Value: "lb.example.net"},
+				{Value: "2001:db8::1"},
+			},
+		},
+	}
+	tgw := dnsRecordsTenantGateway()
+	got := dnsRecordTargets(tgw, gw)
+	want := map[string][]string{"A": {"203.0.113.10"}, "AAAA": {"2001:db8::1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("targets from Gateway=%v, want %v", got, want)
+	}
+
+	tgw.Spec.DNSRecords.Targets = []string{"198.51.100.7", "not-an-ip"}
+	got = dnsRecordTargets(tgw, gw)
+	want = map[string][]string{"A": {"198.51.100.7"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("targets from spec=%v, want %v", got, want)
+	}
+}
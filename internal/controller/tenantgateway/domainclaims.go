@@ -0,0 +1,196 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantgateway
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+)
+
+// namespaceHostLabel carries the apex a tenant namespace publishes
+// under; the apps/tenant chart writes it on every tenant namespace.
+const namespaceHostLabel = "namespace.cozystack.io/host"
+
+// filterUnverifiedHostnames removes route claims on hostnames the
+// route's namespace has not proven it controls, before
+// resolveHostnameOwners sees them. Without it the first tenant to
+// attach a customer domain would own it, verified or not.
+//
+// A hostname under the TenantGateway apex or under the route
+// namespace's own host label is platform-assigned and passes
+// unchanged; the namespace-priority rule in resolveHostnameOwners
+// still decides between tenants there. Anything else needs a
+// Verified DomainClaim covering it in the route's namespace. When
+// verified claims of several namespaces cover a hostname, the most
+// specific domain wins, then the older verification. cozy-* routes
+// are platform-rendered and exempt.
+//
+// Returns, per rejected route, a sorted list of "<hostname>: <why>"
+// entries for the route status. claims is edited in place.
+func (r *Reconciler) filterUnverifiedHostnames(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway, claims map[string][]routeRef) (map[routeRef][]string, error) {
+	if len(claims) == 0 {
+		return nil, nil
+	}
+	list := &gatewayv1alpha1.DomainClaimList{}
+	if err := r.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("list DomainClaims: %w", err)
+	}
+	nsHosts := map[string]string{}
+	nsHost := func(ns string) (string, error) {
+		if host, ok := nsHosts[ns]; ok {
+			return host, nil
+		}
+		obj := &corev1.Namespace{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ns}, obj); client.IgnoreNotFound(err) != nil {
+			return "", fmt.Errorf("get namespace %s: %w", ns, err)
+		}
+		nsHosts[ns] = obj.Labels[namespaceHostLabel]
+		return nsHosts[ns], nil
+	}
+
+	unverified := map[routeRef][]string{}
+	for hostname, refs := range claims {
+		owner := verifiedDomainOwner(list.Items, hostname)
+		kept := refs[:0]
+		for _, ref := range refs {
+			if strings.HasPrefix(ref.namespace, "cozy-") {
+				kept = append(kept, ref)
+				continue
+			}
+			host, err := nsHost(ref.namespace)
+			if err != nil {
+				return nil, err
+			}
+			if hostnameWithin(hostname, tgw.Spec.Apex) || (host != "" && hostnameWithin(hostname, host)) {
+				kept = append(kept, ref)
+				continue
+			}
+			if owner != nil && owner.Namespace == ref.namespace {
+				kept = append(kept, ref)
+				continue
+			}
+			unverified[ref] = append(unverified[ref], hostname+": "+domainClaimHint(list.Items, owner, ref.namespace, hostname))
+		}
+		if len(kept) == 0 {
+			delete(claims, hostname)
+			continue
+		}
+		claims[hostname] = kept
+	}
+	for ref, reasons := range unverified {
+		sort.Strings(reasons)
+		unverified[ref] = slices.Compact(reasons)
+	}
+	return unverified, nil
+}
+
+// verifiedDomainOwner returns the Verified claim that governs
+// hostname, or nil when none covers it.
+func verifiedDomainOwner(claims []gatewayv1alpha1.DomainClaim, hostname string) *gatewayv1alpha1.DomainClaim {
+	var owner *gatewayv1alpha1.DomainClaim
+	for i := range claims {
+		dc := &claims[i]
+		if dc.Status.Phase != gatewayv1alpha1.DomainClaimPhaseVerified || !hostnameWithin(hostname, dc.Status.Domain) {
+			continue
+		}
+		if owner == nil || domainClaimOutranks(dc, owner) {
+			owner = dc
+		}
+	}
+	return owner
+}
+
+func domainClaimOutranks(a, b *gatewayv1alpha1.DomainClaim) bool {
+	if len(a.Status.Domain) != len(b.Status.Domain) {
+		return len(a.Status.Domain) > len(b.Status.Domain)
+	}
+	at, bt := a.Status.VerifiedAt, b.Status.VerifiedAt
+	if at != nil && bt != nil && !at.Equal(bt) {
+		return at.Before(bt)
+	}
+	if (at == nil) != (bt == nil) {
+		return at != nil
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+// domainClaimHint explains to the route owner why hostname was
+// refused: held by another namespace, or the state of their own claim.
+func domainClaimHint(claims []gatewayv1alpha1.DomainClaim, owner *gatewayv1alpha1.DomainClaim, ns, hostname string) string {
+	if owner != nil {
+		return fmt.Sprintf("verified by DomainClaim %s/%s", owner.Namespace, owner.Name)
+	}
+	for i := range claims {
+		dc := &claims[i]
+		if dc.Namespace != ns || !hostnameWithin(hostname, dc.Spec.Domain) {
+			continue
+		}
+		phase := dc.Status.Phase
+		if phase == "" {
+			phase = gatewayv1alpha1.DomainClaimPhasePending
+		}
+		if cond := apimeta.FindStatusCondition(dc.Status.Conditions, gatewayv1alpha1.DomainClaimVerifiedCondition); cond != nil && cond.Message != "" {
+			return fmt.Sprintf("DomainClaim %s is %s: %s", dc.Name, phase, cond.Message)
+		}
+		return fmt.Sprintf("DomainClaim %s is %s", dc.Name, phase)
+	}
+	return fmt.Sprintf("no DomainClaim in %s covers it", ns)
+}
+
+// hostnameWithin reports whether hostname is domain or a name below
+// it. A wildcard hostname counts by the part after "*.".
+func hostnameWithin(hostname, domain string) bool {
+	if domain == "" {
+		return false
+	}
+	hostname = strings.TrimPrefix(strings.ToLower(hostname), "*.")
+	domain = strings.ToLower(domain)
+	return hostname == domain || strings.HasSuffix(hostname, "."+domain)
+}
+
+// mapDomainClaimToTenantGateways requeues every TenantGateway on a
+// DomainClaim change. A claim can free or take hostnames on any
+// Gateway its namespace attaches to, and there are few of both.
+func (r *Reconciler) mapDomainClaimToTenantGateways(ctx context.Context, _ client.Object) []reconcile.Request {
+	list := &gatewayv1alpha1.TenantGatewayList{}
+	if err := r.List(ctx, list); err != nil {
+		log.FromContext(ctx).Error(err, "list TenantGateways for DomainClaim mapper")
+		return nil
+	}
+	out := make([]reconcile.Request, 0, len(list.Items))
+	for i := range list.Items {
+		out = append(out, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: list.Items[i].Namespace, Name: list.Items[i].Name},
+		})
+	}
+	return out
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantgateway
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+)
+
+func verifiedDomainClaim(ns, name, domain string, at time.Time) *gatewayv1alpha1.DomainClaim {
+	return &gatewayv1alpha1.DomainClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+		Spec:       gatewayv1alpha1.DomainClaimSpec{Domain: domain},
+		Status: gatewayv1alpha1.DomainClaimStatus{
+			Domain:     domain,
+			Phase:      gatewayv1alpha1.DomainClaimPhaseVerified,
+			VerifiedAt: &metav1.Time{Time: at},
+		},
+	}
+}
+
+func tenantNamespace(name, gatewayNs, host string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   name,
+		Labels: map[string]string{namespaceGatewayLabel: gatewayNs, namespaceHostLabel: host},
+	}}
+}
+
+func gatewayHasListener(t *testing.T, c client.Client, hostname string) bool {
+	t.Helper()
+	gw := &gatewayv1.Gateway{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "cozystack", Namespace: "tenant-foo"}, gw); err != nil {
+		t.Fatalf("get Gateway: %v", err)
+	}
+	for _, l := range gw.Spec.Listeners {
+		if l.Hostname != nil && string(*l.Hostname) == hostname {
+			return true
+		}
+	}
+	return false
+}
+
+func routeAccepted(t *testing.T, c client.Client, ns, name string) *metav1.Condition {
+	t.Helper()
+	route := &gatewayv1.HTTPRoute{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: ns, Name: name}, route); err != nil {
+		t.Fatalf("get HTTPRoute: %v", err)
+	}
+	for _, ps := range route.Status.Parents {
+		if string(ps.ControllerName) != testControllerName {
+			continue
+		}
+		for i := range ps.Conditions {
+			if ps.Conditions[i].Type == "Accepted" {
+				return &ps.Conditions[i]
+			}
+		}
+	}
+	return nil
+}
+
+// TestReconcile_CustomDomainNeedsVerifiedClaim pins the gate: a tenant
+// route on a hostname outside the platform apexes gets no listener and
+// reports UnverifiedHostname with its claim's challenge until the claim
+// is Verified, while a platform hostname in the same namespace is
+// unaffected.
+func TestReconcile_CustomDomainNeedsVerifiedClaim(t *testing.T) {
+	s := newScheme(t)
+	tgw := policyTenantGateway("cilium")
+	pending := &gatewayv1alpha1.DomainClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-bar", Name: "shop"},
+		Spec:       gatewayv1alpha1.DomainClaimSpec{Domain: "shop.example.org"},
+		Status: gatewayv1alpha1.DomainClaimStatus{
+			Domain: "shop.example.org",
+			Phase:  gatewayv1alpha1.DomainClaimPhasePending,
+			Conditions: []metav1.Condition{{
+				Type:    gatewayv1alpha1.DomainClaimVerifiedCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  "RecordNotFound",
+				Message: `publish TXT _cozystack-challenge.shop.example.org with value "cozystack-domain-verification=abc"`,
+			}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(tgw, tenantNamespace("tenant-bar", "tenant-foo", "bar.foo.example.com"), pending,
+			httpRouteAttached("shop", "tenant-bar", "www.shop.example.org"),
+			httpRouteAttached("app", "tenant-bar", "app.bar.foo.example.com")).
+		WithStatusSubresource(tgw, pending, &gatewayv1.HTTPRoute{}).
+		Build()
+	r := &Reconciler{Client: c, Scheme: s}
+
+	reconcileTGW(t, r)
+	if gatewayHasListener(t, c, "www.shop.example.org") {
+		t.Error("unverified hostname must not get a listener")
+	}
+	cond := routeAccepted(t, c, "tenant-bar", "shop")
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "UnverifiedHostname" ||
+		!strings.Contains(cond.Message, "DomainClaim shop is Pending") || !strings.Contains(cond.Message, "cozystack-domain-verification=abc") {
+		t.Errorf("Accepted=%+v, want UnverifiedHostname carrying the pending challenge", cond)
+	}
+	if !gatewayHasListener(t, c, "app.bar.foo.example.com") {
+		t.Error("hostname under the namespace host label must stay attached")
+	}
+	if cond := routeAccepted(t, c, "tenant-bar", "app"); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Errorf("platform route Accepted=%+v, want True", cond)
+	}
+
+	pending.Status.Phase = gatewayv1alpha1.DomainClaimPhaseVerified
+	pending.Status.VerifiedAt = &metav1.Time{Time: time.Now()}
+	if err := c.Status().Update(context.TODO(), pending); err != nil {
+		t.Fatalf("verify claim: %v", err)
+	}
+	reconcileTGW(t, r)
+	if !gatewayHasListener(t, c, "www.shop.example.org") {
+		t.Error("verified hostname must get a listener")
+	}
+	if cond := routeAccepted(t, c, "tenant-bar", "shop"); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Errorf("Accepted=%+v, want True once verified", cond)
+	}
+}
+
+// TestReconcile_VerifiedClaimBeatsFirstAttacher pins that the namespace
+// holding the verified claim owns the hostname even when another
+// tenant's route sorts first, and that the more specific of two
+// verified claims wins.
+func TestReconcile_VerifiedClaimBeatsFirstAttacher(t *testing.T) {
+	s := newScheme(t)
+	tgw := policyTenantGateway("cilium")
+	older := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(tgw,
+			tenantNamespace("tenant-aaa", "tenant-foo", "aaa.foo.example.com"),
+			tenantNamespace("tenant-bar", "tenant-foo", "bar.foo.example.com"),
+			verifiedDomainClaim("tenant-aaa", "apex", "example.org", older),
+			verifiedDomainClaim("tenant-bar", "shop", "shop.example.org", older.Add(time.Hour)),
+			httpRouteAttached("hijack", "tenant-aaa", "shop.example.org"),
+			httpRouteAttached("shop", "tenant-bar", "shop.example.org")).
+		WithStatusSubresource(tgw, &gatewayv1.HTTPRoute{}).
+		Build()
+	r := &Reconciler{Client: c, Scheme: s}
+
+	reconcileTGW(t, r)
+	if !gatewayHasListener(t, c, "shop.example.org") {
+		t.Fatal("expected a listener for the verified owner")
+	}
+	if cond := routeAccepted(t, c, "tenant-bar", "shop"); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Errorf("owner Accepted=%+v, want True", cond)
+	}
+	cond := routeAccepted(t, c, "tenant-aaa", "hijack")
+	if cond == nil || cond.Reason != "UnverifiedHostname" || !strings.Contains(cond.Message, "verified by DomainClaim tenant-bar/shop") {
+		t.Errorf("other tenant Accepted=%+v, want UnverifiedHostname naming tenant-bar/shop", cond)
+	}
+}
+
+func TestDomainClaimOutranks(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		a, b *gatewayv1alpha1.DomainClaim
+		want bool
+	}{
+		{"more specific", verifiedDomainClaim("b", "x", "a.example.org", t0.Add(time.Hour)), verifiedDomainClaim("a", "x", "example.org", t0), true},
+		{"older verification", verifiedDomainClaim("b", "x", "example.org", t0), verifiedDomainClaim("a", "x", "example.org", t0.Add(time.Hour)), true},
+		{"namespace tiebreak", verifiedDomainClaim("b", "x", "example.org", t0), verifiedDomainClaim("a", "x", "example.org", t0), false},
+	}
+	for _, tc := range cases {
+		if got := domainClaimOutranks(tc.a, tc.b); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
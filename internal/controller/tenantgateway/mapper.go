@@ -19,6 +19,8 @@ package tenantgateway
 import (
 	"context"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -82,3 +84,46 @@ func (r *Reconciler) mapRouteToTenantGateways(ctx context.Context, obj client.Ob
 	}
 	return out
 }
+
+// secretMeta returns a PartialObjectMetadata typed as a Secret for the
+// metadata-only client CA watch.
+func secretMeta() *metav1.PartialObjectMetadata {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Secret"})
+	return obj
+}
+
+// secretReader is the reader Secret data goes through; see
+// Reconciler.Reader.
+func (r *Reconciler) secretReader() client.Reader {
+	if r.Reader != nil {
+		return r.Reader
+	}
+	return r.Client
+}
+
+// mapSecretToTenantGateways requeues every TenantGateway whose
+// spec.clientValidation reads its CA bundle from the changed Secret,
+// so a rotated or newly created bundle is enforced without waiting
+// for the next resync.
+func (r *Reconciler) mapSecretToTenantGateways(ctx context.Context, obj *metav1.PartialObjectMetadata) []reconcile.Request {
+	list := &gatewayv1alpha1.TenantGatewayList{}
+	if err := r.List(ctx, list); err != nil {
+		log.FromContext(ctx).Error(err, "list TenantGateways for Secret mapper")
+		return nil
+	}
+	var out []reconcile.Request
+	for i := range list.Items {
+		tgw := &list.Items[i]
+		if !clientValidationReferencesSecret(tgw, obj.GetNamespace(), obj.GetName()) {
+			continue
+		}
+		out = append(out, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: tgw.Namespace,
+				Name:      tgw.Name,
+			},
+		})
+	}
+	return out
+}
@@ -0,0 +1,622 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantgateway
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+)
+
+// routePoliciesFinalizer holds a TenantGateway until the policy
+// resources it rendered into route namespaces are gone. Those live
+// outside the TenantGateway namespace, so OwnerReferences cannot
+// cascade them.
+const routePoliciesFinalizer = "gateway.cozystack.io/route-policies"
+
+// envoyGatewayControllerName is the GatewayClass controllerName of
+// Envoy Gateway, the only implementation TrafficPolicy translates to
+// today. Cilium has no per-route policy API for these features; the
+// optional cozystack.envoy-gateway package installs a class for it.
+const envoyGatewayControllerName = "gateway.envoyproxy.io/gatewayclass-controller"
+
+// defaultGatewayClassName mirrors the CRD default of
+// Spec.GatewayClassName for objects created before defaulting.
+const defaultGatewayClassName = "cilium"
+
+// policyAttachedCondition is the RouteParentStatus condition type
+// reporting which TrafficPolicies apply to a route.
+const policyAttachedCondition = "PolicyAttached"
+
+// policiesEnforcedCondition is the TenantGateway condition type
+// reporting whether Spec.Policies are in force. Unlike PolicyAttached
+// it is set even when no route matches, so a class that cannot
+// enforce the policies is visible on the TenantGateway itself.
+const policiesEnforcedCondition = "PoliciesEnforced"
+
+// Labels stamped on rendered policy resources so the GC pass can find
+// every object of a TenantGateway across route namespaces.
+const (
+	policyTenantGatewayNamespaceLabel = "gateway.cozystack.io/tenantgateway-namespace"
+	policyTenantGatewayNameLabel      = "gateway.cozystack.io/tenantgateway"
+	policyNameLabel                   = "gateway.cozystack.io/policy"
+)
+
+var (
+	envoySecurityPolicyGVK       = schema.GroupVersionKind{Group: "gateway.envoyproxy.io", Version: "v1alpha1", Kind: "SecurityPolicy"}
+	envoyBackendTrafficPolicyGVK = schema.GroupVersionKind{Group: "gateway.envoyproxy.io", Version: "v1alpha1", Kind: "BackendTrafficPolicy"}
+
+	envoyPolicyGVKs = []schema.GroupVersionKind{envoySecurityPolicyGVK, envoyBackendTrafficPolicyGVK}
+)
+
+// policyRoute is an HTTPRoute attached to this TenantGateway together
+// with the parentRefs it attached through; each ref owns its own
+// RouteParentStatus entry.
+type policyRoute struct {
+	namespace string
+	name      string
+	hostnames []string
+	refs      []gatewayv1.ParentReference
+}
+
+// policyObjectKey identifies a rendered policy resource for GC.
+type policyObjectKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+func routePoliciesActive(tgw *gatewayv1alpha1.TenantGateway) bool {
+	return len(tgw.Spec.Policies) > 0 || controllerutil.ContainsFinalizer(tgw, routePoliciesFinalizer)
+}
+
+// reconcileRoutePolicies renders Spec.Policies into the policy
+// resources of the Gateway implementation, removes the ones no longer
+// desired and reports the outcome as a PolicyAttached condition on
+// every attached HTTPRoute. Routes no policy targets lose the
+// condition. The returned PoliciesEnforced condition is nil when no
+// policy is declared.
+//
+// A TenantGateway that never declared a policy skips the pass
+// entirely, so clusters without Envoy Gateway CRDs never see a
+// request for them.
+func (r *Reconciler) reconcileRoutePolicies(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway) (*metav1.Condition, error) {
+	if !routePoliciesActive(tgw) {
+		return nil, nil
+	}
+	if len(tgw.Spec.Policies) > 0 && !controllerutil.ContainsFinalizer(tgw, routePoliciesFinalizer) {
+		before := tgw.DeepCopy()
+		controllerutil.AddFinalizer(tgw, routePoliciesFinalizer)
+		if err := r.Patch(ctx, tgw, client.MergeFrom(before)); err != nil {
+			return nil, fmt.Errorf("patch route policies finalizer: %w", err)
+		}
+	}
+
+	routes, err := r.collectPolicyRoutes(ctx, tgw)
+	if err != nil {
+		return nil, err
+	}
+	supported, unsupportedReason, err := r.policiesSupported(ctx, tgw)
+	if err != nil {
+		return nil, err
+	}
+	tenant, err := r.tenantNamespaces(ctx, tgw)
+	if err != nil {
+		return nil, err
+	}
+
+	// keep holds every object the GC pass must leave alone: the ones
+	// rendered this pass plus those of policies that failed
+	// validation. Dropping the latter would lift the protection of a
+	// route because of a typo in an unrelated field. Under an
+	// unsupported class nothing is kept, so objects rendered for a
+	// previous class do not linger.
+	keep := map[policyObjectKey]struct{}{}
+	invalid := map[string]string{}
+	applied := map[types.NamespacedName][]string{}
+	for i := range tgw.Spec.Policies {
+		p := &tgw.Spec.Policies[i]
+		if err := validateTrafficPolicy(p, tenant); err != nil {
+			invalid[p.Name] = err.Error()
+		}
+		byNamespace := map[string][]string{}
+		for _, route := range routes {
+			if !policyTargetsRoute(p, route) {
+				continue
+			}
+			key := types.NamespacedName{Namespace: route.namespace, Name: route.name}
+			applied[key] = append(applied[key], p.Name)
+			byNamespace[route.namespace] = append(byNamespace[route.namespace], route.name)
+		}
+		if !supported {
+			continue
+		}
+		for ns, names := range byNamespace {
+			if invalid[p.Name] != "" {
+				for _, gvk := range envoyPolicyGVKs {
+					keep[policyObjectKey{kind: gvk.Kind, namespace: ns, name: policyObjectName(tgw, p)}] = struct{}{}
+				}
+				continue
+			}
+			sort.Strings(names)
+			for _, obj := range renderEnvoyPolicies(tgw, p, ns, names) {
+				if err := r.applyPolicyObject(ctx, tgw, obj); err != nil {
+					return nil, err
+				}
+				keep[policyObjectKey{kind: obj.GetKind(), namespace: ns, name: obj.GetName()}] = struct{}{}
+			}
+		}
+	}
+	if err := r.gcPolicyObjects(ctx, tgw, envoyPolicyGVKs, keep); err != nil {
+		return nil, err
+	}
+
+	logger := log.FromContext(ctx)
+	for _, route := range routes {
+		names := applied[types.NamespacedName{Namespace: route.namespace, Name: route.name}]
+		cond := routePolicyCondition(tgw, names, supported, unsupportedReason, invalid)
+		if err := r.updateRoutePolicyStatus(ctx, route, cond); err != nil {
+			logger.Error(err, "update route policy status", "route", route.namespace+"/"+route.name)
+		}
+	}
+
+	if len(tgw.Spec.Policies) == 0 {
+		before := tgw.DeepCopy()
+		controllerutil.RemoveFinalizer(tgw, routePoliciesFinalizer)
+		if err := r.Patch(ctx, tgw, client.MergeFrom(before)); err != nil {
+			return nil, fmt.Errorf("patch route policies finalizer: %w", err)
+		}
+		return nil, nil
+	}
+	return policiesEnforcedConditionFor(tgw, supported, unsupportedReason, invalid), nil
+}
+
+// finalizeRoutePolicies deletes every policy resource a TenantGateway
+// being deleted rendered, then releases the finalizer.
+func (r *Reconciler) finalizeRoutePolicies(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway) error {
+	if !controllerutil.ContainsFinalizer(tgw, routePoliciesFinalizer) {
+		return nil
+	}
+	if err := r.gcPolicyObjects(ctx, tgw, envoyPolicyGVKs, nil); err != nil {
+		return err
+	}
+	before := tgw.DeepCopy()
+	controllerutil.RemoveFinalizer(tgw, routePoliciesFinalizer)
+	return r.Patch(ctx, tgw, client.MergeFrom(before))
+}
+
+// collectPolicyRoutes returns the HTTPRoutes attached to tgw's Gateway
+// from namespaces allowed to attach, sorted by namespace/name. Unlike
+// collectHostnameClaims it runs in every cert mode: policies apply to
+// wildcard-served routes too.
+func (r *Reconciler) collectPolicyRoutes(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway) ([]policyRoute, error) {
+	allowed, err := r.allowedRouteNamespaces(ctx, tgw)
+	if err != nil {
+		return nil, err
+	}
+	list := &gatewayv1.HTTPRouteList{}
+	if err := r.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("list HTTPRoutes: %w", err)
+	}
+	var out []policyRoute
+	for i := range list.Items {
+		route := &list.Items[i]
+		if _, ok := allowed[route.Namespace]; !ok {
+			continue
+		}
+		// The controller-owned redirect route only answers port 80;
+		// policies belong on the routes serving the apps.
+		if ownedByTenantGateway(route.OwnerReferences, tgw) {
+			continue
+		}
+		refs := allAttachingParentRefs(route.Spec.ParentRefs, route.Namespace, tgw)
+		if len(refs) == 0 {
+			continue
+		}
+		pr := policyRoute{namespace: route.Namespace, name: route.Name, refs: refs}
+		for _, h := range route.Spec.Hostnames {
+			pr.hostnames = append(pr.hostnames, string(h))
+		}
+		out = append(out, pr)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].namespace != out[j].namespace {
+			return out[i].namespace < out[j].namespace
+		}
+		return out[i].name < out[j].name
+	})
+	return out, nil
+}
+
+// policiesSupported reports whether the Gateway implementation behind
+// Spec.GatewayClassName has a translation for TrafficPolicy and
+// ClientValidation. The reason explains a false answer on status.
+func (r *Reconciler) policiesSupported(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway) (bool, string, error) {
+	className := tgw.Spec.GatewayClassName
+	if className == "" {
+		className = defaultGatewayClassName
+	}
+	gc := &gatewayv1.GatewayClass{}
+	if err := r.Get(ctx, types.NamespacedName{Name: className}, gc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, fmt.Sprintf("GatewayClass %q not found", className), nil
+		}
+		return false, "", fmt.Errorf("get GatewayClass %s: %w", className, err)
+	}
+	if gc.Spec.ControllerName != envoyGatewayControllerName {
+		return false, fmt.Sprintf("GatewayClass %q (controller %s) does not support Envoy Gateway policies; enable the cozystack.envoy-gateway package and use the envoy-gateway class", className, gc.Spec.ControllerName), nil
+	}
+	return true, "", nil
+}
+
+// validateTrafficPolicy catches what the CRD schema cannot: malformed
+// CIDRs, which the Gateway implementation would otherwise reject
+// after the fact, and a forward-auth Service outside tenantNS, which
+// would let a tenant route its traffic through platform Services.
+func validateTrafficPolicy(p *gatewayv1alpha1.TrafficPolicy, tenantNS map[string]struct{}) error {
+	if fa := p.ForwardAuth; fa != nil && fa.ServiceNamespace != "" {
+		if _, ok := tenantNS[fa.ServiceNamespace]; !ok {
+			return fmt.Errorf("policy %s: forwardAuth service namespace %q is not a namespace of this tenant", p.Name, fa.ServiceNamespace)
+		}
+	}
+	for _, list := range [][]string{p.IPAllowList, p.IPDenyList} {
+		for _, cidr := range list {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("policy %s: invalid CIDR %q", p.Name, cidr)
+			}
+		}
+	}
+	return nil
+}
+
+// policyTargetsRoute matches a route by explicit reference or by any
+// of its hostnames.
+func policyTargetsRoute(p *gatewayv1alpha1.TrafficPolicy, route policyRoute) bool {
+	for _, ref := range p.Routes {
+		if ref.Namespace == route.namespace && ref.Name == route.name {
+			return true
+		}
+	}
+	for _, want := range p.Hostnames {
+		for _, h := range route.hostnames {
+			if policyHostnameMatches(want, h) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// policyHostnameMatches compares case-insensitively. A leading
+// "*." in the policy hostname matches any hostname below it, as well
+// as the identical wildcard on the route.
+func policyHostnameMatches(want, host string) bool {
+	want, host = strings.ToLower(want), strings.ToLower(host)
+	if want == host {
+		return true
+	}
+	if suffix, ok := strings.CutPrefix(want, "*"); ok {
+		return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+	}
+	return false
+}
+
+// policyObjectName names the rendered resources of one policy in one
+// route namespace. The TenantGateway namespace is part of the name
+// because several tenants' Gateways can serve routes from the same
+// namespace.
+func policyObjectName(tgw *gatewayv1alpha1.TenantGateway, p *gatewayv1alpha1.TrafficPolicy) string {
+	return fmt.Sprintf("%s-%s-%s", tgw.Namespace, tgw.Name, p.Name)
+}
+
+func policyObjectLabels(tgw *gatewayv1alpha1.TenantGateway, policyName string) map[string]string {
+	return map[string]string{
+		cozystackManagedByLabel:           cozystackManagedByValue,
+		policyTenantGatewayNamespaceLabel: tgw.Namespace,
+		policyTenantGatewayNameLabel:      tgw.Name,
+		policyNameLabel:                   policyName,
+	}
+}
+
+// renderEnvoyPolicies translates one TrafficPolicy into Envoy Gateway
+// resources targeting routeNames in namespace: a SecurityPolicy for
+// IP lists and authentication, a BackendTrafficPolicy for rate and
+// body-size limits. Either is omitted when it would be empty.
+func renderEnvoyPolicies(tgw *gatewayv1alpha1.TenantGateway, p *gatewayv1alpha1.TrafficPolicy, namespace string, routeNames []string) []*unstructured.Unstructured {
+	targetRefs := make([]any, 0, len(routeNames))
+	for _, n := range routeNames {
+		targetRefs = append(targetRefs, map[string]any{
+			"group": gatewayv1.GroupName,
+			"kind":  "HTTPRoute",
+			"name":  n,
+		})
+	}
+
+	var out []*unstructured.Unstructured
+	security := map[string]any{}
+	if p.BasicAuth != nil {
+		security["basicAuth"] = map[string]any{
+			"users": map[string]any{"name": p.BasicAuth.SecretRef.Name},
+		}
+	}
+	if fa := p.ForwardAuth; fa != nil {
+		backend := map[string]any{"name": fa.ServiceName, "port": int64(fa.Port)}
+		if fa.ServiceNamespace != "" && fa.ServiceNamespace != namespace {
+			backend["namespace"] = fa.ServiceNamespace
+		}
+		httpAuth := map[string]any{"backendRefs": []any{backend}}
+		if fa.Path != "" {
+			httpAuth["path"] = fa.Path
+		}
+		if len(fa.HeadersToBackend) > 0 {
+			httpAuth["headersToBackend"] = stringsToAny(fa.HeadersToBackend)
+		}
+		security["extAuth"] = map[string]any{"http": httpAuth}
+	}
+	if len(p.IPAllowList) > 0 || len(p.IPDenyList) > 0 {
+		var rules []any
+		if len(p.IPDenyList) > 0 {
+			rules = append(rules, map[string]any{
+				"name":      "ip-deny-list",
+				"action":    "Deny",
+				"principal": map[string]any{"clientCIDRs": stringsToAny(p.IPDenyList)},
+			})
+		}
+		defaultAction := "Allow"
+		if len(p.IPAllowList) > 0 {
+			defaultAction = "Deny"
+			rules = append(rules, map[string]any{
+				"name":      "ip-allow-list",
+				"action":    "Allow",
+				"principal": map[string]any{"clientCIDRs": stringsToAny(p.IPAllowList)},
+			})
+		}
+		security["authorization"] = map[string]any{
+			"defaultAction": defaultAction,
+			"rules":         rules,
+		}
+	}
+	if len(security) > 0 {
+		security["targetRefs"] = targetRefs
+		out = append(out, newPolicyObject(tgw, p, envoySecurityPolicyGVK, namespace, security))
+	}
+
+	traffic := map[string]any{}
+	if rl := p.RateLimit; rl != nil {
+		unit := rl.Unit
+		if unit == "" {
+			unit = gatewayv1alpha1.RateLimitUnitMinute
+		}
+		traffic["rateLimit"] = map[string]any{
+			"type": "Local",
+			"local": map[string]any{
+				"rules": []any{
+					map[string]any{"limit": map[string]any{"requests": int64(rl.Requests), "unit": string(unit)}},
+				},
+			},
+		}
+	}
+	if p.MaxRequestBodySize != nil {
+		traffic["requestBuffer"] = map[string]any{"limit": p.MaxRequestBodySize.String()}
+	}
+	if len(traffic) > 0 {
+		traffic["targetRefs"] = targetRefs
+		out = append(out, newPolicyObject(tgw, p, envoyBackendTrafficPolicyGVK, namespace, traffic))
+	}
+	return out
+}
+
+func newPolicyObject(tgw *gatewayv1alpha1.TenantGateway, p *gatewayv1alpha1.TrafficPolicy, gvk schema.GroupVersionKind, namespace string, spec map[string]any) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(namespace)
+	obj.SetName(policyObjectName(tgw, p))
+	obj.SetLabels(policyObjectLabels(tgw, p.Name))
+	return obj
+}
+
+func stringsToAny(in []string) []any {
+	out := make([]any, len(in))
+	for i, s := range in {
+		out[i] = s
+	}
+	return out
+}
+
+// applyPolicyObject creates or updates a rendered policy resource.
+// An existing object without this TenantGateway's labels is not taken
+// over, mirroring the OwnerReference guard on namespace-local
+// children.
+func (r *Reconciler) applyPolicyObject(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway, desired *unstructured.Unstructured) error {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(desired.GroupVersionKind())
+	err := r.Get(ctx, client.ObjectKeyFromObject(desired), existing)
+	switch {
+	case apierrors.IsNotFound(err):
+		if err := r.Create(ctx, desired); err != nil {
+			return fmt.Errorf("create %s %s/%s: %w", desired.GetKind(), desired.GetNamespace(), desired.GetName(), err)
+		}
+		log.FromContext(ctx).V(1).Info("created route policy", "kind", desired.GetKind(), "name", desired.GetName(), "namespace", desired.GetNamespace())
+		return nil
+	case err != nil:
+		return fmt.Errorf("get %s %s/%s: %w", desired.GetKind(), desired.GetNamespace(), desired.GetName(), err)
+	}
+	if !policyObjectOwnedBy(existing, tgw) {
+		return fmt.Errorf("%s %s/%s exists but is not managed by TenantGateway %s/%s; refusing to take over", desired.GetKind(), desired.GetNamespace(), desired.GetName(), tgw.Namespace, tgw.Name)
+	}
+	if equality.Semantic.DeepEqual(existing.Object["spec"], desired.Object["spec"]) &&
+		labelsEqual(existing.GetLabels(), mergeLabels(existing.GetLabels(), desired.GetLabels())) {
+		return nil
+	}
+	existing.Object["spec"] = desired.Object["spec"]
+	existing.SetLabels(mergeLabels(existing.GetLabels(), desired.GetLabels()))
+	if err := r.Update(ctx, existing); err != nil {
+		return fmt.Errorf("update %s %s/%s: %w", desired.GetKind(), desired.GetNamespace(), desired.GetName(), err)
+	}
+	return nil
+}
+
+func policyObjectOwnedBy(obj *unstructured.Unstructured, tgw *gatewayv1alpha1.TenantGateway) bool {
+	l := obj.GetLabels()
+	return l[cozystackManagedByLabel] == cozystackManagedByValue &&
+		l[policyTenantGatewayNamespaceLabel] == tgw.Namespace &&
+		l[policyTenantGatewayNameLabel] == tgw.Name
+}
+
+// gcPolicyObjects deletes this TenantGateway's policy resources of
+// the given kinds whose namespace/name is not in keep. A cluster
+// without the policy CRDs has nothing to collect.
+func (r *Reconciler) gcPolicyObjects(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway, gvks []schema.GroupVersionKind, keep map[policyObjectKey]struct{}) error {
+	selector := client.MatchingLabels{
+		cozystackManagedByLabel:           cozystackManagedByValue,
+		policyTenantGatewayNamespaceLabel: tgw.Namespace,
+		policyTenantGatewayNameLabel:      tgw.Name,
+	}
+	for _, gvk := range gvks {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := r.List(ctx, list, selector); err != nil {
+			if apimeta.IsNoMatchError(err) {
+				continue
+			}
+			return fmt.Errorf("list %s: %w", gvk.Kind, err)
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			if _, ok := keep[policyObjectKey{kind: gvk.Kind, namespace: obj.GetNamespace(), name: obj.GetName()}]; ok {
+				continue
+			}
+			if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+				return fmt.Errorf("delete %s %s/%s: %w", gvk.Kind, obj.GetNamespace(), obj.GetName(), err)
+			}
+			log.FromContext(ctx).V(1).Info("deleted policy", "kind", gvk.Kind, "name", obj.GetName(), "namespace", obj.GetNamespace())
+		}
+	}
+	return nil
+}
+
+// routePolicyCondition builds the PolicyAttached condition for a route
+// targeted by the named policies. Nil means no policy targets the
+// route and any previous condition is removed.
+func routePolicyCondition(tgw *gatewayv1alpha1.TenantGateway, names []string, supported bool, unsupportedReason string, invalid map[string]string) *metav1.Condition {
+	if len(names) == 0 {
+		return nil
+	}
+	if !supported {
+		return &metav1.Condition{
+			Type:    policyAttachedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Unsupported",
+			Message: fmt.Sprintf("Policies %s not applied: %s", strings.Join(names, ", "), unsupportedReason),
+		}
+	}
+	var problems []string
+	for _, n := range names {
+		if msg, bad := invalid[n]; bad {
+			problems = append(problems, msg)
+		}
+	}
+	if len(problems) > 0 {
+		return &metav1.Condition{
+			Type:    policyAttachedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "InvalidPolicy",
+			Message: strings.Join(problems, "; "),
+		}
+	}
+	return &metav1.Condition{
+		Type:    policyAttachedCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Attached",
+		Message: fmt.Sprintf("Policies %s of TenantGateway %s/%s applied", strings.Join(names, ", "), tgw.Namespace, tgw.Name),
+	}
+}
+
+// policiesEnforcedConditionFor summarises the pass for the
+// TenantGateway: False while the class cannot enforce the policies or
+// one of them is invalid, True once every policy was rendered.
+func policiesEnforcedConditionFor(tgw *gatewayv1alpha1.TenantGateway, supported bool, unsupportedReason string, invalid map[string]string) *metav1.Condition {
+	cond := &metav1.Condition{
+		Type:               policiesEnforcedCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: tgw.Generation,
+		Reason:             "Enforced",
+		Message:            fmt.Sprintf("%d policies rendered for the Gateway implementation", len(tgw.Spec.Policies)),
+	}
+	if !supported {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "UnsupportedGatewayClass"
+		cond.Message = "Policies are not enforced: " + unsupportedReason
+		return cond
+	}
+	var problems []string
+	for i := range tgw.Spec.Policies {
+		if msg, bad := invalid[tgw.Spec.Policies[i].Name]; bad {
+			problems = append(problems, msg)
+		}
+	}
+	if len(problems) > 0 {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "InvalidPolicy"
+		cond.Message = strings.Join(problems, "; ")
+	}
+	return cond
+}
+
+// updateRoutePolicyStatus sets or clears the PolicyAttached condition
+// on every RouteParentStatus entry of ours for the route. A nil cond
+// never creates an entry, it only strips the condition from existing
+// ones.
+func (r *Reconciler) updateRoutePolicyStatus(ctx context.Context, route policyRoute, cond *metav1.Condition) error {
+	obj := &gatewayv1.HTTPRoute{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: route.namespace, Name: route.name}, obj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	before := obj.DeepCopy()
+	for _, ref := range route.refs {
+		if cond != nil {
+			mergeRouteParentStatus(&obj.Status.Parents, ref, []metav1.Condition{*cond})
+			continue
+		}
+		for i := range obj.Status.Parents {
+			ps := &obj.Status.Parents[i]
+			if ps.ControllerName == ControllerName && parentRefEqual(ps.ParentRef, ref) {
+				apimeta.RemoveStatusCondition(&ps.Conditions, policyAttachedCondition)
+			}
+		}
+	}
+	if routeParentStatusEqual(before.Status.Parents, obj.Status.Parents) {
+		return nil
+	}
+	return r.Status().Update(ctx, obj)
+}
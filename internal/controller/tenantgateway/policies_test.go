@@ -0,0 +1,386 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantgateway
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+)
+
+// newPolicyScheme extends newScheme with the Envoy Gateway policy
+// kinds as unstructured types, standing in for the installed CRDs.
+func newPolicyScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	s := newScheme(t)
+	for _, gvk := range envoyPolicyGVKs {
+		s.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+		s.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	}
+	return s
+}
+
+func gatewayClass(name, controller string) *gatewayv1.GatewayClass {
+	return &gatewayv1.GatewayClass{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       gatewayv1.GatewayClassSpec{ControllerName: gatewayv1.GatewayController(controller)},
+	}
+}
+
+func policyTenantGateway(className string, policies ...gatewayv1alpha1.TrafficPolicy) *gatewayv1alpha1.TenantGateway {
+	return &gatewayv1alpha1.TenantGateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack", Namespace: "tenant-foo"},
+		Spec: gatewayv1alpha1.TenantGatewaySpec{
+			Apex:               "foo.example.com",
+			CertMode:           gatewayv1alpha1.CertModeHTTP01,
+			GatewayClassName:   className,
+			AttachedNamespaces: []string{"cozy-harbor"},
+			Policies:           policies,
+		},
+	}
+}
+
+func getPolicyObject(t *testing.T, c client.Client, gvk, name string) (*unstructured.Unstructured, bool) {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	for _, g := range envoyPolicyGVKs {
+		if g.Kind == gvk {
+			obj.SetGroupVersionKind(g)
+		}
+	}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: "cozy-harbor", Name: name}, obj)
+	if client.IgnoreNotFound(err) != nil {
+		t.Fatalf("get %s %s: %v", gvk, name, err)
+	}
+	return obj, err == nil
+}
+
+func routePolicyConditionOf(t *testing.T, c client.Client, ns, name string) *metav1.Condition {
+	t.Helper()
+	route := &gatewayv1.HTTPRoute{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: ns, Name: name}, route); err != nil {
+		t.Fatalf("get HTTPRoute: %v", err)
+	}
+	for _, ps := range route.Status.Parents {
+		if ps.ControllerName == ControllerName {
+			return apimeta.FindStatusCondition(ps.Conditions, policyAttachedCondition)
+		}
+	}
+	return nil
+}
+
+// TestReconcile_PoliciesRenderEnvoyGatewayResources pins the Envoy
+// Gateway translation end to end: one SecurityPolicy and one
+// BackendTrafficPolicy in the route namespace, targeting the route by
+// hostname, plus PolicyAttached=True on the route. Dropping the policy
+// removes the objects, the condition and the finalizer.
+func TestReconcile_PoliciesRenderEnvoyGatewayResources(t *testing.T) {
+	s := newPolicyScheme(t)
+	limit := resource.MustParse("10Mi")
+	tgw := policyTenantGateway("envoy", gatewayv1alpha1.TrafficPolicy{
+		Name:               "harbor",
+		Hostnames:          []string{"*.foo.example.com"},
+		RateLimit:          &gatewayv1alpha1.RateLimitPolicy{Requests: 100},
+		IPAllowList:        []string{"10.0.0.0/8"},
+		IPDenyList:         []string{"10.1.0.0/16"},
+		BasicAuth:          &gatewayv1alpha1.BasicAuthPolicy{SecretRef: corev1.LocalObjectReference{Name: "harbor-htpasswd"}},
+		MaxRequestBodySize: &limit,
+	})
+	route := httpRouteAttached("harbor", "cozy-harbor", "harbor.foo.example.com")
+	c := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(tgw, route, gatewayClass("envoy", envoyGatewayControllerName)).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}, &gatewayv1.HTTPRoute{}).
+		Build()
+	r := &Reconciler{Client: c, Scheme: s}
+	reconcileTGW(t, r)
+
+	sp, ok := getPolicyObject(t, c, "SecurityPolicy", "tenant-foo-cozystack-harbor")
+	if !ok {
+		t.Fatal("expected SecurityPolicy to be rendered")
+	}
+	wantSecurity := map[string]any{
+		"targetRefs": []any{map[string]any{"group": gatewayv1.GroupName, "kind": "HTTPRoute", "name": "harbor"}},
+		"basicAuth":  map[string]any{"users": map[string]any{"name": "harbor-htpasswd"}},
+		"authorization": map[string]any{
+			"defaultAction": "Deny",
+			"rules": []any{
+				map[string]any{"name": "ip-deny-list", "action": "Deny", "principal": map[string]any{"clientCIDRs": []any{"10.1.0.0/16"}}},
+				map[string]any{"name": "ip-allow-list", "action": "Allow", "principal": map[string]any{"clientCIDRs": []any{"10.0.0.0/8"}}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(sp.Object["spec"], wantSecurity) {
+		t.Errorf("SecurityPolicy spec=%v\nwant %v", sp.Object["spec"], wantSecurity)
+	}
+	if sp.GetLabels()[policyNameLabel] != "harbor" {
+		t.Errorf("SecurityPolicy labels=%v, want policy label harbor", sp.GetLabels())
+	}
+
+	btp, ok := getPolicyObject(t, c, "BackendTrafficPolicy", "tenant-foo-cozystack-harbor")
+	if !ok {
+		t.Fatal("expected BackendTrafficPolicy to be rendered")
+	}
+	if got, _, _ := unstructured.NestedString(btp.Object, "spec", "requestBuffer", "limit"); got != "10Mi" {
+		t.Errorf("requestBuffer.limit=%q, want 10Mi", got)
+	}
+	rules, _, _ := unstructured.NestedSlice(btp.Object, "spec", "rateLimit", "local", "rules")
+	wantRules := []any{map[string]any{"limit": map[string]any{"requests": int64(100), "unit": "Minute"}}}
+	if !reflect.DeepEqual(rules, wantRules) {
+		t.Errorf("rateLimit rules=%v, want %v", rules, wantRules)
+	}
+
+	cond := routePolicyConditionOf(t, c, "cozy-harbor", "harbor")
+	if cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != "Attached" {
+		t.Fatalf("PolicyAttached=%+v, want True/Attached", cond)
+	}
+	if !controllerutil.ContainsFinalizer(getTGW(t, c), routePoliciesFinalizer) {
+		t.Fatal("expected route policies finalizer while policies are declared")
+	}
+	if cond := apimeta.FindStatusCondition(getTGW(t, c).Status.Conditions, policiesEnforcedCondition); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Fatalf("PoliciesEnforced=%+v, want True", cond)
+	}
+
+	// Drop the policy: everything it produced goes away.
+	updated := getTGW(t, c)
+	updated.Spec.Policies = nil
+	if err := c.Update(context.TODO(), updated); err != nil {
+		t.Fatalf("drop policies: %v", err)
+	}
+	reconcileTGW(t, r)
+	if _, ok := getPolicyObject(t, c, "SecurityPolicy", "tenant-foo-cozystack-harbor"); ok {
+		t.Error("SecurityPolicy must be removed with its policy")
+	}
+	if _, ok := getPolicyObject(t, c, "BackendTrafficPolicy", "tenant-foo-cozystack-harbor"); ok {
+		t.Error("BackendTrafficPolicy must be removed with its policy")
+	}
+	if cond := routePolicyConditionOf(t, c, "cozy-harbor", "harbor"); cond != nil {
+		t.Errorf("PolicyAttached must be cleared, got %+v", cond)
+	}
+	if controllerutil.ContainsFinalizer(getTGW(t, c), routePoliciesFinalizer) {
+		t.Error("finalizer must be released once no policy is left")
+	}
+	if cond := apimeta.FindStatusCondition(getTGW(t, c).Status.Conditions, policiesEnforcedCondition); cond != nil {
+		t.Errorf("PoliciesEnforced must be cleared, got %+v", cond)
+	}
+}
+
+// TestReconcile_PoliciesUnsupportedClass pins the Cilium path: no
+// policy objects, and both the targeted route and the TenantGateway
+// say why.
+func TestReconcile_PoliciesUnsupportedClass(t *testing.T) {
+	s := newPolicyScheme(t)
+	tgw := policyTenantGateway("cilium", gatewayv1alpha1.TrafficPolicy{
+		Name:      "limits",
+		Routes:    []gatewayv1alpha1.PolicyRouteRef{{Namespace: "cozy-harbor", Name: "harbor"}},
+		RateLimit: &gatewayv1alpha1.RateLimitPolicy{Requests: 5, Unit: gatewayv1alpha1.RateLimitUnitSecond},
+	})
+	route := httpRouteAttached("harbor", "cozy-harbor", "harbor.foo.example.com")
+	other := httpRouteAttached("registry", "cozy-harbor", "registry.foo.example.com")
+	c := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(tgw, route, other, gatewayClass("cilium", "io.cilium/gateway-controller")).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}, &gatewayv1.HTTPRoute{}).
+		Build()
+	r := &Reconciler{Client: c, Scheme: s}
+	reconcileTGW(t, r)
+
+	if _, ok := getPolicyObject(t, c, "BackendTrafficPolicy", "tenant-foo-cozystack-limits"); ok {
+		t.Error("no Envoy Gateway resources may be rendered for a Cilium class")
+	}
+	cond := routePolicyConditionOf(t, c, "cozy-harbor", "harbor")
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "Unsupported" {
+		t.Fatalf("PolicyAttached=%+v, want False/Unsupported", cond)
+	}
+	if cond := routePolicyConditionOf(t, c, "cozy-harbor", "registry"); cond != nil {
+		t.Errorf("untargeted route must not carry PolicyAttached, got %+v", cond)
+	}
+	enforced := apimeta.FindStatusCondition(getTGW(t, c).Status.Conditions, policiesEnforcedCondition)
+	if enforced == nil || enforced.Status != metav1.ConditionFalse || enforced.Reason != "UnsupportedGatewayClass" {
+		t.Fatalf("PoliciesEnforced=%+v, want False/UnsupportedGatewayClass", enforced)
+	}
+}
+
+// TestReconcile_InvalidPolicyKeepsRenderedObjects pins the fail-closed
+// rule: a malformed CIDR is reported on the route, but the objects
+// rendered from the last valid spec stay in place.
+func TestReconcile_InvalidPolicyKeepsRenderedObjects(t *testing.T) {
+	s := newPolicyScheme(t)
+	tgw := policyTenantGateway("envoy", gatewayv1alpha1.TrafficPolicy{
+		Name:        "office",
+		Hostnames:   []string{"harbor.foo.example.com"},
+		IPAllowList: []string{"192.0.2.0/24"},
+	})
+	route := httpRouteAttached("harbor", "cozy-harbor", "harbor.foo.example.com")
+	c := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(tgw, route, gatewayClass("envoy", envoyGatewayControllerName)).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}, &gatewayv1.HTTPRoute{}).
+		Build()
+	r := &Reconciler{Client: c, Scheme: s}
+	reconcileTGW(t, r)
+
+	updated := getTGW(t, c)
+	updated.Spec.Policies[0].IPAllowList = []string{"192.0.2.0/33"}
+	if err := c.Update(context.TODO(), updated); err != nil {
+		t.Fatalf("update policy: %v", err)
+	}
+	reconcileTGW(t, r)
+
+	sp, ok := getPolicyObject(t, c, "SecurityPolicy", "tenant-foo-cozystack-office")
+	if !ok {
+		t.Fatal("SecurityPolicy from the last valid spec must survive an invalid edit")
+	}
+	cidrs, _, _ := unstructured.NestedSlice(sp.Object, "spec", "authorization", "rules")
+	if len(cidrs) != 1 {
+		t.Errorf("authorization rules=%v, want the previous allow rule", cidrs)
+	}
+	cond := routePolicyConditionOf(t, c, "cozy-harbor", "harbor")
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "InvalidPolicy" {
+		t.Fatalf("PolicyAttached=%+v, want False/InvalidPolicy", cond)
+	}
+}
+
+// TestReconcile_ForwardAuthOutsideTenantRejected pins the tenant
+// boundary of forward-auth: a Service in a namespace the tenant does
+// not own, attached platform namespaces included, is refused and
+// nothing is rendered, while a namespace inheriting the Gateway is
+// accepted.
+func TestReconcile_ForwardAuthOutsideTenantRejected(t *testing.T) {
+	s := newPolicyScheme(t)
+	tgw := policyTenantGateway("envoy", gatewayv1alpha1.TrafficPolicy{
+		Name:      "sso",
+		Hostnames: []string{"harbor.foo.example.com"},
+		ForwardAuth: &gatewayv1alpha1.ForwardAuthPolicy{
+			ServiceName:      "oauth2-proxy",
+			ServiceNamespace: "cozy-system",
+			Port:             4180,
+		},
+	})
+	route := httpRouteAttached("harbor", "cozy-harbor", "harbor.foo.example.com")
+	child := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "tenant-foo-bar",
+		Labels: map[string]string{namespaceGatewayLabel: "tenant-foo"},
+	}}
+	c := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(tgw, route, child, gatewayClass("envoy", envoyGatewayControllerName)).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}, &gatewayv1.HTTPRoute{}).
+		Build()
+	r := &Reconciler{Client: c, Scheme: s}
+	reconcileTGW(t, r)
+
+	if _, ok := getPolicyObject(t, c, "SecurityPolicy", "tenant-foo-cozystack-sso"); ok {
+		t.Fatal("a forward-auth Service outside the tenant must not be rendered")
+	}
+	cond := routePolicyConditionOf(t, c, "cozy-harbor", "harbor")
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "InvalidPolicy" {
+		t.Fatalf("PolicyAttached=%+v, want False/InvalidPolicy", cond)
+	}
+
+	for _, ns := range []string{"cozy-harbor", "cozy-system"} {
+		updated := getTGW(t, c)
+		updated.Spec.Policies[0].ForwardAuth.ServiceNamespace = ns
+		if err := c.Update(context.TODO(), updated); err != nil {
+			t.Fatalf("update policy: %v", err)
+		}
+		reconcileTGW(t, r)
+		if _, ok := getPolicyObject(t, c, "SecurityPolicy", "tenant-foo-cozystack-sso"); ok {
+			t.Errorf("serviceNamespace %s: must not be rendered", ns)
+		}
+	}
+
+	updated := getTGW(t, c)
+	updated.Spec.Policies[0].ForwardAuth.ServiceNamespace = "tenant-foo-bar"
+	if err := c.Update(context.TODO(), updated); err != nil {
+		t.Fatalf("update policy: %v", err)
+	}
+	reconcileTGW(t, r)
+	sp, ok := getPolicyObject(t, c, "SecurityPolicy", "tenant-foo-cozystack-sso")
+	if !ok {
+		t.Fatal("a forward-auth Service in an inheriting namespace must be rendered")
+	}
+	refs, _, _ := unstructured.NestedSlice(sp.Object, "spec", "extAuth", "http", "backendRefs")
+	if len(refs) != 1 || refs[0].(map[string]any)["namespace"] != "tenant-foo-bar" {
+		t.Errorf("backendRefs=%v, want the tenant-foo-bar Service", refs)
+	}
+}
+
+// TestReconcile_DeletionRemovesRoutePolicies pins the finalizer path:
+// policy objects in route namespaces have no OwnerReference and must
+// be deleted explicitly before the TenantGateway goes away.
+func TestReconcile_DeletionRemovesRoutePolicies(t *testing.T) {
+	s := newPolicyScheme(t)
+	tgw := policyTenantGateway("envoy", gatewayv1alpha1.TrafficPolicy{
+		Name:      "limits",
+		Hostnames: []string{"harbor.foo.example.com"},
+		RateLimit: &gatewayv1alpha1.RateLimitPolicy{Requests: 10},
+	})
+	route := httpRouteAttached("harbor", "cozy-harbor", "harbor.foo.example.com")
+	c := fake.NewClientBuilder().WithScheme(s).
+		WithObjects(tgw, route, gatewayClass("envoy", envoyGatewayControllerName)).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}, &gatewayv1.HTTPRoute{}).
+		Build()
+	r := &Reconciler{Client: c, Scheme: s}
+	reconcileTGW(t, r)
+	if _, ok := getPolicyObject(t, c, "BackendTrafficPolicy", "tenant-foo-cozystack-limits"); !ok {
+		t.Fatal("expected BackendTrafficPolicy before deletion")
+	}
+
+	if err := c.Delete(context.TODO(), getTGW(t, c)); err != nil {
+		t.Fatalf("delete tgw: %v", err)
+	}
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: "cozystack", Namespace: "tenant-foo"},
+	}); err != nil {
+		t.Fatalf("reconcile deletion: %v", err)
+	}
+	if _, ok := getPolicyObject(t, c, "BackendTrafficPolicy", "tenant-foo-cozystack-limits"); ok {
+		t.Error("BackendTrafficPolicy must be deleted with the TenantGateway")
+	}
+}
+
+func TestPolicyHostnameMatches(t *testing.T) {
+	cases := []struct {
+		want, host string
+		match      bool
+	}{
+		{"harbor.foo.example.com", "harbor.foo.example.com", true},
+		{"Harbor.foo.example.com", "harbor.FOO.example.com", true},
+		{"*.foo.example.com", "harbor.foo.example.com", true},
+		{"*.foo.example.com", "*.foo.example.com", true},
+		{"*.foo.example.com", "foo.example.com", false},
+		{"*.foo.example.com", "harbor.barfoo.example.com", false},
+		{"harbor.foo.example.com", "registry.foo.example.com", false},
+	}
+	for _, tc := range cases {
+		if got := policyHostnameMatches(tc.want, tc.host); got != tc.match {
+			t.Errorf("policyHostnameMatches(%q, %q)=%v, want %v", tc.want, tc.host, got, tc.match)
+		}
+	}
+}
@@ -39,9 +39,12 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	crsource "sigs.k8s.io/controller-runtime/pkg/source"
 	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
 
@@ -91,12 +94,24 @@ const (
 // +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways;httproutes;tlsroutes,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways/status;httproutes/status;tlsroutes/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=cert-manager.io,resources=certificates;issuers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gatewayclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.envoyproxy.io,resources=securitypolicies;backendtrafficpolicies;clienttrafficpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.cozystack.io,resources=domainclaims,verbs=get;list;watch
 
 // Reconciler reconciles TenantGateway resources, owning the downstream
 // Gateway and Certificate state.
 type Reconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Reader reads Secrets past the manager's cache, whose Secret
+	// informer only holds the replicas WildcardSecret manages. Nil
+	// falls back to Client, which is what the fake-client tests use.
+	Reader client.Reader
+
+	// newDNSProvider overrides the DNS record provider factory. Nil in
+	// production (newDNSRecordProvider is used); set by tests.
+	newDNSProvider dnsProviderFactory
 }
 
 // Reconcile renders the desired Gateway from a TenantGateway spec.
@@ -110,6 +125,17 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if !tgw.DeletionTimestamp.IsZero() {
+		// Owned children cascade through their OwnerReferences. DNS
+		// records live outside the cluster and route policies live in
+		// other namespaces; both need an explicit cleanup before the
+		// TenantGateway goes away.
+		if err := r.finalizeRoutePolicies(ctx, tgw); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.finalizeDNSRecords(ctx, tgw)
+	}
+
 	if err := r.runReconcileSteps(ctx, tgw); err != nil {
 		// Surface the failure on the TenantGateway status so
 		// operators see something in `kubectl get tgw` rather than
@@ -121,6 +147,11 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, err
 	}
 
+	for _, l := range tgw.Status.Listeners {
+		if l.DNSRecord == gatewayv1alpha1.DNSRecordStateFailed {
+			return ctrl.Result{RequeueAfter: dnsRecordRetryInterval}, nil
+		}
+	}
 	return ctrl.Result{}, nil
 }
 
@@ -132,19 +163,13 @@ func (r *Reconciler) runReconcileSteps(ctx context.Context, tgw *gatewayv1alpha1
 	if err != nil {
 		return fmt.Errorf("collect attached hostnames: %w", err)
 	}
-	winners, losers := resolveHostnameOwners(claims)
-
-	dynHostnames := make([]string, 0, len(winners))
-	for h := range winners {
-		dynHostnames = append(dynHostnames, h)
-	}
-	sort.Strings(dynHostnames)
 
 	// allRefs is the full set of (route, parentRef) tuples that
 	// attached to this Gateway, including duplicate parentRefs from
 	// the same route. Each tuple owns its own RouteParentStatus
 	// entry per Gateway API's per-(parentRef, controllerName)
-	// status contract.
+	// status contract. Built before the DomainClaim filter so
+	// refused routes still get a status entry saying why.
 	allRefs := map[routeRef]struct{}{}
 	for _, refs := range claims {
 		for _, ref := range refs {
@@ -152,6 +177,18 @@ func (r *Reconciler) runReconcileSteps(ctx context.Context, tgw *gatewayv1alpha1
 		}
 	}
 
+	unverified, err := r.filterUnverifiedHostnames(ctx, tgw, claims)
+	if err != nil {
+		return err
+	}
+	winners, losers := resolveHostnameOwners(claims)
+
+	dynHostnames := make([]string, 0, len(winners))
+	for h := range winners {
+		dynHostnames = append(dynHostnames, h)
+	}
+	sort.Strings(dynHostnames)
+
 	// Label every expected namespace BEFORE rendering the Gateway —
 	// the Gateway's allowedRoutes selector is label-based, so any
 	// route reconcile that races with this run must already see the
@@ -161,8 +198,12 @@ func (r *Reconciler) runReconcileSteps(ctx context.Context, tgw *gatewayv1alpha1
 	if err := r.ensureNamespaceLabels(ctx, tgw); err != nil {
 		return err
 	}
+	if err := r.ensureDNSRecordsFinalizer(ctx, tgw); err != nil {
+		return err
+	}
 
-	if err := r.reconcileGateway(ctx, tgw, dynHostnames); err != nil {
+	clientValidation, err := r.reconcileGateway(ctx, tgw, dynHostnames)
+	if err != nil {
 		return err
 	}
 	if err := r.reconcileIssuer(ctx, tgw); err != nil {
@@ -174,13 +215,21 @@ func (r *Reconciler) runReconcileSteps(ctx context.Context, tgw *gatewayv1alpha1
 	if err := r.reconcilePerListenerCertificates(ctx, tgw, dynHostnames); err != nil {
 		return err
 	}
-	if err := r.updateRouteStatuses(ctx, tgw, allRefs, losers); err != nil {
+	if err := r.updateRouteStatuses(ctx, tgw, allRefs, losers, unverified); err != nil {
+		return err
+	}
+	policies, err := r.reconcileRoutePolicies(ctx, tgw)
+	if err != nil {
 		return err
 	}
 	if err := r.reconcileHTTPToHTTPSRedirect(ctx, tgw); err != nil {
 		return err
 	}
-	return r.reconcileStatus(ctx, tgw, dynHostnames)
+	records, err := r.reconcileDNSRecords(ctx, tgw)
+	if err != nil {
+		return err
+	}
+	return r.reconcileStatus(ctx, tgw, dynHostnames, records, policies, clientValidation)
 }
 
 // markFailed writes a Ready=False condition with Reason=ReconcileError
@@ -286,24 +335,9 @@ func (r *Reconciler) collectHostnameClaims(ctx context.Context, tgw *gatewayv1al
 		return nil, nil
 	}
 
-	allowed := map[string]struct{}{tgw.Namespace: {}}
-	for _, ns := range tgw.Spec.AttachedNamespaces {
-		if ns == "" {
-			continue
-		}
-		allowed[ns] = struct{}{}
-	}
-	// Inheritance: every namespace pointing at this Gateway via the
-	// label is also allowed. The same label drives the Gateway's
-	// allowedRoutes selector, so the two paths agree on which
-	// namespaces can attach.
-	nsList := &corev1.NamespaceList{}
-	selector := labels.SelectorFromSet(labels.Set{namespaceGatewayLabel: tgw.Namespace})
-	if err := r.List(ctx, nsList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
-		return nil, fmt.Errorf("list namespaces by gateway label: %w", err)
-	}
-	for i := range nsList.Items {
-		allowed[nsList.Items[i].Name] = struct{}{}
+	allowed, err := r.allowedRouteNamespaces(ctx, tgw)
+	if err != nil {
+		return nil, err
 	}
 
 	out := map[string][]routeRef{}
@@ -367,6 +401,43 @@ func (r *Reconciler) collectHostnameClaims(ctx context.Context, tgw *gatewayv1al
 	return out, nil
 }
 
+// allowedRouteNamespaces returns the namespaces whose routes may
+// attach to tgw's Gateway; see collectHostnameClaims for the rules.
+func (r *Reconciler) allowedRouteNamespaces(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway) (map[string]struct{}, error) {
+	allowed, err := r.tenantNamespaces(ctx, tgw)
+	if err != nil {
+		return nil, err
+	}
+	for _, ns := range tgw.Spec.AttachedNamespaces {
+		if ns == "" {
+			continue
+		}
+		allowed[ns] = struct{}{}
+	}
+	return allowed, nil
+}
+
+// tenantNamespaces returns the namespaces the tenant owning tgw
+// controls: its own plus every namespace inheriting its Gateway.
+// Spec.AttachedNamespaces are left out; those are platform namespaces
+// the tenant may serve but not reach into.
+func (r *Reconciler) tenantNamespaces(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway) (map[string]struct{}, error) {
+	out := map[string]struct{}{tgw.Namespace: {}}
+	// Inheritance: every namespace pointing at this Gateway via the
+	// label is also allowed. The same label drives the Gateway's
+	// allowedRoutes selector, so the two paths agree on which
+	// namespaces can attach.
+	nsList := &corev1.NamespaceList{}
+	selector := labels.SelectorFromSet(labels.Set{namespaceGatewayLabel: tgw.Namespace})
+	if err := r.List(ctx, nsList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("list namespaces by gateway label: %w", err)
+	}
+	for i := range nsList.Items {
+		out[nsList.Items[i].Name] = struct{}{}
+	}
+	return out, nil
+}
+
 // pickAttachingParentRef returns the first ParentRef in refs that
 // attaches to tgw's Gateway, plus a boolean ok. Used by the mapper
 // for cheap "does this route attach at all?" queries; for hostname
@@ -513,15 +584,22 @@ func ownedByTenantGateway(refs []metav1.OwnerReference, tgw *gatewayv1alpha1.Ten
 	return false
 }
 
-func (r *Reconciler) reconcileGateway(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway, dynHostnames []string) error {
+// reconcileGateway renders and applies the Gateway. Client
+// validation shapes the rendered spec, so its outcome is returned for
+// reconcileStatus.
+func (r *Reconciler) reconcileGateway(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway, dynHostnames []string) (map[string]clientValidationResult, error) {
 	logger := log.FromContext(ctx)
 	childApexes, err := r.collectInheritingChildApexes(ctx, tgw)
 	if err != nil {
-		return fmt.Errorf("collect inheriting child apexes: %w", err)
+		return nil, fmt.Errorf("collect inheriting child apexes: %w", err)
 	}
 	desired, err := r.renderGateway(tgw, dynHostnames, childApexes)
 	if err != nil {
-		return fmt.Errorf("render Gateway: %w", err)
+		return nil, fmt.Errorf("render Gateway: %w", err)
+	}
+	clientValidation, err := r.reconcileClientValidation(ctx, tgw, desired)
+	if err != nil {
+		return nil, err
 	}
 
 	existing := &gatewayv1.Gateway{}
@@ -529,11 +607,11 @@ func (r *Reconciler) reconcileGateway(ctx context.Context, tgw *gatewayv1alpha1.
 	switch {
 	case apierrors.IsNotFound(getErr):
 		if err := r.Create(ctx, desired); err != nil {
-			return fmt.Errorf("create Gateway: %w", err)
+			return nil, fmt.Errorf("create Gateway: %w", err)
 		}
 		logger.V(1).Info("created Gateway", "namespace", tgw.Namespace, "name", tgw.Name)
 	case getErr != nil:
-		return fmt.Errorf("get Gateway: %w", getErr)
+		return nil, fmt.Errorf("get Gateway: %w", getErr)
 	default:
 		// Refuse to silently take over a Gateway that shares our
 		// derived name but is not owned by this TenantGateway. An
@@ -544,7 +622,7 @@ func (r *Reconciler) reconcileGateway(ctx context.Context, tgw *gatewayv1alpha1.
 		// orphan that doesn't cascade-delete with the
 		// TenantGateway.
 		if !ownedByTenantGateway(existing.OwnerReferences, tgw) {
-			return fmt.Errorf("gateway %s/%s exists but is not owned by TenantGateway %s; refusing to take over (delete it manually if you want the controller to manage this Gateway)", tgw.Namespace, tgw.Name, tgw.Name)
+			return nil, fmt.Errorf("gateway %s/%s exists but is not owned by TenantGateway %s; refusing to take over (delete it manually if you want the controller to manage this Gateway)", tgw.Namespace, tgw.Name, tgw.Name)
 		}
 		// Merge labels: keep keys other actors (Cilium operator,
 		// kubectl label, future controllers) wrote, only add /
@@ -558,16 +636,16 @@ func (r *Reconciler) reconcileGateway(ctx context.Context, tgw *gatewayv1alpha1.
 		// and the controller hot-loops indefinitely.
 		if equality.Semantic.DeepEqual(existing.Spec, desired.Spec) &&
 			labelsEqual(existing.Labels, mergedLabels) {
-			return nil
+			return clientValidation, nil
 		}
 		existing.Spec = desired.Spec
 		existing.Labels = mergedLabels
 		if err := r.Update(ctx, existing); err != nil {
-			return fmt.Errorf("update Gateway: %w", err)
+			return nil, fmt.Errorf("update Gateway: %w", err)
 		}
 		logger.V(1).Info("updated Gateway", "namespace", tgw.Namespace, "name", tgw.Name)
 	}
-	return nil
+	return clientValidation, nil
 }
 
 // mergeLabels overlays controller-owned labels onto the existing set,
@@ -598,12 +676,19 @@ func labelsEqual(a, b map[string]string) bool {
 func (r *Reconciler) reconcileIssuer(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway) error {
 	logger := log.FromContext(ctx)
 
-	if tgw.Spec.CertMode == gatewayv1alpha1.CertModeExistingSecret {
-		// existingSecret mode references an operator-supplied Secret and
-		// mints no ACME Issuer. Delete any owned Issuer left from a
-		// previous http01/dns01 phase so the mode switch doesn't leak
-		// ACME machinery. Same ownership-guarded cleanup contract as
+	if !rendersOwnIssuer(tgw) {
+		// existingSecret mode references an operator-supplied Secret
+		// and issuerRef points at an operator-managed issuer; neither
+		// needs a per-tenant Issuer. Delete any owned Issuer left from
+		// a previous phase so the switch doesn't leak ACME machinery.
+		// Same ownership-guarded cleanup contract as
 		// reconcileWildcardCertificate's HTTP-01 branch.
+		if ref := tgw.Spec.Issuer; ref != nil && ref.IssuerRef != nil &&
+			ref.IssuerRef.Kind == gatewayv1alpha1.IssuerReferenceKindIssuer && ref.IssuerRef.Name == gatewayIssuerName(tgw) {
+			// issuerRef names an Issuer that happens to share our
+			// derived name; it is the live issuer now.
+			return nil
+		}
 		stale := &cmv1.Issuer{}
 		err := r.Get(ctx, types.NamespacedName{Namespace: tgw.Namespace, Name: gatewayIssuerName(tgw)}, stale)
 		if apierrors.IsNotFound(err) {
@@ -618,7 +703,7 @@ func (r *Reconciler) reconcileIssuer(ctx context.Context, tgw *gatewayv1alpha1.T
 		if err := r.Delete(ctx, stale); err != nil && !apierrors.IsNotFound(err) {
 			return fmt.Errorf("delete stale Issuer %s: %w", stale.Name, err)
 		}
-		logger.V(1).Info("deleted stale Issuer after switch to existingSecret", "name", stale.Name)
+		logger.V(1).Info("deleted stale Issuer", "name", stale.Name, "certMode", tgw.Spec.CertMode)
 		return nil
 	}
 
@@ -833,6 +918,27 @@ func (r *Reconciler) renderGateway(tgw *gatewayv1alpha1.TenantGateway, dynHostna
 				AllowedRoutes: httpsAllowedRoutes.DeepCopy(),
 			})
 		}
+		// Client certificate verification attaches per listener, so a
+		// hostname under spec.clientValidation is split off the
+		// wildcard onto a listener of its own. The more specific
+		// hostname wins the match, leaving its neighbours on the
+		// wildcard untouched.
+		for _, h := range clientValidationListenerHostnames(tgw, childApexes) {
+			hostnameVal := gatewayv1.Hostname(h)
+			listeners = append(listeners, gatewayv1.Listener{
+				Name:     gatewayv1.SectionName(perListenerName(h)),
+				Port:     443,
+				Protocol: gatewayv1.HTTPSProtocolType,
+				Hostname: &hostnameVal,
+				TLS: &gatewayv1.ListenerTLSConfig{
+					Mode: ptrTLSMode(gatewayv1.TLSModeTerminate),
+					CertificateRefs: []gatewayv1.SecretObjectReference{
+						{Name: gatewayv1.ObjectName(certName)},
+					},
+				},
+				AllowedRoutes: httpsAllowedRoutes.DeepCopy(),
+			})
+		}
 	} else {
 		// HTTP-01 (default): per-app HTTPS listener per attached
 		// HTTPRoute / TLSRoute hostname. Names + cert refs are
@@ -948,7 +1054,7 @@ func (r *Reconciler) collectInheritingChildApexes(ctx context.Context, tgw *gate
 		if nsObj.Name == tgw.Namespace {
 			continue
 		}
-		host := nsObj.Labels["namespace.cozystack.io/host"]
+		host := nsObj.Labels[namespaceHostLabel]
 		if host == "" {
 			continue
 		}
@@ -1053,8 +1159,15 @@ func (r *Reconciler) stripNamespaceGatewayLabel(ctx context.Context, name string
 }
 
 // route additions in attached namespaces re-trigger reconciliation
-// of the parent TenantGateway.
-func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+// of the parent TenantGateway. DomainClaim changes requeue every
+// TenantGateway, since a verification can unlock a hostname anywhere.
+//
+// Client CA Secrets are watched through secretMetaCache, a metadata-only
+// cache of every Secret: the manager's Secret informer is scoped to
+// WildcardSecret replicas and would never deliver them. Owned ConfigMaps
+// come from the manager's ConfigMap informer, which main scopes with
+// ConfigMapCacheByObject.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, secretMetaCache cache.Cache) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		Named("tenantgateway-controller").
 		For(&gatewayv1alpha1.TenantGateway{}).
@@ -1062,6 +1175,10 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&gatewayv1.HTTPRoute{}).
 		Owns(&cmv1.Certificate{}).
 		Owns(&cmv1.Issuer{}).
+		Owns(&corev1.ConfigMap{}).
+		WatchesRawSource(crsource.Kind(secretMetaCache, secretMeta(),
+			handler.TypedEnqueueRequestsFromMapFunc(r.mapSecretToTenantGateways),
+		)).
 		Watches(
 			&gatewayv1.HTTPRoute{},
 			r.routeToTenantGateway(),
@@ -1070,5 +1187,9 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 			&gatewayv1alpha2.TLSRoute{},
 			r.routeToTenantGateway(),
 		).
+		Watches(
+			&gatewayv1alpha1.DomainClaim{},
+			handler.EnqueueRequestsFromMapFunc(r.mapDomainClaimToTenantGateways),
+		).
 		Complete(r)
 }
@@ -24,7 +24,10 @@ import (
 	"testing"
 
 	cmv1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmetav1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -1146,6 +1149,227 @@ func TestReconcile_IssuerNameProdHitsProdACME(t *testing.T) {
 	}
 }
 
+// TestReconcile_CustomACMEIssuerWithEAB pins spec.issuer.acme: the
+// Issuer targets the operator's ACME directory, trusts its CA bundle,
+// carries the EAB credentials, and keeps the certMode solver.
+func TestReconcile_CustomACMEIssuerWithEAB(t *testing.T) {
+	s := newScheme(t)
+	tgw := &gatewayv1alpha1.TenantGateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack", Namespace: "tenant-foo"},
+		Spec: gatewayv1alpha1.TenantGatewaySpec{
+			Apex:             "foo.example.com",
+			CertMode:         gatewayv1alpha1.CertModeHTTP01,
+			IssuerName:       gatewayv1alpha1.IssuerNameLetsEncryptProd,
+			GatewayClassName: "cilium",
+			Issuer: &gatewayv1alpha1.IssuerConfig{
+				ACME: &gatewayv1alpha1.ACMEIssuerConfig{
+					Server:   "https://ca.internal:9000/acme/acme/directory",
+					Email:    "ops@example.com",
+					CABundle: []byte("-----BEGIN CERTIFICATE-----"),
+					ExternalAccountBinding: &gatewayv1alpha1.ExternalAccountBinding{
+						KeyID: "kid-1",
+						KeySecretRef: corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "eab"},
+							Key:                  "secret",
+						},
+					},
+				},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(tgw).WithStatusSubresource(tgw).Build()
+
+	r := &Reconciler{Client: c, Scheme: s}
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: "cozystack", Namespace: "tenant-foo"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	iss := &cmv1.Issuer{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "cozystack-gateway", Namespace: "tenant-foo"}, iss); err != nil {
+		t.Fatalf("get Issuer: %v", err)
+	}
+	acme := iss.Spec.ACME
+	if acme == nil {
+		t.Fatalf("expected ACME issuer, got %+v", iss.Spec)
+	}
+	if acme.Server != "https://ca.internal:9000/acme/acme/directory" {
+		t.Errorf("ACME.Server=%q, want the custom directory", acme.Server)
+	}
+	if acme.Email != "ops@example.com" || string(acme.CABundle) != "-----BEGIN CERTIFICATE-----" {
+		t.Errorf("email/caBundle not propagated: %+v", acme)
+	}
+	eab := acme.ExternalAccountBinding
+	if eab == nil || eab.KeyID != "kid-1" || eab.Key.Name != "eab" || eab.Key.Key != "secret" {
+		t.Errorf("unexpected externalAccountBinding: %+v", eab)
+	}
+	if len(acme.Solvers) != 1 || acme.Solvers[0].HTTP01 == nil {
+		t.Errorf("expected the HTTP-01 solver, got %+v", acme.Solvers)
+	}
+}
+
+// TestReconcile_CAIssuerSignsWildcard pins spec.issuer.ca: the Issuer
+// is a cert-manager CA Issuer on the tenant's Secret and dns01 mode
+// needs no DNS provider, since nothing is solved.
+func TestReconcile_CAIssuerSignsWildcard(t *testing.T) {
+	s := newScheme(t)
+	tgw := &gatewayv1alpha1.TenantGateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack", Namespace: "tenant-foo"},
+		Spec: gatewayv1alpha1.TenantGatewaySpec{
+			Apex:             "foo.example.com",
+			CertMode:         gatewayv1alpha1.CertModeDNS01,
+			GatewayClassName: "cilium",
+			Issuer: &gatewayv1alpha1.IssuerConfig{
+				CA: &gatewayv1alpha1.CAIssuerConfig{
+					SecretRef: corev1.LocalObjectReference{Name: "tenant-ca"},
+				},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(tgw).WithStatusSubresource(tgw).Build()
+
+	r := &Reconciler{Client: c, Scheme: s}
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: "cozystack", Namespace: "tenant-foo"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	iss := &cmv1.Issuer{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "cozystack-gateway", Namespace: "tenant-foo"}, iss); err != nil {
+		t.Fatalf("get Issuer: %v", err)
+	}
+	if iss.Spec.ACME != nil || iss.Spec.CA == nil || iss.Spec.CA.SecretName != "tenant-ca" {
+		t.Errorf("expected CA issuer on tenant-ca, got %+v", iss.Spec)
+	}
+	cert := &cmv1.Certificate{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "cozystack-gateway-tls", Namespace: "tenant-foo"}, cert); err != nil {
+		t.Fatalf("get wildcard Certificate: %v", err)
+	}
+	if cert.Spec.IssuerRef.Kind != "Issuer" || cert.Spec.IssuerRef.Name != "cozystack-gateway" {
+		t.Errorf("wildcard Certificate issuerRef=%+v, want the per-tenant Issuer", cert.Spec.IssuerRef)
+	}
+}
+
+// TestReconcile_ACMEIssuerSwitchesToCA pins the switch from the
+// built-in ACME Issuer to spec.issuer.ca in http01 mode: the owned
+// Issuer is rewritten in place, dropping the ACME block and its
+// solver, and per-listener Certificates keep pointing at it.
+func TestReconcile_ACMEIssuerSwitchesToCA(t *testing.T) {
+	s := newScheme(t)
+	tgw := &gatewayv1alpha1.TenantGateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack", Namespace: "tenant-foo"},
+		Spec: gatewayv1alpha1.TenantGatewaySpec{
+			Apex:               "foo.example.com",
+			CertMode:           gatewayv1alpha1.CertModeHTTP01,
+			GatewayClassName:   "cilium",
+			AttachedNamespaces: []string{"cozy-harbor"},
+		},
+	}
+	route := httpRouteAttached("harbor", "cozy-harbor", "harbor.foo.example.com")
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(tgw, route).WithStatusSubresource(tgw).Build()
+	r := &Reconciler{Client: c, Scheme: s}
+	reconcileTGW(t, r)
+
+	iss := &cmv1.Issuer{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "cozystack-gateway", Namespace: "tenant-foo"}, iss); err != nil {
+		t.Fatalf("get Issuer: %v", err)
+	}
+	if iss.Spec.ACME == nil {
+		t.Fatalf("expected the built-in ACME Issuer first, got %+v", iss.Spec)
+	}
+
+	updated := getTGW(t, c)
+	updated.Spec.Issuer = &gatewayv1alpha1.IssuerConfig{
+		CA: &gatewayv1alpha1.CAIssuerConfig{SecretRef: corev1.LocalObjectReference{Name: "tenant-ca"}},
+	}
+	if err := c.Update(context.TODO(), updated); err != nil {
+		t.Fatalf("set spec.issuer.ca: %v", err)
+	}
+	reconcileTGW(t, r)
+
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "cozystack-gateway", Namespace: "tenant-foo"}, iss); err != nil {
+		t.Fatalf("get Issuer: %v", err)
+	}
+	if iss.Spec.ACME != nil || iss.Spec.CA == nil || iss.Spec.CA.SecretName != "tenant-ca" {
+		t.Errorf("expected the Issuer rewritten as a CA issuer on tenant-ca, got %+v", iss.Spec)
+	}
+	cert := &cmv1.Certificate{}
+	name := perListenerCertName(updated, "harbor.foo.example.com")
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "tenant-foo"}, cert); err != nil {
+		t.Fatalf("get per-listener Certificate: %v", err)
+	}
+	if cert.Spec.IssuerRef.Kind != "Issuer" || cert.Spec.IssuerRef.Name != "cozystack-gateway" {
+		t.Errorf("per-listener Certificate issuerRef=%+v, want the per-tenant Issuer", cert.Spec.IssuerRef)
+	}
+}
+
+// TestReconcile_IssuerRefUsesExternalIssuer pins spec.issuer.issuerRef:
+// Certificates reference the named ClusterIssuer and the per-tenant
+// Issuer from an earlier ACME phase is garbage-collected.
+func TestReconcile_IssuerRefUsesExternalIssuer(t *testing.T) {
+	s := newScheme(t)
+	tgw := &gatewayv1alpha1.TenantGateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack", Namespace: "tenant-foo"},
+		Spec: gatewayv1alpha1.TenantGatewaySpec{
+			Apex:             "foo.example.com",
+			CertMode:         gatewayv1alpha1.CertModeDNS01,
+			GatewayClassName: "cilium",
+			DNS01: &gatewayv1alpha1.DNS01Config{
+				Provider: gatewayv1alpha1.DNS01ProviderCloudflare,
+				Cloudflare: &gatewayv1alpha1.CloudflareDNS01{
+					APITokenSecretRef: corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "cf-token"},
+						Key:                  "api-token",
+					},
+				},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(tgw).WithStatusSubresource(tgw).Build()
+
+	r := &Reconciler{Client: c, Scheme: s}
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: "cozystack", Namespace: "tenant-foo"},
+	}); err != nil {
+		t.Fatalf("phase 1 reconcile: %v", err)
+	}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "cozystack-gateway", Namespace: "tenant-foo"}, &cmv1.Issuer{}); err != nil {
+		t.Fatalf("expected Issuer after ACME phase: %v", err)
+	}
+
+	updated := &gatewayv1alpha1.TenantGateway{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "cozystack", Namespace: "tenant-foo"}, updated); err != nil {
+		t.Fatalf("get tgw: %v", err)
+	}
+	updated.Spec.Issuer = &gatewayv1alpha1.IssuerConfig{
+		IssuerRef: &gatewayv1alpha1.IssuerReference{
+			Kind: gatewayv1alpha1.IssuerReferenceKindClusterIssuer,
+			Name: "corp-pki",
+		},
+	}
+	if err := c.Update(context.TODO(), updated); err != nil {
+		t.Fatalf("set issuerRef: %v", err)
+	}
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: "cozystack", Namespace: "tenant-foo"},
+	}); err != nil {
+		t.Fatalf("phase 2 reconcile: %v", err)
+	}
+
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "cozystack-gateway", Namespace: "tenant-foo"}, &cmv1.Issuer{}); err == nil {
+		t.Errorf("Issuer leaked after switch to issuerRef")
+	}
+	cert := &cmv1.Certificate{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "cozystack-gateway-tls", Namespace: "tenant-foo"}, cert); err != nil {
+		t.Fatalf("get wildcard Certificate: %v", err)
+	}
+	if cert.Spec.IssuerRef.Kind != "ClusterIssuer" || cert.Spec.IssuerRef.Name != "corp-pki" {
+		t.Errorf("wildcard Certificate issuerRef=%+v, want ClusterIssuer/corp-pki", cert.Spec.IssuerRef)
+	}
+}
+
 // TestReconcile_DNS01IssuerCloudflareSolver pins the DNS-01 + cloudflare
 // path: the Issuer carries a dns01.cloudflare solver block that
 // references the operator-supplied API token Secret.
@@ -2251,6 +2475,109 @@ func TestReconcile_StatusReadyTrueWhenGatewayProgrammed(t *testing.T) {
 	}
 }
 
+// TestReconcile_ListenerStatusReportsCertificateReadiness pins
+// per-listener certificate readiness: a programmed listener whose
+// Certificate is not issued yet reports Ready=false with the
+// Certificate's message, and flips once cert-manager marks it Ready.
+func TestReconcile_ListenerStatusReportsCertificateReadiness(t *testing.T) {
+	s := newScheme(t)
+	tgw := &gatewayv1alpha1.TenantGateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack", Namespace: "tenant-foo"},
+		Spec: gatewayv1alpha1.TenantGatewaySpec{
+			Apex:             "foo.example.com",
+			CertMode:         gatewayv1alpha1.CertModeDNS01,
+			GatewayClassName: "cilium",
+			Issuer: &gatewayv1alpha1.IssuerConfig{
+				IssuerRef: &gatewayv1alpha1.IssuerReference{
+					Kind: gatewayv1alpha1.IssuerReferenceKindClusterIssuer,
+					Name: "private-ca",
+				},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(tgw).
+		WithStatusSubresource(tgw, &gatewayv1.Gateway{}).
+		Build()
+	r := &Reconciler{Client: c, Scheme: s}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "cozystack", Namespace: "tenant-foo"}}
+	if _, err := r.Reconcile(context.TODO(), req); err != nil {
+		t.Fatalf("first reconcile: %v", err)
+	}
+
+	gw := &gatewayv1.Gateway{}
+	if err := c.Get(context.TODO(), req.NamespacedName, gw); err != nil {
+		t.Fatalf("get Gateway: %v", err)
+	}
+	programmed := []metav1.Condition{
+		{Type: "Accepted", Status: metav1.ConditionTrue, Reason: "Accepted", LastTransitionTime: metav1.Now()},
+		{Type: "Programmed", Status: metav1.ConditionTrue, Reason: "Programmed", LastTransitionTime: metav1.Now()},
+	}
+	gw.Status.Conditions = programmed
+	for _, l := range gw.Spec.Listeners {
+		gw.Status.Listeners = append(gw.Status.Listeners, gatewayv1.ListenerStatus{
+			Name:           l.Name,
+			Conditions:     programmed,
+			SupportedKinds: []gatewayv1.RouteGroupKind{},
+		})
+	}
+	if err := c.Status().Update(context.TODO(), gw); err != nil {
+		t.Fatalf("patch Gateway status: %v", err)
+	}
+
+	setCertReady := func(status cmmetav1.ConditionStatus, message string) {
+		t.Helper()
+		cert := &cmv1.Certificate{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Name: "cozystack-gateway-tls", Namespace: "tenant-foo"}, cert); err != nil {
+			t.Fatalf("get Certificate: %v", err)
+		}
+		cert.Status.Conditions = []cmv1.CertificateCondition{{
+			Type:    cmv1.CertificateConditionReady,
+			Status:  status,
+			Reason:  "Issuing",
+			Message: message,
+		}}
+		if err := c.Update(context.TODO(), cert); err != nil {
+			t.Fatalf("update Certificate status: %v", err)
+		}
+		if _, err := r.Reconcile(context.TODO(), req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+	}
+
+	setCertReady(cmmetav1.ConditionFalse, "clusterissuer private-ca is not ready")
+	got := &gatewayv1alpha1.TenantGateway{}
+	if err := c.Get(context.TODO(), req.NamespacedName, got); err != nil {
+		t.Fatalf("get tgw: %v", err)
+	}
+	var https *gatewayv1alpha1.TenantGatewayListenerStatus
+	for i := range got.Status.Listeners {
+		if got.Status.Listeners[i].Name == "https" {
+			https = &got.Status.Listeners[i]
+		}
+	}
+	if https == nil {
+		t.Fatalf("no https listener in status: %+v", got.Status.Listeners)
+	}
+	if https.Ready || https.Reason != "CertificateNotReady" || https.CertificateMessage != "clusterissuer private-ca is not ready" {
+		t.Errorf("expected https listener blocked on its Certificate, got %+v", https)
+	}
+	if cond := meta.FindStatusCondition(got.Status.Conditions, "Ready"); cond == nil || cond.Status != metav1.ConditionFalse {
+		t.Errorf("expected Ready=False while the Certificate is pending, got %+v", cond)
+	}
+
+	setCertReady(cmmetav1.ConditionTrue, "Certificate is up to date and has not expired")
+	if err := c.Get(context.TODO(), req.NamespacedName, got); err != nil {
+		t.Fatalf("get tgw: %v", err)
+	}
+	for _, l := range got.Status.Listeners {
+		if !l.Ready || l.CertificateMessage != "" {
+			t.Errorf("expected listener %s ready once the Certificate is issued, got %+v", l.Name, l)
+		}
+	}
+}
+
 // TestReconcile_TwoRoutesSameHostnameCozyWins pins the conflict
 // resolution rule: when two HTTPRoutes attached to the same Gateway
 // claim the same hostname but live in different namespaces, the
@@ -2742,6 +3069,43 @@ func TestReconcile_DNS01ProviderMissingConfigErrors(t *testing.T) {
 			},
 			wantSubs: "dns01.rfc2136",
 		},
+		{
+			name: "clouddns without clouddns block",
+			dns01: &gatewayv1alpha1.DNS01Config{
+				Provider: "clouddns",
+			},
+			wantSubs: "dns01.clouddns",
+		},
+		{
+			name: "azuredns without azuredns block",
+			dns01: &gatewayv1alpha1.DNS01Config{
+				Provider: "azuredns",
+			},
+			wantSubs: "dns01.azuredns",
+		},
+		{
+			name: "powerdns without powerdns block",
+			dns01: &gatewayv1alpha1.DNS01Config{
+				Provider: "powerdns",
+			},
+			wantSubs: "dns01.powerdns",
+		},
+		{
+			name: "hetzner without groupName",
+			dns01: &gatewayv1alpha1.DNS01Config{
+				Provider: "hetzner",
+				Hetzner:  &gatewayv1alpha1.HetznerDNS01{ZoneName: "example.com"},
+			},
+			wantSubs: "dns01.hetzner.groupName",
+		},
+		{
+			name: "webhook without solverName",
+			dns01: &gatewayv1alpha1.DNS01Config{
+				Provider: "webhook",
+				Webhook:  &gatewayv1alpha1.WebhookDNS01{GroupName: "acme.example.com"},
+			},
+			wantSubs: "dns01.webhook.groupName and dns01.webhook.solverName",
+		},
 		{
 			name: "unknown provider",
 			dns01: &gatewayv1alpha1.DNS01Config{
@@ -2772,6 +3136,155 @@ func TestReconcile_DNS01ProviderMissingConfigErrors(t *testing.T) {
 	}
 }
 
+// TestBuildSolver_CloudDNSAndAzureDNS pins the in-tree cert-manager
+// solvers for Google Cloud DNS and Azure DNS, including the Azure
+// environment default cert-manager would otherwise leave empty.
+func TestBuildSolver_CloudDNSAndAzureDNS(t *testing.T) {
+	tgw := &gatewayv1alpha1.TenantGateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack", Namespace: "tenant-foo"},
+		Spec: gatewayv1alpha1.TenantGatewaySpec{
+			Apex:     "foo.example.com",
+			CertMode: gatewayv1alpha1.CertModeDNS01,
+			DNS01: &gatewayv1alpha1.DNS01Config{
+				Provider: gatewayv1alpha1.DNS01ProviderCloudDNS,
+				CloudDNS: &gatewayv1alpha1.CloudDNSDNS01{
+					Project: "my-project",
+					ServiceAccountSecretRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "clouddns-sa"},
+						Key:                  "key.json",
+					},
+				},
+			},
+		},
+	}
+	solver, err := buildSolver(tgw)
+	if err != nil {
+		t.Fatalf("clouddns: %v", err)
+	}
+	if solver.DNS01 == nil || solver.DNS01.CloudDNS == nil {
+		t.Fatalf("expected dns01.cloudDNS solver, got %+v", solver)
+	}
+	if solver.DNS01.CloudDNS.Project != "my-project" || solver.DNS01.CloudDNS.ServiceAccount == nil || solver.DNS01.CloudDNS.ServiceAccount.Name != "clouddns-sa" {
+		t.Errorf("cloudDNS solver=%+v, want project=my-project serviceAccount=clouddns-sa", solver.DNS01.CloudDNS)
+	}
+
+	tgw.Spec.DNS01 = &gatewayv1alpha1.DNS01Config{
+		Provider: gatewayv1alpha1.DNS01ProviderAzureDNS,
+		AzureDNS: &gatewayv1alpha1.AzureDNSDNS01{
+			SubscriptionID:    "sub",
+			ResourceGroupName: "rg",
+			ClientID:          "client",
+			TenantID:          "tenant",
+			ClientSecretSecretRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "azure-sp"},
+				Key:                  "client-secret",
+			},
+		},
+	}
+	solver, err = buildSolver(tgw)
+	if err != nil {
+		t.Fatalf("azuredns: %v", err)
+	}
+	if solver.DNS01 == nil || solver.DNS01.AzureDNS == nil {
+		t.Fatalf("expected dns01.azureDNS solver, got %+v", solver)
+	}
+	az := solver.DNS01.AzureDNS
+	if az.Environment != "AzurePublicCloud" {
+		t.Errorf("azureDNS environment=%q, want AzurePublicCloud default", az.Environment)
+	}
+	if az.ClientSecret == nil || az.ClientSecret.Name != "azure-sp" || az.ClientSecret.Key != "client-secret" {
+		t.Errorf("azureDNS client secret=%+v, want azure-sp/client-secret", az.ClientSecret)
+	}
+}
+
+// TestBuildSolver_WebhookProviders pins the webhook solvers rendered
+// for providers without in-tree cert-manager support: PowerDNS and
+// Hetzner get the upstream webhooks' config shape, the generic
+// webhook provider passes its config through untouched.
+func TestBuildSolver_WebhookProviders(t *testing.T) {
+	cases := []struct {
+		name       string
+		dns01      *gatewayv1alpha1.DNS01Config
+		wantGroup  string
+		wantSolver string
+		wantConfig string
+	}{
+		{
+			name: "powerdns defaults",
+			dns01: &gatewayv1alpha1.DNS01Config{
+				Provider: gatewayv1alpha1.DNS01ProviderPowerDNS,
+				PowerDNS: &gatewayv1alpha1.PowerDNSDNS01{
+					Host: "https://pdns.example.com:8081",
+					APIKeySecretRef: corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "pdns"},
+						Key:                  "api-key",
+					},
+				},
+			},
+			wantGroup:  "acme.zacharyseguin.ca",
+			wantSolver: "pdns",
+			wantConfig: `{"apiKeySecretRef":{"key":"api-key","name":"pdns"},"host":"https://pdns.example.com:8081","serverID":"localhost"}`,
+		},
+		{
+			name: "hetzner",
+			dns01: &gatewayv1alpha1.DNS01Config{
+				Provider: gatewayv1alpha1.DNS01ProviderHetzner,
+				Hetzner: &gatewayv1alpha1.HetznerDNS01{
+					ZoneName:  "example.com",
+					GroupName: "acme.example.com",
+					APIKeySecretRef: corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "hetzner"},
+						Key:                  "api-key",
+					},
+				},
+			},
+			wantGroup:  "acme.example.com",
+			wantSolver: "hetzner",
+			wantConfig: `{"apiUrl":"https://dns.hetzner.com/api/v1","secretName":"hetzner","zoneName":"example.com"}`,
+		},
+		{
+			name: "generic webhook",
+			dns01: &gatewayv1alpha1.DNS01Config{
+				Provider: gatewayv1alpha1.DNS01ProviderWebhook,
+				Webhook: &gatewayv1alpha1.WebhookDNS01{
+					GroupName:  "acme.example.com",
+					SolverName: "gandi",
+					Config:     &apiextensionsv1.JSON{Raw: []byte(`{"apiKeySecretRef":{"name":"gandi"}}`)},
+				},
+			},
+			wantGroup:  "acme.example.com",
+			wantSolver: "gandi",
+			wantConfig: `{"apiKeySecretRef":{"name":"gandi"}}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tgw := &gatewayv1alpha1.TenantGateway{
+				ObjectMeta: metav1.ObjectMeta{Name: "cozystack", Namespace: "tenant-foo"},
+				Spec: gatewayv1alpha1.TenantGatewaySpec{
+					Apex:     "foo.example.com",
+					CertMode: gatewayv1alpha1.CertModeDNS01,
+					DNS01:    tc.dns01,
+				},
+			}
+			solver, err := buildSolver(tgw)
+			if err != nil {
+				t.Fatalf("buildSolver: %v", err)
+			}
+			if solver.DNS01 == nil || solver.DNS01.Webhook == nil {
+				t.Fatalf("expected dns01.webhook solver, got %+v", solver)
+			}
+			wh := solver.DNS01.Webhook
+			if wh.GroupName != tc.wantGroup || wh.SolverName != tc.wantSolver {
+				t.Errorf("webhook groupName=%q solverName=%q, want %q/%q", wh.GroupName, wh.SolverName, tc.wantGroup, tc.wantSolver)
+			}
+			if wh.Config == nil || string(wh.Config.Raw) != tc.wantConfig {
+				t.Errorf("webhook config=%v, want %s", wh.Config, tc.wantConfig)
+			}
+		})
+	}
+}
+
 func ptrNamespace(ns string) *gatewayv1.Namespace {
 	v := gatewayv1.Namespace(ns)
 	return &v
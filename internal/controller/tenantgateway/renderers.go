@@ -19,12 +19,14 @@ package tenantgateway
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	cmacmev1 "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
 	cmv1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	cmmetav1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
@@ -72,7 +74,7 @@ func acmeServerForIssuer(name gatewayv1alpha1.IssuerName) (string, error) {
 	case gatewayv1alpha1.IssuerNameLetsEncryptStage:
 		return letsencryptStageServer, nil
 	default:
-		return "", fmt.Errorf("unsupported issuerName %q (supported: letsencrypt-prod, letsencrypt-stage)", name)
+		return "", fmt.Errorf("unsupported issuerName %q (supported: letsencrypt-prod, letsencrypt-stage; set spec.issuer for other authorities)", name)
 	}
 }
 
@@ -212,18 +214,11 @@ func perListenerCertName(tgw *gatewayv1alpha1.TenantGateway, hostname string) st
 	return tgw.Name + "-" + hostnameFirstLabel(hostname) + "-" + hostnameSuffix(hostname) + "-tls"
 }
 
-// renderIssuer builds the per-tenant ACME Issuer. The solver block
-// is selected by certMode: HTTP-01 with a gatewayHTTPRoute solver
-// pointing back at the tenant's own Gateway/http listener, or DNS-01
-// with the operator-supplied provider config. The ACME server URL is
-// selected by spec.issuerName.
+// renderIssuer builds the per-tenant Issuer. Callers must check
+// rendersOwnIssuer first; issuerRef and existingSecret modes have no
+// Issuer of their own.
 func (r *Reconciler) renderIssuer(tgw *gatewayv1alpha1.TenantGateway) (*cmv1.Issuer, error) {
-	server, err := acmeServerForIssuer(tgw.Spec.IssuerName)
-	if err != nil {
-		return nil, err
-	}
-
-	solver, err := buildSolver(tgw)
+	cfg, err := buildIssuerConfig(tgw)
 	if err != nil {
 		return nil, err
 	}
@@ -237,17 +232,7 @@ func (r *Reconciler) renderIssuer(tgw *gatewayv1alpha1.TenantGateway) (*cmv1.Iss
 			},
 		},
 		Spec: cmv1.IssuerSpec{
-			IssuerConfig: cmv1.IssuerConfig{
-				ACME: &cmacmev1.ACMEIssuer{
-					Server: server,
-					PrivateKey: cmmetav1.SecretKeySelector{
-						LocalObjectReference: cmmetav1.LocalObjectReference{
-							Name: tgw.Name + "-acme-account",
-						},
-					},
-					Solvers: []cmacmev1.ACMEChallengeSolver{*solver},
-				},
-			},
+			IssuerConfig: *cfg,
 		},
 	}
 	if err := controllerutil.SetControllerReference(tgw, issuer, r.Scheme); err != nil {
@@ -256,6 +241,95 @@ func (r *Reconciler) renderIssuer(tgw *gatewayv1alpha1.TenantGateway) (*cmv1.Iss
 	return issuer, nil
 }
 
+// buildIssuerConfig selects the Issuer flavour from spec.issuer: a CA
+// Issuer for issuer.ca, an ACME Issuer against issuer.acme.server, or
+// the Let's Encrypt environment named by spec.issuerName. Both ACME
+// variants use the solver picked by certMode: HTTP-01 with a
+// gatewayHTTPRoute solver pointing back at the tenant's own
+// Gateway/http listener, or DNS-01 with the operator-supplied
+// provider config.
+func buildIssuerConfig(tgw *gatewayv1alpha1.TenantGateway) (*cmv1.IssuerConfig, error) {
+	custom := tgw.Spec.Issuer
+	if custom != nil && custom.CA != nil {
+		if custom.CA.SecretRef.Name == "" {
+			return nil, fmt.Errorf("spec.issuer.ca.secretRef.name must be set")
+		}
+		return &cmv1.IssuerConfig{
+			CA: &cmv1.CAIssuer{SecretName: custom.CA.SecretRef.Name},
+		}, nil
+	}
+
+	acme := &cmacmev1.ACMEIssuer{
+		PrivateKey: cmmetav1.SecretKeySelector{
+			LocalObjectReference: cmmetav1.LocalObjectReference{
+				Name: tgw.Name + "-acme-account",
+			},
+		},
+	}
+	if custom != nil && custom.ACME != nil {
+		if custom.ACME.Server == "" {
+			return nil, fmt.Errorf("spec.issuer.acme.server must be set")
+		}
+		acme.Server = custom.ACME.Server
+		acme.Email = custom.ACME.Email
+		acme.CABundle = custom.ACME.CABundle
+		acme.SkipTLSVerify = custom.ACME.SkipTLSVerify
+		if eab := custom.ACME.ExternalAccountBinding; eab != nil {
+			if eab.KeyID == "" || eab.KeySecretRef.Name == "" || eab.KeySecretRef.Key == "" {
+				return nil, fmt.Errorf("spec.issuer.acme.externalAccountBinding requires keyID, keySecretRef.name and keySecretRef.key")
+			}
+			acme.ExternalAccountBinding = &cmacmev1.ACMEExternalAccountBinding{
+				KeyID: eab.KeyID,
+				Key: cmmetav1.SecretKeySelector{
+					LocalObjectReference: cmmetav1.LocalObjectReference{Name: eab.KeySecretRef.Name},
+					Key:                  eab.KeySecretRef.Key,
+				},
+			}
+		}
+	} else {
+		server, err := acmeServerForIssuer(tgw.Spec.IssuerName)
+		if err != nil {
+			return nil, err
+		}
+		acme.Server = server
+	}
+
+	solver, err := buildSolver(tgw)
+	if err != nil {
+		return nil, err
+	}
+	acme.Solvers = []cmacmev1.ACMEChallengeSolver{*solver}
+	return &cmv1.IssuerConfig{ACME: acme}, nil
+}
+
+// rendersOwnIssuer reports whether the controller mints a per-tenant
+// Issuer. existingSecret mode issues nothing, and spec.issuer.issuerRef
+// points Certificates at an issuer the operator manages.
+func rendersOwnIssuer(tgw *gatewayv1alpha1.TenantGateway) bool {
+	if tgw.Spec.CertMode == gatewayv1alpha1.CertModeExistingSecret {
+		return false
+	}
+	return tgw.Spec.Issuer == nil || tgw.Spec.Issuer.IssuerRef == nil
+}
+
+// certificateIssuerRef returns the issuerRef every rendered
+// Certificate carries: the operator-named issuer when
+// spec.issuer.issuerRef is set, the per-tenant Issuer otherwise.
+func certificateIssuerRef(tgw *gatewayv1alpha1.TenantGateway) cmmetav1.ObjectReference {
+	if tgw.Spec.Issuer != nil && tgw.Spec.Issuer.IssuerRef != nil {
+		ref := tgw.Spec.Issuer.IssuerRef
+		kind := ref.Kind
+		if kind == "" {
+			kind = gatewayv1alpha1.IssuerReferenceKindClusterIssuer
+		}
+		return cmmetav1.ObjectReference{Kind: string(kind), Name: ref.Name}
+	}
+	return cmmetav1.ObjectReference{
+		Kind: "Issuer",
+		Name: gatewayIssuerName(tgw),
+	}
+}
+
 func buildSolver(tgw *gatewayv1alpha1.TenantGateway) (*cmacmev1.ACMEChallengeSolver, error) {
 	switch tgw.Spec.CertMode {
 	case gatewayv1alpha1.CertModeHTTP01, "":
@@ -286,7 +360,7 @@ func buildSolver(tgw *gatewayv1alpha1.TenantGateway) (*cmacmev1.ACMEChallengeSol
 			return nil, fmt.Errorf("certMode=dns01 requires spec.dns01 to be set")
 		}
 		switch tgw.Spec.DNS01.Provider {
-		case "cloudflare":
+		case gatewayv1alpha1.DNS01ProviderCloudflare:
 			if tgw.Spec.DNS01.Cloudflare == nil {
 				return nil, fmt.Errorf("dns01.provider=cloudflare requires dns01.cloudflare to be set")
 			}
@@ -302,7 +376,7 @@ func buildSolver(tgw *gatewayv1alpha1.TenantGateway) (*cmacmev1.ACMEChallengeSol
 					},
 				},
 			}, nil
-		case "route53":
+		case gatewayv1alpha1.DNS01ProviderRoute53:
 			if tgw.Spec.DNS01.Route53 == nil {
 				return nil, fmt.Errorf("dns01.provider=route53 requires dns01.route53 to be set")
 			}
@@ -320,7 +394,7 @@ func buildSolver(tgw *gatewayv1alpha1.TenantGateway) (*cmacmev1.ACMEChallengeSol
 			return &cmacmev1.ACMEChallengeSolver{
 				DNS01: &cmacmev1.ACMEChallengeSolverDNS01{Route53: r53},
 			}, nil
-		case "digitalocean":
+		case gatewayv1alpha1.DNS01ProviderDigitalOcean:
 			if tgw.Spec.DNS01.DigitalOcean == nil {
 				return nil, fmt.Errorf("dns01.provider=digitalocean requires dns01.digitalocean to be set")
 			}
@@ -334,7 +408,7 @@ func buildSolver(tgw *gatewayv1alpha1.TenantGateway) (*cmacmev1.ACMEChallengeSol
 					},
 				},
 			}, nil
-		case "rfc2136":
+		case gatewayv1alpha1.DNS01ProviderRFC2136:
 			if tgw.Spec.DNS01.RFC2136 == nil {
 				return nil, fmt.Errorf("dns01.provider=rfc2136 requires dns01.rfc2136 to be set")
 			}
@@ -356,8 +430,102 @@ func buildSolver(tgw *gatewayv1alpha1.TenantGateway) (*cmacmev1.ACMEChallengeSol
 					},
 				},
 			}, nil
+		case gatewayv1alpha1.DNS01ProviderCloudDNS:
+			if tgw.Spec.DNS01.CloudDNS == nil {
+				return nil, fmt.Errorf("dns01.provider=clouddns requires dns01.clouddns to be set")
+			}
+			cfg := tgw.Spec.DNS01.CloudDNS
+			gcp := &cmacmev1.ACMEIssuerDNS01ProviderCloudDNS{
+				Project:        cfg.Project,
+				HostedZoneName: cfg.HostedZoneName,
+			}
+			if cfg.ServiceAccountSecretRef != nil {
+				gcp.ServiceAccount = &cmmetav1.SecretKeySelector{
+					LocalObjectReference: cmmetav1.LocalObjectReference{Name: cfg.ServiceAccountSecretRef.Name},
+					Key:                  cfg.ServiceAccountSecretRef.Key,
+				}
+			}
+			return &cmacmev1.ACMEChallengeSolver{
+				DNS01: &cmacmev1.ACMEChallengeSolverDNS01{CloudDNS: gcp},
+			}, nil
+		case gatewayv1alpha1.DNS01ProviderAzureDNS:
+			if tgw.Spec.DNS01.AzureDNS == nil {
+				return nil, fmt.Errorf("dns01.provider=azuredns requires dns01.azuredns to be set")
+			}
+			cfg := tgw.Spec.DNS01.AzureDNS
+			env := cmacmev1.AzureDNSEnvironment(cfg.Environment)
+			if env == "" {
+				env = cmacmev1.AzurePublicCloud
+			}
+			azure := &cmacmev1.ACMEIssuerDNS01ProviderAzureDNS{
+				SubscriptionID:    cfg.SubscriptionID,
+				ResourceGroupName: cfg.ResourceGroupName,
+				HostedZoneName:    cfg.HostedZoneName,
+				Environment:       env,
+				TenantID:          cfg.TenantID,
+				ClientID:          cfg.ClientID,
+			}
+			if cfg.ClientSecretSecretRef != nil {
+				azure.ClientSecret = &cmmetav1.SecretKeySelector{
+					LocalObjectReference: cmmetav1.LocalObjectReference{Name: cfg.ClientSecretSecretRef.Name},
+					Key:                  cfg.ClientSecretSecretRef.Key,
+				}
+			}
+			return &cmacmev1.ACMEChallengeSolver{
+				DNS01: &cmacmev1.ACMEChallengeSolverDNS01{AzureDNS: azure},
+			}, nil
+		case gatewayv1alpha1.DNS01ProviderPowerDNS:
+			if tgw.Spec.DNS01.PowerDNS == nil {
+				return nil, fmt.Errorf("dns01.provider=powerdns requires dns01.powerdns to be set")
+			}
+			cfg := tgw.Spec.DNS01.PowerDNS
+			groupName := cfg.GroupName
+			if groupName == "" {
+				groupName = powerDNSWebhookGroupName
+			}
+			return webhookSolver(groupName, "pdns", map[string]any{
+				"host":     cfg.Host,
+				"serverID": powerDNSServerID(cfg),
+				"apiKeySecretRef": map[string]string{
+					"name": cfg.APIKeySecretRef.Name,
+					"key":  cfg.APIKeySecretRef.Key,
+				},
+			})
+		case gatewayv1alpha1.DNS01ProviderHetzner:
+			if tgw.Spec.DNS01.Hetzner == nil {
+				return nil, fmt.Errorf("dns01.provider=hetzner requires dns01.hetzner to be set")
+			}
+			cfg := tgw.Spec.DNS01.Hetzner
+			if cfg.GroupName == "" {
+				return nil, fmt.Errorf("dns01.provider=hetzner requires dns01.hetzner.groupName to be set")
+			}
+			// The upstream Hetzner webhook reads the token from the
+			// fixed hetznerAPIKeySecretKey key of the named Secret, and
+			// so does record publishing.
+			return webhookSolver(cfg.GroupName, "hetzner", map[string]any{
+				"secretName": cfg.APIKeySecretRef.Name,
+				"zoneName":   cfg.ZoneName,
+				"apiUrl":     hetznerAPIURL(cfg),
+			})
+		case gatewayv1alpha1.DNS01ProviderWebhook:
+			if tgw.Spec.DNS01.Webhook == nil {
+				return nil, fmt.Errorf("dns01.provider=webhook requires dns01.webhook to be set")
+			}
+			cfg := tgw.Spec.DNS01.Webhook
+			if cfg.GroupName == "" || cfg.SolverName == "" {
+				return nil, fmt.Errorf("dns01.provider=webhook requires dns01.webhook.groupName and dns01.webhook.solverName to be set")
+			}
+			return &cmacmev1.ACMEChallengeSolver{
+				DNS01: &cmacmev1.ACMEChallengeSolverDNS01{
+					Webhook: &cmacmev1.ACMEIssuerDNS01ProviderWebhook{
+						GroupName:  cfg.GroupName,
+						SolverName: cfg.SolverName,
+						Config:     cfg.Config.DeepCopy(),
+					},
+				},
+			}, nil
 		default:
-			return nil, fmt.Errorf("unsupported dns01.provider=%q (supported: cloudflare, route53, digitalocean, rfc2136)", tgw.Spec.DNS01.Provider)
+			return nil, fmt.Errorf("unsupported dns01.provider=%q (supported: cloudflare, route53, digitalocean, rfc2136, clouddns, azuredns, powerdns, hetzner, webhook)", tgw.Spec.DNS01.Provider)
 		}
 
 	case gatewayv1alpha1.CertModeExistingSecret:
@@ -372,6 +540,51 @@ func buildSolver(tgw *gatewayv1alpha1.TenantGateway) (*cmacmev1.ACMEChallengeSol
 	}
 }
 
+// powerDNSWebhookGroupName is the groupName the upstream
+// cert-manager-webhook-pdns chart registers by default.
+const powerDNSWebhookGroupName = "acme.zacharyseguin.ca"
+
+// hetznerDefaultAPIURL is the public Hetzner DNS API endpoint.
+const hetznerDefaultAPIURL = "https://dns.hetzner.com/api/v1"
+
+// hetznerAPIKeySecretKey is the Secret key the upstream Hetzner webhook
+// reads its token from. It is not configurable there, so
+// HetznerDNS01.APIKeySecretRef.Key must name it.
+const hetznerAPIKeySecretKey = "api-key"
+
+func powerDNSServerID(cfg *gatewayv1alpha1.PowerDNSDNS01) string {
+	if cfg.ServerID == "" {
+		return "localhost"
+	}
+	return cfg.ServerID
+}
+
+func hetznerAPIURL(cfg *gatewayv1alpha1.HetznerDNS01) string {
+	if cfg.APIURL == "" {
+		return hetznerDefaultAPIURL
+	}
+	return strings.TrimSuffix(cfg.APIURL, "/")
+}
+
+// webhookSolver renders a cert-manager webhook DNS-01 solver for the
+// providers cert-manager has no in-tree support for. config is
+// marshalled into the opaque apiextensions JSON the webhook receives.
+func webhookSolver(groupName, solverName string, config map[string]any) (*cmacmev1.ACMEChallengeSolver, error) {
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s webhook solver config: %w", solverName, err)
+	}
+	return &cmacmev1.ACMEChallengeSolver{
+		DNS01: &cmacmev1.ACMEChallengeSolverDNS01{
+			Webhook: &cmacmev1.ACMEIssuerDNS01ProviderWebhook{
+				GroupName:  groupName,
+				SolverName: solverName,
+				Config:     &apiextensionsv1.JSON{Raw: raw},
+			},
+		},
+	}, nil
+}
+
 // renderWildcardCertificate builds the cert-manager Certificate that
 // covers <apex> and *.<apex>, plus per-child-apex SANs for every
 // tenant inheriting through this Gateway. Only used in DNS-01 mode;
@@ -419,11 +632,8 @@ func (r *Reconciler) renderWildcardCertificate(tgw *gatewayv1alpha1.TenantGatewa
 		},
 		Spec: cmv1.CertificateSpec{
 			SecretName: gatewayCertificateName(tgw),
-			IssuerRef: cmmetav1.ObjectReference{
-				Kind: "Issuer",
-				Name: gatewayIssuerName(tgw),
-			},
-			DNSNames: dnsNames,
+			IssuerRef:  certificateIssuerRef(tgw),
+			DNSNames:   dnsNames,
 		},
 	}
 	if err := controllerutil.SetControllerReference(tgw, cert, r.Scheme); err != nil {
@@ -513,11 +723,8 @@ func (r *Reconciler) renderPerListenerCertificate(tgw *gatewayv1alpha1.TenantGat
 		},
 		Spec: cmv1.CertificateSpec{
 			SecretName: name,
-			IssuerRef: cmmetav1.ObjectReference{
-				Kind: "Issuer",
-				Name: gatewayIssuerName(tgw),
-			},
-			DNSNames: []string{hostname},
+			IssuerRef:  certificateIssuerRef(tgw),
+			DNSNames:   []string{hostname},
 		},
 	}
 	if err := controllerutil.SetControllerReference(tgw, cert, r.Scheme); err != nil {
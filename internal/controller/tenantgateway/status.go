@@ -20,6 +20,9 @@ import (
 	"context"
 	"fmt"
 
+	cmv1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmetav1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -31,12 +34,23 @@ import (
 // reconcileStatus refreshes status.observedGeneration, status.listeners,
 // and the Ready condition on the TenantGateway based on the actual
 // state of the rendered Gateway (Gateway.Status.Listeners +
-// Gateway.Status.Conditions). Operators reading `kubectl get tgw`
-// see real readiness, not a fictional always-True flag.
+// Gateway.Status.Conditions) and of the Certificates its listeners
+// reference. Operators reading `kubectl get tgw` see real readiness,
+// not a fictional always-True flag.
+//
+// records is the outcome of reconcileDNSRecords; nil when record
+// publishing is off and nothing was left to withdraw.
+// policies is the PoliciesEnforced condition from
+// reconcileRoutePolicies; nil removes it.
+// clientValidation is the outcome of reconcileClientValidation keyed
+// by listener name.
 func (r *Reconciler) reconcileStatus(
 	ctx context.Context,
 	tgw *gatewayv1alpha1.TenantGateway,
 	dynHostnames []string,
+	records *dnsRecordsResult,
+	policies *metav1.Condition,
+	clientValidation map[string]clientValidationResult,
 ) error {
 	gw := &gatewayv1.Gateway{}
 	if err := r.Get(ctx, types.NamespacedName{Namespace: tgw.Namespace, Name: tgw.Name}, gw); err != nil {
@@ -46,6 +60,7 @@ func (r *Reconciler) reconcileStatus(
 	gwListenerStatus := indexListenerStatus(gw.Status.Listeners)
 
 	listeners := make([]gatewayv1alpha1.TenantGatewayListenerStatus, 0, len(gw.Spec.Listeners))
+	certs := map[string]certificateReadiness{}
 	allReady := true
 	for _, l := range gw.Spec.Listeners {
 		ready, reason := listenerReadinessFromGatewayStatus(string(l.Name), gwListenerStatus)
@@ -59,6 +74,34 @@ func (r *Reconciler) reconcileStatus(
 		}
 		if l.TLS != nil && len(l.TLS.CertificateRefs) > 0 {
 			s.CertificateName = string(l.TLS.CertificateRefs[0].Name)
+			cert, ok := certs[s.CertificateName]
+			if !ok {
+				var err error
+				cert, err = r.certificateReadiness(ctx, tgw, s.CertificateName)
+				if err != nil {
+					return err
+				}
+				certs[s.CertificateName] = cert
+			}
+			// An unissued certificate explains a not-ready listener
+			// better than the Gateway's ResolvedRefs fallout, so it
+			// takes precedence over the Gateway-reported reason.
+			if cert.managed && !cert.ready {
+				ready = false
+				s.Ready = false
+				s.Reason = cert.reason
+				s.CertificateMessage = cert.message
+			}
+		}
+		if records != nil && s.Hostname != "" {
+			if rec, ok := records.listeners[s.Hostname]; ok {
+				s.DNSRecord = rec.State
+				s.DNSRecordMessage = rec.Message
+			}
+		}
+		if cv, ok := clientValidation[s.Name]; ok {
+			s.ClientValidation = cv.state
+			s.ClientValidationMessage = cv.message
 		}
 		listeners = append(listeners, s)
 		if !ready {
@@ -107,7 +150,15 @@ func (r *Reconciler) reconcileStatus(
 	stale := tgw.DeepCopy()
 	stale.Status.ObservedGeneration = tgw.Generation
 	stale.Status.Listeners = listeners
+	if records != nil {
+		stale.Status.PublishedDNSRecords = records.published
+	}
 	meta.SetStatusCondition(&stale.Status.Conditions, ready)
+	if policies != nil {
+		meta.SetStatusCondition(&stale.Status.Conditions, *policies)
+	} else {
+		meta.RemoveStatusCondition(&stale.Status.Conditions, policiesEnforcedCondition)
+	}
 
 	if statusEqual(tgw.Status, stale.Status) {
 		return nil
@@ -116,6 +167,44 @@ func (r *Reconciler) reconcileStatus(
 	return r.Status().Update(ctx, tgw)
 }
 
+// certificateReadiness is the issuance state of the Certificate
+// backing a listener. managed is false when no Certificate owned by
+// the TenantGateway exists under that name — existingSecret mode, or
+// a Certificate not created yet — and the listener's readiness then
+// comes from the Gateway alone.
+type certificateReadiness struct {
+	managed bool
+	ready   bool
+	reason  string
+	message string
+}
+
+// certificateReadiness reads the Ready condition of the Certificate
+// whose Secret a listener references. Every Certificate this
+// controller renders shares its name with its Secret.
+func (r *Reconciler) certificateReadiness(ctx context.Context, tgw *gatewayv1alpha1.TenantGateway, name string) (certificateReadiness, error) {
+	cert := &cmv1.Certificate{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: tgw.Namespace, Name: name}, cert); err != nil {
+		if apierrors.IsNotFound(err) {
+			return certificateReadiness{}, nil
+		}
+		return certificateReadiness{}, fmt.Errorf("get Certificate %s for status: %w", name, err)
+	}
+	if !ownedByTenantGateway(cert.OwnerReferences, tgw) {
+		return certificateReadiness{}, nil
+	}
+	for _, c := range cert.Status.Conditions {
+		if c.Type != cmv1.CertificateConditionReady {
+			continue
+		}
+		if c.Status == cmmetav1.ConditionTrue {
+			return certificateReadiness{managed: true, ready: true}, nil
+		}
+		return certificateReadiness{managed: true, reason: "CertificateNotReady", message: c.Message}, nil
+	}
+	return certificateReadiness{managed: true, reason: "CertificatePending"}, nil
+}
+
 // indexListenerStatus turns Gateway.Status.Listeners into a name→status
 // map for O(1) lookup per spec listener.
 func indexListenerStatus(in []gatewayv1.ListenerStatus) map[string]gatewayv1.ListenerStatus {
@@ -184,6 +273,14 @@ func statusEqual(a, b gatewayv1alpha1.TenantGatewayStatus) bool {
 			return false
 		}
 	}
+	if len(a.PublishedDNSRecords) != len(b.PublishedDNSRecords) {
+		return false
+	}
+	for i := range a.PublishedDNSRecords {
+		if a.PublishedDNSRecords[i] != b.PublishedDNSRecords[i] {
+			return false
+		}
+	}
 	if len(a.Conditions) != len(b.Conditions) {
 		return false
 	}
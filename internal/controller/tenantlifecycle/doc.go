@@ -0,0 +1,43 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tenantlifecycle suspends, archives and schedules the deletion of
+// tenants.
+//
+// A tenant declares its state through the Tenant application's `lifecycle`
+// value, which the tenant chart renders as the namespace.cozystack.io/lifecycle
+// label on the tenant namespace. The state covers the whole subtree: a
+// namespace is held to the most restrictive state declared on itself or any
+// ancestor, so suspending a tenant also suspends its sub-tenants even though
+// their own HelmReleases are no longer reconciled.
+//
+//   - suspended: HelmReleases are suspended, Deployments and StatefulSets are
+//     scaled to zero and VirtualMachines halted. Applications run by an
+//     operator are stopped through their custom resources, which the
+//     operator would otherwise scale straight back up. Volumes are kept,
+//     and going back to active restores exactly what was changed.
+//   - archived: every application with a backup Plan gets a final BackupJob;
+//     the applications whose backup succeeded are deleted, everything else
+//     is held suspended.
+//   - deleting: the tenant is suspended and its Tenant application deleted
+//     once the grace period has passed. Setting the state back to active
+//     before then cancels the deletion.
+//
+// The controller reports what it has done in annotations on the namespace,
+// which the TenantNamespace view surfaces as status, and the aggregated API
+// refuses new applications in any namespace that is not active. The states
+// and the annotations are defined in pkg/tenantstate, which both read.
+package tenantlifecycle
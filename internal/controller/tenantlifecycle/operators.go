@@ -0,0 +1,240 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantlifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// suspendedFieldsAnnotation holds the values an operator resource had in
+// the fields suspend changed, as a JSON list of operatorFieldValue.
+const suspendedFieldsAnnotation = "lifecycle.cozystack.io/suspended-fields"
+
+// operatorApp is a custom resource whose operator owns the workloads of an
+// application, and would scale them straight back up if they were scaled
+// directly. It is stopped by setting fields on the resource itself.
+type operatorApp struct {
+	gvk    schema.GroupVersionKind
+	fields []operatorField
+	// releasesChildren is set for operators that stop reconciling a
+	// suspended resource without stopping its pods: the Deployments,
+	// StatefulSets and StrimziPodSets it controls are then stopped
+	// directly, which the paused operator no longer reverts.
+	releasesChildren bool
+	// childOnly resources are controlled by another operatorApp and are
+	// only stopped once that one has released them.
+	childOnly bool
+}
+
+// operatorField is a field set to value while the application is
+// suspended. Annotations are fields under metadata.annotations.
+type operatorField struct {
+	path  []string
+	value interface{}
+}
+
+// operatorApps are stopped in order and started in reverse, so a paused
+// owner is only resumed after its children have been restored.
+var operatorApps = []operatorApp{{
+	// CloudNativePG hibernation deletes the Postgres pods and keeps the
+	// volumes.
+	gvk:    schema.GroupVersionKind{Group: "postgresql.cnpg.io", Version: "v1", Kind: "Cluster"},
+	fields: []operatorField{{path: []string{"metadata", "annotations", "cnpg.io/hibernation"}, value: "on"}},
+}, {
+	gvk:              schema.GroupVersionKind{Group: "k8s.mariadb.com", Version: "v1alpha1", Kind: "MariaDB"},
+	fields:           []operatorField{{path: []string{"spec", "suspend"}, value: true}},
+	releasesChildren: true,
+}, {
+	gvk:              schema.GroupVersionKind{Group: "kafka.strimzi.io", Version: "v1beta2", Kind: "Kafka"},
+	fields:           []operatorField{{path: []string{"metadata", "annotations", "strimzi.io/pause-reconciliation"}, value: "true"}},
+	releasesChildren: true,
+}, {
+	// Strimzi runs brokers and ZooKeeper from StrimziPodSets, which have
+	// no replica count: emptying the pod list removes the pods.
+	gvk:       schema.GroupVersionKind{Group: "core.strimzi.io", Version: "v1beta2", Kind: "StrimziPodSet"},
+	fields:    []operatorField{{path: []string{"spec", "pods"}, value: []interface{}{}}},
+	childOnly: true,
+}, {
+	gvk: schema.GroupVersionKind{Group: "databases.spotahome.com", Version: "v1", Kind: "RedisFailover"},
+	fields: []operatorField{
+		{path: []string{"spec", "redis", "replicas"}, value: int64(0)},
+		{path: []string{"spec", "sentinel", "replicas"}, value: int64(0)},
+	},
+}, {
+	gvk:    schema.GroupVersionKind{Group: "clickhouse.altinity.com", Version: "v1", Kind: "ClickHouseInstallation"},
+	fields: []operatorField{{path: []string{"spec", "stop"}, value: "yes"}},
+}, {
+	gvk:    schema.GroupVersionKind{Group: "clickhouse-keeper.altinity.com", Version: "v1", Kind: "ClickHouseKeeperInstallation"},
+	fields: []operatorField{{path: []string{"spec", "stop"}, value: "yes"}},
+}, {
+	gvk:    schema.GroupVersionKind{Group: "rabbitmq.com", Version: "v1beta1", Kind: "RabbitmqCluster"},
+	fields: []operatorField{{path: []string{"spec", "replicas"}, value: int64(0)}},
+}, {
+	// A paused Cluster API cluster stops CAPK from restarting the
+	// VirtualMachines of its nodes, which are then halted like any other.
+	gvk:    schema.GroupVersionKind{Group: "cluster.x-k8s.io", Version: "v1beta1", Kind: "Cluster"},
+	fields: []operatorField{{path: []string{"spec", "paused"}, value: true}},
+}}
+
+// operatorFieldValue is a field's value from before suspend; a nil Value
+// means the field was not set.
+type operatorFieldValue struct {
+	Path  []string        `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// suspendOperatorApps stops every operator-managed application in a
+// namespace and returns the resources that released their children.
+func (r *Reconciler) suspendOperatorApps(ctx context.Context, namespace string) (map[types.UID]bool, error) {
+	released := map[types.UID]bool{}
+	for _, app := range operatorApps {
+		items, err := r.listUnstructured(ctx, namespace, app.gvk)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			obj := &items[i]
+			if app.childOnly && !releasedBy(obj, released) {
+				continue
+			}
+			if err := r.suspendOperatorApp(ctx, app, obj); err != nil {
+				return nil, err
+			}
+			if app.releasesChildren {
+				released[obj.GetUID()] = true
+			}
+		}
+	}
+	return released, nil
+}
+
+// suspendOperatorApp sets app's suspended values on obj. Values from
+// before are only recorded the first time, so a later pass re-applies the
+// suspension without overwriting them.
+func (r *Reconciler) suspendOperatorApp(ctx context.Context, app operatorApp, obj *unstructured.Unstructured) error {
+	before := obj.DeepCopy()
+	if _, ok := obj.GetAnnotations()[suspendedFieldsAnnotation]; !ok {
+		prior := make([]operatorFieldValue, 0, len(app.fields))
+		for _, f := range app.fields {
+			v := operatorFieldValue{Path: f.path}
+			if value, found, _ := unstructured.NestedFieldNoCopy(obj.Object, f.path...); found {
+				raw, err := json.Marshal(value)
+				if err != nil {
+					return err
+				}
+				v.Value = raw
+			}
+			prior = append(prior, v)
+		}
+		raw, err := json.Marshal(prior)
+		if err != nil {
+			return err
+		}
+		setAnnotation(obj, suspendedFieldsAnnotation, string(raw))
+	}
+	for _, f := range app.fields {
+		if err := unstructured.SetNestedField(obj.Object, f.value, f.path...); err != nil {
+			return err
+		}
+	}
+	if equality.Semantic.DeepEqual(before.Object, obj.Object) {
+		return nil
+	}
+	if err := r.Patch(ctx, obj, client.MergeFrom(before)); err != nil {
+		return fmt.Errorf("suspend %s %s/%s: %w", app.gvk.Kind, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return nil
+}
+
+// resumeOperatorApps restores the fields suspend changed, children before
+// the resources that own them.
+func (r *Reconciler) resumeOperatorApps(ctx context.Context, namespace string) error {
+	for i := len(operatorApps) - 1; i >= 0; i-- {
+		app := operatorApps[i]
+		items, err := r.listUnstructured(ctx, namespace, app.gvk)
+		if err != nil {
+			return err
+		}
+		for j := range items {
+			if err := r.resumeOperatorApp(ctx, app, &items[j]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) resumeOperatorApp(ctx context.Context, app operatorApp, obj *unstructured.Unstructured) error {
+	raw, ok := obj.GetAnnotations()[suspendedFieldsAnnotation]
+	if !ok {
+		return nil
+	}
+	patch := client.MergeFrom(obj.DeepCopy())
+	var prior []operatorFieldValue
+	if err := json.Unmarshal([]byte(raw), &prior); err != nil {
+		return fmt.Errorf("resume %s %s/%s: parse %s: %w", app.gvk.Kind, obj.GetNamespace(), obj.GetName(), suspendedFieldsAnnotation, err)
+	}
+	for _, v := range prior {
+		if v.Value == nil {
+			unstructured.RemoveNestedField(obj.Object, v.Path...)
+			continue
+		}
+		var value interface{}
+		if err := utiljson.Unmarshal(v.Value, &value); err != nil {
+			return fmt.Errorf("resume %s %s/%s: parse %s: %w", app.gvk.Kind, obj.GetNamespace(), obj.GetName(), suspendedFieldsAnnotation, err)
+		}
+		if err := unstructured.SetNestedField(obj.Object, value, v.Path...); err != nil {
+			return err
+		}
+	}
+	setAnnotation(obj, suspendedFieldsAnnotation, "")
+	if err := r.Patch(ctx, obj, patch); err != nil {
+		return fmt.Errorf("resume %s %s/%s: %w", app.gvk.Kind, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return nil
+}
+
+// releasedBy reports whether obj is controlled by one of the released
+// resources.
+func releasedBy(obj metav1.Object, released map[types.UID]bool) bool {
+	owner := metav1.GetControllerOf(obj)
+	return owner != nil && released[owner.UID]
+}
+
+// listUnstructured lists the objects of a kind in a namespace, none where
+// the kind is not installed.
+func (r *Reconciler) listUnstructured(ctx context.Context, namespace string, gvk schema.GroupVersionKind) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	err := r.reader().List(ctx, list, client.InNamespace(namespace))
+	if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return list.Items, err
+}
@@ -0,0 +1,686 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantlifecycle
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	backupsv1alpha1 "github.com/cozystack/cozystack/api/backups/v1alpha1"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+	"github.com/cozystack/cozystack/pkg/tenantstate"
+)
+
+const (
+	// ArchiveStartedAnnotation records when archiving began, in Unix
+	// seconds; the final BackupJobs are named after it so a later archive
+	// never mistakes an earlier one's backups for its own.
+	ArchiveStartedAnnotation = "namespace.cozystack.io/archive-started"
+
+	// suspendedAnnotation marks a HelmRelease this controller suspended, so
+	// resuming never touches one an operator suspended by hand.
+	suspendedAnnotation = "lifecycle.cozystack.io/suspended"
+	// replicasAnnotation holds a workload's replica count from before it
+	// was scaled to zero.
+	replicasAnnotation = "lifecycle.cozystack.io/replicas"
+	// runStrategyAnnotation holds a VirtualMachine's run strategy from
+	// before it was halted.
+	runStrategyAnnotation = "lifecycle.cozystack.io/run-strategy"
+	// archiveJobLabel marks the final BackupJobs of an archive.
+	archiveJobLabel = "lifecycle.cozystack.io/archive"
+
+	tenantKind = "Tenant"
+
+	// DefaultGracePeriod is how long a deleting tenant waits before its
+	// Tenant application is deleted.
+	DefaultGracePeriod = 72 * time.Hour
+	// resyncInterval re-applies a non-active state, catching anything that
+	// was started or resumed behind the controller's back.
+	resyncInterval = 10 * time.Minute
+	// archivePollInterval is how often running final backups are checked.
+	archivePollInterval = 30 * time.Second
+)
+
+var virtualMachineGVK = schema.GroupVersionKind{Group: "kubevirt.io", Version: "v1", Kind: "VirtualMachine"}
+
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=helm.toolkit.fluxcd.io,resources=helmreleases,verbs=get;list;watch;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;patch
+// +kubebuilder:rbac:groups=kubevirt.io,resources=virtualmachines,verbs=get;list;patch
+// +kubebuilder:rbac:groups=postgresql.cnpg.io,resources=clusters,verbs=get;list;patch
+// +kubebuilder:rbac:groups=k8s.mariadb.com,resources=mariadbs,verbs=get;list;patch
+// +kubebuilder:rbac:groups=kafka.strimzi.io,resources=kafkas,verbs=get;list;patch
+// +kubebuilder:rbac:groups=core.strimzi.io,resources=strimzipodsets,verbs=get;list;patch
+// +kubebuilder:rbac:groups=databases.spotahome.com,resources=redisfailovers,verbs=get;list;patch
+// +kubebuilder:rbac:groups=clickhouse.altinity.com,resources=clickhouseinstallations,verbs=get;list;patch
+// +kubebuilder:rbac:groups=clickhouse-keeper.altinity.com,resources=clickhousekeeperinstallations,verbs=get;list;patch
+// +kubebuilder:rbac:groups=rabbitmq.com,resources=rabbitmqclusters,verbs=get;list;patch
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;patch
+// +kubebuilder:rbac:groups=backups.cozystack.io,resources=plans,verbs=get;list
+// +kubebuilder:rbac:groups=backups.cozystack.io,resources=backupjobs,verbs=get;list;create
+
+// Reconciler brings each tenant namespace to the lifecycle state it is held
+// to.
+type Reconciler struct {
+	client.Client
+	// Reader lists workloads, backup Plans and BackupJobs uncached, so the
+	// controller keeps no cluster-wide informer for them. Nil uses Client.
+	Reader   client.Reader
+	Recorder record.EventRecorder
+	// GracePeriod overrides DefaultGracePeriod.
+	GracePeriod time.Duration
+	// now stubs the clock in tests.
+	now func() time.Time
+}
+
+func (r *Reconciler) reader() client.Reader {
+	if r.Reader != nil {
+		return r.Reader
+	}
+	return r.Client
+}
+
+func (r *Reconciler) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+func (r *Reconciler) gracePeriod() time.Duration {
+	if r.GracePeriod > 0 {
+		return r.GracePeriod
+	}
+	return DefaultGracePeriod
+}
+
+// Reconcile applies the effective state of one tenant namespace. A namespace
+// that is active and was never acted on is left alone.
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: req.Name}, ns); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !ns.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	state, from := tenantstate.Effective(ns.Name, r.labelsOf(ctx))
+	prev := tenantstate.Phase(ns.Annotations[tenantstate.PhaseAnnotation])
+	if state == tenantstate.Active && prev == "" {
+		return ctrl.Result{}, nil
+	}
+
+	st := status{}
+	result := ctrl.Result{RequeueAfter: resyncInterval}
+	var err error
+	switch state {
+	case tenantstate.Active:
+		err = r.resume(ctx, ns.Name)
+		result = ctrl.Result{}
+	case tenantstate.Suspended:
+		st.phase = tenantstate.PhaseSuspended
+		if from != ns.Name {
+			st.message = "suspended with " + from
+		}
+		err = r.suspend(ctx, ns.Name)
+	case tenantstate.Archived:
+		st, result, err = r.archive(ctx, ns)
+	case tenantstate.Deleting:
+		st, result, err = r.scheduleDeletion(ctx, ns, from)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.writeStatus(ctx, ns, st); err != nil {
+		return ctrl.Result{}, err
+	}
+	if st.phase != prev {
+		r.recordTransition(ns, prev, st)
+	}
+	return result, nil
+}
+
+// status is what the controller reports on a namespace; the zero value
+// clears every lifecycle annotation.
+type status struct {
+	phase          tenantstate.Phase
+	message        string
+	deletionAt     string
+	archiveStarted string
+}
+
+func (r *Reconciler) writeStatus(ctx context.Context, ns *corev1.Namespace, st status) error {
+	want := map[string]string{
+		tenantstate.PhaseAnnotation:      string(st.phase),
+		tenantstate.MessageAnnotation:    st.message,
+		tenantstate.DeletionAtAnnotation: st.deletionAt,
+		ArchiveStartedAnnotation:         st.archiveStarted,
+	}
+	changed := false
+	for k, v := range want {
+		if ns.Annotations[k] != v {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	patch := client.MergeFrom(ns.DeepCopy())
+	if ns.Annotations == nil {
+		ns.Annotations = map[string]string{}
+	}
+	for k, v := range want {
+		if v == "" {
+			delete(ns.Annotations, k)
+		} else {
+			ns.Annotations[k] = v
+		}
+	}
+	return r.Patch(ctx, ns, patch)
+}
+
+func (r *Reconciler) recordTransition(ns *corev1.Namespace, prev tenantstate.Phase, st status) {
+	if r.Recorder == nil {
+		return
+	}
+	switch {
+	case st.phase == "" && prev == tenantstate.PhasePendingDeletion:
+		r.Recorder.Event(ns, corev1.EventTypeNormal, "DeletionCancelled", "tenant deletion cancelled; workloads resumed")
+	case st.phase == "":
+		r.Recorder.Event(ns, corev1.EventTypeNormal, "Resumed", "tenant is active again; workloads resumed")
+	case st.phase == tenantstate.PhasePendingDeletion && st.deletionAt != "":
+		r.Recorder.Eventf(ns, corev1.EventTypeNormal, "DeletionScheduled", "tenant suspended, deletion scheduled for %s", st.deletionAt)
+	default:
+		msg := "tenant is " + strings.ToLower(string(st.phase))
+		if st.message != "" {
+			msg += ": " + st.message
+		}
+		r.Recorder.Event(ns, corev1.EventTypeNormal, string(st.phase), msg)
+	}
+}
+
+func (r *Reconciler) labelsOf(ctx context.Context) func(string) map[string]string {
+	return func(name string) map[string]string {
+		ns := &corev1.Namespace{}
+		if err := r.Get(ctx, client.ObjectKey{Name: name}, ns); err != nil {
+			return nil
+		}
+		return ns.Labels
+	}
+}
+
+// suspend stops everything running in a namespace: HelmReleases first, so
+// Flux does not scale the workloads straight back up, then the resources of
+// operator-managed applications, so their operators do not either.
+func (r *Reconciler) suspend(ctx context.Context, namespace string) error {
+	releases := &helmv2.HelmReleaseList{}
+	if err := r.List(ctx, releases, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for i := range releases.Items {
+		hr := &releases.Items[i]
+		if hr.Spec.Suspend {
+			continue
+		}
+		patch := client.MergeFrom(hr.DeepCopy())
+		hr.Spec.Suspend = true
+		metav1.SetMetaDataAnnotation(&hr.ObjectMeta, suspendedAnnotation, "true")
+		if err := r.Patch(ctx, hr, patch); err != nil {
+			return fmt.Errorf("suspend HelmRelease %s/%s: %w", namespace, hr.Name, err)
+		}
+	}
+
+	released, err := r.suspendOperatorApps(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	workloads, err := r.workloads(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	for _, w := range workloads {
+		_, marked := w.GetAnnotations()[replicasAnnotation]
+		n := replicas(w)
+		// Workloads of an operator still reconciling them are stopped
+		// through its resource above.
+		if n == 0 || marked || (metav1.GetControllerOf(w) != nil && !releasedBy(w, released)) {
+			continue
+		}
+		patch := client.MergeFrom(w.DeepCopyObject().(client.Object))
+		setReplicas(w, 0)
+		metav1.SetMetaDataAnnotation(objectMeta(w), replicasAnnotation, strconv.Itoa(int(n)))
+		if err := r.Patch(ctx, w, patch); err != nil {
+			return fmt.Errorf("scale down %s/%s: %w", namespace, w.GetName(), err)
+		}
+	}
+
+	vms, err := r.virtualMachines(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	for i := range vms {
+		vm := &vms[i]
+		if _, ok := vm.GetAnnotations()[runStrategyAnnotation]; ok {
+			continue
+		}
+		strategy, _, _ := unstructured.NestedString(vm.Object, "spec", "runStrategy")
+		if strategy == "" {
+			strategy = "Halted"
+			if running, _, _ := unstructured.NestedBool(vm.Object, "spec", "running"); running {
+				strategy = "Always"
+			}
+		}
+		if strategy == "Halted" {
+			continue
+		}
+		patch := client.MergeFrom(vm.DeepCopy())
+		unstructured.RemoveNestedField(vm.Object, "spec", "running")
+		if err := unstructured.SetNestedField(vm.Object, "Halted", "spec", "runStrategy"); err != nil {
+			return err
+		}
+		setAnnotation(vm, runStrategyAnnotation, strategy)
+		if err := r.Patch(ctx, vm, patch); err != nil {
+			return fmt.Errorf("halt VirtualMachine %s/%s: %w", namespace, vm.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// resume undoes suspend: workloads first, then the operator resources and
+// the HelmReleases this controller suspended.
+func (r *Reconciler) resume(ctx context.Context, namespace string) error {
+	vms, err := r.virtualMachines(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	for i := range vms {
+		vm := &vms[i]
+		strategy, ok := vm.GetAnnotations()[runStrategyAnnotation]
+		if !ok {
+			continue
+		}
+		patch := client.MergeFrom(vm.DeepCopy())
+		if err := unstructured.SetNestedField(vm.Object, strategy, "spec", "runStrategy"); err != nil {
+			return err
+		}
+		setAnnotation(vm, runStrategyAnnotation, "")
+		if err := r.Patch(ctx, vm, patch); err != nil {
+			return fmt.Errorf("restart VirtualMachine %s/%s: %w", namespace, vm.GetName(), err)
+		}
+	}
+
+	workloads, err := r.workloads(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	for _, w := range workloads {
+		raw, ok := objectMeta(w).Annotations[replicasAnnotation]
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			n = 1
+		}
+		patch := client.MergeFrom(w.DeepCopyObject().(client.Object))
+		setReplicas(w, int32(n))
+		delete(objectMeta(w).Annotations, replicasAnnotation)
+		if err := r.Patch(ctx, w, patch); err != nil {
+			return fmt.Errorf("scale up %s/%s: %w", namespace, w.GetName(), err)
+		}
+	}
+
+	if err := r.resumeOperatorApps(ctx, namespace); err != nil {
+		return err
+	}
+	return r.resumeReleases(ctx, namespace)
+}
+
+func (r *Reconciler) resumeReleases(ctx context.Context, namespace string) error {
+	releases := &helmv2.HelmReleaseList{}
+	if err := r.List(ctx, releases, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for i := range releases.Items {
+		if err := r.resumeRelease(ctx, &releases.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resumeRelease lifts a suspension this controller put on hr. Flux skips
+// the Helm uninstall of a suspended release, so this also has to happen
+// before such a release is deleted.
+func (r *Reconciler) resumeRelease(ctx context.Context, hr *helmv2.HelmRelease) error {
+	if !metav1.HasAnnotation(hr.ObjectMeta, suspendedAnnotation) {
+		return nil
+	}
+	patch := client.MergeFrom(hr.DeepCopy())
+	hr.Spec.Suspend = false
+	delete(hr.Annotations, suspendedAnnotation)
+	if err := r.Patch(ctx, hr, patch); err != nil {
+		return fmt.Errorf("resume HelmRelease %s/%s: %w", hr.Namespace, hr.Name, err)
+	}
+	return nil
+}
+
+// archive takes a final backup of every application with a backup Plan,
+// deletes the applications whose backup succeeded once all have finished,
+// and holds the rest suspended.
+func (r *Reconciler) archive(ctx context.Context, ns *corev1.Namespace) (status, ctrl.Result, error) {
+	st := status{phase: tenantstate.PhaseArchiving, archiveStarted: ns.Annotations[ArchiveStartedAnnotation]}
+	if st.archiveStarted == "" {
+		st.archiveStarted = strconv.FormatInt(r.clock().Unix(), 10)
+	}
+
+	plans := &backupsv1alpha1.PlanList{}
+	if err := r.reader().List(ctx, plans, client.InNamespace(ns.Name)); err != nil && !meta.IsNoMatchError(err) {
+		return status{}, ctrl.Result{}, err
+	}
+	backedUp := map[string]bool{}
+	var failed []string
+	running := 0
+	for i := range plans.Items {
+		plan := &plans.Items[i]
+		ref := plan.Spec.ApplicationRef
+		app := ref.Kind + "/" + ref.Name
+		job, err := r.ensureArchiveJob(ctx, plan, st.archiveStarted)
+		if err != nil {
+			return status{}, ctrl.Result{}, err
+		}
+		switch job.Status.Phase {
+		case backupsv1alpha1.BackupJobPhaseSucceeded:
+			backedUp[app] = true
+		case backupsv1alpha1.BackupJobPhaseFailed:
+			failed = append(failed, app)
+		default:
+			running++
+		}
+	}
+	if running > 0 {
+		st.message = fmt.Sprintf("waiting for %d final backup(s)", running)
+		return st, ctrl.Result{RequeueAfter: archivePollInterval}, nil
+	}
+
+	releases := &helmv2.HelmReleaseList{}
+	if err := r.List(ctx, releases, client.InNamespace(ns.Name)); err != nil {
+		return status{}, ctrl.Result{}, err
+	}
+	var kept []string
+	for i := range releases.Items {
+		hr := &releases.Items[i]
+		kind, name := hr.Labels[appsv1alpha1.ApplicationKindLabel], hr.Labels[appsv1alpha1.ApplicationNameLabel]
+		if kind == "" || kind == tenantKind {
+			continue
+		}
+		if !backedUp[kind+"/"+name] {
+			kept = append(kept, kind+"/"+name)
+			continue
+		}
+		if err := r.resumeRelease(ctx, hr); err != nil {
+			return status{}, ctrl.Result{}, err
+		}
+		if err := r.Delete(ctx, hr); client.IgnoreNotFound(err) != nil {
+			return status{}, ctrl.Result{}, fmt.Errorf("delete archived application %s/%s: %w", ns.Name, hr.Name, err)
+		}
+	}
+	if err := r.suspend(ctx, ns.Name); err != nil {
+		return status{}, ctrl.Result{}, err
+	}
+
+	st.phase = tenantstate.PhaseArchived
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		st.message = "final backup failed for " + strings.Join(failed, ", ") + "; "
+	}
+	if len(kept) > 0 {
+		sort.Strings(kept)
+		st.message += "kept suspended without a final backup: " + strings.Join(kept, ", ")
+	}
+	st.message = strings.TrimSuffix(st.message, "; ")
+	return st, ctrl.Result{RequeueAfter: resyncInterval}, nil
+}
+
+func (r *Reconciler) ensureArchiveJob(ctx context.Context, plan *backupsv1alpha1.Plan, started string) (*backupsv1alpha1.BackupJob, error) {
+	job := &backupsv1alpha1.BackupJob{}
+	key := client.ObjectKey{Namespace: plan.Namespace, Name: fmt.Sprintf("archive-%s-%s", plan.Name, started)}
+	err := r.reader().Get(ctx, key, job)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return job, err
+	}
+	job = &backupsv1alpha1.BackupJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: key.Namespace,
+			Name:      key.Name,
+			Labels:    map[string]string{archiveJobLabel: started},
+		},
+		Spec: backupsv1alpha1.BackupJobSpec{
+			ApplicationRef:  plan.Spec.ApplicationRef,
+			BackupClassName: plan.Spec.BackupClassName,
+		},
+	}
+	if err := r.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("create final BackupJob %s/%s: %w", key.Namespace, key.Name, err)
+	}
+	return job, nil
+}
+
+// scheduleDeletion suspends a deleting tenant and, once the grace period
+// has passed, deletes its Tenant application. A namespace held to the state
+// by an ancestor only waits for that ancestor's deletion.
+func (r *Reconciler) scheduleDeletion(ctx context.Context, ns *corev1.Namespace, from string) (status, ctrl.Result, error) {
+	if err := r.suspend(ctx, ns.Name); err != nil {
+		return status{}, ctrl.Result{}, err
+	}
+	st := status{phase: tenantstate.PhasePendingDeletion}
+	release, ok := tenantRelease(ns.Name)
+	if from != ns.Name || !ok {
+		st.message = "deletion of " + from + " is scheduled"
+		if !ok {
+			st.message = ns.Name + " cannot be deleted"
+		}
+		return st, ctrl.Result{RequeueAfter: resyncInterval}, nil
+	}
+
+	now := r.clock()
+	at, err := time.Parse(time.RFC3339, ns.Annotations[tenantstate.DeletionAtAnnotation])
+	if err != nil {
+		at = now.Add(r.gracePeriod()).UTC().Truncate(time.Second)
+	}
+	st.deletionAt = at.Format(time.RFC3339)
+	if now.Before(at) {
+		st.message = "set lifecycle back to active to cancel"
+		return st, ctrl.Result{RequeueAfter: min(at.Sub(now), resyncInterval)}, nil
+	}
+
+	// Flux only uninstalls releases that are not suspended, so lift the
+	// suspensions across the subtree before the tenant chart tears it down.
+	subtree, err := r.subtree(ctx, ns.Name)
+	if err != nil {
+		return status{}, ctrl.Result{}, err
+	}
+	for _, name := range subtree {
+		if err := r.resumeReleases(ctx, name); err != nil {
+			return status{}, ctrl.Result{}, err
+		}
+	}
+	hr := &helmv2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Namespace: release.Namespace, Name: release.Name}}
+	if err := r.Delete(ctx, hr); client.IgnoreNotFound(err) != nil {
+		return status{}, ctrl.Result{}, fmt.Errorf("delete tenant %s: %w", ns.Name, err)
+	}
+	log.FromContext(ctx).Info("deleted tenant after grace period", "namespace", ns.Name, "release", release)
+	st.message = "grace period over; tenant is being deleted"
+	return st, ctrl.Result{RequeueAfter: resyncInterval}, nil
+}
+
+// tenantRelease names the HelmRelease of the Tenant application owning a
+// namespace: tenant-foo is HelmRelease tenant-foo in tenant-root, and
+// tenant-foo-bar is tenant-bar in tenant-foo. tenant-root has none.
+func tenantRelease(namespace string) (client.ObjectKey, bool) {
+	lineage := tenantstate.Lineage(namespace)
+	if len(lineage) < 2 {
+		return client.ObjectKey{}, false
+	}
+	parent := lineage[1]
+	if parent == tenantstate.RootTenant {
+		return client.ObjectKey{Namespace: parent, Name: namespace}, true
+	}
+	return client.ObjectKey{Namespace: parent, Name: tenantstate.TenantPrefix + strings.TrimPrefix(namespace, parent+"-")}, true
+}
+
+// subtree returns a namespace and all of its descendants.
+func (r *Reconciler) subtree(ctx context.Context, namespace string) ([]string, error) {
+	list := &corev1.NamespaceList{}
+	if err := r.List(ctx, list); err != nil {
+		return nil, err
+	}
+	out := []string{namespace}
+	for i := range list.Items {
+		if strings.HasPrefix(list.Items[i].Name, namespace+"-") {
+			out = append(out, list.Items[i].Name)
+		}
+	}
+	return out, nil
+}
+
+// workloads lists the Deployments and StatefulSets of a namespace.
+func (r *Reconciler) workloads(ctx context.Context, namespace string) ([]client.Object, error) {
+	deployments := &appsv1.DeploymentList{}
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.reader().List(ctx, deployments, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	if err := r.reader().List(ctx, statefulSets, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	out := make([]client.Object, 0, len(deployments.Items)+len(statefulSets.Items))
+	for i := range deployments.Items {
+		out = append(out, &deployments.Items[i])
+	}
+	for i := range statefulSets.Items {
+		out = append(out, &statefulSets.Items[i])
+	}
+	return out, nil
+}
+
+// virtualMachines lists the KubeVirt VirtualMachines of a namespace, none
+// where KubeVirt is not installed.
+func (r *Reconciler) virtualMachines(ctx context.Context, namespace string) ([]unstructured.Unstructured, error) {
+	return r.listUnstructured(ctx, namespace, virtualMachineGVK)
+}
+
+func objectMeta(w client.Object) *metav1.ObjectMeta {
+	switch o := w.(type) {
+	case *appsv1.Deployment:
+		return &o.ObjectMeta
+	case *appsv1.StatefulSet:
+		return &o.ObjectMeta
+	}
+	panic(fmt.Sprintf("unexpected workload %T", w))
+}
+
+// replicas returns a workload's desired replicas; unset means one.
+func replicas(w client.Object) int32 {
+	var n *int32
+	switch o := w.(type) {
+	case *appsv1.Deployment:
+		n = o.Spec.Replicas
+	case *appsv1.StatefulSet:
+		n = o.Spec.Replicas
+	}
+	if n == nil {
+		return 1
+	}
+	return *n
+}
+
+func setReplicas(w client.Object, n int32) {
+	switch o := w.(type) {
+	case *appsv1.Deployment:
+		o.Spec.Replicas = &n
+	case *appsv1.StatefulSet:
+		o.Spec.Replicas = &n
+	}
+}
+
+// setAnnotation sets an annotation on u, or removes it when value is empty.
+func setAnnotation(u *unstructured.Unstructured, key, value string) {
+	annotations := u.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if value == "" {
+		delete(annotations, key)
+	} else {
+		annotations[key] = value
+	}
+	u.SetAnnotations(annotations)
+}
+
+// SetupWithManager wires the controller. A namespace change also requeues
+// its descendants, since they are held to the states of their ancestors.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	subtree := handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+		names, err := r.subtree(ctx, o.GetName())
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to list descendant namespaces", "namespace", o.GetName())
+			names = []string{o.GetName()}
+		}
+		out := make([]reconcile.Request, 0, len(names))
+		for _, name := range names {
+			out = append(out, reconcile.Request{NamespacedName: client.ObjectKey{Name: name}})
+		}
+		return out
+	})
+	tenantNamespaces := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return strings.HasPrefix(o.GetName(), tenantstate.TenantPrefix)
+	})
+	lifecycleChanged := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.ObjectOld.GetLabels()[tenantstate.LifecycleLabel] != e.ObjectNew.GetLabels()[tenantstate.LifecycleLabel]
+		},
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("tenantlifecycle-controller").
+		Watches(&corev1.Namespace{}, subtree, builder.WithPredicates(tenantNamespaces, lifecycleChanged)).
+		Complete(r)
+}
@@ -0,0 +1,391 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantlifecycle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	backupsv1alpha1 "github.com/cozystack/cozystack/api/backups/v1alpha1"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+	"github.com/cozystack/cozystack/pkg/tenantstate"
+)
+
+var t0 = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newReconciler(t *testing.T, objs ...client.Object) (*Reconciler, client.Client, *time.Time) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme, helmv2.AddToScheme, backupsv1alpha1.AddToScheme,
+	} {
+		if err := add(scheme); err != nil {
+			t.Fatalf("register scheme: %v", err)
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	now := t0
+	return &Reconciler{Client: c, now: func() time.Time { return now }}, c, &now
+}
+
+func namespace(name string, state tenantstate.State) *corev1.Namespace {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if state != "" {
+		ns.Labels = map[string]string{tenantstate.LifecycleLabel: string(state)}
+	}
+	return ns
+}
+
+func release(ns, name, kind, app string) *helmv2.HelmRelease {
+	return &helmv2.HelmRelease{ObjectMeta: metav1.ObjectMeta{
+		Namespace: ns, Name: name,
+		Labels: map[string]string{
+			appsv1alpha1.ApplicationKindLabel: kind,
+			appsv1alpha1.ApplicationNameLabel: app,
+		},
+	}}
+}
+
+func deployment(ns, name string, replicas int32, owner *metav1.OwnerReference) *appsv1.Deployment {
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(replicas)},
+	}
+	if owner != nil {
+		d.OwnerReferences = []metav1.OwnerReference{*owner}
+	}
+	return d
+}
+
+func reconcileNS(t *testing.T, r *Reconciler, name string) ctrl.Result {
+	t.Helper()
+	res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: name}})
+	if err != nil {
+		t.Fatalf("reconcile %s: %v", name, err)
+	}
+	return res
+}
+
+func setState(t *testing.T, c client.Client, name string, state tenantstate.State) {
+	t.Helper()
+	ns := &corev1.Namespace{}
+	if err := c.Get(context.Background(), client.ObjectKey{Name: name}, ns); err != nil {
+		t.Fatalf("get namespace: %v", err)
+	}
+	ns.Labels = map[string]string{tenantstate.LifecycleLabel: string(state)}
+	if err := c.Update(context.Background(), ns); err != nil {
+		t.Fatalf("update namespace: %v", err)
+	}
+}
+
+func annotations(t *testing.T, c client.Client, name string) map[string]string {
+	t.Helper()
+	ns := &corev1.Namespace{}
+	if err := c.Get(context.Background(), client.ObjectKey{Name: name}, ns); err != nil {
+		t.Fatalf("get namespace: %v", err)
+	}
+	return ns.Annotations
+}
+
+func TestReconcile_SuspendAndResume(t *testing.T) {
+	operator := &metav1.OwnerReference{APIVersion: "example.io/v1", Kind: "Cluster", Name: "db", UID: "1", Controller: ptr.To(true)}
+	r, c, _ := newReconciler(t,
+		namespace("tenant-foo", tenantstate.Suspended), namespace("tenant-foo-bar", ""),
+		release("tenant-foo", "redis-cache", "Redis", "cache"),
+		deployment("tenant-foo", "web", 3, nil),
+		deployment("tenant-foo", "db-pooler", 2, operator),
+		deployment("tenant-foo-bar", "api", 1, nil),
+	)
+	ctx := context.Background()
+	reconcileNS(t, r, "tenant-foo")
+	reconcileNS(t, r, "tenant-foo-bar")
+
+	hr := &helmv2.HelmRelease{}
+	_ = c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo", Name: "redis-cache"}, hr)
+	if !hr.Spec.Suspend {
+		t.Errorf("HelmRelease must be suspended")
+	}
+	web := &appsv1.Deployment{}
+	_ = c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo", Name: "web"}, web)
+	if *web.Spec.Replicas != 0 || web.Annotations[replicasAnnotation] != "3" {
+		t.Errorf("web replicas=%d annotations=%v, want 0 remembering 3", *web.Spec.Replicas, web.Annotations)
+	}
+	pooler := &appsv1.Deployment{}
+	_ = c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo", Name: "db-pooler"}, pooler)
+	if *pooler.Spec.Replicas != 2 {
+		t.Errorf("operator-managed deployment must be left to its operator")
+	}
+	api := &appsv1.Deployment{}
+	_ = c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo-bar", Name: "api"}, api)
+	if *api.Spec.Replicas != 0 {
+		t.Errorf("a sub-tenant must be suspended with its parent")
+	}
+	if got := annotations(t, c, "tenant-foo-bar"); got[tenantstate.PhaseAnnotation] != string(tenantstate.PhaseSuspended) || got[tenantstate.MessageAnnotation] != "suspended with tenant-foo" {
+		t.Errorf("child annotations = %v", got)
+	}
+
+	setState(t, c, "tenant-foo", tenantstate.Active)
+	reconcileNS(t, r, "tenant-foo")
+	_ = c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo", Name: "redis-cache"}, hr)
+	_ = c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo", Name: "web"}, web)
+	if hr.Spec.Suspend || *web.Spec.Replicas != 3 {
+		t.Errorf("resume: suspend=%v replicas=%d, want false/3", hr.Spec.Suspend, *web.Spec.Replicas)
+	}
+	if _, ok := web.Annotations[replicasAnnotation]; ok {
+		t.Errorf("replicas annotation must be removed on resume")
+	}
+	if got := annotations(t, c, "tenant-foo"); len(got) != 0 {
+		t.Errorf("active namespace annotations = %v, want none", got)
+	}
+}
+
+func TestReconcile_DeletionAfterGracePeriod(t *testing.T) {
+	r, c, now := newReconciler(t,
+		namespace("tenant-root", ""), namespace("tenant-foo", tenantstate.Deleting),
+		release("tenant-root", "tenant-foo", "Tenant", "foo"),
+		release("tenant-foo", "redis-cache", "Redis", "cache"),
+	)
+	r.GracePeriod = 5 * time.Minute
+	ctx := context.Background()
+
+	res := reconcileNS(t, r, "tenant-foo")
+	if got := annotations(t, c, "tenant-foo")[tenantstate.DeletionAtAnnotation]; got != "2026-03-01T12:05:00Z" {
+		t.Fatalf("deletion-at = %q, want one grace period out", got)
+	}
+	if res.RequeueAfter != 5*time.Minute {
+		t.Errorf("requeue = %s, want the remaining grace period", res.RequeueAfter)
+	}
+
+	*now = t0.Add(2 * time.Hour)
+	reconcileNS(t, r, "tenant-foo")
+	err := c.Get(ctx, client.ObjectKey{Namespace: "tenant-root", Name: "tenant-foo"}, &helmv2.HelmRelease{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("tenant HelmRelease after the grace period: err = %v, want NotFound", err)
+	}
+	hr := &helmv2.HelmRelease{}
+	_ = c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo", Name: "redis-cache"}, hr)
+	if hr.Spec.Suspend {
+		t.Errorf("releases must be resumed so Flux uninstalls them")
+	}
+}
+
+func TestReconcile_DeletionCancelled(t *testing.T) {
+	r, c, _ := newReconciler(t,
+		namespace("tenant-root", ""), namespace("tenant-foo", tenantstate.Deleting),
+		release("tenant-root", "tenant-foo", "Tenant", "foo"),
+	)
+	reconcileNS(t, r, "tenant-foo")
+	setState(t, c, "tenant-foo", tenantstate.Active)
+	reconcileNS(t, r, "tenant-foo")
+
+	if _, ok := annotations(t, c, "tenant-foo")[tenantstate.DeletionAtAnnotation]; ok {
+		t.Errorf("deletion-at must be cleared on cancel")
+	}
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "tenant-root", Name: "tenant-foo"}, &helmv2.HelmRelease{}); err != nil {
+		t.Errorf("tenant HelmRelease must survive a cancelled deletion: %v", err)
+	}
+}
+
+func TestReconcile_ArchiveBacksUpThenDeletes(t *testing.T) {
+	r, c, _ := newReconciler(t,
+		namespace("tenant-foo", tenantstate.Archived),
+		release("tenant-foo", "postgres-db", "Postgres", "db"),
+		release("tenant-foo", "redis-cache", "Redis", "cache"),
+		&backupsv1alpha1.Plan{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo", Name: "db-nightly"},
+			Spec: backupsv1alpha1.PlanSpec{
+				ApplicationRef:  corev1.TypedLocalObjectReference{Kind: "Postgres", Name: "db"},
+				BackupClassName: "s3",
+			},
+		},
+	)
+	ctx := context.Background()
+
+	res := reconcileNS(t, r, "tenant-foo")
+	if res.RequeueAfter != archivePollInterval {
+		t.Errorf("requeue = %s while backing up, want %s", res.RequeueAfter, archivePollInterval)
+	}
+	jobs := &backupsv1alpha1.BackupJobList{}
+	_ = c.List(ctx, jobs, client.InNamespace("tenant-foo"))
+	if len(jobs.Items) != 1 || jobs.Items[0].Spec.BackupClassName != "s3" {
+		t.Fatalf("final BackupJobs = %+v, want one using the plan's class", jobs.Items)
+	}
+	if got := annotations(t, c, "tenant-foo")[tenantstate.PhaseAnnotation]; got != string(tenantstate.PhaseArchiving) {
+		t.Errorf("phase = %s, want Archiving", got)
+	}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo", Name: "postgres-db"}, &helmv2.HelmRelease{}); err != nil {
+		t.Errorf("nothing may be deleted before the backups finish: %v", err)
+	}
+
+	job := &jobs.Items[0]
+	job.Status.Phase = backupsv1alpha1.BackupJobPhaseSucceeded
+	if err := c.Update(ctx, job); err != nil {
+		t.Fatalf("update job: %v", err)
+	}
+	reconcileNS(t, r, "tenant-foo")
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo", Name: "postgres-db"}, &helmv2.HelmRelease{}); !apierrors.IsNotFound(err) {
+		t.Errorf("backed-up application: err = %v, want deleted", err)
+	}
+	hr := &helmv2.HelmRelease{}
+	_ = c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo", Name: "redis-cache"}, hr)
+	if !hr.Spec.Suspend {
+		t.Errorf("an application without a final backup must be kept suspended")
+	}
+	got := annotations(t, c, "tenant-foo")
+	if got[tenantstate.PhaseAnnotation] != string(tenantstate.PhaseArchived) || got[tenantstate.MessageAnnotation] != "kept suspended without a final backup: Redis/cache" {
+		t.Errorf("annotations = %v", got)
+	}
+}
+
+func operatorResource(gvk schema.GroupVersionKind, ns, name, uid string, spec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	u.SetGroupVersionKind(gvk)
+	u.SetNamespace(ns)
+	u.SetName(name)
+	u.SetUID(types.UID(uid))
+	return u
+}
+
+func getResource(t *testing.T, c client.Client, gvk schema.GroupVersionKind, ns, name string) *unstructured.Unstructured {
+	t.Helper()
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: ns, Name: name}, u); err != nil {
+		t.Fatalf("get %s %s: %v", gvk.Kind, name, err)
+	}
+	return u
+}
+
+func TestReconcile_SuspendsOperatorApps(t *testing.T) {
+	var (
+		cnpg    = operatorApps[0].gvk
+		mariadb = operatorApps[1].gvk
+		kafka   = operatorApps[2].gvk
+		podSet  = operatorApps[3].gvk
+		redis   = operatorApps[4].gvk
+		capi    = operatorApps[8].gvk
+		ownedBy = func(gvk schema.GroupVersionKind, name, uid string) *metav1.OwnerReference {
+			return &metav1.OwnerReference{APIVersion: gvk.GroupVersion().String(), Kind: gvk.Kind, Name: name, UID: types.UID(uid), Controller: ptr.To(true)}
+		}
+	)
+	pods := []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"name": "events-kafka-0"}}}
+	brokers := operatorResource(podSet, "tenant-foo", "events-kafka", "ps", map[string]interface{}{"pods": pods})
+	brokers.SetOwnerReferences([]metav1.OwnerReference{*ownedBy(kafka, "events", "kafka")})
+	orphanPodSet := operatorResource(podSet, "tenant-foo", "other-kafka", "ps2", map[string]interface{}{"pods": pods})
+	mariadbSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo", Name: "sql", OwnerReferences: []metav1.OwnerReference{*ownedBy(mariadb, "sql", "mariadb")}},
+		Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To(int32(3))},
+	}
+	r, c, _ := newReconciler(t,
+		namespace("tenant-foo", tenantstate.Suspended),
+		operatorResource(cnpg, "tenant-foo", "pg", "cnpg", map[string]interface{}{"instances": int64(2)}),
+		operatorResource(mariadb, "tenant-foo", "sql", "mariadb", map[string]interface{}{"replicas": int64(3)}),
+		mariadbSet,
+		operatorResource(kafka, "tenant-foo", "events", "kafka", map[string]interface{}{}),
+		brokers, orphanPodSet,
+		operatorResource(redis, "tenant-foo", "cache", "redis", map[string]interface{}{
+			"redis":    map[string]interface{}{"replicas": int64(2)},
+			"sentinel": map[string]interface{}{"replicas": int64(3)},
+		}),
+		operatorResource(capi, "tenant-foo", "k8s", "capi", map[string]interface{}{}),
+	)
+	ctx := context.Background()
+	reconcileNS(t, r, "tenant-foo")
+	// A second pass must keep the values recorded by the first.
+	reconcileNS(t, r, "tenant-foo")
+
+	if got := getResource(t, c, cnpg, "tenant-foo", "pg").GetAnnotations()["cnpg.io/hibernation"]; got != "on" {
+		t.Errorf("postgres hibernation = %q, want on", got)
+	}
+	if got, _, _ := unstructured.NestedBool(getResource(t, c, mariadb, "tenant-foo", "sql").Object, "spec", "suspend"); !got {
+		t.Errorf("mariadb must be suspended")
+	}
+	sts := &appsv1.StatefulSet{}
+	_ = c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo", Name: "sql"}, sts)
+	if *sts.Spec.Replicas != 0 || sts.Annotations[replicasAnnotation] != "3" {
+		t.Errorf("mariadb statefulset replicas=%d annotations=%v, want 0 remembering 3", *sts.Spec.Replicas, sts.Annotations)
+	}
+	if got := getResource(t, c, kafka, "tenant-foo", "events").GetAnnotations()["strimzi.io/pause-reconciliation"]; got != "true" {
+		t.Errorf("kafka pause-reconciliation = %q, want true", got)
+	}
+	if got, _, _ := unstructured.NestedSlice(getResource(t, c, podSet, "tenant-foo", "events-kafka").Object, "spec", "pods"); len(got) != 0 {
+		t.Errorf("kafka podset pods = %v, want none", got)
+	}
+	if got, _, _ := unstructured.NestedSlice(getResource(t, c, podSet, "tenant-foo", "other-kafka").Object, "spec", "pods"); len(got) != 1 {
+		t.Errorf("a podset whose Kafka is not paused must be left alone")
+	}
+	redisObj := getResource(t, c, redis, "tenant-foo", "cache").Object
+	if n, _, _ := unstructured.NestedInt64(redisObj, "spec", "redis", "replicas"); n != 0 {
+		t.Errorf("redis replicas = %d, want 0", n)
+	}
+	if n, _, _ := unstructured.NestedInt64(redisObj, "spec", "sentinel", "replicas"); n != 0 {
+		t.Errorf("sentinel replicas = %d, want 0", n)
+	}
+	if got, _, _ := unstructured.NestedBool(getResource(t, c, capi, "tenant-foo", "k8s").Object, "spec", "paused"); !got {
+		t.Errorf("cluster-api cluster must be paused")
+	}
+
+	setState(t, c, "tenant-foo", tenantstate.Active)
+	reconcileNS(t, r, "tenant-foo")
+
+	pg := getResource(t, c, cnpg, "tenant-foo", "pg")
+	if _, ok := pg.GetAnnotations()["cnpg.io/hibernation"]; ok {
+		t.Errorf("postgres hibernation must be removed on resume")
+	}
+	if _, ok := pg.GetAnnotations()[suspendedFieldsAnnotation]; ok {
+		t.Errorf("suspended-fields annotation must be removed on resume")
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(getResource(t, c, mariadb, "tenant-foo", "sql").Object, "spec", "suspend"); found {
+		t.Errorf("mariadb suspend must be removed on resume")
+	}
+	_ = c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo", Name: "sql"}, sts)
+	if *sts.Spec.Replicas != 3 {
+		t.Errorf("mariadb statefulset replicas = %d, want 3", *sts.Spec.Replicas)
+	}
+	if got, _, _ := unstructured.NestedSlice(getResource(t, c, podSet, "tenant-foo", "events-kafka").Object, "spec", "pods"); len(got) != 1 {
+		t.Errorf("kafka podset pods = %v, want the original pod", got)
+	}
+	if _, ok := getResource(t, c, kafka, "tenant-foo", "events").GetAnnotations()["strimzi.io/pause-reconciliation"]; ok {
+		t.Errorf("kafka must be unpaused on resume")
+	}
+	redisObj = getResource(t, c, redis, "tenant-foo", "cache").Object
+	if n, _, _ := unstructured.NestedInt64(redisObj, "spec", "redis", "replicas"); n != 2 {
+		t.Errorf("redis replicas = %d, want 2", n)
+	}
+	if n, _, _ := unstructured.NestedInt64(redisObj, "spec", "sentinel", "replicas"); n != 3 {
+		t.Errorf("sentinel replicas = %d, want 3", n)
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(getResource(t, c, capi, "tenant-foo", "k8s").Object, "spec", "paused"); found {
+		t.Errorf("cluster-api cluster must be unpaused on resume")
+	}
+}
@@ -0,0 +1,388 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantmigration
+
+import (
+	"context"
+	"fmt"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	backupsv1alpha1 "github.com/cozystack/cozystack/api/backups/v1alpha1"
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+)
+
+// advance takes one application a step further. It returns true once the
+// step is complete and the application's phase has moved on; false means
+// it is waiting on something and should be looked at again later. An
+// application that cannot be moved is left Failed.
+func (r *Reconciler) advance(ctx context.Context, m *cozyv1alpha1.TenantMigration, app *cozyv1alpha1.TenantMigrationApplication) (bool, error) {
+	dest := destinationOf(&m.Status, app.Namespace)
+	backup := m.Spec.VolumeStrategy == cozyv1alpha1.TenantMigrationBackup
+	app.Message = ""
+	switch app.Phase {
+	case cozyv1alpha1.TenantMigrationApplicationPending, "":
+		if err := r.prepare(ctx, m, app, dest); err != nil {
+			return false, err
+		}
+		if backup {
+			app.Phase = cozyv1alpha1.TenantMigrationApplicationBackingUp
+			return true, nil
+		}
+		if err := r.retainVolumes(ctx, app); err != nil {
+			return false, err
+		}
+		app.Phase = cozyv1alpha1.TenantMigrationApplicationStopping
+
+	case cozyv1alpha1.TenantMigrationApplicationBackingUp:
+		job, err := r.finalBackup(ctx, m, app)
+		if err != nil {
+			return false, err
+		}
+		switch job.Status.Phase {
+		case backupsv1alpha1.BackupJobPhaseSucceeded:
+			if job.Status.BackupRef == nil {
+				return r.fail(app, "final backup succeeded without recording a Backup")
+			}
+			app.Backup = job.Status.BackupRef.Name
+			app.Phase = cozyv1alpha1.TenantMigrationApplicationStopping
+		case backupsv1alpha1.BackupJobPhaseFailed:
+			return r.fail(app, "final backup failed: "+job.Status.Message)
+		default:
+			app.Message = "waiting for BackupJob " + job.Name
+			return false, nil
+		}
+
+	case cozyv1alpha1.TenantMigrationApplicationStopping:
+		gone, err := r.stop(ctx, app)
+		if err != nil || !gone {
+			app.Message = "waiting for the old release to be uninstalled"
+			return false, err
+		}
+		app.Phase = cozyv1alpha1.TenantMigrationApplicationReleasing
+		if backup {
+			app.Phase = cozyv1alpha1.TenantMigrationApplicationRecreating
+		}
+
+	case cozyv1alpha1.TenantMigrationApplicationReleasing:
+		released, err := r.releaseVolumes(ctx, app, dest)
+		if err != nil || !released {
+			app.Message = "waiting for the old claims to be deleted"
+			return false, err
+		}
+		app.Phase = cozyv1alpha1.TenantMigrationApplicationRecreating
+
+	case cozyv1alpha1.TenantMigrationApplicationRecreating:
+		if backup {
+			if err := r.copyBackup(ctx, app, dest); err != nil {
+				return false, err
+			}
+		} else if err := r.claimVolumes(ctx, app, dest); err != nil {
+			return false, err
+		}
+		if err := r.release(ctx, app, dest); err != nil {
+			return false, err
+		}
+		app.Phase = cozyv1alpha1.TenantMigrationApplicationMoved
+		if backup {
+			app.Phase = cozyv1alpha1.TenantMigrationApplicationRestoring
+		}
+
+	case cozyv1alpha1.TenantMigrationApplicationRestoring:
+		job, err := r.restore(ctx, m, app, dest)
+		if err != nil {
+			return false, err
+		}
+		switch job.Status.Phase {
+		case backupsv1alpha1.RestoreJobPhaseSucceeded:
+			app.Phase = cozyv1alpha1.TenantMigrationApplicationMoved
+		case backupsv1alpha1.RestoreJobPhaseFailed:
+			return r.fail(app, "restore failed: "+job.Status.Message)
+		default:
+			app.Message = "waiting for RestoreJob " + job.Name
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *Reconciler) fail(app *cozyv1alpha1.TenantMigrationApplication, msg string) (bool, error) {
+	app.Phase = cozyv1alpha1.TenantMigrationApplicationFailed
+	app.Message = msg
+	return true, nil
+}
+
+// sourceRelease finds the HelmRelease of an application in its old
+// namespace, nil once it is gone.
+func (r *Reconciler) sourceRelease(ctx context.Context, app *cozyv1alpha1.TenantMigrationApplication) (*helmv2.HelmRelease, error) {
+	list := &helmv2.HelmReleaseList{}
+	if err := r.List(ctx, list, client.InNamespace(app.Namespace), appSelector(app)); err != nil {
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, nil
+	}
+	return &list.Items[0], nil
+}
+
+// prepare copies an application's Secrets and creates its release in the
+// new namespace, suspended until its data is there.
+func (r *Reconciler) prepare(ctx context.Context, m *cozyv1alpha1.TenantMigration, app *cozyv1alpha1.TenantMigrationApplication, dest string) error {
+	src, err := r.sourceRelease(ctx, app)
+	if err != nil {
+		return err
+	}
+	if src == nil {
+		return fmt.Errorf("release of %s %s/%s disappeared", app.Kind, app.Namespace, app.Name)
+	}
+	if err := r.copySecrets(ctx, app, dest); err != nil {
+		return err
+	}
+	hr := copyRelease(src, dest, src.Name)
+	hr.Spec.Suspend = true
+	hr.Annotations[heldAnnotation] = m.Name
+	return r.createCopy(ctx, hr)
+}
+
+// retainVolumes records the bound claims of an application and sets their
+// volumes to Retain, so deleting the claims keeps the data.
+func (r *Reconciler) retainVolumes(ctx context.Context, app *cozyv1alpha1.TenantMigrationApplication) error {
+	claims := &corev1.PersistentVolumeClaimList{}
+	if err := r.reader().List(ctx, claims, client.InNamespace(app.Namespace), appSelector(app)); err != nil {
+		return err
+	}
+	app.Volumes = nil
+	for i := range claims.Items {
+		pvc := &claims.Items[i]
+		if pvc.Spec.VolumeName == "" || pvc.Status.Phase != corev1.ClaimBound {
+			continue
+		}
+		pv := &corev1.PersistentVolume{}
+		if err := r.Get(ctx, client.ObjectKey{Name: pvc.Spec.VolumeName}, pv); err != nil {
+			return err
+		}
+		app.Volumes = append(app.Volumes, cozyv1alpha1.TenantMigrationVolume{
+			Claim:         pvc.Name,
+			Volume:        pv.Name,
+			ReclaimPolicy: string(pv.Spec.PersistentVolumeReclaimPolicy),
+			Labels:        pvc.Labels,
+		})
+		if pv.Spec.PersistentVolumeReclaimPolicy == corev1.PersistentVolumeReclaimRetain {
+			continue
+		}
+		patch := client.MergeFrom(pv.DeepCopy())
+		pv.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimRetain
+		if err := r.Patch(ctx, pv, patch); err != nil {
+			return fmt.Errorf("retain PersistentVolume %s: %w", pv.Name, err)
+		}
+	}
+	return nil
+}
+
+// stop deletes an application's old release and reports whether it is
+// gone. Flux skips the uninstall of a suspended release, so a suspension is
+// lifted first.
+func (r *Reconciler) stop(ctx context.Context, app *cozyv1alpha1.TenantMigrationApplication) (bool, error) {
+	hr, err := r.sourceRelease(ctx, app)
+	if err != nil {
+		return false, err
+	}
+	if hr == nil {
+		return true, nil
+	}
+	if hr.Spec.Suspend {
+		patch := client.MergeFrom(hr.DeepCopy())
+		hr.Spec.Suspend = false
+		if err := r.Patch(ctx, hr, patch); err != nil {
+			return false, fmt.Errorf("resume HelmRelease %s/%s: %w", hr.Namespace, hr.Name, err)
+		}
+	}
+	if hr.DeletionTimestamp.IsZero() {
+		if err := r.Delete(ctx, hr); client.IgnoreNotFound(err) != nil {
+			return false, fmt.Errorf("delete HelmRelease %s/%s: %w", hr.Namespace, hr.Name, err)
+		}
+	}
+	return false, nil
+}
+
+// releaseVolumes deletes an application's old claims and pre-binds their
+// volumes to claims of the same name in the new namespace. It reports
+// whether every volume is ready to be claimed there.
+func (r *Reconciler) releaseVolumes(ctx context.Context, app *cozyv1alpha1.TenantMigrationApplication, dest string) (bool, error) {
+	ready := true
+	for _, v := range app.Volumes {
+		pvc := &corev1.PersistentVolumeClaim{}
+		err := r.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: v.Claim}, pvc)
+		if err == nil {
+			ready = false
+			if pvc.DeletionTimestamp.IsZero() {
+				if err := r.Delete(ctx, pvc); client.IgnoreNotFound(err) != nil {
+					return false, fmt.Errorf("delete claim %s/%s: %w", app.Namespace, v.Claim, err)
+				}
+			}
+			continue
+		}
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+
+		pv := &corev1.PersistentVolume{}
+		if err := r.Get(ctx, client.ObjectKey{Name: v.Volume}, pv); err != nil {
+			return false, err
+		}
+		if ref := pv.Spec.ClaimRef; ref != nil && ref.Namespace == dest && ref.Name == v.Claim {
+			continue
+		}
+		patch := client.MergeFrom(pv.DeepCopy())
+		pv.Spec.ClaimRef = &corev1.ObjectReference{Kind: "PersistentVolumeClaim", APIVersion: "v1", Namespace: dest, Name: v.Claim}
+		if err := r.Patch(ctx, pv, patch); err != nil {
+			return false, fmt.Errorf("rebind PersistentVolume %s: %w", pv.Name, err)
+		}
+	}
+	return ready, nil
+}
+
+// claimVolumes creates the new claims, each naming its old volume, before
+// the release can create empty ones in their place.
+func (r *Reconciler) claimVolumes(ctx context.Context, app *cozyv1alpha1.TenantMigrationApplication, dest string) error {
+	for _, v := range app.Volumes {
+		pv := &corev1.PersistentVolume{}
+		if err := r.Get(ctx, client.ObjectKey{Name: v.Volume}, pv); err != nil {
+			return err
+		}
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   dest,
+				Name:        v.Claim,
+				Labels:      v.Labels,
+				Annotations: map[string]string{migratedFromAnnotation: app.Namespace + "/" + v.Claim},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      pv.Spec.AccessModes,
+				StorageClassName: &pv.Spec.StorageClassName,
+				VolumeMode:       pv.Spec.VolumeMode,
+				VolumeName:       pv.Name,
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: pv.Spec.Capacity[corev1.ResourceStorage]},
+				},
+			},
+		}
+		if err := r.createCopy(ctx, pvc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// release lets the application's new release install.
+func (r *Reconciler) release(ctx context.Context, app *cozyv1alpha1.TenantMigrationApplication, dest string) error {
+	list := &helmv2.HelmReleaseList{}
+	if err := r.List(ctx, list, client.InNamespace(dest), appSelector(app)); err != nil {
+		return err
+	}
+	for i := range list.Items {
+		hr := &list.Items[i]
+		if _, held := hr.Annotations[heldAnnotation]; !held {
+			continue
+		}
+		patch := client.MergeFrom(hr.DeepCopy())
+		hr.Spec.Suspend = false
+		delete(hr.Annotations, heldAnnotation)
+		if err := r.Patch(ctx, hr, patch); err != nil {
+			return fmt.Errorf("resume HelmRelease %s/%s: %w", hr.Namespace, hr.Name, err)
+		}
+	}
+	return nil
+}
+
+// finalBackup runs the application's final BackupJob through its Plan.
+func (r *Reconciler) finalBackup(ctx context.Context, m *cozyv1alpha1.TenantMigration, app *cozyv1alpha1.TenantMigrationApplication) (*backupsv1alpha1.BackupJob, error) {
+	plans := &backupsv1alpha1.PlanList{}
+	if err := r.reader().List(ctx, plans, client.InNamespace(app.Namespace)); err != nil {
+		return nil, err
+	}
+	var plan *backupsv1alpha1.Plan
+	for i := range plans.Items {
+		if ref := plans.Items[i].Spec.ApplicationRef; ref.Kind == app.Kind && ref.Name == app.Name {
+			plan = &plans.Items[i]
+			break
+		}
+	}
+	if plan == nil {
+		return nil, fmt.Errorf("backup Plan of %s %s/%s disappeared", app.Kind, app.Namespace, app.Name)
+	}
+
+	job := &backupsv1alpha1.BackupJob{}
+	key := client.ObjectKey{Namespace: app.Namespace, Name: fmt.Sprintf("migrate-%s-%s", m.Name, plan.Name)}
+	err := r.reader().Get(ctx, key, job)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return job, err
+	}
+	job = &backupsv1alpha1.BackupJob{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+		Spec: backupsv1alpha1.BackupJobSpec{
+			PlanRef:         &corev1.LocalObjectReference{Name: plan.Name},
+			ApplicationRef:  plan.Spec.ApplicationRef,
+			BackupClassName: plan.Spec.BackupClassName,
+		},
+	}
+	if err := r.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("create BackupJob %s/%s: %w", key.Namespace, key.Name, err)
+	}
+	return job, nil
+}
+
+// copyBackup copies the final Backup into the new namespace, where a
+// RestoreJob can refer to it.
+func (r *Reconciler) copyBackup(ctx context.Context, app *cozyv1alpha1.TenantMigrationApplication, dest string) error {
+	src := &backupsv1alpha1.Backup{}
+	if err := r.reader().Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: app.Backup}, src); err != nil {
+		return fmt.Errorf("get Backup %s/%s: %w", app.Namespace, app.Backup, err)
+	}
+	return r.createCopy(ctx, &backupsv1alpha1.Backup{
+		ObjectMeta: copyMeta(src.ObjectMeta, dest, src.Name),
+		Spec:       *src.Spec.DeepCopy(),
+		Status:     *src.Status.DeepCopy(),
+	})
+}
+
+// restore runs the RestoreJob bringing the final backup into the new
+// application.
+func (r *Reconciler) restore(ctx context.Context, m *cozyv1alpha1.TenantMigration, app *cozyv1alpha1.TenantMigrationApplication, dest string) (*backupsv1alpha1.RestoreJob, error) {
+	job := &backupsv1alpha1.RestoreJob{}
+	key := client.ObjectKey{Namespace: dest, Name: "migrate-" + m.Name + "-" + app.Backup}
+	err := r.reader().Get(ctx, key, job)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return job, err
+	}
+	group := backupsv1alpha1.DefaultApplicationAPIGroup
+	job = &backupsv1alpha1.RestoreJob{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+		Spec: backupsv1alpha1.RestoreJobSpec{
+			BackupRef:            corev1.LocalObjectReference{Name: app.Backup},
+			TargetApplicationRef: &corev1.TypedLocalObjectReference{APIGroup: &group, Kind: app.Kind, Name: app.Name},
+		},
+	}
+	if err := r.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("create RestoreJob %s/%s: %w", key.Namespace, key.Name, err)
+	}
+	return job, nil
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantmigration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	backupsv1alpha1 "github.com/cozystack/cozystack/api/backups/v1alpha1"
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+)
+
+const (
+	// migratedFromAnnotation records where a copied object came from.
+	migratedFromAnnotation = "migration.cozystack.io/source"
+	// heldAnnotation marks a HelmRelease created suspended by a migration
+	// until its data is in place.
+	heldAnnotation = "migration.cozystack.io/held"
+
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+	managedByLabel                 = "app.kubernetes.io/managed-by"
+)
+
+// copyMeta returns the metadata of a copy of src in namespace. Helm's
+// ownership annotation follows the object, so the release recreated there
+// adopts it instead of refusing to overwrite it.
+func copyMeta(src metav1.ObjectMeta, namespace, name string) metav1.ObjectMeta {
+	out := metav1.ObjectMeta{
+		Namespace:   namespace,
+		Name:        name,
+		Labels:      src.Labels,
+		Annotations: map[string]string{},
+	}
+	for k, v := range src.Annotations {
+		if strings.Contains(k, "fluxcd.io/") || k == corev1.LastAppliedConfigAnnotation {
+			continue
+		}
+		out.Annotations[k] = v
+	}
+	if _, ok := out.Annotations[helmReleaseNamespaceAnnotation]; ok {
+		out.Annotations[helmReleaseNamespaceAnnotation] = namespace
+	}
+	out.Annotations[migratedFromAnnotation] = src.Namespace + "/" + src.Name
+	return out
+}
+
+// createCopy creates obj unless it already exists.
+func (r *Reconciler) createCopy(ctx context.Context, obj client.Object) error {
+	if err := r.Create(ctx, obj); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create %T %s/%s: %w", obj, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return nil
+}
+
+// copyRelease copies a HelmRelease into namespace under name. Fields naming
+// the old namespace are pointed at the new one.
+func copyRelease(src *helmv2.HelmRelease, namespace, name string) *helmv2.HelmRelease {
+	hr := &helmv2.HelmRelease{
+		ObjectMeta: copyMeta(src.ObjectMeta, namespace, name),
+		Spec:       *src.Spec.DeepCopy(),
+	}
+	if hr.Spec.TargetNamespace == src.Namespace {
+		hr.Spec.TargetNamespace = namespace
+	}
+	if hr.Spec.StorageNamespace == src.Namespace {
+		hr.Spec.StorageNamespace = namespace
+	}
+	return hr
+}
+
+// copyTenant creates the Tenant application owning dest from the one owning
+// source. The top of the subtree takes its new name; sub-tenants keep
+// theirs.
+func (r *Reconciler) copyTenant(ctx context.Context, source, dest string) error {
+	src := &helmv2.HelmRelease{}
+	if err := r.Get(ctx, tenantRelease(source), src); err != nil {
+		return fmt.Errorf("get Tenant application of %s: %w", source, err)
+	}
+	key := tenantRelease(dest)
+	hr := copyRelease(src, key.Namespace, key.Name)
+	hr.Labels = map[string]string{}
+	for k, v := range src.Labels {
+		hr.Labels[k] = v
+	}
+	hr.Labels[appsv1alpha1.ApplicationNameLabel] = tenantName(dest)
+	// The tenant is brought up in whatever state it is in, but a deletion
+	// scheduled on the old namespace is not carried to the new one.
+	hr.Spec.Suspend = false
+	return r.createCopy(ctx, hr)
+}
+
+// copySecrets copies the Secrets of an application, so credentials the
+// chart generated once still match the data they protect.
+func (r *Reconciler) copySecrets(ctx context.Context, app *cozyv1alpha1.TenantMigrationApplication, dest string) error {
+	list := &corev1.SecretList{}
+	if err := r.reader().List(ctx, list, client.InNamespace(app.Namespace), appSelector(app)); err != nil {
+		return err
+	}
+	for i := range list.Items {
+		src := &list.Items[i]
+		switch src.Type {
+		case corev1.SecretTypeServiceAccountToken, "helm.sh/release.v1":
+			continue
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: copyMeta(src.ObjectMeta, dest, src.Name),
+			Type:       src.Type,
+			Data:       src.Data,
+		}
+		if err := r.createCopy(ctx, secret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tenantRoles are the ClusterRoles the tenant chart binds. The controller
+// may bind only these, so RoleBindings of other roles are not copied and
+// have to be recreated by whoever granted them.
+var tenantRoles = map[string]bool{
+	"cozy:tenant":             true,
+	"cozy:tenant:view":        true,
+	"cozy:tenant:use":         true,
+	"cozy:tenant:admin":       true,
+	"cozy:tenant:super-admin": true,
+}
+
+// accessLevels suffix the names of a tenant's access groups:
+// tenant-foo-admin is the admin group of tenant-foo.
+var accessLevels = []string{"view", "use", "admin", "super-admin"}
+
+// copyRoleBindings copies the RoleBindings users added to a namespace for
+// the tenant roles; the ones the tenant chart renders are rendered again
+// for the new name. Only subjects the tenant chart itself derives from a
+// namespace of the subtree, its service accounts and access groups, are
+// renamed with it. Users and other groups are copied as they are.
+func (r *Reconciler) copyRoleBindings(ctx context.Context, st *cozyv1alpha1.TenantMigrationStatus, pair cozyv1alpha1.TenantMigrationNamespace) error {
+	list := &rbacv1.RoleBindingList{}
+	if err := r.reader().List(ctx, list, client.InNamespace(pair.Source)); err != nil {
+		return err
+	}
+	for i := range list.Items {
+		src := &list.Items[i]
+		if src.Labels[managedByLabel] == "Helm" || src.Annotations[helmReleaseNamespaceAnnotation] != "" {
+			continue
+		}
+		if src.RoleRef.Kind != "ClusterRole" || !tenantRoles[src.RoleRef.Name] {
+			log.FromContext(ctx).Info("RoleBinding not copied: it does not bind a tenant role",
+				"namespace", src.Namespace, "name", src.Name, "role", src.RoleRef.Name)
+			continue
+		}
+		rb := &rbacv1.RoleBinding{
+			ObjectMeta: copyMeta(src.ObjectMeta, pair.Destination, src.Name),
+			RoleRef:    src.RoleRef,
+		}
+		for _, subject := range src.Subjects {
+			switch subject.Kind {
+			case rbacv1.ServiceAccountKind:
+				// A tenant's service account is named after its namespace.
+				if subject.Name == subject.Namespace {
+					subject.Name = renamed(st, subject.Name)
+				}
+				subject.Namespace = renamed(st, subject.Namespace)
+			case rbacv1.GroupKind:
+				if accessGroup(st, subject.Name) {
+					subject.Name = renamed(st, subject.Name)
+				}
+			}
+			rb.Subjects = append(rb.Subjects, subject)
+		}
+		if err := r.createCopy(ctx, rb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accessGroup reports whether group is the access group of a namespace of
+// the subtree.
+func accessGroup(st *cozyv1alpha1.TenantMigrationStatus, group string) bool {
+	for _, pair := range st.Namespaces {
+		for _, level := range accessLevels {
+			if group == pair.Source+"-"+level {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// renamed rewrites a name that is, or is prefixed by, a namespace of the
+// subtree: tenant-foo-bar-admin becomes tenant-baz-bar-admin.
+func renamed(st *cozyv1alpha1.TenantMigrationStatus, name string) string {
+	// Namespaces are sorted parents first, so the last match is the
+	// longest.
+	best := cozyv1alpha1.TenantMigrationNamespace{}
+	for _, pair := range st.Namespaces {
+		if inSubtree(name, pair.Source) {
+			best = pair
+		}
+	}
+	if best.Source == "" {
+		return name
+	}
+	return best.Destination + strings.TrimPrefix(name, best.Source)
+}
+
+// copyDomainClaims copies a namespace's DomainClaims. The copies are
+// verified afresh, and routes in the new namespace wait for that.
+func (r *Reconciler) copyDomainClaims(ctx context.Context, pair cozyv1alpha1.TenantMigrationNamespace) error {
+	list := &gatewayv1alpha1.DomainClaimList{}
+	if err := r.reader().List(ctx, list, client.InNamespace(pair.Source)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+	for i := range list.Items {
+		src := &list.Items[i]
+		claim := &gatewayv1alpha1.DomainClaim{
+			ObjectMeta: copyMeta(src.ObjectMeta, pair.Destination, src.Name),
+			Spec:       src.Spec,
+		}
+		if err := r.createCopy(ctx, claim); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyPlans copies a namespace's backup Plans. They are copied last, so no
+// scheduled backup fires against an application still being moved.
+func (r *Reconciler) copyPlans(ctx context.Context, pair cozyv1alpha1.TenantMigrationNamespace) error {
+	list := &backupsv1alpha1.PlanList{}
+	if err := r.reader().List(ctx, list, client.InNamespace(pair.Source)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+	for i := range list.Items {
+		src := &list.Items[i]
+		plan := &backupsv1alpha1.Plan{
+			ObjectMeta: copyMeta(src.ObjectMeta, pair.Destination, src.Name),
+			Spec:       *src.Spec.DeepCopy(),
+		}
+		if err := r.createCopy(ctx, plan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appSelector selects the objects the lineage webhook attributed to an
+// application.
+func appSelector(app *cozyv1alpha1.TenantMigrationApplication) client.MatchingLabels {
+	return client.MatchingLabels{
+		appsv1alpha1.ApplicationKindLabel: app.Kind,
+		appsv1alpha1.ApplicationNameLabel: app.Name,
+	}
+}
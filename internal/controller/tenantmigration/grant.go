@@ -0,0 +1,71 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantmigration
+
+import (
+	"context"
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+)
+
+// DefaultGrantBinding is the ClusterRoleBinding the chart renders, without
+// subjects, for the rights only a running migration needs: rebinding
+// volumes and moving backups.
+const DefaultGrantBinding = "cozystack-controller:tenant-migration"
+
+// syncGrant binds the controller to the migration role while any migration
+// is running and unbinds it once none is, so the rights to delete claims
+// and repoint volumes are not held between migrations.
+func (r *Reconciler) syncGrant(ctx context.Context) error {
+	if r.GrantBinding == "" {
+		return nil
+	}
+	list := &cozyv1alpha1.TenantMigrationList{}
+	if err := r.List(ctx, list); err != nil {
+		return err
+	}
+	var want []rbacv1.Subject
+	for i := range list.Items {
+		m := &list.Items[i]
+		if !finished(m.Status.Phase) && m.DeletionTimestamp.IsZero() {
+			want = []rbacv1.Subject{r.Subject}
+			break
+		}
+	}
+
+	crb := &rbacv1.ClusterRoleBinding{}
+	if err := r.Get(ctx, client.ObjectKey{Name: r.GrantBinding}, crb); err != nil {
+		if want == nil {
+			return client.IgnoreNotFound(err)
+		}
+		return fmt.Errorf("get ClusterRoleBinding %s: %w", r.GrantBinding, err)
+	}
+	if apiequality.Semantic.DeepEqual(crb.Subjects, want) {
+		return nil
+	}
+	patch := client.MergeFrom(crb.DeepCopy())
+	crb.Subjects = want
+	if err := r.Patch(ctx, crb, patch); err != nil {
+		return fmt.Errorf("update ClusterRoleBinding %s: %w", r.GrantBinding, err)
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tenantmigration moves a tenant subtree under a different parent.
+//
+// A tenant's place in the tree is its namespace name: tenant "bar" under
+// tenant-foo owns tenant-foo-bar, and every consumer of the hierarchy
+// (quotas, RBAC group names, inherited services) derives the parent by
+// stripping the last segment. Nothing can be renamed in place, so a
+// TenantMigration rebuilds the subtree under its new names:
+//
+//  1. Provisioning copies the Tenant applications, so the tenant chart
+//     creates every new namespace, and copies the namespace's own
+//     configuration: RoleBindings users added for the tenant roles, with
+//     the subtree's service accounts and access groups renamed, and
+//     DomainClaims.
+//  2. Moving takes the applications across one at a time. Each is created
+//     suspended in its new namespace, together with its Secrets so
+//     generated credentials still match the data, and then either rebound
+//     to its old PersistentVolumes or restored from a final backup.
+//  3. Finalizing copies backup Plans, gives the volumes their reclaim
+//     policy back and deletes the old tenant.
+//
+// Quotas are not copied: a tenant's declared budget travels in its Tenant
+// values and the tenant quota controller recomputes pools from the tree.
+// The migration checks up front that the subtree fits the new parent's pool
+// instead: its own budget is carved out of it without overcommitting it, or,
+// for a subtree without one, what it uses fits what the pool has left.
+//
+// The rights to delete claims, repoint volumes and create backup objects
+// are bound to the controller only while a migration is running.
+package tenantmigration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	backupsv1alpha1 "github.com/cozystack/cozystack/api/backups/v1alpha1"
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+)
+
+const (
+	// MigrationAnnotation marks every namespace of a subtree being moved
+	// with the name of the TenantMigration; the aggregated API refuses new
+	// applications in them.
+	MigrationAnnotation = "namespace.cozystack.io/migration"
+
+	tenantKind        = "Tenant"
+	tenantModuleLabel = "internal.cozystack.io/tenantmodule"
+	rootTenant        = "tenant-root"
+	tenantPrefix      = "tenant-"
+
+	etcdLabel      = "namespace.cozystack.io/etcd"
+	seaweedfsLabel = "namespace.cozystack.io/seaweedfs"
+)
+
+// validationError is a reason the migration cannot run; it fails the
+// TenantMigration rather than being retried.
+type validationError string
+
+func (e validationError) Error() string { return string(e) }
+
+func invalid(format string, args ...any) error {
+	return validationError(fmt.Sprintf(format, args...))
+}
+
+// ownedNamespace is the namespace a tenant named name owns under parent. It
+// mirrors computeTenantNamespace in the aggregated API.
+func ownedNamespace(parent, name string) string {
+	if parent == rootTenant {
+		return tenantPrefix + name
+	}
+	return parent + "-" + name
+}
+
+// tenantName is the last segment of a tenant namespace.
+func tenantName(namespace string) string {
+	return namespace[strings.LastIndex(namespace, "-")+1:]
+}
+
+// parentNamespace is the namespace of the tenant a namespace other than
+// tenant-root is nested in.
+func parentNamespace(namespace string) string {
+	i := strings.LastIndex(namespace, "-")
+	if i < len(tenantPrefix) {
+		return rootTenant
+	}
+	return namespace[:i]
+}
+
+// tenantRelease is the HelmRelease of the Tenant application owning a
+// namespace other than tenant-root.
+func tenantRelease(namespace string) client.ObjectKey {
+	return client.ObjectKey{Namespace: parentNamespace(namespace), Name: tenantPrefix + tenantName(namespace)}
+}
+
+// inSubtree reports whether ns is root or one of its descendants.
+func inSubtree(ns, root string) bool {
+	return ns == root || strings.HasPrefix(ns, root+"-")
+}
+
+// destinationOf maps a source namespace to its new name.
+func destinationOf(st *cozyv1alpha1.TenantMigrationStatus, source string) string {
+	for _, pair := range st.Namespaces {
+		if pair.Source == source {
+			return pair.Destination
+		}
+	}
+	return ""
+}
+
+// isApplication reports whether hr is an application the migration moves:
+// Tenants are recreated as namespaces and tenant modules are rendered by the
+// new tenant chart itself.
+func isApplication(hr *helmv2.HelmRelease) bool {
+	kind := hr.Labels[appsv1alpha1.ApplicationKindLabel]
+	return kind != "" && kind != tenantKind && hr.Labels[tenantModuleLabel] != "true"
+}
+
+// plan validates a migration and lays out its namespaces and applications.
+// A validationError means it can never run as specified.
+func (r *Reconciler) plan(ctx context.Context, m *cozyv1alpha1.TenantMigration) (cozyv1alpha1.TenantMigrationStatus, error) {
+	st := cozyv1alpha1.TenantMigrationStatus{}
+	source, newParent := m.Spec.Tenant, m.Spec.NewParent
+	if !strings.HasPrefix(source, tenantPrefix) || source == rootTenant {
+		return st, invalid("%s is not a tenant that can be moved", source)
+	}
+	if !strings.HasPrefix(newParent, tenantPrefix) {
+		return st, invalid("%s is not a tenant namespace", newParent)
+	}
+	if inSubtree(newParent, source) {
+		return st, invalid("cannot move %s under itself", source)
+	}
+	name := m.Spec.NewName
+	if name == "" {
+		name = tenantName(source)
+	}
+	st.Destination = ownedNamespace(newParent, name)
+	if st.Destination == source {
+		return st, invalid("%s is already named %s under %s", source, name, newParent)
+	}
+
+	namespaces := &corev1.NamespaceList{}
+	if err := r.List(ctx, namespaces); err != nil {
+		return st, err
+	}
+	byName := map[string]*corev1.Namespace{}
+	for i := range namespaces.Items {
+		byName[namespaces.Items[i].Name] = &namespaces.Items[i]
+	}
+	if byName[source] == nil {
+		return st, invalid("tenant namespace %s does not exist", source)
+	}
+	parent := byName[newParent]
+	if parent == nil {
+		return st, invalid("new parent %s does not exist", newParent)
+	}
+	if err := r.Get(ctx, tenantRelease(source), &helmv2.HelmRelease{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return st, invalid("Tenant application of %s not found", source)
+		}
+		return st, err
+	}
+
+	for ns := range byName {
+		if !inSubtree(ns, source) {
+			continue
+		}
+		dest := st.Destination + strings.TrimPrefix(ns, source)
+		if errs := validation.IsDNS1123Label(dest); len(errs) > 0 {
+			return st, invalid("namespace %s would become %s: %s", ns, dest, strings.Join(errs, "; "))
+		}
+		if byName[dest] != nil {
+			return st, invalid("namespace %s already exists", dest)
+		}
+		st.Namespaces = append(st.Namespaces, cozyv1alpha1.TenantMigrationNamespace{Source: ns, Destination: dest})
+	}
+	// Parents sort before their children, so walking the list in order
+	// always finds a namespace's new parent already created.
+	sort.Slice(st.Namespaces, func(i, j int) bool { return st.Namespaces[i].Source < st.Namespaces[j].Source })
+
+	if err := r.checkOverlap(ctx, m, &st); err != nil {
+		return st, err
+	}
+
+	plans := map[string]bool{}
+	for _, pair := range st.Namespaces {
+		ns := byName[pair.Source]
+		for _, provider := range []string{etcdLabel, seaweedfsLabel} {
+			if ns.Labels[provider] == ns.Name {
+				return st, invalid("%s runs its own %s, whose data cannot be moved", ns.Name, strings.TrimPrefix(provider, "namespace.cozystack.io/"))
+			}
+		}
+		releases := &helmv2.HelmReleaseList{}
+		if err := r.List(ctx, releases, client.InNamespace(ns.Name)); err != nil {
+			return st, err
+		}
+		if m.Spec.VolumeStrategy == cozyv1alpha1.TenantMigrationBackup {
+			if err := r.plannedApplications(ctx, ns.Name, plans); err != nil {
+				return st, err
+			}
+		}
+		for i := range releases.Items {
+			hr := &releases.Items[i]
+			if !isApplication(hr) {
+				continue
+			}
+			kind, app := hr.Labels[appsv1alpha1.ApplicationKindLabel], hr.Labels[appsv1alpha1.ApplicationNameLabel]
+			// Kubernetes clusters keep their state in the inherited etcd and
+			// buckets theirs in the inherited SeaweedFS; both stay behind
+			// with the old ancestor.
+			if provider := dataProvider(kind); provider != "" && ns.Labels[provider] != parent.Labels[provider] {
+				return st, invalid("%s %s/%s keeps its data in %s, which %s does not inherit", kind, ns.Name, app, ns.Labels[provider], st.Destination)
+			}
+			if m.Spec.VolumeStrategy == cozyv1alpha1.TenantMigrationBackup && !plans[ns.Name+"/"+kind+"/"+app] {
+				return st, invalid("%s %s/%s has no backup Plan; add one or use the Rebind strategy", kind, ns.Name, app)
+			}
+			st.Applications = append(st.Applications, cozyv1alpha1.TenantMigrationApplication{
+				Namespace: ns.Name, Kind: kind, Name: app, Phase: cozyv1alpha1.TenantMigrationApplicationPending,
+			})
+		}
+	}
+	sort.SliceStable(st.Applications, func(i, j int) bool {
+		a, b := st.Applications[i], st.Applications[j]
+		return a.Namespace+"/"+a.Kind+"/"+a.Name < b.Namespace+"/"+b.Kind+"/"+b.Name
+	})
+
+	if err := r.checkQuota(ctx, &st); err != nil {
+		return st, err
+	}
+	return st, nil
+}
+
+// dataProvider names the namespace label of the service an application
+// kind keeps its data in, if it is one an ancestor tenant provides.
+func dataProvider(kind string) string {
+	switch kind {
+	case "Kubernetes":
+		return etcdLabel
+	case "Bucket":
+		return seaweedfsLabel
+	}
+	return ""
+}
+
+// plannedApplications adds the applications of namespace that have a
+// backup Plan to plans, keyed namespace/kind/name.
+func (r *Reconciler) plannedApplications(ctx context.Context, namespace string, plans map[string]bool) error {
+	list := &backupsv1alpha1.PlanList{}
+	if err := r.reader().List(ctx, list, client.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+	for i := range list.Items {
+		ref := list.Items[i].Spec.ApplicationRef
+		plans[namespace+"/"+ref.Kind+"/"+ref.Name] = true
+	}
+	return nil
+}
+
+// checkOverlap refuses a migration touching namespaces another unfinished
+// migration is moving or creating.
+func (r *Reconciler) checkOverlap(ctx context.Context, m *cozyv1alpha1.TenantMigration, st *cozyv1alpha1.TenantMigrationStatus) error {
+	list := &cozyv1alpha1.TenantMigrationList{}
+	if err := r.List(ctx, list); err != nil {
+		return err
+	}
+	for i := range list.Items {
+		other := &list.Items[i]
+		if other.Name == m.Name || finished(other.Status.Phase) || other.Status.Destination == "" {
+			continue
+		}
+		for _, root := range []string{other.Spec.Tenant, other.Status.Destination} {
+			if inSubtree(m.Spec.Tenant, root) || inSubtree(root, m.Spec.Tenant) ||
+				inSubtree(st.Destination, root) || inSubtree(m.Spec.NewParent, root) {
+				return invalid("%s overlaps TenantMigration %s, which is still running", m.Spec.Tenant, other.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func finished(phase cozyv1alpha1.TenantMigrationPhase) bool {
+	return phase == cozyv1alpha1.TenantMigrationSucceeded || phase == cozyv1alpha1.TenantMigrationFailed
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantmigration
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	quota "k8s.io/apiserver/pkg/quota/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	"github.com/cozystack/cozystack/internal/controller/tenantquota"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+)
+
+// checkQuota refuses a move that does not fit its new parent's budget. The
+// tree is recomputed as it will stand afterwards: a subtree declaring its own
+// quota is carved out of the new parent's pool, one without shares it, in
+// which case what the subtree uses today has to fit as well, and has to be
+// known: usage no ResourceQuota reports cannot be checked, so it is refused.
+func (r *Reconciler) checkQuota(ctx context.Context, st *cozyv1alpha1.TenantMigrationStatus) error {
+	releases := &helmv2.HelmReleaseList{}
+	if err := r.List(ctx, releases, client.MatchingLabels{appsv1alpha1.ApplicationKindLabel: tenantKind}); err != nil {
+		return err
+	}
+	quotas := &corev1.ResourceQuotaList{}
+	if err := r.reader().List(ctx, quotas); err != nil {
+		return err
+	}
+	state, err := tenantquota.ReadState(ctx, r.reader(), quotas.Items)
+	if err != nil {
+		return err
+	}
+	before := tenantquota.TenantsFromReleases(releases.Items, state.Budgets(nil))
+
+	rename := func(ns string) string {
+		if dest := destinationOf(st, ns); dest != "" {
+			return dest
+		}
+		return ns
+	}
+	moved := map[string]bool{}
+	after := make([]tenantquota.Tenant, 0, len(before))
+	used := map[string]corev1.ResourceList{}
+	for _, t := range before {
+		ns := rename(t.Namespace)
+		moved[ns] = ns != t.Namespace
+		after = append(after, tenantquota.Tenant{Namespace: ns, Declared: t.Declared})
+		used[ns] = state.Used[t.Namespace]
+	}
+
+	was := tenantquota.ComputePools(before)
+	for root, pool := range tenantquota.ComputePools(after) {
+		if over := pool.Overcommitted(); len(over) > 0 {
+			var already corev1.ResourceList
+			if p := was[root]; p != nil {
+				already = p.Overcommitted()
+			}
+			if names := newlyExceeded(over, already); len(names) > 0 {
+				return invalid("moving would overcommit the quota of %s on %s", root, strings.Join(names, ", "))
+			}
+		}
+		if moved[root] {
+			continue
+		}
+		gained := false
+		total := corev1.ResourceList{}
+		for _, member := range pool.Members {
+			if moved[member] {
+				gained = true
+				if names := untracked(pool.Available, used[member]); len(names) > 0 {
+					return invalid("no quota tracks what %s uses of %s, so it cannot be checked against the quota of %s; give %s a quota before moving it",
+						member, strings.Join(names, ", "), root, st.Namespaces[0].Source)
+				}
+			}
+			total = quota.Add(total, used[member])
+		}
+		if !gained {
+			continue
+		}
+		if ok, exceeded := quota.LessThanOrEqual(quota.Mask(total, quota.ResourceNames(pool.Available)), pool.Available); !ok {
+			names := make([]string, 0, len(exceeded))
+			for _, name := range exceeded {
+				names = append(names, string(name))
+			}
+			sort.Strings(names)
+			return invalid("what %s uses does not fit the quota of %s on %s", st.Namespaces[0].Source, root, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// untracked lists the resources available bounds that no ResourceQuota
+// reports the usage of in used. Platform resources are measured rather
+// than reported, so they are always known.
+func untracked(available, used corev1.ResourceList) []string {
+	var out []string
+	for name := range available {
+		if _, ok := used[name]; !ok && !tenantquota.IsPlatformResource(name) {
+			out = append(out, string(name))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// newlyExceeded lists the resources over exceeds by more than already did.
+func newlyExceeded(over, already corev1.ResourceList) []string {
+	var out []string
+	for name, q := range over {
+		if prev, ok := already[name]; ok && q.Cmp(prev) <= 0 {
+			continue
+		}
+		out = append(out, string(name))
+	}
+	sort.Strings(out)
+	return out
+}
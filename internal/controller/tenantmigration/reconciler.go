@@ -0,0 +1,358 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantmigration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+)
+
+// pollInterval is how often a running migration checks on what it is
+// waiting for: namespaces, uninstalls, claims, backups and restores.
+const pollInterval = 15 * time.Second
+
+// +kubebuilder:rbac:groups=cozystack.io,resources=tenantmigrations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cozystack.io,resources=tenantmigrations/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;create
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;create;delete
+// +kubebuilder:rbac:groups="",resources=persistentvolumes,verbs=get;patch
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;create
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,verbs=bind,resourceNames="cozy:tenant";"cozy:tenant:view";"cozy:tenant:use";"cozy:tenant:admin";"cozy:tenant:super-admin";"cozystack-controller:tenant-migration"
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterrolebindings,verbs=get;update;patch,resourceNames="cozystack-controller:tenant-migration"
+// +kubebuilder:rbac:groups=helm.toolkit.fluxcd.io,resources=helmreleases,verbs=get;list;watch;create;patch;delete
+// +kubebuilder:rbac:groups=gateway.cozystack.io,resources=domainclaims,verbs=get;list;create
+// +kubebuilder:rbac:groups=backups.cozystack.io,resources=plans,verbs=get;list;create
+// +kubebuilder:rbac:groups=backups.cozystack.io,resources=backups,verbs=get;list;create
+// +kubebuilder:rbac:groups=backups.cozystack.io,resources=backupjobs;restorejobs,verbs=get;list;create
+
+// Reconciler drives TenantMigrations.
+type Reconciler struct {
+	client.Client
+	// Reader reads Secrets, claims, RoleBindings and backup objects
+	// uncached, so the controller keeps no cluster-wide informer for them.
+	// Nil uses Client.
+	Reader   client.Reader
+	Recorder record.EventRecorder
+	// GrantBinding names the ClusterRoleBinding of the rights only a
+	// running migration needs; Subject is added to it while one runs.
+	// Empty leaves those rights to be granted statically.
+	GrantBinding string
+	Subject      rbacv1.Subject
+	// now stubs the clock in tests.
+	now func() time.Time
+}
+
+func (r *Reconciler) reader() client.Reader {
+	if r.Reader != nil {
+		return r.Reader
+	}
+	return r.Client
+}
+
+func (r *Reconciler) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// Reconcile takes a migration one step further and records where it got.
+// Every completed step changes the status, and the update brings the
+// migration straight back for the next one.
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	m := &cozyv1alpha1.TenantMigration{}
+	if err := r.Get(ctx, req.NamespacedName, m); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, r.syncGrant(ctx)
+		}
+		return ctrl.Result{}, err
+	}
+	// Whether this migration starts, runs or ends, the grant follows.
+	if err := r.syncGrant(ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+	if finished(m.Status.Phase) || !m.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	prev := m.Status.DeepCopy()
+
+	var result ctrl.Result
+	var err error
+	switch m.Status.Phase {
+	case "", cozyv1alpha1.TenantMigrationPending:
+		var st cozyv1alpha1.TenantMigrationStatus
+		st, err = r.plan(ctx, m)
+		if err == nil {
+			now := metav1.NewTime(r.clock())
+			st.Phase = cozyv1alpha1.TenantMigrationProvisioning
+			st.StartedAt = &now
+			m.Status = st
+		}
+	case cozyv1alpha1.TenantMigrationProvisioning:
+		result, err = r.provision(ctx, m)
+	case cozyv1alpha1.TenantMigrationMoving:
+		result, err = r.move(ctx, m)
+	case cozyv1alpha1.TenantMigrationFinalizing:
+		result, err = r.finalize(ctx, m)
+	}
+
+	var verr validationError
+	if errors.As(err, &verr) {
+		m.Status.Phase = cozyv1alpha1.TenantMigrationFailed
+		m.Status.Message = verr.Error()
+		err = nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	if finished(m.Status.Phase) && m.Status.CompletedAt == nil {
+		now := metav1.NewTime(r.clock())
+		m.Status.CompletedAt = &now
+	}
+	if !apiequality.Semantic.DeepEqual(*prev, m.Status) {
+		if err := r.Status().Update(ctx, m); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if prev.Phase != m.Status.Phase {
+		r.recordTransition(m)
+	}
+	return result, nil
+}
+
+// provision marks the old namespaces, creates the new ones parent first and
+// copies their configuration once all of them exist.
+func (r *Reconciler) provision(ctx context.Context, m *cozyv1alpha1.TenantMigration) (ctrl.Result, error) {
+	st := &m.Status
+	for _, pair := range st.Namespaces {
+		if err := r.markSource(ctx, pair.Source, m.Name); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	for _, pair := range st.Namespaces {
+		ready, err := r.namespaceReady(ctx, pair.Destination)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if ready {
+			continue
+		}
+		if err := r.copyTenant(ctx, pair.Source, pair.Destination); err != nil {
+			return ctrl.Result{}, err
+		}
+		st.Message = "waiting for namespace " + pair.Destination
+		return ctrl.Result{RequeueAfter: pollInterval}, nil
+	}
+	for _, pair := range st.Namespaces {
+		if err := r.copyRoleBindings(ctx, st, pair); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.copyDomainClaims(ctx, pair); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	st.Phase = cozyv1alpha1.TenantMigrationMoving
+	st.Message = ""
+	return ctrl.Result{}, nil
+}
+
+// markSource annotates an old namespace with the migration moving it.
+func (r *Reconciler) markSource(ctx context.Context, namespace, migration string) error {
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return err
+	}
+	if ns.Annotations[MigrationAnnotation] == migration {
+		return nil
+	}
+	patch := client.MergeFrom(ns.DeepCopy())
+	metav1.SetMetaDataAnnotation(&ns.ObjectMeta, MigrationAnnotation, migration)
+	return r.Patch(ctx, ns, patch)
+}
+
+// namespaceReady reports whether the tenant chart has created a namespace
+// and the values Secret its applications read.
+func (r *Reconciler) namespaceReady(ctx context.Context, namespace string) (bool, error) {
+	err := r.reader().Get(ctx, client.ObjectKey{Namespace: namespace, Name: "cozystack-values"}, &corev1.Secret{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// move advances the first application not yet moved. Applications go one
+// at a time, so at most one of them is down at any moment.
+func (r *Reconciler) move(ctx context.Context, m *cozyv1alpha1.TenantMigration) (ctrl.Result, error) {
+	st := &m.Status
+	for i := range st.Applications {
+		app := &st.Applications[i]
+		if app.Phase == cozyv1alpha1.TenantMigrationApplicationMoved {
+			continue
+		}
+		progressed, err := r.advance(ctx, m, app)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if app.Phase == cozyv1alpha1.TenantMigrationApplicationFailed {
+			st.Phase = cozyv1alpha1.TenantMigrationFailed
+			st.Message = fmt.Sprintf("%s %s/%s: %s", app.Kind, app.Namespace, app.Name, app.Message)
+			return ctrl.Result{}, nil
+		}
+		st.Message = fmt.Sprintf("moving %s %s/%s", app.Kind, app.Namespace, app.Name)
+		if !progressed {
+			return ctrl.Result{RequeueAfter: pollInterval}, nil
+		}
+		return ctrl.Result{}, nil
+	}
+	st.Phase = cozyv1alpha1.TenantMigrationFinalizing
+	st.Message = ""
+	return ctrl.Result{}, nil
+}
+
+// finalize copies backup Plans, gives rebound volumes their reclaim policy
+// back once their new claims are bound, and deletes the old tenant.
+func (r *Reconciler) finalize(ctx context.Context, m *cozyv1alpha1.TenantMigration) (ctrl.Result, error) {
+	st := &m.Status
+	for _, pair := range st.Namespaces {
+		if err := r.copyPlans(ctx, pair); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	for _, app := range st.Applications {
+		dest := destinationOf(st, app.Namespace)
+		for _, v := range app.Volumes {
+			bound, err := r.restoreReclaimPolicy(ctx, dest, v)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if !bound {
+				st.Message = fmt.Sprintf("waiting for claim %s/%s to bind", dest, v.Claim)
+				return ctrl.Result{RequeueAfter: pollInterval}, nil
+			}
+		}
+	}
+
+	// Flux skips the uninstall of suspended releases, and the old tenant
+	// may have been suspended when it was moved.
+	for _, pair := range st.Namespaces {
+		releases := &helmv2.HelmReleaseList{}
+		if err := r.List(ctx, releases, client.InNamespace(pair.Source)); err != nil {
+			return ctrl.Result{}, err
+		}
+		for i := range releases.Items {
+			if err := r.unsuspend(ctx, &releases.Items[i]); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+	hr := &helmv2.HelmRelease{}
+	err := r.Get(ctx, tenantRelease(st.Namespaces[0].Source), hr)
+	if client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, err
+	}
+	if err == nil {
+		if err := r.unsuspend(ctx, hr); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.Delete(ctx, hr); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, fmt.Errorf("delete tenant %s: %w", st.Namespaces[0].Source, err)
+		}
+	}
+	log.FromContext(ctx).Info("tenant migrated", "from", st.Namespaces[0].Source, "to", st.Destination)
+	st.Phase = cozyv1alpha1.TenantMigrationSucceeded
+	st.Message = ""
+	return ctrl.Result{}, nil
+}
+
+func (r *Reconciler) unsuspend(ctx context.Context, hr *helmv2.HelmRelease) error {
+	if !hr.Spec.Suspend {
+		return nil
+	}
+	patch := client.MergeFrom(hr.DeepCopy())
+	hr.Spec.Suspend = false
+	if err := r.Patch(ctx, hr, patch); err != nil {
+		return fmt.Errorf("resume HelmRelease %s/%s: %w", hr.Namespace, hr.Name, err)
+	}
+	return nil
+}
+
+// restoreReclaimPolicy puts a rebound volume's policy back once its new
+// claim is bound, and reports whether it is.
+func (r *Reconciler) restoreReclaimPolicy(ctx context.Context, namespace string, v cozyv1alpha1.TenantMigrationVolume) (bool, error) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: v.Claim}, pvc); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	if pvc.Status.Phase != corev1.ClaimBound {
+		return false, nil
+	}
+	pv := &corev1.PersistentVolume{}
+	if err := r.Get(ctx, client.ObjectKey{Name: v.Volume}, pv); err != nil {
+		return false, err
+	}
+	policy := corev1.PersistentVolumeReclaimPolicy(v.ReclaimPolicy)
+	if policy == "" || pv.Spec.PersistentVolumeReclaimPolicy == policy {
+		return true, nil
+	}
+	patch := client.MergeFrom(pv.DeepCopy())
+	pv.Spec.PersistentVolumeReclaimPolicy = policy
+	if err := r.Patch(ctx, pv, patch); err != nil {
+		return false, fmt.Errorf("restore reclaim policy of PersistentVolume %s: %w", pv.Name, err)
+	}
+	return true, nil
+}
+
+func (r *Reconciler) recordTransition(m *cozyv1alpha1.TenantMigration) {
+	if r.Recorder == nil {
+		return
+	}
+	switch m.Status.Phase {
+	case cozyv1alpha1.TenantMigrationFailed:
+		r.Recorder.Event(m, corev1.EventTypeWarning, "MigrationFailed", m.Status.Message)
+	case cozyv1alpha1.TenantMigrationSucceeded:
+		r.Recorder.Eventf(m, corev1.EventTypeNormal, "MigrationSucceeded", "%s moved to %s", m.Spec.Tenant, m.Status.Destination)
+	default:
+		r.Recorder.Eventf(m, corev1.EventTypeNormal, string(m.Status.Phase), "migration of %s to %s is %s",
+			m.Spec.Tenant, m.Status.Destination, strings.ToLower(string(m.Status.Phase)))
+	}
+}
+
+// SetupWithManager wires the controller. Progress is polled rather than
+// watched, since a migration waits on many kinds of objects it does not
+// own.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("tenantmigration-controller").
+		For(&cozyv1alpha1.TenantMigration{}).
+		Complete(r)
+}
@@ -0,0 +1,446 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantmigration
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	backupsv1alpha1 "github.com/cozystack/cozystack/api/backups/v1alpha1"
+	gatewayv1alpha1 "github.com/cozystack/cozystack/api/gateway/v1alpha1"
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+)
+
+func newReconciler(t *testing.T, objs ...client.Object) (*Reconciler, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme, helmv2.AddToScheme, backupsv1alpha1.AddToScheme,
+		gatewayv1alpha1.AddToScheme, cozyv1alpha1.AddToScheme,
+	} {
+		if err := add(scheme); err != nil {
+			t.Fatalf("register scheme: %v", err)
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithStatusSubresource(&cozyv1alpha1.TenantMigration{}).Build()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &Reconciler{Client: c, now: func() time.Time { return now }}, c
+}
+
+func ns(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func release(namespace, name, kind, app string) *helmv2.HelmRelease {
+	return &helmv2.HelmRelease{ObjectMeta: metav1.ObjectMeta{
+		Namespace: namespace, Name: name,
+		Labels: map[string]string{
+			appsv1alpha1.ApplicationKindLabel: kind,
+			appsv1alpha1.ApplicationNameLabel: app,
+		},
+	}}
+}
+
+func migration(tenant, newParent string) *cozyv1alpha1.TenantMigration {
+	return &cozyv1alpha1.TenantMigration{
+		ObjectMeta: metav1.ObjectMeta{Name: "move"},
+		Spec:       cozyv1alpha1.TenantMigrationSpec{Tenant: tenant, NewParent: newParent},
+	}
+}
+
+func reconcile(t *testing.T, r *Reconciler) *cozyv1alpha1.TenantMigration {
+	t.Helper()
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "move"}}); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	m := &cozyv1alpha1.TenantMigration{}
+	if err := r.Get(context.Background(), client.ObjectKey{Name: "move"}, m); err != nil {
+		t.Fatalf("get migration: %v", err)
+	}
+	return m
+}
+
+func TestPlan_Refused(t *testing.T) {
+	cases := []struct {
+		name    string
+		objs    []client.Object
+		m       *cozyv1alpha1.TenantMigration
+		message string
+	}{{
+		name:    "under itself",
+		m:       migration("tenant-foo", "tenant-foo-bar"),
+		message: "cannot move tenant-foo under itself",
+	}, {
+		name:    "missing parent",
+		objs:    []client.Object{ns("tenant-foo", nil)},
+		m:       migration("tenant-foo", "tenant-baz"),
+		message: "new parent tenant-baz does not exist",
+	}, {
+		name: "name taken",
+		objs: []client.Object{
+			ns("tenant-foo", nil), ns("tenant-foo-bar", nil), ns("tenant-baz", nil), ns("tenant-baz-bar", nil),
+			release("tenant-foo", "tenant-bar", tenantKind, "bar"),
+		},
+		m:       migration("tenant-foo-bar", "tenant-baz"),
+		message: "namespace tenant-baz-bar already exists",
+	}, {
+		name: "own etcd",
+		objs: []client.Object{
+			ns("tenant-foo", map[string]string{etcdLabel: "tenant-foo"}), ns("tenant-baz", nil),
+			release("tenant-root", "tenant-foo", tenantKind, "foo"),
+		},
+		m:       migration("tenant-foo", "tenant-baz"),
+		message: "tenant-foo runs its own etcd",
+	}, {
+		name: "kubernetes loses its etcd",
+		objs: []client.Object{
+			ns("tenant-foo", map[string]string{etcdLabel: "tenant-root"}), ns("tenant-baz", map[string]string{etcdLabel: "tenant-baz"}),
+			release("tenant-root", "tenant-foo", tenantKind, "foo"),
+			release("tenant-foo", "kubernetes-k8s", "Kubernetes", "k8s"),
+		},
+		m:       migration("tenant-foo", "tenant-baz"),
+		message: "Kubernetes tenant-foo/k8s keeps its data in tenant-root",
+	}, {
+		name: "quota overcommit",
+		objs: []client.Object{
+			ns("tenant-foo", nil), ns("tenant-baz", nil),
+			release("tenant-root", "tenant-foo", tenantKind, "foo"),
+			release("tenant-root", "tenant-baz", tenantKind, "baz"),
+			chartQuota("tenant-foo", "8"), chartQuota("tenant-baz", "4"),
+		},
+		m:       migration("tenant-foo", "tenant-baz"),
+		message: "moving would overcommit the quota of tenant-baz on requests.cpu",
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, _ := newReconciler(t, append(tc.objs, tc.m)...)
+			m := reconcile(t, r)
+			if m.Status.Phase != cozyv1alpha1.TenantMigrationFailed || !strings.Contains(m.Status.Message, tc.message) {
+				t.Errorf("status = %s %q, want Failed with %q", m.Status.Phase, m.Status.Message, tc.message)
+			}
+		})
+	}
+}
+
+func chartQuota(namespace, cpu string) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "tenant-quota"},
+		Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{"requests.cpu": resource.MustParse(cpu)}},
+	}
+}
+
+// trackedQuota is a ResourceQuota reporting cpu usage in a namespace without
+// declaring a budget of its own, like the allocated quota of a pool member.
+func trackedQuota(namespace, hard, used string) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "tenant-quota-allocated"},
+		Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{"requests.cpu": resource.MustParse(hard)}},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{"requests.cpu": resource.MustParse(hard)},
+			Used: corev1.ResourceList{"requests.cpu": resource.MustParse(used)},
+		},
+	}
+}
+
+// TestCheckQuota moves tenant-foo-bar from tenant-foo's pool of 20 CPUs to
+// tenant-baz's pool of 10, of which tenant-baz itself uses 5.
+func TestCheckQuota(t *testing.T) {
+	baz := chartQuota("tenant-baz", "10")
+	baz.Status.Used = corev1.ResourceList{"requests.cpu": resource.MustParse("5")}
+	cases := []struct {
+		name    string
+		bar     []client.Object
+		message string
+	}{{
+		name: "own quota carved out",
+		bar:  []client.Object{chartQuota("tenant-foo-bar", "4")},
+	}, {
+		name:    "own quota overcommits",
+		bar:     []client.Object{chartQuota("tenant-foo-bar", "12")},
+		message: "moving would overcommit the quota of tenant-baz on requests.cpu",
+	}, {
+		name: "shared usage fits",
+		bar:  []client.Object{trackedQuota("tenant-foo-bar", "20", "3")},
+	}, {
+		name:    "shared usage does not fit",
+		bar:     []client.Object{trackedQuota("tenant-foo-bar", "20", "6")},
+		message: "what tenant-foo-bar uses does not fit the quota of tenant-baz on requests.cpu",
+	}, {
+		name:    "shared usage untracked",
+		message: "no quota tracks what tenant-baz-bar uses of requests.cpu",
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			objs := append([]client.Object{
+				release("tenant-root", "tenant-foo", tenantKind, "foo"),
+				release("tenant-root", "tenant-baz", tenantKind, "baz"),
+				release("tenant-foo", "tenant-bar", tenantKind, "bar"),
+				chartQuota("tenant-foo", "20"), baz.DeepCopy(),
+			}, tc.bar...)
+			r, _ := newReconciler(t, objs...)
+			err := r.checkQuota(context.Background(), &cozyv1alpha1.TenantMigrationStatus{
+				Namespaces: []cozyv1alpha1.TenantMigrationNamespace{{Source: "tenant-foo-bar", Destination: "tenant-baz-bar"}},
+			})
+			switch {
+			case tc.message == "" && err != nil:
+				t.Errorf("move refused: %v", err)
+			case tc.message != "" && (err == nil || !strings.Contains(err.Error(), tc.message)):
+				t.Errorf("error = %v, want %q", err, tc.message)
+			}
+		})
+	}
+}
+
+// TestReconcile_RebindMovesSubtree walks tenant-foo-bar, with an
+// application holding a volume, to tenant-baz-bar.
+func TestReconcile_RebindMovesSubtree(t *testing.T) {
+	ctx := context.Background()
+	appLabels := map[string]string{appsv1alpha1.ApplicationKindLabel: "Postgres", appsv1alpha1.ApplicationNameLabel: "db"}
+	db := release("tenant-foo-bar", "postgres-db", "Postgres", "db")
+	db.Annotations = map[string]string{"meta.helm.sh/release-namespace": "tenant-foo-bar"}
+	r, c := newReconciler(t,
+		ns("tenant-foo", nil), ns("tenant-foo-bar", nil), ns("tenant-baz", nil),
+		release("tenant-foo", "tenant-bar", tenantKind, "bar"),
+		db,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo-bar", Name: "db-credentials", Labels: appLabels,
+				Annotations: map[string]string{"meta.helm.sh/release-namespace": "tenant-foo-bar"}},
+			Data: map[string][]byte{"password": []byte("s3cret")},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo-bar", Name: "data-db-0", Labels: appLabels},
+			Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: "pv-1"},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+		},
+		&corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete,
+				StorageClassName:              "replicated",
+				Capacity:                      corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+				ClaimRef:                      &corev1.ObjectReference{Namespace: "tenant-foo-bar", Name: "data-db-0"},
+			},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo-bar", Name: "ops"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "cozy:tenant:admin"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.GroupKind, Name: "tenant-foo-bar-admin"},
+				{Kind: rbacv1.UserKind, Name: "tenant-foo-bar-admin"},
+				{Kind: rbacv1.GroupKind, Name: "tenant-foo-bar-oncall"},
+				{Kind: rbacv1.ServiceAccountKind, Namespace: "tenant-foo-bar", Name: "tenant-foo-bar"},
+			},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo-bar", Name: "custom"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "cluster-admin"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "alice"}},
+		},
+		migration("tenant-foo-bar", "tenant-baz"),
+	)
+
+	m := reconcile(t, r)
+	if m.Status.Phase != cozyv1alpha1.TenantMigrationProvisioning || m.Status.Destination != "tenant-baz-bar" {
+		t.Fatalf("after planning: %s to %q (%s)", m.Status.Phase, m.Status.Destination, m.Status.Message)
+	}
+	if len(m.Status.Applications) != 1 || m.Status.Applications[0].Name != "db" {
+		t.Fatalf("applications = %+v, want db", m.Status.Applications)
+	}
+
+	reconcile(t, r)
+	tenant := &helmv2.HelmRelease{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tenant-baz", Name: "tenant-bar"}, tenant); err != nil {
+		t.Fatalf("new Tenant application: %v", err)
+	}
+	source := &corev1.Namespace{}
+	_ = c.Get(ctx, client.ObjectKey{Name: "tenant-foo-bar"}, source)
+	if source.Annotations[MigrationAnnotation] != "move" {
+		t.Errorf("source namespace not marked as migrating")
+	}
+	// The tenant chart creates the namespace.
+	for _, obj := range []client.Object{
+		ns("tenant-baz-bar", nil),
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-baz-bar", Name: "cozystack-values"}},
+	} {
+		if err := c.Create(ctx, obj); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	if m = reconcile(t, r); m.Status.Phase != cozyv1alpha1.TenantMigrationMoving {
+		t.Fatalf("phase = %s, want Moving (%s)", m.Status.Phase, m.Status.Message)
+	}
+	rb := &rbacv1.RoleBinding{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tenant-baz-bar", Name: "ops"}, rb); err != nil {
+		t.Fatalf("copied RoleBinding: %v", err)
+	}
+	want := []rbacv1.Subject{
+		{Kind: rbacv1.GroupKind, Name: "tenant-baz-bar-admin"},
+		{Kind: rbacv1.UserKind, Name: "tenant-foo-bar-admin"},
+		{Kind: rbacv1.GroupKind, Name: "tenant-foo-bar-oncall"},
+		{Kind: rbacv1.ServiceAccountKind, Namespace: "tenant-baz-bar", Name: "tenant-baz-bar"},
+	}
+	if !apiequality.Semantic.DeepEqual(rb.Subjects, want) {
+		t.Errorf("subjects = %+v, want only the access group and service account renamed", rb.Subjects)
+	}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tenant-baz-bar", Name: "custom"}, &rbacv1.RoleBinding{}); !apierrors.IsNotFound(err) {
+		t.Errorf("binding of a role other than the tenant roles copied: %v", err)
+	}
+
+	reconcile(t, r)
+	held := &helmv2.HelmRelease{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tenant-baz-bar", Name: "postgres-db"}, held); err != nil {
+		t.Fatalf("new release: %v", err)
+	}
+	if !held.Spec.Suspend || held.Annotations["meta.helm.sh/release-namespace"] != "tenant-baz-bar" {
+		t.Errorf("new release must be held with its ownership rewritten: suspend=%v annotations=%v", held.Spec.Suspend, held.Annotations)
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tenant-baz-bar", Name: "db-credentials"}, secret); err != nil || string(secret.Data["password"]) != "s3cret" {
+		t.Errorf("credentials not copied: %v", err)
+	}
+	pv := &corev1.PersistentVolume{}
+	_ = c.Get(ctx, client.ObjectKey{Name: "pv-1"}, pv)
+	if pv.Spec.PersistentVolumeReclaimPolicy != corev1.PersistentVolumeReclaimRetain {
+		t.Errorf("reclaim policy = %s, want Retain", pv.Spec.PersistentVolumeReclaimPolicy)
+	}
+
+	// Stopping, then Releasing: each deletes and waits a round for it.
+	for range 4 {
+		m = reconcile(t, r)
+	}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo-bar", Name: "postgres-db"}, &helmv2.HelmRelease{}); !apierrors.IsNotFound(err) {
+		t.Errorf("old release still there: %v", err)
+	}
+	_ = c.Get(ctx, client.ObjectKey{Name: "pv-1"}, pv)
+	if ref := pv.Spec.ClaimRef; ref == nil || ref.Namespace != "tenant-baz-bar" || ref.Name != "data-db-0" {
+		t.Errorf("volume not pre-bound to the new claim: %+v", ref)
+	}
+
+	if m = reconcile(t, r); m.Status.Applications[0].Phase != cozyv1alpha1.TenantMigrationApplicationMoved {
+		t.Fatalf("application phase = %s (%s), want Moved", m.Status.Applications[0].Phase, m.Status.Applications[0].Message)
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tenant-baz-bar", Name: "data-db-0"}, pvc); err != nil || pvc.Spec.VolumeName != "pv-1" {
+		t.Fatalf("new claim for pv-1: %v", err)
+	}
+	_ = c.Get(ctx, client.ObjectKey{Namespace: "tenant-baz-bar", Name: "postgres-db"}, held)
+	if held.Spec.Suspend {
+		t.Errorf("new release still held")
+	}
+
+	if m = reconcile(t, r); m.Status.Phase != cozyv1alpha1.TenantMigrationFinalizing {
+		t.Fatalf("phase = %s, want Finalizing", m.Status.Phase)
+	}
+	if m = reconcile(t, r); m.Status.Phase != cozyv1alpha1.TenantMigrationFinalizing {
+		t.Fatalf("must wait for the new claim to bind, got %s", m.Status.Phase)
+	}
+	pvc.Status.Phase = corev1.ClaimBound
+	if err := c.Status().Update(ctx, pvc); err != nil {
+		t.Fatalf("bind claim: %v", err)
+	}
+	if m = reconcile(t, r); m.Status.Phase != cozyv1alpha1.TenantMigrationSucceeded || m.Status.CompletedAt == nil {
+		t.Fatalf("phase = %s (%s), want Succeeded", m.Status.Phase, m.Status.Message)
+	}
+	_ = c.Get(ctx, client.ObjectKey{Name: "pv-1"}, pv)
+	if pv.Spec.PersistentVolumeReclaimPolicy != corev1.PersistentVolumeReclaimDelete {
+		t.Errorf("reclaim policy = %s, want Delete restored", pv.Spec.PersistentVolumeReclaimPolicy)
+	}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo", Name: "tenant-bar"}, &helmv2.HelmRelease{}); !apierrors.IsNotFound(err) {
+		t.Errorf("old Tenant application still there: %v", err)
+	}
+}
+
+func TestRenamed(t *testing.T) {
+	st := &cozyv1alpha1.TenantMigrationStatus{Namespaces: []cozyv1alpha1.TenantMigrationNamespace{
+		{Source: "tenant-foo", Destination: "tenant-baz-foo"},
+		{Source: "tenant-foo-bar", Destination: "tenant-baz-foo-bar"},
+	}}
+	for in, want := range map[string]string{
+		"tenant-foo-bar-use": "tenant-baz-foo-bar-use",
+		"tenant-foo":         "tenant-baz-foo",
+		"tenant-foobar":      "tenant-foobar",
+		"tenant-root-admin":  "tenant-root-admin",
+	} {
+		if got := renamed(st, in); got != want {
+			t.Errorf("renamed(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestTenantRelease(t *testing.T) {
+	for in, want := range map[string]client.ObjectKey{
+		"tenant-foo":     {Namespace: "tenant-root", Name: "tenant-foo"},
+		"tenant-foo-bar": {Namespace: "tenant-foo", Name: "tenant-bar"},
+	} {
+		if got := tenantRelease(in); got != want {
+			t.Errorf("tenantRelease(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestSyncGrant(t *testing.T) {
+	ctx := context.Background()
+	r, c := newReconciler(t,
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: DefaultGrantBinding},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: DefaultGrantBinding},
+		},
+		migration("tenant-foo", "tenant-foo"),
+	)
+	r.GrantBinding = DefaultGrantBinding
+	r.Subject = rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: "cozy-system", Name: "cozystack-controller"}
+	subjects := func() []rbacv1.Subject {
+		crb := &rbacv1.ClusterRoleBinding{}
+		if err := c.Get(ctx, client.ObjectKey{Name: DefaultGrantBinding}, crb); err != nil {
+			t.Fatalf("get binding: %v", err)
+		}
+		return crb.Subjects
+	}
+
+	// Planning refuses the move, so the grant comes and goes with it.
+	if err := r.syncGrant(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := subjects(); len(got) != 1 || got[0] != r.Subject {
+		t.Fatalf("subjects while a migration runs = %+v", got)
+	}
+	if m := reconcile(t, r); m.Status.Phase != cozyv1alpha1.TenantMigrationFailed {
+		t.Fatalf("phase = %s, want Failed", m.Status.Phase)
+	}
+	reconcile(t, r)
+	if got := subjects(); len(got) != 0 {
+		t.Errorf("subjects after the migration ended = %+v, want none", got)
+	}
+}
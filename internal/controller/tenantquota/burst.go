@@ -0,0 +1,349 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantquota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	quota "k8s.io/apiserver/pkg/quota/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+)
+
+// Approved QuotaRequests raise a tenant's budget. Kubernetes enforces the
+// smallest of a namespace's ResourceQuotas, so the raise has to reach the
+// chart-rendered tenant-quota itself; writing it into that Helm-managed
+// object would be reverted by every upgrade and drift correction. Instead
+// the controller owns a values Secret next to the tenant's HelmRelease,
+// which reads it through an optional valuesFrom entry, and the chart
+// renders the raised limits. The chart records the budget it declared,
+// before any raise, in DeclaredAnnotation.
+const (
+	// DeclaredAnnotation holds the tenant-quota budget the chart declared
+	// from tenant.spec.resourceQuotas, as JSON.
+	DeclaredAnnotation = "quota.cozystack.io/declared"
+	// burstValuesSuffix names the values Secret after the tenant's
+	// HelmRelease.
+	burstValuesSuffix = "-quota-burst"
+	// burstValuesKey is the chart value carrying the raised limits.
+	burstValuesKey = "_quotaBurst"
+)
+
+// BurstValuesReference is the valuesFrom entry through which the tenant
+// HelmRelease releaseName picks up its approved quota raises. Optional: the
+// Secret only exists while a raise is in force.
+func BurstValuesReference(releaseName string) helmv2.ValuesReference {
+	return helmv2.ValuesReference{
+		Kind:     "Secret",
+		Name:     releaseName + burstValuesSuffix,
+		Optional: true,
+	}
+}
+
+// declaredHard recovers the budget the chart declared from its tenant-quota.
+// A tenant-quota rendered before the chart recorded it carries no raise, so
+// its spec.hard is the declared budget.
+func declaredHard(rq *corev1.ResourceQuota) corev1.ResourceList {
+	raw, ok := rq.Annotations[DeclaredAnnotation]
+	if !ok {
+		return rq.Spec.Hard
+	}
+	var declared corev1.ResourceList
+	if err := json.Unmarshal([]byte(raw), &declared); err != nil {
+		return rq.Spec.Hard
+	}
+	return declared
+}
+
+// EvaluateQuotaRequest computes the status of qr at now. base is the
+// namespace's declared budget before any raise, nil when it has none.
+func EvaluateQuotaRequest(qr *cozyv1alpha1.QuotaRequest, base corev1.ResourceList, now time.Time) cozyv1alpha1.QuotaRequestStatus {
+	st := *qr.Status.DeepCopy()
+	if st.Phase == cozyv1alpha1.QuotaRequestExpired || st.Phase == cozyv1alpha1.QuotaRequestDenied {
+		return st
+	}
+	// An approval the controller refused stays refused: the approver
+	// decided on a budget that was not there, and must decide again on a
+	// new request rather than have this one start whenever room appears.
+	if st.Phase == cozyv1alpha1.QuotaRequestInvalid && qr.Spec.Decision != nil && qr.Spec.Decision.Approved {
+		return st
+	}
+	st.Message = ""
+	if d := qr.Spec.Decision; d != nil && !d.Approved {
+		st.Phase = cozyv1alpha1.QuotaRequestDenied
+		return st
+	}
+	if st.Phase != cozyv1alpha1.QuotaRequestActive {
+		if msg := invalidRequest(qr, base); msg != "" {
+			st.Phase = cozyv1alpha1.QuotaRequestInvalid
+			st.Message = msg
+			return st
+		}
+	}
+	if qr.Spec.Decision == nil {
+		st.Phase = cozyv1alpha1.QuotaRequestPending
+		return st
+	}
+
+	if st.ApprovedAt == nil {
+		at := metav1.NewTime(now)
+		st.ApprovedAt = &at
+	}
+	expires := metav1.NewTime(st.ApprovedAt.Add(qr.Spec.Duration.Duration))
+	st.ExpiresAt = &expires
+	if !now.Before(expires.Time) {
+		st.Phase = cozyv1alpha1.QuotaRequestExpired
+	} else {
+		st.Phase = cozyv1alpha1.QuotaRequestActive
+	}
+	return st
+}
+
+func invalidRequest(qr *cozyv1alpha1.QuotaRequest, base corev1.ResourceList) string {
+	if len(base) == 0 {
+		return "the tenant declares no resourceQuotas to raise"
+	}
+	if qr.Spec.Duration.Duration <= 0 {
+		return "spec.duration must be positive"
+	}
+	names := make([]string, 0, len(qr.Spec.Resources))
+	for name := range qr.Spec.Resources {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		q := qr.Spec.Resources[corev1.ResourceName(name)]
+		if q.Sign() < 0 {
+			return fmt.Sprintf("%s must not be negative", name)
+		}
+		if _, ok := base[corev1.ResourceName(name)]; !ok {
+			return fmt.Sprintf("%s is not limited by the tenant's quota", name)
+		}
+	}
+	return ""
+}
+
+// reconcileRequests advances every QuotaRequest and returns the raises in
+// force per namespace, plus the earliest time one of them ends. A request
+// approved since the last sweep only takes effect when the raise fits the
+// pool its parent tenant's budget governs; otherwise it is refused.
+func (r *Reconciler) reconcileRequests(ctx context.Context, releases []helmv2.HelmRelease, state QuotaState, now time.Time) (map[string]corev1.ResourceList, time.Time, error) {
+	logger := log.FromContext(ctx)
+	requests := &cozyv1alpha1.QuotaRequestList{}
+	if err := r.List(ctx, requests); err != nil {
+		return nil, time.Time{}, err
+	}
+	sort.Slice(requests.Items, func(i, j int) bool {
+		a, b := &requests.Items[i], &requests.Items[j]
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		return a.Namespace+"/"+a.Name < b.Namespace+"/"+b.Name
+	})
+
+	statuses := make([]cozyv1alpha1.QuotaRequestStatus, len(requests.Items))
+	bursts := map[string]corev1.ResourceList{}
+	for i := range requests.Items {
+		qr := &requests.Items[i]
+		statuses[i] = EvaluateQuotaRequest(qr, state.Base[qr.Namespace], now)
+		if statuses[i].Phase == cozyv1alpha1.QuotaRequestActive && qr.Status.Phase == cozyv1alpha1.QuotaRequestActive {
+			bursts[qr.Namespace] = quota.Add(bursts[qr.Namespace], qr.Spec.Resources)
+		}
+	}
+	// Raises already in force are part of the pools new approvals are
+	// checked against; each admitted approval is, for the ones after it.
+	for i := range requests.Items {
+		qr := &requests.Items[i]
+		if statuses[i].Phase != cozyv1alpha1.QuotaRequestActive || qr.Status.Phase == cozyv1alpha1.QuotaRequestActive {
+			continue
+		}
+		if msg := parentPoolShortfall(TenantsFromReleases(releases, state.Budgets(bursts)), qr.Namespace, qr.Spec.Resources); msg != "" {
+			statuses[i] = cozyv1alpha1.QuotaRequestStatus{Phase: cozyv1alpha1.QuotaRequestInvalid, Message: msg}
+			continue
+		}
+		bursts[qr.Namespace] = quota.Add(bursts[qr.Namespace], qr.Spec.Resources)
+	}
+
+	var next time.Time
+	for i := range requests.Items {
+		qr := &requests.Items[i]
+		st := statuses[i]
+		if st.Phase == cozyv1alpha1.QuotaRequestActive && (next.IsZero() || st.ExpiresAt.Before(&metav1.Time{Time: next})) {
+			next = st.ExpiresAt.Time
+		}
+		if apiequality.Semantic.DeepEqual(st, qr.Status) {
+			continue
+		}
+		prev := qr.Status.Phase
+		qr.Status = st
+		if err := r.Status().Update(ctx, qr); err != nil {
+			logger.Error(err, "failed to update quota request status", "namespace", qr.Namespace, "name", qr.Name)
+			continue
+		}
+		if prev != st.Phase {
+			r.recordDecision(qr)
+		}
+	}
+	return bursts, next, nil
+}
+
+// parentPoolShortfall explains why raising ns's budget by raise would
+// promise sub-tenants more than the pool of its parent tenant holds, or
+// returns "" when the raise fits. A tenant with no bounded ancestor draws on
+// no pool and is only limited by its own budget.
+func parentPoolShortfall(tenants []Tenant, ns string, raise corev1.ResourceList) string {
+	declaredByNS := make(map[string]corev1.ResourceList, len(tenants))
+	for i := range tenants {
+		if len(tenants[i].Declared) == 0 {
+			continue
+		}
+		if tenants[i].Namespace == ns {
+			tenants[i].Declared = quota.Add(tenants[i].Declared, raise)
+		}
+		declaredByNS[tenants[i].Namespace] = tenants[i].Declared
+	}
+	root := poolRootOf(parentNamespace(ns), declaredByNS)
+	if root == "" {
+		return ""
+	}
+	over := ComputePools(tenants)[root].Overcommitted()
+	names := make([]string, 0, len(raise))
+	for name := range raise {
+		if _, ok := over[name]; ok {
+			names = append(names, string(name))
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		q := over[corev1.ResourceName(name)]
+		parts = append(parts, name+"="+q.String())
+	}
+	return fmt.Sprintf("the raise exceeds the budget left in the pool of tenant %s by %s", root, strings.Join(parts, ", "))
+}
+
+// recordDecision leaves an Event on the request for each phase change, so
+// who approved what, and when it lapsed, stays auditable next to the
+// request itself.
+func (r *Reconciler) recordDecision(qr *cozyv1alpha1.QuotaRequest) {
+	if r.Recorder == nil {
+		return
+	}
+	switch qr.Status.Phase {
+	case cozyv1alpha1.QuotaRequestActive:
+		r.Recorder.Eventf(qr, corev1.EventTypeNormal, "QuotaRequestApproved",
+			"approved by %s: %s until %s", qr.Spec.Decision.By, resourceListString(qr.Spec.Resources), qr.Status.ExpiresAt.UTC().Format(time.RFC3339))
+	case cozyv1alpha1.QuotaRequestDenied:
+		r.Recorder.Eventf(qr, corev1.EventTypeNormal, "QuotaRequestDenied", "denied by %s", qr.Spec.Decision.By)
+	case cozyv1alpha1.QuotaRequestExpired:
+		r.Recorder.Eventf(qr, corev1.EventTypeNormal, "QuotaRequestExpired", "raise of %s approved by %s has expired",
+			resourceListString(qr.Spec.Resources), qr.Spec.Decision.By)
+	case cozyv1alpha1.QuotaRequestInvalid:
+		r.Recorder.Eventf(qr, corev1.EventTypeWarning, "QuotaRequestInvalid", "%s", qr.Status.Message)
+	}
+}
+
+// overlay adds a raise to a declared budget. Only resources the budget
+// limits are raised; anything else would turn an unlimited resource into a
+// limited one.
+func overlay(declared, burst corev1.ResourceList) corev1.ResourceList {
+	if len(burst) == 0 {
+		return declared
+	}
+	out := corev1.ResourceList{}
+	for name, q := range declared {
+		sum := q.DeepCopy()
+		if extra, ok := burst[name]; ok {
+			sum.Add(extra)
+		}
+		out[name] = sum
+	}
+	return out
+}
+
+// applyBursts keeps each tenant's values Secret in step with the raises in
+// force: the raised limit of every resource a raise applies to, read by the
+// chart through BurstValuesReference. The Secret of a tenant with no raise
+// left is deleted, which puts the declared budget back on the next upgrade.
+func (r *Reconciler) applyBursts(ctx context.Context, releases []helmv2.HelmRelease, base, bursts map[string]corev1.ResourceList) error {
+	desired := map[types.NamespacedName]struct{}{}
+	for i := range releases {
+		hr := &releases[i]
+		ns := ownedNamespace(hr.Namespace, strings.TrimPrefix(hr.Name, tenantNamespacePrefix))
+		burst := bursts[ns]
+		if len(burst) == 0 {
+			continue
+		}
+		raised := quota.Mask(overlay(base[ns], burst), quota.ResourceNames(burst))
+		limits := make(map[string]string, len(raised))
+		for name, q := range raised {
+			limits[string(name)] = q.String()
+		}
+		values, err := yaml.Marshal(map[string]any{burstValuesKey: limits})
+		if err != nil {
+			return err
+		}
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: hr.Namespace, Name: hr.Name + burstValuesSuffix}}
+		desired[client.ObjectKeyFromObject(secret)] = struct{}{}
+		if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+			if secret.Labels == nil {
+				secret.Labels = map[string]string{}
+			}
+			secret.Labels[managedByLabel] = managedByValue
+			// helm-controller upgrades the release as soon as a watched
+			// valuesFrom source changes.
+			secret.Labels["reconcile.fluxcd.io/watch"] = "Enabled"
+			secret.Data = map[string][]byte{"values.yaml": values}
+			return controllerutil.SetOwnerReference(hr, secret, r.Scheme)
+		}); err != nil {
+			return fmt.Errorf("write quota raise for %s/%s: %w", hr.Namespace, hr.Name, err)
+		}
+	}
+
+	managed := &corev1.SecretList{}
+	if err := r.List(ctx, managed, client.MatchingLabels{managedByLabel: managedByValue}); err != nil {
+		return err
+	}
+	for i := range managed.Items {
+		m := &managed.Items[i]
+		if _, keep := desired[client.ObjectKeyFromObject(m)]; keep {
+			continue
+		}
+		if err := r.Delete(ctx, m); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantquota
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+)
+
+func quotaRequest(namespace, name string, resources map[string]string, decision *cozyv1alpha1.QuotaRequestDecision) *cozyv1alpha1.QuotaRequest {
+	return &cozyv1alpha1.QuotaRequest{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: cozyv1alpha1.QuotaRequestSpec{
+			Resources: resourceList(resources),
+			Duration:  metav1.Duration{Duration: time.Hour},
+			Decision:  decision,
+		},
+	}
+}
+
+func chartHard(t *testing.T, c client.Client, namespace string) *corev1.ResourceQuota {
+	t.Helper()
+	rq := &corev1.ResourceQuota{}
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: chartQuotaName}, rq); err != nil {
+		t.Fatalf("get tenant-quota: %v", err)
+	}
+	return rq
+}
+
+// burstValues returns the raised limits in a tenant's values Secret, and
+// whether the Secret exists.
+func burstValues(t *testing.T, c client.Client, namespace, release string) (map[string]string, bool) {
+	t.Helper()
+	secret := &corev1.Secret{}
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: release + burstValuesSuffix}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, false
+		}
+		t.Fatalf("get values Secret: %v", err)
+	}
+	var values map[string]map[string]string
+	if err := yaml.Unmarshal(secret.Data["values.yaml"], &values); err != nil {
+		t.Fatalf("decode values: %v", err)
+	}
+	return values[burstValuesKey], true
+}
+
+func getQuotaRequest(t *testing.T, c client.Client, namespace, name string) *cozyv1alpha1.QuotaRequest {
+	t.Helper()
+	qr := &cozyv1alpha1.QuotaRequest{}
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, qr); err != nil {
+		t.Fatalf("get request: %v", err)
+	}
+	return qr
+}
+
+func TestEvaluateQuotaRequest(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	base := resourceList(map[string]string{"requests.cpu": "4"})
+	approved := &cozyv1alpha1.QuotaRequestDecision{Approved: true, By: "alice"}
+
+	if st := EvaluateQuotaRequest(quotaRequest("tenant-foo", "r", map[string]string{"requests.cpu": "2"}, nil), base, now); st.Phase != cozyv1alpha1.QuotaRequestPending {
+		t.Errorf("undecided phase = %s, want Pending", st.Phase)
+	}
+	if st := EvaluateQuotaRequest(quotaRequest("tenant-foo", "r", map[string]string{"requests.memory": "1Gi"}, approved), base, now); st.Phase != cozyv1alpha1.QuotaRequestInvalid {
+		t.Errorf("unlimited resource phase = %s, want Invalid", st.Phase)
+	}
+
+	qr := quotaRequest("tenant-foo", "r", map[string]string{"requests.cpu": "2"}, approved)
+	st := EvaluateQuotaRequest(qr, base, now)
+	if st.Phase != cozyv1alpha1.QuotaRequestActive || !st.ExpiresAt.Time.Equal(now.Add(time.Hour)) {
+		t.Fatalf("approved status = %+v, want Active until now+1h", st)
+	}
+	qr.Status = st
+	if st := EvaluateQuotaRequest(qr, base, now.Add(2*time.Hour)); st.Phase != cozyv1alpha1.QuotaRequestExpired {
+		t.Errorf("phase after expiry = %s, want Expired", st.Phase)
+	}
+}
+
+// TestReconcile_QuotaRequestRaisesAndRestores: an approved request is
+// handed to the chart through the tenant's values Secret, widens the pool
+// its unbounded child shares, and once it is gone the Secret is removed.
+// The Helm-managed tenant-quota itself is never written.
+func TestReconcile_QuotaRequestRaisesAndRestores(t *testing.T) {
+	r, c := newReconciler(t,
+		tenantHR("foo", "tenant-root"),
+		tenantHR("bar", "tenant-foo"),
+		ns("tenant-foo"), ns("tenant-foo-bar"),
+		chartQuota("tenant-foo", map[string]string{"requests.cpu": "10", "requests.memory": "4Gi"}, nil),
+		quotaRequest("tenant-foo", "burst", map[string]string{"requests.cpu": "5"},
+			&cozyv1alpha1.QuotaRequestDecision{Approved: true, By: "admin"}),
+	)
+	ctx := context.Background()
+	if _, err := r.Reconcile(ctx, sweepKey); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if got, ok := burstValues(t, c, "tenant-root", "tenant-foo"); !ok || !reflect.DeepEqual(got, map[string]string{"requests.cpu": "15"}) {
+		t.Errorf("raised limits = %v (exists %v), want requests.cpu=15 only", got, ok)
+	}
+	if got := chartHard(t, c, "tenant-foo").Spec.Hard[corev1.ResourceName("requests.cpu")]; got.String() != "10" {
+		t.Errorf("tenant-quota requests.cpu = %s, want the chart's 10 left alone", got.String())
+	}
+	if got, ok := allocatedHard(t, c, "tenant-foo-bar", "requests.cpu"); !ok || got != "15" {
+		t.Errorf("child allocated requests.cpu = %q, want 15", got)
+	}
+	qr := getQuotaRequest(t, c, "tenant-foo", "burst")
+	if qr.Status.Phase != cozyv1alpha1.QuotaRequestActive {
+		t.Errorf("phase = %s, want Active", qr.Status.Phase)
+	}
+
+	// Once Helm has rendered the raise, the declared budget is still read
+	// from the chart's annotation rather than the raised spec.hard.
+	rq := chartHard(t, c, "tenant-foo")
+	rq.Annotations = map[string]string{DeclaredAnnotation: `{"requests.cpu":"10","requests.memory":"4Gi"}`}
+	rq.Spec.Hard[corev1.ResourceName("requests.cpu")] = resource.MustParse("15")
+	if err := c.Update(ctx, rq); err != nil {
+		t.Fatalf("render raise: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, sweepKey); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got, _ := burstValues(t, c, "tenant-root", "tenant-foo"); got["requests.cpu"] != "15" {
+		t.Errorf("raised requests.cpu after resweep = %s, want 15", got["requests.cpu"])
+	}
+
+	if err := c.Delete(ctx, qr); err != nil {
+		t.Fatalf("delete request: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, sweepKey); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if _, ok := burstValues(t, c, "tenant-root", "tenant-foo"); ok {
+		t.Errorf("values Secret must be removed once no raise is left")
+	}
+}
+
+// TestReconcile_QuotaRequestRefusedOverParentPool: a raise approved for a
+// bounded sub-tenant is refused when its parent's pool cannot hold it, and
+// stays refused once room appears; a raise that fits is applied.
+func TestReconcile_QuotaRequestRefusedOverParentPool(t *testing.T) {
+	approved := &cozyv1alpha1.QuotaRequestDecision{Approved: true, By: "owner"}
+	r, c := newReconciler(t,
+		tenantHR("foo", "tenant-root"),
+		tenantHR("bar", "tenant-foo"),
+		tenantHR("baz", "tenant-foo"),
+		ns("tenant-foo"), ns("tenant-foo-bar"), ns("tenant-foo-baz"),
+		chartQuota("tenant-foo", map[string]string{"requests.cpu": "10"}, nil),
+		chartQuota("tenant-foo-bar", map[string]string{"requests.cpu": "6"}, nil),
+		chartQuota("tenant-foo-baz", map[string]string{"requests.cpu": "3"}, nil),
+		quotaRequest("tenant-foo-bar", "too-big", map[string]string{"requests.cpu": "2"}, approved),
+	)
+	ctx := context.Background()
+	if _, err := r.Reconcile(ctx, sweepKey); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	qr := getQuotaRequest(t, c, "tenant-foo-bar", "too-big")
+	want := "the raise exceeds the budget left in the pool of tenant tenant-foo by requests.cpu=1"
+	if qr.Status.Phase != cozyv1alpha1.QuotaRequestInvalid || qr.Status.Message != want {
+		t.Errorf("status = %+v, want Invalid with %q", qr.Status, want)
+	}
+	if _, ok := burstValues(t, c, "tenant-foo", "tenant-bar"); ok {
+		t.Errorf("a refused raise must not reach the chart")
+	}
+
+	// Shrinking the sibling frees the room, but the refusal stands.
+	sibling := chartHard(t, c, "tenant-foo-baz")
+	sibling.Spec.Hard[corev1.ResourceName("requests.cpu")] = resource.MustParse("1")
+	if err := c.Update(ctx, sibling); err != nil {
+		t.Fatalf("shrink sibling: %v", err)
+	}
+	if err := c.Create(ctx, quotaRequest("tenant-foo-bar", "fits", map[string]string{"requests.cpu": "2"}, approved)); err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, sweepKey); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := getQuotaRequest(t, c, "tenant-foo-bar", "too-big").Status.Phase; got != cozyv1alpha1.QuotaRequestInvalid {
+		t.Errorf("refused request phase = %s, want it to stay Invalid", got)
+	}
+	if got := getQuotaRequest(t, c, "tenant-foo-bar", "fits").Status.Phase; got != cozyv1alpha1.QuotaRequestActive {
+		t.Errorf("fitting request phase = %s, want Active", got)
+	}
+	if got, ok := burstValues(t, c, "tenant-foo", "tenant-bar"); !ok || got["requests.cpu"] != "8" {
+		t.Errorf("raised requests.cpu = %v, want 8", got)
+	}
+}
+
+// TestDeclaredHard_ReadsChartAnnotation: the declared budget is the one the
+// chart recorded, not the raised spec.hard; a tenant-quota rendered without
+// the annotation carries no raise.
+func TestDeclaredHard_ReadsChartAnnotation(t *testing.T) {
+	rq := chartQuota("tenant-foo", map[string]string{"requests.cpu": "15", "requests.memory": "4Gi"}, nil)
+	if got := declaredHard(rq)[corev1.ResourceName("requests.cpu")]; got.String() != "15" {
+		t.Errorf("unannotated requests.cpu = %s, want spec.hard 15", got.String())
+	}
+	rq.Annotations = map[string]string{DeclaredAnnotation: `{"requests.cpu":10,"requests.memory":"4Gi"}`}
+	declared := declaredHard(rq)
+	if got := declared[corev1.ResourceName("requests.cpu")]; got.String() != "10" {
+		t.Errorf("declared requests.cpu = %s, want the chart's 10", got.String())
+	}
+	if got := declared[corev1.ResourceName("requests.memory")]; got.String() != "4Gi" {
+		t.Errorf("declared requests.memory = %s, want 4Gi", got.String())
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantquota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	quota "k8s.io/apiserver/pkg/quota/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	backupsv1alpha1 "github.com/cozystack/cozystack/api/backups/v1alpha1"
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+)
+
+// Platform resources are resourceQuotas keys Kubernetes ResourceQuota has no
+// notion of. The tenant chart leaves them out of tenant-quota's spec.hard and
+// records them in PlatformAnnotation instead, and ReadPlatformUsage measures
+// them from their own sources, so they take part in the same pools as every
+// other key. Only the aggregated apiserver enforces them, at Application
+// admission.
+const (
+	// PlatformAnnotation holds the platform resources the tenant declared,
+	// as JSON.
+	PlatformAnnotation = "quota.cozystack.io/platform"
+
+	// ExternalIPs counts LoadBalancer Services holding an external IP.
+	ExternalIPs corev1.ResourceName = "external-ips"
+	// BucketStorage is the logical size of the tenant's S3 buckets as
+	// recorded on bucket Workloads by the WorkloadMonitor reconciler.
+	BucketStorage corev1.ResourceName = "buckets.storage"
+	// BackupStorage is the artifact size of the tenant's Backups.
+	BackupStorage corev1.ResourceName = "backups.storage"
+)
+
+// platformResources lists every platform resource; kept in sync with
+// cozy-lib.resources.platformQuotaKeys.
+var platformResources = []corev1.ResourceName{ExternalIPs, BucketStorage, BackupStorage}
+
+// BucketWorkloadResource is the Workload status.resources key the
+// WorkloadMonitor reconciler records a bucket's logical size under.
+const BucketWorkloadResource = "s3-storage-bytes"
+
+// IsPlatformResource reports whether name is a platform resource.
+func IsPlatformResource(name corev1.ResourceName) bool {
+	for _, p := range platformResources {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutPlatform drops the platform resources from rl: what is left can be
+// written into a ResourceQuota.
+func withoutPlatform(rl corev1.ResourceList) corev1.ResourceList {
+	out := corev1.ResourceList{}
+	for name, q := range rl {
+		if !IsPlatformResource(name) {
+			out[name] = q
+		}
+	}
+	return out
+}
+
+// declaredPlatform reads the platform resources the chart recorded on its
+// tenant-quota.
+func declaredPlatform(rq *corev1.ResourceQuota) corev1.ResourceList {
+	raw, ok := rq.Annotations[PlatformAnnotation]
+	if !ok {
+		return nil
+	}
+	var declared corev1.ResourceList
+	if err := json.Unmarshal([]byte(raw), &declared); err != nil {
+		return nil
+	}
+	return declared
+}
+
+// ReadPlatformUsage measures the platform resources names in namespace, or in
+// every namespace when namespace is "", keyed by namespace. A namespace
+// using none of them is absent. Sources that are not installed in the
+// cluster (no Backup CRD, say) count as zero usage.
+func ReadPlatformUsage(ctx context.Context, c client.Reader, namespace string, names []corev1.ResourceName) (map[string]corev1.ResourceList, error) {
+	out := map[string]corev1.ResourceList{}
+	add := func(ns string, name corev1.ResourceName, q resource.Quantity) {
+		out[ns] = quota.Add(out[ns], corev1.ResourceList{name: q})
+	}
+	var opts []client.ListOption
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	for _, name := range names {
+		switch name {
+		case ExternalIPs:
+			services := &corev1.ServiceList{}
+			if err := c.List(ctx, services, opts...); err != nil {
+				return nil, fmt.Errorf("list services: %w", err)
+			}
+			for i := range services.Items {
+				svc := &services.Items[i]
+				if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+					continue
+				}
+				for _, ingress := range svc.Status.LoadBalancer.Ingress {
+					if ingress.IP != "" {
+						add(svc.Namespace, ExternalIPs, *resource.NewQuantity(1, resource.DecimalSI))
+						break
+					}
+				}
+			}
+		case BucketStorage:
+			workloads := &cozyv1alpha1.WorkloadList{}
+			if err := c.List(ctx, workloads, opts...); err != nil && !apimeta.IsNoMatchError(err) {
+				return nil, fmt.Errorf("list workloads: %w", err)
+			}
+			for i := range workloads.Items {
+				if q, ok := workloads.Items[i].Status.Resources[BucketWorkloadResource]; ok {
+					add(workloads.Items[i].Namespace, BucketStorage, q)
+				}
+			}
+		case BackupStorage:
+			backups := &backupsv1alpha1.BackupList{}
+			if err := c.List(ctx, backups, opts...); err != nil && !apimeta.IsNoMatchError(err) {
+				return nil, fmt.Errorf("list backups: %w", err)
+			}
+			for i := range backups.Items {
+				if a := backups.Items[i].Status.Artifact; a != nil {
+					add(backups.Items[i].Namespace, BackupStorage, *resource.NewQuantity(a.SizeBytes, resource.BinarySI))
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// PlatformNames returns the platform resources any tenant declares: the
+// only ones worth measuring.
+func (s QuotaState) PlatformNames() []corev1.ResourceName {
+	var names []corev1.ResourceName
+	for _, p := range platformResources {
+		for _, declared := range s.Platform {
+			if _, ok := declared[p]; ok {
+				names = append(names, p)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// AddPlatformUsage merges measured platform usage into Used, so pools and
+// the TenantQuota view account for it like any ResourceQuota-tracked key.
+func (s QuotaState) AddPlatformUsage(usage map[string]corev1.ResourceList) {
+	for ns, used := range usage {
+		s.Used[ns] = quota.Add(s.Used[ns], used)
+	}
+}
+
+// ReadState is ReadQuotaState completed with the usage of the platform
+// resources any tenant declares, read through c.
+func ReadState(ctx context.Context, c client.Reader, items []corev1.ResourceQuota) (QuotaState, error) {
+	state := ReadQuotaState(items)
+	if names := state.PlatformNames(); len(names) > 0 {
+		usage, err := ReadPlatformUsage(ctx, c, "", names)
+		if err != nil {
+			return state, err
+		}
+		state.AddPlatformUsage(usage)
+	}
+	return state, nil
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantquota
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	backupsv1alpha1 "github.com/cozystack/cozystack/api/backups/v1alpha1"
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+)
+
+// withPlatform records platform on a chart quota the way the tenant chart
+// does.
+func withPlatform(t *testing.T, rq *corev1.ResourceQuota, platform map[string]string) *corev1.ResourceQuota {
+	t.Helper()
+	raw, err := json.Marshal(platform)
+	if err != nil {
+		t.Fatalf("marshal platform: %v", err)
+	}
+	rq.Annotations = map[string]string{PlatformAnnotation: string(raw)}
+	return rq
+}
+
+func bucketWorkload(namespace, name, size string) *cozyv1alpha1.Workload {
+	return &cozyv1alpha1.Workload{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Status: cozyv1alpha1.WorkloadStatus{Resources: map[string]resource.Quantity{
+			BucketWorkloadResource: resource.MustParse(size),
+		}},
+	}
+}
+
+// TestReadState_RollsUpPlatformResources: tenant-foo declares bucket storage
+// and external IPs, its bounded child bar carves bucket storage out of that
+// pool and its unbounded child qux shares it. Usage measured from Workloads,
+// Backups and Services rolls up like ResourceQuota usage does.
+func TestReadState_RollsUpPlatformResources(t *testing.T) {
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{
+		corev1.AddToScheme, helmv2.AddToScheme, cozyv1alpha1.AddToScheme, backupsv1alpha1.AddToScheme,
+	} {
+		if err := add(scheme); err != nil {
+			t.Fatalf("register scheme: %v", err)
+		}
+	}
+	lb := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo-qux", Name: "ingress"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+			Ingress: []corev1.LoadBalancerIngress{{IP: "192.0.2.10"}},
+		}},
+	}
+	pending := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo", Name: "pending"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+	}
+	backup := &backupsv1alpha1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo-bar", Name: "nightly"},
+		Status: backupsv1alpha1.BackupStatus{Artifact: &backupsv1alpha1.BackupArtifact{
+			SizeBytes: 1 << 30,
+		}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		lb, pending, backup,
+		bucketWorkload("tenant-foo", "logs", "5Gi"),
+		bucketWorkload("tenant-foo-bar", "media", "3Gi"),
+		bucketWorkload("tenant-foo-qux", "cache", "2Gi"),
+	).Build()
+
+	quotas := []corev1.ResourceQuota{
+		*withPlatform(t, chartQuota("tenant-foo", map[string]string{"requests.cpu": "10"}, nil),
+			map[string]string{"buckets.storage": "20Gi", "external-ips": "2", "backups.storage": "10Gi"}),
+		*withPlatform(t, chartQuota("tenant-foo-bar", map[string]string{"requests.cpu": "4"}, nil),
+			map[string]string{"buckets.storage": "8Gi"}),
+	}
+	state, err := ReadState(context.Background(), c, quotas)
+	if err != nil {
+		t.Fatalf("read state: %v", err)
+	}
+	if _, ok := state.Base["tenant-foo"][BucketStorage]; ok {
+		t.Fatalf("Base = %v, want the rendered budget only: platform resources cannot be raised", state.Base["tenant-foo"])
+	}
+	releases := []helmv2.HelmRelease{*tenantHR("foo", "tenant-root"), *tenantHR("bar", "tenant-foo"), *tenantHR("qux", "tenant-foo")}
+	nodes := BuildTree(TenantsFromReleases(releases, state.Declared), state)
+
+	foo := nodes["tenant-foo"]
+	quantityEqual(t, foo.Declared, "buckets.storage", "20Gi")
+	quantityEqual(t, foo.Declared, "requests.cpu", "10")
+	quantityEqual(t, foo.CarvedOut, "buckets.storage", "8Gi")
+	quantityEqual(t, foo.Available, "buckets.storage", "12Gi")
+	quantityEqual(t, foo.Available, "external-ips", "2")
+	quantityEqual(t, foo.SubtreeUsed, "buckets.storage", "10Gi")
+	quantityEqual(t, foo.SubtreeUsed, "external-ips", "1")
+	quantityEqual(t, foo.SubtreeUsed, "backups.storage", "1Gi")
+
+	qux := nodes["tenant-foo-qux"]
+	if qux.PoolRoot != "tenant-foo" {
+		t.Fatalf("qux.PoolRoot = %q, want tenant-foo", qux.PoolRoot)
+	}
+	quantityEqual(t, qux.Available, "buckets.storage", "12Gi")
+	quantityEqual(t, qux.Used, "buckets.storage", "2Gi")
+	quantityEqual(t, qux.Used, "external-ips", "1")
+
+	bar := nodes["tenant-foo-bar"]
+	if bar.PoolRoot != "tenant-foo-bar" {
+		t.Fatalf("bar.PoolRoot = %q, want its own pool", bar.PoolRoot)
+	}
+	quantityEqual(t, bar.Available, "buckets.storage", "8Gi")
+	quantityEqual(t, bar.Used, "buckets.storage", "3Gi")
+	if len(foo.Overcommitted) != 0 {
+		t.Fatalf("foo.Overcommitted = %v, want none", foo.Overcommitted)
+	}
+}
+
+// TestReadState_SkipsUndeclaredPlatformResources: nothing is measured when
+// no tenant declares a platform resource, so a cluster without the Backup
+// or Workload kinds registered reads as before.
+func TestReadState_SkipsUndeclaredPlatformResources(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("corev1 scheme: %v", err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	state, err := ReadState(context.Background(), c, []corev1.ResourceQuota{
+		*chartQuota("tenant-foo", map[string]string{"requests.cpu": "10"}, map[string]string{"requests.cpu": "1"}),
+	})
+	if err != nil {
+		t.Fatalf("read state: %v", err)
+	}
+	quantityEqual(t, state.Used["tenant-foo"], "requests.cpu", "1")
+}
+
+// TestReconcile_PlatformCarveOutStaysOutOfAllocatedQuota: a child bounded
+// only by platform resources still carves its slice out of the parent pool,
+// which is then clamped on the ResourceQuota keys alone.
+func TestReconcile_PlatformCarveOutStaysOutOfAllocatedQuota(t *testing.T) {
+	r, c := newReconciler(t,
+		tenantHR("foo", "tenant-root"),
+		tenantHR("bar", "tenant-foo"),
+		ns("tenant-foo"), ns("tenant-foo-bar"),
+		withPlatform(t, chartQuota("tenant-foo", map[string]string{"cpu": "10"}, nil),
+			map[string]string{"buckets.storage": "20Gi"}),
+		withPlatform(t, chartQuota("tenant-foo-bar", nil, nil),
+			map[string]string{"buckets.storage": "30Gi"}),
+	)
+	if _, err := r.Reconcile(context.Background(), sweepKey); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got, _ := allocatedHard(t, c, "tenant-foo", "cpu"); got != "10" {
+		t.Fatalf("tenant-foo allocated cpu = %q, want 10", got)
+	}
+	if got, ok := allocatedHard(t, c, "tenant-foo", "buckets.storage"); ok {
+		t.Fatalf("tenant-foo allocated buckets.storage = %q, want none: ResourceQuota cannot carry it", got)
+	}
+
+	quotas := &corev1.ResourceQuotaList{}
+	if err := c.List(context.Background(), quotas, client.MatchingLabels{managedByLabel: managedByValue}); err != nil {
+		t.Fatalf("list allocated quotas: %v", err)
+	}
+	if len(quotas.Items) != 1 {
+		t.Fatalf("allocated quotas = %d, want only tenant-foo's", len(quotas.Items))
+	}
+	pools := ComputePools(TenantsFromReleases(
+		[]helmv2.HelmRelease{*tenantHR("foo", "tenant-root"), *tenantHR("bar", "tenant-foo")},
+		ReadQuotaState(mustListQuotas(t, c)).Budgets(nil)))
+	quantityEqual(t, pools["tenant-foo"].Overcommitted(), "buckets.storage", "10Gi")
+}
+
+func mustListQuotas(t *testing.T, c client.Client) []corev1.ResourceQuota {
+	t.Helper()
+	quotas := &corev1.ResourceQuotaList{}
+	if err := c.List(context.Background(), quotas); err != nil {
+		t.Fatalf("list quotas: %v", err)
+	}
+	return quotas.Items
+}
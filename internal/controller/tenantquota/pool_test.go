@@ -224,3 +224,45 @@ func TestScaleResourceList(t *testing.T) {
 	same := ScaleResourceList(rl(map[string]string{"cpu": "10"}), 100)
 	quantityEqual(t, same, "cpu", "10")
 }
+
+func TestBuildTree(t *testing.T) {
+	// tenant-foo (cpu 10) with a bounded child bar (cpu 4) and an unbounded
+	// child qux that is using 2 cpu; the controller clamped qux to 4.
+	nodes := BuildTree([]Tenant{
+		{Namespace: "tenant-foo", Declared: rl(map[string]string{"cpu": "10"})},
+		{Namespace: "tenant-foo-bar", Declared: rl(map[string]string{"cpu": "4"})},
+		{Namespace: "tenant-foo-qux"},
+	}, QuotaState{
+		Used: map[string]corev1.ResourceList{
+			"tenant-foo":     rl(map[string]string{"cpu": "1"}),
+			"tenant-foo-bar": rl(map[string]string{"cpu": "3"}),
+			"tenant-foo-qux": rl(map[string]string{"cpu": "2"}),
+		},
+		Hard: map[string]corev1.ResourceList{
+			"tenant-foo-qux": rl(map[string]string{"cpu": "5"}),
+		},
+	})
+
+	qux := nodes["tenant-foo-qux"]
+	if qux.Parent != "tenant-foo" || qux.PoolRoot != "tenant-foo" {
+		t.Fatalf("qux parent/poolRoot = %q/%q, want tenant-foo/tenant-foo", qux.Parent, qux.PoolRoot)
+	}
+	quantityEqual(t, qux.Budget, "cpu", "10")
+	quantityEqual(t, qux.Available, "cpu", "6")
+	quantityEqual(t, qux.Hard, "cpu", "5")
+	if len(qux.Allocations) != 1 || qux.Allocations[0].Namespace != "tenant-foo-bar" {
+		t.Fatalf("qux.Allocations = %+v, want the tenant-foo-bar carve-out", qux.Allocations)
+	}
+
+	foo := nodes["tenant-foo"]
+	quantityEqual(t, foo.SubtreeUsed, "cpu", "6")
+	if len(foo.Children) != 2 || foo.Children[0] != "tenant-foo-bar" || foo.Children[1] != "tenant-foo-qux" {
+		t.Fatalf("foo.Children = %v", foo.Children)
+	}
+
+	bar := nodes["tenant-foo-bar"]
+	if bar.PoolRoot != "tenant-foo-bar" || len(bar.Allocations) != 0 {
+		t.Fatalf("bar poolRoot=%q allocations=%+v, want its own pool with no carve-outs", bar.PoolRoot, bar.Allocations)
+	}
+	quantityEqual(t, bar.SubtreeUsed, "cpu", "3")
+}
@@ -27,6 +27,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	quota "k8s.io/apiserver/pkg/quota/v1"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -37,6 +38,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
 	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
 )
 
@@ -52,8 +54,8 @@ const (
 	// whenever it is below the chart quota, without the controller fighting Flux
 	// over the chart-owned object.
 	allocatedQuotaName = "tenant-quota-allocated"
-	// managedByLabel marks the controller-owned ResourceQuotas so they can be
-	// listed and garbage-collected.
+	// managedByLabel marks the controller-owned ResourceQuotas and quota raise
+	// values Secrets so they can be listed and garbage-collected.
 	managedByLabel = "quota.cozystack.io/managed-by"
 	managedByValue = "tenant-quota-controller"
 	// resyncInterval bounds how stale the shared-pool clamp can get if a usage
@@ -94,10 +96,24 @@ func (r *Reconciler) bufferPercent() int64 {
 func (r *Reconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
-	tenants, usedByNS, existing, err := r.snapshot(ctx)
+	releases, quotas, existing, err := r.snapshot(ctx)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	// Platform usage is not measured: it only matters to EnforcedHard,
+	// whose platform resources no ResourceQuota can carry. The declared
+	// platform budgets still shape the pools and their overcommit.
+	state := ReadQuotaState(quotas)
+
+	bursts, nextExpiry, err := r.reconcileRequests(ctx, releases, state, time.Now())
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.applyBursts(ctx, releases, state.Base, bursts); err != nil {
+		logger.Error(err, "failed to apply quota raises")
+	}
+	tenants := TenantsFromReleases(releases, state.Budgets(bursts))
+	usedByNS := state.Used
 
 	pools := ComputePools(tenants)
 
@@ -118,8 +134,13 @@ func (r *Reconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result
 			Members:   p.Members,
 		}
 		for _, ns := range p.Members {
-			if existing[ns] {
-				desired[ns] = buffered.EnforcedHard(ns, usedByNS)
+			if !existing[ns] {
+				continue
+			}
+			// Platform resources are held at admission; a ResourceQuota
+			// cannot carry them.
+			if hard := withoutPlatform(buffered.EnforcedHard(ns, usedByNS)); len(hard) > 0 {
+				desired[ns] = hard
 			}
 		}
 	}
@@ -134,12 +155,17 @@ func (r *Reconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result
 		logger.Error(err, "failed to garbage-collect stale allocated quotas")
 	}
 
-	return ctrl.Result{RequeueAfter: resyncInterval}, nil
+	requeue := resyncInterval
+	if !nextExpiry.IsZero() {
+		if untilExpiry := time.Until(nextExpiry); untilExpiry < requeue {
+			requeue = max(untilExpiry, time.Second)
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeue}, nil
 }
 
-// snapshot reads the whole picture in one pass: the tenant namespaces (from
-// tenant HelmReleases), each tenant's declared budget, current usage per
-// namespace, and the set of existing namespaces.
+// snapshot reads the whole picture in one pass: the tenant releases, every
+// ResourceQuota, and the set of existing namespaces.
 //
 // A tenant's declared budget is taken from its chart-rendered "tenant-quota"
 // ResourceQuota (`.spec.hard`), not from the raw tenant.spec.resourceQuotas
@@ -149,28 +175,16 @@ func (r *Reconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result
 // controller's allocated quota in exactly the same key space, so the two
 // quotas compose correctly and the controller never has to replicate that
 // (cluster-configurable) transformation.
-func (r *Reconciler) snapshot(ctx context.Context) (tenants []Tenant, usedByNS map[string]corev1.ResourceList, existing map[string]bool, err error) {
-	releases := &helmv2.HelmReleaseList{}
-	if err = r.List(ctx, releases, client.MatchingLabels{appsv1alpha1.ApplicationKindLabel: tenantKind}); err != nil {
+func (r *Reconciler) snapshot(ctx context.Context) (releases []helmv2.HelmRelease, quotas []corev1.ResourceQuota, existing map[string]bool, err error) {
+	releaseList := &helmv2.HelmReleaseList{}
+	if err = r.List(ctx, releaseList, client.MatchingLabels{appsv1alpha1.ApplicationKindLabel: tenantKind}); err != nil {
 		return nil, nil, nil, err
 	}
 
-	quotas := &corev1.ResourceQuotaList{}
-	if err = r.List(ctx, quotas); err != nil {
+	quotaList := &corev1.ResourceQuotaList{}
+	if err = r.List(ctx, quotaList); err != nil {
 		return nil, nil, nil, err
 	}
-	declaredByNS := map[string]corev1.ResourceList{}
-	usedByNS = map[string]corev1.ResourceList{}
-	for i := range quotas.Items {
-		rq := &quotas.Items[i]
-		// Multiple ResourceQuotas in a namespace each report the same usage for
-		// a given resource, so usage is merged with a per-resource max (not a
-		// sum) to avoid double counting.
-		usedByNS[rq.Namespace] = maxResourceList(usedByNS[rq.Namespace], rq.Status.Used)
-		if rq.Name == chartQuotaName {
-			declaredByNS[rq.Namespace] = rq.Spec.Hard
-		}
-	}
 
 	nsList := &corev1.NamespaceList{}
 	if err = r.List(ctx, nsList); err != nil {
@@ -181,13 +195,74 @@ func (r *Reconciler) snapshot(ctx context.Context) (tenants []Tenant, usedByNS m
 		existing[nsList.Items[i].Name] = true
 	}
 
-	tenants = make([]Tenant, 0, len(releases.Items))
-	for i := range releases.Items {
-		hr := &releases.Items[i]
+	return releaseList.Items, quotaList.Items, existing, nil
+}
+
+// QuotaState is what the tenant ResourceQuotas say about each namespace.
+type QuotaState struct {
+	// Declared is the chart-rendered tenant-quota's spec.hard, including
+	// any quota raise the chart renders from an approved QuotaRequest, plus
+	// the declared platform resources.
+	Declared map[string]corev1.ResourceList
+	// Base is spec.hard without the raise: the budget the chart declared.
+	Base map[string]corev1.ResourceList
+	// Platform is the platform resources the chart declared.
+	Platform map[string]corev1.ResourceList
+	// Used merges status.used of every quota in the namespace, and the
+	// platform usage once AddPlatformUsage has measured it.
+	Used map[string]corev1.ResourceList
+	// Hard is the per-resource minimum of spec.hard over every quota in
+	// the namespace: the limit Kubernetes actually enforces.
+	Hard map[string]corev1.ResourceList
+}
+
+// ReadQuotaState folds a ResourceQuota list into per-namespace declared
+// budget, usage and effective hard limit.
+func ReadQuotaState(items []corev1.ResourceQuota) QuotaState {
+	state := QuotaState{
+		Declared: map[string]corev1.ResourceList{},
+		Base:     map[string]corev1.ResourceList{},
+		Platform: map[string]corev1.ResourceList{},
+		Used:     map[string]corev1.ResourceList{},
+		Hard:     map[string]corev1.ResourceList{},
+	}
+	for i := range items {
+		rq := &items[i]
+		// Multiple ResourceQuotas in a namespace each report the same usage for
+		// a given resource, so usage is merged with a per-resource max (not a
+		// sum) to avoid double counting.
+		state.Used[rq.Namespace] = maxResourceList(state.Used[rq.Namespace], rq.Status.Used)
+		state.Hard[rq.Namespace] = minResourceList(state.Hard[rq.Namespace], rq.Spec.Hard)
+		if rq.Name == chartQuotaName {
+			platform := declaredPlatform(rq)
+			state.Declared[rq.Namespace] = quota.Add(rq.Spec.Hard, platform)
+			state.Base[rq.Namespace] = declaredHard(rq)
+			state.Platform[rq.Namespace] = platform
+		}
+	}
+	return state
+}
+
+// Budgets is every tenant's budget with the raises in bursts applied and
+// the declared platform resources added: what the pools are computed from.
+func (s QuotaState) Budgets(bursts map[string]corev1.ResourceList) map[string]corev1.ResourceList {
+	out := make(map[string]corev1.ResourceList, len(s.Base))
+	for ns, base := range s.Base {
+		out[ns] = quota.Add(overlay(base, bursts[ns]), s.Platform[ns])
+	}
+	return out
+}
+
+// TenantsFromReleases maps tenant HelmReleases to the namespaces they own,
+// attaching each one's declared budget.
+func TenantsFromReleases(releases []helmv2.HelmRelease, declaredByNS map[string]corev1.ResourceList) []Tenant {
+	tenants := make([]Tenant, 0, len(releases))
+	for i := range releases {
+		hr := &releases[i]
 		ns := ownedNamespace(hr.Namespace, strings.TrimPrefix(hr.Name, tenantNamespacePrefix))
 		tenants = append(tenants, Tenant{Namespace: ns, Declared: declaredByNS[ns]})
 	}
-	return tenants, usedByNS, existing, nil
+	return tenants
 }
 
 func (r *Reconciler) upsertAllocatedQuota(ctx context.Context, namespace string, hard corev1.ResourceList) error {
@@ -237,8 +312,9 @@ func (r *Reconciler) recordOvercommit(ctx context.Context, rootNamespace string,
 }
 
 // SetupWithManager wires the controller. Every relevant change (a tenant
-// HelmRelease, a namespace, or a tenant ResourceQuota whose usage moved)
-// coalesces into one full-tree sweep.
+// HelmRelease, a namespace, a tenant ResourceQuota whose usage moved, a
+// QuotaRequest or a quota raise values Secret) coalesces into one full-tree
+// sweep.
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	toSweep := handler.EnqueueRequestsFromMapFunc(func(context.Context, client.Object) []reconcile.Request {
 		return []reconcile.Request{sweepKey}
@@ -249,11 +325,18 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	tenantQuotas := predicate.NewPredicateFuncs(func(o client.Object) bool {
 		return o.GetName() == chartQuotaName || o.GetName() == allocatedQuotaName
 	})
+	// A values Secret edited or deleted behind the controller's back is
+	// rewritten straight away.
+	burstValues := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return o.GetLabels()[managedByLabel] == managedByValue
+	})
 	return ctrl.NewControllerManagedBy(mgr).
 		Named("tenantquota-controller").
 		Watches(&helmv2.HelmRelease{}, toSweep, builder.WithPredicates(tenantReleases)).
 		Watches(&corev1.Namespace{}, toSweep).
 		Watches(&corev1.ResourceQuota{}, toSweep, builder.WithPredicates(tenantQuotas)).
+		Watches(&cozyv1alpha1.QuotaRequest{}, toSweep).
+		Watches(&corev1.Secret{}, toSweep, builder.WithPredicates(burstValues)).
 		Complete(r)
 }
 
@@ -285,6 +368,21 @@ func maxResourceList(a, b corev1.ResourceList) corev1.ResourceList {
 	return out
 }
 
+// minResourceList returns the per-resource minimum of a and b; a resource
+// present in only one of them is kept as is.
+func minResourceList(a, b corev1.ResourceList) corev1.ResourceList {
+	out := corev1.ResourceList{}
+	for k, v := range a {
+		out[k] = v.DeepCopy()
+	}
+	for k, v := range b {
+		if cur, ok := out[k]; !ok || v.Cmp(cur) < 0 {
+			out[k] = v.DeepCopy()
+		}
+	}
+	return out
+}
+
 func resourceListString(rl corev1.ResourceList) string {
 	parts := make([]string, 0, len(rl))
 	for k, v := range rl {
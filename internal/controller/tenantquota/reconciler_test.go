@@ -29,6 +29,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
 	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
 )
 
@@ -90,7 +91,12 @@ func newReconciler(t *testing.T, objs ...client.Object) (*Reconciler, client.Cli
 	if err := helmv2.AddToScheme(scheme); err != nil {
 		t.Fatalf("helmv2 scheme: %v", err)
 	}
-	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	if err := cozyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("cozystack scheme: %v", err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithStatusSubresource(&cozyv1alpha1.QuotaRequest{}).
+		Build()
 	return &Reconciler{Client: c, Scheme: scheme}, c
 }
 
@@ -0,0 +1,121 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantquota
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	quota "k8s.io/apiserver/pkg/quota/v1"
+)
+
+// Node explains one tenant's quota: which pool governs it, what that pool
+// holds, who else has carved slices out of it, and the limit that results.
+// It is the read model behind the core.cozystack.io tenantquotas resource.
+type Node struct {
+	Namespace string
+	Parent    string
+	// PoolRoot is the nearest bounded ancestor, inclusive; "" when the
+	// tenant is governed by no pool.
+	PoolRoot string
+	// Declared is the tenant's own budget; empty for an unbounded tenant.
+	Declared corev1.ResourceList
+	// Budget, CarvedOut and Available describe the PoolRoot's pool.
+	Budget    corev1.ResourceList
+	CarvedOut corev1.ResourceList
+	Available corev1.ResourceList
+	// Allocations are the bounded sub-tenants whose budgets are carved
+	// out of the pool: the siblings of an unbounded tenant, the children
+	// of a bounded one.
+	Allocations []Allocation
+	// Members share the pool's Available budget.
+	Members []string
+	// Used is the namespace's own usage; SubtreeUsed adds every
+	// descendant tenant's, bounded or not.
+	Used        corev1.ResourceList
+	SubtreeUsed corev1.ResourceList
+	// Hard is the limit currently enforced in the namespace.
+	Hard corev1.ResourceList
+	// Overcommitted is the PoolRoot pool's overcommit, if any.
+	Overcommitted corev1.ResourceList
+	Children      []string
+}
+
+// Allocation is a bounded tenant's slice of a pool.
+type Allocation struct {
+	Namespace string
+	Declared  corev1.ResourceList
+}
+
+// BuildTree computes a Node for every tenant in the snapshot. The pool
+// arithmetic is ComputePools'; this adds the per-tenant bookkeeping a
+// reader needs to understand why a limit is lower than declared.
+func BuildTree(tenants []Tenant, state QuotaState) map[string]*Node {
+	pools := ComputePools(tenants)
+	declaredByNS := map[string]corev1.ResourceList{}
+	nodes := make(map[string]*Node, len(tenants))
+	for _, t := range tenants {
+		if len(t.Declared) > 0 {
+			declaredByNS[t.Namespace] = t.Declared
+		}
+		nodes[t.Namespace] = &Node{
+			Namespace: t.Namespace,
+			Parent:    parentNamespace(t.Namespace),
+			Declared:  t.Declared,
+			Used:      state.Used[t.Namespace],
+			Hard:      state.Hard[t.Namespace],
+		}
+	}
+
+	allocations := map[string][]Allocation{}
+	for ns, declared := range declaredByNS {
+		if parentPool := poolRootOf(parentNamespace(ns), declaredByNS); parentPool != "" {
+			allocations[parentPool] = append(allocations[parentPool], Allocation{Namespace: ns, Declared: declared})
+		}
+	}
+
+	for ns, n := range nodes {
+		if parent, ok := nodes[n.Parent]; ok && n.Parent != ns {
+			parent.Children = append(parent.Children, ns)
+		}
+		// Usage rolls up every ancestor, so SubtreeUsed of a node is its own
+		// usage plus all of its descendants'.
+		for cur := ns; cur != ""; cur = parentNamespace(cur) {
+			if anc, ok := nodes[cur]; ok {
+				anc.SubtreeUsed = quota.Add(anc.SubtreeUsed, n.Used)
+			}
+		}
+		root := poolRootOf(ns, declaredByNS)
+		p, ok := pools[root]
+		if !ok {
+			continue
+		}
+		n.PoolRoot = root
+		n.Budget = p.Budget
+		n.CarvedOut = p.CarvedOut
+		n.Available = p.Available
+		n.Members = p.Members
+		n.Overcommitted = p.Overcommitted()
+		n.Allocations = allocations[root]
+	}
+
+	for _, n := range nodes {
+		sort.Strings(n.Children)
+		sort.Slice(n.Allocations, func(i, j int) bool { return n.Allocations[i].Namespace < n.Allocations[j].Namespace })
+	}
+	return nodes
+}
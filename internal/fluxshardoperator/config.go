@@ -54,16 +54,28 @@ const (
 	// Deployment.
 	HelmControllerContainerName = "helm-controller"
 
+	// SourceControllerContainerName and KustomizeControllerContainerName are
+	// cloned as well when the FluxShardPolicy shards them.
+	SourceControllerContainerName    = "source-controller"
+	KustomizeControllerContainerName = "kustomize-controller"
+
 	shardPrefix           = "shard"
 	shardDeploymentPrefix = "helm-controller-"
 )
 
-// ShardCountAuto is the --shard-count value that enables automatic sizing
-// from the tenant HelmRelease count.
+// ShardCountAuto is the FluxShardPolicy shardCount value that enables
+// automatic sizing from the tenant HelmRelease count.
 const ShardCountAuto = "auto"
 
+// Default settings, used while no FluxShardPolicy exists.
+const (
+	defaultShardConcurrent    = 5
+	defaultRebalanceThreshold = 0.25
+)
+
 // Config carries the operator-wide settings shared by the placement
-// controller, the shard provisioner and the admission webhook.
+// controller, the shard provisioner and the admission webhook. Everything but
+// FluxNamespace comes from the FluxShardPolicy (see Config.WithPolicy).
 type Config struct {
 	// FluxNamespace is the namespace the flux-aio Deployment and the shard
 	// Deployments live in.
@@ -85,6 +97,50 @@ type Config struct {
 	// ShardResources overrides the helm-controller container resources cloned
 	// from flux-aio. Empty fields inherit the cloned values.
 	ShardResources corev1.ResourceRequirements
+	// ShardNodeAffinity replaces the node affinity cloned from flux-aio when
+	// set.
+	ShardNodeAffinity *corev1.NodeAffinity
+	// ShardOverrides adjust individual shards, keyed by shard index.
+	ShardOverrides map[int]ShardOverride
+	// ShardSourceController runs a source-controller per shard for the
+	// sources in tenant namespaces.
+	ShardSourceController bool
+	// ShardKustomizeController runs a kustomize-controller per shard for the
+	// Kustomizations in tenant namespaces.
+	ShardKustomizeController bool
+}
+
+// ShardedControllers returns the flux controllers run per shard:
+// helm-controller always, source- and kustomize-controller when enabled.
+func (c *Config) ShardedControllers() []string {
+	controllers := []string{HelmControllerContainerName}
+	if c.ShardSourceController {
+		controllers = append(controllers, SourceControllerContainerName)
+	}
+	if c.ShardKustomizeController {
+		controllers = append(controllers, KustomizeControllerContainerName)
+	}
+	return controllers
+}
+
+// ShardOverride adjusts a single shard on top of the shared settings.
+type ShardOverride struct {
+	// Resources are merged over Config.ShardResources.
+	Resources corev1.ResourceRequirements
+	// NodeAffinity replaces Config.ShardNodeAffinity when set.
+	NodeAffinity *corev1.NodeAffinity
+}
+
+// DefaultConfig returns the settings in effect without a FluxShardPolicy:
+// automatic sizing, no pins and the flux-aio resources.
+func DefaultConfig(fluxNamespace string) *Config {
+	return &Config{
+		FluxNamespace:      fluxNamespace,
+		AutoShardCount:     true,
+		ShardConcurrent:    defaultShardConcurrent,
+		RebalanceThreshold: defaultRebalanceThreshold,
+		PinnedTenants:      map[string]string{},
+	}
 }
 
 // ShardName returns the canonical shard key value for index i (shard0..).
@@ -112,8 +168,8 @@ func ParseShardIndex(s string) (int, bool) {
 	return i, true
 }
 
-// ParseShardDeploymentIndex parses a shard Deployment name back into its
-// shard index.
+// ParseShardDeploymentIndex parses a helm-controller shard Deployment name
+// back into its shard index.
 func ParseShardDeploymentIndex(name string) (int, bool) {
 	rest, found := strings.CutPrefix(name, shardDeploymentPrefix)
 	if !found {
@@ -122,7 +178,25 @@ func ParseShardDeploymentIndex(name string) (int, bool) {
 	return ParseShardIndex(rest)
 }
 
-// ParseShardCount parses the --shard-count flag value: "auto" enables
+// ControllerShardDeploymentName returns the Deployment name of a flux
+// controller's shard i; for helm-controller it is ShardDeploymentName(i).
+func ControllerShardDeploymentName(controller string, i int) string {
+	return controller + "-" + ShardName(i)
+}
+
+// ParseControllerShardDeployment parses any shard Deployment name back into
+// its controller and shard index.
+func ParseControllerShardDeployment(name string) (string, int, bool) {
+	for _, controller := range []string{HelmControllerContainerName, SourceControllerContainerName, KustomizeControllerContainerName} {
+		if rest, found := strings.CutPrefix(name, controller+"-"); found {
+			idx, ok := ParseShardIndex(rest)
+			return controller, idx, ok
+		}
+	}
+	return "", 0, false
+}
+
+// ParseShardCount parses a shard count given as a string: "auto" enables
 // automatic sizing, otherwise a positive integer is an explicit shard count.
 func ParseShardCount(s string) (count int, auto bool, err error) {
 	if strings.EqualFold(strings.TrimSpace(s), ShardCountAuto) {
@@ -134,23 +208,3 @@ func ParseShardCount(s string) (count int, auto bool, err error) {
 	}
 	return n, false, nil
 }
-
-// ParsePinnedTenants parses the --pinned-tenants flag value
-// ("tenant-a=shard1,tenant-b=shard0") into a map.
-func ParsePinnedTenants(s string) (map[string]string, error) {
-	pinned := map[string]string{}
-	if s == "" {
-		return pinned, nil
-	}
-	for _, pair := range strings.Split(s, ",") {
-		k, v, found := strings.Cut(strings.TrimSpace(pair), "=")
-		if !found || k == "" {
-			return nil, fmt.Errorf("invalid pinned tenant entry %q, expected <tenant-namespace>=<shard>", pair)
-		}
-		if _, ok := ParseShardIndex(v); !ok {
-			return nil, fmt.Errorf("invalid shard %q for pinned tenant %q", v, k)
-		}
-		pinned[k] = v
-	}
-	return pinned, nil
-}
@@ -40,7 +40,7 @@ func FuzzShardNameRoundTrip(f *testing.F) {
 	})
 }
 
-// FuzzParseShardCount checks that the --shard-count flag parser never panics and
+// FuzzParseShardCount checks that the shard count parser never panics and
 // upholds its contract: a successful non-auto parse yields a positive count, and
 // the auto sentinel yields count 0.
 func FuzzParseShardCount(f *testing.F) {
@@ -63,29 +63,3 @@ func FuzzParseShardCount(f *testing.F) {
 		}
 	})
 }
-
-// FuzzParsePinnedTenants checks that the --pinned-tenants flag parser never
-// panics and that every entry in a successful parse is well-formed: a non-empty
-// tenant key mapped to a value that is itself a valid shard name.
-func FuzzParsePinnedTenants(f *testing.F) {
-	for _, seed := range []string{
-		"", "a=shard0", "a=shard0,b=shard1", " a = shard0 ", "a=", "=shard0",
-		"a=shardx", "a", "a=shard0,", ",", "a=shard0,b",
-	} {
-		f.Add(seed)
-	}
-	f.Fuzz(func(t *testing.T, s string) {
-		pinned, err := ParsePinnedTenants(s)
-		if err != nil {
-			return
-		}
-		for k, v := range pinned {
-			if k == "" {
-				t.Fatalf("ParsePinnedTenants(%q) produced an empty tenant key", s)
-			}
-			if _, ok := ParseShardIndex(v); !ok {
-				t.Fatalf("ParsePinnedTenants(%q) mapped %q to invalid shard %q", s, k, v)
-			}
-		}
-	})
-}
@@ -10,6 +10,7 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -22,6 +23,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
 )
 
 const (
@@ -48,10 +51,18 @@ const (
 // stubs.
 //
 // +kubebuilder:rbac:groups=helm.toolkit.fluxcd.io,resources=helmreleases,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=helmcharts;helmrepositories;gitrepositories;ocirepositories;buckets,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=kustomize.toolkit.fluxcd.io,resources=kustomizations,verbs=get;list;watch;patch
 // +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cozystack.io,resources=fluxshardpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cozystack.io,resources=fluxshardpolicies/status,verbs=update
 type PlacementReconciler struct {
 	client.Client
+	// Config holds the settings used while no FluxShardPolicy exists.
 	Config *Config
+	// Scrape fetches shard helm-controller metrics; nil uses HTTPScrape.
+	Scrape ScrapeFunc
 
 	// now is the clock, replaceable in tests.
 	now func() time.Time
@@ -59,6 +70,9 @@ type PlacementReconciler struct {
 	// restart the worst case is one rebalance move happening sooner than the
 	// cooldown, which is harmless.
 	lastMoved map[string]time.Time
+	// costs is the smoothed reconcile cost per tenant. Also in-memory: a new
+	// leader weighs tenants by HelmRelease count until its second scrape.
+	costs *costTracker
 }
 
 // SetupWithManager registers the placement controller.
@@ -67,6 +81,10 @@ func (r *PlacementReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		r.now = time.Now
 	}
 	r.lastMoved = map[string]time.Time{}
+	r.costs = newCostTracker()
+	if r.Scrape == nil {
+		r.Scrape = HTTPScrape
+	}
 
 	toSingleton := handler.EnqueueRequestsFromMapFunc(func(context.Context, client.Object) []reconcile.Request {
 		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "placement"}}}
@@ -79,6 +97,7 @@ func (r *PlacementReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		// Shard Deployment readiness gates reassignments; react to it directly
 		// instead of waiting for the next requeue.
 		Watches(&appsv1.Deployment{}, toSingleton, builder.WithPredicates(r.shardDeployment())).
+		Watches(&cozyv1alpha1.FluxShardPolicy{}, toSingleton, builder.WithPredicates(policySpecChanged())).
 		Complete(r)
 }
 
@@ -114,62 +133,97 @@ func tenantNamespace() predicate.Predicate {
 
 // tenantView is the gathered state of one tenant.
 type tenantView struct {
-	info     TenantInfo
-	hrs      []*metav1.PartialObjectMetadata
+	info TenantInfo
+	hrs  []*metav1.PartialObjectMetadata
+	// objs are the tenant's Flux sources and Kustomizations. They follow
+	// their HelmReleases onto the shard when the FluxShardPolicy shards their
+	// controller, and are released back to the shared controllers otherwise.
+	objs     []shardedObject
 	nsExists bool
 	nsLabel  string
 }
 
+// shardedObject is a non-HelmRelease Flux object and the controller
+// reconciling it.
+type shardedObject struct {
+	gvk        schema.GroupVersionKind
+	controller string
+	meta       *metav1.PartialObjectMetadata
+}
+
 // Reconcile performs one full placement sync.
 func (r *PlacementReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
-	views, err := r.gather(ctx)
+	cfg, policy, err := loadPolicy(ctx, r.Client, r.Config)
+	if err != nil {
+		if policy != nil {
+			status := policy.Status
+			status.ObservedGeneration, status.Message = policy.Generation, err.Error()
+			if statusErr := r.updatePolicyStatus(ctx, policy, status); statusErr != nil {
+				logger.Error(statusErr, "reporting invalid FluxShardPolicy")
+			}
+		}
+		return ctrl.Result{}, err
+	}
+
+	views, err := r.gather(ctx, cfg)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	if err := r.sampleCosts(ctx, views); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.costs.weigh(views)
 
 	tenants := make([]TenantInfo, 0, len(views))
 	totalHRs := 0
 	for _, v := range views {
 		tenants = append(tenants, v.info)
-		totalHRs += v.info.Weight
+		totalHRs += len(v.hrs)
 	}
 
-	readyShards, currentShards, err := r.observeShards(ctx)
+	readyShards, currentShards, err := r.observeShards(ctx, cfg)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
-	shardCount := r.Config.EffectiveShardCount(totalHRs, len(views), currentShards)
+	shardCount := cfg.EffectiveShardCount(totalHRs, len(views), currentShards)
 
 	desired := ComputePlacement(PlacementInput{
 		Tenants:            tenants,
 		ShardCount:         shardCount,
-		Pinned:             r.Config.PinnedTenants,
-		RebalanceThreshold: r.Config.RebalanceThreshold,
+		Pinned:             cfg.PinnedTenants,
+		RebalanceThreshold: cfg.RebalanceThreshold,
 		CanRebalance: func(tenant string) bool {
 			return r.now().Sub(r.lastMoved[tenant]) >= rebalanceCooldown
 		},
 	})
 
-	pending, errs := r.apply(ctx, views, desired, readyShards)
+	pending, errs := r.apply(ctx, cfg, views, desired, readyShards)
 
-	r.report(views, desired, shardCount, totalHRs, pending)
+	r.report(views, desired, shardCount, totalHRs, len(pending))
 	r.pruneCooldowns(views)
+	r.costs.prune(views)
+	if policy != nil {
+		status := policyStatus(policy, cfg, views, desired, pending, readyShards, shardCount, totalHRs)
+		if err := r.updatePolicyStatus(ctx, policy, status); err != nil {
+			errs = append(errs, err)
+		}
+	}
 
 	if len(errs) > 0 {
 		return ctrl.Result{}, utilerrors.NewAggregate(errs)
 	}
-	if pending > 0 {
-		logger.V(1).Info("placement sync paced", "pendingMoves", pending)
+	if len(pending) > 0 {
+		logger.V(1).Info("placement sync paced", "pendingMoves", len(pending))
 		return ctrl.Result{RequeueAfter: requeueShort}, nil
 	}
 	return ctrl.Result{RequeueAfter: resyncPeriod}, nil
 }
 
 // gather lists tenant HelmReleases and namespaces (metadata only) and groups
-// them per tenant.
-func (r *PlacementReconciler) gather(ctx context.Context) (map[string]*tenantView, error) {
+// them per tenant, along with the tenant's sources and Kustomizations.
+func (r *PlacementReconciler) gather(ctx context.Context, cfg *Config) (map[string]*tenantView, error) {
 	hrs := HelmReleaseMetaList()
 	if err := r.List(ctx, hrs, client.HasLabels{ShardKeyLabel}); err != nil {
 		return nil, fmt.Errorf("listing HelmReleases: %w", err)
@@ -197,7 +251,9 @@ func (r *PlacementReconciler) gather(ctx context.Context) (map[string]*tenantVie
 			views[tenantNS] = v
 		}
 		v.hrs = append(v.hrs, hr)
-		v.info.Weight++
+	}
+	if err := r.gatherObjects(ctx, cfg, views); err != nil {
+		return nil, err
 	}
 
 	for tenantNS, v := range views {
@@ -217,6 +273,41 @@ func (r *PlacementReconciler) gather(ctx context.Context) (map[string]*tenantVie
 	return views, nil
 }
 
+// gatherObjects attaches the sources and Kustomizations of each tenant to its
+// view. A sharded controller's kinds are listed in full; the others only
+// where they still carry a shard key to release. Kinds whose CRD is not
+// installed are skipped.
+func (r *PlacementReconciler) gatherObjects(ctx context.Context, cfg *Config, views map[string]*tenantView) error {
+	sharded := map[string]bool{}
+	for _, controller := range cfg.ShardedControllers() {
+		sharded[controller] = true
+	}
+	for _, controller := range []string{SourceControllerContainerName, KustomizeControllerContainerName} {
+		var opts []client.ListOption
+		if !sharded[controller] {
+			opts = append(opts, client.HasLabels{ShardKeyLabel})
+		}
+		for _, gvk := range ControllerGVKs(controller) {
+			list := MetaList(gvk)
+			if err := r.List(ctx, list, opts...); err != nil {
+				if meta.IsNoMatchError(err) {
+					continue
+				}
+				return fmt.Errorf("listing %s: %w", gvk.Kind, err)
+			}
+			for i := range list.Items {
+				obj := &list.Items[i]
+				tenantNS, ok := TenantNamespaceForHR(obj)
+				if !ok || views[tenantNS] == nil {
+					continue
+				}
+				views[tenantNS].objs = append(views[tenantNS].objs, shardedObject{gvk: gvk, controller: controller, meta: obj})
+			}
+		}
+	}
+	return nil
+}
+
 // majorityShard returns the most common canonical shard value among the
 // tenant's HelmRelease labels, tie-break lowest shard index. Legacy "tenants"
 // and unparseable values count as unassigned.
@@ -239,35 +330,56 @@ func majorityShard(hrs []*metav1.PartialObjectMetadata) string {
 	return ShardName(best)
 }
 
-// observeShards reports which shard Deployments currently have a ready
-// replica, and how many shards are provisioned (the auto-sizing hysteresis
-// anchor). Reassignments only target ready shards, so a backfill can never
-// hand tenants to a shard whose helm-controller is not actually running (e.g.
-// crashlooping after a bad clone) — the safe-online-migration guarantee.
-func (r *PlacementReconciler) observeShards(ctx context.Context) (map[string]bool, int, error) {
+// observeShards reports which shards currently have a ready replica of every
+// sharded controller, and how many shards are provisioned (the auto-sizing
+// hysteresis anchor). Reassignments only target ready shards, so a backfill
+// can never hand tenants to a shard whose controllers are not actually
+// running (e.g. crashlooping after a bad clone) — the safe-online-migration
+// guarantee.
+func (r *PlacementReconciler) observeShards(ctx context.Context, cfg *Config) (map[string]bool, int, error) {
 	deps := &appsv1.DeploymentList{}
 	if err := r.List(ctx, deps, client.InNamespace(r.Config.FluxNamespace),
 		client.MatchingLabels{ManagedByLabel: ManagedByValue}); err != nil {
 		return nil, 0, fmt.Errorf("listing shard Deployments: %w", err)
 	}
-	ready := map[string]bool{}
+	ready, count := shardReadiness(deps.Items, cfg.ShardedControllers())
+	return ready, count, nil
+}
+
+// shardReadiness derives per-shard readiness and the provisioned shard count
+// from the shard Deployments. The count follows the helm-controller shards.
+func shardReadiness(deps []appsv1.Deployment, controllers []string) (map[string]bool, int) {
+	readyBy := map[string]map[int]bool{}
 	count := 0
-	for i := range deps.Items {
-		if idx, ok := ParseShardDeploymentIndex(deps.Items[i].Name); ok {
-			ready[ShardName(idx)] = deps.Items[i].Status.ReadyReplicas > 0
-			if idx+1 > count {
-				count = idx + 1
-			}
+	for i := range deps {
+		controller, idx, ok := ParseControllerShardDeployment(deps[i].Name)
+		if !ok {
+			continue
+		}
+		if readyBy[controller] == nil {
+			readyBy[controller] = map[int]bool{}
+		}
+		readyBy[controller][idx] = deps[i].Status.ReadyReplicas > 0
+		if controller == HelmControllerContainerName && idx+1 > count {
+			count = idx + 1
 		}
 	}
-	return ready, count, nil
+	ready := map[string]bool{}
+	for idx, helmReady := range readyBy[HelmControllerContainerName] {
+		shardReady := helmReady
+		for _, controller := range controllers {
+			shardReady = shardReady && readyBy[controller][idx]
+		}
+		ready[ShardName(idx)] = shardReady
+	}
+	return ready, count
 }
 
 // apply pushes the desired assignment out, pacing tenant reassignments and
 // self-healing label stragglers without limit. Reassignments whose target
-// shard is not ready are deferred. Returns the number of reassignments left
-// for the next batch.
-func (r *PlacementReconciler) apply(ctx context.Context, views map[string]*tenantView, desired map[string]string, readyShards map[string]bool) (int, []error) {
+// shard is not ready are deferred. Returns the reassignments left for the
+// next batch.
+func (r *PlacementReconciler) apply(ctx context.Context, cfg *Config, views map[string]*tenantView, desired map[string]string, readyShards map[string]bool) ([]cozyv1alpha1.FluxShardMove, []error) {
 	logger := log.FromContext(ctx)
 
 	names := make([]string, 0, len(views))
@@ -277,7 +389,7 @@ func (r *PlacementReconciler) apply(ctx context.Context, views map[string]*tenan
 	sort.Strings(names)
 
 	budget := movesPerSync
-	pending := 0
+	var pending []cozyv1alpha1.FluxShardMove
 	var errs []error
 	for _, tenantNS := range names {
 		v := views[tenantNS]
@@ -288,12 +400,12 @@ func (r *PlacementReconciler) apply(ctx context.Context, views map[string]*tenan
 		moved := v.info.Current != target
 		if moved {
 			if budget == 0 || !readyShards[target] {
-				pending++
+				pending = append(pending, cozyv1alpha1.FluxShardMove{Tenant: tenantNS, From: v.info.Current, To: target})
 				continue
 			}
 			budget--
 		}
-		if err := r.stamp(ctx, v, target); err != nil {
+		if err := r.stamp(ctx, cfg, v, target); err != nil {
 			errs = append(errs, err)
 			continue
 		}
@@ -310,8 +422,11 @@ func (r *PlacementReconciler) apply(ctx context.Context, views map[string]*tenan
 
 // stamp records the assignment on the tenant namespace and relabels every
 // HelmRelease of the tenant that does not carry it yet. Patches the namespace
-// first so the webhook hands out the new shard before the HRs settle.
-func (r *PlacementReconciler) stamp(ctx context.Context, v *tenantView, target string) error {
+// first so the webhook hands out the new shard before the HRs settle. Sources
+// and Kustomizations follow when their controller is sharded and lose a
+// canonical shard key when it is not, handing them back to the flux-aio
+// controllers.
+func (r *PlacementReconciler) stamp(ctx context.Context, cfg *Config, v *tenantView, target string) error {
 	var errs []error
 	if v.nsExists && v.nsLabel != target {
 		if err := r.patchLabel(ctx, NamespaceGVK, "", v.info.Namespace, TenantShardLabel, target); err != nil {
@@ -326,6 +441,25 @@ func (r *PlacementReconciler) stamp(ctx context.Context, v *tenantView, target s
 			errs = append(errs, fmt.Errorf("helmrelease %s/%s: %w", hr.GetNamespace(), hr.GetName(), err))
 		}
 	}
+	sharded := map[string]bool{}
+	for _, controller := range cfg.ShardedControllers() {
+		sharded[controller] = true
+	}
+	for _, o := range v.objs {
+		current := o.meta.GetLabels()[ShardKeyLabel]
+		var err error
+		switch {
+		case sharded[o.controller] && current != target:
+			err = r.patchLabel(ctx, o.gvk, o.meta.GetNamespace(), o.meta.GetName(), ShardKeyLabel, target)
+		case !sharded[o.controller]:
+			if _, ok := ParseShardIndex(current); ok {
+				err = r.removeLabel(ctx, o.gvk, o.meta.GetNamespace(), o.meta.GetName(), ShardKeyLabel)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s/%s: %w", strings.ToLower(o.gvk.Kind), o.meta.GetNamespace(), o.meta.GetName(), err))
+		}
+	}
 	return utilerrors.NewAggregate(errs)
 }
 
@@ -343,6 +477,20 @@ func (r *PlacementReconciler) patchLabel(ctx context.Context, gvk schema.GroupVe
 	return err
 }
 
+// removeLabel merge-patches a single label away.
+func (r *PlacementReconciler) removeLabel(ctx context.Context, gvk schema.GroupVersionKind, namespace, name, label string) error {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	patch := fmt.Sprintf(`{"metadata":{"labels":{%q:null}}}`, label)
+	err := r.Patch(ctx, obj, client.RawPatch(types.MergePatchType, []byte(patch)))
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
 // report refreshes the telemetry gauges.
 func (r *PlacementReconciler) report(views map[string]*tenantView, desired map[string]string, shardCount, totalHRs, pending int) {
 	shardLoadGauge.Reset()
@@ -356,6 +504,10 @@ func (r *PlacementReconciler) report(views map[string]*tenantView, desired map[s
 	for shard, l := range load {
 		shardLoadGauge.WithLabelValues(shard).Set(float64(l))
 	}
+	tenantCostGauge.Reset()
+	for tenantNS, cost := range r.costs.cost {
+		tenantCostGauge.WithLabelValues(tenantNS).Set(cost)
+	}
 	tenantsGauge.Set(float64(len(views)))
 	helmReleasesGauge.Set(float64(totalHRs))
 	pendingMovesGauge.Set(float64(pending))
@@ -0,0 +1,285 @@
+package fluxshardoperator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Placement weights tenants by what they actually cost their shard: the
+// helm-controller time spent reconciling their HelmReleases. Every shard
+// helm-controller exports the cumulative reconcile duration of each object it
+// reconciles, so the delta between two scrapes is the time spent on each
+// HelmRelease in between — duration and frequency in one number. Deltas are
+// summed per tenant and smoothed with an exponentially weighted moving
+// average, so one slow upgrade does not move a tenant but a tenant that keeps
+// failing its remediation does.
+const (
+	// reconcileDurationMetric is the per-object reconcile duration histogram
+	// of the Flux controllers (fluxcd/pkg/runtime/metrics).
+	reconcileDurationMetric = "gotk_reconcile_duration_seconds"
+	// defaultMetricsPort is helm-controller's --metrics-addr default.
+	defaultMetricsPort = "8080"
+	// costSampleInterval is the minimum pause between two scrapes; syncs
+	// triggered more often reuse the last estimate.
+	costSampleInterval = time.Minute
+	// costHalfLife is how long a past observation keeps half its influence
+	// on the smoothed cost.
+	costHalfLife = 30 * time.Minute
+	// scrapeTimeout bounds a single shard scrape.
+	scrapeTimeout = 5 * time.Second
+)
+
+// ReconcileSample is the cumulative reconcile time a shard helm-controller
+// reports for one HelmRelease.
+type ReconcileSample struct {
+	Seconds float64
+	Count   uint64
+}
+
+// ScrapeFunc fetches the per-HelmRelease reconcile samples exported at url.
+type ScrapeFunc func(ctx context.Context, url string) (map[types.NamespacedName]ReconcileSample, error)
+
+// counterState is the last sample seen for a HelmRelease and the pod that
+// exported it; a sample from another pod restarts the counter.
+type counterState struct {
+	source string
+	sample ReconcileSample
+}
+
+// costTracker keeps the smoothed reconcile cost of each tenant, in
+// milliseconds of helm-controller time per minute.
+type costTracker struct {
+	sampledAt time.Time
+	last      map[types.NamespacedName]counterState
+	cost      map[string]float64
+}
+
+func newCostTracker() *costTracker {
+	return &costTracker{
+		last: map[types.NamespacedName]counterState{},
+		cost: map[string]float64{},
+	}
+}
+
+// due reports whether a new scrape should be taken.
+func (c *costTracker) due(now time.Time) bool {
+	return c.sampledAt.IsZero() || now.Sub(c.sampledAt) >= costSampleInterval
+}
+
+// observe folds one round of scrapes, keyed by the exporting pod, into the
+// smoothed costs. tenantOf attributes HelmReleases to tenants. Only tenants
+// with at least one HelmRelease seen twice by the same pod are updated:
+// a tenant whose shard could not be scraped, or that just moved, keeps its
+// previous estimate instead of dropping to zero. The first round only primes
+// the counters.
+func (c *costTracker) observe(now time.Time, scrapes map[string]map[types.NamespacedName]ReconcileSample, tenantOf map[types.NamespacedName]string) {
+	elapsed := now.Sub(c.sampledAt)
+	primed := !c.sampledAt.IsZero() && elapsed > 0
+	c.sampledAt = now
+
+	spent := map[string]float64{}
+	seen := map[types.NamespacedName]bool{}
+	for source, samples := range scrapes {
+		for hr, s := range samples {
+			tenant, ok := tenantOf[hr]
+			if !ok {
+				continue
+			}
+			seen[hr] = true
+			prev, had := c.last[hr]
+			c.last[hr] = counterState{source: source, sample: s}
+			// A counter that went backwards belongs to a restarted
+			// controller even if the pod is the same.
+			if !primed || !had || prev.source != source || s.Count < prev.sample.Count || s.Seconds < prev.sample.Seconds {
+				continue
+			}
+			spent[tenant] += s.Seconds - prev.sample.Seconds
+		}
+	}
+	for hr := range c.last {
+		if !seen[hr] {
+			delete(c.last, hr)
+		}
+	}
+	if !primed {
+		return
+	}
+
+	alpha := 1 - math.Exp2(-elapsed.Seconds()/costHalfLife.Seconds())
+	for tenant, seconds := range spent {
+		rate := seconds * 1000 / elapsed.Minutes()
+		if prev, ok := c.cost[tenant]; ok {
+			rate = prev + alpha*(rate-prev)
+		}
+		c.cost[tenant] = rate
+	}
+}
+
+// weigh sets the placement weight of every tenant. Measured tenants weigh
+// their smoothed cost; tenants without a measurement yet (new, or still on
+// the legacy bucket no shard exports) are estimated from their HelmRelease
+// count at the average cost per HelmRelease of the measured ones. With no
+// measurement at all the weight is the HelmRelease count. Every tenant weighs
+// at least 1 so an idle one still counts towards its shard.
+func (c *costTracker) weigh(views map[string]*tenantView) {
+	measured, measuredHRs := 0.0, 0
+	for tenant, v := range views {
+		if cost, ok := c.cost[tenant]; ok {
+			measured += cost
+			measuredHRs += len(v.hrs)
+		}
+	}
+	perHR := 1.0
+	if measuredHRs > 0 {
+		perHR = measured / float64(measuredHRs)
+	}
+	for tenant, v := range views {
+		cost, ok := c.cost[tenant]
+		if !ok {
+			cost = perHR * float64(len(v.hrs))
+		}
+		v.info.Weight = max(1, int(math.Round(cost)))
+	}
+}
+
+// prune drops the state of tenants that no longer exist.
+func (c *costTracker) prune(views map[string]*tenantView) {
+	for tenant := range c.cost {
+		if _, ok := views[tenant]; !ok {
+			delete(c.cost, tenant)
+		}
+	}
+}
+
+// sampleCosts scrapes every running shard helm-controller and folds the
+// result into the cost estimate. A shard that cannot be scraped is logged and
+// skipped; placement then works from the estimates it already has.
+func (r *PlacementReconciler) sampleCosts(ctx context.Context, views map[string]*tenantView) error {
+	now := r.now()
+	if !r.costs.due(now) {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(r.Config.FluxNamespace)); err != nil {
+		return fmt.Errorf("listing shard pods: %w", err)
+	}
+
+	tenantOf := map[types.NamespacedName]string{}
+	for tenant, v := range views {
+		for _, hr := range v.hrs {
+			tenantOf[types.NamespacedName{Namespace: hr.GetNamespace(), Name: hr.GetName()}] = tenant
+		}
+	}
+
+	scrapes := map[string]map[types.NamespacedName]ReconcileSample{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if _, ok := ParseShardDeploymentIndex(pod.Labels["app.kubernetes.io/name"]); !ok {
+			continue
+		}
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		url := "http://" + net.JoinHostPort(pod.Status.PodIP, metricsPort(pod)) + "/metrics"
+		samples, err := r.Scrape(ctx, url)
+		if err != nil {
+			costScrapeErrorsCounter.Inc()
+			logger.V(1).Info("scraping shard metrics failed", "pod", pod.Name, "error", err.Error())
+			continue
+		}
+		scrapes[string(pod.UID)] = samples
+	}
+	r.costs.observe(now, scrapes, tenantOf)
+	return nil
+}
+
+// metricsPort returns the port a shard pod's helm-controller serves metrics
+// on, as set by the --metrics-addr argument cloned from flux-aio.
+func metricsPort(pod *corev1.Pod) string {
+	for _, c := range pod.Spec.Containers {
+		if c.Name != HelmControllerContainerName {
+			continue
+		}
+		for _, arg := range c.Args {
+			if addr, found := strings.CutPrefix(arg, "--metrics-addr="); found {
+				if _, port, err := net.SplitHostPort(addr); err == nil && port != "" {
+					return port
+				}
+			}
+		}
+	}
+	return defaultMetricsPort
+}
+
+// HTTPScrape is the ScrapeFunc used in production: a plain GET of the
+// Prometheus text exposition.
+func HTTPScrape(ctx context.Context, url string) (map[types.NamespacedName]ReconcileSample, error) {
+	ctx, cancel := context.WithTimeout(ctx, scrapeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return ParseReconcileSamples(resp.Body)
+}
+
+// ParseReconcileSamples extracts the HelmRelease reconcile duration totals
+// from a Prometheus text exposition.
+func ParseReconcileSamples(in io.Reader) (map[types.NamespacedName]ReconcileSample, error) {
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	families, err := parser.TextToMetricFamilies(in)
+	if err != nil {
+		return nil, fmt.Errorf("parsing metrics: %w", err)
+	}
+	out := map[types.NamespacedName]ReconcileSample{}
+	family := families[reconcileDurationMetric]
+	if family == nil {
+		return out, nil
+	}
+	for _, m := range family.GetMetric() {
+		h := m.GetHistogram()
+		if h == nil {
+			continue
+		}
+		var key types.NamespacedName
+		kind := ""
+		for _, l := range m.GetLabel() {
+			switch l.GetName() {
+			case "kind":
+				kind = l.GetValue()
+			case "name":
+				key.Name = l.GetValue()
+			case "namespace":
+				key.Namespace = l.GetValue()
+			}
+		}
+		if kind != HelmReleaseGVK.Kind || key.Name == "" {
+			continue
+		}
+		out[key] = ReconcileSample{Seconds: h.GetSampleSum(), Count: h.GetSampleCount()}
+	}
+	return out, nil
+}
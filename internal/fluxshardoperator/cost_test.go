@@ -0,0 +1,146 @@
+package fluxshardoperator
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestParseReconcileSamples(t *testing.T) {
+	exposition := `# HELP gotk_reconcile_duration_seconds The duration in seconds of a GitOps Toolkit resource reconciliation.
+# TYPE gotk_reconcile_duration_seconds histogram
+gotk_reconcile_duration_seconds_bucket{kind="HelmRelease",name="kubernetes-a",namespace="tenant-a",le="1"} 2
+gotk_reconcile_duration_seconds_bucket{kind="HelmRelease",name="kubernetes-a",namespace="tenant-a",le="+Inf"} 4
+gotk_reconcile_duration_seconds_sum{kind="HelmRelease",name="kubernetes-a",namespace="tenant-a"} 42.5
+gotk_reconcile_duration_seconds_count{kind="HelmRelease",name="kubernetes-a",namespace="tenant-a"} 4
+gotk_reconcile_duration_seconds_bucket{kind="HelmChart",name="tenant-a-kubernetes-a",namespace="tenant-a",le="+Inf"} 9
+gotk_reconcile_duration_seconds_sum{kind="HelmChart",name="tenant-a-kubernetes-a",namespace="tenant-a"} 3
+gotk_reconcile_duration_seconds_count{kind="HelmChart",name="tenant-a-kubernetes-a",namespace="tenant-a"} 9
+# TYPE controller_runtime_reconcile_total counter
+controller_runtime_reconcile_total{controller="helmrelease",result="success"} 12
+`
+	samples, err := ParseReconcileSamples(strings.NewReader(exposition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 {
+		t.Fatalf("expected only the HelmRelease sample, got %v", samples)
+	}
+	got := samples[types.NamespacedName{Namespace: "tenant-a", Name: "kubernetes-a"}]
+	if got.Seconds != 42.5 || got.Count != 4 {
+		t.Fatalf("unexpected sample %+v", got)
+	}
+
+	samples, err = ParseReconcileSamples(strings.NewReader("# TYPE up gauge\nup 1\n"))
+	if err != nil || len(samples) != 0 {
+		t.Fatalf("expected no samples without the histogram, got %v, %v", samples, err)
+	}
+}
+
+func TestCostTrackerObserve(t *testing.T) {
+	heavy := types.NamespacedName{Namespace: "tenant-a", Name: "kubernetes-a"}
+	small := types.NamespacedName{Namespace: "tenant-b", Name: "redis-b"}
+	tenantOf := map[types.NamespacedName]string{heavy: "tenant-a", small: "tenant-b"}
+	scrape := func(pod string, h, s float64, count uint64) map[string]map[types.NamespacedName]ReconcileSample {
+		return map[string]map[types.NamespacedName]ReconcileSample{pod: {
+			heavy: {Seconds: h, Count: count},
+			small: {Seconds: s, Count: count},
+		}}
+	}
+
+	c := newCostTracker()
+	start := time.Unix(0, 0)
+	c.observe(start, scrape("pod-1", 100, 10, 5), tenantOf)
+	if len(c.cost) != 0 {
+		t.Fatalf("first round must only prime the counters, got %v", c.cost)
+	}
+
+	// 6s and 0.6s over one minute.
+	c.observe(start.Add(time.Minute), scrape("pod-1", 106, 10.6, 6), tenantOf)
+	if c.cost["tenant-a"] != 6000 || math.Abs(c.cost["tenant-b"]-600) > 1e-6 {
+		t.Fatalf("unexpected first estimate %v", c.cost)
+	}
+
+	// A restarted shard (new pod) restarts the counters: no delta, the
+	// estimate is kept.
+	c.observe(start.Add(2*time.Minute), scrape("pod-2", 1, 0.1, 1), tenantOf)
+	if c.cost["tenant-a"] != 6000 {
+		t.Fatalf("counter reset must not change the estimate, got %v", c.cost)
+	}
+
+	// An idle minute is smoothed in, not taken at face value.
+	c.observe(start.Add(3*time.Minute), scrape("pod-2", 1, 0.1, 1), tenantOf)
+	if got := c.cost["tenant-a"]; got <= 5000 || got >= 6000 {
+		t.Fatalf("expected a smoothed decay below 6000, got %v", got)
+	}
+
+	// A failed scrape leaves the estimates alone.
+	before := c.cost["tenant-a"]
+	c.observe(start.Add(4*time.Minute), nil, tenantOf)
+	if c.cost["tenant-a"] != before {
+		t.Fatalf("missing scrape changed the estimate: %v -> %v", before, c.cost["tenant-a"])
+	}
+}
+
+func TestCostTrackerWeigh(t *testing.T) {
+	hrs := func(n int) []*metav1.PartialObjectMetadata {
+		return make([]*metav1.PartialObjectMetadata, n)
+	}
+	views := map[string]*tenantView{
+		"tenant-a": {hrs: hrs(1)},
+		"tenant-b": {hrs: hrs(10)},
+		"tenant-c": {hrs: hrs(4)},
+	}
+
+	c := newCostTracker()
+	c.weigh(views)
+	for tenant, want := range map[string]int{"tenant-a": 1, "tenant-b": 10, "tenant-c": 4} {
+		if got := views[tenant].info.Weight; got != want {
+			t.Fatalf("unmeasured %s: weight %d, want the HelmRelease count %d", tenant, got, want)
+		}
+	}
+
+	// One Kubernetes cluster outweighs ten small releases; the unmeasured
+	// tenant is estimated at (3000+500)/11 per HelmRelease.
+	c.cost["tenant-a"] = 3000
+	c.cost["tenant-b"] = 500
+	c.weigh(views)
+	if views["tenant-a"].info.Weight != 3000 || views["tenant-b"].info.Weight != 500 {
+		t.Fatalf("measured weights not applied: a=%d b=%d", views["tenant-a"].info.Weight, views["tenant-b"].info.Weight)
+	}
+	if got := views["tenant-c"].info.Weight; got != 1273 {
+		t.Fatalf("unmeasured tenant-c: weight %d, want 1273", got)
+	}
+
+	// An idle tenant still weighs something.
+	c.cost["tenant-b"] = 0.2
+	c.weigh(views)
+	if views["tenant-b"].info.Weight != 1 {
+		t.Fatalf("idle tenant weight %d, want 1", views["tenant-b"].info.Weight)
+	}
+
+	delete(views, "tenant-a")
+	c.prune(views)
+	if _, ok := c.cost["tenant-a"]; ok {
+		t.Fatal("cost of a removed tenant was not pruned")
+	}
+}
+
+func TestMetricsPort(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+		Name: HelmControllerContainerName,
+		Args: []string{"--watch-all-namespaces", "--metrics-addr=:9795"},
+	}}}}
+	if got := metricsPort(pod); got != "9795" {
+		t.Fatalf("got %q, want 9795", got)
+	}
+	pod.Spec.Containers[0].Args = nil
+	if got := metricsPort(pod); got != defaultMetricsPort {
+		t.Fatalf("got %q, want the default %s", got, defaultMetricsPort)
+	}
+}
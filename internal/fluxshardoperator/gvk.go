@@ -49,3 +49,53 @@ func NamespaceMetaList() *metav1.PartialObjectMetadataList {
 	list.SetGroupVersionKind(NamespaceGVK.GroupVersion().WithKind("NamespaceList"))
 	return list
 }
+
+// SourceGVKs are the Flux source kinds a source-controller shard serves.
+// ExternalArtifacts are left out: they are produced by other controllers,
+// not reconciled by source-controller.
+var SourceGVKs = []schema.GroupVersionKind{
+	{Group: "source.toolkit.fluxcd.io", Version: "v1", Kind: "HelmChart"},
+	{Group: "source.toolkit.fluxcd.io", Version: "v1", Kind: "HelmRepository"},
+	{Group: "source.toolkit.fluxcd.io", Version: "v1", Kind: "GitRepository"},
+	{Group: "source.toolkit.fluxcd.io", Version: "v1", Kind: "OCIRepository"},
+	{Group: "source.toolkit.fluxcd.io", Version: "v1", Kind: "Bucket"},
+}
+
+// KustomizationGVK is the GroupVersionKind of Flux Kustomizations.
+var KustomizationGVK = schema.GroupVersionKind{
+	Group:   "kustomize.toolkit.fluxcd.io",
+	Version: "v1",
+	Kind:    "Kustomization",
+}
+
+// ControllerGVKs returns the kinds a sharded flux controller reconciles.
+func ControllerGVKs(controller string) []schema.GroupVersionKind {
+	switch controller {
+	case HelmControllerContainerName:
+		return []schema.GroupVersionKind{HelmReleaseGVK}
+	case SourceControllerContainerName:
+		return SourceGVKs
+	case KustomizeControllerContainerName:
+		return []schema.GroupVersionKind{KustomizationGVK}
+	}
+	return nil
+}
+
+// ControllerForGVK returns the sharded flux controller reconciling a kind.
+func ControllerForGVK(gvk schema.GroupVersionKind) (string, bool) {
+	for _, controller := range []string{HelmControllerContainerName, SourceControllerContainerName, KustomizeControllerContainerName} {
+		for _, candidate := range ControllerGVKs(controller) {
+			if candidate.Group == gvk.Group && candidate.Kind == gvk.Kind {
+				return controller, true
+			}
+		}
+	}
+	return "", false
+}
+
+// MetaList returns a PartialObjectMetadataList typed as a list of gvk.
+func MetaList(gvk schema.GroupVersionKind) *metav1.PartialObjectMetadataList {
+	list := &metav1.PartialObjectMetadataList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	return list
+}
@@ -8,9 +8,14 @@ import (
 var (
 	shardLoadGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "cozy_flux_shard_load",
-		Help: "Number of tenant HelmReleases assigned to each helm-controller shard.",
+		Help: "Placement weight assigned to each helm-controller shard: the summed reconcile cost of its tenants, in milliseconds of reconcile time per minute.",
 	}, []string{"shard"})
 
+	tenantCostGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cozy_flux_tenant_reconcile_cost",
+		Help: "Smoothed helm-controller reconcile time spent on each tenant's HelmReleases, in milliseconds per minute.",
+	}, []string{"tenant"})
+
 	tenantsGauge = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "cozy_flux_shard_tenants",
 		Help: "Number of tenants known to the placement controller.",
@@ -35,15 +40,22 @@ var (
 		Name: "cozy_flux_shard_moves_total",
 		Help: "Total number of tenant shard reassignments performed.",
 	})
+
+	costScrapeErrorsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "cozy_flux_shard_cost_scrape_errors_total",
+		Help: "Total number of failed scrapes of shard helm-controller metrics.",
+	})
 )
 
 func init() {
 	metrics.Registry.MustRegister(
 		shardLoadGauge,
+		tenantCostGauge,
 		tenantsGauge,
 		helmReleasesGauge,
 		pendingMovesGauge,
 		recommendedShardsGauge,
 		movesCounter,
+		costScrapeErrorsCounter,
 	)
 }
@@ -8,8 +8,10 @@ import (
 type TenantInfo struct {
 	// Namespace is the tenant namespace ("tenant-<id>") and the placement key.
 	Namespace string
-	// Weight is the tenant's HelmRelease count (parent + children). Shards are
-	// balanced by weight, not by raw tenant count.
+	// Weight is the tenant's reconcile cost: the smoothed helm-controller time
+	// its HelmReleases (parent + children) take, in milliseconds per minute,
+	// or an estimate from its HelmRelease count until it has been measured.
+	// Shards are balanced by weight, not by raw tenant count.
 	Weight int
 	// Current is the tenant's current assignment: a canonical "shard<i>" value
 	// or "" when unassigned (never assigned, or still on the legacy "tenants"
@@ -284,19 +284,3 @@ func TestParseShardCount(t *testing.T) {
 		}
 	}
 }
-
-func TestParsePinnedTenants(t *testing.T) {
-	pinned, err := ParsePinnedTenants("tenant-a=shard1, tenant-b=shard0")
-	if err != nil {
-		t.Fatal(err)
-	}
-	if pinned["tenant-a"] != "shard1" || pinned["tenant-b"] != "shard0" {
-		t.Fatalf("unexpected parse result: %v", pinned)
-	}
-	if _, err := ParsePinnedTenants("tenant-a=tenants"); err == nil {
-		t.Fatal("non-canonical shard value must be rejected")
-	}
-	if _, err := ParsePinnedTenants("tenant-a"); err == nil {
-		t.Fatal("missing shard value must be rejected")
-	}
-}
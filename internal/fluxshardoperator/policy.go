@@ -0,0 +1,181 @@
+package fluxshardoperator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+)
+
+// WithPolicy returns a copy of c with the settings of policy applied. A nil
+// policy leaves c as is.
+func (c *Config) WithPolicy(policy *cozyv1alpha1.FluxShardPolicy) (*Config, error) {
+	out := *c
+	if policy == nil {
+		return &out, nil
+	}
+	spec := &policy.Spec
+
+	switch {
+	case spec.ShardCount.Type == intstr.Int:
+		if spec.ShardCount.IntVal < 1 {
+			return nil, fmt.Errorf("invalid shard count %d: must be %q or a positive integer", spec.ShardCount.IntVal, ShardCountAuto)
+		}
+		out.ShardCount, out.AutoShardCount = int(spec.ShardCount.IntVal), false
+	case spec.ShardCount.StrVal != "":
+		count, auto, err := ParseShardCount(spec.ShardCount.StrVal)
+		if err != nil {
+			return nil, err
+		}
+		out.ShardCount, out.AutoShardCount = count, auto
+	}
+	if spec.Concurrent > 0 {
+		out.ShardConcurrent = int(spec.Concurrent)
+	}
+	out.RebalanceThreshold = float64(spec.RebalanceThresholdPercent) / 100
+
+	out.PinnedTenants = make(map[string]string, len(spec.PinnedTenants))
+	for _, pin := range spec.PinnedTenants {
+		if _, ok := ParseShardIndex(pin.Shard); !ok {
+			return nil, fmt.Errorf("invalid shard %q for pinned tenant %q", pin.Shard, pin.Tenant)
+		}
+		out.PinnedTenants[pin.Tenant] = pin.Shard
+	}
+
+	out.ShardSourceController = spec.SourceController
+	out.ShardKustomizeController = spec.KustomizeController
+	out.ShardResources = *spec.Resources.DeepCopy()
+	out.ShardNodeAffinity = spec.NodeAffinity.DeepCopy()
+	out.ShardOverrides = make(map[int]ShardOverride, len(spec.Shards))
+	for _, shard := range spec.Shards {
+		idx, ok := ParseShardIndex(shard.Name)
+		if !ok {
+			return nil, fmt.Errorf("invalid shard name %q", shard.Name)
+		}
+		out.ShardOverrides[idx] = ShardOverride{
+			Resources:    *shard.Resources.DeepCopy(),
+			NodeAffinity: shard.NodeAffinity.DeepCopy(),
+		}
+	}
+	return &out, nil
+}
+
+// loadPolicy reads the FluxShardPolicy and returns the settings in effect.
+// Without a policy the base settings apply; policy is then nil.
+func loadPolicy(ctx context.Context, c client.Reader, base *Config) (*Config, *cozyv1alpha1.FluxShardPolicy, error) {
+	policy := &cozyv1alpha1.FluxShardPolicy{}
+	err := c.Get(ctx, types.NamespacedName{Name: cozyv1alpha1.FluxShardPolicyName}, policy)
+	if apierrors.IsNotFound(err) {
+		return base, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting FluxShardPolicy: %w", err)
+	}
+	cfg, err := base.WithPolicy(policy)
+	if err != nil {
+		return nil, policy, fmt.Errorf("FluxShardPolicy %s: %w", policy.Name, err)
+	}
+	return cfg, policy, nil
+}
+
+// policySpecChanged passes the FluxShardPolicy on creation, deletion and spec
+// changes, but not on the status writes of the placement controller.
+func policySpecChanged() predicate.Predicate {
+	return predicate.And(
+		predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return obj.GetName() == cozyv1alpha1.FluxShardPolicyName
+		}),
+		predicate.GenerationChangedPredicate{},
+	)
+}
+
+// policyStatus builds the FluxShardPolicy status from a placement sync.
+// Tenants are listed where they are now: a pending move keeps its tenant on
+// its current shard, or nowhere while it is not assigned yet.
+func policyStatus(policy *cozyv1alpha1.FluxShardPolicy, cfg *Config, views map[string]*tenantView, desired map[string]string,
+	pending []cozyv1alpha1.FluxShardMove, readyShards map[string]bool, shardCount, totalHRs int) cozyv1alpha1.FluxShardPolicyStatus {
+	status := cozyv1alpha1.FluxShardPolicyStatus{
+		ObservedGeneration:    policy.Generation,
+		ShardCount:            int32(shardCount),
+		RecommendedShardCount: int32(RecommendedShardCount(totalHRs, len(views))),
+		PendingMoves:          pending,
+	}
+
+	waiting := make(map[string]bool, len(pending))
+	for _, move := range pending {
+		waiting[move.Tenant] = true
+	}
+	shards := map[int]*cozyv1alpha1.FluxShardStatus{}
+	shard := func(idx int) *cozyv1alpha1.FluxShardStatus {
+		if shards[idx] == nil {
+			name := ShardName(idx)
+			shards[idx] = &cozyv1alpha1.FluxShardStatus{Name: name, Ready: readyShards[name]}
+		}
+		return shards[idx]
+	}
+	for i := 0; i < shardCount; i++ {
+		shard(i)
+	}
+	// Shards past the count still draining their tenants are reported too.
+	for name := range readyShards {
+		if idx, ok := ParseShardIndex(name); ok {
+			shard(idx)
+		}
+	}
+	for tenantNS, v := range views {
+		current := desired[tenantNS]
+		if waiting[tenantNS] {
+			current = v.info.Current
+		}
+		idx, ok := ParseShardIndex(current)
+		if !ok {
+			continue
+		}
+		s := shard(idx)
+		s.Tenants = append(s.Tenants, tenantNS)
+		s.HelmReleases += int32(len(v.hrs))
+		s.Load += int64(v.info.Weight)
+	}
+	indexes := make([]int, 0, len(shards))
+	for idx := range shards {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+	for _, idx := range indexes {
+		sort.Strings(shards[idx].Tenants)
+		status.Shards = append(status.Shards, *shards[idx])
+	}
+
+	var ignored []string
+	for tenantNS, pin := range cfg.PinnedTenants {
+		if idx, ok := ParseShardIndex(pin); ok && idx >= shardCount {
+			ignored = append(ignored, tenantNS+"="+pin)
+		}
+	}
+	if len(ignored) > 0 {
+		sort.Strings(ignored)
+		status.Message = fmt.Sprintf("pins to shards beyond the %d in use are ignored: %s", shardCount, strings.Join(ignored, ", "))
+	}
+	return status
+}
+
+// updatePolicyStatus writes status unless the policy already carries it.
+func (r *PlacementReconciler) updatePolicyStatus(ctx context.Context, policy *cozyv1alpha1.FluxShardPolicy, status cozyv1alpha1.FluxShardPolicyStatus) error {
+	if equality.Semantic.DeepEqual(policy.Status, status) {
+		return nil
+	}
+	policy.Status = status
+	if err := r.Status().Update(ctx, policy); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("updating FluxShardPolicy status: %w", err)
+	}
+	return nil
+}
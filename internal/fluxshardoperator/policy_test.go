@@ -0,0 +1,128 @@
+package fluxshardoperator
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+)
+
+func TestConfigWithPolicy(t *testing.T) {
+	base := DefaultConfig("cozy-fluxcd")
+
+	cfg, err := base.WithPolicy(nil)
+	if err != nil || !cfg.AutoShardCount || cfg.ShardConcurrent != 5 || cfg.RebalanceThreshold != 0.25 {
+		t.Fatalf("without a policy the defaults must apply: %+v, %v", cfg, err)
+	}
+
+	policy := &cozyv1alpha1.FluxShardPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: cozyv1alpha1.FluxShardPolicyName},
+		Spec: cozyv1alpha1.FluxShardPolicySpec{
+			ShardCount:                intstr.FromInt32(4),
+			Concurrent:                10,
+			RebalanceThresholdPercent: 40,
+			PinnedTenants:             []cozyv1alpha1.FluxShardPin{{Tenant: "tenant-bigone", Shard: "shard3"}},
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+			},
+			Shards: []cozyv1alpha1.FluxShardOverride{{
+				Name: "shard3",
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")},
+				},
+			}},
+		},
+	}
+	cfg, err = base.WithPolicy(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AutoShardCount || cfg.ShardCount != 4 || cfg.ShardConcurrent != 10 || cfg.RebalanceThreshold != 0.4 {
+		t.Fatalf("policy settings not applied: %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.PinnedTenants, map[string]string{"tenant-bigone": "shard3"}) {
+		t.Fatalf("unexpected pins %v", cfg.PinnedTenants)
+	}
+	if got := cfg.ShardOverrides[3].Resources.Limits[corev1.ResourceMemory]; got.String() != "8Gi" {
+		t.Fatalf("shard3 override not applied, got %s", got.String())
+	}
+	if !slices.Equal(cfg.ShardedControllers(), []string{HelmControllerContainerName}) {
+		t.Fatalf("only helm-controller is sharded by default, got %v", cfg.ShardedControllers())
+	}
+	if cfg.FluxNamespace != "cozy-fluxcd" || base.ShardCount != 0 || !base.AutoShardCount {
+		t.Fatalf("the base config must be left alone: %+v", base)
+	}
+
+	policy.Spec.ShardCount = intstr.FromString("auto")
+	policy.Spec.RebalanceThresholdPercent = 0
+	if cfg, err = base.WithPolicy(policy); err != nil || !cfg.AutoShardCount || cfg.RebalanceThreshold != 0 {
+		t.Fatalf("auto shard count with rebalancing off: %+v, %v", cfg, err)
+	}
+
+	policy.Spec.SourceController = true
+	policy.Spec.KustomizeController = true
+	cfg, err = base.WithPolicy(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{HelmControllerContainerName, SourceControllerContainerName, KustomizeControllerContainerName}; !slices.Equal(cfg.ShardedControllers(), want) {
+		t.Fatalf("sharded controllers %v, want %v", cfg.ShardedControllers(), want)
+	}
+
+	policy.Spec.ShardCount = intstr.FromString("many")
+	if _, err := base.WithPolicy(policy); err == nil {
+		t.Fatal("an invalid shard count must be rejected")
+	}
+}
+
+func TestPolicyStatus(t *testing.T) {
+	view := func(ns, current string, weight, hrs int) *tenantView {
+		return &tenantView{
+			info: TenantInfo{Namespace: ns, Current: current, Weight: weight},
+			hrs:  make([]*metav1.PartialObjectMetadata, hrs),
+		}
+	}
+	views := map[string]*tenantView{
+		"tenant-a":   view("tenant-a", "shard0", 300, 3),
+		"tenant-b":   view("tenant-b", "shard1", 100, 1),
+		"tenant-new": view("tenant-new", "", 50, 2),
+		"tenant-old": view("tenant-old", "shard2", 20, 1),
+	}
+	desired := map[string]string{
+		"tenant-a":   "shard0",
+		"tenant-b":   "shard1",
+		"tenant-new": "shard1",
+		"tenant-old": "shard0",
+	}
+	pending := []cozyv1alpha1.FluxShardMove{
+		{Tenant: "tenant-new", To: "shard1"},
+		{Tenant: "tenant-old", From: "shard2", To: "shard0"},
+	}
+	ready := map[string]bool{"shard0": true, "shard1": false, "shard2": true}
+	cfg := &Config{PinnedTenants: map[string]string{"tenant-b": "shard1", "tenant-x": "shard7"}}
+	policy := &cozyv1alpha1.FluxShardPolicy{ObjectMeta: metav1.ObjectMeta{Generation: 3}}
+
+	status := policyStatus(policy, cfg, views, desired, pending, ready, 2, 7)
+
+	want := []cozyv1alpha1.FluxShardStatus{
+		{Name: "shard0", Ready: true, Tenants: []string{"tenant-a"}, HelmReleases: 3, Load: 300},
+		{Name: "shard1", Ready: false, Tenants: []string{"tenant-b"}, HelmReleases: 1, Load: 100},
+		// Past the shard count, still draining.
+		{Name: "shard2", Ready: true, Tenants: []string{"tenant-old"}, HelmReleases: 1, Load: 20},
+	}
+	if !reflect.DeepEqual(status.Shards, want) {
+		t.Fatalf("unexpected shards:\n got %+v\nwant %+v", status.Shards, want)
+	}
+	if status.ObservedGeneration != 3 || status.ShardCount != 2 || len(status.PendingMoves) != 2 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if status.Message != "pins to shards beyond the 2 in use are ignored: tenant-x=shard7" {
+		t.Fatalf("unexpected message %q", status.Message)
+	}
+}
@@ -3,6 +3,7 @@ package fluxshardoperator
 import (
 	"context"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 	"time"
@@ -10,8 +11,11 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -20,15 +24,21 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
 )
 
 // provisionRetry is the pause before re-checking a blocked step (flux
 // Deployment not found yet, shard or legacy Deployment still draining).
 const provisionRetry = 30 * time.Second
 
+// defaultStoragePort is source-controller's --storage-addr default port.
+const defaultStoragePort = 9090
+
 // ShardSetReconciler provisions one helm-controller Deployment per shard,
 // cloned from the flux-aio "flux" Deployment's helm-controller container and
-// sanitised for standalone use. The image and feature-gates are inherited from
+// sanitised for standalone use; with the FluxShardPolicy sharding them,
+// source- and kustomize-controller Deployments are cloned the same way. The image and feature-gates are inherited from
 // flux-aio automatically, so shards stay version-synced with no manual bump.
 //
 // It also prunes shard Deployments beyond ShardCount once they drain, and
@@ -36,8 +46,11 @@ const provisionRetry = 30 * time.Second
 // carries the legacy "tenants" shard key.
 //
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cozystack.io,resources=fluxshardpolicies,verbs=get;list;watch
 type ShardSetReconciler struct {
 	client.Client
+	// Config holds the settings used while no FluxShardPolicy exists.
 	Config *Config
 }
 
@@ -53,6 +66,8 @@ func (r *ShardSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		// HelmRelease label changes drive the drain checks (shard prune and
 		// flux-tenants retirement).
 		WatchesMetadata(HelmReleaseMeta(), toSingleton, builder.WithPredicates(metadataChanged())).
+		// Sizing, resources and affinity come from the policy.
+		Watches(&cozyv1alpha1.FluxShardPolicy{}, toSingleton, builder.WithPredicates(policySpecChanged())).
 		Complete(r)
 }
 
@@ -84,24 +99,26 @@ func (r *ShardSetReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctr
 		return ctrl.Result{}, fmt.Errorf("getting flux Deployment: %w", err)
 	}
 
-	shardCount, err := r.effectiveShardCount(ctx)
+	cfg, _, err := loadPolicy(ctx, r.Client, r.Config)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	shardCount, err := r.effectiveShardCount(ctx, cfg)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
 	for i := 0; i < shardCount; i++ {
-		desired, err := BuildShardDeployment(flux, i, r.Config)
-		if err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.Patch(ctx, desired, client.Apply, client.FieldOwner(FieldOwner), client.ForceOwnership); err != nil {
-			return ctrl.Result{}, fmt.Errorf("applying %s: %w", desired.Name, err)
+		for _, controller := range cfg.ShardedControllers() {
+			if err := r.applyShard(ctx, flux, controller, i, cfg); err != nil {
+				return ctrl.Result{}, err
+			}
 		}
 	}
 
 	blocked := false
 
-	pruneBlocked, err := r.pruneExtraShards(ctx, shardCount)
+	pruneBlocked, err := r.pruneExtraShards(ctx, cfg, shardCount)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -119,6 +136,29 @@ func (r *ShardSetReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctr
 	return ctrl.Result{}, nil
 }
 
+// applyShard server-side applies one controller Deployment of a shard, and
+// for source-controller the Service its artifacts are advertised on.
+func (r *ShardSetReconciler) applyShard(ctx context.Context, flux *appsv1.Deployment, controller string, idx int, cfg *Config) error {
+	desired, err := BuildControllerShardDeployment(flux, controller, idx, cfg)
+	if err != nil {
+		return err
+	}
+	if err := r.Patch(ctx, desired, client.Apply, client.FieldOwner(FieldOwner), client.ForceOwnership); err != nil {
+		return fmt.Errorf("applying %s: %w", desired.Name, err)
+	}
+	if controller != SourceControllerContainerName {
+		return nil
+	}
+	svc, err := BuildSourceShardService(flux, idx, cfg)
+	if err != nil {
+		return err
+	}
+	if err := r.Patch(ctx, svc, client.Apply, client.FieldOwner(FieldOwner), client.ForceOwnership); err != nil {
+		return fmt.Errorf("applying Service %s: %w", svc.Name, err)
+	}
+	return nil
+}
+
 // effectiveShardCount resolves the shard count for this sync: the configured
 // value, or in auto mode the recommendation with hysteresis anchored on the
 // currently provisioned shards (see Config.EffectiveShardCount).
@@ -127,9 +167,9 @@ func (r *ShardSetReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctr
 // distributes only tenant-attributable ones — so this K can run slightly
 // ahead of what placement fills. The error direction is intentional: worst
 // case is an idle shard, never an overloaded one.
-func (r *ShardSetReconciler) effectiveShardCount(ctx context.Context) (int, error) {
-	if !r.Config.AutoShardCount {
-		return r.Config.EffectiveShardCount(0, 0, 0), nil
+func (r *ShardSetReconciler) effectiveShardCount(ctx context.Context, cfg *Config) (int, error) {
+	if !cfg.AutoShardCount {
+		return cfg.EffectiveShardCount(0, 0, 0), nil
 	}
 
 	hrs := HelmReleaseMetaList()
@@ -155,13 +195,15 @@ func (r *ShardSetReconciler) effectiveShardCount(ctx context.Context) (int, erro
 		}
 	}
 
-	return r.Config.EffectiveShardCount(len(hrs.Items), len(tenants), current), nil
+	return cfg.EffectiveShardCount(len(hrs.Items), len(tenants), current), nil
 }
 
 // pruneExtraShards deletes operator-managed shard Deployments beyond the
-// effective shard count once no HelmRelease points at them anymore. Returns
-// true while a drain is still in progress.
-func (r *ShardSetReconciler) pruneExtraShards(ctx context.Context, shardCount int) (bool, error) {
+// effective shard count, and those of controllers the policy no longer
+// shards, once none of the objects they reconcile point at them anymore.
+// A source-controller shard's Service goes with its Deployment. Returns true
+// while a drain is still in progress.
+func (r *ShardSetReconciler) pruneExtraShards(ctx context.Context, cfg *Config, shardCount int) (bool, error) {
 	logger := log.FromContext(ctx)
 
 	deps := &appsv1.DeploymentList{}
@@ -169,15 +211,19 @@ func (r *ShardSetReconciler) pruneExtraShards(ctx context.Context, shardCount in
 		client.MatchingLabels{ManagedByLabel: ManagedByValue}); err != nil {
 		return false, fmt.Errorf("listing shard Deployments: %w", err)
 	}
+	sharded := map[string]bool{}
+	for _, controller := range cfg.ShardedControllers() {
+		sharded[controller] = true
+	}
 
 	blocked := false
 	for i := range deps.Items {
 		dep := &deps.Items[i]
-		idx, ok := ParseShardDeploymentIndex(dep.Name)
-		if !ok || idx < shardCount {
+		controller, idx, ok := ParseControllerShardDeployment(dep.Name)
+		if !ok || (idx < shardCount && sharded[controller]) {
 			continue
 		}
-		drained, err := r.drained(ctx, ShardName(idx))
+		drained, err := r.drained(ctx, ControllerGVKs(controller), ShardName(idx))
 		if err != nil {
 			return false, err
 		}
@@ -189,6 +235,12 @@ func (r *ShardSetReconciler) pruneExtraShards(ctx context.Context, shardCount in
 		if err := client.IgnoreNotFound(r.Delete(ctx, dep)); err != nil {
 			return false, fmt.Errorf("deleting %s: %w", dep.Name, err)
 		}
+		if controller == SourceControllerContainerName {
+			svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: dep.Namespace, Name: dep.Name}}
+			if err := client.IgnoreNotFound(r.Delete(ctx, svc)); err != nil {
+				return false, fmt.Errorf("deleting Service %s: %w", dep.Name, err)
+			}
+		}
 	}
 	return blocked, nil
 }
@@ -208,7 +260,7 @@ func (r *ShardSetReconciler) retireLegacy(ctx context.Context) (bool, error) {
 		return false, fmt.Errorf("getting %s Deployment: %w", LegacyTenantsDeploymentName, err)
 	}
 
-	drained, err := r.drained(ctx, LegacyShardKey)
+	drained, err := r.drained(ctx, []schema.GroupVersionKind{HelmReleaseGVK}, LegacyShardKey)
 	if err != nil {
 		return false, err
 	}
@@ -223,13 +275,22 @@ func (r *ShardSetReconciler) retireLegacy(ctx context.Context) (bool, error) {
 	return false, nil
 }
 
-// drained reports whether no HelmRelease carries the given shard key anymore.
-func (r *ShardSetReconciler) drained(ctx context.Context, shardKey string) (bool, error) {
-	hrs := HelmReleaseMetaList()
-	if err := r.List(ctx, hrs, client.MatchingLabels{ShardKeyLabel: shardKey}); err != nil {
-		return false, fmt.Errorf("listing HelmReleases with %s=%s: %w", ShardKeyLabel, shardKey, err)
+// drained reports whether no object of the given kinds carries the shard key
+// anymore. A kind whose CRD is not installed has nothing to drain.
+func (r *ShardSetReconciler) drained(ctx context.Context, gvks []schema.GroupVersionKind, shardKey string) (bool, error) {
+	for _, gvk := range gvks {
+		list := MetaList(gvk)
+		if err := r.List(ctx, list, client.MatchingLabels{ShardKeyLabel: shardKey}); err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return false, fmt.Errorf("listing %ss with %s=%s: %w", gvk.Kind, ShardKeyLabel, shardKey, err)
+		}
+		if len(list.Items) > 0 {
+			return false, nil
+		}
 	}
-	return len(hrs.Items) == 0, nil
+	return true, nil
 }
 
 // mergeResourceList overlays the configured quantities onto the cloned ones,
@@ -246,17 +307,27 @@ func mergeResourceList(dst *corev1.ResourceList, overrides corev1.ResourceList)
 	}
 }
 
-// BuildShardDeployment clones the helm-controller container out of the
-// flux-aio Deployment and sanitises it into a standalone single-shard
-// Deployment:
+// BuildShardDeployment builds the helm-controller Deployment of shard idx.
+func BuildShardDeployment(flux *appsv1.Deployment, idx int, cfg *Config) (*appsv1.Deployment, error) {
+	return BuildControllerShardDeployment(flux, HelmControllerContainerName, idx, cfg)
+}
+
+// BuildControllerShardDeployment clones one controller container (helm-,
+// source- or kustomize-controller) out of the flux-aio Deployment and
+// sanitises it into a standalone single-shard Deployment:
 //
 //   - hostNetwork off (flux-aio is hostNetwork:true; N shard pods would
 //     collide on host ports 9795/9796), DNS policy back to ClusterFirst;
-//   - only the helm-controller container is kept out of the all-in-one pod;
+//   - only the cloned container is kept out of the all-in-one pod;
 //   - --events-addr is dropped (it points at the notification-controller on
 //     localhost, which does not exist in a standalone pod);
 //   - --watch-label-selector is replaced with this shard's key selector;
-//   - --concurrent and resources come from the operator values;
+//   - for helm-controller, --concurrent and resources come from the
+//     FluxShardPolicy (per-shard overrides on top of the shared settings);
+//     node affinity applies to every controller;
+//   - for source-controller, --storage-adv-addr points at the shard's own
+//     Service (see BuildSourceShardService) instead of the flux-aio one, so
+//     the artifacts it serves are fetched from the shard;
 //   - SOURCE_*_LOCALHOST env wiring and tolerations for bootstrapping
 //     CNI-less nodes are flux-aio specifics and are removed (the hand-rolled
 //     flux-tenants shard had neither);
@@ -272,34 +343,33 @@ func mergeResourceList(dst *corev1.ResourceList, overrides corev1.ResourceList)
 //   - a startupProbe is derived from the liveness handler (generous failure
 //     budget, liveness handler and TimeoutSeconds inherited) so a slow but
 //     progressing start is not killed by the short inherited liveness window.
-func BuildShardDeployment(flux *appsv1.Deployment, idx int, cfg *Config) (*appsv1.Deployment, error) {
-	var src *corev1.Container
-	for i := range flux.Spec.Template.Spec.Containers {
-		if flux.Spec.Template.Spec.Containers[i].Name == HelmControllerContainerName {
-			src = &flux.Spec.Template.Spec.Containers[i]
-			break
-		}
-	}
+func BuildControllerShardDeployment(flux *appsv1.Deployment, controller string, idx int, cfg *Config) (*appsv1.Deployment, error) {
+	src := fluxContainer(flux, controller)
 	if src == nil {
 		return nil, fmt.Errorf("container %q not found in Deployment %s/%s",
-			HelmControllerContainerName, flux.Namespace, flux.Name)
+			controller, flux.Namespace, flux.Name)
 	}
+	isHelm := controller == HelmControllerContainerName
+	name := ControllerShardDeploymentName(controller, idx)
 
 	podSpec := flux.Spec.Template.Spec.DeepCopy()
 	hc := src.DeepCopy()
 
 	selectorArg := "--watch-label-selector=" + ShardKeyLabel + "=" + ShardName(idx)
 	concurrentArg := "--concurrent=" + strconv.Itoa(cfg.ShardConcurrent)
+	advAddrArg := "--storage-adv-addr=" + name + ".$(RUNTIME_NAMESPACE).svc"
 	args := make([]string, 0, len(hc.Args))
-	haveSelector, haveConcurrent := false, false
+	haveSelector, haveConcurrent := false, !isHelm
 	for _, arg := range hc.Args {
 		switch {
 		case strings.HasPrefix(arg, "--events-addr"):
 			continue
 		case strings.HasPrefix(arg, "--watch-label-selector"):
 			args, haveSelector = append(args, selectorArg), true
-		case strings.HasPrefix(arg, "--concurrent"):
+		case isHelm && strings.HasPrefix(arg, "--concurrent"):
 			args, haveConcurrent = append(args, concurrentArg), true
+		case strings.HasPrefix(arg, "--storage-adv-addr"):
+			args = append(args, advAddrArg)
 		default:
 			args = append(args, arg)
 		}
@@ -349,9 +419,16 @@ func BuildShardDeployment(flux *appsv1.Deployment, idx int, cfg *Config) (*appsv
 
 	// Merge per resource name so an unset value inherits the cloned one, as
 	// the flag help documents — replacing the whole block would drop e.g. a
-	// cloned cpu limit when only a memory limit is configured.
-	mergeResourceList(&hc.Resources.Requests, cfg.ShardResources.Requests)
-	mergeResourceList(&hc.Resources.Limits, cfg.ShardResources.Limits)
+	// cloned cpu limit when only a memory limit is configured. The policy
+	// sizes helm-controller only; the other controllers keep the flux-aio
+	// resources.
+	override := cfg.ShardOverrides[idx]
+	if isHelm {
+		mergeResourceList(&hc.Resources.Requests, cfg.ShardResources.Requests)
+		mergeResourceList(&hc.Resources.Limits, cfg.ShardResources.Limits)
+		mergeResourceList(&hc.Resources.Requests, override.Resources.Requests)
+		mergeResourceList(&hc.Resources.Limits, override.Resources.Limits)
+	}
 
 	// Guard startup with a startupProbe derived from the liveness handler.
 	// Without it the inherited ~30s liveness window kills a controller that is
@@ -398,8 +475,19 @@ func BuildShardDeployment(flux *appsv1.Deployment, idx int, cfg *Config) (*appsv
 	if podSpec.Affinity != nil {
 		podSpec.Affinity.PodAntiAffinity = nil
 	}
+	// A node affinity from the policy replaces the cloned one, the shard's
+	// own taking precedence over the shared one.
+	nodeAffinity := cfg.ShardNodeAffinity
+	if override.NodeAffinity != nil {
+		nodeAffinity = override.NodeAffinity
+	}
+	if nodeAffinity != nil {
+		if podSpec.Affinity == nil {
+			podSpec.Affinity = &corev1.Affinity{}
+		}
+		podSpec.Affinity.NodeAffinity = nodeAffinity.DeepCopy()
+	}
 
-	name := ShardDeploymentName(idx)
 	labels := map[string]string{
 		"app.kubernetes.io/name":    name,
 		"app.kubernetes.io/part-of": "flux",
@@ -419,8 +507,8 @@ func BuildShardDeployment(flux *appsv1.Deployment, idx int, cfg *Config) (*appsv
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: ptr.To(int32(1)),
-			// helm-controller runs with leader election disabled, so two pods
-			// of one shard must never overlap during a rollout.
+			// The flux-aio controllers run with leader election disabled, so
+			// two pods of one shard must never overlap during a rollout.
 			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{"app.kubernetes.io/name": name},
@@ -438,3 +526,60 @@ func BuildShardDeployment(flux *appsv1.Deployment, idx int, cfg *Config) (*appsv
 		},
 	}, nil
 }
+
+// fluxContainer returns the named container of the flux-aio Deployment.
+func fluxContainer(flux *appsv1.Deployment, name string) *corev1.Container {
+	for i := range flux.Spec.Template.Spec.Containers {
+		if flux.Spec.Template.Spec.Containers[i].Name == name {
+			return &flux.Spec.Template.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+// BuildSourceShardService builds the Service a source-controller shard
+// advertises its artifacts on: port 80, like the flux-aio Service, to the
+// storage port of the cloned container.
+func BuildSourceShardService(flux *appsv1.Deployment, idx int, cfg *Config) (*corev1.Service, error) {
+	src := fluxContainer(flux, SourceControllerContainerName)
+	if src == nil {
+		return nil, fmt.Errorf("container %q not found in Deployment %s/%s",
+			SourceControllerContainerName, flux.Namespace, flux.Name)
+	}
+	port := defaultStoragePort
+	for _, arg := range src.Args {
+		if addr, found := strings.CutPrefix(arg, "--storage-addr="); found {
+			if _, p, err := net.SplitHostPort(addr); err == nil && p != "" {
+				n, err := strconv.Atoi(p)
+				if err != nil {
+					return nil, fmt.Errorf("invalid source-controller --storage-addr %q", addr)
+				}
+				port = n
+			}
+		}
+	}
+
+	name := ControllerShardDeploymentName(SourceControllerContainerName, idx)
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cfg.FluxNamespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":    name,
+				"app.kubernetes.io/part-of": "flux",
+				ManagedByLabel:              ManagedByValue,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: map[string]string{"app.kubernetes.io/name": name},
+			Ports: []corev1.ServicePort{{
+				Name:       "http",
+				Port:       80,
+				Protocol:   corev1.ProtocolTCP,
+				TargetPort: intstr.FromInt(port),
+			}},
+		},
+	}, nil
+}
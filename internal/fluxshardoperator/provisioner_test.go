@@ -326,6 +326,56 @@ func TestBuildShardDeploymentMergesPartialResourceOverrides(t *testing.T) {
 	}
 }
 
+func TestBuildShardDeploymentAppliesShardOverrides(t *testing.T) {
+	gpuNodes := &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{{
+					Key: "node-role.kubernetes.io/flux", Operator: corev1.NodeSelectorOpExists,
+				}},
+			}},
+		},
+	}
+	cfg := &Config{
+		FluxNamespace:   "cozy-fluxcd",
+		ShardConcurrent: 5,
+		ShardResources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+		},
+		ShardOverrides: map[int]ShardOverride{1: {
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")},
+			},
+			NodeAffinity: gpuNodes,
+		}},
+	}
+
+	plain, err := BuildShardDeployment(fluxAIODeployment(), 0, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := plain.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String(); got != "1Gi" {
+		t.Fatalf("shard0 must get the shared memory limit, got %s", got)
+	}
+	if terms := plain.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms; terms[0].MatchExpressions[0].Key != "kubernetes.io/os" {
+		t.Fatalf("shard0 must keep the cloned node affinity, got %v", terms)
+	}
+
+	heavy, err := BuildShardDeployment(fluxAIODeployment(), 1, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := heavy.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String(); got != "4Gi" {
+		t.Fatalf("shard1 must get its own memory limit, got %s", got)
+	}
+	if terms := heavy.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms; terms[0].MatchExpressions[0].Key != "node-role.kubernetes.io/flux" {
+		t.Fatalf("shard1 must get its own node affinity, got %v", terms)
+	}
+	if heavy.Spec.Template.Spec.Affinity.PodAntiAffinity != nil {
+		t.Fatal("the cloned podAntiAffinity must still be dropped")
+	}
+}
+
 func TestBuildShardDeploymentMissingContainer(t *testing.T) {
 	flux := fluxAIODeployment()
 	flux.Spec.Template.Spec.Containers = flux.Spec.Template.Spec.Containers[:1]
@@ -334,6 +384,117 @@ func TestBuildShardDeploymentMissingContainer(t *testing.T) {
 	}
 }
 
+func TestBuildControllerShardDeploymentSource(t *testing.T) {
+	flux := fluxAIODeployment()
+	flux.Spec.Template.Spec.Containers[0] = corev1.Container{
+		Name:  "source-controller",
+		Image: "ghcr.io/fluxcd/source-controller:v1.8.0",
+		Args: []string{
+			"--watch-all-namespaces",
+			"--concurrent=10",
+			"--watch-label-selector=!sharding.fluxcd.io/key",
+			"--storage-addr=:9790",
+			"--storage-path=/data",
+			"--storage-adv-addr=flux.$(RUNTIME_NAMESPACE).svc",
+			"--events-addr=http://localhost:9690",
+		},
+		VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+	}
+	cfg := &Config{
+		FluxNamespace:   "cozy-fluxcd",
+		ShardConcurrent: 3,
+		ShardResources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+		},
+	}
+
+	dep, err := BuildControllerShardDeployment(flux, SourceControllerContainerName, 2, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dep.Name != "source-controller-shard2" || dep.Labels[ManagedByLabel] != ManagedByValue {
+		t.Fatalf("unexpected Deployment %s %v", dep.Name, dep.Labels)
+	}
+	c := dep.Spec.Template.Spec.Containers[0]
+	want := []string{
+		"--watch-all-namespaces",
+		"--concurrent=10",
+		"--watch-label-selector=sharding.fluxcd.io/key=shard2",
+		"--storage-addr=:9790",
+		"--storage-path=/data",
+		"--storage-adv-addr=source-controller-shard2.$(RUNTIME_NAMESPACE).svc",
+	}
+	if !slices.Equal(c.Args, want) {
+		t.Fatalf("unexpected args:\n got %v\nwant %v", c.Args, want)
+	}
+	if len(c.Resources.Limits) != 0 {
+		t.Fatalf("helm-controller resources must not apply to source-controller, got %v", c.Resources.Limits)
+	}
+	if vols := dep.Spec.Template.Spec.Volumes; len(vols) != 1 || vols[0].Name != "data" {
+		t.Fatalf("only the storage volume must be kept, got %v", vols)
+	}
+
+	svc, err := BuildSourceShardService(flux, 2, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.Name != dep.Name || svc.Spec.Selector["app.kubernetes.io/name"] != dep.Name {
+		t.Fatalf("Service must be named after and select the shard, got %s %v", svc.Name, svc.Spec.Selector)
+	}
+	if p := svc.Spec.Ports[0]; p.Port != 80 || p.TargetPort.IntValue() != 9790 {
+		t.Fatalf("unexpected Service port %+v", p)
+	}
+}
+
+func TestShardReadiness(t *testing.T) {
+	dep := func(name string, ready int32) appsv1.Deployment {
+		return appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: ready},
+		}
+	}
+	deps := []appsv1.Deployment{
+		dep("helm-controller-shard0", 1),
+		dep("helm-controller-shard1", 1),
+		dep("source-controller-shard0", 1),
+		dep("source-controller-shard1", 0),
+		dep("kustomize-controller-shard2", 1),
+	}
+
+	ready, count := shardReadiness(deps, []string{HelmControllerContainerName})
+	if count != 2 || !ready["shard0"] || !ready["shard1"] {
+		t.Fatalf("helm-only readiness: %v, count %d", ready, count)
+	}
+	ready, count = shardReadiness(deps, []string{HelmControllerContainerName, SourceControllerContainerName})
+	if count != 2 || !ready["shard0"] || ready["shard1"] {
+		t.Fatalf("shard1 must wait for its source-controller: %v, count %d", ready, count)
+	}
+}
+
+func TestParseControllerShardDeployment(t *testing.T) {
+	for name, want := range map[string]struct {
+		controller string
+		idx        int
+	}{
+		"helm-controller-shard4":      {HelmControllerContainerName, 4},
+		"source-controller-shard0":    {SourceControllerContainerName, 0},
+		"kustomize-controller-shard1": {KustomizeControllerContainerName, 1},
+	} {
+		controller, idx, ok := ParseControllerShardDeployment(name)
+		if !ok || controller != want.controller || idx != want.idx {
+			t.Fatalf("ParseControllerShardDeployment(%s) = %s,%d,%v", name, controller, idx, ok)
+		}
+		if ControllerShardDeploymentName(controller, idx) != name {
+			t.Fatalf("ControllerShardDeploymentName does not round-trip %s", name)
+		}
+	}
+	for _, name := range []string{"flux", "source-controller-tenants", "notification-controller-shard0"} {
+		if _, _, ok := ParseControllerShardDeployment(name); ok {
+			t.Fatalf("ParseControllerShardDeployment(%s) must fail", name)
+		}
+	}
+}
+
 func TestParseShardDeploymentIndex(t *testing.T) {
 	if idx, ok := ParseShardDeploymentIndex("helm-controller-shard4"); !ok || idx != 4 {
 		t.Fatalf("ParseShardDeploymentIndex(helm-controller-shard4) = %d,%v", idx, ok)
@@ -11,6 +11,7 @@ import (
 	admissionv1 "k8s.io/api/admission/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -37,10 +38,16 @@ const WebhookPath = "/mutate-helmrelease-shard"
 // shard label over (pkg/registry/apps/application/rest.go); helm-controller
 // itself applies child HelmReleases with server-side apply, which leaves
 // labels owned by other field managers untouched.
+//
+// Sources and Kustomizations in tenant namespaces are stamped the same way
+// when the FluxShardPolicy shards source- or kustomize-controller, keeping
+// them on the shard of their tenant's HelmReleases.
 type ShardWebhook struct {
-	// Reader resolves tenant namespaces; backed by the manager's metadata
-	// cache, so lookups are in-memory.
+	// Reader resolves tenant namespaces and the FluxShardPolicy; backed by
+	// the manager's cache, so lookups are in-memory.
 	Reader client.Reader
+	// Config holds the settings used while no FluxShardPolicy exists.
+	Config *Config
 }
 
 // SetupWithManager registers the admission handler on the webhook server of
@@ -51,7 +58,7 @@ func (h *ShardWebhook) SetupWithManager(mgr ctrl.Manager) error {
 }
 
 // Handle stamps the recorded shard assignment onto a newly created
-// HelmRelease. Every miss is permissive: correctness is restored by the
+// HelmRelease, source or Kustomization. Every miss is permissive: correctness is restored by the
 // placement controller, the webhook only removes the handoff gap.
 func (h *ShardWebhook) Handle(ctx context.Context, req admission.Request) admission.Response {
 	if req.Operation != admissionv1.Create {
@@ -69,7 +76,16 @@ func (h *ShardWebhook) Handle(ctx context.Context, req admission.Request) admiss
 
 	tenantNS, ok := TenantNamespaceForHR(obj)
 	if !ok {
-		return admission.Allowed("not a tenant HelmRelease")
+		return admission.Allowed("not a tenant object")
+	}
+
+	// Requests without a kind are HelmReleases, the only kind registered
+	// before sources and Kustomizations were sharded.
+	gvk := schema.GroupVersionKind{Group: req.Kind.Group, Version: req.Kind.Version, Kind: req.Kind.Kind}
+	if gvk.Kind != "" && gvk.Kind != HelmReleaseGVK.Kind {
+		if allowed, reason := h.sharded(ctx, gvk); !allowed {
+			return admission.Allowed(reason)
+		}
 	}
 
 	ns := NamespaceMeta()
@@ -103,6 +119,26 @@ func (h *ShardWebhook) Handle(ctx context.Context, req admission.Request) admiss
 	return admission.Patched("stamped shard "+shard+" for tenant "+tenantNS, op)
 }
 
+// sharded reports whether the controller of a non-HelmRelease kind is
+// sharded by the FluxShardPolicy, with the reason when it is not.
+func (h *ShardWebhook) sharded(ctx context.Context, gvk schema.GroupVersionKind) (bool, string) {
+	controller, ok := ControllerForGVK(gvk)
+	if !ok {
+		return false, "kind " + gvk.Kind + " is not sharded"
+	}
+	cfg, _, err := loadPolicy(ctx, h.Reader, h.Config)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "loading FluxShardPolicy")
+		return false, "policy lookup failed, deferring to the placement controller"
+	}
+	for _, c := range cfg.ShardedControllers() {
+		if c == controller {
+			return true, ""
+		}
+	}
+	return false, controller + " is not sharded"
+}
+
 // escapeJSONPointer escapes a JSON pointer path segment (RFC 6901).
 func escapeJSONPointer(s string) string {
 	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
@@ -3,28 +3,33 @@ package fluxshardoperator
 import (
 	"context"
 	"encoding/json"
+	"reflect"
 	"testing"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
 )
 
-func webhookWithNamespaces(t *testing.T, namespaces ...*corev1.Namespace) *ShardWebhook {
+func webhookWithNamespaces(t *testing.T, objs ...client.Object) *ShardWebhook {
 	t.Helper()
 	scheme := runtime.NewScheme()
 	if err := clientgoscheme.AddToScheme(scheme); err != nil {
 		t.Fatal(err)
 	}
-	builder := fake.NewClientBuilder().WithScheme(scheme)
-	for _, ns := range namespaces {
-		builder = builder.WithObjects(ns)
+	if err := cozyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
 	}
-	return &ShardWebhook{Reader: builder.Build()}
+	builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...)
+	return &ShardWebhook{Reader: builder.Build(), Config: DefaultConfig("cozy-fluxcd")}
 }
 
 func createRequest(t *testing.T, namespace, name string, labels map[string]string) admission.Request {
@@ -131,3 +136,38 @@ func TestShardWebhookAlreadyCorrect(t *testing.T) {
 		t.Fatalf("correctly labeled HR must pass untouched: %v", resp)
 	}
 }
+
+func TestShardWebhookStampsSourcesWhenSharded(t *testing.T) {
+	helmChart := func() admission.Request {
+		req := createRequest(t, "tenant-foo", "tenant-foo-app", nil)
+		req.Kind = metav1.GroupVersionKind{Group: "source.toolkit.fluxcd.io", Version: "v1", Kind: "HelmChart"}
+		return req
+	}
+
+	h := webhookWithNamespaces(t, nsWithShard("tenant-foo", "shard1"))
+	if resp := h.Handle(context.Background(), helmChart()); !resp.Allowed || len(resp.Patches) != 0 {
+		t.Fatalf("without source sharding the HelmChart must stay on flux-aio: %v", resp)
+	}
+
+	policy := &cozyv1alpha1.FluxShardPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: cozyv1alpha1.FluxShardPolicyName},
+		Spec: cozyv1alpha1.FluxShardPolicySpec{
+			ShardCount:       intstr.FromString(ShardCountAuto),
+			SourceController: true,
+		},
+	}
+	h = webhookWithNamespaces(t, nsWithShard("tenant-foo", "shard1"), policy)
+	resp := h.Handle(context.Background(), helmChart())
+	if len(resp.Patches) != 1 {
+		t.Fatalf("expected the HelmChart to be stamped, got %v", resp.Patches)
+	}
+	if p := resp.Patches[0]; p.Path != "/metadata/labels" || !reflect.DeepEqual(p.Value, map[string]string{ShardKeyLabel: "shard1"}) {
+		t.Fatalf("unexpected patch: %+v", p)
+	}
+
+	kustomization := createRequest(t, "tenant-foo", "app", nil)
+	kustomization.Kind = metav1.GroupVersionKind{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Kind: "Kustomization"}
+	if resp := h.Handle(context.Background(), kustomization); len(resp.Patches) != 0 {
+		t.Fatalf("Kustomizations are not sharded by this policy: %v", resp.Patches)
+	}
+}
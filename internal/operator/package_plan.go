@@ -0,0 +1,302 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+// PlanAction is what reconciling a Package does to one HelmRelease.
+type PlanAction string
+
+const (
+	PlanCreate    PlanAction = "create"
+	PlanUpdate    PlanAction = "update"
+	PlanDelete    PlanAction = "delete"
+	PlanUnchanged PlanAction = "unchanged"
+)
+
+// PlannedRelease is the planned change to one HelmRelease.
+type PlannedRelease struct {
+	Action    PlanAction
+	Namespace string
+	Name      string
+	// Desired is the HelmRelease as Reconcile would write it; nil for a
+	// deletion.
+	Desired *helmv2.HelmRelease
+	// Current is the HelmRelease in the cluster; nil for a creation.
+	Current *helmv2.HelmRelease
+	// Diff is a line diff of Current to Desired rendered as YAML; only set
+	// for an update.
+	Diff string
+}
+
+// PackagePlan is what reconciling a Package against a PackageSource would
+// do to the cluster.
+type PackagePlan struct {
+	Package string
+	Variant string
+	// Blocked lists the dependencies that are missing or not ready. While
+	// any is, Reconcile creates, updates and deletes nothing.
+	Blocked []string
+	// Conflicts are the dependency constraints that cannot be satisfied.
+	// While there are any, Reconcile changes nothing either.
+	Conflicts []cozyv1alpha1.DependencyConflict
+	// InvalidValues are the component values of the Package that do not
+	// match the schema of their component, which also stop Reconcile.
+	InvalidValues []string
+	// Releases are sorted by namespace and name.
+	Releases []PlannedRelease
+}
+
+// Changes counts the planned creations, updates and deletions.
+func (p *PackagePlan) Changes() (create, update, del int) {
+	for _, rel := range p.Releases {
+		switch rel.Action {
+		case PlanCreate:
+			create++
+		case PlanUpdate:
+			update++
+		case PlanDelete:
+			del++
+		}
+	}
+	return create, update, del
+}
+
+// Plan computes what Reconcile would do for pkg if packageSource were the
+// PackageSource of the same name, without changing anything. pkg and
+// packageSource need not exist in the cluster yet; the dependency Packages
+// and the HelmReleases are read from it.
+func (r *PackageReconciler) Plan(ctx context.Context, pkg *cozyv1alpha1.Package, packageSource *cozyv1alpha1.PackageSource) (*PackagePlan, error) {
+	variantName := pkg.Spec.Variant
+	if variantName == "" {
+		variantName = "default"
+	}
+	var variant *cozyv1alpha1.Variant
+	for i := range packageSource.Spec.Variants {
+		if packageSource.Spec.Variants[i].Name == variantName {
+			variant = &packageSource.Spec.Variants[i]
+			break
+		}
+	}
+	if variant == nil {
+		return nil, fmt.Errorf("variant %s not found in PackageSource %s", variantName, packageSource.Name)
+	}
+	plan := &PackagePlan{Package: pkg.Name, Variant: variantName}
+
+	if errs := ValidatePackageValues(ctx, r.Client, pkg, packageSource, variant); len(errs) > 0 {
+		for _, err := range errs {
+			plan.InvalidValues = append(plan.InvalidValues, err.Error())
+		}
+		return plan, nil
+	}
+
+	conflicts, err := r.resolvePackageConflicts(ctx, pkg, packageSource)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 {
+		plan.Conflicts = conflicts
+		return plan, nil
+	}
+
+	// Reconcile gates everything on the dependency status it records; work
+	// it out on a copy.
+	status := pkg.DeepCopy()
+	status.Status.Dependencies = nil
+	if err := r.updateDependenciesStatus(ctx, status, variant); err != nil {
+		return nil, err
+	}
+	if !r.areDependenciesReady(status, variant) {
+		for dep, st := range status.Status.Dependencies {
+			if !st.Ready {
+				plan.Blocked = append(plan.Blocked, dep)
+			}
+		}
+		sort.Strings(plan.Blocked)
+		return plan, nil
+	}
+
+	for i := range variant.Components {
+		component := &variant.Components[i]
+		if component.Install == nil || !componentEnabled(pkg, component) {
+			continue
+		}
+		desired, buildErr := r.buildHelmRelease(ctx, pkg, packageSource, variant, component)
+		if buildErr != nil {
+			return nil, buildErr
+		}
+		rel, err := r.planRelease(ctx, desired)
+		if err != nil {
+			return nil, err
+		}
+		plan.Releases = append(plan.Releases, rel)
+	}
+
+	orphans, err := r.orphanedHelmReleases(ctx, pkg, variant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list HelmReleases of Package %s: %w", pkg.Name, err)
+	}
+	for i := range orphans {
+		plan.Releases = append(plan.Releases, PlannedRelease{
+			Action:    PlanDelete,
+			Namespace: orphans[i].Namespace,
+			Name:      orphans[i].Name,
+			Current:   &orphans[i],
+		})
+	}
+
+	sort.Slice(plan.Releases, func(i, j int) bool {
+		a, b := plan.Releases[i], plan.Releases[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return plan, nil
+}
+
+// planRelease compares desired with the HelmRelease in the cluster, merged
+// the way createOrUpdateHelmRelease would merge it.
+func (r *PackageReconciler) planRelease(ctx context.Context, desired *helmv2.HelmRelease) (PlannedRelease, error) {
+	rel := PlannedRelease{Namespace: desired.Namespace, Name: desired.Name, Desired: desired}
+	current := &helmv2.HelmRelease{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: desired.Namespace, Name: desired.Name}, current)
+	if apierrors.IsNotFound(err) {
+		rel.Action = PlanCreate
+		return rel, nil
+	}
+	if err != nil {
+		return rel, fmt.Errorf("failed to get HelmRelease %s/%s: %w", desired.Namespace, desired.Name, err)
+	}
+	rel.Current = current
+
+	merged := current.DeepCopy()
+	mergeHelmRelease(merged, desired.DeepCopy())
+	rel.Desired = merged
+	if equality.Semantic.DeepEqual(writtenFields(current), writtenFields(merged)) {
+		rel.Action = PlanUnchanged
+		return rel, nil
+	}
+	rel.Action = PlanUpdate
+	before, err := yaml.Marshal(writtenFields(current))
+	if err != nil {
+		return rel, err
+	}
+	after, err := yaml.Marshal(writtenFields(merged))
+	if err != nil {
+		return rel, err
+	}
+	rel.Diff = lineDiff(string(before), string(after))
+	return rel, nil
+}
+
+// writtenFields is the part of a HelmRelease Reconcile writes.
+func writtenFields(hr *helmv2.HelmRelease) map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": metav1.ObjectMeta{
+			Labels:          hr.Labels,
+			Annotations:     hr.Annotations,
+			OwnerReferences: hr.OwnerReferences,
+		},
+		"spec": hr.Spec,
+	}
+}
+
+// lineDiff renders the lines of a and b, prefixed "- " when only in a, "+ "
+// when only in b and "  " when in both, keeping three lines of context
+// around every change.
+func lineDiff(a, b string) string {
+	x := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	y := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+
+	// lcs[i][j] is the longest common subsequence of x[i:] and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, line{' ', x[i]})
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', x[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', y[j]})
+			j++
+		}
+	}
+
+	const diffContext = 3
+	keep := make([]bool, len(lines))
+	for n, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for k := max(0, n-diffContext); k <= min(len(lines)-1, n+diffContext); k++ {
+			keep[k] = true
+		}
+	}
+	var out strings.Builder
+	skipped := false
+	for n, l := range lines {
+		if !keep[n] {
+			skipped = true
+			continue
+		}
+		if skipped && out.Len() > 0 {
+			out.WriteString("  ...\n")
+		}
+		skipped = false
+		out.WriteByte(l.op)
+		out.WriteByte(' ')
+		out.WriteString(l.text)
+		out.WriteByte('\n')
+	}
+	return out.String()
+}
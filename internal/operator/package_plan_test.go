@@ -0,0 +1,169 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func planSource(variants ...cozyv1alpha1.Variant) *cozyv1alpha1.PackageSource {
+	return &cozyv1alpha1.PackageSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack.networking"},
+		Spec:       cozyv1alpha1.PackageSourceSpec{Variants: variants},
+	}
+}
+
+func planComponent(name, namespace string) cozyv1alpha1.Component {
+	return cozyv1alpha1.Component{
+		Name:    name,
+		Install: &cozyv1alpha1.ComponentInstall{Namespace: namespace},
+	}
+}
+
+func planReconciler(t *testing.T, objs ...client.Object) *PackageReconciler {
+	t.Helper()
+	scheme := testScheme(t)
+	if err := helmv2.AddToScheme(scheme); err != nil {
+		t.Fatalf("helmv2.AddToScheme: %v", err)
+	}
+	return &PackageReconciler{
+		Client:                    fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Scheme:                    scheme,
+		HelmReleaseInterval:       5 * time.Minute,
+		HelmReleaseRetryInterval:  30 * time.Second,
+		HelmReleaseInstallTimeout: 10 * time.Minute,
+		HelmReleaseUpgradeTimeout: 10 * time.Minute,
+		HelmReleaseMaxHistory:     5,
+	}
+}
+
+func TestPlanCreateUpdateDelete(t *testing.T) {
+	pkg := &cozyv1alpha1.Package{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack.networking", UID: "pkg-uid"},
+		Spec: cozyv1alpha1.PackageSpec{
+			Variant: "cilium",
+			Components: map[string]cozyv1alpha1.PackageComponent{
+				"cilium": {Values: &apiextensionsv1.JSON{Raw: []byte(`{"mtu":1400}`)}},
+			},
+		},
+	}
+	oldSource := planSource(cozyv1alpha1.Variant{
+		Name:       "cilium",
+		Components: []cozyv1alpha1.Component{planComponent("cilium", "cozy-cilium"), planComponent("kube-ovn", "cozy-kubeovn")},
+	})
+
+	// Render what the old source installed and put it in the cluster.
+	seed := planReconciler(t, pkg)
+	var existing []client.Object
+	for i := range oldSource.Spec.Variants[0].Components {
+		hr, buildErr := seed.buildHelmRelease(context.Background(), pkg, oldSource, &oldSource.Spec.Variants[0], &oldSource.Spec.Variants[0].Components[i])
+		if buildErr != nil {
+			t.Fatal(buildErr)
+		}
+		existing = append(existing, hr)
+	}
+	r := planReconciler(t, append(existing, pkg)...)
+
+	newSource := planSource(cozyv1alpha1.Variant{
+		Name: "cilium",
+		Components: []cozyv1alpha1.Component{
+			planComponent("cilium", "cozy-cilium"),
+			planComponent("hubble", "cozy-cilium"),
+		},
+	})
+	newSource.Spec.Variants[0].Components[0].Install.UpgradeCRDs = "CreateReplace"
+
+	plan, err := r.Plan(context.Background(), pkg, newSource)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	got := map[string]PlanAction{}
+	for _, rel := range plan.Releases {
+		got[rel.Namespace+"/"+rel.Name] = rel.Action
+	}
+	want := map[string]PlanAction{
+		"cozy-cilium/cilium":    PlanUpdate,
+		"cozy-cilium/hubble":    PlanCreate,
+		"cozy-kubeovn/kube-ovn": PlanDelete,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: action = %q, want %q (plan %v)", k, got[k], v, got)
+		}
+	}
+	if c, u, d := plan.Changes(); c != 1 || u != 1 || d != 1 {
+		t.Errorf("Changes() = %d/%d/%d, want 1/1/1", c, u, d)
+	}
+	for _, rel := range plan.Releases {
+		if rel.Action == PlanUpdate && !strings.Contains(rel.Diff, "+     crds: CreateReplace") {
+			t.Errorf("diff does not show the CRD policy change:\n%s", rel.Diff)
+		}
+	}
+
+	// Planning the source that is already applied changes nothing.
+	plan, err = r.Plan(context.Background(), pkg, oldSource)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if c, u, d := plan.Changes(); c+u+d != 0 {
+		t.Errorf("re-planning the applied source: %d/%d/%d changes, want none: %+v", c, u, d, plan.Releases)
+	}
+}
+
+func TestPlanBlockedOnDependencies(t *testing.T) {
+	r := planReconciler(t)
+	pkg := &cozyv1alpha1.Package{ObjectMeta: metav1.ObjectMeta{Name: "cozystack.networking"}}
+	source := planSource(cozyv1alpha1.Variant{
+		Name:       "default",
+		DependsOn:  []string{"cozystack.cert-manager"},
+		Components: []cozyv1alpha1.Component{planComponent("cilium", "cozy-cilium")},
+	})
+
+	plan, err := r.Plan(context.Background(), pkg, source)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(plan.Blocked) != 1 || plan.Blocked[0] != "cozystack.cert-manager" || len(plan.Releases) != 0 {
+		t.Errorf("plan = %+v, want blocked on cozystack.cert-manager with no releases", plan)
+	}
+
+	if _, err := r.Plan(context.Background(), &cozyv1alpha1.Package{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack.networking"},
+		Spec:       cozyv1alpha1.PackageSpec{Variant: "kubeovn"},
+	}, source); err == nil {
+		t.Error("Plan with an unknown variant succeeded")
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\n"
+	b := "a\nb\nc\nd\nE\nf\ng\nh\ni\n"
+	want := "  b\n  c\n  d\n- e\n+ E\n  f\n  g\n  h\n"
+	if got := lineDiff(a, b); got != want {
+		t.Errorf("lineDiff =\n%s\nwant\n%s", got, want)
+	}
+}
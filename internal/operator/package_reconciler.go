@@ -25,6 +25,7 @@ import (
 	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
 	"github.com/cozystack/cozystack/pkg/config"
 	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -43,6 +44,10 @@ const (
 	// AnnotationSkipCozystackValues disables injection of cozystack-values secret into HelmRelease
 	// This annotation should be placed on PackageSource
 	AnnotationSkipCozystackValues = "operator.cozystack.io/skip-cozystack-values"
+	// AnnotationBundleDigest pins a PackageSource imported from a bundle to
+	// the digest of the bundle's artifact; the PackageSource controller
+	// builds nothing from a source that serves any other artifact.
+	AnnotationBundleDigest = "operator.cozystack.io/bundle-digest"
 	// SecretCozystackValues is the name of the secret containing cluster and namespace configuration
 	SecretCozystackValues = "cozystack-values"
 )
@@ -172,6 +177,67 @@ func (r *PackageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, nil
 	}
 
+	// Refuse values the components do not accept, before Helm renders
+	// them, or ignores them
+	if errs := ValidatePackageValues(ctx, r.Client, pkg, packageSource, variant); len(errs) > 0 {
+		logger.Info("component values do not match their schema, skipping HelmRelease creation", "package", pkg.Name, "errors", errs.ToAggregate().Error())
+		meta.SetStatusCondition(&pkg.Status.Conditions, metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  "InvalidValues",
+			Message: errs.ToAggregate().Error(),
+		})
+		if err := r.Status().Update(ctx, pkg); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Refuse to install a variant whose dependency constraints cannot be
+	// satisfied together with the installed packages, rather than waiting
+	// for dependencies that will never be what it needs.
+	conflicts, err := r.resolvePackageConflicts(ctx, pkg, packageSource)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	pkg.Status.Conflicts = conflicts
+	if len(conflicts) > 0 {
+		messages := make([]string, 0, len(conflicts))
+		for _, c := range conflicts {
+			messages = append(messages, c.Message)
+		}
+		logger.Info("dependency constraints unsatisfiable, skipping HelmRelease creation", "package", pkg.Name, "conflicts", messages)
+		ready := metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  "DependencyConflict",
+			Message: strings.Join(messages, "; "),
+		}
+		if upgradeHeld(conflicts, pkg.Name) {
+			// What was applied stays installed for the Packages refusing
+			// the upgrade, and as ready as it was, so they are not blocked
+			// on it. Without a rollout policy the charts follow the source,
+			// and are held at the applied revision here; a rollout keeps
+			// them pinned until it advances.
+			ready.Reason = "UpgradeRefused"
+			if prev := meta.FindStatusCondition(pkg.Status.Conditions, "Ready"); prev != nil {
+				ready.Status = prev.Status
+			}
+			if pkg.Spec.Rollout == nil && pkg.Status.Applied != nil && pkg.Status.Applied.Revision != "" {
+				if err := r.pinPackageSource(ctx, packageSource, &sourcePins{baseline: pkg.Status.Applied.Revision}); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+		}
+		meta.SetStatusCondition(&pkg.Status.Conditions, ready)
+		if err := r.Status().Update(ctx, pkg); err != nil {
+			return ctrl.Result{}, err
+		}
+		// Another Package or PackageSource has to change first; the watches
+		// below enqueue this one when it does.
+		return ctrl.Result{}, nil
+	}
+
 	// Reconcile namespaces from components
 	if err := r.reconcileNamespaces(ctx, pkg, variant); err != nil {
 		logger.Error(err, "failed to reconcile namespaces")
@@ -201,6 +267,37 @@ func (r *PackageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, nil
 	}
 
+	// Work out which components the rollout lets through to the new
+	// revision of the source
+	rollout, buildErr := r.reconcileRollout(ctx, pkg, packageSource, variant)
+	if buildErr != nil {
+		logger.Error(buildErr, "failed to reconcile rollout", "package", pkg.Name)
+		meta.SetStatusCondition(&pkg.Status.Conditions, metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  buildErr.reason,
+			Message: buildErr.message,
+		})
+		if err := r.Status().Update(ctx, pkg); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, buildErr.err
+	}
+
+	// Without a rollout policy, a pin left on the source is the one that
+	// held a refused upgrade, which goes through now
+	if rollout.pins == nil && pkg.Spec.Rollout == nil && packageSource.Annotations[AnnotationPinnedRevision] != "" {
+		rollout.pins = &sourcePins{}
+	}
+
+	// Pin the charts before any release is touched, so a component whose
+	// wave has not started never sees the new revision
+	if rollout.pins != nil {
+		if err := r.pinPackageSource(ctx, packageSource, rollout.pins); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	// Create HelmReleases for components with Install section
 	helmReleaseCount := 0
 	for _, component := range variant.Components {
@@ -210,133 +307,47 @@ func (r *PackageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		}
 
 		// Check if component is disabled via Package spec
-		if pkgComponent, ok := pkg.Spec.Components[component.Name]; ok {
-			if pkgComponent.Enabled != nil && !*pkgComponent.Enabled {
-				logger.V(1).Info("skipping disabled component", "package", pkg.Name, "component", component.Name)
-				continue
-			}
-		}
-
-		// Build artifact name: <packagesource>-<variant>-<componentname> (with dots replaced by dashes)
-		artifactName := fmt.Sprintf("%s-%s-%s",
-			strings.ReplaceAll(packageSource.Name, ".", "-"),
-			strings.ReplaceAll(variantName, ".", "-"),
-			strings.ReplaceAll(component.Name, ".", "-"))
-
-		// Namespace must be set
-		namespace := component.Install.Namespace
-		if namespace == "" {
-			logger.Error(fmt.Errorf("component %s has empty namespace in Install section", component.Name), "namespace validation failed")
-			meta.SetStatusCondition(&pkg.Status.Conditions, metav1.Condition{
-				Type:    "Ready",
-				Status:  metav1.ConditionFalse,
-				Reason:  "InvalidConfiguration",
-				Message: fmt.Sprintf("Component %s has empty namespace in Install section", component.Name),
-			})
-			if err := r.Status().Update(ctx, pkg); err != nil {
-				return ctrl.Result{}, err
-			}
-			return ctrl.Result{}, fmt.Errorf("component %s has empty namespace in Install section", component.Name)
-		}
-
-		// Determine release name (from Install or use component name)
-		releaseName := component.Install.ReleaseName
-		if releaseName == "" {
-			releaseName = component.Name
-		}
-
-		// Build labels
-		labels := make(map[string]string)
-		labels["cozystack.io/package"] = pkg.Name
-		if component.Install.Privileged {
-			labels["cozystack.io/privileged"] = "true"
-		}
-
-		// Create HelmRelease
-		hr := &helmv2.HelmRelease{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      releaseName,
-				Namespace: namespace,
-				Labels:    labels,
-			},
-			Spec: r.buildHelmReleaseSpec(component.Install, artifactName),
-		}
-
-		// Add valuesFrom for cozystack-values secret unless disabled by annotation on PackageSource
-		if packageSource.GetAnnotations()[AnnotationSkipCozystackValues] != "true" {
-			hr.Spec.ValuesFrom = []helmv2.ValuesReference{
-				{
-					Kind: "Secret",
-					Name: SecretCozystackValues,
-				},
-			}
+		if !componentEnabled(pkg, &component) {
+			logger.V(1).Info("skipping disabled component", "package", pkg.Name, "component", component.Name)
+			continue
 		}
 
-		// Set ownerReference
-		gvk, err := apiutil.GVKForObject(pkg, r.Scheme)
-		if err != nil {
-			logger.Error(err, "failed to get GVK for Package")
+		hr, buildErr := r.buildHelmRelease(ctx, pkg, packageSource, variant, &component)
+		if buildErr != nil {
+			logger.Error(buildErr, "failed to build HelmRelease", "component", component.Name)
 			meta.SetStatusCondition(&pkg.Status.Conditions, metav1.Condition{
 				Type:    "Ready",
 				Status:  metav1.ConditionFalse,
-				Reason:  "InternalError",
-				Message: fmt.Sprintf("Failed to get GVK for Package: %v", err),
+				Reason:  buildErr.reason,
+				Message: buildErr.message,
 			})
 			if err := r.Status().Update(ctx, pkg); err != nil {
 				return ctrl.Result{}, err
 			}
-			return ctrl.Result{}, fmt.Errorf("failed to get GVK for Package: %w", err)
-		}
-		hr.OwnerReferences = []metav1.OwnerReference{
-			{
-				APIVersion: gvk.GroupVersion().String(),
-				Kind:       gvk.Kind,
-				Name:       pkg.Name,
-				UID:        pkg.UID,
-				Controller: func() *bool { b := true; return &b }(),
-			},
-		}
-
-		// Merge values from Package spec if provided
-		if pkgComponent, ok := pkg.Spec.Components[component.Name]; ok && pkgComponent.Values != nil {
-			hr.Spec.Values = pkgComponent.Values
+			return ctrl.Result{}, buildErr.err
 		}
 
-		// Build DependsOn from component Install and variant DependsOn
-		dependsOn, err := r.buildDependsOn(ctx, pkg, packageSource, variant, &component)
-		if err != nil {
-			logger.Error(err, "failed to build DependsOn", "component", component.Name)
-			meta.SetStatusCondition(&pkg.Status.Conditions, metav1.Condition{
-				Type:    "Ready",
-				Status:  metav1.ConditionFalse,
-				Reason:  "DependsOnFailed",
-				Message: fmt.Sprintf("Failed to build DependsOn for component %s: %v", component.Name, err),
-			})
-			if err := r.Status().Update(ctx, pkg); err != nil {
+		if rollout.held[component.Name] {
+			// The component's wave has not started. A release it already
+			// has stays on the pinned chart; a new one waits for the wave.
+			exists, err := r.helmReleaseExists(ctx, hr)
+			if err != nil {
+				logger.Error(err, "failed to get HelmRelease", "name", hr.Name, "namespace", hr.Namespace)
 				return ctrl.Result{}, err
 			}
-			// Return nil to stop reconciliation, error is recorded in status
-			return ctrl.Result{}, nil
-		}
-		if len(dependsOn) > 0 {
-			hr.Spec.DependsOn = dependsOn
-		}
-
-		// Set valuesFiles annotation
-		if len(component.ValuesFiles) > 0 {
-			if hr.Annotations == nil {
-				hr.Annotations = make(map[string]string)
+			if !exists {
+				logger.V(1).Info("holding HelmRelease for a later rollout wave", "package", pkg.Name, "component", component.Name)
+				continue
 			}
-			hr.Annotations["cozyhr.cozystack.io/values-files"] = strings.Join(component.ValuesFiles, ",")
 		}
 
 		if err := r.createOrUpdateHelmRelease(ctx, hr); err != nil {
-			logger.Error(err, "failed to reconcile HelmRelease", "name", releaseName, "namespace", namespace)
+			logger.Error(err, "failed to reconcile HelmRelease", "name", hr.Name, "namespace", hr.Namespace)
 			meta.SetStatusCondition(&pkg.Status.Conditions, metav1.Condition{
 				Type:    "Ready",
 				Status:  metav1.ConditionFalse,
 				Reason:  "HelmReleaseFailed",
-				Message: fmt.Sprintf("Failed to create HelmRelease %s: %v", releaseName, err),
+				Message: fmt.Sprintf("Failed to create HelmRelease %s: %v", hr.Name, err),
 			})
 			if err := r.Status().Update(ctx, pkg); err != nil {
 				return ctrl.Result{}, err
@@ -345,7 +356,7 @@ func (r *PackageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		}
 
 		helmReleaseCount++
-		logger.Info("reconciled HelmRelease", "package", pkg.Name, "component", component.Name, "releaseName", releaseName, "namespace", namespace)
+		logger.Info("reconciled HelmRelease", "package", pkg.Name, "component", component.Name, "releaseName", hr.Name, "namespace", hr.Namespace)
 	}
 
 	// Cleanup orphaned HelmReleases
@@ -354,6 +365,14 @@ func (r *PackageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		// Don't return error, continue with status update
 	}
 
+	// Record what was applied, which the Packages depending on this one
+	// are checked against while a later upgrade of it is refused
+	applied, err := r.appliedPackage(ctx, pkg, packageSource, variant)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	pkg.Status.Applied = applied
+
 	// Update status with success message
 	message := fmt.Sprintf("reconciliation succeeded, generated %d helmrelease(s)", helmReleaseCount)
 	meta.SetStatusCondition(&pkg.Status.Conditions, metav1.Condition{
@@ -379,7 +398,141 @@ func (r *PackageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	// Dependent Packages will be automatically enqueued by the watch handler
 	// when this Package's status is updated (see SetupWithManager watch handler)
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: rollout.requeueAfter}, nil
+}
+
+// appliedPackage returns what the HelmReleases of pkg were reconciled to.
+// The revision is only recorded for charts that follow the source, which a
+// refused upgrade pins them back to; a rollout tracks its own.
+func (r *PackageReconciler) appliedPackage(ctx context.Context, pkg *cozyv1alpha1.Package, packageSource *cozyv1alpha1.PackageSource, variant *cozyv1alpha1.Variant) (*cozyv1alpha1.AppliedPackage, error) {
+	applied := &cozyv1alpha1.AppliedPackage{Version: packageSource.Spec.Version, Variant: variant.Name}
+	if pkg.Spec.Rollout != nil || packageSource.Spec.SourceRef == nil {
+		return applied, nil
+	}
+	src, err := getFluxSource(ctx, r.Client, packageSource.Spec.SourceRef)
+	if err != nil {
+		return nil, err
+	}
+	if src != nil && src.artifact != nil {
+		applied.Revision = src.artifact.Revision
+	}
+	return applied, nil
+}
+
+// releaseBuildError is a failure to build the HelmRelease of a component,
+// with the Ready condition Reconcile records for it.
+type releaseBuildError struct {
+	reason  string
+	message string
+	// err is returned from Reconcile; nil records the failure in status
+	// without a requeue, as retrying cannot fix it until the Package or
+	// PackageSource changes.
+	err error
+}
+
+func (e *releaseBuildError) Error() string { return e.message }
+
+// componentEnabled reports whether the Package leaves the component enabled.
+func componentEnabled(pkg *cozyv1alpha1.Package, component *cozyv1alpha1.Component) bool {
+	pkgComponent, ok := pkg.Spec.Components[component.Name]
+	return !ok || pkgComponent.Enabled == nil || *pkgComponent.Enabled
+}
+
+// buildHelmRelease renders the HelmRelease of one installable component of
+// the Package's variant, as Reconcile writes it.
+func (r *PackageReconciler) buildHelmRelease(ctx context.Context, pkg *cozyv1alpha1.Package, packageSource *cozyv1alpha1.PackageSource, variant *cozyv1alpha1.Variant, component *cozyv1alpha1.Component) (*helmv2.HelmRelease, *releaseBuildError) {
+	// Build artifact name: <packagesource>-<variant>-<componentname> (with dots replaced by dashes)
+	artifactName := fmt.Sprintf("%s-%s-%s",
+		strings.ReplaceAll(packageSource.Name, ".", "-"),
+		strings.ReplaceAll(variant.Name, ".", "-"),
+		strings.ReplaceAll(component.Name, ".", "-"))
+
+	// Namespace must be set
+	namespace := component.Install.Namespace
+	if namespace == "" {
+		return nil, &releaseBuildError{
+			reason:  "InvalidConfiguration",
+			message: fmt.Sprintf("Component %s has empty namespace in Install section", component.Name),
+			err:     fmt.Errorf("component %s has empty namespace in Install section", component.Name),
+		}
+	}
+
+	// Determine release name (from Install or use component name)
+	releaseName := component.Install.ReleaseName
+	if releaseName == "" {
+		releaseName = component.Name
+	}
+
+	// Build labels
+	labels := make(map[string]string)
+	labels["cozystack.io/package"] = pkg.Name
+	if component.Install.Privileged {
+		labels["cozystack.io/privileged"] = "true"
+	}
+
+	hr := &helmv2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      releaseName,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: r.buildHelmReleaseSpec(component.Install, artifactName),
+	}
+
+	// Add valuesFrom for cozystack-values secret unless disabled by annotation on PackageSource
+	if packageSource.GetAnnotations()[AnnotationSkipCozystackValues] != "true" {
+		hr.Spec.ValuesFrom = []helmv2.ValuesReference{
+			{
+				Kind: "Secret",
+				Name: SecretCozystackValues,
+			},
+		}
+	}
+
+	// Set ownerReference
+	gvk, err := apiutil.GVKForObject(pkg, r.Scheme)
+	if err != nil {
+		return nil, &releaseBuildError{
+			reason:  "InternalError",
+			message: fmt.Sprintf("Failed to get GVK for Package: %v", err),
+			err:     fmt.Errorf("failed to get GVK for Package: %w", err),
+		}
+	}
+	hr.OwnerReferences = []metav1.OwnerReference{
+		{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+			Name:       pkg.Name,
+			UID:        pkg.UID,
+			Controller: func() *bool { b := true; return &b }(),
+		},
+	}
+
+	// Merge values from Package spec if provided
+	if pkgComponent, ok := pkg.Spec.Components[component.Name]; ok && pkgComponent.Values != nil {
+		hr.Spec.Values = pkgComponent.Values
+	}
+
+	// Build DependsOn from component Install and variant DependsOn
+	dependsOn, err := r.buildDependsOn(ctx, pkg, packageSource, variant, component)
+	if err != nil {
+		return nil, &releaseBuildError{
+			reason:  "DependsOnFailed",
+			message: fmt.Sprintf("Failed to build DependsOn for component %s: %v", component.Name, err),
+		}
+	}
+	if len(dependsOn) > 0 {
+		hr.Spec.DependsOn = dependsOn
+	}
+
+	// Set valuesFiles annotation
+	if len(component.ValuesFiles) > 0 {
+		hr.Annotations = map[string]string{
+			"cozyhr.cozystack.io/values-files": strings.Join(component.ValuesFiles, ","),
+		}
+	}
+
+	return hr, nil
 }
 
 // createOrUpdateHelmRelease creates or updates a HelmRelease
@@ -397,6 +550,14 @@ func (r *PackageReconciler) createOrUpdateHelmRelease(ctx context.Context, hr *h
 		return err
 	}
 
+	mergeHelmRelease(existing, hr)
+	return r.Update(ctx, existing)
+}
+
+// mergeHelmRelease applies the generated hr onto the existing HelmRelease
+// the way createOrUpdateHelmRelease updates it: labels and annotations set
+// by others are kept, and so is a suspension.
+func mergeHelmRelease(existing, hr *helmv2.HelmRelease) {
 	// Preserve resource version
 	hr.SetResourceVersion(existing.GetResourceVersion())
 
@@ -430,8 +591,6 @@ func (r *PackageReconciler) createOrUpdateHelmRelease(ctx context.Context, hr *h
 	existing.SetLabels(hr.GetLabels())
 	existing.SetAnnotations(hr.GetAnnotations())
 	existing.SetOwnerReferences(hr.GetOwnerReferences())
-
-	return r.Update(ctx, existing)
 }
 
 // getVariantForPackage retrieves the Variant for a given Package
@@ -905,6 +1064,23 @@ func (r *PackageReconciler) createOrUpdateNamespace(ctx context.Context, namespa
 func (r *PackageReconciler) cleanupOrphanedHelmReleases(ctx context.Context, pkg *cozyv1alpha1.Package, variant *cozyv1alpha1.Variant) error {
 	logger := log.FromContext(ctx)
 
+	orphans, err := r.orphanedHelmReleases(ctx, pkg, variant)
+	if err != nil {
+		return err
+	}
+	for _, hr := range orphans {
+		logger.Info("deleting orphaned HelmRelease", "name", hr.Name, "namespace", hr.Namespace, "package", pkg.Name)
+		if err := r.Delete(ctx, &hr); err != nil && !apierrors.IsNotFound(err) {
+			logger.Error(err, "failed to delete orphaned HelmRelease", "name", hr.Name, "namespace", hr.Namespace)
+		}
+	}
+
+	return nil
+}
+
+// orphanedHelmReleases lists the HelmReleases of the Package that its
+// variant no longer installs.
+func (r *PackageReconciler) orphanedHelmReleases(ctx context.Context, pkg *cozyv1alpha1.Package, variant *cozyv1alpha1.Variant) ([]helmv2.HelmRelease, error) {
 	// Build map of desired HelmRelease names (from components with Install)
 	desiredReleases := make(map[types.NamespacedName]bool)
 	for _, component := range variant.Components {
@@ -913,10 +1089,8 @@ func (r *PackageReconciler) cleanupOrphanedHelmReleases(ctx context.Context, pkg
 		}
 
 		// Check if component is disabled via Package spec
-		if pkgComponent, ok := pkg.Spec.Components[component.Name]; ok {
-			if pkgComponent.Enabled != nil && !*pkgComponent.Enabled {
-				continue
-			}
+		if !componentEnabled(pkg, &component) {
+			continue
 		}
 
 		namespace := component.Install.Namespace
@@ -941,24 +1115,20 @@ func (r *PackageReconciler) cleanupOrphanedHelmReleases(ctx context.Context, pkg
 	if err := r.List(ctx, hrList, client.MatchingLabels{
 		"cozystack.io/package": pkg.Name,
 	}); err != nil {
-		return err
+		return nil, err
 	}
 
-	// Delete HelmReleases that are not in desired list
+	var orphans []helmv2.HelmRelease
 	for _, hr := range hrList.Items {
 		key := types.NamespacedName{
 			Name:      hr.Name,
 			Namespace: hr.Namespace,
 		}
 		if !desiredReleases[key] {
-			logger.Info("deleting orphaned HelmRelease", "name", hr.Name, "namespace", hr.Namespace, "package", pkg.Name)
-			if err := r.Delete(ctx, &hr); err != nil && !apierrors.IsNotFound(err) {
-				logger.Error(err, "failed to delete orphaned HelmRelease", "name", hr.Name, "namespace", hr.Namespace)
-			}
+			orphans = append(orphans, hr)
 		}
 	}
-
-	return nil
+	return orphans, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -981,12 +1151,13 @@ func (r *PackageReconciler) SetupWithManager(mgr ctrl.Manager) error {
 					// Package not found, that's ok - it might not exist yet
 					return nil
 				}
-				// Trigger reconcile for the corresponding Package
-				return []reconcile.Request{{
+				// Trigger reconcile for the corresponding Package, and for the
+				// Packages whose constraints its new version may settle
+				return append([]reconcile.Request{{
 					NamespacedName: types.NamespacedName{
 						Name: pkg.Name,
 					},
-				}}
+				}}, r.constrainedPackageRequests(ctx, mgr.GetClient(), pkg.Name)...)
 			}),
 		).
 		Watches(
@@ -1035,8 +1206,31 @@ func (r *PackageReconciler) SetupWithManager(mgr ctrl.Manager) error {
 						}
 					}
 				}
+				// Packages with constraints are resolved again, skipping
+				// those already enqueued as dependents
+				for _, req := range r.constrainedPackageRequests(ctx, mgr.GetClient(), updatedPkg.Name) {
+					enqueued := false
+					for _, existing := range requests {
+						enqueued = enqueued || existing == req
+					}
+					if !enqueued {
+						requests = append(requests, req)
+					}
+				}
 				return requests
 			}),
 		).
+		Watches(
+			&sourcev1.GitRepository{},
+			handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+				return r.rolloutSourceRequests(ctx, mgr.GetClient(), obj)
+			}),
+		).
+		Watches(
+			&sourcev1.OCIRepository{},
+			handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+				return r.rolloutSourceRequests(ctx, mgr.GetClient(), obj)
+			}),
+		).
 		Complete(r)
 }
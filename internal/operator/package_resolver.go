@@ -0,0 +1,297 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// CheckConstraint reports how source, installed with variant, fails
+// constraint; nil when it satisfies it. An empty variant skips the variant
+// check, for a package that is not installed yet.
+func CheckConstraint(constraint cozyv1alpha1.DependencyConstraint, source *cozyv1alpha1.PackageSource, variant string) error {
+	if constraint.Version != "" {
+		c, err := semver.NewConstraint(constraint.Version)
+		if err != nil {
+			return fmt.Errorf("invalid version constraint %q: %w", constraint.Version, err)
+		}
+		if source.Spec.Version == "" {
+			return fmt.Errorf("%s has no version, %s required", source.Name, constraint.Version)
+		}
+		v, err := semver.NewVersion(source.Spec.Version)
+		if err != nil {
+			return fmt.Errorf("%s has invalid version %q: %w", source.Name, source.Spec.Version, err)
+		}
+		if !c.Check(v) {
+			return fmt.Errorf("%s %s does not satisfy %s", source.Name, source.Spec.Version, constraint.Version)
+		}
+	}
+	if variant != "" && len(constraint.Variants) > 0 && !containsString(constraint.Variants, variant) {
+		return fmt.Errorf("%s variant %s is not one of %s", source.Name, variant, strings.Join(constraint.Variants, ", "))
+	}
+	return nil
+}
+
+// resolveConflicts works out which installed packages cannot be installed
+// consistently and why. packages are the installed Packages and sources the
+// PackageSources, by name. A package conflicts when a constraint of its
+// variant is not met by a package that is installed or that it depends on,
+// when its variant requirement on a package it shares with others leaves no
+// variant to install, or when a package it depends on conflicts.
+//
+// An installed package whose upgrade, to another version or variant than it
+// applied, fails the constraint of another package conflicts as well, on
+// that constraint: the upgrade is refused rather than applied under the
+// packages it breaks.
+func resolveConflicts(packages map[string]*cozyv1alpha1.Package, sources map[string]*cozyv1alpha1.PackageSource) map[string][]cozyv1alpha1.DependencyConflict {
+	conflicts := map[string][]cozyv1alpha1.DependencyConflict{}
+	add := func(requiredBy, pkg, message string) {
+		conflicts[requiredBy] = append(conflicts[requiredBy], cozyv1alpha1.DependencyConflict{Package: pkg, RequiredBy: requiredBy, Message: message})
+	}
+	refuse := func(pkg, requiredBy, message string) {
+		conflicts[pkg] = append(conflicts[pkg], cozyv1alpha1.DependencyConflict{Package: pkg, RequiredBy: requiredBy, Message: message})
+	}
+
+	variants := map[string]*cozyv1alpha1.Variant{}
+	for name, pkg := range packages {
+		if v := packageVariant(pkg, sources[name]); v != nil {
+			variants[name] = v
+		}
+	}
+
+	// Variant requirements on packages that are not installed yet, which
+	// must leave at least one variant to install them with.
+	type requirement struct {
+		by       string
+		variants []string
+	}
+	pending := map[string][]requirement{}
+
+	for _, name := range sortedKeys(variants) {
+		pkg, variant := packages[name], variants[name]
+		for _, constraint := range variant.Constraints {
+			if containsString(pkg.Spec.IgnoreDependencies, constraint.Name) {
+				continue
+			}
+			target, installed := packages[constraint.Name]
+			if !installed && !containsString(variant.DependsOn, constraint.Name) {
+				continue
+			}
+			source := sources[constraint.Name]
+			if source == nil {
+				// A missing dependency is waited for, not a conflict.
+				continue
+			}
+			targetVariant := ""
+			if installed {
+				targetVariant = packageVariantName(target)
+			} else if len(constraint.Variants) > 0 {
+				pending[constraint.Name] = append(pending[constraint.Name], requirement{by: name, variants: constraint.Variants})
+			}
+			if err := CheckConstraint(constraint, source, targetVariant); err != nil {
+				add(name, constraint.Name, err.Error())
+				if installed && upgrading(target, source) {
+					refuse(constraint.Name, name, fmt.Sprintf("upgrade refused, %s requires it: %v", name, err))
+				}
+			}
+		}
+	}
+
+	for _, target := range sortedKeys(pending) {
+		reqs := pending[target]
+		allowed := reqs[0].variants
+		for _, req := range reqs[1:] {
+			var both []string
+			for _, v := range allowed {
+				if containsString(req.variants, v) {
+					both = append(both, v)
+				}
+			}
+			allowed = both
+		}
+		if len(allowed) > 0 {
+			continue
+		}
+		var parts []string
+		for _, req := range reqs {
+			parts = append(parts, fmt.Sprintf("%s requires %s", req.by, strings.Join(req.variants, " or ")))
+		}
+		for _, req := range reqs {
+			add(req.by, target, fmt.Sprintf("no variant of %s satisfies every package: %s", target, strings.Join(parts, "; ")))
+		}
+	}
+
+	// A package cannot be installed while a dependency cannot. A dependency
+	// whose upgrade is refused stays installed as it is.
+	for changed := true; changed; {
+		changed = false
+		for _, name := range sortedKeys(variants) {
+			pkg, variant := packages[name], variants[name]
+			for _, dep := range variant.DependsOn {
+				if upgradeHeld(conflicts[dep], dep) || containsString(pkg.Spec.IgnoreDependencies, dep) || hasConflictOn(conflicts[name], dep) {
+					continue
+				}
+				add(name, dep, fmt.Sprintf("dependency %s has unsatisfiable constraints", dep))
+				changed = true
+			}
+		}
+	}
+
+	for name := range conflicts {
+		sort.SliceStable(conflicts[name], func(i, j int) bool {
+			return conflicts[name][i].Package < conflicts[name][j].Package
+		})
+	}
+	return conflicts
+}
+
+// resolvePackageConflicts resolves the conflicts of pkg against the
+// Packages and PackageSources in the cluster, with pkg and packageSource in
+// place of their namesakes. Every other Package is taken at the version and
+// variant it applied, so pkg is not blocked by an upgrade of another that
+// is refused, or not applied yet.
+func (r *PackageReconciler) resolvePackageConflicts(ctx context.Context, pkg *cozyv1alpha1.Package, packageSource *cozyv1alpha1.PackageSource) ([]cozyv1alpha1.DependencyConflict, error) {
+	packageList := &cozyv1alpha1.PackageList{}
+	if err := r.List(ctx, packageList); err != nil {
+		return nil, fmt.Errorf("failed to list Packages: %w", err)
+	}
+	sourceList := &cozyv1alpha1.PackageSourceList{}
+	if err := r.List(ctx, sourceList); err != nil {
+		return nil, fmt.Errorf("failed to list PackageSources: %w", err)
+	}
+	packages := map[string]*cozyv1alpha1.Package{pkg.Name: pkg}
+	for i := range packageList.Items {
+		other := &packageList.Items[i]
+		if other.Name == pkg.Name {
+			continue
+		}
+		if applied := other.Status.Applied; applied != nil {
+			other.Spec.Variant = applied.Variant
+		}
+		packages[other.Name] = other
+	}
+	sources := map[string]*cozyv1alpha1.PackageSource{packageSource.Name: packageSource}
+	for i := range sourceList.Items {
+		source := &sourceList.Items[i]
+		if source.Name == packageSource.Name {
+			continue
+		}
+		if other := packages[source.Name]; other != nil && other.Status.Applied != nil {
+			source.Spec.Version = other.Status.Applied.Version
+		}
+		sources[source.Name] = source
+	}
+	return resolveConflicts(packages, sources)[pkg.Name], nil
+}
+
+// constrainedPackageRequests enqueues the Packages whose variant declares
+// constraints, except the one named name: installing, removing or changing
+// any package may satisfy or break them.
+func (r *PackageReconciler) constrainedPackageRequests(ctx context.Context, c client.Client, name string) []reconcile.Request {
+	packageList := &cozyv1alpha1.PackageList{}
+	if err := c.List(ctx, packageList); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range packageList.Items {
+		pkg := &packageList.Items[i]
+		if pkg.Name == name {
+			continue
+		}
+		variant, err := r.getVariantForPackage(ctx, pkg, c)
+		if err != nil || len(variant.Constraints) == 0 {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: pkg.Name}})
+	}
+	return requests
+}
+
+func packageVariantName(pkg *cozyv1alpha1.Package) string {
+	if pkg.Spec.Variant == "" {
+		return "default"
+	}
+	return pkg.Spec.Variant
+}
+
+func packageVariant(pkg *cozyv1alpha1.Package, source *cozyv1alpha1.PackageSource) *cozyv1alpha1.Variant {
+	if source == nil {
+		return nil
+	}
+	name := packageVariantName(pkg)
+	for i := range source.Spec.Variants {
+		if source.Spec.Variants[i].Name == name {
+			return &source.Spec.Variants[i]
+		}
+	}
+	return nil
+}
+
+// upgrading reports whether installing pkg from source changes the version
+// or variant it applied.
+func upgrading(pkg *cozyv1alpha1.Package, source *cozyv1alpha1.PackageSource) bool {
+	applied := pkg.Status.Applied
+	return applied != nil && (applied.Version != source.Spec.Version || applied.Variant != packageVariantName(pkg))
+}
+
+// upgradeHeld reports whether none of conflicts, those of the Package
+// named name, is on a constraint of its own: refusals of its upgrade by
+// other packages leave it installed as it applied.
+func upgradeHeld(conflicts []cozyv1alpha1.DependencyConflict, name string) bool {
+	for _, c := range conflicts {
+		if c.RequiredBy == name {
+			return false
+		}
+	}
+	return true
+}
+
+func hasConflictOn(conflicts []cozyv1alpha1.DependencyConflict, pkg string) bool {
+	for _, c := range conflicts {
+		if c.Package == pkg {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
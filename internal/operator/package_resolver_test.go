@@ -0,0 +1,381 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func resolverSource(name, version string, variants ...cozyv1alpha1.Variant) *cozyv1alpha1.PackageSource {
+	if len(variants) == 0 {
+		variants = []cozyv1alpha1.Variant{{Name: "default"}}
+	}
+	return &cozyv1alpha1.PackageSource{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       cozyv1alpha1.PackageSourceSpec{Version: version, Variants: variants},
+	}
+}
+
+func resolverPackage(name, variant string) *cozyv1alpha1.Package {
+	return &cozyv1alpha1.Package{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       cozyv1alpha1.PackageSpec{Variant: variant},
+	}
+}
+
+func TestCheckConstraint(t *testing.T) {
+	networking := resolverSource("cozystack.networking", "1.4.2")
+	cases := []struct {
+		name       string
+		constraint cozyv1alpha1.DependencyConstraint
+		source     *cozyv1alpha1.PackageSource
+		variant    string
+		wantErr    string
+	}{
+		{"satisfied", cozyv1alpha1.DependencyConstraint{Version: ">= 1.4"}, networking, "cilium", ""},
+		{"too old", cozyv1alpha1.DependencyConstraint{Version: ">= 1.5"}, networking, "cilium", "1.4.2 does not satisfy >= 1.5"},
+		{"caret", cozyv1alpha1.DependencyConstraint{Version: "^1.2 || ^2"}, networking, "", ""},
+		{"no version", cozyv1alpha1.DependencyConstraint{Version: ">= 1.4"}, resolverSource("cozystack.networking", ""), "", "has no version"},
+		{"invalid version", cozyv1alpha1.DependencyConstraint{Version: ">= 1.4"}, resolverSource("cozystack.networking", "latest"), "", "invalid version"},
+		{"invalid constraint", cozyv1alpha1.DependencyConstraint{Version: ">>1"}, networking, "", "invalid version constraint"},
+		{"allowed variant", cozyv1alpha1.DependencyConstraint{Variants: []string{"cilium", "kubeovn"}}, networking, "kubeovn", ""},
+		{"other variant", cozyv1alpha1.DependencyConstraint{Variants: []string{"cilium"}}, networking, "kubeovn", "variant kubeovn is not one of cilium"},
+		{"variant not known yet", cozyv1alpha1.DependencyConstraint{Variants: []string{"cilium"}}, networking, "", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckConstraint(tc.constraint, tc.source, tc.variant)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckConstraint: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("CheckConstraint err = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveConflicts(t *testing.T) {
+	needs := func(name string, dependsOn bool, version string, variants ...string) cozyv1alpha1.Variant {
+		v := cozyv1alpha1.Variant{
+			Name:        "default",
+			Constraints: []cozyv1alpha1.DependencyConstraint{{Name: name, Version: version, Variants: variants}},
+		}
+		if dependsOn {
+			v.DependsOn = []string{name}
+		}
+		return v
+	}
+
+	cases := []struct {
+		name     string
+		packages []*cozyv1alpha1.Package
+		sources  []*cozyv1alpha1.PackageSource
+		want     map[string][]string // package -> constrained packages in conflict
+	}{
+		{
+			name:     "satisfied dependency",
+			packages: []*cozyv1alpha1.Package{resolverPackage("app", ""), resolverPackage("net", "cilium")},
+			sources: []*cozyv1alpha1.PackageSource{
+				resolverSource("app", "1.0.0", needs("net", true, ">= 1.4", "cilium")),
+				resolverSource("net", "1.4.0", cozyv1alpha1.Variant{Name: "cilium"}),
+			},
+		},
+		{
+			name:     "dependency too old",
+			packages: []*cozyv1alpha1.Package{resolverPackage("app", ""), resolverPackage("net", "")},
+			sources: []*cozyv1alpha1.PackageSource{
+				resolverSource("app", "1.0.0", needs("net", true, ">= 1.4")),
+				resolverSource("net", "1.3.0"),
+			},
+			want: map[string][]string{"app": {"net"}},
+		},
+		{
+			name:     "dependency not installed yet is checked by version",
+			packages: []*cozyv1alpha1.Package{resolverPackage("app", "")},
+			sources: []*cozyv1alpha1.PackageSource{
+				resolverSource("app", "1.0.0", needs("net", true, ">= 1.4")),
+				resolverSource("net", "1.3.0"),
+			},
+			want: map[string][]string{"app": {"net"}},
+		},
+		{
+			name:     "dependency source missing is waited for",
+			packages: []*cozyv1alpha1.Package{resolverPackage("app", "")},
+			sources:  []*cozyv1alpha1.PackageSource{resolverSource("app", "1.0.0", needs("net", true, ">= 1.4"))},
+		},
+		{
+			name:     "conflict only applies when installed",
+			packages: []*cozyv1alpha1.Package{resolverPackage("app", "")},
+			sources: []*cozyv1alpha1.PackageSource{
+				resolverSource("app", "1.0.0", needs("legacy", false, ">= 2")),
+				resolverSource("legacy", "1.0.0"),
+			},
+		},
+		{
+			name:     "installed package conflicts",
+			packages: []*cozyv1alpha1.Package{resolverPackage("app", ""), resolverPackage("legacy", "")},
+			sources: []*cozyv1alpha1.PackageSource{
+				resolverSource("app", "1.0.0", needs("legacy", false, ">= 2")),
+				resolverSource("legacy", "1.0.0"),
+			},
+			want: map[string][]string{"app": {"legacy"}},
+		},
+		{
+			name:     "ignored dependency",
+			packages: []*cozyv1alpha1.Package{{ObjectMeta: metav1.ObjectMeta{Name: "app"}, Spec: cozyv1alpha1.PackageSpec{IgnoreDependencies: []string{"net"}}}},
+			sources: []*cozyv1alpha1.PackageSource{
+				resolverSource("app", "1.0.0", needs("net", true, ">= 1.4")),
+				resolverSource("net", "1.3.0"),
+			},
+		},
+		{
+			name:     "incompatible variants of a package not installed",
+			packages: []*cozyv1alpha1.Package{resolverPackage("a", ""), resolverPackage("b", ""), resolverPackage("c", "")},
+			sources: []*cozyv1alpha1.PackageSource{
+				resolverSource("a", "1.0.0", needs("net", true, "", "cilium")),
+				resolverSource("b", "1.0.0", needs("net", true, "", "kubeovn", "calico")),
+				resolverSource("c", "1.0.0", needs("net", true, "", "cilium", "calico")),
+				resolverSource("net", "1.0.0"),
+			},
+			want: map[string][]string{"a": {"net"}, "b": {"net"}, "c": {"net"}},
+		},
+		{
+			name:     "conflict propagates to dependents",
+			packages: []*cozyv1alpha1.Package{resolverPackage("top", ""), resolverPackage("app", ""), resolverPackage("net", "")},
+			sources: []*cozyv1alpha1.PackageSource{
+				resolverSource("top", "1.0.0", cozyv1alpha1.Variant{Name: "default", DependsOn: []string{"app"}}),
+				resolverSource("app", "1.0.0", needs("net", true, ">= 2")),
+				resolverSource("net", "1.0.0"),
+			},
+			want: map[string][]string{"top": {"app"}, "app": {"net"}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			packages := map[string]*cozyv1alpha1.Package{}
+			for _, pkg := range tc.packages {
+				packages[pkg.Name] = pkg
+			}
+			sources := map[string]*cozyv1alpha1.PackageSource{}
+			for _, ps := range tc.sources {
+				sources[ps.Name] = ps
+			}
+			got := resolveConflicts(packages, sources)
+			if len(got) != len(tc.want) {
+				t.Fatalf("conflicts = %+v, want on %v", got, tc.want)
+			}
+			for name, wantOn := range tc.want {
+				var on []string
+				for _, c := range got[name] {
+					if c.RequiredBy != name {
+						t.Errorf("conflict %+v recorded on %s", c, name)
+					}
+					on = append(on, c.Package)
+				}
+				if strings.Join(on, ",") != strings.Join(wantOn, ",") {
+					t.Errorf("%s conflicts on %v (%+v), want %v", name, on, got[name], wantOn)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcile_DependencyConflict(t *testing.T) {
+	app := resolverSource("app", "1.0.0", cozyv1alpha1.Variant{
+		Name:        "default",
+		DependsOn:   []string{"net"},
+		Constraints: []cozyv1alpha1.DependencyConstraint{{Name: "net", Version: ">= 1.4"}},
+		Components:  []cozyv1alpha1.Component{planComponent("app", "cozy-app")},
+	})
+	net := resolverSource("net", "1.3.0")
+	objs := []client.Object{app, net, resolverPackage("app", ""), resolverPackage("net", "")}
+	r := planReconciler(t)
+	r.Client = fake.NewClientBuilder().WithScheme(r.Scheme).WithObjects(objs...).
+		WithStatusSubresource(&cozyv1alpha1.Package{}).Build()
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "app"}}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got := &cozyv1alpha1.Package{}
+	if err := r.Get(context.Background(), req.NamespacedName, got); err != nil {
+		t.Fatal(err)
+	}
+	ready := meta.FindStatusCondition(got.Status.Conditions, "Ready")
+	if ready == nil || ready.Reason != "DependencyConflict" || !strings.Contains(ready.Message, "net 1.3.0 does not satisfy >= 1.4") {
+		t.Errorf("Ready = %+v, want DependencyConflict", ready)
+	}
+	if len(got.Status.Conflicts) != 1 || got.Status.Conflicts[0].Package != "net" {
+		t.Errorf("Conflicts = %+v", got.Status.Conflicts)
+	}
+
+	if requests := r.constrainedPackageRequests(context.Background(), r.Client, "net"); len(requests) != 1 || requests[0].Name != "app" {
+		t.Errorf("net change enqueued %v, want app", requests)
+	}
+
+	// The plan for a source that satisfies the constraint is unblocked on it.
+	upgraded := net.DeepCopy()
+	upgraded.Spec.Version = "1.4.0"
+	plan, err := r.Plan(context.Background(), resolverPackage("net", ""), upgraded)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(plan.Conflicts) != 0 {
+		t.Errorf("plan conflicts = %+v, want none", plan.Conflicts)
+	}
+	plan, err = r.Plan(context.Background(), resolverPackage("app", ""), app)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(plan.Conflicts) != 1 {
+		t.Errorf("plan conflicts = %+v, want the net constraint", plan.Conflicts)
+	}
+}
+
+func TestResolveConflicts_RefusedUpgrade(t *testing.T) {
+	applied := func(name, version string) *cozyv1alpha1.Package {
+		pkg := resolverPackage(name, "")
+		pkg.Status.Applied = &cozyv1alpha1.AppliedPackage{Version: version, Variant: "default"}
+		return pkg
+	}
+	packages := map[string]*cozyv1alpha1.Package{
+		"net":   applied("net", "1.0.0"),
+		"app":   applied("app", "1.0.0"),
+		"store": applied("store", "1.0.0"),
+	}
+	sources := map[string]*cozyv1alpha1.PackageSource{
+		"net": resolverSource("net", "2.0.0"),
+		"app": resolverSource("app", "1.0.0", cozyv1alpha1.Variant{
+			Name:        "default",
+			DependsOn:   []string{"net"},
+			Constraints: []cozyv1alpha1.DependencyConstraint{{Name: "net", Version: "< 2"}},
+		}),
+		"store": resolverSource("store", "1.0.0", cozyv1alpha1.Variant{Name: "default", DependsOn: []string{"net"}}),
+	}
+	got := resolveConflicts(packages, sources)
+	if len(got["net"]) != 1 || got["net"][0].Package != "net" || got["net"][0].RequiredBy != "app" ||
+		!strings.Contains(got["net"][0].Message, "upgrade refused, app requires it: net 2.0.0 does not satisfy < 2") {
+		t.Errorf("net conflicts = %+v, want its upgrade refused by app", got["net"])
+	}
+	// A dependency held at what it applied does not block the packages
+	// depending on it.
+	if len(got["store"]) != 0 {
+		t.Errorf("store conflicts = %+v, want none", got["store"])
+	}
+
+	// Nothing is refused before the package applied anything.
+	packages["net"].Status.Applied = nil
+	if got := resolveConflicts(packages, sources); len(got["net"]) != 0 {
+		t.Errorf("net conflicts = %+v, want none before it is installed", got["net"])
+	}
+}
+
+func TestReconcile_RefusedUpgrade(t *testing.T) {
+	ctx := context.Background()
+	ref := &cozyv1alpha1.PackageSourceRef{Kind: sourcev1.OCIRepositoryKind, Name: "cozystack-packages", Namespace: "cozy-system"}
+	net := resolverSource("net", "2.0.0", cozyv1alpha1.Variant{Name: "default", Components: []cozyv1alpha1.Component{planComponent("net", "cozy-net")}})
+	net.Spec.SourceRef = ref
+	app := resolverSource("app", "1.0.0", cozyv1alpha1.Variant{
+		Name:        "default",
+		DependsOn:   []string{"net"},
+		Constraints: []cozyv1alpha1.DependencyConstraint{{Name: "net", Version: "< 2"}},
+		Components:  []cozyv1alpha1.Component{planComponent("app", "cozy-app")},
+	})
+	netPkg := resolverPackage("net", "")
+	netPkg.Status.Applied = &cozyv1alpha1.AppliedPackage{Version: "1.0.0", Variant: "default", Revision: "v1@sha256:1111"}
+	netPkg.Status.Conditions = []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue, Reason: "ReconciliationSucceeded", LastTransitionTime: metav1.Now()}}
+	appPkg := resolverPackage("app", "")
+	appPkg.Status.Applied = &cozyv1alpha1.AppliedPackage{Version: "1.0.0", Variant: "default"}
+	r := rolloutReconciler(t, netPkg, appPkg, net, app, rolloutOCIRepository("v2@sha256:2222"))
+
+	reconcile := func(name string) *cozyv1alpha1.Package {
+		t.Helper()
+		req := ctrl.Request{NamespacedName: types.NamespacedName{Name: name}}
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile %s: %v", name, err)
+		}
+		pkg := &cozyv1alpha1.Package{}
+		if err := r.Get(ctx, req.NamespacedName, pkg); err != nil {
+			t.Fatal(err)
+		}
+		return pkg
+	}
+	pinned := func() string {
+		t.Helper()
+		ps := &cozyv1alpha1.PackageSource{}
+		if err := r.Get(ctx, types.NamespacedName{Name: "net"}, ps); err != nil {
+			t.Fatal(err)
+		}
+		return ps.Annotations[AnnotationPinnedRevision]
+	}
+
+	got := reconcile("net")
+	ready := meta.FindStatusCondition(got.Status.Conditions, "Ready")
+	if ready == nil || ready.Status != metav1.ConditionTrue || ready.Reason != "UpgradeRefused" {
+		t.Errorf("Ready = %+v, want True with the upgrade refused", ready)
+	}
+	if len(got.Status.Conflicts) != 1 || got.Status.Conflicts[0].RequiredBy != "app" {
+		t.Errorf("Conflicts = %+v, want the app constraint", got.Status.Conflicts)
+	}
+	if pin := pinned(); pin != "v1@sha256:1111" {
+		t.Errorf("net pinned to %q, want the applied revision", pin)
+	}
+	if exists, err := r.helmReleaseExists(ctx, &helmv2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Name: "net", Namespace: "cozy-net"}}); err != nil || exists {
+		t.Errorf("HelmRelease of the refused upgrade written: %v, %v", exists, err)
+	}
+
+	// The dependent is checked against what net applied.
+	got = reconcile("app")
+	if ready := meta.FindStatusCondition(got.Status.Conditions, "Ready"); ready == nil || ready.Reason != "ReconciliationSucceeded" {
+		t.Errorf("app Ready = %+v, want it reconciled", ready)
+	}
+
+	// Once the dependent accepts it, the upgrade goes through.
+	if err := r.Get(ctx, types.NamespacedName{Name: "app"}, app); err != nil {
+		t.Fatal(err)
+	}
+	app.Spec.Variants[0].Constraints[0].Version = "< 3"
+	if err := r.Update(ctx, app); err != nil {
+		t.Fatal(err)
+	}
+	got = reconcile("net")
+	if ready := meta.FindStatusCondition(got.Status.Conditions, "Ready"); ready == nil || ready.Reason != "ReconciliationSucceeded" {
+		t.Errorf("Ready = %+v, want the upgrade applied", ready)
+	}
+	if pin := pinned(); pin != "" {
+		t.Errorf("net still pinned to %q", pin)
+	}
+	if a := got.Status.Applied; a == nil || a.Version != "2.0.0" || a.Revision != "v2@sha256:2222" {
+		t.Errorf("Applied = %+v, want 2.0.0 at v2@sha256:2222", a)
+	}
+}
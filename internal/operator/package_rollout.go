@@ -0,0 +1,496 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// defaultRolloutHealthTimeout is how long a wave may take to become healthy
+// when the rollout policy does not say.
+const defaultRolloutHealthTimeout = 15 * time.Minute
+
+// +kubebuilder:rbac:groups=cozystack.io,resources=packagesources,verbs=update;patch
+// +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=externalartifacts,verbs=get;list;watch
+
+// rolloutStep is what the rollout of a Package asks of the reconcile that
+// evaluated it.
+type rolloutStep struct {
+	// held are the components whose wave has not started, which must not
+	// get a HelmRelease yet if they have none
+	held map[string]bool
+	// pins, when set, are the revisions the charts of the PackageSource
+	// must be generated from
+	pins *sourcePins
+	// requeueAfter is when the rollout must be looked at again without
+	// anything changing, to end a pause or time out a wave
+	requeueAfter time.Duration
+}
+
+// sourcePins are the revisions a rollout pins the charts of a PackageSource
+// to, as AnnotationPinnedRevision, AnnotationRolloutRevision and
+// AnnotationRolloutComponents.
+type sourcePins struct {
+	// baseline is the revision every component not listed in components
+	// reads; empty follows the source
+	baseline string
+	// revision is the revision being rolled out, read by components
+	revision   string
+	components []string
+}
+
+// rolloutWaves returns the components of each wave of the rollout policy of
+// pkg, with the installable components the policy leaves out in a last wave
+// of their own.
+func rolloutWaves(pkg *cozyv1alpha1.Package, variant *cozyv1alpha1.Variant) ([][]string, error) {
+	installable := map[string]bool{}
+	known := map[string]bool{}
+	for i := range variant.Components {
+		component := &variant.Components[i]
+		known[component.Name] = true
+		installable[component.Name] = component.Install != nil && componentEnabled(pkg, component)
+	}
+
+	var waves [][]string
+	assigned := map[string]string{}
+	for _, wave := range pkg.Spec.Rollout.Waves {
+		var components []string
+		for _, name := range wave.Components {
+			if !known[name] {
+				return nil, fmt.Errorf("rollout wave %s lists component %s, which variant %s does not have", wave.Name, name, variant.Name)
+			}
+			if other, ok := assigned[name]; ok {
+				return nil, fmt.Errorf("component %s is in rollout waves %s and %s", name, other, wave.Name)
+			}
+			assigned[name] = wave.Name
+			if installable[name] {
+				components = append(components, name)
+			}
+		}
+		waves = append(waves, components)
+	}
+
+	var rest []string
+	for i := range variant.Components {
+		name := variant.Components[i].Name
+		if _, ok := assigned[name]; !ok && installable[name] {
+			rest = append(rest, name)
+		}
+	}
+	if len(rest) > 0 {
+		waves = append(waves, rest)
+	}
+	return waves, nil
+}
+
+// reconcileRollout advances the staged rollout of pkg to the revision of
+// the source of packageSource and records its progress in pkg's status,
+// without writing it. It does nothing for a Package without a rollout
+// policy.
+//
+// Waves are gated at the artifact: the PackageSource stays pinned to the
+// last-known-good revision, and only the components of the waves that have
+// started read the revision being rolled out, so a new revision of the
+// source reaches no chart before its wave starts. A wave is healthy when
+// the HelmRelease of each of its components is Ready for the artifact it
+// points at. That artifact is regenerated by the ArtifactGenerator of
+// packageSource after the wave starts, so a wave can look healthy on the
+// previous artifact for a short while; the pause between waves is what
+// absorbs that.
+func (r *PackageReconciler) reconcileRollout(ctx context.Context, pkg *cozyv1alpha1.Package, packageSource *cozyv1alpha1.PackageSource, variant *cozyv1alpha1.Variant) (*rolloutStep, *releaseBuildError) {
+	if pkg.Spec.Rollout == nil {
+		step := &rolloutStep{}
+		if pkg.Status.Rollout != nil {
+			// The pins were ours, and so is lifting them.
+			step.pins = &sourcePins{}
+		}
+		pkg.Status.Rollout = nil
+		return step, nil
+	}
+
+	waves, err := rolloutWaves(pkg, variant)
+	if err != nil {
+		return nil, &releaseBuildError{reason: "InvalidRollout", message: err.Error()}
+	}
+	step, buildErr := r.advanceRollout(ctx, pkg, packageSource, variant, waves)
+	if buildErr != nil {
+		return nil, buildErr
+	}
+	if st := pkg.Status.Rollout; st != nil {
+		step.pins = rolloutPins(st, waves)
+	}
+	return step, nil
+}
+
+// advanceRollout moves the rollout status of pkg on by what the source and
+// the HelmReleases of the current wave report.
+func (r *PackageReconciler) advanceRollout(ctx context.Context, pkg *cozyv1alpha1.Package, packageSource *cozyv1alpha1.PackageSource, variant *cozyv1alpha1.Variant, waves [][]string) (*rolloutStep, *releaseBuildError) {
+	step := &rolloutStep{}
+	policy := pkg.Spec.Rollout
+
+	revision := ""
+	if packageSource.Spec.SourceRef != nil {
+		src, err := getFluxSource(ctx, r.Client, packageSource.Spec.SourceRef)
+		if err != nil {
+			return nil, &releaseBuildError{reason: "RolloutFailed", message: err.Error(), err: err}
+		}
+		if src != nil && src.artifact != nil {
+			revision = src.artifact.Revision
+		}
+	}
+
+	now := metav1.Now()
+	st := pkg.Status.Rollout
+	if revision == "" && st != nil {
+		// The source is being fetched; carry on with what was seen last.
+		revision = st.Revision
+	}
+	if revision == "" {
+		return step, nil
+	}
+
+	switch {
+	case st == nil:
+		installed, err := r.anyReleaseInstalled(ctx, variant, waves)
+		if err != nil {
+			return nil, &releaseBuildError{reason: "RolloutFailed", message: err.Error(), err: err}
+		}
+		if installed {
+			// The policy was added to a Package that is already installed:
+			// what its charts were generated from is the baseline.
+			pkg.Status.Rollout = &cozyv1alpha1.RolloutStatus{
+				Phase:                 cozyv1alpha1.RolloutSucceeded,
+				Revision:              revision,
+				LastKnownGoodRevision: revision,
+				Message:               fmt.Sprintf("revision %s adopted as last known good", revision),
+			}
+			return step, nil
+		}
+		st = &cozyv1alpha1.RolloutStatus{Phase: cozyv1alpha1.RolloutProgressing, Revision: revision, WaveStartedAt: &now}
+		pkg.Status.Rollout = st
+	case st.Phase == cozyv1alpha1.RolloutRolledBack && revision == st.FailedRevision:
+		return step, nil
+	case revision != st.Revision:
+		st.Phase = cozyv1alpha1.RolloutProgressing
+		st.Revision = revision
+		st.FailedRevision = ""
+		st.Wave = 0
+		st.WaveStartedAt = &now
+		st.WaveHealthyAt = nil
+	}
+
+	if st.Phase == cozyv1alpha1.RolloutSucceeded || len(waves) == 0 {
+		return step, nil
+	}
+	if int(st.Wave) >= len(waves) {
+		// The policy lost waves since the rollout started.
+		st.Wave = int32(len(waves) - 1)
+	}
+	step.held = heldComponents(waves, int(st.Wave))
+	waveName := rolloutWaveName(policy, int(st.Wave))
+	if st.Phase == cozyv1alpha1.RolloutFailed {
+		return step, nil
+	}
+
+	healthy, reason, err := r.waveHealthy(ctx, variant, waves[st.Wave])
+	if err != nil {
+		return nil, &releaseBuildError{reason: "RolloutFailed", message: err.Error(), err: err}
+	}
+
+	if healthy {
+		if st.WaveHealthyAt == nil {
+			st.WaveHealthyAt = &now
+		}
+		var pause time.Duration
+		if policy.Pause != nil {
+			pause = policy.Pause.Duration
+		}
+		if remaining := pause - now.Sub(st.WaveHealthyAt.Time); remaining > 0 {
+			st.Message = fmt.Sprintf("wave %s is healthy, next wave in %s", waveName, remaining.Round(time.Second))
+			step.requeueAfter = remaining
+			return step, nil
+		}
+		if int(st.Wave) == len(waves)-1 {
+			st.Phase = cozyv1alpha1.RolloutSucceeded
+			st.LastKnownGoodRevision = st.Revision
+			st.WaveHealthyAt = nil
+			st.Message = fmt.Sprintf("revision %s rolled out", st.Revision)
+			step.held = nil
+			return step, nil
+		}
+		st.Wave++
+		st.WaveStartedAt = &now
+		st.WaveHealthyAt = nil
+		step.held = heldComponents(waves, int(st.Wave))
+		waveName = rolloutWaveName(policy, int(st.Wave))
+		st.Message = fmt.Sprintf("upgrading wave %s", waveName)
+		step.requeueAfter = healthTimeout(policy)
+		return step, nil
+	}
+
+	st.WaveHealthyAt = nil
+	elapsed := now.Sub(st.WaveStartedAt.Time)
+	if remaining := healthTimeout(policy) - elapsed; remaining > 0 {
+		st.Message = fmt.Sprintf("upgrading wave %s: %s", waveName, reason)
+		step.requeueAfter = remaining
+		return step, nil
+	}
+
+	autoRevert := policy.AutoRevert == nil || *policy.AutoRevert
+	if autoRevert && st.LastKnownGoodRevision != "" && st.LastKnownGoodRevision != st.Revision {
+		st.Phase = cozyv1alpha1.RolloutRolledBack
+		st.FailedRevision = st.Revision
+		st.Message = fmt.Sprintf("wave %s not healthy after %s (%s), reverted to %s", waveName, healthTimeout(policy), reason, st.LastKnownGoodRevision)
+		step.held = nil
+		return step, nil
+	}
+	st.Phase = cozyv1alpha1.RolloutFailed
+	st.Message = fmt.Sprintf("wave %s not healthy after %s: %s", waveName, healthTimeout(policy), reason)
+	return step, nil
+}
+
+// rolloutPins returns the revisions the charts of the components in waves
+// are generated from at rollout status st. Until a revision has rolled out
+// everywhere, the components of the waves that have not started stay on
+// the last-known-good one.
+func rolloutPins(st *cozyv1alpha1.RolloutStatus, waves [][]string) *sourcePins {
+	switch st.Phase {
+	case cozyv1alpha1.RolloutSucceeded:
+		return &sourcePins{baseline: st.Revision}
+	case cozyv1alpha1.RolloutRolledBack:
+		return &sourcePins{baseline: st.LastKnownGoodRevision}
+	}
+	pins := &sourcePins{baseline: st.LastKnownGoodRevision, revision: st.Revision}
+	for i := 0; i <= int(st.Wave) && i < len(waves); i++ {
+		pins.components = append(pins.components, waves[i]...)
+	}
+	return pins
+}
+
+// anyReleaseInstalled reports whether a component in waves already has a
+// HelmRelease.
+func (r *PackageReconciler) anyReleaseInstalled(ctx context.Context, variant *cozyv1alpha1.Variant, waves [][]string) (bool, error) {
+	for _, components := range waves {
+		for _, name := range components {
+			component := variantComponent(variant, name)
+			if component == nil || component.Install == nil {
+				continue
+			}
+			key := helmReleaseKey(component)
+			if err := r.Get(ctx, key, &helmv2.HelmRelease{}); err == nil {
+				return true, nil
+			} else if !apierrors.IsNotFound(err) {
+				return false, fmt.Errorf("failed to get HelmRelease %s: %w", key, err)
+			}
+		}
+	}
+	return false, nil
+}
+
+// waveHealthy reports whether the HelmReleases of components are Ready for
+// their current artifact and, when not, why.
+func (r *PackageReconciler) waveHealthy(ctx context.Context, variant *cozyv1alpha1.Variant, components []string) (bool, string, error) {
+	for _, name := range components {
+		component := variantComponent(variant, name)
+		if component == nil || component.Install == nil {
+			continue
+		}
+		key := helmReleaseKey(component)
+		hr := &helmv2.HelmRelease{}
+		if err := r.Get(ctx, key, hr); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, fmt.Sprintf("HelmRelease %s not created yet", key), nil
+			}
+			return false, "", fmt.Errorf("failed to get HelmRelease %s: %w", key, err)
+		}
+		ready := meta.FindStatusCondition(hr.Status.Conditions, "Ready")
+		if hr.Status.ObservedGeneration != hr.Generation || ready == nil || ready.Status != metav1.ConditionTrue {
+			return false, fmt.Sprintf("HelmRelease %s is not ready", key), nil
+		}
+		upgraded, err := r.releaseOnCurrentArtifact(ctx, hr)
+		if err != nil {
+			return false, "", err
+		}
+		if !upgraded {
+			return false, fmt.Sprintf("HelmRelease %s is not upgraded to the new artifact yet", key), nil
+		}
+	}
+	return true, "", nil
+}
+
+// releaseOnCurrentArtifact reports whether hr last reconciled the artifact
+// its chartRef points at now. A release whose artifact or digest is not
+// known is taken at its Ready condition.
+func (r *PackageReconciler) releaseOnCurrentArtifact(ctx context.Context, hr *helmv2.HelmRelease) (bool, error) {
+	ref := hr.Spec.ChartRef
+	if ref == nil || ref.Kind != sourcev1.ExternalArtifactKind || hr.Status.LastAttemptedRevisionDigest == "" {
+		return true, nil
+	}
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = hr.Namespace
+	}
+	artifact := &sourcev1.ExternalArtifact{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, artifact); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to get ExternalArtifact %s/%s: %w", namespace, ref.Name, err)
+	}
+	if artifact.Status.Artifact == nil || artifact.Status.Artifact.Digest == "" {
+		return true, nil
+	}
+	return artifact.Status.Artifact.Digest == hr.Status.LastAttemptedRevisionDigest, nil
+}
+
+// helmReleaseExists reports whether hr has been created.
+func (r *PackageReconciler) helmReleaseExists(ctx context.Context, hr *helmv2.HelmRelease) (bool, error) {
+	if err := r.Get(ctx, client.ObjectKeyFromObject(hr), &helmv2.HelmRelease{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// pinPackageSource sets the annotations of packageSource to pins, removing
+// those pins leaves empty.
+func (r *PackageReconciler) pinPackageSource(ctx context.Context, packageSource *cozyv1alpha1.PackageSource, pins *sourcePins) error {
+	want := map[string]string{
+		AnnotationPinnedRevision:    pins.baseline,
+		AnnotationRolloutRevision:   pins.revision,
+		AnnotationRolloutComponents: strings.Join(pins.components, ","),
+	}
+	if pins.revision == "" || len(pins.components) == 0 {
+		want[AnnotationRolloutRevision] = ""
+		want[AnnotationRolloutComponents] = ""
+	}
+	changed := false
+	for key, value := range want {
+		if packageSource.Annotations[key] != value {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	patch := client.MergeFrom(packageSource.DeepCopy())
+	if packageSource.Annotations == nil {
+		packageSource.Annotations = map[string]string{}
+	}
+	for key, value := range want {
+		if value == "" {
+			delete(packageSource.Annotations, key)
+		} else {
+			packageSource.Annotations[key] = value
+		}
+	}
+	if err := r.Patch(ctx, packageSource, patch); err != nil {
+		return fmt.Errorf("failed to pin PackageSource %s to %q: %w", packageSource.Name, pins.baseline, err)
+	}
+	return nil
+}
+
+// rolloutSourceRequests enqueues the Packages with a rollout policy whose
+// PackageSource reads from the source obj, so a new revision of it starts
+// their rollout.
+func (r *PackageReconciler) rolloutSourceRequests(ctx context.Context, c client.Client, obj client.Object) []reconcile.Request {
+	kind := sourcev1.GitRepositoryKind
+	if _, ok := obj.(*sourcev1.OCIRepository); ok {
+		kind = sourcev1.OCIRepositoryKind
+	}
+	packageList := &cozyv1alpha1.PackageList{}
+	if err := c.List(ctx, packageList); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, pkg := range packageList.Items {
+		if pkg.Spec.Rollout == nil {
+			continue
+		}
+		ps := &cozyv1alpha1.PackageSource{}
+		if err := c.Get(ctx, types.NamespacedName{Name: pkg.Name}, ps); err != nil {
+			continue
+		}
+		ref := ps.Spec.SourceRef
+		if ref == nil || ref.Kind != kind || ref.Name != obj.GetName() || ref.Namespace != obj.GetNamespace() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: pkg.Name}})
+	}
+	return requests
+}
+
+// heldComponents returns the components of the waves after wave.
+func heldComponents(waves [][]string, wave int) map[string]bool {
+	held := map[string]bool{}
+	for _, components := range waves[wave+1:] {
+		for _, name := range components {
+			held[name] = true
+		}
+	}
+	return held
+}
+
+// rolloutWaveName names wave i of policy, including the implicit last wave.
+func rolloutWaveName(policy *cozyv1alpha1.RolloutPolicy, i int) string {
+	if i < len(policy.Waves) {
+		return policy.Waves[i].Name
+	}
+	return "(remaining components)"
+}
+
+func healthTimeout(policy *cozyv1alpha1.RolloutPolicy) time.Duration {
+	if policy.HealthTimeout != nil {
+		return policy.HealthTimeout.Duration
+	}
+	return defaultRolloutHealthTimeout
+}
+
+// variantComponent returns the component of variant called name, or nil.
+func variantComponent(variant *cozyv1alpha1.Variant, name string) *cozyv1alpha1.Component {
+	for i := range variant.Components {
+		if variant.Components[i].Name == name {
+			return &variant.Components[i]
+		}
+	}
+	return nil
+}
+
+// helmReleaseKey is where buildHelmRelease puts the HelmRelease of
+// component.
+func helmReleaseKey(component *cozyv1alpha1.Component) types.NamespacedName {
+	name := component.Install.ReleaseName
+	if name == "" {
+		name = component.Name
+	}
+	return types.NamespacedName{Namespace: component.Install.Namespace, Name: name}
+}
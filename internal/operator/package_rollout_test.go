@@ -0,0 +1,484 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	fluxmeta "github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcewatcherv1beta1 "github.com/fluxcd/source-watcher/api/v2/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const rolloutPackage = "cozystack.monitoring"
+
+func rolloutSource() *cozyv1alpha1.PackageSource {
+	return &cozyv1alpha1.PackageSource{
+		ObjectMeta: metav1.ObjectMeta{Name: rolloutPackage},
+		Spec: cozyv1alpha1.PackageSourceSpec{
+			SourceRef: &cozyv1alpha1.PackageSourceRef{Kind: sourcev1.OCIRepositoryKind, Name: "cozystack-packages", Namespace: "cozy-system"},
+			Variants: []cozyv1alpha1.Variant{{
+				Name:       "default",
+				Components: []cozyv1alpha1.Component{planComponent("operator", "cozy-monitoring"), planComponent("agents", "cozy-monitoring")},
+			}},
+		},
+	}
+}
+
+func rolloutOCIRepository(revision string) *sourcev1.OCIRepository {
+	return &sourcev1.OCIRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack-packages", Namespace: "cozy-system"},
+		Status:     sourcev1.OCIRepositoryStatus{Artifact: &fluxmeta.Artifact{Revision: revision}},
+	}
+}
+
+// rolloutArtifact is the ExternalArtifact of a component of rolloutSource,
+// at digest.
+func rolloutArtifact(component, digest string) *sourcev1.ExternalArtifact {
+	return &sourcev1.ExternalArtifact{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack-monitoring-default-" + component, Namespace: "cozy-system"},
+		Status:     sourcev1.ExternalArtifactStatus{Artifact: &fluxmeta.Artifact{Digest: digest}},
+	}
+}
+
+func rolloutReconciler(t *testing.T, pkg *cozyv1alpha1.Package, objs ...client.Object) *PackageReconciler {
+	t.Helper()
+	r := planReconciler(t)
+	if err := sourcev1.AddToScheme(r.Scheme); err != nil {
+		t.Fatalf("sourcev1.AddToScheme: %v", err)
+	}
+	if err := clientgoscheme.AddToScheme(r.Scheme); err != nil {
+		t.Fatalf("clientgoscheme.AddToScheme: %v", err)
+	}
+	r.Client = fake.NewClientBuilder().WithScheme(r.Scheme).WithObjects(append(objs, pkg)...).
+		WithStatusSubresource(&cozyv1alpha1.Package{}, &helmv2.HelmRelease{}).Build()
+	return r
+}
+
+func reconcilePackage(t *testing.T, r *PackageReconciler) (ctrl.Result, *cozyv1alpha1.Package) {
+	t.Helper()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: rolloutPackage}}
+	res, err := r.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	pkg := &cozyv1alpha1.Package{}
+	if err := r.Get(context.Background(), req.NamespacedName, pkg); err != nil {
+		t.Fatal(err)
+	}
+	return res, pkg
+}
+
+// markReleaseReady reports the HelmRelease of component Ready on digest.
+func markReleaseReady(t *testing.T, r *PackageReconciler, component, digest string) {
+	t.Helper()
+	hr := &helmv2.HelmRelease{}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "cozy-monitoring", Name: component}, hr); err != nil {
+		t.Fatal(err)
+	}
+	hr.Status.ObservedGeneration = hr.Generation
+	hr.Status.LastAttemptedRevisionDigest = digest
+	hr.Status.Conditions = []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Succeeded", LastTransitionTime: metav1.Now()}}
+	if err := r.Status().Update(context.Background(), hr); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func getRelease(t *testing.T, r *PackageReconciler, component string) *helmv2.HelmRelease {
+	t.Helper()
+	hr := &helmv2.HelmRelease{}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "cozy-monitoring", Name: component}, hr); err != nil {
+		t.Fatal(err)
+	}
+	return hr
+}
+
+// sourceAnnotations returns the annotations the rollout left on the
+// PackageSource.
+func sourceAnnotations(t *testing.T, r *PackageReconciler) map[string]string {
+	t.Helper()
+	ps := &cozyv1alpha1.PackageSource{}
+	if err := r.Get(context.Background(), types.NamespacedName{Name: rolloutPackage}, ps); err != nil {
+		t.Fatal(err)
+	}
+	return ps.Annotations
+}
+
+// wantPins checks the revisions the PackageSource is pinned to.
+func wantPins(t *testing.T, r *PackageReconciler, baseline, revision, components string) {
+	t.Helper()
+	annotations := sourceAnnotations(t, r)
+	if annotations[AnnotationPinnedRevision] != baseline || annotations[AnnotationRolloutRevision] != revision || annotations[AnnotationRolloutComponents] != components {
+		t.Errorf("PackageSource annotations = %v, want pinned to %q, %q for %q", annotations, baseline, revision, components)
+	}
+}
+
+// generateArtifacts runs the ArtifactGenerator reconcile of the
+// PackageSource against the state r left, and returns the source alias each
+// component's chart is copied from.
+func generateArtifacts(t *testing.T, r *PackageReconciler) (map[string]string, *sourcewatcherv1beta1.ArtifactGenerator) {
+	t.Helper()
+	ps := &cozyv1alpha1.PackageSource{}
+	if err := r.Get(context.Background(), types.NamespacedName{Name: rolloutPackage}, ps); err != nil {
+		t.Fatal(err)
+	}
+	psr := &PackageSourceReconciler{Client: r.Client, Scheme: r.Scheme}
+	if err := psr.reconcileArtifactGenerators(context.Background(), ps, nil); err != nil {
+		t.Fatalf("reconcileArtifactGenerators: %v", err)
+	}
+	ag := &sourcewatcherv1beta1.ArtifactGenerator{}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "cozy-system", Name: rolloutPackage}, ag); err != nil {
+		t.Fatal(err)
+	}
+	from := map[string]string{}
+	for _, artifact := range ag.Spec.OutputArtifacts {
+		component := strings.TrimPrefix(artifact.Name, "cozystack-monitoring-default-")
+		alias, _, _ := strings.Cut(strings.TrimPrefix(artifact.Copy[0].From, "@"), "/")
+		from[component] = alias
+	}
+	return from, ag
+}
+
+func rolloutPackageWith(policy *cozyv1alpha1.RolloutPolicy, status *cozyv1alpha1.RolloutStatus) *cozyv1alpha1.Package {
+	return &cozyv1alpha1.Package{
+		ObjectMeta: metav1.ObjectMeta{Name: rolloutPackage, UID: "pkg-uid"},
+		Spec:       cozyv1alpha1.PackageSpec{Rollout: policy},
+		Status:     cozyv1alpha1.PackageStatus{Rollout: status},
+	}
+}
+
+func TestRolloutWaves(t *testing.T) {
+	variant := &rolloutSource().Spec.Variants[0]
+	variant.Components = append(variant.Components, cozyv1alpha1.Component{Name: "dashboards"})
+
+	pkg := rolloutPackageWith(&cozyv1alpha1.RolloutPolicy{Waves: []cozyv1alpha1.RolloutWave{{Name: "canary", Components: []string{"operator", "dashboards"}}}}, nil)
+	waves, err := rolloutWaves(pkg, variant)
+	if err != nil {
+		t.Fatalf("rolloutWaves: %v", err)
+	}
+	if len(waves) != 2 || strings.Join(waves[0], ",") != "operator" || strings.Join(waves[1], ",") != "agents" {
+		t.Errorf("waves = %v, want [[operator] [agents]]", waves)
+	}
+
+	pkg.Spec.Rollout.Waves = append(pkg.Spec.Rollout.Waves, cozyv1alpha1.RolloutWave{Name: "rest", Components: []string{"operator"}})
+	if _, err := rolloutWaves(pkg, variant); err == nil || !strings.Contains(err.Error(), "waves canary and rest") {
+		t.Errorf("duplicate component err = %v", err)
+	}
+
+	pkg.Spec.Rollout.Waves = []cozyv1alpha1.RolloutWave{{Name: "canary", Components: []string{"grafana"}}}
+	if _, err := rolloutWaves(pkg, variant); err == nil || !strings.Contains(err.Error(), "component grafana") {
+		t.Errorf("unknown component err = %v", err)
+	}
+}
+
+func TestReconcile_RolloutProgressesThroughWaves(t *testing.T) {
+	policy := &cozyv1alpha1.RolloutPolicy{Waves: []cozyv1alpha1.RolloutWave{{Name: "operator", Components: []string{"operator"}}}}
+	pkg := rolloutPackageWith(policy, &cozyv1alpha1.RolloutStatus{
+		Phase:                 cozyv1alpha1.RolloutSucceeded,
+		Revision:              "v1@sha256:1111",
+		LastKnownGoodRevision: "v1@sha256:1111",
+	})
+	ps := rolloutSource()
+
+	// Both components are installed at the previous revision.
+	seed := planReconciler(t)
+	var releases []client.Object
+	for i := range ps.Spec.Variants[0].Components {
+		hr, buildErr := seed.buildHelmRelease(context.Background(), pkg, ps, &ps.Spec.Variants[0], &ps.Spec.Variants[0].Components[i])
+		if buildErr != nil {
+			t.Fatal(buildErr)
+		}
+		releases = append(releases, hr)
+	}
+	r := rolloutReconciler(t, pkg, append(releases, ps, rolloutOCIRepository("v2@sha256:2222"),
+		rolloutArtifact("operator", "sha256:op2"), rolloutArtifact("agents", "sha256:ag2"))...)
+	markReleaseReady(t, r, "operator", "sha256:op1")
+	markReleaseReady(t, r, "agents", "sha256:ag1")
+
+	res, got := reconcilePackage(t, r)
+	st := got.Status.Rollout
+	if st.Phase != cozyv1alpha1.RolloutProgressing || st.Revision != "v2@sha256:2222" || st.Wave != 0 {
+		t.Fatalf("rollout = %+v, want wave 0 of v2 in progress", st)
+	}
+	if res.RequeueAfter != defaultRolloutHealthTimeout {
+		t.Errorf("RequeueAfter = %v, want the health timeout", res.RequeueAfter)
+	}
+	// The agents keep their v1 chart: only the operator reads v2.
+	wantPins(t, r, "v1@sha256:1111", "v2@sha256:2222", "operator")
+	if agents := getRelease(t, r, "agents"); agents.Spec.Suspend {
+		t.Error("agents release suspended, want it held at the artifact")
+	}
+
+	// The first wave upgrades; the remaining components are let through.
+	markReleaseReady(t, r, "operator", "sha256:op2")
+	_, got = reconcilePackage(t, r)
+	if st := got.Status.Rollout; st.Phase != cozyv1alpha1.RolloutProgressing || st.Wave != 1 {
+		t.Fatalf("rollout = %+v, want wave 1 in progress", st)
+	}
+	wantPins(t, r, "v1@sha256:1111", "v2@sha256:2222", "operator,agents")
+
+	markReleaseReady(t, r, "agents", "sha256:ag2")
+	_, got = reconcilePackage(t, r)
+	if st := got.Status.Rollout; st.Phase != cozyv1alpha1.RolloutSucceeded || st.LastKnownGoodRevision != "v2@sha256:2222" {
+		t.Fatalf("rollout = %+v, want v2 succeeded", st)
+	}
+	wantPins(t, r, "v2@sha256:2222", "", "")
+	if ready := meta.FindStatusCondition(got.Status.Conditions, "Ready"); ready == nil || ready.Status != metav1.ConditionTrue {
+		t.Errorf("Ready = %+v", ready)
+	}
+}
+
+func TestReconcile_RolloutPause(t *testing.T) {
+	policy := &cozyv1alpha1.RolloutPolicy{
+		Waves: []cozyv1alpha1.RolloutWave{{Name: "operator", Components: []string{"operator"}}},
+		Pause: &metav1.Duration{Duration: 10 * time.Minute},
+	}
+	pkg := rolloutPackageWith(policy, nil)
+	ps := rolloutSource()
+	r := rolloutReconciler(t, pkg, ps, rolloutOCIRepository("v1@sha256:1111"))
+
+	// A first install is staged too: later waves are not created yet.
+	res, got := reconcilePackage(t, r)
+	if st := got.Status.Rollout; st == nil || st.Phase != cozyv1alpha1.RolloutProgressing || st.Wave != 0 {
+		t.Fatalf("rollout = %+v, want wave 0 in progress", st)
+	}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "cozy-monitoring", Name: "agents"}, &helmv2.HelmRelease{}); err == nil {
+		t.Error("agents release created before its wave")
+	}
+	wantPins(t, r, "", "v1@sha256:1111", "operator")
+
+	markReleaseReady(t, r, "operator", "")
+	res, got = reconcilePackage(t, r)
+	if st := got.Status.Rollout; st.Wave != 0 || st.WaveHealthyAt == nil {
+		t.Fatalf("rollout = %+v, want wave 0 healthy and paused", st)
+	}
+	if res.RequeueAfter <= 0 || res.RequeueAfter > 10*time.Minute {
+		t.Errorf("RequeueAfter = %v, want the rest of the pause", res.RequeueAfter)
+	}
+}
+
+func TestReconcile_RolloutRevertsFailedWave(t *testing.T) {
+	policy := &cozyv1alpha1.RolloutPolicy{
+		Waves:         []cozyv1alpha1.RolloutWave{{Name: "operator", Components: []string{"operator"}}},
+		HealthTimeout: &metav1.Duration{Duration: 5 * time.Minute},
+	}
+	started := metav1.NewTime(time.Now().Add(-10 * time.Minute))
+	pkg := rolloutPackageWith(policy, &cozyv1alpha1.RolloutStatus{
+		Phase:                 cozyv1alpha1.RolloutProgressing,
+		Revision:              "v2@sha256:2222",
+		LastKnownGoodRevision: "v1@sha256:1111",
+		WaveStartedAt:         &started,
+	})
+	ps := rolloutSource()
+	oci := rolloutOCIRepository("v2@sha256:2222")
+	r := rolloutReconciler(t, pkg, ps, oci)
+
+	_, got := reconcilePackage(t, r)
+	st := got.Status.Rollout
+	if st.Phase != cozyv1alpha1.RolloutRolledBack || st.FailedRevision != "v2@sha256:2222" {
+		t.Fatalf("rollout = %+v, want v2 rolled back", st)
+	}
+	if !strings.Contains(st.Message, "reverted to v1@sha256:1111") {
+		t.Errorf("message = %q", st.Message)
+	}
+	wantPins(t, r, "v1@sha256:1111", "", "")
+	// Everything is reverted together, so nothing is held.
+	if getRelease(t, r, "agents").Spec.Suspend {
+		t.Error("agents release held after the revert")
+	}
+
+	// The Package stays on v1 while the source serves v2.
+	_, got = reconcilePackage(t, r)
+	if got.Status.Rollout.Phase != cozyv1alpha1.RolloutRolledBack {
+		t.Fatalf("rollout = %+v, want still rolled back", got.Status.Rollout)
+	}
+
+	// A new revision is rolled out again, from the last-known-good one.
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(oci), oci); err != nil {
+		t.Fatal(err)
+	}
+	oci.Status.Artifact.Revision = "v3@sha256:3333"
+	if err := r.Update(context.Background(), oci); err != nil {
+		t.Fatal(err)
+	}
+	_, got = reconcilePackage(t, r)
+	if st := got.Status.Rollout; st.Phase != cozyv1alpha1.RolloutProgressing || st.Revision != "v3@sha256:3333" || st.FailedRevision != "" {
+		t.Fatalf("rollout = %+v, want v3 in progress", st)
+	}
+	wantPins(t, r, "v1@sha256:1111", "v3@sha256:3333", "operator")
+}
+
+func TestReconcile_RolloutFailsWithoutRevert(t *testing.T) {
+	autoRevert := false
+	policy := &cozyv1alpha1.RolloutPolicy{
+		Waves:      []cozyv1alpha1.RolloutWave{{Name: "operator", Components: []string{"operator"}}},
+		AutoRevert: &autoRevert,
+	}
+	started := metav1.NewTime(time.Now().Add(-time.Hour))
+	pkg := rolloutPackageWith(policy, &cozyv1alpha1.RolloutStatus{
+		Phase:                 cozyv1alpha1.RolloutProgressing,
+		Revision:              "v2@sha256:2222",
+		LastKnownGoodRevision: "v1@sha256:1111",
+		WaveStartedAt:         &started,
+	})
+	agents := &helmv2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Name: "agents", Namespace: "cozy-monitoring"}}
+	r := rolloutReconciler(t, pkg, rolloutSource(), rolloutOCIRepository("v2@sha256:2222"), agents)
+
+	_, got := reconcilePackage(t, r)
+	if st := got.Status.Rollout; st.Phase != cozyv1alpha1.RolloutFailed || st.FailedRevision != "" {
+		t.Fatalf("rollout = %+v, want failed", st)
+	}
+	// The agents stay on v1 without being reverted to it.
+	wantPins(t, r, "v1@sha256:1111", "v2@sha256:2222", "operator")
+}
+
+func TestReconcile_RolloutGatesTheArtifact(t *testing.T) {
+	policy := &cozyv1alpha1.RolloutPolicy{Waves: []cozyv1alpha1.RolloutWave{{Name: "operator", Components: []string{"operator"}}}}
+	pkg := rolloutPackageWith(policy, &cozyv1alpha1.RolloutStatus{
+		Phase:                 cozyv1alpha1.RolloutSucceeded,
+		Revision:              "v1@sha256:1111",
+		LastKnownGoodRevision: "v1@sha256:1111",
+	})
+	ps := rolloutSource()
+	ps.UID = "ps-uid"
+	ps.Annotations = map[string]string{AnnotationPinnedRevision: "v1@sha256:1111"}
+	for i := range ps.Spec.Variants[0].Components {
+		component := &ps.Spec.Variants[0].Components[i]
+		component.Path = "system/monitoring-" + component.Name
+	}
+	oci := rolloutOCIRepository("v1@sha256:1111")
+	oci.Spec = sourcev1.OCIRepositorySpec{URL: "oci://ghcr.io/cozystack/packages", Reference: &sourcev1.OCIRepositoryRef{Tag: "latest"}}
+	r := rolloutReconciler(t, pkg, ps, oci)
+
+	// The source moves to v2 before the Package is reconciled: the charts
+	// are still generated from the v1 copy.
+	oci.Status.Artifact.Revision = "v2@sha256:2222"
+	if err := r.Update(context.Background(), oci); err != nil {
+		t.Fatal(err)
+	}
+	from, ag := generateArtifacts(t, r)
+	if from["operator"] != "cozystack-packages" || from["agents"] != "cozystack-packages" || len(ag.Spec.Sources) != 1 || ag.Spec.Sources[0].Name != "cozystack.monitoring-pinned" {
+		t.Fatalf("artifacts copied from %v, sources %+v, want everything from the v1 copy", from, ag.Spec.Sources)
+	}
+
+	// The first wave starts: only the operator reads v2.
+	reconcilePackage(t, r)
+	from, ag = generateArtifacts(t, r)
+	if from["operator"] != "cozystack-packages-rollout" || from["agents"] != "cozystack-packages" {
+		t.Errorf("artifacts copied from %v, want the operator from the rollout copy", from)
+	}
+	if len(ag.Spec.Sources) != 2 || ag.Spec.Sources[1].Name != "cozystack.monitoring-rollout" {
+		t.Errorf("sources = %+v, want the pinned and rollout copies", ag.Spec.Sources)
+	}
+	rollout := &sourcev1.OCIRepository{}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "cozy-system", Name: "cozystack.monitoring-rollout"}, rollout); err != nil {
+		t.Fatal(err)
+	}
+	if rollout.Spec.Reference.Digest != "sha256:2222" {
+		t.Errorf("rollout copy reference = %+v, want v2", rollout.Spec.Reference)
+	}
+}
+
+func TestReconcile_RolloutAdoptsInstalledPackage(t *testing.T) {
+	policy := &cozyv1alpha1.RolloutPolicy{Waves: []cozyv1alpha1.RolloutWave{{Name: "operator", Components: []string{"operator"}}}}
+	pkg := rolloutPackageWith(policy, nil)
+	operator := &helmv2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Name: "operator", Namespace: "cozy-monitoring"}}
+	r := rolloutReconciler(t, pkg, rolloutSource(), rolloutOCIRepository("v1@sha256:1111"), operator)
+
+	_, got := reconcilePackage(t, r)
+	if st := got.Status.Rollout; st == nil || st.Phase != cozyv1alpha1.RolloutSucceeded || st.LastKnownGoodRevision != "v1@sha256:1111" {
+		t.Fatalf("rollout = %+v, want v1 adopted", st)
+	}
+	wantPins(t, r, "v1@sha256:1111", "", "")
+	getRelease(t, r, "agents")
+}
+
+func TestPinToRevision(t *testing.T) {
+	git := &sourcev1.GitRepository{Spec: sourcev1.GitRepositorySpec{Reference: &sourcev1.GitRepositoryRef{Branch: "main"}}}
+	if err := pinToRevision(git, "main@sha1:abc123"); err != nil {
+		t.Fatalf("pinToRevision: %v", err)
+	}
+	if ref := git.Spec.Reference; ref.Branch != "main" || ref.Commit != "abc123" {
+		t.Errorf("git reference = %+v", ref)
+	}
+
+	oci := &sourcev1.OCIRepository{Spec: sourcev1.OCIRepositorySpec{Reference: &sourcev1.OCIRepositoryRef{Tag: "latest"}}}
+	if err := pinToRevision(oci, "latest@sha256:def456"); err != nil {
+		t.Fatalf("pinToRevision: %v", err)
+	}
+	if ref := oci.Spec.Reference; ref.Tag != "" || ref.Digest != "sha256:def456" {
+		t.Errorf("oci reference = %+v", ref)
+	}
+
+	if err := pinToRevision(oci, "main@sha1:abc123"); err == nil {
+		t.Error("pinned an OCIRepository to a Git commit")
+	}
+	if err := pinToRevision(git, "v1.0.0"); err == nil {
+		t.Error("pinned to a revision without a checksum")
+	}
+}
+
+func TestReconcilePinnedSource(t *testing.T) {
+	scheme := testScheme(t)
+	if err := sourcev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	ps := rolloutSource()
+	ps.UID = "ps-uid"
+	ps.Annotations = map[string]string{AnnotationPinnedRevision: "v1@sha256:1111"}
+	oci := rolloutOCIRepository("v2@sha256:2222")
+	oci.Spec = sourcev1.OCIRepositorySpec{URL: "oci://ghcr.io/cozystack/packages", Reference: &sourcev1.OCIRepositoryRef{Tag: "latest"}}
+	r := &PackageSourceReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(ps, oci).Build(), Scheme: scheme}
+
+	name, err := r.reconcilePinnedSource(context.Background(), ps)
+	if err != nil {
+		t.Fatalf("reconcilePinnedSource: %v", err)
+	}
+	if name != pinnedSourceName(ps) {
+		t.Errorf("source = %q, want %q", name, pinnedSourceName(ps))
+	}
+	pinned := &sourcev1.OCIRepository{}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "cozy-system", Name: name}, pinned); err != nil {
+		t.Fatal(err)
+	}
+	if pinned.Spec.URL != oci.Spec.URL || pinned.Spec.Reference.Digest != "sha256:1111" {
+		t.Errorf("pinned spec = %+v", pinned.Spec)
+	}
+	if len(pinned.OwnerReferences) != 1 || pinned.OwnerReferences[0].UID != ps.UID {
+		t.Errorf("pinned owner references = %+v", pinned.OwnerReferences)
+	}
+
+	delete(ps.Annotations, AnnotationPinnedRevision)
+	if name, err = r.reconcilePinnedSource(context.Background(), ps); err != nil || name != "cozystack-packages" {
+		t.Fatalf("reconcilePinnedSource = %q, %v", name, err)
+	}
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(pinned), pinned); err == nil {
+		t.Error("pinned source left behind after unpinning")
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// PackageValuesWebhookPath is the HTTP path the Package values webhook is
+// served on.
+const PackageValuesWebhookPath = "/validate-package-values"
+
+// PackageValuesWebhook refuses Packages whose component values do not match
+// the values schemas of their components, so a typo fails the write instead
+// of the install. The Package reconciler still checks the values, against
+// the schemas of the revision it installs, and reports a mismatch on the
+// Package; that is also what catches values a later schema no longer
+// accepts.
+//
+// The cozystack-operator cannot serve it: it runs on the host network and
+// must not bind a port. cozystack-controller serves it instead, with
+// failurePolicy: Ignore, since cozystack-controller is itself installed from
+// a Package.
+type PackageValuesWebhook struct {
+	// Reader reads PackageSources and the ConfigMaps holding the values
+	// schemas of their charts. An uncached reader keeps those out of memory.
+	Reader client.Reader
+}
+
+// SetupWithManager registers the admission handler on the webhook server.
+func (h *PackageValuesWebhook) SetupWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(PackageValuesWebhookPath, &admission.Webhook{Handler: h})
+	return nil
+}
+
+// Handle validates the component values of a created or updated Package.
+// An update that changes neither the variant nor the components is let
+// through, so values a newer schema refuses do not pin the Package's
+// metadata.
+func (h *PackageValuesWebhook) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("only CREATE and UPDATE are validated")
+	}
+	pkg := &cozyv1alpha1.Package{}
+	if err := json.Unmarshal(req.Object.Raw, pkg); err != nil {
+		return admission.Errored(http.StatusBadRequest, fmt.Errorf("decoding Package: %w", err))
+	}
+	if req.Operation == admissionv1.Update {
+		old := &cozyv1alpha1.Package{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, fmt.Errorf("decoding old Package: %w", err))
+		}
+		if old.Spec.Variant == pkg.Spec.Variant && equality.Semantic.DeepEqual(old.Spec.Components, pkg.Spec.Components) {
+			return admission.Allowed("component values unchanged")
+		}
+	}
+
+	packageSource := &cozyv1alpha1.PackageSource{}
+	if err := h.Reader.Get(ctx, types.NamespacedName{Name: pkg.Name}, packageSource); err != nil {
+		if apierrors.IsNotFound(err) {
+			// The reconciler reports the missing PackageSource.
+			return admission.Allowed("no PackageSource to validate against")
+		}
+		return admission.Errored(http.StatusInternalServerError, fmt.Errorf("failed to get PackageSource %s: %w", pkg.Name, err))
+	}
+	variant := packageVariant(pkg, packageSource)
+	if variant == nil {
+		return admission.Allowed(fmt.Sprintf("variant %s not found in PackageSource %s", packageVariantName(pkg), pkg.Name))
+	}
+	if errs := ValidatePackageValues(ctx, h.Reader, pkg, packageSource, variant); len(errs) > 0 {
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+	return admission.Allowed("")
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func packageRequest(t *testing.T, op admissionv1.Operation, pkg, old *cozyv1alpha1.Package) admission.Request {
+	t.Helper()
+	raw := func(pkg *cozyv1alpha1.Package) runtime.RawExtension {
+		if pkg == nil {
+			return runtime.RawExtension{}
+		}
+		b, err := json.Marshal(pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runtime.RawExtension{Raw: b}
+	}
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: op,
+		Object:    raw(pkg),
+		OldObject: raw(old),
+	}}
+}
+
+func TestPackageValuesWebhook(t *testing.T) {
+	h := &PackageValuesWebhook{Reader: fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(schemaSource(), multusSchemaConfigMap()).Build()}
+	ctx := context.Background()
+
+	valid := valuesPackage("cilium", `{"mtu":1400}`)
+	misspelt := valuesPackage("cilium", `{"mut":1400}`)
+	chartMisspelt := valuesPackage("multus", `{"cniVerison":"1.0.0"}`)
+
+	if resp := h.Handle(ctx, packageRequest(t, admissionv1.Create, valid, nil)); !resp.Allowed {
+		t.Errorf("valid Package refused: %v", resp.Result)
+	}
+	resp := h.Handle(ctx, packageRequest(t, admissionv1.Create, misspelt, nil))
+	if resp.Allowed || !strings.Contains(resp.Result.Message, "spec.components[cilium].values.mut") {
+		t.Errorf("misspelt key admitted: %+v", resp.Result)
+	}
+	if resp := h.Handle(ctx, packageRequest(t, admissionv1.Update, chartMisspelt, valid)); resp.Allowed {
+		t.Error("a key the chart's values.schema.json does not list was admitted")
+	}
+
+	// Values that no longer match do not keep the Package's metadata from
+	// being written.
+	labelled := misspelt.DeepCopy()
+	labelled.Labels = map[string]string{"team": "net"}
+	if resp := h.Handle(ctx, packageRequest(t, admissionv1.Update, labelled, misspelt)); !resp.Allowed {
+		t.Errorf("update leaving the values alone refused: %v", resp.Result)
+	}
+
+	orphan := valuesPackage("cilium", `{"mut":1400}`)
+	orphan.Name = "cozystack.unknown"
+	if resp := h.Handle(ctx, packageRequest(t, admissionv1.Create, orphan, nil)); !resp.Allowed {
+		t.Errorf("Package without a PackageSource refused: %v", resp.Result)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	reasonBundleSourceMismatch = "BundleSourceMismatch"
+	reasonBundleSourcePending  = "BundleSourcePending"
+	reasonBundleDigestMismatch = "BundleDigestMismatch"
+)
+
+// +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=ocirepositories,verbs=get;list;watch
+
+// verifyBundleSource checks that the source of a PackageSource imported from
+// a bundle serves the artifact the bundle's checksum manifest lists under
+// digest: the OCIRepository must be pinned to it and must have fetched it.
+// Flux verifies the layer digests against the manifest on pull, so a
+// matching manifest digest vouches for every file in the artifact. When the
+// check fails or cannot be made yet, the Ready condition says why and false
+// is returned; the ArtifactGenerator must then be left alone.
+func (r *PackageSourceReconciler) verifyBundleSource(ctx context.Context, packageSource *cozyv1alpha1.PackageSource, digest string) (bool, error) {
+	ref := packageSource.Spec.SourceRef
+	if ref == nil || ref.Kind != sourcev1.OCIRepositoryKind {
+		return false, r.setBundleCondition(ctx, packageSource, metav1.ConditionFalse, reasonBundleSourceMismatch,
+			fmt.Sprintf("%s is set but sourceRef is not an OCIRepository", AnnotationBundleDigest))
+	}
+
+	repo := &sourcev1.OCIRepository{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, repo); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, r.setBundleCondition(ctx, packageSource, metav1.ConditionUnknown, reasonBundleSourcePending,
+				fmt.Sprintf("OCIRepository %s/%s not found", ref.Namespace, ref.Name))
+		}
+		return false, fmt.Errorf("failed to get OCIRepository: %w", err)
+	}
+	if repo.Spec.Reference == nil || repo.Spec.Reference.Digest != digest {
+		return false, r.setBundleCondition(ctx, packageSource, metav1.ConditionFalse, reasonBundleDigestMismatch,
+			fmt.Sprintf("OCIRepository %s/%s is not pinned to bundle digest %s", ref.Namespace, ref.Name, digest))
+	}
+	if repo.Status.Artifact == nil {
+		return false, r.setBundleCondition(ctx, packageSource, metav1.ConditionUnknown, reasonBundleSourcePending,
+			fmt.Sprintf("OCIRepository %s/%s has not fetched an artifact yet", ref.Namespace, ref.Name))
+	}
+	// The revision is "<tag>@<digest>", or the bare digest for a digest
+	// reference.
+	if revision := repo.Status.Artifact.Revision; !strings.HasSuffix(revision, digest) {
+		return false, r.setBundleCondition(ctx, packageSource, metav1.ConditionFalse, reasonBundleDigestMismatch,
+			fmt.Sprintf("OCIRepository %s/%s serves revision %s, want bundle digest %s", ref.Namespace, ref.Name, revision, digest))
+	}
+	return true, nil
+}
+
+func (r *PackageSourceReconciler) setBundleCondition(ctx context.Context, packageSource *cozyv1alpha1.PackageSource, status metav1.ConditionStatus, reason, message string) error {
+	meta.SetStatusCondition(&packageSource.Status.Conditions, metav1.Condition{
+		Type:               "Ready",
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: packageSource.Generation,
+	})
+	return r.Status().Update(ctx, packageSource)
+}
+
+// bundleSourceRequests maps an OCIRepository to the bundle-pinned
+// PackageSources built from it, so they are verified again when it fetches
+// a new artifact.
+func (r *PackageSourceReconciler) bundleSourceRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &cozyv1alpha1.PackageSourceList{}
+	if err := r.List(ctx, list); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, ps := range list.Items {
+		ref := ps.Spec.SourceRef
+		if ps.Annotations[AnnotationBundleDigest] == "" || ref == nil || ref.Kind != sourcev1.OCIRepositoryKind {
+			continue
+		}
+		if ref.Name == obj.GetName() && ref.Namespace == obj.GetNamespace() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: ps.Name}})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"testing"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	fluxmeta "github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcewatcherv1beta1 "github.com/fluxcd/source-watcher/api/v2/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const bundleDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+func bundleSource(kind string) *cozyv1alpha1.PackageSource {
+	return &cozyv1alpha1.PackageSource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "cozystack.app",
+			Annotations: map[string]string{AnnotationBundleDigest: bundleDigest},
+		},
+		Spec: cozyv1alpha1.PackageSourceSpec{
+			SourceRef: &cozyv1alpha1.PackageSourceRef{Kind: kind, Name: "cozystack.app", Namespace: "cozy-system"},
+			Variants: []cozyv1alpha1.Variant{{
+				Name:       "default",
+				Components: []cozyv1alpha1.Component{{Name: "app", Path: "system/app"}},
+			}},
+		},
+	}
+}
+
+func bundleRepository(digest, revision string) *sourcev1.OCIRepository {
+	repo := &sourcev1.OCIRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack.app", Namespace: "cozy-system"},
+		Spec:       sourcev1.OCIRepositorySpec{URL: "oci://registry.local/cozystack/packagesources/cozystack.app"},
+	}
+	if digest != "" {
+		repo.Spec.Reference = &sourcev1.OCIRepositoryRef{Digest: digest}
+	}
+	if revision != "" {
+		repo.Status.Artifact = &fluxmeta.Artifact{Revision: revision}
+	}
+	return repo
+}
+
+func bundleReconciler(t *testing.T, objs ...client.Object) *PackageSourceReconciler {
+	t.Helper()
+	scheme := testScheme(t)
+	if err := sourcev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("sourcev1.AddToScheme: %v", err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithStatusSubresource(&cozyv1alpha1.PackageSource{}).Build()
+	return &PackageSourceReconciler{Client: c, Scheme: scheme}
+}
+
+func TestVerifyBundleSource(t *testing.T) {
+	other := "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	cases := []struct {
+		name       string
+		kind       string
+		repo       *sourcev1.OCIRepository
+		wantOK     bool
+		wantStatus metav1.ConditionStatus
+		wantReason string
+	}{
+		{"git source", "GitRepository", nil, false, metav1.ConditionFalse, reasonBundleSourceMismatch},
+		{"repository missing", "OCIRepository", nil, false, metav1.ConditionUnknown, reasonBundleSourcePending},
+		{"not pinned", "OCIRepository", bundleRepository("", ""), false, metav1.ConditionFalse, reasonBundleDigestMismatch},
+		{"pinned elsewhere", "OCIRepository", bundleRepository(other, ""), false, metav1.ConditionFalse, reasonBundleDigestMismatch},
+		{"not fetched", "OCIRepository", bundleRepository(bundleDigest, ""), false, metav1.ConditionUnknown, reasonBundleSourcePending},
+		{"serves another artifact", "OCIRepository", bundleRepository(bundleDigest, "latest@"+other), false, metav1.ConditionFalse, reasonBundleDigestMismatch},
+		{"serves the bundle", "OCIRepository", bundleRepository(bundleDigest, bundleDigest), true, "", ""},
+		{"serves the bundle by tag", "OCIRepository", bundleRepository(bundleDigest, "111111111111@"+bundleDigest), true, "", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ps := bundleSource(tc.kind)
+			objs := []client.Object{ps}
+			if tc.repo != nil {
+				objs = append(objs, tc.repo)
+			}
+			r := bundleReconciler(t, objs...)
+
+			ok, err := r.verifyBundleSource(context.Background(), ps, bundleDigest)
+			if err != nil {
+				t.Fatalf("verifyBundleSource: %v", err)
+			}
+			if ok != tc.wantOK {
+				t.Fatalf("verified = %v, want %v", ok, tc.wantOK)
+			}
+			got := &cozyv1alpha1.PackageSource{}
+			if err := r.Get(context.Background(), types.NamespacedName{Name: ps.Name}, got); err != nil {
+				t.Fatal(err)
+			}
+			ready := meta.FindStatusCondition(got.Status.Conditions, "Ready")
+			if tc.wantOK {
+				if ready != nil {
+					t.Errorf("verified source wrote Ready=%s/%s", ready.Status, ready.Reason)
+				}
+				return
+			}
+			if ready == nil || ready.Status != tc.wantStatus || ready.Reason != tc.wantReason {
+				t.Errorf("Ready = %+v, want %s/%s", ready, tc.wantStatus, tc.wantReason)
+			}
+		})
+	}
+}
+
+func TestReconcile_BundleDigestMismatchSkipsArtifactGenerator(t *testing.T) {
+	other := "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	r := bundleReconciler(t, bundleSource("OCIRepository"), bundleRepository(bundleDigest, "latest@"+other))
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "cozystack.app"}}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: "cozy-system", Name: "cozystack.app"}, &sourcewatcherv1beta1.ArtifactGenerator{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("ArtifactGenerator get err = %v, want NotFound", err)
+	}
+
+	requests := r.bundleSourceRequests(context.Background(), bundleRepository(bundleDigest, ""))
+	if len(requests) != 1 || requests[0].Name != "cozystack.app" {
+		t.Errorf("OCIRepository change enqueued %v, want cozystack.app", requests)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// AnnotationPinnedRevision on a PackageSource pins its charts to a revision
+// of its source: the ArtifactGenerator reads from a copy of the source
+// checked out at that revision instead of from the source itself. The
+// Package reconciler of a Package with a rollout policy keeps it at the
+// last-known-good revision, so a new revision of the source reaches no
+// chart before its wave starts. Without a rollout policy it pins the
+// revision the Package applied while an upgrade is refused, for breaking
+// Packages that depend on it.
+const AnnotationPinnedRevision = "operator.cozystack.io/pinned-revision"
+
+// AnnotationRolloutRevision and AnnotationRolloutComponents on a
+// PackageSource let the components listed, comma separated, read a second
+// copy of the source checked out at the revision being rolled out, while
+// the others stay on AnnotationPinnedRevision. The Package reconciler adds
+// the components of each wave as it starts.
+const (
+	AnnotationRolloutRevision   = "operator.cozystack.io/rollout-revision"
+	AnnotationRolloutComponents = "operator.cozystack.io/rollout-components"
+)
+
+// +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=gitrepositories;ocirepositories,verbs=create;update;patch;delete
+
+// pinnedSourceName is the name of the copy of the source of packageSource
+// pinned to AnnotationPinnedRevision.
+func pinnedSourceName(packageSource *cozyv1alpha1.PackageSource) string {
+	return packageSource.Name + "-pinned"
+}
+
+// rolloutSourceName is the name of the copy of the source of packageSource
+// pinned to AnnotationRolloutRevision.
+func rolloutSourceName(packageSource *cozyv1alpha1.PackageSource) string {
+	return packageSource.Name + "-rollout"
+}
+
+// rolloutComponents returns the components of packageSource that read the
+// revision being rolled out, or nil when no rollout is under way.
+func rolloutComponents(packageSource *cozyv1alpha1.PackageSource) map[string]bool {
+	if packageSource.Annotations[AnnotationRolloutRevision] == "" {
+		return nil
+	}
+	components := map[string]bool{}
+	for _, name := range strings.Split(packageSource.Annotations[AnnotationRolloutComponents], ",") {
+		if name != "" {
+			components[name] = true
+		}
+	}
+	if len(components) == 0 {
+		return nil
+	}
+	return components
+}
+
+// reconcilePinnedSource makes the pinned copy of the source of
+// packageSource match AnnotationPinnedRevision, deleting it when the
+// annotation is unset, and returns the name of the source the
+// ArtifactGenerator must read from.
+func (r *PackageSourceReconciler) reconcilePinnedSource(ctx context.Context, packageSource *cozyv1alpha1.PackageSource) (string, error) {
+	return r.reconcileSourceCopy(ctx, packageSource, pinnedSourceName(packageSource), packageSource.Annotations[AnnotationPinnedRevision])
+}
+
+// reconcileRolloutSource does the same for the copy at
+// AnnotationRolloutRevision, which only exists while some component reads
+// it.
+func (r *PackageSourceReconciler) reconcileRolloutSource(ctx context.Context, packageSource *cozyv1alpha1.PackageSource) (string, error) {
+	revision := ""
+	if rolloutComponents(packageSource) != nil {
+		revision = packageSource.Annotations[AnnotationRolloutRevision]
+	}
+	return r.reconcileSourceCopy(ctx, packageSource, rolloutSourceName(packageSource), revision)
+}
+
+// reconcileSourceCopy makes the copy name of the source of packageSource
+// check out revision, or deletes it when revision is empty, and returns
+// the name of the source to read: the copy, or the source itself.
+func (r *PackageSourceReconciler) reconcileSourceCopy(ctx context.Context, packageSource *cozyv1alpha1.PackageSource, name, revision string) (string, error) {
+	ref := packageSource.Spec.SourceRef
+	key := types.NamespacedName{Namespace: ref.Namespace, Name: name}
+
+	var pinned client.Object
+	switch ref.Kind {
+	case sourcev1.GitRepositoryKind:
+		pinned = &sourcev1.GitRepository{}
+	case sourcev1.OCIRepositoryKind:
+		pinned = &sourcev1.OCIRepository{}
+	default:
+		if revision != "" {
+			return "", fmt.Errorf("cannot pin a %s to a revision", ref.Kind)
+		}
+		return ref.Name, nil
+	}
+
+	if revision == "" {
+		pinned.SetNamespace(key.Namespace)
+		pinned.SetName(key.Name)
+		if err := r.Delete(ctx, pinned); err != nil && !apierrors.IsNotFound(err) {
+			return "", fmt.Errorf("failed to delete pinned source %s: %w", key, err)
+		}
+		return ref.Name, nil
+	}
+
+	if err := r.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, pinned); err != nil {
+		return "", fmt.Errorf("failed to get %s %s/%s: %w", ref.Kind, ref.Namespace, ref.Name, err)
+	}
+	if err := pinToRevision(pinned, revision); err != nil {
+		return "", err
+	}
+
+	gvk, err := apiutil.GVKForObject(packageSource, r.Scheme)
+	if err != nil {
+		return "", fmt.Errorf("failed to get GVK for PackageSource: %w", err)
+	}
+	controller := true
+	pinned.SetName(key.Name)
+	pinned.SetLabels(map[string]string{"cozystack.io/packagesource": packageSource.Name})
+	pinned.SetAnnotations(map[string]string{AnnotationPinnedRevision: revision})
+	pinned.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Name:       packageSource.Name,
+		UID:        packageSource.UID,
+		Controller: &controller,
+	}})
+	pinned.SetResourceVersion("")
+	pinned.SetUID("")
+	pinned.SetGeneration(0)
+	pinned.SetCreationTimestamp(metav1.Time{})
+	pinned.SetManagedFields(nil)
+	pinned.SetFinalizers(nil)
+
+	if err := r.createOrUpdate(ctx, pinned); err != nil {
+		return "", fmt.Errorf("failed to reconcile pinned source %s: %w", key, err)
+	}
+	return key.Name, nil
+}
+
+// pinToRevision points the spec of a copied source at revision, as the
+// source controller reports it in its artifact: "<ref>@sha1:<commit>" for
+// Git, "<tag>@sha256:<digest>" or the bare digest for OCI. The status of the
+// copy is dropped.
+func pinToRevision(obj client.Object, revision string) error {
+	checksum := revision
+	if i := strings.LastIndex(revision, "@"); i >= 0 {
+		checksum = revision[i+1:]
+	}
+	algorithm, hex, ok := strings.Cut(checksum, ":")
+	if !ok || hex == "" {
+		return fmt.Errorf("revision %q has no checksum to pin to", revision)
+	}
+
+	switch src := obj.(type) {
+	case *sourcev1.GitRepository:
+		if algorithm != "sha1" && algorithm != "sha256" {
+			return fmt.Errorf("revision %q is not a Git commit", revision)
+		}
+		// The branch, if any, is kept so the commit is fetched from it.
+		branch := ""
+		if src.Spec.Reference != nil {
+			branch = src.Spec.Reference.Branch
+		}
+		src.Spec.Reference = &sourcev1.GitRepositoryRef{Branch: branch, Commit: hex}
+		src.Status = sourcev1.GitRepositoryStatus{}
+	case *sourcev1.OCIRepository:
+		if algorithm != "sha256" {
+			return fmt.Errorf("revision %q is not an OCI digest", revision)
+		}
+		src.Spec.Reference = &sourcev1.OCIRepositoryRef{Digest: checksum}
+		src.Status = sourcev1.OCIRepositoryStatus{}
+	}
+	return nil
+}
@@ -24,7 +24,9 @@ import (
 	"time"
 
 	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 	sourcewatcherv1beta1 "github.com/fluxcd/source-watcher/api/v2/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -33,6 +35,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -96,6 +99,16 @@ var nowFunc = time.Now
 type PackageSourceReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// APIReader reads objects the manager does not cache; the Client is
+	// used when it is nil.
+	APIReader client.Reader
+}
+
+func (r *PackageSourceReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
 }
 
 // +kubebuilder:rbac:groups=cozystack.io,resources=packagesources,verbs=get;list;watch;create;update;patch;delete
@@ -125,11 +138,54 @@ func (r *PackageSourceReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, nil
 	}
 
-	// Generate ArtifactGenerator for package source
-	if err := r.reconcileArtifactGenerators(ctx, packageSource); err != nil {
-		logger.Error(err, "failed to reconcile ArtifactGenerator")
+	// A PackageSource imported from a bundle is only built once its source
+	// is shown to serve the bundle's artifact.
+	if digest := packageSource.Annotations[AnnotationBundleDigest]; digest != "" {
+		verified, err := r.verifyBundleSource(ctx, packageSource, digest)
+		if err != nil || !verified {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Variants whose artifact fails its verification policy are left out of
+	// the ArtifactGenerator. While some variant cannot be verified yet the
+	// ArtifactGenerator reads the last verified revision, or is kept as it
+	// is before any was verified, so neither an unverified revision nor an
+	// outage of the source controller publishes or withdraws anything.
+	blocked, pending, err := r.verifyVariants(ctx, packageSource)
+	if err != nil {
 		return ctrl.Result{}, err
 	}
+	// Values schemas are only read from a revision that is published.
+	if !pending {
+		if err := r.loadValuesSchemas(ctx, packageSource); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if pending {
+		if held := heldAtVerifiedRevision(packageSource); held != nil {
+			if err := r.reconcileArtifactGenerators(ctx, held, blocked); err != nil {
+				logger.Error(err, "failed to reconcile ArtifactGenerator")
+				return ctrl.Result{}, err
+			}
+		}
+	} else {
+		// Generate ArtifactGenerator for package source
+		if err := r.reconcileArtifactGenerators(ctx, packageSource, blocked); err != nil {
+			logger.Error(err, "failed to reconcile ArtifactGenerator")
+			return ctrl.Result{}, err
+		}
+		if len(blocked) > 0 && len(blocked) == len(packageSource.Spec.Variants) {
+			meta.SetStatusCondition(&packageSource.Status.Conditions, metav1.Condition{
+				Type:               "Ready",
+				Status:             metav1.ConditionFalse,
+				Reason:             reasonVariantsBlocked,
+				Message:            "every variant failed artifact verification",
+				ObservedGeneration: packageSource.Generation,
+			})
+			return ctrl.Result{}, r.Status().Update(ctx, packageSource)
+		}
+	}
 
 	// Update PackageSource status (variants and conditions from ArtifactGenerator).
 	// The status update may schedule a follow-up reconcile via RequeueAfter when
@@ -145,7 +201,8 @@ func (r *PackageSourceReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 // reconcileArtifactGenerators generates a single ArtifactGenerator for the package source
 // Creates one ArtifactGenerator per package source with all OutputArtifacts from components
-func (r *PackageSourceReconciler) reconcileArtifactGenerators(ctx context.Context, packageSource *cozyv1alpha1.PackageSource) error {
+// of the variants not in blocked
+func (r *PackageSourceReconciler) reconcileArtifactGenerators(ctx context.Context, packageSource *cozyv1alpha1.PackageSource, blocked map[string]bool) error {
 	logger := log.FromContext(ctx)
 
 	// Check if SourceRef is set
@@ -162,8 +219,20 @@ func (r *PackageSourceReconciler) reconcileArtifactGenerators(ctx context.Contex
 	// Collect all OutputArtifacts
 	outputArtifacts := []sourcewatcherv1beta1.OutputArtifact{}
 
+	// Components whose rollout wave has started read the revision being
+	// rolled out through a second source alias; the rest stay pinned.
+	sourceAlias := packageSource.Spec.SourceRef.Name
+	rolloutAlias := sourceAlias + "-rollout"
+	rollout := rolloutComponents(packageSource)
+	readsRollout := false
+
 	// Process all variants and their components
 	for _, variant := range packageSource.Spec.Variants {
+		if blocked[variant.Name] {
+			logger.Info("skipping variant that failed artifact verification", "packageSource", packageSource.Name, "variant", variant.Name)
+			continue
+		}
+
 		// Build library map for this variant
 		// Map key is the library name (from lib.Name or extracted from path)
 		// This allows components in this variant to reference libraries by name
@@ -200,10 +269,16 @@ func (r *PackageSourceReconciler) reconcileArtifactGenerators(ctx context.Contex
 			// Get basePath with default values
 			basePath := r.getBasePath(packageSource)
 
+			alias := sourceAlias
+			if rollout[component.Name] {
+				alias = rolloutAlias
+				readsRollout = true
+			}
+
 			// Build copy operations
 			copyOps := []sourcewatcherv1beta1.CopyOperation{
 				{
-					From: r.buildSourcePath(packageSource.Spec.SourceRef.Name, basePath, component.Path),
+					From: r.buildSourcePath(alias, basePath, component.Path),
 					To:   fmt.Sprintf("@artifact/%s/", componentPathName),
 				},
 			}
@@ -212,7 +287,7 @@ func (r *PackageSourceReconciler) reconcileArtifactGenerators(ctx context.Contex
 			for _, libName := range component.Libraries {
 				if lib, ok := libraryMap[libName]; ok {
 					copyOps = append(copyOps, sourcewatcherv1beta1.CopyOperation{
-						From: r.buildSourcePath(packageSource.Spec.SourceRef.Name, basePath, lib.Path),
+						From: r.buildSourcePath(alias, basePath, lib.Path),
 						To:   fmt.Sprintf("@artifact/%s/charts/%s/", componentPathName, libName),
 					})
 				}
@@ -225,7 +300,7 @@ func (r *PackageSourceReconciler) reconcileArtifactGenerators(ctx context.Contex
 					strategy = "Overwrite"
 				}
 				copyOps = append(copyOps, sourcewatcherv1beta1.CopyOperation{
-					From:     r.buildSourceFilePath(packageSource.Spec.SourceRef.Name, basePath, fmt.Sprintf("%s/%s", component.Path, valuesFile)),
+					From:     r.buildSourceFilePath(alias, basePath, fmt.Sprintf("%s/%s", component.Path, valuesFile)),
 					To:       fmt.Sprintf("@artifact/%s/values.yaml", componentPathName),
 					Strategy: strategy,
 				})
@@ -249,10 +324,47 @@ func (r *PackageSourceReconciler) reconcileArtifactGenerators(ctx context.Contex
 
 	// If there are no OutputArtifacts, return (ownerReference will handle cleanup if needed)
 	if len(outputArtifacts) == 0 {
+		if len(blocked) > 0 {
+			// Withdraw what was generated before verification failed.
+			ag := &sourcewatcherv1beta1.ArtifactGenerator{ObjectMeta: metav1.ObjectMeta{Name: agName, Namespace: namespace}}
+			if err := r.Delete(ctx, ag); err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete ArtifactGenerator %s: %w", agName, err)
+			}
+		}
 		logger.Info("no OutputArtifacts to generate, skipping ArtifactGenerator creation", "packageSource", packageSource.Name)
 		return nil
 	}
 
+	// Read from a pinned copy of the source while a revision is pinned. The
+	// alias stays the source's name so the copy operations are unchanged.
+	sourceName, err := r.reconcilePinnedSource(ctx, packageSource)
+	if err != nil {
+		return err
+	}
+	sources := []sourcewatcherv1beta1.SourceReference{{
+		Alias:     sourceAlias,
+		Kind:      packageSource.Spec.SourceRef.Kind,
+		Name:      sourceName,
+		Namespace: packageSource.Spec.SourceRef.Namespace,
+	}}
+	if !readsRollout {
+		// The components the annotation names are gone from the variants.
+		packageSource = packageSource.DeepCopy()
+		delete(packageSource.Annotations, AnnotationRolloutRevision)
+	}
+	rolloutName, err := r.reconcileRolloutSource(ctx, packageSource)
+	if err != nil {
+		return err
+	}
+	if readsRollout {
+		sources = append(sources, sourcewatcherv1beta1.SourceReference{
+			Alias:     rolloutAlias,
+			Kind:      packageSource.Spec.SourceRef.Kind,
+			Name:      rolloutName,
+			Namespace: packageSource.Spec.SourceRef.Namespace,
+		})
+	}
+
 	// Build labels
 	labels := make(map[string]string)
 	labels["cozystack.io/packagesource"] = packageSource.Name
@@ -265,14 +377,7 @@ func (r *PackageSourceReconciler) reconcileArtifactGenerators(ctx context.Contex
 			Labels:    labels,
 		},
 		Spec: sourcewatcherv1beta1.ArtifactGeneratorSpec{
-			Sources: []sourcewatcherv1beta1.SourceReference{
-				{
-					Alias:     packageSource.Spec.SourceRef.Name,
-					Kind:      packageSource.Spec.SourceRef.Kind,
-					Name:      packageSource.Spec.SourceRef.Name,
-					Namespace: packageSource.Spec.SourceRef.Namespace,
-				},
-			},
+			Sources:         sources,
 			OutputArtifacts: outputArtifacts,
 		},
 	}
@@ -314,9 +419,15 @@ func (r *PackageSourceReconciler) getPackageNameFromPath(path string) string {
 
 // getBasePath returns the basePath with default values based on source kind
 func (r *PackageSourceReconciler) getBasePath(packageSource *cozyv1alpha1.PackageSource) string {
+	return SourceBasePath(packageSource.Spec.SourceRef)
+}
+
+// SourceBasePath returns the directory of the source that component and
+// library paths are relative to.
+func SourceBasePath(sourceRef *cozyv1alpha1.PackageSourceRef) string {
 	// If path is explicitly set in SourceRef, use it (but normalize "/" to empty)
-	if packageSource.Spec.SourceRef.Path != "" {
-		path := strings.Trim(packageSource.Spec.SourceRef.Path, "/")
+	if sourceRef.Path != "" {
+		path := strings.Trim(sourceRef.Path, "/")
 		// If path is "/" or empty after trim, return empty string
 		if path == "" {
 			return ""
@@ -324,7 +435,7 @@ func (r *PackageSourceReconciler) getBasePath(packageSource *cozyv1alpha1.Packag
 		return path
 	}
 	// Default values based on kind
-	if packageSource.Spec.SourceRef.Kind == "OCIRepository" {
+	if sourceRef.Kind == "OCIRepository" {
 		return "" // Root for OCI
 	}
 	// Default for GitRepository
@@ -1017,6 +1128,9 @@ func (r *PackageSourceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Named("cozystack-packagesource").
 		For(&cozyv1alpha1.PackageSource{}).
 		Owns(&sourcewatcherv1beta1.ArtifactGenerator{}).
+		Owns(&corev1.ConfigMap{}).
+		Watches(&sourcev1.OCIRepository{}, handler.EnqueueRequestsFromMapFunc(r.sourceRequests)).
+		Watches(&sourcev1.GitRepository{}, handler.EnqueueRequestsFromMapFunc(r.sourceRequests)).
 		Complete(r)
 }
 
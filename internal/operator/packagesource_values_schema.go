@@ -0,0 +1,373 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	fluxmeta "github.com/fluxcd/pkg/apis/meta"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// ConditionValuesSchemas reports whether the values schemas of the
+	// component charts could be read from the source artifact.
+	ConditionValuesSchemas = "ValuesSchemasLoaded"
+
+	reasonValuesSchemasLoaded  = "Loaded"
+	reasonValuesSchemasFailed  = "FetchFailed"
+	reasonValuesSchemasInvalid = "InvalidSchema"
+
+	chartValuesSchemaFile = "values.schema.json"
+
+	// maxValuesSchemaSize bounds a single values.schema.json, which ends up
+	// in a ConfigMap.
+	maxValuesSchemaSize = 256 << 10
+
+	// valuesSchemaNamespace holds the ConfigMaps the values schemas are
+	// kept in, one per chart, owned by their PackageSource.
+	valuesSchemaNamespace = "cozy-system"
+	// LabelValuesSchema marks those ConfigMaps, so the operator caches
+	// them and no other ConfigMap.
+	LabelValuesSchema = "cozystack.io/values-schema"
+)
+
+// artifactHTTPClient fetches source artifacts from the source controller.
+var artifactHTTPClient = &http.Client{Timeout: time.Minute}
+
+// +kubebuilder:rbac:groups="",resources=services,verbs=get
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+
+// ComponentValuesSchema returns the values schema of a component of
+// packageSource: its inline valuesSchema, or else the values.schema.json
+// its chart ships, read through c from the ConfigMap the status references;
+// nil when it has neither. A referenced ConfigMap that cannot be read is an
+// error rather than no schema, so validation never silently stops.
+func ComponentValuesSchema(ctx context.Context, c client.Reader, packageSource *cozyv1alpha1.PackageSource, component *cozyv1alpha1.Component) (*apiextensionsv1.JSON, error) {
+	if component.ValuesSchema != nil {
+		return component.ValuesSchema, nil
+	}
+	chart := strings.Trim(component.Path, "/")
+	for i := range packageSource.Status.ValuesSchemas {
+		s := &packageSource.Status.ValuesSchemas[i]
+		if s.Path != chart {
+			continue
+		}
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: valuesSchemaNamespace, Name: s.ConfigMapName}, cm); err != nil {
+			return nil, fmt.Errorf("cannot read the values schema of %s: %w", chart, err)
+		}
+		raw, ok := cm.Data[chartValuesSchemaFile]
+		if !ok {
+			return nil, fmt.Errorf("ConfigMap %s/%s has no %s", valuesSchemaNamespace, s.ConfigMapName, chartValuesSchemaFile)
+		}
+		return &apiextensionsv1.JSON{Raw: []byte(raw)}, nil
+	}
+	return nil, nil
+}
+
+// LoadComponentValuesSchema is ComponentValuesSchema, parsed.
+func LoadComponentValuesSchema(ctx context.Context, c client.Reader, packageSource *cozyv1alpha1.PackageSource, component *cozyv1alpha1.Component) (*ValuesSchema, error) {
+	raw, err := ComponentValuesSchema(ctx, c, packageSource, component)
+	if err != nil {
+		return nil, err
+	}
+	return ParseValuesSchema(raw)
+}
+
+// valuesSchemaConfigMapName names the ConfigMap holding the values schema
+// of chart. Chart paths are not valid object names, so a digest of the
+// path stands in for it.
+func valuesSchemaConfigMapName(packageSource *cozyv1alpha1.PackageSource, chart string) string {
+	sum := sha256.Sum256([]byte(chart))
+	return packageSource.Name + "-values-schema-" + hex.EncodeToString(sum[:])[:10]
+}
+
+// loadValuesSchemas reads the values.schema.json of every component chart
+// without an inline schema from the current artifact of the source, writes
+// each into a ConfigMap of its own and references them in packageSource's
+// status, without writing it. They are read once per revision, and again
+// when one of the ConfigMaps went missing. When the artifact cannot be read
+// the schemas of the previous revision are kept, so a source controller
+// outage does not turn validation off.
+func (r *PackageSourceReconciler) loadValuesSchemas(ctx context.Context, packageSource *cozyv1alpha1.PackageSource) error {
+	if packageSource.Spec.SourceRef == nil {
+		return nil
+	}
+	charts := map[string]bool{}
+	for _, variant := range packageSource.Spec.Variants {
+		for _, component := range variant.Components {
+			if component.ValuesSchema == nil {
+				charts[strings.Trim(component.Path, "/")] = true
+			}
+		}
+	}
+	if len(charts) == 0 {
+		if len(packageSource.Status.ValuesSchemas) > 0 {
+			if err := r.pruneValuesSchemas(ctx, packageSource, nil); err != nil {
+				return err
+			}
+		}
+		packageSource.Status.ValuesSchemas = nil
+		packageSource.Status.ValuesSchemasRevision = ""
+		meta.RemoveStatusCondition(&packageSource.Status.Conditions, ConditionValuesSchemas)
+		return nil
+	}
+
+	src, err := getFluxSource(ctx, r.Client, packageSource.Spec.SourceRef)
+	if err != nil {
+		return err
+	}
+	if src == nil || src.artifact == nil || src.artifact.URL == "" {
+		return nil
+	}
+	// A new generation may name charts the revision was not read for.
+	if cond := meta.FindStatusCondition(packageSource.Status.Conditions, ConditionValuesSchemas); cond != nil &&
+		cond.Status == metav1.ConditionTrue && cond.ObservedGeneration == packageSource.Generation &&
+		packageSource.Status.ValuesSchemasRevision == src.artifact.Revision {
+		present, err := r.valuesSchemasPresent(ctx, packageSource)
+		if err != nil || present {
+			return err
+		}
+	}
+
+	basePath := SourceBasePath(packageSource.Spec.SourceRef)
+	files := map[string]string{}
+	for chart := range charts {
+		files[path.Join(basePath, chart, chartValuesSchemaFile)] = chart
+	}
+	found, err := r.readArtifactFiles(ctx, src.artifact, files)
+	if err != nil {
+		meta.SetStatusCondition(&packageSource.Status.Conditions, metav1.Condition{
+			Type:               ConditionValuesSchemas,
+			Status:             metav1.ConditionFalse,
+			Reason:             reasonValuesSchemasFailed,
+			Message:            fmt.Sprintf("cannot read %s from %s revision %s: %v", chartValuesSchemaFile, src, src.artifact.Revision, err),
+			ObservedGeneration: packageSource.Generation,
+		})
+		return nil
+	}
+
+	var schemas []cozyv1alpha1.ChartValuesSchema
+	var invalid []string
+	for _, chart := range sortedKeys(charts) {
+		raw, ok := found[chart]
+		if !ok {
+			continue
+		}
+		schema := apiextensionsv1.JSON{Raw: raw}
+		if _, err := ParseValuesSchema(&schema); err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s: %v", chart, err))
+			continue
+		}
+		name := valuesSchemaConfigMapName(packageSource, chart)
+		if err := r.writeValuesSchema(ctx, packageSource, name, chart, raw); err != nil {
+			return fmt.Errorf("failed to write the values schema of %s: %w", chart, err)
+		}
+		schemas = append(schemas, cozyv1alpha1.ChartValuesSchema{Path: chart, ConfigMapName: name})
+	}
+	if err := r.pruneValuesSchemas(ctx, packageSource, schemas); err != nil {
+		return err
+	}
+	packageSource.Status.ValuesSchemas = schemas
+	packageSource.Status.ValuesSchemasRevision = src.artifact.Revision
+	cond := &metav1.Condition{
+		Type:               ConditionValuesSchemas,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonValuesSchemasLoaded,
+		Message:            fmt.Sprintf("%d of %d charts ship a values schema", len(schemas), len(charts)),
+		ObservedGeneration: packageSource.Generation,
+	}
+	if len(invalid) > 0 {
+		// The charts with a broken schema are left unvalidated rather than
+		// refusing every Package that installs them.
+		cond.Reason = reasonValuesSchemasInvalid
+		cond.Message = "ignored invalid values schemas: " + strings.Join(invalid, "; ")
+	}
+	meta.SetStatusCondition(&packageSource.Status.Conditions, *cond)
+	return nil
+}
+
+// writeValuesSchema creates or updates the ConfigMap name holding the
+// values schema of chart.
+func (r *PackageSourceReconciler) writeValuesSchema(ctx context.Context, packageSource *cozyv1alpha1.PackageSource, name, chart string, raw []byte) error {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: valuesSchemaNamespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		if cm.Labels == nil {
+			cm.Labels = map[string]string{}
+		}
+		cm.Labels[LabelValuesSchema] = ""
+		if cm.Annotations == nil {
+			cm.Annotations = map[string]string{}
+		}
+		cm.Annotations["cozystack.io/values-schema-chart"] = chart
+		cm.Data = map[string]string{chartValuesSchemaFile: string(raw)}
+		return controllerutil.SetControllerReference(packageSource, cm, r.Scheme)
+	})
+	return err
+}
+
+// pruneValuesSchemas deletes the values schema ConfigMaps of packageSource
+// that keep is no longer referencing.
+func (r *PackageSourceReconciler) pruneValuesSchemas(ctx context.Context, packageSource *cozyv1alpha1.PackageSource, keep []cozyv1alpha1.ChartValuesSchema) error {
+	list := &corev1.ConfigMapList{}
+	if err := r.List(ctx, list, client.InNamespace(valuesSchemaNamespace), client.HasLabels{LabelValuesSchema}); err != nil {
+		return err
+	}
+	kept := map[string]bool{}
+	for _, s := range keep {
+		kept[s.ConfigMapName] = true
+	}
+	for i := range list.Items {
+		cm := &list.Items[i]
+		if kept[cm.Name] || !metav1.IsControlledBy(cm, packageSource) {
+			continue
+		}
+		if err := r.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// valuesSchemasPresent reports whether every ConfigMap the status of
+// packageSource references exists.
+func (r *PackageSourceReconciler) valuesSchemasPresent(ctx context.Context, packageSource *cozyv1alpha1.PackageSource) (bool, error) {
+	for _, s := range packageSource.Status.ValuesSchemas {
+		cm := &corev1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: valuesSchemaNamespace, Name: s.ConfigMapName}, cm); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// readArtifactFiles downloads artifact and returns the content of the
+// files it has among files, keyed by the value files gives them. The
+// download is checked against the digest of the artifact.
+func (r *PackageSourceReconciler) readArtifactFiles(ctx context.Context, artifact *fluxmeta.Artifact, files map[string]string) (map[string][]byte, error) {
+	rawURL, err := r.artifactURL(ctx, artifact.URL)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := artifactHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", artifact.URL, resp.Status)
+	}
+
+	algorithm, want, _ := strings.Cut(artifact.Digest, ":")
+	if algorithm != "sha256" {
+		return nil, fmt.Errorf("unsupported artifact digest %q", artifact.Digest)
+	}
+	digest := sha256.New()
+	body := io.TeeReader(resp.Body, digest)
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, err
+	}
+	found := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		key, ok := files[path.Clean(strings.TrimPrefix(hdr.Name, "./"))]
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Size > maxValuesSchemaSize {
+			return nil, fmt.Errorf("%s is larger than %d bytes", hdr.Name, maxValuesSchemaSize)
+		}
+		if found[key], err = io.ReadAll(tr); err != nil {
+			return nil, err
+		}
+	}
+	// Hash what the archive reader did not consume.
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		return nil, err
+	}
+	if got := hex.EncodeToString(digest.Sum(nil)); got != want {
+		return nil, fmt.Errorf("artifact digest is sha256:%s, want %s", got, artifact.Digest)
+	}
+	return found, nil
+}
+
+// artifactURL returns the URL to download an artifact from. The source
+// controller advertises artifacts under its Service name, which the
+// operator cannot resolve: it runs on the host network, before cluster DNS
+// may exist. The Service's cluster IP is used instead.
+func (r *PackageSourceReconciler) artifactURL(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	labels := strings.Split(strings.TrimSuffix(u.Hostname(), "."), ".")
+	if len(labels) < 3 || labels[2] != "svc" {
+		return rawURL, nil
+	}
+	svc := &corev1.Service{}
+	if err := r.apiReader().Get(ctx, types.NamespacedName{Namespace: labels[1], Name: labels[0]}, svc); err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", u.Host, err)
+	}
+	if svc.Spec.ClusterIP == "" || svc.Spec.ClusterIP == corev1.ClusterIPNone {
+		return "", fmt.Errorf("service %s/%s has no cluster IP", labels[1], labels[0])
+	}
+	host := svc.Spec.ClusterIP
+	if port := u.Port(); port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	u.Host = host
+	return u.String(), nil
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	fluxmeta "github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const multusValuesSchema = `{"type":"object","properties":{"cniVersion":{"type":"string"}}}`
+
+// chartArchive builds a source artifact holding files.
+func chartArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range sortedKeys(files) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func schemaArtifactSource(url, digest, revision string) (*cozyv1alpha1.PackageSource, *sourcev1.OCIRepository) {
+	ps := &cozyv1alpha1.PackageSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack.networking", Generation: 1},
+		Spec: cozyv1alpha1.PackageSourceSpec{
+			SourceRef: &cozyv1alpha1.PackageSourceRef{Kind: "OCIRepository", Name: "cozystack-packages", Namespace: "cozy-system"},
+			Variants: []cozyv1alpha1.Variant{{
+				Name: "cilium",
+				Components: []cozyv1alpha1.Component{
+					{Name: "multus", Path: "system/multus"},
+					{Name: "kube-ovn", Path: "system/kubeovn"},
+					{Name: "cilium", Path: "system/cilium"},
+				},
+			}},
+		},
+	}
+	repo := &sourcev1.OCIRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack-packages", Namespace: "cozy-system"},
+		Status: sourcev1.OCIRepositoryStatus{
+			Artifact: &fluxmeta.Artifact{URL: url, Digest: digest, Revision: revision},
+		},
+	}
+	return ps, repo
+}
+
+func TestLoadValuesSchemas(t *testing.T) {
+	archive := chartArchive(t, map[string]string{
+		"system/multus/Chart.yaml":             "name: multus",
+		"system/multus/values.schema.json":     multusValuesSchema,
+		"system/cilium/values.schema.json":     `{"type": 1}`,
+		"system/kubeovn/Chart.yaml":            "name: kubeovn",
+		"system/other/values.schema.json":      `{"type":"object"}`,
+		"./system/multus/templates/empty.yaml": "",
+	})
+	sum := sha256.Sum256(archive)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		_, _ = w.Write(archive)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	ps, repo := schemaArtifactSource(srv.URL+"/artifact.tar.gz", digest, "v1.0.0@"+digest)
+	r := bundleReconciler(t, repo)
+	if err := r.loadValuesSchemas(ctx, ps); err != nil {
+		t.Fatalf("loadValuesSchemas: %v", err)
+	}
+	if len(ps.Status.ValuesSchemas) != 1 || ps.Status.ValuesSchemas[0].Path != "system/multus" {
+		t.Fatalf("ValuesSchemas = %+v, want the multus schema only", ps.Status.ValuesSchemas)
+	}
+	cm := &corev1.ConfigMap{}
+	key := types.NamespacedName{Namespace: "cozy-system", Name: ps.Status.ValuesSchemas[0].ConfigMapName}
+	if err := r.Get(ctx, key, cm); err != nil {
+		t.Fatalf("get schema ConfigMap: %v", err)
+	}
+	if cm.Data[chartValuesSchemaFile] != multusValuesSchema || !metav1.IsControlledBy(cm, ps) {
+		t.Errorf("schema ConfigMap = %+v, want the multus schema owned by the PackageSource", cm)
+	}
+	if ps.Status.ValuesSchemasRevision != "v1.0.0@"+digest {
+		t.Errorf("ValuesSchemasRevision = %q", ps.Status.ValuesSchemasRevision)
+	}
+	cond := meta.FindStatusCondition(ps.Status.Conditions, ConditionValuesSchemas)
+	if cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != reasonValuesSchemasInvalid {
+		t.Errorf("condition = %+v, want True with the invalid cilium schema reported", cond)
+	}
+	if got, err := ComponentValuesSchema(ctx, r.Client, ps, &ps.Spec.Variants[0].Components[0]); err != nil || got == nil || string(got.Raw) != multusValuesSchema {
+		t.Errorf("ComponentValuesSchema(multus) = %v, %v", got, err)
+	}
+
+	// The same revision is not read again.
+	if err := r.loadValuesSchemas(ctx, ps); err != nil {
+		t.Fatalf("loadValuesSchemas: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("artifact fetched %d times, want once", requests)
+	}
+
+	// A schema ConfigMap gone missing is written again.
+	if err := r.Delete(ctx, cm); err != nil {
+		t.Fatalf("delete schema ConfigMap: %v", err)
+	}
+	if err := r.loadValuesSchemas(ctx, ps); err != nil {
+		t.Fatalf("loadValuesSchemas: %v", err)
+	}
+	if err := r.Get(ctx, key, cm); err != nil || requests != 2 {
+		t.Errorf("schema ConfigMap not restored (%v), artifact fetched %d times", err, requests)
+	}
+
+	// Charts no longer shipping a schema lose their ConfigMap.
+	stale := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "stale", Namespace: "cozy-system", Labels: map[string]string{LabelValuesSchema: ""}}}
+	if err := controllerutil.SetControllerReference(ps, stale, r.Scheme); err != nil {
+		t.Fatalf("set owner: %v", err)
+	}
+	if err := r.Create(ctx, stale); err != nil {
+		t.Fatalf("create stale ConfigMap: %v", err)
+	}
+	ps.Status.ValuesSchemasRevision = ""
+	if err := r.loadValuesSchemas(ctx, ps); err != nil {
+		t.Fatalf("loadValuesSchemas: %v", err)
+	}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(stale), stale); !apierrors.IsNotFound(err) {
+		t.Errorf("stale schema ConfigMap kept: %v", err)
+	}
+
+	// A download that does not match the digest keeps the schemas read
+	// before.
+	other := "sha256:" + hex.EncodeToString(make([]byte, sha256.Size))
+	repo.Status.Artifact.Digest, repo.Status.Artifact.Revision = other, "v1.1.0@"+other
+	r = bundleReconciler(t, repo)
+	if err := r.loadValuesSchemas(ctx, ps); err != nil {
+		t.Fatalf("loadValuesSchemas: %v", err)
+	}
+	cond = meta.FindStatusCondition(ps.Status.Conditions, ConditionValuesSchemas)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != reasonValuesSchemasFailed {
+		t.Errorf("condition = %+v, want FetchFailed", cond)
+	}
+	if len(ps.Status.ValuesSchemas) != 1 || ps.Status.ValuesSchemasRevision != "v1.0.0@"+digest {
+		t.Errorf("schemas of the previous revision were not kept: %+v", ps.Status)
+	}
+}
+
+func TestArtifactURL(t *testing.T) {
+	scheme := testScheme(t)
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("corev1.AddToScheme: %v", err)
+	}
+	r := &PackageSourceReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "flux", Namespace: "cozy-fluxcd"},
+		Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.20"},
+	}).Build(), Scheme: scheme}
+	ctx := context.Background()
+	cases := map[string]string{
+		"http://flux.cozy-fluxcd.svc./ocirepository/cozy-system/p/sha256.tar.gz":                   "http://10.96.0.20/ocirepository/cozy-system/p/sha256.tar.gz",
+		"http://flux.cozy-fluxcd.svc.cluster.local:9090/ocirepository/cozy-system/p/sha256.tar.gz": "http://10.96.0.20:9090/ocirepository/cozy-system/p/sha256.tar.gz",
+		"http://127.0.0.1:9090/ocirepository/cozy-system/p/sha256.tar.gz":                          "http://127.0.0.1:9090/ocirepository/cozy-system/p/sha256.tar.gz",
+	}
+	for in, want := range cases {
+		got, err := r.artifactURL(ctx, in)
+		if err != nil || got != want {
+			t.Errorf("artifactURL(%s) = %s, %v; want %s", in, got, err, want)
+		}
+	}
+	if _, err := r.artifactURL(ctx, "http://missing.cozy-fluxcd.svc./a.tar.gz"); err == nil {
+		t.Error("artifactURL resolved a missing Service")
+	}
+}
@@ -0,0 +1,368 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	fluxmeta "github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// ConditionVerified summarises the verification of every variant with a
+	// policy. It is absent when no variant has one.
+	ConditionVerified = "Verified"
+
+	reasonVerified             = "Verified"
+	reasonVerificationPending  = "VerificationPending"
+	reasonSignatureNotEnforced = "SignatureNotEnforced"
+	reasonSignatureFailed      = "SignatureVerificationFailed"
+	reasonOriginMismatch       = "OriginMismatch"
+	reasonInvalidPolicy        = "InvalidPolicy"
+	reasonVariantsBlocked      = "VariantsBlocked"
+
+	annotationOCISource   = "org.opencontainers.image.source"
+	annotationOCIRevision = "org.opencontainers.image.revision"
+)
+
+// +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=gitrepositories,verbs=get;list;watch
+
+// verificationPolicy returns the policy variant is verified against, or nil
+// when it has none.
+func verificationPolicy(packageSource *cozyv1alpha1.PackageSource, variant *cozyv1alpha1.Variant) *cozyv1alpha1.ArtifactVerification {
+	if variant.Verify != nil {
+		return variant.Verify
+	}
+	return packageSource.Spec.Verify
+}
+
+// fluxSource is the part of a GitRepository or OCIRepository verification
+// looks at.
+type fluxSource struct {
+	kind       string
+	key        types.NamespacedName
+	generation int64
+	url        string
+	artifact   *fluxmeta.Artifact
+	conditions []metav1.Condition
+	oci        *sourcev1.OCIRepositoryVerification
+	git        *sourcev1.GitRepositoryVerification
+}
+
+func (s *fluxSource) String() string {
+	return fmt.Sprintf("%s %s", s.kind, s.key)
+}
+
+// getFluxSource reads the source ref points at; nil when it does not exist.
+func getFluxSource(ctx context.Context, c client.Reader, ref *cozyv1alpha1.PackageSourceRef) (*fluxSource, error) {
+	src := &fluxSource{kind: ref.Kind, key: types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}}
+	var err error
+	switch ref.Kind {
+	case sourcev1.OCIRepositoryKind:
+		repo := &sourcev1.OCIRepository{}
+		if err = c.Get(ctx, src.key, repo); err == nil {
+			src.generation, src.url, src.artifact, src.conditions, src.oci = repo.Generation, repo.Spec.URL, repo.Status.Artifact, repo.Status.Conditions, repo.Spec.Verify
+		}
+	case sourcev1.GitRepositoryKind:
+		repo := &sourcev1.GitRepository{}
+		if err = c.Get(ctx, src.key, repo); err == nil {
+			src.generation, src.url, src.artifact, src.conditions, src.git = repo.Generation, repo.Spec.URL, repo.Status.Artifact, repo.Status.Conditions, repo.Spec.Verification
+		}
+	default:
+		return nil, fmt.Errorf("unsupported source kind %s", ref.Kind)
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s %s: %w", ref.Kind, src.key, err)
+	}
+	return src, nil
+}
+
+// verifyVariants checks the artifact of every variant with a verification
+// policy and records the outcome in packageSource's status, without writing
+// it. It returns the variants that fail their policy and must be blocked,
+// and whether any variant cannot be told yet, in which case the
+// ArtifactGenerator must not read the current revision.
+func (r *PackageSourceReconciler) verifyVariants(ctx context.Context, packageSource *cozyv1alpha1.PackageSource) (map[string]bool, bool, error) {
+	var verification []cozyv1alpha1.VariantVerification
+	var src *fluxSource
+	fetched := false
+	for i := range packageSource.Spec.Variants {
+		variant := &packageSource.Spec.Variants[i]
+		policy := verificationPolicy(packageSource, variant)
+		if policy == nil {
+			continue
+		}
+		if !fetched && packageSource.Spec.SourceRef != nil {
+			var err error
+			if src, err = getFluxSource(ctx, r.Client, packageSource.Spec.SourceRef); err != nil {
+				return nil, false, err
+			}
+			fetched = true
+		}
+		v := verifyArtifact(policy, src)
+		v.Variant = variant.Name
+		verification = append(verification, v)
+	}
+	packageSource.Status.Verification = verification
+	if len(verification) == 0 {
+		packageSource.Status.VerifiedRevision = ""
+		meta.RemoveStatusCondition(&packageSource.Status.Conditions, ConditionVerified)
+		return nil, false, nil
+	}
+
+	blocked := map[string]bool{}
+	pending := false
+	var failed, waiting, verified []string
+	for _, v := range verification {
+		switch v.Status {
+		case metav1.ConditionFalse:
+			blocked[v.Variant] = true
+			failed = append(failed, fmt.Sprintf("variant %s blocked: %s", v.Variant, v.Message))
+		case metav1.ConditionUnknown:
+			pending = true
+			waiting = append(waiting, fmt.Sprintf("variant %s: %s", v.Variant, v.Message))
+		default:
+			verified = append(verified, v.Variant)
+		}
+	}
+	cond := metav1.Condition{Type: ConditionVerified, ObservedGeneration: packageSource.Generation}
+	switch {
+	case len(failed) > 0:
+		cond.Status, cond.Reason, cond.Message = metav1.ConditionFalse, reasonVariantsBlocked, strings.Join(append(failed, waiting...), "; ")
+	case len(waiting) > 0:
+		cond.Status, cond.Reason, cond.Message = metav1.ConditionUnknown, reasonVerificationPending, strings.Join(waiting, "; ")
+	default:
+		sort.Strings(verified)
+		cond.Status, cond.Reason, cond.Message = metav1.ConditionTrue, reasonVerified, "Verified variants: "+strings.Join(verified, ", ")
+		packageSource.Status.VerifiedRevision = verification[0].Revision
+	}
+	meta.SetStatusCondition(&packageSource.Status.Conditions, cond)
+	return blocked, pending, nil
+}
+
+// verifyArtifact checks the current artifact of src against policy.
+func verifyArtifact(policy *cozyv1alpha1.ArtifactVerification, src *fluxSource) cozyv1alpha1.VariantVerification {
+	if src == nil {
+		return cozyv1alpha1.VariantVerification{Status: metav1.ConditionUnknown, Reason: reasonVerificationPending, Message: "source not found"}
+	}
+	v := cozyv1alpha1.VariantVerification{}
+	if src.artifact != nil {
+		v.Revision = src.artifact.Revision
+	}
+	if policy.Signature != nil {
+		if v.Status, v.Reason, v.Message = checkSignature(policy.Signature, src); v.Status != metav1.ConditionTrue {
+			return v
+		}
+	}
+	if src.artifact == nil {
+		v.Status, v.Reason, v.Message = metav1.ConditionUnknown, reasonVerificationPending, fmt.Sprintf("%s has no artifact yet", src)
+		return v
+	}
+	if policy.Origin != nil {
+		if v.Status, v.Reason, v.Message = checkOrigin(policy.Origin, src); v.Status != metav1.ConditionTrue {
+			return v
+		}
+	}
+	v.Status, v.Reason, v.Message = metav1.ConditionTrue, reasonVerified, ""
+	return v
+}
+
+// checkSignature checks that src verifies signatures the way policy
+// requires, and that the source controller found its artifact signed.
+func checkSignature(policy *cozyv1alpha1.SignaturePolicy, src *fluxSource) (metav1.ConditionStatus, string, string) {
+	notEnforced := func(format string, args ...interface{}) (metav1.ConditionStatus, string, string) {
+		return metav1.ConditionFalse, reasonSignatureNotEnforced, fmt.Sprintf("%s %s", src, fmt.Sprintf(format, args...))
+	}
+
+	switch policy.Provider {
+	case "git":
+		if src.kind != sourcev1.GitRepositoryKind {
+			return notEnforced("cannot verify git signatures")
+		}
+		if src.git == nil {
+			return notEnforced("does not verify commit signatures")
+		}
+		if policy.SecretRef != nil && src.git.SecretRef.Name != policy.SecretRef.Name {
+			return notEnforced("trusts the keys in Secret %s, not %s", src.git.SecretRef.Name, policy.SecretRef.Name)
+		}
+		mode := sourcev1.GitVerificationMode(policy.GitMode)
+		if mode == "" {
+			mode = sourcev1.ModeGitHEAD
+		}
+		needHEAD := mode == sourcev1.ModeGitHEAD || mode == sourcev1.ModeGitTagAndHEAD
+		needTag := mode == sourcev1.ModeGitTag || mode == sourcev1.ModeGitTagAndHEAD
+		if needHEAD && !src.git.VerifyHEAD() || needTag && !src.git.VerifyTag() {
+			return notEnforced("verifies %s, policy requires %s", src.git.GetMode(), mode)
+		}
+	default:
+		if src.kind != sourcev1.OCIRepositoryKind {
+			return notEnforced("cannot verify %s signatures", policy.Provider)
+		}
+		if src.oci == nil {
+			return notEnforced("does not verify signatures")
+		}
+		provider := src.oci.Provider
+		if provider == "" {
+			provider = "cosign"
+		}
+		if provider != policy.Provider {
+			return notEnforced("verifies %s signatures, policy requires %s", provider, policy.Provider)
+		}
+		if msg := trustMismatch(policy, src.oci); msg != "" {
+			return notEnforced("%s", msg)
+		}
+	}
+
+	cond := meta.FindStatusCondition(src.conditions, sourcev1.SourceVerifiedCondition)
+	switch {
+	case cond == nil || cond.ObservedGeneration < src.generation || cond.Status == metav1.ConditionUnknown:
+		return metav1.ConditionUnknown, reasonVerificationPending, fmt.Sprintf("waiting for %s to verify its artifact", src)
+	case cond.Status == metav1.ConditionFalse:
+		return metav1.ConditionFalse, reasonSignatureFailed, fmt.Sprintf("%s: %s", src, cond.Message)
+	}
+	return metav1.ConditionTrue, reasonVerified, ""
+}
+
+// trustMismatch explains how the keys or identities an OCIRepository trusts
+// go beyond what policy allows; empty when they do not. A policy with
+// neither keys nor identities accepts whatever the source trusts.
+func trustMismatch(policy *cozyv1alpha1.SignaturePolicy, verify *sourcev1.OCIRepositoryVerification) string {
+	if policy.SecretRef == nil && len(policy.MatchOIDCIdentity) == 0 {
+		return ""
+	}
+	if verify.SecretRef != nil {
+		if policy.SecretRef != nil && verify.SecretRef.Name == policy.SecretRef.Name {
+			return ""
+		}
+		return fmt.Sprintf("trusts the keys in Secret %s, which the policy does not", verify.SecretRef.Name)
+	}
+	if len(policy.MatchOIDCIdentity) == 0 {
+		return "verifies keyless signatures, policy requires the keys in Secret " + policy.SecretRef.Name
+	}
+	if len(verify.MatchOIDCIdentity) == 0 {
+		return "trusts any keyless identity"
+	}
+	for _, id := range verify.MatchOIDCIdentity {
+		allowed := false
+		for _, want := range policy.MatchOIDCIdentity {
+			allowed = allowed || id.Issuer == want.Issuer && id.Subject == want.Subject
+		}
+		if !allowed {
+			return fmt.Sprintf("trusts identity %s %s, which the policy does not", id.Issuer, id.Subject)
+		}
+	}
+	return ""
+}
+
+// checkOrigin matches where the artifact of src comes from against policy:
+// the org.opencontainers.image.source and .revision annotations of an OCI
+// artifact, or the URL and fetched revision of a Git source.
+func checkOrigin(policy *cozyv1alpha1.OriginPolicy, src *fluxSource) (metav1.ConditionStatus, string, string) {
+	sourceURI, revision := src.url, src.artifact.Revision
+	if src.kind == sourcev1.OCIRepositoryKind {
+		sourceURI, revision = src.artifact.Metadata[annotationOCISource], src.artifact.Metadata[annotationOCIRevision]
+	}
+	for _, check := range []struct{ field, pattern, value string }{
+		{"sourceURI", policy.SourceURI, sourceURI},
+		{"revision", policy.Revision, revision},
+	} {
+		if check.pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile("^(?:" + check.pattern + ")$")
+		if err != nil {
+			return metav1.ConditionFalse, reasonInvalidPolicy, fmt.Sprintf("origin %s: %v", check.field, err)
+		}
+		if check.value == "" {
+			return metav1.ConditionFalse, reasonOriginMismatch, fmt.Sprintf("artifact of %s records no %s", src, check.field)
+		}
+		if !re.MatchString(check.value) {
+			return metav1.ConditionFalse, reasonOriginMismatch, fmt.Sprintf("artifact of %s has %s %q, policy requires %q", src, check.field, check.value, check.pattern)
+		}
+	}
+	return metav1.ConditionTrue, reasonVerified, ""
+}
+
+// sourceRequests maps a GitRepository or OCIRepository to the PackageSources
+// that verify its artifact, either against a bundle digest or a policy, so
+// they are verified again when it fetches a new artifact.
+func (r *PackageSourceReconciler) sourceRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	kind := sourcev1.GitRepositoryKind
+	var requests []reconcile.Request
+	if _, ok := obj.(*sourcev1.OCIRepository); ok {
+		kind = sourcev1.OCIRepositoryKind
+		requests = r.bundleSourceRequests(ctx, obj)
+	}
+
+	list := &cozyv1alpha1.PackageSourceList{}
+	if err := r.List(ctx, list); err != nil {
+		return requests
+	}
+	for _, ps := range list.Items {
+		ref := ps.Spec.SourceRef
+		if ps.Annotations[AnnotationBundleDigest] != "" || ref == nil || ref.Kind != kind ||
+			ref.Name != obj.GetName() || ref.Namespace != obj.GetNamespace() {
+			continue
+		}
+		for i := range ps.Spec.Variants {
+			if verificationPolicy(&ps, &ps.Spec.Variants[i]) != nil {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: ps.Name}})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// heldAtVerifiedRevision returns a copy of packageSource whose charts are
+// pinned to Status.VerifiedRevision, for building the ArtifactGenerator
+// while a newer revision of the source awaits verification; nil when no
+// revision was verified yet. A pin set by a rollout is kept, and a rollout
+// revision other than the verified one is held back with the rest.
+func heldAtVerifiedRevision(packageSource *cozyv1alpha1.PackageSource) *cozyv1alpha1.PackageSource {
+	verified := packageSource.Status.VerifiedRevision
+	if verified == "" {
+		return nil
+	}
+	held := packageSource.DeepCopy()
+	if held.Annotations == nil {
+		held.Annotations = map[string]string{}
+	}
+	if held.Annotations[AnnotationPinnedRevision] == "" {
+		held.Annotations[AnnotationPinnedRevision] = verified
+	}
+	if held.Annotations[AnnotationRolloutRevision] != verified {
+		delete(held.Annotations, AnnotationRolloutRevision)
+		delete(held.Annotations, AnnotationRolloutComponents)
+	}
+	return held
+}
@@ -0,0 +1,307 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"testing"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	fluxmeta "github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcewatcherv1beta1 "github.com/fluxcd/source-watcher/api/v2/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const signedRevision = "v1.2.0@sha256:3333333333333333333333333333333333333333333333333333333333333333"
+
+func signedRepository(verify *sourcev1.OCIRepositoryVerification, verified metav1.ConditionStatus) *sourcev1.OCIRepository {
+	repo := &sourcev1.OCIRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack.app", Namespace: "cozy-system", Generation: 2},
+		Spec:       sourcev1.OCIRepositorySpec{URL: "oci://ghcr.io/cozystack/app", Verify: verify},
+		Status: sourcev1.OCIRepositoryStatus{
+			Artifact: &fluxmeta.Artifact{
+				Revision: signedRevision,
+				Metadata: map[string]string{
+					annotationOCISource:   "https://github.com/cozystack/cozystack",
+					annotationOCIRevision: "v1.2.0@sha1:abcdef",
+				},
+			},
+		},
+	}
+	if verified != "" {
+		repo.Status.Conditions = []metav1.Condition{{
+			Type:               sourcev1.SourceVerifiedCondition,
+			Status:             verified,
+			Reason:             "Succeeded",
+			Message:            "verification result",
+			ObservedGeneration: 2,
+		}}
+	}
+	return repo
+}
+
+func TestVerifyArtifact(t *testing.T) {
+	keyless := &sourcev1.OCIRepositoryVerification{
+		Provider:          "cosign",
+		MatchOIDCIdentity: []sourcev1.OIDCIdentityMatch{{Issuer: "https://token.actions.githubusercontent.com", Subject: "cozystack"}},
+	}
+	keyed := &sourcev1.OCIRepositoryVerification{Provider: "cosign", SecretRef: &fluxmeta.LocalObjectReference{Name: "cosign-keys"}}
+	identity := &cozyv1alpha1.SignaturePolicy{
+		Provider:          "cosign",
+		MatchOIDCIdentity: []cozyv1alpha1.OIDCIdentity{{Issuer: "https://token.actions.githubusercontent.com", Subject: "cozystack"}},
+	}
+	key := &cozyv1alpha1.SignaturePolicy{Provider: "cosign", SecretRef: &fluxmeta.LocalObjectReference{Name: "cosign-keys"}}
+
+	stale := signedRepository(keyless, metav1.ConditionTrue)
+	stale.Generation = 3
+
+	cases := []struct {
+		name       string
+		policy     *cozyv1alpha1.ArtifactVerification
+		repo       *sourcev1.OCIRepository
+		wantStatus metav1.ConditionStatus
+		wantReason string
+	}{
+		{"source missing", &cozyv1alpha1.ArtifactVerification{Signature: identity}, nil, metav1.ConditionUnknown, reasonVerificationPending},
+		{"source does not verify", &cozyv1alpha1.ArtifactVerification{Signature: identity}, signedRepository(nil, ""), metav1.ConditionFalse, reasonSignatureNotEnforced},
+		{"other provider", &cozyv1alpha1.ArtifactVerification{Signature: &cozyv1alpha1.SignaturePolicy{Provider: "notation"}}, signedRepository(keyless, metav1.ConditionTrue), metav1.ConditionFalse, reasonSignatureNotEnforced},
+		{"keys instead of identity", &cozyv1alpha1.ArtifactVerification{Signature: identity}, signedRepository(keyed, metav1.ConditionTrue), metav1.ConditionFalse, reasonSignatureNotEnforced},
+		{"identity instead of keys", &cozyv1alpha1.ArtifactVerification{Signature: key}, signedRepository(keyless, metav1.ConditionTrue), metav1.ConditionFalse, reasonSignatureNotEnforced},
+		{"not verified yet", &cozyv1alpha1.ArtifactVerification{Signature: identity}, signedRepository(keyless, ""), metav1.ConditionUnknown, reasonVerificationPending},
+		{"stale verification", &cozyv1alpha1.ArtifactVerification{Signature: identity}, stale, metav1.ConditionUnknown, reasonVerificationPending},
+		{"signature rejected", &cozyv1alpha1.ArtifactVerification{Signature: identity}, signedRepository(keyless, metav1.ConditionFalse), metav1.ConditionFalse, reasonSignatureFailed},
+		{"keyless signature", &cozyv1alpha1.ArtifactVerification{Signature: identity}, signedRepository(keyless, metav1.ConditionTrue), metav1.ConditionTrue, reasonVerified},
+		{"key signature", &cozyv1alpha1.ArtifactVerification{Signature: key}, signedRepository(keyed, metav1.ConditionTrue), metav1.ConditionTrue, reasonVerified},
+		{"origin matches", &cozyv1alpha1.ArtifactVerification{Origin: &cozyv1alpha1.OriginPolicy{
+			SourceURI: "https://github.com/cozystack/.*", Revision: `v1\.\d+\.\d+@.*`,
+		}}, signedRepository(nil, ""), metav1.ConditionTrue, reasonVerified},
+		{"origin elsewhere", &cozyv1alpha1.ArtifactVerification{Origin: &cozyv1alpha1.OriginPolicy{
+			SourceURI: "https://github.com/cozystack/website",
+		}}, signedRepository(nil, ""), metav1.ConditionFalse, reasonOriginMismatch},
+		{"invalid origin pattern", &cozyv1alpha1.ArtifactVerification{Origin: &cozyv1alpha1.OriginPolicy{
+			Revision: "v1.(",
+		}}, signedRepository(nil, ""), metav1.ConditionFalse, reasonInvalidPolicy},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var src *fluxSource
+			if tc.repo != nil {
+				r := bundleReconciler(t, tc.repo)
+				var err error
+				src, err = getFluxSource(context.Background(), r.Client, &cozyv1alpha1.PackageSourceRef{Kind: "OCIRepository", Name: "cozystack.app", Namespace: "cozy-system"})
+				if err != nil {
+					t.Fatalf("getFluxSource: %v", err)
+				}
+			}
+			got := verifyArtifact(tc.policy, src)
+			if got.Status != tc.wantStatus || got.Reason != tc.wantReason {
+				t.Errorf("verification = %s/%s (%s), want %s/%s", got.Status, got.Reason, got.Message, tc.wantStatus, tc.wantReason)
+			}
+		})
+	}
+}
+
+func TestCheckSignature_GitMode(t *testing.T) {
+	repo := &sourcev1.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack.app", Namespace: "cozy-system"},
+		Spec: sourcev1.GitRepositorySpec{
+			URL: "https://github.com/cozystack/cozystack",
+			Verification: &sourcev1.GitRepositoryVerification{
+				Mode:      sourcev1.ModeGitHEAD,
+				SecretRef: fluxmeta.LocalObjectReference{Name: "maintainers"},
+			},
+		},
+		Status: sourcev1.GitRepositoryStatus{
+			Conditions: []metav1.Condition{{Type: sourcev1.SourceVerifiedCondition, Status: metav1.ConditionTrue}},
+		},
+	}
+	r := bundleReconciler(t, repo)
+	src, err := getFluxSource(context.Background(), r.Client, &cozyv1alpha1.PackageSourceRef{Kind: "GitRepository", Name: "cozystack.app", Namespace: "cozy-system"})
+	if err != nil {
+		t.Fatalf("getFluxSource: %v", err)
+	}
+
+	cases := []struct {
+		policy     cozyv1alpha1.SignaturePolicy
+		wantStatus metav1.ConditionStatus
+	}{
+		{cozyv1alpha1.SignaturePolicy{Provider: "git"}, metav1.ConditionTrue},
+		{cozyv1alpha1.SignaturePolicy{Provider: "git", SecretRef: &fluxmeta.LocalObjectReference{Name: "maintainers"}}, metav1.ConditionTrue},
+		{cozyv1alpha1.SignaturePolicy{Provider: "git", SecretRef: &fluxmeta.LocalObjectReference{Name: "anyone"}}, metav1.ConditionFalse},
+		{cozyv1alpha1.SignaturePolicy{Provider: "git", GitMode: "TagAndHEAD"}, metav1.ConditionFalse},
+		{cozyv1alpha1.SignaturePolicy{Provider: "cosign"}, metav1.ConditionFalse},
+	}
+	for _, tc := range cases {
+		if status, reason, msg := checkSignature(&tc.policy, src); status != tc.wantStatus {
+			t.Errorf("policy %+v: %s/%s (%s), want %s", tc.policy, status, reason, msg, tc.wantStatus)
+		}
+	}
+}
+
+func TestReconcile_BlocksUnverifiedVariants(t *testing.T) {
+	ps := &cozyv1alpha1.PackageSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack.app"},
+		Spec: cozyv1alpha1.PackageSourceSpec{
+			SourceRef: &cozyv1alpha1.PackageSourceRef{Kind: "OCIRepository", Name: "cozystack.app", Namespace: "cozy-system"},
+			Verify: &cozyv1alpha1.ArtifactVerification{
+				Origin: &cozyv1alpha1.OriginPolicy{SourceURI: "https://github.com/cozystack/cozystack"},
+			},
+			Variants: []cozyv1alpha1.Variant{
+				{Name: "default", Components: []cozyv1alpha1.Component{{Name: "app", Path: "system/app"}}},
+				{
+					Name:       "edge",
+					Components: []cozyv1alpha1.Component{{Name: "app", Path: "system/app-edge"}},
+					Verify: &cozyv1alpha1.ArtifactVerification{
+						Origin: &cozyv1alpha1.OriginPolicy{Revision: "main@.*"},
+					},
+				},
+			},
+		},
+	}
+	r := bundleReconciler(t, ps, signedRepository(nil, ""))
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "cozystack.app"}}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	ag := &sourcewatcherv1beta1.ArtifactGenerator{}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "cozy-system", Name: "cozystack.app"}, ag); err != nil {
+		t.Fatalf("get ArtifactGenerator: %v", err)
+	}
+	if len(ag.Spec.OutputArtifacts) != 1 || ag.Spec.OutputArtifacts[0].Name != "cozystack-app-default-app" {
+		t.Errorf("OutputArtifacts = %+v, want only the default variant", ag.Spec.OutputArtifacts)
+	}
+
+	got := &cozyv1alpha1.PackageSource{}
+	if err := r.Get(context.Background(), req.NamespacedName, got); err != nil {
+		t.Fatal(err)
+	}
+	verified := meta.FindStatusCondition(got.Status.Conditions, ConditionVerified)
+	if verified == nil || verified.Status != metav1.ConditionFalse || verified.Reason != reasonVariantsBlocked {
+		t.Errorf("Verified = %+v, want False/%s", verified, reasonVariantsBlocked)
+	}
+	if len(got.Status.Verification) != 2 || got.Status.Verification[0].Status != metav1.ConditionTrue ||
+		got.Status.Verification[1].Reason != reasonOriginMismatch {
+		t.Errorf("Verification = %+v", got.Status.Verification)
+	}
+
+	// Once every variant is blocked the ArtifactGenerator is withdrawn.
+	got.Spec.Verify.Origin.SourceURI = "https://example.com/fork"
+	if err := r.Update(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(ag), &sourcewatcherv1beta1.ArtifactGenerator{}); !apierrors.IsNotFound(err) {
+		t.Errorf("ArtifactGenerator get err = %v, want NotFound", err)
+	}
+	if err := r.Get(context.Background(), req.NamespacedName, got); err != nil {
+		t.Fatal(err)
+	}
+	if ready := meta.FindStatusCondition(got.Status.Conditions, "Ready"); ready == nil || ready.Reason != reasonVariantsBlocked {
+		t.Errorf("Ready = %+v, want reason %s", ready, reasonVariantsBlocked)
+	}
+
+	if requests := r.sourceRequests(context.Background(), signedRepository(nil, "")); len(requests) != 1 {
+		t.Errorf("OCIRepository change enqueued %v, want cozystack.app", requests)
+	}
+}
+
+func TestReconcile_HoldsPendingRevisionAtVerifiedRevision(t *testing.T) {
+	ps := &cozyv1alpha1.PackageSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack.app"},
+		Spec: cozyv1alpha1.PackageSourceSpec{
+			SourceRef: &cozyv1alpha1.PackageSourceRef{Kind: "OCIRepository", Name: "cozystack.app", Namespace: "cozy-system"},
+			Verify: &cozyv1alpha1.ArtifactVerification{
+				Signature: &cozyv1alpha1.SignaturePolicy{Provider: "cosign"},
+			},
+			Variants: []cozyv1alpha1.Variant{
+				{Name: "default", Components: []cozyv1alpha1.Component{{Name: "app", Path: "system/app"}}},
+			},
+		},
+	}
+	repo := signedRepository(&sourcev1.OCIRepositoryVerification{Provider: "cosign"}, metav1.ConditionTrue)
+	r := bundleReconciler(t, ps, repo)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "cozystack.app"}}
+	agKey := types.NamespacedName{Namespace: "cozy-system", Name: "cozystack.app"}
+	pinnedKey := types.NamespacedName{Namespace: "cozy-system", Name: "cozystack.app-pinned"}
+	reconcileAndGetSource := func() string {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		ag := &sourcewatcherv1beta1.ArtifactGenerator{}
+		if err := r.Get(ctx, agKey, ag); err != nil {
+			t.Fatalf("get ArtifactGenerator: %v", err)
+		}
+		return ag.Spec.Sources[0].Name
+	}
+
+	if got := reconcileAndGetSource(); got != "cozystack.app" {
+		t.Errorf("verified: ArtifactGenerator reads %s, want the source itself", got)
+	}
+	got := &cozyv1alpha1.PackageSource{}
+	if err := r.Get(ctx, req.NamespacedName, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.VerifiedRevision != signedRevision {
+		t.Errorf("verifiedRevision = %q, want %q", got.Status.VerifiedRevision, signedRevision)
+	}
+
+	// A new revision the source controller has not verified yet.
+	if err := r.Get(ctx, client.ObjectKeyFromObject(repo), repo); err != nil {
+		t.Fatal(err)
+	}
+	repo.Generation = 3
+	repo.Status.Artifact.Revision = "v1.3.0@sha256:4444444444444444444444444444444444444444444444444444444444444444"
+	if err := r.Update(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+	if got := reconcileAndGetSource(); got != pinnedKey.Name {
+		t.Errorf("pending: ArtifactGenerator reads %s, want %s", got, pinnedKey.Name)
+	}
+	pinned := &sourcev1.OCIRepository{}
+	if err := r.Get(ctx, pinnedKey, pinned); err != nil {
+		t.Fatalf("get pinned source: %v", err)
+	}
+	if want := "sha256:3333333333333333333333333333333333333333333333333333333333333333"; pinned.Spec.Reference == nil || pinned.Spec.Reference.Digest != want {
+		t.Errorf("pinned reference = %+v, want digest %s", pinned.Spec.Reference, want)
+	}
+
+	// Verified: the ArtifactGenerator moves on and the copy goes away.
+	if err := r.Get(ctx, client.ObjectKeyFromObject(repo), repo); err != nil {
+		t.Fatal(err)
+	}
+	repo.Status.Conditions[0].ObservedGeneration = 3
+	if err := r.Update(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+	if got := reconcileAndGetSource(); got != "cozystack.app" {
+		t.Errorf("verified again: ArtifactGenerator reads %s, want the source itself", got)
+	}
+	if err := r.Get(ctx, pinnedKey, &sourcev1.OCIRepository{}); !apierrors.IsNotFound(err) {
+		t.Errorf("pinned source get err = %v, want NotFound", err)
+	}
+}
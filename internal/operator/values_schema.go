@@ -0,0 +1,178 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ValuesSchema is the values schema a component of a PackageSource
+// publishes.
+type ValuesSchema struct {
+	props     *apiextensionsv1.JSONSchemaProps
+	validator validation.SchemaValidator
+}
+
+// ParseValuesSchema parses the values schema of a component; nil when it
+// has none.
+func ParseValuesSchema(raw *apiextensionsv1.JSON) (*ValuesSchema, error) {
+	if raw == nil || len(raw.Raw) == 0 {
+		return nil, nil
+	}
+	props := &apiextensionsv1.JSONSchemaProps{}
+	if err := json.Unmarshal(raw.Raw, props); err != nil {
+		return nil, fmt.Errorf("invalid values schema: %w", err)
+	}
+	internal := &apiextensions.JSONSchemaProps{}
+	if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(props, internal, nil); err != nil {
+		return nil, fmt.Errorf("invalid values schema: %w", err)
+	}
+	validator, _, err := validation.NewSchemaValidator(internal)
+	if err != nil {
+		return nil, fmt.Errorf("invalid values schema: %w", err)
+	}
+	return &ValuesSchema{props: props, validator: validator}, nil
+}
+
+// Validate checks values, found at fldPath, against the schema. Keys the
+// schema does not list are rejected too, unless it allows them with
+// additionalProperties or x-kubernetes-preserve-unknown-fields, so a
+// misspelt key does not go unnoticed.
+func (s *ValuesSchema) Validate(fldPath *field.Path, values *apiextensionsv1.JSON) field.ErrorList {
+	if values == nil || len(values.Raw) == 0 {
+		return nil
+	}
+	var obj interface{}
+	if err := json.Unmarshal(values.Raw, &obj); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, string(values.Raw), err.Error())}
+	}
+	errs := validation.ValidateCustomResource(fldPath, obj, s.validator)
+	return append(errs, unknownKeys(fldPath, obj, s.props)...)
+}
+
+// Lookup returns the schema of the value at path; nil when the schema does
+// not allow a value there.
+func (s *ValuesSchema) Lookup(path []string) *apiextensionsv1.JSONSchemaProps {
+	props := s.props
+	for _, key := range path {
+		next, ok := childSchema(props, key)
+		if !ok {
+			return nil
+		}
+		props = next
+	}
+	return props
+}
+
+// Keys returns the keys the schema lists for the object at path, sorted.
+func (s *ValuesSchema) Keys(path []string) []string {
+	props := s.Lookup(path)
+	if props == nil {
+		return nil
+	}
+	return sortedKeys(props.Properties)
+}
+
+// childSchema returns the schema of key in an object of schema props, and
+// whether the object may have it at all.
+func childSchema(props *apiextensionsv1.JSONSchemaProps, key string) (*apiextensionsv1.JSONSchemaProps, bool) {
+	if child, ok := props.Properties[key]; ok {
+		return &child, true
+	}
+	if props.AdditionalProperties != nil {
+		if props.AdditionalProperties.Schema != nil {
+			return props.AdditionalProperties.Schema, true
+		}
+		return &apiextensionsv1.JSONSchemaProps{}, props.AdditionalProperties.Allows
+	}
+	if len(props.Properties) == 0 || props.XPreserveUnknownFields != nil && *props.XPreserveUnknownFields {
+		return &apiextensionsv1.JSONSchemaProps{}, true
+	}
+	return nil, false
+}
+
+// unknownKeys reports the keys of the objects in obj that props does not
+// allow.
+func unknownKeys(fldPath *field.Path, obj interface{}, props *apiextensionsv1.JSONSchemaProps) field.ErrorList {
+	var errs field.ErrorList
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			child, ok := childSchema(props, key)
+			if !ok {
+				// additionalProperties: false is the validator's to report.
+				if props.AdditionalProperties == nil {
+					errs = append(errs, field.NotSupported(fldPath.Child(key), key, sortedKeys(props.Properties)))
+				}
+				continue
+			}
+			errs = append(errs, unknownKeys(fldPath.Child(key), v[key], child)...)
+		}
+	case []interface{}:
+		if props.Items == nil || props.Items.Schema == nil {
+			return nil
+		}
+		for i, item := range v {
+			errs = append(errs, unknownKeys(fldPath.Index(i), item, props.Items.Schema)...)
+		}
+	}
+	return errs
+}
+
+// ValidatePackageValues checks the values pkg overrides for the components
+// of variant, a variant of packageSource, against their schemas, read
+// through c.
+func ValidatePackageValues(ctx context.Context, c client.Reader, pkg *cozyv1alpha1.Package, packageSource *cozyv1alpha1.PackageSource, variant *cozyv1alpha1.Variant) field.ErrorList {
+	var errs field.ErrorList
+	for _, name := range sortedKeys(pkg.Spec.Components) {
+		values := pkg.Spec.Components[name].Values
+		if values == nil {
+			continue
+		}
+		var component *cozyv1alpha1.Component
+		for i := range variant.Components {
+			if variant.Components[i].Name == name {
+				component = &variant.Components[i]
+				break
+			}
+		}
+		if component == nil {
+			continue
+		}
+		fldPath := field.NewPath("spec", "components").Key(name).Child("values")
+		schema, err := LoadComponentValuesSchema(ctx, c, packageSource, component)
+		if err != nil {
+			errs = append(errs, field.InternalError(fldPath, fmt.Errorf("component %s: %w", name, err)))
+			continue
+		}
+		if schema != nil {
+			errs = append(errs, schema.Validate(fldPath, values)...)
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
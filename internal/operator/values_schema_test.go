@@ -0,0 +1,192 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const ciliumValuesSchema = `{
+  "type": "object",
+  "properties": {
+    "mtu": {"type": "integer", "minimum": 1280, "default": 1500},
+    "routingMode": {"type": "string", "enum": ["tunnel", "native"]},
+    "hubble": {
+      "type": "object",
+      "properties": {"enabled": {"type": "boolean"}}
+    },
+    "nodeSelector": {"type": "object", "additionalProperties": {"type": "string"}},
+    "extraConfig": {"type": "object", "x-kubernetes-preserve-unknown-fields": true}
+  }
+}`
+
+func schemaVariant() *cozyv1alpha1.Variant {
+	component := planComponent("cilium", "cozy-cilium")
+	component.ValuesSchema = &apiextensionsv1.JSON{Raw: []byte(ciliumValuesSchema)}
+	multus := planComponent("multus", "cozy-multus")
+	multus.Path = "system/multus"
+	return &cozyv1alpha1.Variant{Name: "cilium", Components: []cozyv1alpha1.Component{component, planComponent("kube-ovn", "cozy-kubeovn"), multus}}
+}
+
+// schemaSource is a PackageSource with schemaVariant whose multus chart
+// ships a values schema, kept in multusSchemaConfigMap.
+func schemaSource() *cozyv1alpha1.PackageSource {
+	ps := planSource(*schemaVariant())
+	ps.Status.ValuesSchemas = []cozyv1alpha1.ChartValuesSchema{{
+		Path:          "system/multus",
+		ConfigMapName: "multus-values-schema",
+	}}
+	return ps
+}
+
+func multusSchemaConfigMap() *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "multus-values-schema", Namespace: "cozy-system", Labels: map[string]string{LabelValuesSchema: ""}},
+		Data:       map[string]string{chartValuesSchemaFile: `{"type":"object","properties":{"cniVersion":{"type":"string"}}}`},
+	}
+}
+
+func valuesPackage(component, values string) *cozyv1alpha1.Package {
+	return &cozyv1alpha1.Package{
+		ObjectMeta: metav1.ObjectMeta{Name: "cozystack.networking"},
+		Spec: cozyv1alpha1.PackageSpec{
+			Variant: "cilium",
+			Components: map[string]cozyv1alpha1.PackageComponent{
+				component: {Values: &apiextensionsv1.JSON{Raw: []byte(values)}},
+			},
+		},
+	}
+}
+
+func TestValidatePackageValues(t *testing.T) {
+	cases := []struct {
+		name      string
+		component string
+		values    string
+		wantErrs  []string
+	}{
+		{"valid", "cilium", `{"mtu":1400,"routingMode":"native","hubble":{"enabled":true}}`, nil},
+		{"wrong type", "cilium", `{"mtu":"1400"}`, []string{"spec.components[cilium].values.mtu"}},
+		{"below minimum", "cilium", `{"mtu":1000}`, []string{"spec.components[cilium].values.mtu"}},
+		{"not in enum", "cilium", `{"routingMode":"direct"}`, []string{"spec.components[cilium].values.routingMode"}},
+		{"misspelt key", "cilium", `{"mut":1400}`, []string{`spec.components[cilium].values.mut: Unsupported value: "mut"`}},
+		{"misspelt nested key", "cilium", `{"hubble":{"enable":true}}`, []string{"spec.components[cilium].values.hubble.enable"}},
+		{"additional properties", "cilium", `{"nodeSelector":{"zone":"a"}}`, nil},
+		{"additional property type", "cilium", `{"nodeSelector":{"zone":1}}`, []string{"spec.components[cilium].values.nodeSelector.zone"}},
+		{"preserved unknown fields", "cilium", `{"extraConfig":{"anything":{"goes":1}}}`, nil},
+		{"component without schema", "kube-ovn", `{"anything":1}`, nil},
+		{"chart schema", "multus", `{"cniVersion":"1.0.0"}`, nil},
+		{"misspelt chart schema key", "multus", `{"cniVerison":"1.0.0"}`, []string{"spec.components[multus].values.cniVerison"}},
+		{"component of another variant", "calico", `{"anything":1}`, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ps := schemaSource()
+			c := fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(multusSchemaConfigMap()).Build()
+			errs := ValidatePackageValues(context.Background(), c, valuesPackage(tc.component, tc.values), ps, &ps.Spec.Variants[0])
+			if len(errs) != len(tc.wantErrs) {
+				t.Fatalf("errors = %v, want %d", errs, len(tc.wantErrs))
+			}
+			for i, want := range tc.wantErrs {
+				if !strings.Contains(errs[i].Error(), want) {
+					t.Errorf("error %d = %q, want %q", i, errs[i].Error(), want)
+				}
+			}
+		})
+	}
+}
+
+// TestValidatePackageValues_SchemaConfigMapMissing: a chart schema that
+// cannot be read refuses the values rather than letting them through.
+func TestValidatePackageValues_SchemaConfigMapMissing(t *testing.T) {
+	ps := schemaSource()
+	c := fake.NewClientBuilder().WithScheme(testScheme(t)).Build()
+	errs := ValidatePackageValues(context.Background(), c, valuesPackage("multus", `{"cniVersion":"1.0.0"}`), ps, &ps.Spec.Variants[0])
+	if len(errs) != 1 || errs[0].Type != field.ErrorTypeInternal {
+		t.Fatalf("errors = %v, want an internal error", errs)
+	}
+}
+
+func TestValuesSchemaLookup(t *testing.T) {
+	schema, err := ParseValuesSchema(&apiextensionsv1.JSON{Raw: []byte(ciliumValuesSchema)})
+	if err != nil {
+		t.Fatalf("ParseValuesSchema: %v", err)
+	}
+	if got := strings.Join(schema.Keys(nil), ","); got != "extraConfig,hubble,mtu,nodeSelector,routingMode" {
+		t.Errorf("Keys() = %s", got)
+	}
+	if got := strings.Join(schema.Keys([]string{"hubble"}), ","); got != "enabled" {
+		t.Errorf("Keys(hubble) = %s", got)
+	}
+	if props := schema.Lookup([]string{"mtu"}); props == nil || props.Type != "integer" || string(props.Default.Raw) != "1500" {
+		t.Errorf("Lookup(mtu) = %+v", props)
+	}
+	if props := schema.Lookup([]string{"nodeSelector", "zone"}); props == nil || props.Type != "string" {
+		t.Errorf("Lookup(nodeSelector.zone) = %+v", props)
+	}
+	if props := schema.Lookup([]string{"hubble", "relay"}); props != nil {
+		t.Errorf("Lookup(hubble.relay) = %+v, want nil", props)
+	}
+
+	if _, err := ParseValuesSchema(&apiextensionsv1.JSON{Raw: []byte(`{"type": 1}`)}); err == nil {
+		t.Error("ParseValuesSchema accepted an invalid schema")
+	}
+	if schema, err := ParseValuesSchema(nil); schema != nil || err != nil {
+		t.Errorf("ParseValuesSchema(nil) = %v, %v", schema, err)
+	}
+}
+
+func TestReconcile_InvalidValues(t *testing.T) {
+	ps := planSource(*schemaVariant())
+	pkg := valuesPackage("cilium", `{"mut":1400}`)
+	r := planReconciler(t)
+	r.Client = fake.NewClientBuilder().WithScheme(r.Scheme).WithObjects(ps, pkg).
+		WithStatusSubresource(&cozyv1alpha1.Package{}).Build()
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: pkg.Name}}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got := &cozyv1alpha1.Package{}
+	if err := r.Get(context.Background(), req.NamespacedName, got); err != nil {
+		t.Fatal(err)
+	}
+	ready := meta.FindStatusCondition(got.Status.Conditions, "Ready")
+	if ready == nil || ready.Reason != "InvalidValues" || !strings.Contains(ready.Message, "spec.components[cilium].values.mut") {
+		t.Errorf("Ready = %+v, want InvalidValues", ready)
+	}
+
+	plan, err := r.Plan(context.Background(), pkg, ps)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(plan.InvalidValues) != 1 || len(plan.Releases) != 0 {
+		t.Errorf("plan = %+v, want the invalid value and no releases", plan)
+	}
+}
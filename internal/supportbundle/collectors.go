@@ -0,0 +1,435 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package supportbundle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	backupsv1alpha1 "github.com/cozystack/cozystack/api/backups/v1alpha1"
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	"github.com/cozystack/cozystack/internal/fluxshardoperator"
+	corev1alpha1 "github.com/cozystack/cozystack/pkg/apis/core/v1alpha1"
+	"github.com/cozystack/cozystack/pkg/ovnstatus"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// listPageSize bounds every LIST the collector makes.
+const listPageSize = 500
+
+// resourceSpec is a kind of object collected into one file.
+type resourceSpec struct {
+	path        string
+	description string
+	gvr         schema.GroupVersionResource
+	// keep selects the objects to include; nil keeps all of them.
+	keep func(*unstructured.Unstructured) bool
+}
+
+var (
+	helmReleasesGVR = helmv2.GroupVersion.WithResource("helmreleases")
+	shardPolicyGVR  = cozyv1alpha1.GroupVersion.WithResource("fluxshardpolicies")
+)
+
+var resourceSpecs = []resourceSpec{
+	{"cozystack/packages.yaml", "Packages with their conditions", cozyv1alpha1.GroupVersion.WithResource("packages"), nil},
+	{"cozystack/packagesources.yaml", "PackageSources with their conditions", cozyv1alpha1.GroupVersion.WithResource("packagesources"), nil},
+	{"cozystack/applicationdefinitions.yaml", "ApplicationDefinitions", cozyv1alpha1.GroupVersion.WithResource("applicationdefinitions"), nil},
+	{"cozystack/workloadmonitors.yaml", "WorkloadMonitors with their observed state", cozyv1alpha1.GroupVersion.WithResource("workloadmonitors"), nil},
+	{"flux/helmreleases.yaml", "HelmReleases with their conditions, credentials in values redacted", helmReleasesGVR, nil},
+	{"backups/failed-backupjobs.yaml", "BackupJobs in the Failed phase", backupsv1alpha1.GroupVersion.WithResource("backupjobs"), failedPhase},
+	{"backups/failed-restorejobs.yaml", "RestoreJobs in the Failed phase", backupsv1alpha1.GroupVersion.WithResource("restorejobs"), failedPhase},
+	{"secrets/secrets.yaml", "Secrets other than Helm release storage, values redacted", corev1.SchemeGroupVersion.WithResource("secrets"), notHelmRelease},
+	{"secrets/tenantsecrets.yaml", "TenantSecrets, values redacted", corev1alpha1.SchemeGroupVersion.WithResource("tenantsecrets"), nil},
+}
+
+func failedPhase(obj *unstructured.Unstructured) bool {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	return phase == "Failed"
+}
+
+// notHelmRelease drops the Secrets Helm stores releases in: they hold every
+// rendered manifest and are the bulk of the Secrets of a cluster.
+func notHelmRelease(obj *unstructured.Unstructured) bool {
+	secretType, _, _ := unstructured.NestedString(obj.Object, "type")
+	return secretType != "helm.sh/release.v1"
+}
+
+type collector struct {
+	opts Options
+	now  time.Time
+	out  *bundleWriter
+	// listed keeps the objects collected by resource, for the collectors
+	// that summarise them.
+	listed map[schema.GroupVersionResource][]unstructured.Unstructured
+}
+
+// list returns every object of gvr in the cluster.
+func (c *collector) list(ctx context.Context, gvr schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	var items []unstructured.Unstructured
+	opts := metav1.ListOptions{Limit: listPageSize}
+	for {
+		page, err := c.opts.Dynamic.Resource(gvr).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		if page.GetContinue() == "" {
+			break
+		}
+		opts.Continue = page.GetContinue()
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].GetNamespace() != items[j].GetNamespace() {
+			return items[i].GetNamespace() < items[j].GetNamespace()
+		}
+		return items[i].GetName() < items[j].GetName()
+	})
+	return items, nil
+}
+
+// failure turns err into the index entry of a file that could not be
+// collected.
+func failure(entry IndexEntry, err error) IndexEntry {
+	if apierrors.IsNotFound(err) {
+		entry.Skipped = "not installed"
+	} else {
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// marshalList renders objects as a v1 List.
+func marshalList(items []unstructured.Unstructured) ([]byte, error) {
+	list := map[string]interface{}{"apiVersion": "v1", "kind": "List"}
+	objs := make([]interface{}, 0, len(items))
+	for i := range items {
+		objs = append(objs, items[i].Object)
+	}
+	list["items"] = objs
+	return sigsyaml.Marshal(list)
+}
+
+func (c *collector) collectResources(ctx context.Context) error {
+	c.listed = map[schema.GroupVersionResource][]unstructured.Unstructured{}
+	for _, spec := range resourceSpecs {
+		entry := IndexEntry{Path: spec.path, Description: spec.description}
+		items, err := c.list(ctx, spec.gvr)
+		if err != nil {
+			if err := c.out.add(failure(entry, err), nil); err != nil {
+				return err
+			}
+			continue
+		}
+		kept := items[:0]
+		for i := range items {
+			if spec.keep == nil || spec.keep(&items[i]) {
+				Redact(&items[i])
+				kept = append(kept, items[i])
+			}
+		}
+		c.listed[spec.gvr] = kept
+		data, err := marshalList(kept)
+		if err != nil {
+			return err
+		}
+		entry.Items = len(kept)
+		if err := c.out.add(entry, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shardPlacement is where the flux-shard-operator put tenants and their
+// HelmReleases.
+type shardPlacement struct {
+	Policies []map[string]interface{} `json:"policies"`
+	// Tenants maps a tenant namespace to its shard, "" when unassigned.
+	Tenants map[string]string `json:"tenants"`
+	// Shards counts the tenants and HelmReleases of each shard.
+	Shards map[string]*shardLoad `json:"shards"`
+}
+
+type shardLoad struct {
+	Tenants      int `json:"tenants"`
+	HelmReleases int `json:"helmReleases"`
+}
+
+func (c *collector) collectFluxShards(ctx context.Context) error {
+	entry := IndexEntry{Path: "flux/shards.yaml", Description: "flux-shard-operator policies and the shard of each tenant"}
+	policies, err := c.list(ctx, shardPolicyGVR)
+	if err != nil {
+		return c.out.add(failure(entry, err), nil)
+	}
+	var namespaces []corev1.Namespace
+	opts := metav1.ListOptions{Limit: listPageSize}
+	for {
+		page, err := c.opts.Clientset.CoreV1().Namespaces().List(ctx, opts)
+		if err != nil {
+			return c.out.add(failure(entry, err), nil)
+		}
+		namespaces = append(namespaces, page.Items...)
+		if page.Continue == "" {
+			break
+		}
+		opts.Continue = page.Continue
+	}
+
+	placement := shardPlacement{Policies: []map[string]interface{}{}, Tenants: map[string]string{}, Shards: map[string]*shardLoad{}}
+	for i := range policies {
+		Redact(&policies[i])
+		placement.Policies = append(placement.Policies, policies[i].Object)
+	}
+	load := func(shard string) *shardLoad {
+		if placement.Shards[shard] == nil {
+			placement.Shards[shard] = &shardLoad{}
+		}
+		return placement.Shards[shard]
+	}
+	for _, ns := range namespaces {
+		shard, assigned := ns.Labels[fluxshardoperator.TenantShardLabel]
+		if !assigned && !strings.HasPrefix(ns.Name, "tenant-") {
+			continue
+		}
+		placement.Tenants[ns.Name] = shard
+		if shard != "" {
+			load(shard).Tenants++
+		}
+	}
+	for _, hr := range c.listed[helmReleasesGVR] {
+		if shard := hr.GetLabels()[fluxshardoperator.ShardKeyLabel]; shard != "" {
+			load(shard).HelmReleases++
+		}
+	}
+
+	data, err := sigsyaml.Marshal(placement)
+	if err != nil {
+		return err
+	}
+	entry.Items = len(placement.Tenants)
+	return c.out.add(entry, data)
+}
+
+func (c *collector) collectEvents(ctx context.Context) error {
+	entry := IndexEntry{Path: "events.yaml", Description: fmt.Sprintf("Events of the last %s, oldest first", c.opts.Since)}
+	since := c.now.Add(-c.opts.Since)
+	var recent []corev1.Event
+	// Only the events of the window are kept, page by page, so a cluster
+	// with a long event history is never held in memory at once.
+	opts := metav1.ListOptions{Limit: listPageSize}
+	for {
+		page, err := c.opts.Clientset.CoreV1().Events(metav1.NamespaceAll).List(ctx, opts)
+		if err != nil {
+			return c.out.add(failure(entry, err), nil)
+		}
+		for _, ev := range page.Items {
+			if eventTime(&ev).Before(since) {
+				continue
+			}
+			ev.ManagedFields = nil
+			ev.Message = RedactText(ev.Message)
+			recent = append(recent, ev)
+		}
+		if page.Continue == "" {
+			break
+		}
+		opts.Continue = page.Continue
+	}
+	sort.SliceStable(recent, func(i, j int) bool { return eventTime(&recent[i]).Before(eventTime(&recent[j])) })
+
+	data, err := sigsyaml.Marshal(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": recent})
+	if err != nil {
+		return err
+	}
+	entry.Items = len(recent)
+	return c.out.add(entry, data)
+}
+
+// eventTime is when ev last happened.
+func eventTime(ev *corev1.Event) time.Time {
+	switch {
+	case ev.Series != nil && !ev.Series.LastObservedTime.IsZero():
+		return ev.Series.LastObservedTime.Time
+	case !ev.LastTimestamp.IsZero():
+		return ev.LastTimestamp.Time
+	case !ev.EventTime.IsZero():
+		return ev.EventTime.Time
+	}
+	return ev.CreationTimestamp.Time
+}
+
+// ovnConsensus is what every ovn-central member reports about the
+// membership of the Raft clusters of the northbound and southbound
+// databases.
+type ovnConsensus struct {
+	NB ovnstatus.ExtendedConsensusResult `json:"nb"`
+	SB ovnstatus.ExtendedConsensusResult `json:"sb"`
+	// Errors maps a pod to why its view is missing.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// collectOVNConsensus runs the kubeovn-plunger consensus check once: every
+// ovn-central pod is asked for its view of both Raft clusters and the views
+// are compared.
+func (c *collector) collectOVNConsensus(ctx context.Context) error {
+	entry := IndexEntry{Path: "ovn/consensus.json", Description: "ConsensusResult of the OVN northbound and southbound Raft clusters"}
+	textEntry := IndexEntry{Path: "ovn/consensus.txt", Description: "The OVN consensus, as kubeovn-plunger prints it"}
+	skip := func(reason string) error {
+		entry.Skipped, textEntry.Skipped = reason, reason
+		if err := c.out.add(entry, nil); err != nil {
+			return err
+		}
+		return c.out.add(textEntry, nil)
+	}
+	if c.opts.Exec == nil {
+		return skip("pod exec unavailable")
+	}
+	ns, name := c.opts.KubeOVNNamespace, c.opts.OVNCentralName
+	deploy, err := c.opts.Clientset.AppsV1().Deployments(ns).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return skip(fmt.Sprintf("no %s/%s Deployment", ns, name))
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		return c.out.add(entry, nil)
+	}
+	hints := &ovnstatus.Hints{ExpectedIPs: map[string]string{}}
+	if len(deploy.Spec.Template.Spec.Containers) > 0 {
+		for _, env := range deploy.Spec.Template.Spec.Containers[0].Env {
+			if env.Name == "NODE_IPS" {
+				for _, ip := range strings.Split(env.Value, ",") {
+					hints.ExpectedIPs[ip] = ""
+				}
+			}
+		}
+	}
+	pods, err := c.opts.Clientset.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{LabelSelector: "app=" + name})
+	if err != nil {
+		entry.Error = err.Error()
+		return c.out.add(entry, nil)
+	}
+
+	result := ovnConsensus{Errors: map[string]string{}}
+	var nbViews, sbViews []ovnstatus.MemberView
+	for _, pod := range pods.Items {
+		if len(pod.Spec.Containers) == 0 {
+			continue
+		}
+		pod := pod
+		o := ovnstatus.OVNClient{}
+		o.ApplyDefaults()
+		o.Runner = func(ctx context.Context, bin string, args ...string) (string, error) {
+			return c.opts.Exec(ctx, ns, pod.Name, pod.Spec.Containers[0].Name, append([]string{bin}, args...))
+		}
+		nb, sb, err1, err2 := o.HealthBoth(ctx)
+		if err1 != nil || err2 != nil {
+			result.Errors[pod.Name] = fmt.Sprintf("nb: %v, sb: %v", err1, err2)
+			continue
+		}
+		nbViews = append(nbViews, ovnstatus.BuildMemberView(nb))
+		sbViews = append(sbViews, ovnstatus.BuildMemberView(sb))
+	}
+	result.NB = ovnstatus.AnalyzeConsensusWithIPHints(ovnstatus.NormalizeViews(nbViews), hints)
+	result.SB = ovnstatus.AnalyzeConsensusWithIPHints(ovnstatus.NormalizeViews(sbViews), hints)
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	entry.Items = len(pods.Items)
+	if err := c.out.add(entry, append(data, '\n')); err != nil {
+		return err
+	}
+	text := "Northbound database\n" + result.NB.PrettyString() + "\nSouthbound database\n" + result.SB.PrettyString()
+	return c.out.add(textEntry, []byte(text))
+}
+
+func (c *collector) collectLogs(ctx context.Context) error {
+	seen := map[string]bool{}
+	sinceSeconds := int64(c.opts.Since.Seconds())
+	for _, src := range c.opts.LogSources {
+		pods, err := c.opts.Clientset.CoreV1().Pods(src.Namespace).List(ctx, metav1.ListOptions{LabelSelector: src.Selector})
+		if err != nil {
+			entry := IndexEntry{Path: path.Join("logs", src.Namespace), Description: "Logs of pods matching " + src.Selector}
+			if err := c.out.add(failure(entry, err), nil); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, pod := range pods.Items {
+			key := pod.Namespace + "/" + pod.Name
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			for _, container := range pod.Spec.Containers {
+				if err := c.collectLog(ctx, &pod, container.Name, false, sinceSeconds); err != nil {
+					return err
+				}
+				for _, status := range pod.Status.ContainerStatuses {
+					if status.Name == container.Name && status.RestartCount > 0 {
+						if err := c.collectLog(ctx, &pod, container.Name, true, sinceSeconds); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// collectLog adds the log of one container, or of its previous instance.
+func (c *collector) collectLog(ctx context.Context, pod *corev1.Pod, container string, previous bool, sinceSeconds int64) error {
+	file := container + ".log"
+	description := fmt.Sprintf("Log of container %s of pod %s/%s, credentials redacted", container, pod.Namespace, pod.Name)
+	if previous {
+		file = container + ".previous.log"
+		description = fmt.Sprintf("Log of the previous instance of container %s of pod %s/%s, credentials redacted", container, pod.Namespace, pod.Name)
+	}
+	entry := IndexEntry{Path: path.Join("logs", pod.Namespace, pod.Name, file), Description: description}
+	opts := &corev1.PodLogOptions{Container: container, Previous: previous, Timestamps: true}
+	if sinceSeconds > 0 {
+		opts.SinceSeconds = &sinceSeconds
+	}
+	if c.opts.LogLines > 0 {
+		opts.TailLines = &c.opts.LogLines
+	}
+	stream, err := c.opts.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, opts).Stream(ctx)
+	if err != nil {
+		return c.out.add(failure(entry, err), nil)
+	}
+	defer stream.Close()
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return c.out.add(failure(entry, err), nil)
+	}
+	return c.out.add(entry, []byte(RedactText(string(data))))
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package supportbundle
+
+import (
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Redacted replaces every value scrubbed from the bundle.
+const Redacted = "REDACTED"
+
+// sensitiveKeyWords mark a map key whose value is a credential, as in the
+// values of a HelmRelease or a Package.
+var sensitiveKeyWords = []string{
+	"password", "passwd", "secret", "token", "apikey", "api_key", "api-key",
+	"accesskey", "access_key", "privatekey", "private_key", "credential",
+	"kubeconfig", "auth", "certificate",
+}
+
+// sensitiveKeySuffixes mark a key naming key or certificate material, such
+// as sshKey, tlsCert or caCerts, without catching keys that merely start
+// with the same letters, such as keepalive or certManager.
+var sensitiveKeySuffixes = []string{"key", "keys", "cert", "certs"}
+
+// referenceKeySuffixes mark a key that names a credential rather than
+// holding it, such as secretName or tokenRef, which is worth keeping.
+var referenceKeySuffixes = []string{"name", "ref", "namespace"}
+
+// sensitiveText matches credentials in free text such as log lines:
+// key=value and key: value pairs with a sensitive key, bearer tokens and PEM
+// private keys.
+var sensitiveText = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api[_-]?key|access[_-]?key)["']?\s*[:=]\s*["']?)[^\s"',;}]+`),
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`),
+	regexp.MustCompile(`(?s)(-----BEGIN [A-Z ]*PRIVATE KEY-----).*?-----END [A-Z ]*PRIVATE KEY-----`),
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	if strings.HasPrefix(key, "existing") {
+		return false
+	}
+	for _, suffix := range referenceKeySuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return false
+		}
+	}
+	for _, word := range sensitiveKeyWords {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	for _, suffix := range sensitiveKeySuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact scrubs obj in place before it goes into a bundle: the values of
+// Secrets and TenantSecrets (their keys are kept, which is what tells a
+// missing key apart from a wrong value), the last-applied-configuration
+// annotation that would repeat them, managed fields, and every value held
+// under a key that names a credential.
+func Redact(obj *unstructured.Unstructured) {
+	obj.SetManagedFields(nil)
+	if annotations := obj.GetAnnotations(); annotations != nil {
+		if _, ok := annotations["kubectl.kubernetes.io/last-applied-configuration"]; ok {
+			annotations["kubectl.kubernetes.io/last-applied-configuration"] = Redacted
+			obj.SetAnnotations(annotations)
+		}
+	}
+	if obj.GetKind() == "Secret" || obj.GetKind() == "TenantSecret" {
+		for _, field := range []string{"data", "stringData"} {
+			if data, ok := obj.Object[field].(map[string]interface{}); ok {
+				for key := range data {
+					data[key] = Redacted
+				}
+			}
+		}
+	}
+	// Label keys such as sharding.fluxcd.io/key read like credentials but
+	// label values are selectors, which the bundle needs intact.
+	labels := obj.GetLabels()
+	obj.SetLabels(nil)
+	redactValue(obj.Object)
+	obj.SetLabels(labels)
+}
+
+func redactValue(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if sensitiveKey(key) {
+				v[key] = redactLeaves(child)
+				continue
+			}
+			redactValue(child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			redactValue(child)
+		}
+	}
+}
+
+// redactLeaves replaces every scalar in v with Redacted, keeping the keys
+// of maps and the length of lists: under a key such as secrets or
+// kubeconfig a number, a boolean or a whole nested map can be the
+// credential as much as a string can.
+func redactLeaves(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = redactLeaves(child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactLeaves(child)
+		}
+		return v
+	}
+	return Redacted
+}
+
+// RedactText scrubs the credentials sensitiveText recognises in s.
+func RedactText(s string) string {
+	for _, re := range sensitiveText {
+		s = re.ReplaceAllString(s, "${1}"+Redacted)
+	}
+	return s
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package supportbundle gathers the platform state needed to diagnose a
+// Cozystack installation into a single redacted archive.
+package supportbundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// IndexFile is the name of the index at the root of the archive.
+const IndexFile = "index.yaml"
+
+// ExecFunc runs command in a container and returns its standard output.
+type ExecFunc func(ctx context.Context, namespace, pod, container string, command []string) (string, error)
+
+// LogSource selects pods whose logs go into the bundle.
+type LogSource struct {
+	// Namespace limits the source to one namespace; empty means all.
+	Namespace string
+	// Selector is a label selector; empty selects every pod.
+	Selector string
+}
+
+// DefaultLogSources are the pods of the Cozystack controllers and of Flux.
+var DefaultLogSources = []LogSource{
+	{Selector: "app in (cozystack-operator,cozystack-controller,cozystack-api,flux-shard-operator)"},
+	{Namespace: "cozy-fluxcd"},
+}
+
+// Options configures Collect.
+type Options struct {
+	Dynamic   dynamic.Interface
+	Clientset kubernetes.Interface
+	// Exec runs the OVN health commands in the ovn-central pods; the OVN
+	// consensus is left out when nil.
+	Exec ExecFunc
+
+	// Since is how far back Events and logs go.
+	Since time.Duration
+	// LogLines caps the lines kept of each container log.
+	LogLines int64
+	// LogSources defaults to DefaultLogSources.
+	LogSources []LogSource
+	// KubeOVNNamespace and OVNCentralName locate the ovn-central
+	// Deployment, as for kubeovn-plunger.
+	KubeOVNNamespace string
+	OVNCentralName   string
+
+	// Version is recorded in the index.
+	Version string
+	// Now defaults to time.Now.
+	Now func() time.Time
+	// Log receives progress, one line per collected file.
+	Log io.Writer
+}
+
+// Index describes the content of a bundle.
+type Index struct {
+	GeneratedAt time.Time    `json:"generatedAt"`
+	Version     string       `json:"version,omitempty"`
+	Since       string       `json:"since"`
+	Files       []IndexEntry `json:"files"`
+}
+
+// IndexEntry is one file of the bundle, or the reason it is missing.
+type IndexEntry struct {
+	Path        string `json:"path"`
+	Description string `json:"description"`
+	// Items is the number of objects in a file listing objects.
+	Items int `json:"items,omitempty"`
+	// Skipped says why nothing was collected, e.g. a CRD not installed.
+	Skipped string `json:"skipped,omitempty"`
+	// Error is set when collecting the file failed.
+	Error string `json:"error,omitempty"`
+}
+
+// bundleWriter writes files into the archive and records them in the index.
+type bundleWriter struct {
+	tw    *tar.Writer
+	root  string
+	now   time.Time
+	index *Index
+	log   io.Writer
+}
+
+func (b *bundleWriter) add(entry IndexEntry, data []byte) error {
+	if entry.Error == "" && entry.Skipped == "" {
+		if err := b.tw.WriteHeader(&tar.Header{
+			Name:    path.Join(b.root, entry.Path),
+			Mode:    0o644,
+			Size:    int64(len(data)),
+			ModTime: b.now,
+		}); err != nil {
+			return err
+		}
+		if _, err := b.tw.Write(data); err != nil {
+			return err
+		}
+	}
+	b.index.Files = append(b.index.Files, entry)
+	if b.log != nil {
+		switch {
+		case entry.Error != "":
+			fmt.Fprintf(b.log, "✗ %s: %s\n", entry.Path, entry.Error)
+		case entry.Skipped != "":
+			fmt.Fprintf(b.log, "- %s: %s\n", entry.Path, entry.Skipped)
+		default:
+			fmt.Fprintf(b.log, "✓ %s\n", entry.Path)
+		}
+	}
+	return nil
+}
+
+// Collect gathers the diagnostics bundle and writes it to w as a gzipped
+// tarball with everything under a single directory and an index.yaml
+// listing each file. Collection is best effort: a source that cannot be
+// read is recorded in the index with its error and the rest is still
+// collected, since a bundle is most needed when the cluster is broken.
+func Collect(ctx context.Context, w io.Writer, opts Options) (*Index, error) {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.LogSources == nil {
+		opts.LogSources = DefaultLogSources
+	}
+	if opts.KubeOVNNamespace == "" {
+		opts.KubeOVNNamespace = "cozy-kubeovn"
+	}
+	if opts.OVNCentralName == "" {
+		opts.OVNCentralName = "ovn-central"
+	}
+	now := opts.Now().UTC()
+
+	gz := gzip.NewWriter(w)
+	b := &bundleWriter{
+		tw:    tar.NewWriter(gz),
+		root:  "cozystack-support-" + now.Format("20060102-150405"),
+		now:   now,
+		index: &Index{GeneratedAt: now, Version: opts.Version, Since: opts.Since.String()},
+		log:   opts.Log,
+	}
+
+	c := &collector{opts: opts, now: now, out: b}
+	for _, collect := range []func(context.Context) error{
+		c.collectResources,
+		c.collectFluxShards,
+		c.collectEvents,
+		c.collectOVNConsensus,
+		c.collectLogs,
+	} {
+		if err := collect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	index, err := sigsyaml.Marshal(b.index)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.tw.WriteHeader(&tar.Header{
+		Name: path.Join(b.root, IndexFile), Mode: 0o644, Size: int64(len(index)), ModTime: now,
+	}); err != nil {
+		return nil, err
+	}
+	if _, err := b.tw.Write(index); err != nil {
+		return nil, err
+	}
+	if err := b.tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return b.index, nil
+}
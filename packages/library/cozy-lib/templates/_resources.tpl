@@ -195,7 +195,9 @@
   This is a helper function that takes an argument like `list "limits" "services.loadbalancers"`
   or `list "limits" "storage"` or `list "requests" "cpu"` and returns "services.loadbalancers",
   "", and "requests.cpu", respectively, thus transforming them to an acceptable format for k8s
-  ResourceQuotas objects.
+  ResourceQuotas objects. Per-StorageClass keys such as
+  "fast.storageclass.storage.k8s.io/requests.storage" are already in that format and pass
+  through unchanged.
 */}}
 {{- define "cozy-lib.resources.flattenResource" }}
 {{-   $rawQuotaKeys := list
@@ -210,10 +212,15 @@
         "replicationcontrollers"
         "resourcequotas"
 -}}
+{{-   $platformQuotaKeys := include "cozy-lib.resources.platformQuotaKeys" . | fromJsonArray }}
 {{-   $section := index . 0 }}
 {{-   $type := index . 1 }}
 {{-   $out := "" }}
-{{-   if and (eq $section "limits") (eq $type "storage") }}
+{{-   if has $type $platformQuotaKeys }}
+{{-     $out = "" }}
+{{-   else if contains ".storageclass.storage.k8s.io/" $type }}
+{{-     $out = $type }}
+{{-   else if and (eq $section "limits") (eq $type "storage") }}
 {{-     $out = "" }}
 {{-   else if and (eq $section "limits") (has $type $rawQuotaKeys) }}
 {{-     $out = $type }}
@@ -222,3 +229,27 @@
 {{-   end }}
 {{-   $out -}}
 {{- end }}
+
+{{/*
+  Platform quota keys have no ResourceQuota counterpart; the aggregated API
+  server enforces them at Application admission, so flatten does not render
+  them. Kept in sync with platformResources in internal/controller/tenantquota.
+*/}}
+{{- define "cozy-lib.resources.platformQuotaKeys" }}
+{{-   list "external-ips" "buckets.storage" "backups.storage" | toJson }}
+{{- end }}
+
+{{/*
+  Picks the platform quota keys out of a resourceQuotas map, as strings:
+  `include "cozy-lib.resources.platformQuotas" .Values.resourceQuotas | fromJson`.
+*/}}
+{{- define "cozy-lib.resources.platformQuotas" }}
+{{-   $platformQuotaKeys := include "cozy-lib.resources.platformQuotaKeys" . | fromJsonArray }}
+{{-   $out := dict }}
+{{-   range $k, $v := . }}
+{{-     if has $k $platformQuotaKeys }}
+{{-       $_ := set $out $k (toString $v) }}
+{{-     end }}
+{{-   end }}
+{{-   $out | toJson }}
+{{- end }}
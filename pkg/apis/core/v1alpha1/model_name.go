@@ -71,6 +71,22 @@ func (in OptionSpec) OpenAPIModelName() string {
 	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.OptionSpec"
 }
 
+func (in Reconciliation) OpenAPIModelName() string {
+	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.Reconciliation"
+}
+
+func (in ReconciliationAttempt) OpenAPIModelName() string {
+	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.ReconciliationAttempt"
+}
+
+func (in ReconciliationList) OpenAPIModelName() string {
+	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.ReconciliationList"
+}
+
+func (in ReconciliationStatus) OpenAPIModelName() string {
+	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.ReconciliationStatus"
+}
+
 func (in TenantModule) OpenAPIModelName() string {
 	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.TenantModule"
 }
@@ -91,6 +107,26 @@ func (in TenantNamespaceList) OpenAPIModelName() string {
 	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.TenantNamespaceList"
 }
 
+func (in TenantNamespaceStatus) OpenAPIModelName() string {
+	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.TenantNamespaceStatus"
+}
+
+func (in TenantQuota) OpenAPIModelName() string {
+	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.TenantQuota"
+}
+
+func (in TenantQuotaAllocation) OpenAPIModelName() string {
+	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.TenantQuotaAllocation"
+}
+
+func (in TenantQuotaList) OpenAPIModelName() string {
+	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.TenantQuotaList"
+}
+
+func (in TenantQuotaStatus) OpenAPIModelName() string {
+	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.TenantQuotaStatus"
+}
+
 func (in TenantSecret) OpenAPIModelName() string {
 	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.TenantSecret"
 }
@@ -98,3 +134,19 @@ func (in TenantSecret) OpenAPIModelName() string {
 func (in TenantSecretList) OpenAPIModelName() string {
 	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.TenantSecretList"
 }
+
+func (in UsageReport) OpenAPIModelName() string {
+	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.UsageReport"
+}
+
+func (in UsageReportList) OpenAPIModelName() string {
+	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.UsageReportList"
+}
+
+func (in UsageReportRow) OpenAPIModelName() string {
+	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.UsageReportRow"
+}
+
+func (in UsageReportStatus) OpenAPIModelName() string {
+	return "com.github.cozystack.cozystack.pkg.apis.core.v1alpha1.UsageReportStatus"
+}
@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2026 The Cozystack Authors.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Reconciliation is a read-only, virtual resource listing the recent
+// helm-controller reconcile attempts of one Application, so a tenant can see
+// why it is stuck without access to controller logs or cluster-scoped
+// events. It is named after the Application's HelmRelease and served from
+// the ReconcileHistory the cozystack-controller records for it.
+type Reconciliation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status ReconciliationStatus `json:"status,omitempty"`
+}
+
+// ReconciliationStatus is the history of one Application.
+type ReconciliationStatus struct {
+	// ApplicationKind and ApplicationName identify the Application.
+	ApplicationKind string `json:"applicationKind"`
+	ApplicationName string `json:"applicationName"`
+	// InProgress is the attempt under way, if any.
+	InProgress *ReconciliationAttempt `json:"inProgress,omitempty"`
+	// Attempts are the finished attempts, newest first.
+	Attempts []ReconciliationAttempt `json:"attempts,omitempty"`
+}
+
+// ReconciliationAttempt is one reconcile attempt of the Application's
+// HelmRelease.
+type ReconciliationAttempt struct {
+	// StartTime is when the attempt began.
+	StartTime metav1.Time `json:"startTime"`
+	// CompletionTime is when the outcome was observed; unset while the
+	// attempt is in progress.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Duration is CompletionTime minus StartTime, e.g. 1m30s.
+	Duration string `json:"duration,omitempty"`
+	// Outcome is InProgress, Succeeded or Failed.
+	Outcome string `json:"outcome"`
+	// Reason and Message explain the outcome; Message carries the failure.
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Action is the Helm action attempted: install or upgrade.
+	Action string `json:"action,omitempty"`
+	// ChartVersion is the chart version attempted.
+	ChartVersion string `json:"chartVersion,omitempty"`
+	// Shard is the helm-controller shard that handled the attempt; empty for
+	// the unsharded one.
+	Shard string `json:"shard,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type ReconciliationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Reconciliation `json:"items"`
+}
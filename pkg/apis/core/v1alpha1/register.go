@@ -63,7 +63,13 @@ func RegisterStaticTypes(scheme *runtime.Scheme) {
 		&TenantModuleList{},
 		&Option{},
 		&OptionList{},
+		&TenantQuota{},
+		&TenantQuotaList{},
+		&UsageReport{},
+		&UsageReportList{},
+		&Reconciliation{},
+		&ReconciliationList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
-	klog.V(1).Info("Registered static kinds: TenantNamespace, TenantSecret, TenantModule, Option")
+	klog.V(1).Info("Registered static kinds: TenantNamespace, TenantSecret, TenantModule, Option, TenantQuota, UsageReport, Reconciliation")
 }
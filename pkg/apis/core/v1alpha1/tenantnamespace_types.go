@@ -13,11 +13,36 @@ import (
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
-// TenantNamespace is a thin wrapper around ObjectMeta.  It has no spec/status
-// because it merely reflects an existing Namespace object.
+// TenantNamespace is a thin wrapper around ObjectMeta.  It has no spec
+// because it merely reflects an existing Namespace object; its status is
+// read from the lifecycle label and annotations on that Namespace.
 type TenantNamespace struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status TenantNamespaceStatus `json:"status,omitempty"`
+}
+
+// TenantNamespaceStatus reports the tenant's lifecycle.
+type TenantNamespaceStatus struct {
+	// Lifecycle is the state the tenant declares: active, suspended,
+	// archived or deleting.
+	// +optional
+	Lifecycle string `json:"lifecycle,omitempty"`
+
+	// Phase is what the lifecycle controller has brought the namespace to:
+	// Active, Suspended, Archiving, Archived or PendingDeletion. It also
+	// follows a state declared by an ancestor tenant.
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Message explains the phase, e.g. the applications an archive kept.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// DeletionAt is when a deleting tenant will be deleted.
+	// +optional
+	DeletionAt *metav1.Time `json:"deletionAt,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2026 The Cozystack Authors.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// TenantQuota is a read-only, virtual resource that explains the
+// hierarchical quota of one tenant namespace. metadata.name is the
+// namespace; the status is computed on read from the tenant HelmReleases
+// and ResourceQuotas, the same inputs the tenant quota controller uses to
+// write the tenant-quota-allocated ResourceQuota.
+type TenantQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status TenantQuotaStatus `json:"status,omitempty"`
+}
+
+// TenantQuotaStatus describes the pool governing a tenant and the limit
+// that results from it.
+type TenantQuotaStatus struct {
+	// Parent is the parent tenant namespace; empty for tenant-root.
+	Parent string `json:"parent,omitempty"`
+	// PoolRoot is the nearest tenant, this one included, that declares a
+	// budget. Empty when no ancestor is bounded.
+	PoolRoot string `json:"poolRoot,omitempty"`
+	// Declared is this tenant's own budget (the tenant-quota ResourceQuota).
+	Declared corev1.ResourceList `json:"declared,omitempty"`
+	// Budget is the PoolRoot's declared budget.
+	Budget corev1.ResourceList `json:"budget,omitempty"`
+	// CarvedOut is the sum of the Allocations.
+	CarvedOut corev1.ResourceList `json:"carvedOut,omitempty"`
+	// Available is Budget minus CarvedOut, shared by the Members.
+	Available corev1.ResourceList `json:"available,omitempty"`
+	// Allocations are the bounded tenants whose budgets are carved out of
+	// the pool.
+	Allocations []TenantQuotaAllocation `json:"allocations,omitempty"`
+	// Members are the namespaces sharing Available.
+	Members []string `json:"members,omitempty"`
+	// Used is the namespace's own usage.
+	Used corev1.ResourceList `json:"used,omitempty"`
+	// SubtreeUsed is Used plus the usage of every descendant tenant.
+	SubtreeUsed corev1.ResourceList `json:"subtreeUsed,omitempty"`
+	// Hard is the limit Kubernetes enforces in the namespace: the
+	// per-resource minimum over all of its ResourceQuotas.
+	Hard corev1.ResourceList `json:"hard,omitempty"`
+	// Overcommitted is how far the pool's allocations exceed its budget.
+	Overcommitted corev1.ResourceList `json:"overcommitted,omitempty"`
+	// Children are the direct child tenant namespaces.
+	Children []string `json:"children,omitempty"`
+}
+
+// TenantQuotaAllocation is a bounded tenant's slice of a pool.
+type TenantQuotaAllocation struct {
+	Namespace string              `json:"namespace"`
+	Declared  corev1.ResourceList `json:"declared,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type TenantQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TenantQuota `json:"items"`
+}
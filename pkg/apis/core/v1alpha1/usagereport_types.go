@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2026 The Cozystack Authors.
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// UsageReportHourly breaks one UTC day down by hour; such reports are
+	// named hourly-<YYYY-MM-DD>.
+	UsageReportHourly = "Hourly"
+	// UsageReportDaily breaks one UTC month down by day; such reports are
+	// named daily-<YYYY-MM>.
+	UsageReportDaily = "Daily"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// UsageReport is a read-only, virtual resource that reports the metered
+// usage of a tenant namespace and every tenant below it, priced from the
+// PriceList named "default". It is computed on read from the UsageRollups
+// the metering controller writes; metadata.name selects the period.
+type UsageReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status UsageReportStatus `json:"status,omitempty"`
+}
+
+// UsageReportStatus is the report itself. A list returns the available
+// reports with the period only; rows are computed by get.
+type UsageReportStatus struct {
+	// Granularity is Hourly or Daily.
+	Granularity string `json:"granularity"`
+	// Start is the beginning of the period.
+	Start metav1.Time `json:"start"`
+	// End is the end of the period, exclusive.
+	End metav1.Time `json:"end"`
+	// Currency is the currency of every cost, from the PriceList.
+	Currency string `json:"currency,omitempty"`
+	// Rows holds one entry per interval, namespace, Application and
+	// resource with non-zero usage.
+	Rows []UsageReportRow `json:"rows,omitempty"`
+	// TotalCost is the sum of the row costs, as a decimal with four
+	// fractional digits.
+	TotalCost string `json:"totalCost,omitempty"`
+	// CSV is Rows rendered as comma-separated values with a header line,
+	// for spreadsheets and billing imports.
+	CSV string `json:"csv,omitempty"`
+}
+
+// UsageReportRow is the usage of one resource by one Application over one
+// interval of the report.
+type UsageReportRow struct {
+	// Start is the beginning of the hour or day.
+	Start metav1.Time `json:"start"`
+	// Namespace is the tenant namespace the usage was metered in.
+	Namespace string `json:"namespace"`
+	// Kind and Name identify the Application; both are empty for Workloads
+	// that belong to none.
+	Kind string `json:"kind,omitempty"`
+	Name string `json:"name,omitempty"`
+	// Resource is the Workload resource name, e.g. cpu.
+	Resource string `json:"resource"`
+	// Quantity is the usage in resource-hours.
+	Quantity resource.Quantity `json:"quantity"`
+	// Cost is Quantity times the hourly price, as a decimal with four
+	// fractional digits; empty when the resource has no price.
+	Cost string `json:"cost,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type UsageReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []UsageReport `json:"items"`
+}
@@ -22,6 +22,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -123,6 +124,115 @@ func (in *OptionSpec) DeepCopy() *OptionSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Reconciliation) DeepCopyInto(out *Reconciliation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Reconciliation.
+func (in *Reconciliation) DeepCopy() *Reconciliation {
+	if in == nil {
+		return nil
+	}
+	out := new(Reconciliation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Reconciliation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReconciliationAttempt) DeepCopyInto(out *ReconciliationAttempt) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReconciliationAttempt.
+func (in *ReconciliationAttempt) DeepCopy() *ReconciliationAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(ReconciliationAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReconciliationList) DeepCopyInto(out *ReconciliationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Reconciliation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReconciliationList.
+func (in *ReconciliationList) DeepCopy() *ReconciliationList {
+	if in == nil {
+		return nil
+	}
+	out := new(ReconciliationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReconciliationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReconciliationStatus) DeepCopyInto(out *ReconciliationStatus) {
+	*out = *in
+	if in.InProgress != nil {
+		in, out := &in.InProgress, &out.InProgress
+		*out = new(ReconciliationAttempt)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Attempts != nil {
+		in, out := &in.Attempts, &out.Attempts
+		*out = make([]ReconciliationAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReconciliationStatus.
+func (in *ReconciliationStatus) DeepCopy() *ReconciliationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReconciliationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TenantModule) DeepCopyInto(out *TenantModule) {
 	*out = *in
@@ -211,6 +321,7 @@ func (in *TenantNamespace) DeepCopyInto(out *TenantNamespace) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -265,6 +376,198 @@ func (in *TenantNamespaceList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantNamespaceStatus) DeepCopyInto(out *TenantNamespaceStatus) {
+	*out = *in
+	if in.DeletionAt != nil {
+		in, out := &in.DeletionAt, &out.DeletionAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantNamespaceStatus.
+func (in *TenantNamespaceStatus) DeepCopy() *TenantNamespaceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantNamespaceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantQuota) DeepCopyInto(out *TenantQuota) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantQuota.
+func (in *TenantQuota) DeepCopy() *TenantQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TenantQuota) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantQuotaAllocation) DeepCopyInto(out *TenantQuotaAllocation) {
+	*out = *in
+	if in.Declared != nil {
+		in, out := &in.Declared, &out.Declared
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantQuotaAllocation.
+func (in *TenantQuotaAllocation) DeepCopy() *TenantQuotaAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantQuotaAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantQuotaList) DeepCopyInto(out *TenantQuotaList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TenantQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantQuotaList.
+func (in *TenantQuotaList) DeepCopy() *TenantQuotaList {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantQuotaList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TenantQuotaList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantQuotaStatus) DeepCopyInto(out *TenantQuotaStatus) {
+	*out = *in
+	if in.Declared != nil {
+		in, out := &in.Declared, &out.Declared
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Budget != nil {
+		in, out := &in.Budget, &out.Budget
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.CarvedOut != nil {
+		in, out := &in.CarvedOut, &out.CarvedOut
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Available != nil {
+		in, out := &in.Available, &out.Available
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Allocations != nil {
+		in, out := &in.Allocations, &out.Allocations
+		*out = make([]TenantQuotaAllocation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Used != nil {
+		in, out := &in.Used, &out.Used
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.SubtreeUsed != nil {
+		in, out := &in.SubtreeUsed, &out.SubtreeUsed
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Hard != nil {
+		in, out := &in.Hard, &out.Hard
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Overcommitted != nil {
+		in, out := &in.Overcommitted, &out.Overcommitted
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Children != nil {
+		in, out := &in.Children, &out.Children
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantQuotaStatus.
+func (in *TenantQuotaStatus) DeepCopy() *TenantQuotaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantQuotaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TenantSecret) DeepCopyInto(out *TenantSecret) {
 	*out = *in
@@ -345,3 +648,106 @@ func (in *TenantSecretList) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UsageReport) DeepCopyInto(out *UsageReport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UsageReport.
+func (in *UsageReport) DeepCopy() *UsageReport {
+	if in == nil {
+		return nil
+	}
+	out := new(UsageReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UsageReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UsageReportList) DeepCopyInto(out *UsageReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]UsageReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UsageReportList.
+func (in *UsageReportList) DeepCopy() *UsageReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(UsageReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UsageReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UsageReportRow) DeepCopyInto(out *UsageReportRow) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	out.Quantity = in.Quantity.DeepCopy()
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UsageReportRow.
+func (in *UsageReportRow) DeepCopy() *UsageReportRow {
+	if in == nil {
+		return nil
+	}
+	out := new(UsageReportRow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UsageReportStatus) DeepCopyInto(out *UsageReportStatus) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+	if in.Rows != nil {
+		in, out := &in.Rows, &out.Rows
+		*out = make([]UsageReportRow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UsageReportStatus.
+func (in *UsageReportStatus) DeepCopy() *UsageReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UsageReportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
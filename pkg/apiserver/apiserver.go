@@ -21,10 +21,12 @@ import (
 	"fmt"
 	"time"
 
+	backupsv1alpha1 "github.com/cozystack/cozystack/api/backups/v1alpha1"
 	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
 	helmv2 "github.com/fluxcd/helm-controller/api/v2"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -48,9 +50,12 @@ import (
 	cozyregistry "github.com/cozystack/cozystack/pkg/registry"
 	applicationstorage "github.com/cozystack/cozystack/pkg/registry/apps/application"
 	optionstorage "github.com/cozystack/cozystack/pkg/registry/core/option"
+	reconciliationstorage "github.com/cozystack/cozystack/pkg/registry/core/reconciliation"
 	tenantmodulestorage "github.com/cozystack/cozystack/pkg/registry/core/tenantmodule"
 	tenantnamespacestorage "github.com/cozystack/cozystack/pkg/registry/core/tenantnamespace"
+	tenantquotastorage "github.com/cozystack/cozystack/pkg/registry/core/tenantquota"
 	tenantsecretstorage "github.com/cozystack/cozystack/pkg/registry/core/tenantsecret"
+	usagereportstorage "github.com/cozystack/cozystack/pkg/registry/core/usagereport"
 	securitygroupstorage "github.com/cozystack/cozystack/pkg/registry/sdn/securitygroup"
 )
 
@@ -96,6 +101,15 @@ func init() {
 	if err := cozyv1alpha1.AddToScheme(mgrScheme); err != nil {
 		panic(fmt.Errorf("failed to add Cozystack types to scheme: %w", err))
 	}
+	// Backups are read (uncached) to account backup storage against tenant quotas.
+	if err := backupsv1alpha1.AddToScheme(mgrScheme); err != nil {
+		panic(fmt.Errorf("failed to add backup types to scheme: %w", err))
+	}
+	// StorageClasses are read (uncached) to find the default class that
+	// per-class storage quotas charge unclassed volumes to.
+	if err := storagev1.AddToScheme(mgrScheme); err != nil {
+		panic(fmt.Errorf("failed to add storage types to scheme: %w", err))
+	}
 	// Add unversioned types.
 	metav1.AddToGroupVersion(Scheme, schema.GroupVersion{Version: "v1"})
 
@@ -184,6 +198,7 @@ func (c completedConfig) New() (*CozyServer, error) {
 		&corev1.Secret{},
 		&corev1.Namespace{},
 		&corev1.Service{},
+		&corev1.ResourceQuota{},
 		&rbacv1.RoleBinding{},
 		&cozyv1alpha1.WorkloadMonitor{},
 	); err != nil {
@@ -223,6 +238,15 @@ func (c completedConfig) New() (*CozyServer, error) {
 	coreV1alpha1Storage["tenantmodules"] = cozyregistry.RESTInPeace(
 		tenantmodulestorage.NewREST(cli, watchCli),
 	)
+	coreV1alpha1Storage["tenantquotas"] = cozyregistry.RESTInPeace(
+		tenantquotastorage.NewREST(cli, watchCli, mgr.GetCache()),
+	)
+	coreV1alpha1Storage["usagereports"] = cozyregistry.RESTInPeace(
+		usagereportstorage.NewREST(cli, watchCli),
+	)
+	coreV1alpha1Storage["reconciliations"] = cozyregistry.RESTInPeace(
+		reconciliationstorage.NewREST(cli, watchCli),
+	)
 	coreV1alpha1Storage["options"] = cozyregistry.RESTInPeace(
 		optionstorage.NewREST(optionstorage.DefaultProviders(dyn)),
 	)
@@ -0,0 +1,60 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/cozystack/cozystack/internal/controller/tenantmigration"
+	"github.com/cozystack/cozystack/pkg/tenantstate"
+)
+
+// checkTenantLifecycle refuses a new application in a tenant that is
+// suspended, archived or being deleted, or whose ancestor is, and in one
+// being moved by a TenantMigration, which would leave it behind. The state is
+// read from the namespace labels directly rather than from what the
+// lifecycle controller has reported, so there is no window in which a
+// freshly suspended tenant still takes new applications.
+func (r *REST) checkTenantLifecycle(ctx context.Context, namespace, name string) error {
+	labels := map[string]map[string]string{}
+	for _, ns := range tenantstate.Lineage(namespace) {
+		obj := &corev1.Namespace{}
+		err := r.c.Get(ctx, client.ObjectKey{Name: ns}, obj)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return apierrors.NewInternalError(fmt.Errorf("read lifecycle of %s: %w", ns, err))
+		}
+		labels[ns] = obj.Labels
+		if migration := obj.Annotations[tenantmigration.MigrationAnnotation]; ns == namespace && migration != "" {
+			return apierrors.NewForbidden(r.gvr.GroupResource(), name,
+				fmt.Errorf("tenant %s is being moved by TenantMigration %s; create the application once it has finished", ns, migration))
+		}
+	}
+	state, from := tenantstate.Effective(namespace, func(ns string) map[string]string { return labels[ns] })
+	if state == tenantstate.Active {
+		return nil
+	}
+	return apierrors.NewForbidden(r.gvr.GroupResource(), name,
+		fmt.Errorf("tenant %s is %s; new applications cannot be created until it is active again", from, state))
+}
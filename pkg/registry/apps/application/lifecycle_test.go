@@ -0,0 +1,52 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCreate_RefusedInInactiveTenant(t *testing.T) {
+	r := newAppREST(t, "Redis",
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-foo", Labels: map[string]string{
+			"namespace.cozystack.io/lifecycle": "suspended",
+		}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-foo-bar"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-other"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-moving", Annotations: map[string]string{
+			"namespace.cozystack.io/migration": "move-it",
+		}}},
+	)
+	ctx := context.Background()
+
+	_, err := r.Create(ctx, appWithValues(t, "cache", "tenant-foo-bar", map[string]any{}), nil, &metav1.CreateOptions{})
+	if !apierrors.IsForbidden(err) || !strings.Contains(err.Error(), "tenant tenant-foo is suspended") {
+		t.Fatalf("Create under a suspended parent: err = %v, want Forbidden naming tenant-foo", err)
+	}
+	if err := r.checkTenantLifecycle(ctx, "tenant-moving", "cache"); !apierrors.IsForbidden(err) || !strings.Contains(err.Error(), "TenantMigration move-it") {
+		t.Errorf("tenant being migrated: err = %v, want Forbidden naming the migration", err)
+	}
+	if err := r.checkTenantLifecycle(ctx, "tenant-other", "cache"); err != nil {
+		t.Errorf("active tenant: err = %v, want nil", err)
+	}
+}
@@ -221,7 +221,7 @@ func (r *REST) validateTenantResourceQuotas(ctx context.Context, app *appsv1alph
 	// already-running pods are never evicted) — the tenant-quota controller is
 	// the runtime backstop.
 	childNamespace := r.computeTenantNamespace(app.Namespace, app.Name)
-	poolUsed, err := r.parentPoolUsage(ctx, app.Namespace, childNamespace)
+	poolUsed, err := r.parentPoolUsage(ctx, app.Namespace, childNamespace, sortedQuotaKeys(childQuota))
 	if err != nil {
 		return append(allErrs, field.InternalError(fldPath, err))
 	}
@@ -294,8 +294,11 @@ var rawQuotaKeys = map[string]struct{}{
 // rendered ResourceQuota.status.used without replicating the chart's ratio math.
 //
 //	cpu, memory, ephemeral-storage, custom resources -> "limits.<key>"
+//	extended resources (nvidia.com/<device>, ...)    -> "requests.<key>" (quota tracks no limits for them)
 //	storage                                          -> "requests.storage" (no hard limits.storage)
 //	pods, services, services.loadbalancers, ...      -> "<key>" (verbatim)
+//	<class>.storageclass.storage.k8s.io/<resource>   -> "<key>" (verbatim)
+//	platform keys (external-ips, buckets.storage...) -> "<key>" (never rendered, see poolUsage)
 func renderedLimitKey(raw string) string {
 	if raw == "storage" {
 		return "requests.storage"
@@ -303,16 +306,31 @@ func renderedLimitKey(raw string) string {
 	if _, ok := rawQuotaKeys[raw]; ok {
 		return raw
 	}
+	if isPlatformQuotaKey(raw) || strings.Contains(raw, storageClassQuotaInfix) {
+		return raw
+	}
+	if strings.Contains(raw, "/") {
+		return "requests." + raw
+	}
 	return "limits." + raw
 }
 
+// storageClassQuotaInfix marks the per-StorageClass quota keys, e.g.
+// "fast.storageclass.storage.k8s.io/requests.storage", which ResourceQuota
+// takes verbatim.
+const storageClassQuotaInfix = ".storageclass.storage.k8s.io/"
+
+// tenantNamespacePrefix is the prefix every tenant namespace, and every tenant
+// HelmRelease, carries (see computeTenantNamespace).
+const tenantNamespacePrefix = "tenant-"
+
 // parentNamespaceOf returns the namespace owned by the parent of the tenant that
 // owns ns, or "" for the root tenant and non-tenant namespaces. It mirrors
 // tenantquota.parentNamespace / the inverse of computeTenantNamespace: the
 // hierarchy is encoded in the namespace name, so the parent is recovered by
 // stripping the trailing "-<name>" segment.
-func (r *REST) parentNamespaceOf(ns string) string {
-	if ns == rootTenantNamespace || !strings.HasPrefix(ns, r.releaseConfig.Prefix) {
+func parentNamespaceOf(ns string) string {
+	if ns == rootTenantNamespace || !strings.HasPrefix(ns, tenantNamespacePrefix) {
 		return ""
 	}
 	segments := strings.Split(ns, "-")
@@ -325,8 +343,8 @@ func (r *REST) parentNamespaceOf(ns string) string {
 // poolRootOf returns the nearest ancestor namespace (inclusive of ns) that is
 // bounded — declares its own quota — or "" when none is. That ancestor is the
 // pool root governing ns.
-func (r *REST) poolRootOf(ns string, bounded map[string]bool) string {
-	for cur := ns; cur != ""; cur = r.parentNamespaceOf(cur) {
+func poolRootOf(ns string, bounded map[string]bool) string {
+	for cur := ns; cur != ""; cur = parentNamespaceOf(cur) {
 		if bounded[cur] {
 			return cur
 		}
@@ -334,73 +352,118 @@ func (r *REST) poolRootOf(ns string, bounded map[string]bool) string {
 	return ""
 }
 
-// parentPoolUsage sums the current usage of the pool rooted at parentNS: the
-// parent's own namespace plus every unbounded descendant whose nearest bounded
-// ancestor is parentNS. Bounded sub-tenants are excluded — their reservations
-// are already charged as sibling carve-outs — as is excludeNS (the tenant being
-// created/updated). Usage is read from each member's ResourceQuota.status.used,
-// taking a per-resource max within a namespace to avoid double-counting the
-// chart-rendered and controller-allocated quotas, and is keyed in the rendered
-// quota key space (see renderedLimitKey). Boundedness is decided by declared
-// resourceQuotas so the result does not depend on whether Flux has rendered a
-// freshly-created tenant's quota yet.
-func (r *REST) parentPoolUsage(ctx context.Context, parentNS, excludeNS string) (map[string]resource.Quantity, error) {
+// tenantDeclaredQuotas reads every tenant HelmRelease and returns its declared
+// resourceQuotas keyed by the namespace the tenant owns. Unbounded tenants are
+// present with an empty map. It selects on the Tenant kind explicitly, so it
+// works from the REST handler of any Application kind.
+func (r *REST) tenantDeclaredQuotas(ctx context.Context) (map[string]map[string]resource.Quantity, error) {
 	releases := &helmv2.HelmReleaseList{}
 	selector := labels.SelectorFromSet(labels.Set{
-		ApplicationKindLabel:  r.kindName,
+		ApplicationKindLabel:  validation.TenantKind,
 		ApplicationGroupLabel: r.gvk.Group,
 	})
 	if err := r.c.List(ctx, releases, &client.ListOptions{LabelSelector: selector}); err != nil {
 		return nil, err
 	}
 
-	bounded := map[string]bool{}
-	owned := make([]string, 0, len(releases.Items))
+	out := make(map[string]map[string]resource.Quantity, len(releases.Items))
 	for i := range releases.Items {
 		hr := &releases.Items[i]
-		name := strings.TrimPrefix(hr.Name, r.releaseConfig.Prefix)
+		name := strings.TrimPrefix(hr.Name, tenantNamespacePrefix)
 		ns := r.computeTenantNamespace(hr.Namespace, name)
-		owned = append(owned, ns)
 		quotas, err := declaredQuotasFromHelmRelease(hr)
 		if err != nil {
 			klog.Warningf("skipping tenant %s/%s with unparseable quotas while summing pool usage: %v", hr.Namespace, hr.Name, err)
-			continue
+			quotas = nil
 		}
+		out[ns] = quotas
+	}
+	return out, nil
+}
+
+// boundedNamespaces is the set of tenant namespaces that declare a quota.
+// Boundedness is decided by declared resourceQuotas so the result does not
+// depend on whether Flux has rendered a freshly-created tenant's quota yet.
+func boundedNamespaces(tenants map[string]map[string]resource.Quantity) map[string]bool {
+	bounded := map[string]bool{}
+	for ns, quotas := range tenants {
 		if len(quotas) > 0 {
 			bounded[ns] = true
 		}
 	}
+	return bounded
+}
 
-	members := map[string]bool{parentNS: true}
-	for _, ns := range owned {
-		if ns == excludeNS {
+// poolMembers returns the namespaces drawing on the pool rooted at root: root
+// itself plus every unbounded descendant whose nearest bounded ancestor is
+// root. Bounded sub-tenants are excluded — their reservations are charged as
+// carve-outs — as is excludeNS.
+func poolMembers(root, excludeNS string, tenants map[string]map[string]resource.Quantity, bounded map[string]bool) []string {
+	members := []string{}
+	if root != excludeNS {
+		members = append(members, root)
+	}
+	for ns := range tenants {
+		if ns == root || ns == excludeNS {
 			continue
 		}
-		if r.poolRootOf(ns, bounded) == parentNS {
-			members[ns] = true
+		if poolRootOf(ns, bounded) == root {
+			members = append(members, ns)
 		}
 	}
-	delete(members, excludeNS)
+	sort.Strings(members)
+	return members
+}
 
-	perNamespace := map[string]map[string]resource.Quantity{}
-	for ns := range members {
+// parentPoolUsage sums the current usage of the pool rooted at parentNS (see
+// poolMembers), excluding excludeNS (the tenant being created/updated). keys are
+// the shorthand resourceQuotas keys the caller compares; platform keys among
+// them are measured from their own sources (see poolUsage).
+func (r *REST) parentPoolUsage(ctx context.Context, parentNS, excludeNS string, keys []string) (map[string]resource.Quantity, error) {
+	tenants, err := r.tenantDeclaredQuotas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.poolUsage(ctx, poolMembers(parentNS, excludeNS, tenants, boundedNamespaces(tenants)), keys)
+}
+
+// poolUsage sums the current usage of members. Usage is read from each
+// member's ResourceQuota.status.used, taking a per-resource max within a
+// namespace to avoid double-counting the chart-rendered and
+// controller-allocated quotas, and is keyed in the rendered quota key space
+// (see renderedLimitKey). Platform keys among keys, which no ResourceQuota
+// tracks, are added from platformUsage under their own names.
+func (r *REST) poolUsage(ctx context.Context, members []string, keys []string) (map[string]resource.Quantity, error) {
+	platform := map[string]struct{}{}
+	for _, k := range keys {
+		if isPlatformQuotaKey(k) {
+			platform[k] = struct{}{}
+		}
+	}
+
+	total := map[string]resource.Quantity{}
+	for _, ns := range members {
 		quotas := &corev1.ResourceQuotaList{}
-		// Direct (uncached), namespace-scoped read: the aggregated apiserver
-		// must not spin up a cluster-wide ResourceQuota informer just for
-		// admission, so this goes through the watch client (r.w) rather than the
-		// cached r.c, and is scoped to the handful of pool-member namespaces.
+		// Direct (uncached), namespace-scoped read: admission carves an
+		// allocation out of what is left, so it reads usage through the watch
+		// client (r.w) rather than the cached r.c, which can lag behind the
+		// quota controller, and only for the handful of pool-member namespaces.
 		if err := r.w.List(ctx, quotas, client.InNamespace(ns)); err != nil {
 			return nil, err
 		}
+		var used map[string]resource.Quantity
 		for i := range quotas.Items {
-			rq := &quotas.Items[i]
-			perNamespace[ns] = maxQuotas(perNamespace[ns], resourceListToQuotas(rq.Status.Used))
+			used = maxQuotas(used, resourceListToQuotas(quotas.Items[i].Status.Used))
 		}
-	}
-
-	total := map[string]resource.Quantity{}
-	for _, used := range perNamespace {
 		addQuotas(total, used)
+
+		if len(platform) > 0 {
+			extra, err := r.platformUsage(ctx, ns, platform)
+			if err != nil {
+				return nil, err
+			}
+			addQuotas(total, extra)
+		}
 	}
 	return total, nil
 }
@@ -0,0 +1,380 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	quotatree "github.com/cozystack/cozystack/internal/controller/tenantquota"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+	"github.com/cozystack/cozystack/pkg/apis/apps/validation"
+)
+
+// Platform quota keys are resourceQuotas entries Kubernetes ResourceQuota has
+// no notion of (see quotatree.ExternalIPs). The tenant chart leaves them out of
+// the rendered quota, so they are never enforced at runtime by the
+// kube-apiserver; the budget is held at Application admission instead. Their
+// usage is measured by quotatree.ReadPlatformUsage, the same figures the
+// TenantQuota view and the tenant quota controller roll up through the pools.
+const (
+	quotaExternalIPs   = string(quotatree.ExternalIPs)
+	quotaBucketStorage = string(quotatree.BucketStorage)
+	quotaBackupStorage = string(quotatree.BackupStorage)
+)
+
+// bucketKind is the Application kind whose instances consume bucket storage.
+const bucketKind = "Bucket"
+
+// defaultStorageClassAnnotation marks the StorageClass volumes without a
+// class are provisioned from.
+const defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+func isPlatformQuotaKey(key string) bool {
+	return quotatree.IsPlatformResource(corev1.ResourceName(key))
+}
+
+// platformUsage measures the requested platform keys in one namespace.
+func (r *REST) platformUsage(ctx context.Context, namespace string, keys map[string]struct{}) (map[string]resource.Quantity, error) {
+	names := make([]corev1.ResourceName, 0, len(keys))
+	for k := range keys {
+		names = append(names, corev1.ResourceName(k))
+	}
+	usage, err := quotatree.ReadPlatformUsage(ctx, r.w, namespace, names)
+	if err != nil {
+		return nil, fmt.Errorf("measure platform usage in %s: %w", namespace, err)
+	}
+	out := map[string]resource.Quantity{}
+	for k := range keys {
+		out[k] = usage[namespace][corev1.ResourceName(k)]
+	}
+	return out, nil
+}
+
+// quotaDemand is what admitting an Application adds to one quota key. An
+// unsized backup demand (the application declares no storage size) is
+// refused only when the pool has nothing left; an unsized bucket is refused
+// wherever bucket storage is limited. A defaultClass demand is
+// per-StorageClass storage of volumes that name no class; its key is filled
+// in once the cluster default class is known.
+type quotaDemand struct {
+	key          string
+	amount       resource.Quantity
+	unsized      bool
+	defaultClass bool
+	path         *field.Path
+}
+
+// demandSpec is the projection of Application values the demand estimate
+// reads. These field names are shared across the catalog charts: size,
+// replicas and storageClass describe the data volumes of an application, or
+// the capacity a Bucket reserves.
+type demandSpec struct {
+	External bool `json:"external"`
+	GPUs     []struct {
+		Name string `json:"name"`
+	} `json:"gpus"`
+	Backup struct {
+		Enabled bool `json:"enabled"`
+	} `json:"backup"`
+	Size         *resource.Quantity `json:"size"`
+	Replicas     *int64             `json:"replicas"`
+	StorageClass string             `json:"storageClass"`
+}
+
+func parseDemandSpec(app *appsv1alpha1.Application) (demandSpec, error) {
+	var spec demandSpec
+	if app != nil && app.Spec != nil && len(app.Spec.Raw) > 0 {
+		if err := json.Unmarshal(app.Spec.Raw, &spec); err != nil {
+			return spec, err
+		}
+	}
+	return spec, nil
+}
+
+// volumeStorage is the storage the data volumes of spec request: one volume
+// of size per replica. Charts with further volumes (shards, per-component
+// sizes) request at least this much.
+func (s demandSpec) volumeStorage() resource.Quantity {
+	if s.Size == nil {
+		return resource.Quantity{}
+	}
+	replicas := int64(1)
+	if s.Replicas != nil {
+		replicas = *s.Replicas
+	}
+	return *resource.NewQuantity(s.Size.Value()*replicas, resource.BinarySI)
+}
+
+// backupStorage estimates what the backups of spec hold: one copy of the
+// data of a replica. ok is false when backups are on but no size is known.
+func (s demandSpec) backupStorage() (q resource.Quantity, ok bool) {
+	if !s.Backup.Enabled {
+		return resource.Quantity{}, true
+	}
+	if s.Size == nil {
+		return resource.Quantity{}, false
+	}
+	return *resource.NewQuantity(s.Size.Value(), resource.BinarySI), true
+}
+
+// growth returns cur-prev when positive.
+func growth(cur, prev resource.Quantity) (resource.Quantity, bool) {
+	d := cur.DeepCopy()
+	d.Sub(prev)
+	return d, d.Sign() > 0
+}
+
+// storageClassQuotaKey is the per-StorageClass key ResourceQuota holds the
+// storage requested from class under.
+func storageClassQuotaKey(class string) string {
+	return class + storageClassQuotaInfix + "requests.storage"
+}
+
+// applicationQuotaDemand estimates what the write of app (an update when old
+// is non-nil) newly consumes. Only growth counts, so an unrelated edit to an
+// application already holding an external IP or a GPU is never refused.
+func applicationQuotaDemand(kind string, app, old *appsv1alpha1.Application) ([]quotaDemand, error) {
+	spec := field.NewPath("spec")
+	cur, err := parseDemandSpec(app)
+	if err != nil {
+		return nil, err
+	}
+	// The stored object was admitted and is not re-judged: an unreadable
+	// one counts as demanding nothing.
+	prev, _ := parseDemandSpec(old)
+	var out []quotaDemand
+
+	if cur.External && !prev.External {
+		out = append(out, quotaDemand{key: quotaExternalIPs, amount: *resource.NewQuantity(1, resource.DecimalSI), path: spec.Child("external")})
+	}
+
+	gpus := map[string]int64{}
+	for _, g := range cur.GPUs {
+		if g.Name != "" {
+			gpus[g.Name]++
+		}
+	}
+	for _, g := range prev.GPUs {
+		if _, ok := gpus[g.Name]; ok {
+			gpus[g.Name]--
+		}
+	}
+	names := make([]string, 0, len(gpus))
+	for name, n := range gpus {
+		if n > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out = append(out, quotaDemand{key: name, amount: *resource.NewQuantity(gpus[name], resource.DecimalSI), path: spec.Child("gpus")})
+	}
+
+	if kind == bucketKind {
+		// A bucket has no volumes: its size is the capacity it reserves.
+		var prevSize resource.Quantity
+		if prev.Size != nil {
+			prevSize = *prev.Size
+		}
+		switch {
+		case cur.Size != nil:
+			if d, ok := growth(*cur.Size, prevSize); ok {
+				out = append(out, quotaDemand{key: quotaBucketStorage, amount: d, path: spec.Child("size")})
+			}
+		case old == nil:
+			out = append(out, quotaDemand{key: quotaBucketStorage, unsized: true, path: spec.Child("size")})
+		}
+		return out, nil
+	}
+
+	curStorage, prevStorage := cur.volumeStorage(), prev.volumeStorage()
+	if d, ok := growth(curStorage, prevStorage); ok {
+		out = append(out, quotaDemand{key: "storage", amount: d, path: spec.Child("size")})
+	}
+	// A change of class moves every volume, so only an unchanged class
+	// offsets what the previous spec requested from it.
+	if cur.StorageClass != prev.StorageClass {
+		prevStorage = resource.Quantity{}
+	}
+	if d, ok := growth(curStorage, prevStorage); ok {
+		demand := quotaDemand{amount: d, path: spec.Child("storageClass")}
+		if cur.StorageClass == "" {
+			demand.defaultClass = true
+		} else {
+			demand.key = storageClassQuotaKey(cur.StorageClass)
+		}
+		out = append(out, demand)
+	}
+
+	curBackup, sized := cur.backupStorage()
+	prevBackup, _ := prev.backupStorage()
+	switch {
+	case !sized:
+		if !prev.Backup.Enabled {
+			out = append(out, quotaDemand{key: quotaBackupStorage, unsized: true, path: spec.Child("backup", "enabled")})
+		}
+	default:
+		if d, ok := growth(curBackup, prevBackup); ok {
+			out = append(out, quotaDemand{key: quotaBackupStorage, amount: d, path: spec.Child("backup", "enabled")})
+		}
+	}
+	return out, nil
+}
+
+// defaultStorageClass returns the name of the cluster default StorageClass,
+// "" when none is marked. Volumes without a class are bound to it, and
+// ResourceQuota counts them under its per-class key.
+func (r *REST) defaultStorageClass(ctx context.Context) (string, error) {
+	classes := &storagev1.StorageClassList{}
+	if err := r.w.List(ctx, classes); err != nil {
+		return "", fmt.Errorf("list storage classes: %w", err)
+	}
+	// Like the DefaultStorageClass admission plugin, the most recently
+	// created of several defaults wins.
+	var best *storagev1.StorageClass
+	for i := range classes.Items {
+		sc := &classes.Items[i]
+		if sc.Annotations[defaultStorageClassAnnotation] != "true" {
+			continue
+		}
+		if best == nil || sc.CreationTimestamp.After(best.CreationTimestamp.Time) ||
+			sc.CreationTimestamp.Equal(&best.CreationTimestamp) && sc.Name < best.Name {
+			best = sc
+		}
+	}
+	if best == nil {
+		return "", nil
+	}
+	return best.Name, nil
+}
+
+// validateApplicationQuotas holds a non-tenant Application to the remaining
+// budget of the pool its namespace draws from, for the quota keys admission
+// can foresee: platform keys, which nothing else enforces, and GPU devices,
+// which the kube-apiserver would otherwise only refuse once the workload's
+// pod is created, leaving the Application half-deployed.
+//
+// The pool is the nearest bounded tenant at or above the namespace. What is
+// left of it is its declared budget minus the budgets carved out by bounded
+// sub-tenants and the current usage of every namespace sharing the pool,
+// the same arithmetic validateTenantResourceQuotas applies to a child tenant.
+func (r *REST) validateApplicationQuotas(ctx context.Context, app, old *appsv1alpha1.Application) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if r.kindName == validation.TenantKind {
+		return allErrs
+	}
+	demands, err := applicationQuotaDemand(r.kindName, app, old)
+	if err != nil {
+		return append(allErrs, field.Invalid(field.NewPath("spec"), "", fmt.Sprintf("cannot read values: %v", err)))
+	}
+	if len(demands) == 0 {
+		return allErrs
+	}
+
+	tenants, err := r.tenantDeclaredQuotas(ctx)
+	if err != nil {
+		return append(allErrs, field.InternalError(field.NewPath("spec"), err))
+	}
+	bounded := boundedNamespaces(tenants)
+	root := poolRootOf(app.Namespace, bounded)
+	if root == "" {
+		return allErrs
+	}
+	budget := tenants[root]
+
+	var constrained []quotaDemand
+	for _, d := range demands {
+		if d.defaultClass {
+			if !hasStorageClassQuota(budget) {
+				continue
+			}
+			class, err := r.defaultStorageClass(ctx)
+			if err != nil {
+				return append(allErrs, field.InternalError(field.NewPath("spec"), err))
+			}
+			if class == "" {
+				continue
+			}
+			d.key = storageClassQuotaKey(class)
+		}
+		if _, ok := budget[d.key]; ok {
+			constrained = append(constrained, d)
+		}
+	}
+	if len(constrained) == 0 {
+		return allErrs
+	}
+
+	carved := map[string]resource.Quantity{}
+	for ns, declared := range tenants {
+		if ns != root && bounded[ns] && poolRootOf(parentNamespaceOf(ns), bounded) == root {
+			addQuotas(carved, declared)
+		}
+	}
+	keys := make([]string, 0, len(constrained))
+	for _, d := range constrained {
+		keys = append(keys, d.key)
+	}
+	used, err := r.poolUsage(ctx, poolMembers(root, "", tenants, bounded), keys)
+	if err != nil {
+		return append(allErrs, field.InternalError(field.NewPath("spec"), err))
+	}
+
+	for _, d := range constrained {
+		limit := budget[d.key]
+		remaining := limit.DeepCopy()
+		allocated := carved[d.key]
+		inUse := used[renderedLimitKey(d.key)]
+		remaining.Sub(allocated)
+		remaining.Sub(inUse)
+		if d.unsized && d.key == quotaBucketStorage {
+			allErrs = append(allErrs, field.Required(d.path,
+				fmt.Sprintf("tenant %s limits %q; set the capacity the bucket reserves (%s left)", root, d.key, remaining.String())))
+			continue
+		}
+		if d.unsized && remaining.Sign() > 0 || !d.unsized && d.amount.Cmp(remaining) <= 0 {
+			continue
+		}
+		want := "more"
+		if !d.unsized {
+			want = d.amount.String()
+		}
+		allErrs = append(allErrs, field.Forbidden(d.path,
+			fmt.Sprintf("needs %s %q but tenant %s has %s left (budget %s, %s allocated to sub-tenants, %s in use)",
+				want, d.key, root, remaining.String(), limit.String(), allocated.String(), inUse.String())))
+	}
+	return allErrs
+}
+
+// hasStorageClassQuota reports whether budget limits any StorageClass.
+func hasStorageClassQuota(budget map[string]resource.Quantity) bool {
+	for key := range budget {
+		if strings.Contains(key, storageClassQuotaInfix) {
+			return true
+		}
+	}
+	return false
+}
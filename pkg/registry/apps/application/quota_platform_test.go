@@ -0,0 +1,307 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	backupsv1alpha1 "github.com/cozystack/cozystack/api/backups/v1alpha1"
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	quotatree "github.com/cozystack/cozystack/internal/controller/tenantquota"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+	"github.com/cozystack/cozystack/pkg/config"
+)
+
+// newAppREST wires the REST handler of a non-tenant kind over a fake client
+// that carries every source platform usage is read from.
+func newAppREST(t *testing.T, kind string, objects ...client.Object) *REST {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{
+		helmv2.AddToScheme, corev1.AddToScheme, storagev1.AddToScheme, cozyv1alpha1.AddToScheme, backupsv1alpha1.AddToScheme,
+	} {
+		if err := add(scheme); err != nil {
+			t.Fatalf("register scheme: %v", err)
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).
+		WithIndex(&corev1.Service{}, "spec.type", func(o client.Object) []string {
+			return []string{string(o.(*corev1.Service).Spec.Type)}
+		}).
+		Build()
+	return &REST{
+		c:             c,
+		w:             c,
+		gvk:           schema.GroupVersionKind{Group: appsv1alpha1.GroupName, Version: "v1alpha1", Kind: kind},
+		kindName:      kind,
+		releaseConfig: config.ReleaseConfig{Prefix: strings.ToLower(kind) + "-"},
+	}
+}
+
+func appWithValues(t *testing.T, name, namespace string, values map[string]any) *appsv1alpha1.Application {
+	t.Helper()
+	raw, err := json.Marshal(values)
+	if err != nil {
+		t.Fatalf("marshal values: %v", err)
+	}
+	return &appsv1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       &apiextv1.JSON{Raw: raw},
+	}
+}
+
+func loadBalancer(namespace, name string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+			Ingress: []corev1.LoadBalancerIngress{{IP: "192.0.2.1"}},
+		}},
+	}
+}
+
+func TestApplicationQuotaDemand(t *testing.T) {
+	vm := func(external bool, gpus ...string) *appsv1alpha1.Application {
+		list := []map[string]string{}
+		for _, g := range gpus {
+			list = append(list, map[string]string{"name": g})
+		}
+		return appWithValues(t, "vm", "tenant-foo", map[string]any{"external": external, "gpus": list})
+	}
+
+	demand := func(kind string, app, old *appsv1alpha1.Application) []quotaDemand {
+		t.Helper()
+		got, err := applicationQuotaDemand(kind, app, old)
+		if err != nil {
+			t.Fatalf("demand: %v", err)
+		}
+		return got
+	}
+
+	got := demand("VMInstance", vm(true, "nvidia.com/A10", "nvidia.com/A10"), nil)
+	if len(got) != 2 || got[0].key != quotaExternalIPs || got[1].key != "nvidia.com/A10" || got[1].amount.Value() != 2 {
+		t.Fatalf("create demand = %+v, want one external IP and two A10s", got)
+	}
+	if got := demand("VMInstance", vm(true, "nvidia.com/A10"), vm(true, "nvidia.com/A10")); len(got) != 0 {
+		t.Errorf("unchanged update demand = %+v, want none", got)
+	}
+	if got := demand("VMInstance", vm(true, "nvidia.com/A10", "nvidia.com/A10"), vm(true, "nvidia.com/A10")); len(got) != 1 || got[0].amount.Value() != 1 {
+		t.Errorf("added GPU demand = %+v, want one A10", got)
+	}
+	if got := demand(bucketKind, appWithValues(t, "b", "tenant-foo", nil), nil); len(got) != 1 || got[0].key != quotaBucketStorage || !got[0].unsized {
+		t.Errorf("bucket demand = %+v, want unsized buckets.storage", got)
+	}
+	if got := demand(bucketKind, appWithValues(t, "b", "tenant-foo", map[string]any{"size": "5Gi"}), appWithValues(t, "b", "tenant-foo", map[string]any{"size": "2Gi"})); len(got) != 1 || got[0].key != quotaBucketStorage || got[0].amount.Cmp(resource.MustParse("3Gi")) != 0 {
+		t.Errorf("grown bucket demand = %+v, want 3Gi of buckets.storage", got)
+	}
+
+	db := func(values map[string]any) *appsv1alpha1.Application {
+		return appWithValues(t, "db", "tenant-foo", values)
+	}
+	got = demand("Postgres", db(map[string]any{"size": "10Gi", "replicas": 2, "storageClass": "fast", "backup": map[string]any{"enabled": true}}), nil)
+	want := map[string]string{"storage": "20Gi", "fast.storageclass.storage.k8s.io/requests.storage": "20Gi", quotaBackupStorage: "10Gi"}
+	if len(got) != len(want) {
+		t.Fatalf("database demand = %+v, want %v", got, want)
+	}
+	for _, d := range got {
+		if d.amount.Cmp(resource.MustParse(want[d.key])) != 0 {
+			t.Errorf("demand %s = %s, want %s", d.key, d.amount.String(), want[d.key])
+		}
+	}
+	got = demand("Postgres", db(map[string]any{"size": "10Gi", "replicas": 2, "storageClass": "slow"}), db(map[string]any{"size": "10Gi", "replicas": 2, "storageClass": "fast"}))
+	if len(got) != 1 || got[0].key != "slow.storageclass.storage.k8s.io/requests.storage" || got[0].amount.Cmp(resource.MustParse("20Gi")) != 0 {
+		t.Errorf("class change demand = %+v, want 20Gi of the new class only", got)
+	}
+	if got := demand("Postgres", db(map[string]any{"size": "10Gi"}), nil); len(got) != 2 || !got[1].defaultClass {
+		t.Errorf("unclassed demand = %+v, want storage and a default-class demand", got)
+	}
+	if _, err := applicationQuotaDemand("Postgres", db(map[string]any{"size": "ten gigs"}), nil); err == nil {
+		t.Error("a malformed size must fail the demand estimate")
+	}
+}
+
+func TestValidateApplicationQuotas(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("external IPs roll up through unbounded children", func(t *testing.T) {
+		objects := []client.Object{
+			tenantHelmRelease(t, "foo", "tenant-root", map[string]string{quotaExternalIPs: "2"}),
+			tenantHelmRelease(t, "bar", "tenant-foo", nil),
+			loadBalancer("tenant-foo", "ingress"),
+			loadBalancer("tenant-foo-bar", "db"),
+		}
+		r := newAppREST(t, "VMInstance", objects...)
+		errs := r.validateApplicationQuotas(ctx, appWithValues(t, "vm", "tenant-foo-bar", map[string]any{"external": true}), nil)
+		if len(errs) != 1 || !strings.Contains(errs[0].Detail, "tenant tenant-foo has 0 left") {
+			t.Fatalf("errs = %v, want the tenant-foo pool exhausted", errs)
+		}
+		if errs := r.validateApplicationQuotas(ctx, appWithValues(t, "vm", "tenant-foo-bar", map[string]any{"external": false}), nil); len(errs) > 0 {
+			t.Errorf("internal VM must be admitted, got %v", errs)
+		}
+	})
+
+	t.Run("GPU carve-outs and usage shrink the pool", func(t *testing.T) {
+		objects := []client.Object{
+			tenantHelmRelease(t, "foo", "tenant-root", map[string]string{"nvidia.com/A10": "3"}),
+			tenantHelmRelease(t, "qux", "tenant-foo", map[string]string{"nvidia.com/A10": "1"}),
+			usageQuota(t, "tenant-foo", "tenant-quota", map[string]string{"requests.nvidia.com/A10": "1"}),
+		}
+		r := newAppREST(t, "VMInstance", objects...)
+		one := appWithValues(t, "vm", "tenant-foo", map[string]any{"gpus": []map[string]string{{"name": "nvidia.com/A10"}}})
+		if errs := r.validateApplicationQuotas(ctx, one, nil); len(errs) > 0 {
+			t.Errorf("one GPU fits (3 - 1 carved - 1 used), got %v", errs)
+		}
+		two := appWithValues(t, "vm", "tenant-foo", map[string]any{"gpus": []map[string]string{{"name": "nvidia.com/A10"}, {"name": "nvidia.com/A10"}}})
+		if errs := r.validateApplicationQuotas(ctx, two, nil); len(errs) != 1 {
+			t.Errorf("two GPUs must be refused, got %v", errs)
+		}
+	})
+
+	t.Run("full bucket storage refuses new buckets only", func(t *testing.T) {
+		objects := []client.Object{
+			tenantHelmRelease(t, "foo", "tenant-root", map[string]string{quotaBucketStorage: "10Gi"}),
+			&cozyv1alpha1.Workload{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo", Name: "bucket-data"},
+				Status: cozyv1alpha1.WorkloadStatus{Resources: map[string]resource.Quantity{
+					quotatree.BucketWorkloadResource: resource.MustParse("10Gi"),
+				}},
+			},
+		}
+		r := newAppREST(t, bucketKind, objects...)
+		bucket := appWithValues(t, "logs", "tenant-foo", map[string]any{"size": "1Gi"})
+		if errs := r.validateApplicationQuotas(ctx, bucket, nil); len(errs) != 1 {
+			t.Errorf("new bucket must be refused at a full pool, got %v", errs)
+		}
+		if errs := r.validateApplicationQuotas(ctx, bucket, bucket); len(errs) > 0 {
+			t.Errorf("updating an existing bucket must be admitted, got %v", errs)
+		}
+	})
+
+	t.Run("buckets must be sized where bucket storage is limited", func(t *testing.T) {
+		objects := []client.Object{
+			tenantHelmRelease(t, "foo", "tenant-root", map[string]string{quotaBucketStorage: "10Gi"}),
+		}
+		r := newAppREST(t, bucketKind, objects...)
+		errs := r.validateApplicationQuotas(ctx, appWithValues(t, "logs", "tenant-foo", nil), nil)
+		if len(errs) != 1 || errs[0].Field != "spec.size" {
+			t.Fatalf("errs = %v, want spec.size required", errs)
+		}
+		if errs := r.validateApplicationQuotas(ctx, appWithValues(t, "logs", "tenant-foo", map[string]any{"size": "10Gi"}), nil); len(errs) > 0 {
+			t.Errorf("a bucket within the budget must be admitted, got %v", errs)
+		}
+		if errs := r.validateApplicationQuotas(ctx, appWithValues(t, "logs", "tenant-foo", map[string]any{"size": "11Gi"}), nil); len(errs) != 1 {
+			t.Errorf("a bucket over the budget must be refused, got %v", errs)
+		}
+	})
+
+	t.Run("unclassed volumes are charged to the default class", func(t *testing.T) {
+		objects := []client.Object{
+			tenantHelmRelease(t, "foo", "tenant-root", map[string]string{"replicated.storageclass.storage.k8s.io/requests.storage": "15Gi"}),
+			usageQuota(t, "tenant-foo", "tenant-quota", map[string]string{"replicated.storageclass.storage.k8s.io/requests.storage": "5Gi"}),
+			&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "local"}},
+			&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{
+				Name:        "replicated",
+				Annotations: map[string]string{defaultStorageClassAnnotation: "true"},
+			}},
+		}
+		r := newAppREST(t, "Postgres", objects...)
+		if errs := r.validateApplicationQuotas(ctx, appWithValues(t, "db", "tenant-foo", map[string]any{"size": "5Gi", "replicas": 2}), nil); len(errs) > 0 {
+			t.Errorf("10Gi fits the 10Gi left of the default class, got %v", errs)
+		}
+		errs := r.validateApplicationQuotas(ctx, appWithValues(t, "db", "tenant-foo", map[string]any{"size": "5Gi", "replicas": 3}), nil)
+		if len(errs) != 1 || errs[0].Field != "spec.storageClass" {
+			t.Errorf("15Gi must be refused on spec.storageClass, got %v", errs)
+		}
+		if errs := r.validateApplicationQuotas(ctx, appWithValues(t, "db", "tenant-foo", map[string]any{"size": "5Gi", "replicas": 3, "storageClass": "local"}), nil); len(errs) > 0 {
+			t.Errorf("an unlimited class must be admitted, got %v", errs)
+		}
+	})
+
+	t.Run("malformed values are rejected", func(t *testing.T) {
+		r := newAppREST(t, "Postgres")
+		errs := r.validateApplicationQuotas(ctx, appWithValues(t, "db", "tenant-foo", map[string]any{"replicas": "two"}), nil)
+		if len(errs) != 1 || errs[0].Type != field.ErrorTypeInvalid {
+			t.Errorf("errs = %v, want spec invalid", errs)
+		}
+	})
+
+	t.Run("backup storage counts artifact sizes", func(t *testing.T) {
+		objects := []client.Object{
+			tenantHelmRelease(t, "foo", "tenant-root", map[string]string{quotaBackupStorage: "1Gi"}),
+			&backupsv1alpha1.Backup{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo", Name: "db-1"},
+				Status: backupsv1alpha1.BackupStatus{Artifact: &backupsv1alpha1.BackupArtifact{
+					URI: "s3://backups/db-1", SizeBytes: 512 << 20,
+				}},
+			},
+		}
+		r := newAppREST(t, "Postgres", objects...)
+		enabled := appWithValues(t, "db", "tenant-foo", map[string]any{"backup": map[string]any{"enabled": true}})
+		if errs := r.validateApplicationQuotas(ctx, enabled, nil); len(errs) > 0 {
+			t.Errorf("half-used backup budget must admit, got %v", errs)
+		}
+		r = newAppREST(t, "Postgres", append(objects, &backupsv1alpha1.Backup{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo", Name: "db-2"},
+			Status: backupsv1alpha1.BackupStatus{Artifact: &backupsv1alpha1.BackupArtifact{
+				URI: "s3://backups/db-2", SizeBytes: 512 << 20,
+			}},
+		})...)
+		if errs := r.validateApplicationQuotas(ctx, enabled, nil); len(errs) != 1 {
+			t.Errorf("exhausted backup budget must refuse enabling backups, got %v", errs)
+		}
+	})
+
+	t.Run("backups are sized by the data they copy", func(t *testing.T) {
+		objects := []client.Object{
+			tenantHelmRelease(t, "foo", "tenant-root", map[string]string{quotaBackupStorage: "10Gi"}),
+			&backupsv1alpha1.Backup{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo", Name: "db-1"},
+				Status: backupsv1alpha1.BackupStatus{Artifact: &backupsv1alpha1.BackupArtifact{
+					URI: "s3://backups/db-1", SizeBytes: 4 << 30,
+				}},
+			},
+		}
+		r := newAppREST(t, "Postgres", objects...)
+		sized := func(size string) *appsv1alpha1.Application {
+			return appWithValues(t, "db", "tenant-foo", map[string]any{"size": size, "replicas": 3, "backup": map[string]any{"enabled": true}})
+		}
+		if errs := r.validateApplicationQuotas(ctx, sized("6Gi"), nil); len(errs) > 0 {
+			t.Errorf("one 6Gi copy fits the 6Gi left, got %v", errs)
+		}
+		if errs := r.validateApplicationQuotas(ctx, sized("7Gi"), nil); len(errs) != 1 {
+			t.Errorf("a 7Gi copy must be refused, got %v", errs)
+		}
+		if errs := r.validateApplicationQuotas(ctx, sized("7Gi"), sized("6Gi")); len(errs) > 0 {
+			t.Errorf("growing by 1Gi must be admitted, got %v", errs)
+		}
+	})
+}
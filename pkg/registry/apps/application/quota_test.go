@@ -141,10 +141,13 @@ func TestRenderedLimitKey(t *testing.T) {
 		"cpu":                    "limits.cpu",
 		"memory":                 "limits.memory",
 		"ephemeral-storage":      "limits.ephemeral-storage",
-		"devices.com/nvidia":     "limits.devices.com/nvidia",
+		"devices.com/nvidia":     "requests.devices.com/nvidia",
 		"storage":                "requests.storage",
 		"pods":                   "pods",
 		"services.loadbalancers": "services.loadbalancers",
+		"fast.storageclass.storage.k8s.io/requests.storage": "fast.storageclass.storage.k8s.io/requests.storage",
+		"external-ips":    "external-ips",
+		"buckets.storage": "buckets.storage",
 	}
 	for raw, want := range cases {
 		if got := renderedLimitKey(raw); got != want {
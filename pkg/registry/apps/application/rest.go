@@ -45,6 +45,7 @@ import (
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	quotatree "github.com/cozystack/cozystack/internal/controller/tenantquota"
 	fluxshard "github.com/cozystack/cozystack/internal/fluxshardoperator"
 	"github.com/cozystack/cozystack/pkg/apis/apps/presets"
 	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
@@ -180,6 +181,10 @@ func (r *REST) Create(ctx context.Context, obj runtime.Object, createValidation
 		return nil, apierrors.NewInvalid(r.gvk.GroupKind(), app.Name, nameLenErrs)
 	}
 
+	if err := r.checkTenantLifecycle(ctx, app.Namespace, app.Name); err != nil {
+		return nil, err
+	}
+
 	// For Tenant applications, also validate that the computed workload
 	// namespace fits within the DNS-1123 label limit. A deeply-nested tenant
 	// can exceed the limit even when its own name passes the per-name Helm
@@ -195,6 +200,11 @@ func (r *REST) Create(ctx context.Context, obj runtime.Object, createValidation
 			return nil, apierrors.NewInvalid(r.gvk.GroupKind(), app.Name, qErrs)
 		}
 	}
+	// Hold platform resources (external IPs, GPUs, bucket and backup storage)
+	// to the remaining budget of the tenant pool the namespace draws from.
+	if qErrs := r.validateApplicationQuotas(ctx, app, nil); len(qErrs) > 0 {
+		return nil, apierrors.NewInvalid(r.gvk.GroupKind(), app.Name, qErrs)
+	}
 
 	// Validate that values don't contain reserved keys (starting with "_")
 	if err := validateNoInternalKeys(app.Spec); err != nil {
@@ -547,6 +557,11 @@ func (r *REST) Update(ctx context.Context, name string, objInfo rest.UpdatedObje
 			return nil, false, apierrors.NewInvalid(r.gvk.GroupKind(), app.Name, qErrs)
 		}
 	}
+	if oldApp, ok := oldObj.(*appsv1alpha1.Application); ok {
+		if qErrs := r.validateApplicationQuotas(ctx, app, oldApp); len(qErrs) > 0 {
+			return nil, false, apierrors.NewInvalid(r.gvk.GroupKind(), app.Name, qErrs)
+		}
+	}
 
 	r.warnLegacyPresets(app)
 
@@ -1645,6 +1660,12 @@ func (r *REST) convertApplicationToHelmRelease(app *appsv1alpha1.Application) (*
 	helmRelease.Spec.HealthCheckExprs = r.releaseConfig.HealthCheckExprs
 	helmRelease.Spec.WaitStrategy = config.ResolveWaitStrategy(r.releaseConfig.WaitStrategy, len(r.releaseConfig.HealthCheckExprs) > 0)
 
+	// A Tenant's approved QuotaRequest raises reach its chart through an
+	// optional values Secret the tenant quota controller maintains.
+	if r.kindName == "Tenant" {
+		helmRelease.Spec.ValuesFrom = append(helmRelease.Spec.ValuesFrom, quotatree.BurstValuesReference(helmRelease.Name))
+	}
+
 	return helmRelease, nil
 }
 
@@ -23,6 +23,7 @@ import (
 	"testing"
 
 	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -52,6 +53,9 @@ func TestCreate_AdmissionChain_RejectsViaCreateValidation(t *testing.T) {
 	if err := helmv2.AddToScheme(scheme); err != nil {
 		t.Fatalf("register helmv2 scheme: %v", err)
 	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("register core scheme: %v", err)
+	}
 	resourceCfg := &config.ResourceConfig{
 		Resources: []config.Resource{
 			{Application: config.ApplicationConfig{Kind: "MySQL"}},
@@ -117,6 +121,9 @@ func TestUpdate_AdmissionChain_RejectsViaUpdateValidation(t *testing.T) {
 	if err := helmv2.AddToScheme(scheme); err != nil {
 		t.Fatalf("register helmv2 scheme: %v", err)
 	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("register core scheme: %v", err)
+	}
 	resourceCfg := &config.ResourceConfig{
 		Resources: []config.Resource{
 			{Application: config.ApplicationConfig{Kind: "MySQL"}},
@@ -220,6 +227,9 @@ func TestDelete_AdmissionChain_RejectsViaDeleteValidation(t *testing.T) {
 	if err := helmv2.AddToScheme(scheme); err != nil {
 		t.Fatalf("register helmv2 scheme: %v", err)
 	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("register core scheme: %v", err)
+	}
 	resourceCfg := &config.ResourceConfig{
 		Resources: []config.Resource{
 			{Application: config.ApplicationConfig{Kind: validation.TenantKind}},
@@ -0,0 +1,311 @@
+// SPDX-License-Identifier: Apache-2.0
+// Reconciliation registry: a read-only, virtual resource exposing the
+// recent reconcile attempts of each Application in a tenant namespace. The
+// cozystack-controller records them into ReconcileHistories as the
+// Application HelmReleases change status; this resource only relays them,
+// tenant-scoped, so support and tenants can self-diagnose without reading
+// helm-controller logs.
+
+package reconciliation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metainternal "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+	corev1alpha1 "github.com/cozystack/cozystack/pkg/apis/core/v1alpha1"
+	"github.com/cozystack/cozystack/pkg/registry/core/tenantnamespace"
+)
+
+const (
+	prefix       = "tenant-"
+	singularName = "reconciliation"
+)
+
+var (
+	_ rest.Lister               = &REST{}
+	_ rest.Getter               = &REST{}
+	_ rest.Watcher              = &REST{}
+	_ rest.TableConvertor       = &REST{}
+	_ rest.Scoper               = &REST{}
+	_ rest.SingularNameProvider = &REST{}
+)
+
+// REST implements the read-only Reconciliation resource.
+type REST struct {
+	// w reads ReconcileHistories uncached: they are rewritten on every
+	// reconcile of every Application and a cached copy would only lag.
+	w      client.WithWatch
+	access *tenantnamespace.REST
+	gvr    schema.GroupVersionResource
+}
+
+func NewREST(c client.Client, w client.WithWatch) *REST {
+	return &REST{
+		w:      w,
+		access: tenantnamespace.NewREST(c, w),
+		gvr: schema.GroupVersionResource{
+			Group:    corev1alpha1.GroupName,
+			Version:  "v1alpha1",
+			Resource: "reconciliations",
+		},
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Basic meta
+// -----------------------------------------------------------------------------
+
+func (*REST) NamespaceScoped() bool   { return true }
+func (*REST) New() runtime.Object     { return &corev1alpha1.Reconciliation{} }
+func (*REST) NewList() runtime.Object { return &corev1alpha1.ReconciliationList{} }
+func (*REST) Kind() string            { return "Reconciliation" }
+func (r *REST) GroupVersionKind(_ schema.GroupVersion) schema.GroupVersionKind {
+	return r.gvr.GroupVersion().WithKind("Reconciliation")
+}
+func (*REST) GetSingularName() string { return singularName }
+func (*REST) Destroy()                {}
+
+// -----------------------------------------------------------------------------
+// Lister / Getter
+// -----------------------------------------------------------------------------
+
+// List returns one Reconciliation per Application in the namespace that has
+// been reconciled since recording began. A label selector on the
+// apps.cozystack.io/application.* labels narrows it to one kind or name.
+func (r *REST) List(ctx context.Context, opts *metainternal.ListOptions) (runtime.Object, error) {
+	ns, err := r.authorize(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	listOpts := []client.ListOption{client.InNamespace(ns), client.HasLabels{appsv1alpha1.ApplicationKindLabel}}
+	if opts != nil && opts.LabelSelector != nil && !opts.LabelSelector.Empty() {
+		listOpts = append(listOpts, client.MatchingLabelsSelector{Selector: opts.LabelSelector})
+	}
+	histories := &cozyv1alpha1.ReconcileHistoryList{}
+	if err := r.w.List(ctx, histories, listOpts...); err != nil && !apimeta.IsNoMatchError(err) {
+		return nil, apierrors.NewInternalError(fmt.Errorf("failed to list reconcile histories: %w", err))
+	}
+	sort.Slice(histories.Items, func(i, j int) bool { return histories.Items[i].Name < histories.Items[j].Name })
+
+	out := &corev1alpha1.ReconciliationList{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1alpha1.SchemeGroupVersion.String(),
+			Kind:       "ReconciliationList",
+		},
+		ListMeta: metav1.ListMeta{ResourceVersion: "0"},
+	}
+	for i := range histories.Items {
+		out.Items = append(out.Items, makeReconciliation(&histories.Items[i]))
+	}
+	return out, nil
+}
+
+func (r *REST) Get(ctx context.Context, name string, _ *metav1.GetOptions) (runtime.Object, error) {
+	ns, err := r.authorize(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	history := &cozyv1alpha1.ReconcileHistory{}
+	err = r.w.Get(ctx, types.NamespacedName{Namespace: ns, Name: name}, history)
+	if apierrors.IsNotFound(err) || apimeta.IsNoMatchError(err) {
+		return nil, apierrors.NewNotFound(r.gvr.GroupResource(), name)
+	}
+	if err != nil {
+		return nil, apierrors.NewInternalError(fmt.Errorf("failed to get reconcile history: %w", err))
+	}
+	if history.Labels[appsv1alpha1.ApplicationKindLabel] == "" {
+		return nil, apierrors.NewNotFound(r.gvr.GroupResource(), name)
+	}
+	rec := makeReconciliation(history)
+	return &rec, nil
+}
+
+// authorize resolves the request namespace and checks the caller may read
+// it; the resource is open to every authenticated user, so this is the only
+// tenant boundary.
+func (r *REST) authorize(ctx context.Context, name string) (string, error) {
+	ns, ok := request.NamespaceFrom(ctx)
+	if !ok || ns == "" {
+		return "", apierrors.NewBadRequest("namespace required")
+	}
+	if !strings.HasPrefix(ns, prefix) {
+		return "", apierrors.NewNotFound(r.gvr.GroupResource(), name)
+	}
+	hasAccess, err := r.access.HasAccessToNamespace(ctx, ns)
+	if err != nil {
+		return "", err
+	}
+	if !hasAccess {
+		return "", apierrors.NewForbidden(r.gvr.GroupResource(), name, fmt.Errorf("access denied to namespace %s", ns))
+	}
+	return ns, nil
+}
+
+func makeReconciliation(h *cozyv1alpha1.ReconcileHistory) corev1alpha1.Reconciliation {
+	rec := corev1alpha1.Reconciliation{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1alpha1.SchemeGroupVersion.String(),
+			Kind:       "Reconciliation",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              h.Name,
+			Namespace:         h.Namespace,
+			CreationTimestamp: h.CreationTimestamp,
+			ResourceVersion:   "0",
+			Labels: labels.Set{
+				appsv1alpha1.ApplicationKindLabel: h.Labels[appsv1alpha1.ApplicationKindLabel],
+				appsv1alpha1.ApplicationNameLabel: h.Labels[appsv1alpha1.ApplicationNameLabel],
+			},
+		},
+		Status: corev1alpha1.ReconciliationStatus{
+			ApplicationKind: h.Labels[appsv1alpha1.ApplicationKindLabel],
+			ApplicationName: h.Labels[appsv1alpha1.ApplicationNameLabel],
+		},
+	}
+	if h.Status.InProgress != nil {
+		a := makeAttempt(h.Status.InProgress)
+		rec.Status.InProgress = &a
+	}
+	for i := range h.Status.Attempts {
+		rec.Status.Attempts = append(rec.Status.Attempts, makeAttempt(&h.Status.Attempts[i]))
+	}
+	return rec
+}
+
+func makeAttempt(a *cozyv1alpha1.ReconcileAttempt) corev1alpha1.ReconciliationAttempt {
+	out := corev1alpha1.ReconciliationAttempt{
+		StartTime:    a.StartTime,
+		Outcome:      a.Outcome,
+		Reason:       a.Reason,
+		Message:      a.Message,
+		Action:       a.Action,
+		ChartVersion: a.ChartVersion,
+		Shard:        a.Shard,
+	}
+	if a.CompletionTime != nil {
+		out.CompletionTime = a.CompletionTime.DeepCopy()
+		out.Duration = a.CompletionTime.Sub(a.StartTime.Time).String()
+	}
+	return out
+}
+
+// -----------------------------------------------------------------------------
+// Watcher (one-shot): emit the current histories once and hold the stream
+// open until the client goes away.
+// -----------------------------------------------------------------------------
+
+func (r *REST) Watch(ctx context.Context, opts *metainternal.ListOptions) (watch.Interface, error) {
+	events := make(chan watch.Event)
+	pw := watch.NewProxyWatcher(events)
+
+	go func() {
+		defer pw.Stop()
+		listObj, err := r.List(ctx, opts)
+		if err != nil {
+			klog.ErrorS(err, "reconciliations: initial list for watch failed")
+		} else {
+			list := listObj.(*corev1alpha1.ReconciliationList)
+			for i := range list.Items {
+				select {
+				case events <- watch.Event{Type: watch.Added, Object: &list.Items[i]}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+		<-ctx.Done()
+	}()
+
+	return pw, nil
+}
+
+// -----------------------------------------------------------------------------
+// TableConvertor
+// -----------------------------------------------------------------------------
+
+func (r *REST) ConvertToTable(_ context.Context, obj runtime.Object, _ runtime.Object) (*metav1.Table, error) {
+	row := func(o *corev1alpha1.Reconciliation) metav1.TableRow {
+		var outcome, reason, chart, last string
+		latest := o.Status.InProgress
+		if latest == nil && len(o.Status.Attempts) > 0 {
+			latest = &o.Status.Attempts[0]
+		}
+		if latest != nil {
+			outcome, reason, chart = latest.Outcome, latest.Reason, latest.ChartVersion
+			last = latest.StartTime.UTC().Format("2006-01-02T15:04:05Z")
+		}
+		return metav1.TableRow{
+			Cells: []interface{}{
+				o.Name,
+				o.Status.ApplicationKind,
+				outcome,
+				reason,
+				chart,
+				last,
+			},
+			Object: runtime.RawExtension{Object: o},
+		}
+	}
+	tbl := &metav1.Table{
+		TypeMeta: metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "Table"},
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "NAME", Type: "string"},
+			{Name: "KIND", Type: "string"},
+			{Name: "OUTCOME", Type: "string"},
+			{Name: "REASON", Type: "string"},
+			{Name: "CHART", Type: "string"},
+			{Name: "LAST", Type: "string"},
+		},
+	}
+	switch v := obj.(type) {
+	case *corev1alpha1.ReconciliationList:
+		for i := range v.Items {
+			tbl.Rows = append(tbl.Rows, row(&v.Items[i]))
+		}
+		tbl.ResourceVersion = v.ResourceVersion
+	case *corev1alpha1.Reconciliation:
+		tbl.Rows = append(tbl.Rows, row(v))
+		tbl.ResourceVersion = v.ResourceVersion
+	default:
+		return nil, notAcceptable{r.gvr.GroupResource(), fmt.Sprintf("unexpected %T", obj)}
+	}
+	return tbl, nil
+}
+
+// -----------------------------------------------------------------------------
+// Helpers / boiler-plate
+// -----------------------------------------------------------------------------
+
+type notAcceptable struct {
+	resource schema.GroupResource
+	message  string
+}
+
+func (e notAcceptable) Error() string { return e.message }
+func (e notAcceptable) Status() metav1.Status {
+	return metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusNotAcceptable,
+		Reason:  metav1.StatusReason("NotAcceptable"),
+		Message: e.message,
+	}
+}
@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package reconciliation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metainternal "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+	corev1alpha1 "github.com/cozystack/cozystack/pkg/apis/core/v1alpha1"
+)
+
+var t0 = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func history(ns, name, kind, app string, attempts ...cozyv1alpha1.ReconcileAttempt) *cozyv1alpha1.ReconcileHistory {
+	h := &cozyv1alpha1.ReconcileHistory{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+	if kind != "" {
+		h.Labels = map[string]string{
+			appsv1alpha1.ApplicationKindLabel: kind,
+			appsv1alpha1.ApplicationNameLabel: app,
+		}
+	}
+	h.Status.Attempts = attempts
+	return h
+}
+
+func failed(msg string, took time.Duration) cozyv1alpha1.ReconcileAttempt {
+	done := metav1.NewTime(t0.Add(took))
+	return cozyv1alpha1.ReconcileAttempt{
+		StartTime:      metav1.NewTime(t0),
+		CompletionTime: &done,
+		Outcome:        cozyv1alpha1.ReconcileFailed,
+		Reason:         "UpgradeFailed",
+		Message:        msg,
+		ChartVersion:   "0.12.1",
+		Shard:          "shard1",
+	}
+}
+
+// newTestREST records two Applications in tenant-foo and one in
+// tenant-bar, plus an unlabelled history, and gives alice a RoleBinding in
+// tenant-foo only.
+func newTestREST(t *testing.T) *REST {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	_ = rbacv1.AddToScheme(scheme)
+	_ = cozyv1alpha1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(
+			history("tenant-foo", "postgres-db", "Postgres", "db", failed("timed out", 90*time.Second)),
+			history("tenant-foo", "redis-cache", "Redis", "cache"),
+			history("tenant-foo", "stray", "", ""),
+			history("tenant-bar", "postgres-db", "Postgres", "db"),
+			&rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo", Name: "alice"},
+				Subjects:   []rbacv1.Subject{{Kind: "User", Name: "alice"}},
+				RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "view"},
+			},
+		).Build()
+	return NewREST(c, c)
+}
+
+func aliceIn(ns string) context.Context {
+	ctx := request.WithUser(context.Background(), &user.DefaultInfo{Name: "alice"})
+	return request.WithNamespace(ctx, ns)
+}
+
+func TestGet_RelaysAttempts(t *testing.T) {
+	r := newTestREST(t)
+	obj, err := r.Get(aliceIn("tenant-foo"), "postgres-db", &metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	st := obj.(*corev1alpha1.Reconciliation).Status
+	if st.ApplicationKind != "Postgres" || st.ApplicationName != "db" {
+		t.Errorf("application = %s/%s, want Postgres/db", st.ApplicationKind, st.ApplicationName)
+	}
+	if len(st.Attempts) != 1 {
+		t.Fatalf("attempts = %+v, want one", st.Attempts)
+	}
+	a := st.Attempts[0]
+	if a.Outcome != cozyv1alpha1.ReconcileFailed || a.Message != "timed out" || a.Shard != "shard1" || a.Duration != "1m30s" {
+		t.Errorf("attempt = %+v", a)
+	}
+
+	if _, err := r.Get(aliceIn("tenant-foo"), "stray", &metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Get of a non-Application history err = %v, want NotFound", err)
+	}
+	if _, err := r.Get(aliceIn("tenant-bar"), "postgres-db", &metav1.GetOptions{}); !apierrors.IsForbidden(err) {
+		t.Errorf("Get in tenant-bar err = %v, want Forbidden", err)
+	}
+}
+
+func TestList_FiltersByLabel(t *testing.T) {
+	r := newTestREST(t)
+	obj, err := r.List(aliceIn("tenant-foo"), nil)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	items := obj.(*corev1alpha1.ReconciliationList).Items
+	if len(items) != 2 || items[0].Name != "postgres-db" || items[1].Name != "redis-cache" {
+		t.Fatalf("items = %+v, want postgres-db and redis-cache", items)
+	}
+
+	sel := labels.SelectorFromSet(labels.Set{appsv1alpha1.ApplicationKindLabel: "Redis"})
+	obj, err = r.List(aliceIn("tenant-foo"), &metainternal.ListOptions{LabelSelector: sel})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if items := obj.(*corev1alpha1.ReconciliationList).Items; len(items) != 1 || items[0].Name != "redis-cache" {
+		t.Errorf("items = %+v, want redis-cache only", items)
+	}
+}
@@ -32,6 +32,7 @@ import (
 	corev1alpha1 "github.com/cozystack/cozystack/pkg/apis/core/v1alpha1"
 	"github.com/cozystack/cozystack/pkg/registry"
 	"github.com/cozystack/cozystack/pkg/registry/sorting"
+	"github.com/cozystack/cozystack/pkg/tenantstate"
 )
 
 const (
@@ -153,6 +154,7 @@ func (r *REST) Get(
 			Kind:       "TenantNamespace",
 		},
 		ObjectMeta: ns.ObjectMeta,
+		Status:     lifecycleStatus(ns),
 	}, nil
 }
 
@@ -295,6 +297,7 @@ func (r *REST) Watch(ctx context.Context, opts *metainternal.ListOptions) (watch
 					Labels:            ns.Labels,
 					Annotations:       ns.Annotations,
 				},
+				Status: lifecycleStatus(ns),
 			}
 
 			// Skip ADDED events based on resourceVersion comparison
@@ -337,7 +340,7 @@ func (r *REST) ConvertToTable(_ context.Context, obj runtime.Object, _ runtime.O
 	now := time.Now()
 	row := func(o *corev1alpha1.TenantNamespace) metav1.TableRow {
 		return metav1.TableRow{
-			Cells:  []interface{}{o.Name, duration.HumanDuration(now.Sub(o.CreationTimestamp.Time))},
+			Cells:  []interface{}{o.Name, o.Status.Phase, duration.HumanDuration(now.Sub(o.CreationTimestamp.Time))},
 			Object: runtime.RawExtension{Object: o},
 		}
 	}
@@ -346,6 +349,7 @@ func (r *REST) ConvertToTable(_ context.Context, obj runtime.Object, _ runtime.O
 		TypeMeta: metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "Table"},
 		ColumnDefinitions: []metav1.TableColumnDefinition{
 			{Name: "NAME", Type: "string"},
+			{Name: "PHASE", Type: "string"},
 			{Name: "AGE", Type: "string"},
 		},
 	}
@@ -408,6 +412,7 @@ func (r *REST) makeList(src *corev1.NamespaceList, allowed []string) *corev1alph
 				Labels:            ns.Labels,
 				Annotations:       ns.Annotations,
 			},
+			Status: lifecycleStatus(ns),
 		})
 	}
 
@@ -416,6 +421,23 @@ func (r *REST) makeList(src *corev1.NamespaceList, allowed []string) *corev1alph
 	return out
 }
 
+// lifecycleStatus reads the tenant lifecycle the controller recorded on a
+// namespace. A namespace it never acted on is Active.
+func lifecycleStatus(ns *corev1.Namespace) corev1alpha1.TenantNamespaceStatus {
+	st := corev1alpha1.TenantNamespaceStatus{
+		Lifecycle: string(tenantstate.Declared(ns.Labels)),
+		Phase:     ns.Annotations[tenantstate.PhaseAnnotation],
+		Message:   ns.Annotations[tenantstate.MessageAnnotation],
+	}
+	if st.Phase == "" {
+		st.Phase = string(tenantstate.PhaseActive)
+	}
+	if at, err := time.Parse(time.RFC3339, ns.Annotations[tenantstate.DeletionAtAnnotation]); err == nil {
+		st.DeletionAt = &metav1.Time{Time: at}
+	}
+	return st
+}
+
 // matchesSubject checks if a RoleBinding subject matches the user's identity.
 // It handles Group, User, and ServiceAccount subjects with proper namespace fallback.
 func matchesSubject(subj rbacv1.Subject, bindingNamespace, username string, groups map[string]struct{}) bool {
@@ -487,6 +509,18 @@ func (r *REST) filterAccessible(
 	return allowed, nil
 }
 
+// FilterAccessible returns the subset of tenant namespace names the
+// requesting user may see. Other cluster-scoped views keyed by tenant
+// namespace use it to apply the same visibility rules as this resource.
+func (r *REST) FilterAccessible(ctx context.Context, names []string) ([]string, error) {
+	return r.filterAccessible(ctx, names)
+}
+
+// HasAccessToNamespace is the single-namespace form of FilterAccessible.
+func (r *REST) HasAccessToNamespace(ctx context.Context, namespace string) (bool, error) {
+	return r.hasAccessToNamespace(ctx, namespace)
+}
+
 // hasAccessToNamespace checks if the user has access to a single namespace.
 // This is optimized for Get/Watch operations where we check one namespace at a time.
 // It lists RoleBindings only in the target namespace instead of all cluster RoleBindings.
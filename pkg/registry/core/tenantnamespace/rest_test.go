@@ -52,6 +52,43 @@ func TestMakeListSortsAlphabetically(t *testing.T) {
 	}
 }
 
+func TestGet_ReportsLifecycleStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = rbacv1.AddToScheme(scheme)
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "tenant-test",
+		Labels: map[string]string{"namespace.cozystack.io/lifecycle": "deleting"},
+		Annotations: map[string]string{
+			"namespace.cozystack.io/lifecycle-phase": "PendingDeletion",
+			"namespace.cozystack.io/deletion-at":     "2026-03-04T12:00:00Z",
+		},
+	}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ns).Build()
+	r := &REST{
+		c:   c,
+		gvr: schema.GroupVersionResource{Group: "core.cozystack.io", Version: "v1alpha1", Resource: "tenantnamespaces"},
+	}
+	ctx := request.WithUser(context.Background(), &user.DefaultInfo{Name: "admin", Groups: []string{"system:masters"}})
+
+	obj, err := r.Get(ctx, "tenant-test", &metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	st := obj.(*corev1alpha1.TenantNamespace).Status
+	if st.Lifecycle != "deleting" || st.Phase != "PendingDeletion" || st.DeletionAt == nil {
+		t.Errorf("status = %+v, want deleting/PendingDeletion with deletionAt", st)
+	}
+
+	list := r.makeList(&corev1.NamespaceList{Items: []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "tenant-plain"}},
+	}}, []string{"tenant-plain"})
+	if got := list.Items[0].Status; got.Lifecycle != "active" || got.Phase != "Active" {
+		t.Errorf("untouched namespace status = %+v, want active/Active", got)
+	}
+}
+
 // Security tests for IDOR fix
 
 func TestHasAccessToNamespace_WithUserAccess(t *testing.T) {
@@ -0,0 +1,433 @@
+// SPDX-License-Identifier: Apache-2.0
+// TenantQuota registry: a read-only, virtual resource that explains each
+// tenant's hierarchical quota. Objects are computed on read from the same
+// HelmReleases and ResourceQuotas the tenant quota controller reconciles, so
+// a tenant can see the pool behind its tenant-quota-allocated limit without
+// read access to its ancestors' namespaces.
+
+package tenantquota
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metainternal "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/registry/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	quotatree "github.com/cozystack/cozystack/internal/controller/tenantquota"
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+	corev1alpha1 "github.com/cozystack/cozystack/pkg/apis/core/v1alpha1"
+	"github.com/cozystack/cozystack/pkg/registry"
+	"github.com/cozystack/cozystack/pkg/registry/core/tenantnamespace"
+)
+
+const (
+	prefix       = "tenant-"
+	singularName = "tenantquota"
+)
+
+var (
+	_ rest.Lister               = &REST{}
+	_ rest.Getter               = &REST{}
+	_ rest.Watcher              = &REST{}
+	_ rest.TableConvertor       = &REST{}
+	_ rest.Scoper               = &REST{}
+	_ rest.SingularNameProvider = &REST{}
+)
+
+// watchedKinds are the inputs of a TenantQuota: the tree and its budgets
+// come from tenant HelmReleases and ResourceQuotas, and who may see which
+// node from RoleBindings. A change to any of them re-evaluates a watch.
+// Platform resource usage is measured on every evaluation but not watched.
+var watchedKinds = []client.Object{&helmv2.HelmRelease{}, &corev1.ResourceQuota{}, &rbacv1.RoleBinding{}}
+
+// REST implements the read-only TenantQuota resource.
+type REST struct {
+	// c reads HelmReleases and ResourceQuotas from the informer cache.
+	c client.Client
+	// w reads the sources of platform resource usage directly, without
+	// starting informers for them.
+	w client.WithWatch
+	// informers are the ones behind c; Watch re-evaluates the tree on
+	// their events.
+	informers cache.Informers
+	access    *tenantnamespace.REST
+	gvr       schema.GroupVersionResource
+}
+
+func NewREST(c client.Client, w client.WithWatch, informers cache.Informers) *REST {
+	return &REST{
+		c:         c,
+		w:         w,
+		informers: informers,
+		access:    tenantnamespace.NewREST(c, w),
+		gvr: schema.GroupVersionResource{
+			Group:    corev1alpha1.GroupName,
+			Version:  "v1alpha1",
+			Resource: "tenantquotas",
+		},
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Basic meta
+// -----------------------------------------------------------------------------
+
+func (*REST) NamespaceScoped() bool   { return false }
+func (*REST) New() runtime.Object     { return &corev1alpha1.TenantQuota{} }
+func (*REST) NewList() runtime.Object { return &corev1alpha1.TenantQuotaList{} }
+func (*REST) Kind() string            { return "TenantQuota" }
+func (r *REST) GroupVersionKind(_ schema.GroupVersion) schema.GroupVersionKind {
+	return r.gvr.GroupVersion().WithKind("TenantQuota")
+}
+func (*REST) GetSingularName() string { return singularName }
+func (*REST) Destroy()                {}
+
+// -----------------------------------------------------------------------------
+// Lister / Getter
+// -----------------------------------------------------------------------------
+
+func (r *REST) List(ctx context.Context, _ *metainternal.ListOptions) (runtime.Object, error) {
+	nodes, err := r.tree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(nodes))
+	for ns := range nodes {
+		names = append(names, ns)
+	}
+	allowed, err := r.access.FilterAccessible(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(allowed)
+
+	out := &corev1alpha1.TenantQuotaList{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1alpha1.SchemeGroupVersion.String(),
+			Kind:       "TenantQuotaList",
+		},
+		ListMeta: metav1.ListMeta{ResourceVersion: "0"},
+	}
+	for _, ns := range allowed {
+		out.Items = append(out.Items, makeTenantQuota(nodes[ns]))
+	}
+	return out, nil
+}
+
+func (r *REST) Get(ctx context.Context, name string, _ *metav1.GetOptions) (runtime.Object, error) {
+	if !strings.HasPrefix(name, prefix) {
+		return nil, apierrors.NewNotFound(r.gvr.GroupResource(), name)
+	}
+	hasAccess, err := r.access.HasAccessToNamespace(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, apierrors.NewForbidden(r.gvr.GroupResource(), name, fmt.Errorf("access denied"))
+	}
+	nodes, err := r.tree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	node, ok := nodes[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(r.gvr.GroupResource(), name)
+	}
+	tq := makeTenantQuota(node)
+	return &tq, nil
+}
+
+// tree computes the whole quota tree. A single tenant's view depends on its
+// siblings and ancestors, so there is no cheaper per-namespace read.
+func (r *REST) tree(ctx context.Context) (map[string]*quotatree.Node, error) {
+	releases := &helmv2.HelmReleaseList{}
+	if err := r.c.List(ctx, releases, client.MatchingLabels{appsv1alpha1.ApplicationKindLabel: "Tenant"}); err != nil {
+		return nil, apierrors.NewInternalError(fmt.Errorf("failed to list tenant releases: %w", err))
+	}
+	quotas := &corev1.ResourceQuotaList{}
+	if err := r.c.List(ctx, quotas); err != nil {
+		return nil, apierrors.NewInternalError(fmt.Errorf("failed to list resourcequotas: %w", err))
+	}
+	state, err := quotatree.ReadState(ctx, r.w, quotas.Items)
+	if err != nil {
+		return nil, apierrors.NewInternalError(fmt.Errorf("failed to measure platform usage: %w", err))
+	}
+	return quotatree.BuildTree(quotatree.TenantsFromReleases(releases.Items, state.Declared), state), nil
+}
+
+func makeTenantQuota(n *quotatree.Node) corev1alpha1.TenantQuota {
+	tq := corev1alpha1.TenantQuota{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1alpha1.SchemeGroupVersion.String(),
+			Kind:       "TenantQuota",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            n.Namespace,
+			ResourceVersion: "0",
+		},
+		Status: corev1alpha1.TenantQuotaStatus{
+			Parent:        n.Parent,
+			PoolRoot:      n.PoolRoot,
+			Declared:      n.Declared,
+			Budget:        n.Budget,
+			CarvedOut:     n.CarvedOut,
+			Available:     n.Available,
+			Members:       n.Members,
+			Used:          n.Used,
+			SubtreeUsed:   n.SubtreeUsed,
+			Hard:          n.Hard,
+			Overcommitted: n.Overcommitted,
+			Children:      n.Children,
+		},
+	}
+	for _, a := range n.Allocations {
+		tq.Status.Allocations = append(tq.Status.Allocations, corev1alpha1.TenantQuotaAllocation{
+			Namespace: a.Namespace,
+			Declared:  a.Declared,
+		})
+	}
+	return tq
+}
+
+// -----------------------------------------------------------------------------
+// Watcher: every TenantQuota is derived from several upstream objects, so a
+// watch recomputes the tree whenever one of their informers reports a change
+// and emits the difference from what the client was last sent.
+// -----------------------------------------------------------------------------
+
+func (r *REST) Watch(ctx context.Context, opts *metainternal.ListOptions) (watch.Interface, error) {
+	// Register before the initial list, so a change racing with it is
+	// still followed by a recomputation.
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	handler := toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { notify() },
+		UpdateFunc: func(interface{}, interface{}) { notify() },
+		DeleteFunc: func(interface{}) { notify() },
+	}
+	var stops []func()
+	stopHandlers := func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+	for _, obj := range watchedKinds {
+		informer, err := r.informers.GetInformer(ctx, obj)
+		if err != nil {
+			stopHandlers()
+			return nil, apierrors.NewInternalError(fmt.Errorf("failed to get informer for %T: %w", obj, err))
+		}
+		reg, err := informer.AddEventHandler(handler)
+		if err != nil {
+			stopHandlers()
+			return nil, apierrors.NewInternalError(fmt.Errorf("failed to watch %T: %w", obj, err))
+		}
+		stops = append(stops, func() {
+			if err := informer.RemoveEventHandler(reg); err != nil {
+				klog.ErrorS(err, "tenantquotas: failed to remove watch handler")
+			}
+		})
+	}
+
+	initial, err := r.List(ctx, opts)
+	if err != nil {
+		stopHandlers()
+		return nil, err
+	}
+
+	sendInitialEvents := opts != nil && opts.SendInitialEvents != nil && *opts.SendInitialEvents
+	bookmarker := registry.NewInitialEventsBookmarker(sendInitialEvents, "0", func() runtime.Object {
+		return &corev1alpha1.TenantQuota{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: corev1alpha1.SchemeGroupVersion.String(),
+				Kind:       "TenantQuota",
+			},
+		}
+	})
+
+	events := make(chan watch.Event)
+	pw := watch.NewProxyWatcher(events)
+
+	go func() {
+		defer close(events)
+		defer pw.Stop()
+		defer stopHandlers()
+
+		send := func(ev watch.Event) bool {
+			select {
+			case events <- ev:
+				return true
+			case <-pw.StopChan():
+				return false
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		sent := map[string]*corev1alpha1.TenantQuota{}
+		for _, ev := range diffTenantQuotas(sent, initial.(*corev1alpha1.TenantQuotaList)) {
+			if !send(ev) {
+				return
+			}
+		}
+		// The initial list is complete: there is no backing bookmark to
+		// wait for.
+		if bookmark, ok := bookmarker.OnClose(); ok {
+			if !send(bookmark) {
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-changed:
+			case <-pw.StopChan():
+				return
+			case <-ctx.Done():
+				return
+			}
+			current, err := r.List(ctx, opts)
+			if err != nil {
+				klog.ErrorS(err, "tenantquotas: list for watch failed")
+				continue
+			}
+			for _, ev := range diffTenantQuotas(sent, current.(*corev1alpha1.TenantQuotaList)) {
+				if !send(ev) {
+					return
+				}
+			}
+		}
+	}()
+
+	return pw, nil
+}
+
+// diffTenantQuotas returns the events turning sent into current, in name
+// order with deletions last, and updates sent to match.
+func diffTenantQuotas(sent map[string]*corev1alpha1.TenantQuota, current *corev1alpha1.TenantQuotaList) []watch.Event {
+	var out []watch.Event
+	seen := make(map[string]struct{}, len(current.Items))
+	for i := range current.Items {
+		tq := &current.Items[i]
+		seen[tq.Name] = struct{}{}
+		prev, ok := sent[tq.Name]
+		switch {
+		case !ok:
+			out = append(out, watch.Event{Type: watch.Added, Object: tq})
+		case !equality.Semantic.DeepEqual(prev.Status, tq.Status):
+			out = append(out, watch.Event{Type: watch.Modified, Object: tq})
+		default:
+			continue
+		}
+		sent[tq.Name] = tq
+	}
+	var gone []string
+	for name := range sent {
+		if _, ok := seen[name]; !ok {
+			gone = append(gone, name)
+		}
+	}
+	sort.Strings(gone)
+	for _, name := range gone {
+		out = append(out, watch.Event{Type: watch.Deleted, Object: sent[name]})
+		delete(sent, name)
+	}
+	return out
+}
+
+// -----------------------------------------------------------------------------
+// TableConvertor
+// -----------------------------------------------------------------------------
+
+func (r *REST) ConvertToTable(_ context.Context, obj runtime.Object, _ runtime.Object) (*metav1.Table, error) {
+	row := func(o *corev1alpha1.TenantQuota) metav1.TableRow {
+		return metav1.TableRow{
+			Cells: []interface{}{
+				o.Name,
+				o.Status.Parent,
+				o.Status.PoolRoot,
+				resourceListString(o.Status.Hard),
+				len(o.Status.Overcommitted) > 0,
+			},
+			Object: runtime.RawExtension{Object: o},
+		}
+	}
+	tbl := &metav1.Table{
+		TypeMeta: metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "Table"},
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "NAME", Type: "string"},
+			{Name: "PARENT", Type: "string"},
+			{Name: "POOL", Type: "string"},
+			{Name: "HARD", Type: "string"},
+			{Name: "OVERCOMMITTED", Type: "boolean"},
+		},
+	}
+	switch v := obj.(type) {
+	case *corev1alpha1.TenantQuotaList:
+		for i := range v.Items {
+			tbl.Rows = append(tbl.Rows, row(&v.Items[i]))
+		}
+		tbl.ResourceVersion = v.ResourceVersion
+	case *corev1alpha1.TenantQuota:
+		tbl.Rows = append(tbl.Rows, row(v))
+		tbl.ResourceVersion = v.ResourceVersion
+	default:
+		return nil, notAcceptable{r.gvr.GroupResource(), fmt.Sprintf("unexpected %T", obj)}
+	}
+	return tbl, nil
+}
+
+// -----------------------------------------------------------------------------
+// Helpers / boiler-plate
+// -----------------------------------------------------------------------------
+
+func resourceListString(rl corev1.ResourceList) string {
+	names := make([]string, 0, len(rl))
+	for name := range rl {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		q := rl[corev1.ResourceName(name)]
+		parts = append(parts, name+"="+q.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+type notAcceptable struct {
+	resource schema.GroupResource
+	message  string
+}
+
+func (e notAcceptable) Error() string { return e.message }
+func (e notAcceptable) Status() metav1.Status {
+	return metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusNotAcceptable,
+		Reason:  metav1.StatusReason("NotAcceptable"),
+		Message: e.message,
+	}
+}
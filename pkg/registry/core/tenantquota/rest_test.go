@@ -0,0 +1,204 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package tenantquota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appsv1alpha1 "github.com/cozystack/cozystack/pkg/apis/apps/v1alpha1"
+	corev1alpha1 "github.com/cozystack/cozystack/pkg/apis/core/v1alpha1"
+)
+
+func tenantRelease(ns, name string) *helmv2.HelmRelease {
+	return &helmv2.HelmRelease{ObjectMeta: metav1.ObjectMeta{
+		Namespace: ns,
+		Name:      name,
+		Labels:    map[string]string{appsv1alpha1.ApplicationKindLabel: "Tenant"},
+	}}
+}
+
+func resourceQuota(ns, name, hard, used string) *corev1.ResourceQuota {
+	rq := &corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+	if hard != "" {
+		rq.Spec.Hard = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(hard)}
+	}
+	if used != "" {
+		rq.Status.Used = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(used)}
+	}
+	return rq
+}
+
+// newTestREST builds a tree of tenant-root (unbounded) > tenant-foo (cpu 10)
+// > {tenant-foo-bar (cpu 4), tenant-foo-qux (unbounded, clamped to 5)} and
+// gives alice a RoleBinding in tenant-foo-qux only.
+func newTestREST(t *testing.T) *REST {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = rbacv1.AddToScheme(scheme)
+	_ = helmv2.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		tenantRelease("tenant-root", "tenant-root"),
+		tenantRelease("tenant-root", "tenant-foo"),
+		tenantRelease("tenant-foo", "tenant-bar"),
+		tenantRelease("tenant-foo", "tenant-qux"),
+		resourceQuota("tenant-foo", "tenant-quota", "10", "1"),
+		resourceQuota("tenant-foo-bar", "tenant-quota", "4", "3"),
+		resourceQuota("tenant-foo-qux", "tenant-quota-allocated", "5", "2"),
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo-qux", Name: "alice"},
+			Subjects:   []rbacv1.Subject{{Kind: "User", Name: "alice"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "view"},
+		},
+	).Build()
+	return NewREST(c, c, &informertest.FakeInformers{Scheme: scheme})
+}
+
+func TestList_FiltersByAccessAndExplainsPool(t *testing.T) {
+	r := newTestREST(t)
+	ctx := request.WithUser(context.Background(), &user.DefaultInfo{Name: "alice"})
+
+	obj, err := r.List(ctx, nil)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	list := obj.(*corev1alpha1.TenantQuotaList)
+	if len(list.Items) != 1 || list.Items[0].Name != "tenant-foo-qux" {
+		t.Fatalf("items = %+v, want only tenant-foo-qux", list.Items)
+	}
+	st := list.Items[0].Status
+	if st.Parent != "tenant-foo" || st.PoolRoot != "tenant-foo" {
+		t.Errorf("parent/poolRoot = %q/%q, want tenant-foo/tenant-foo", st.Parent, st.PoolRoot)
+	}
+	if got := st.Available[corev1.ResourceCPU]; got.Cmp(resource.MustParse("6")) != 0 {
+		t.Errorf("available cpu = %s, want 6", got.String())
+	}
+	if got := st.Hard[corev1.ResourceCPU]; got.Cmp(resource.MustParse("5")) != 0 {
+		t.Errorf("hard cpu = %s, want 5", got.String())
+	}
+	if len(st.Allocations) != 1 || st.Allocations[0].Namespace != "tenant-foo-bar" {
+		t.Errorf("allocations = %+v, want the tenant-foo-bar carve-out", st.Allocations)
+	}
+}
+
+func TestList_AdminSeesWholeTree(t *testing.T) {
+	r := newTestREST(t)
+	ctx := request.WithUser(context.Background(), &user.DefaultInfo{Name: "admin", Groups: []string{"system:masters"}})
+
+	obj, err := r.List(ctx, nil)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	list := obj.(*corev1alpha1.TenantQuotaList)
+	want := []string{"tenant-foo", "tenant-foo-bar", "tenant-foo-qux", "tenant-root"}
+	if len(list.Items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(list.Items), len(want))
+	}
+	for i, name := range want {
+		if list.Items[i].Name != name {
+			t.Errorf("item %d = %q, want %q", i, list.Items[i].Name, name)
+		}
+	}
+	root := list.Items[3].Status
+	if got := root.SubtreeUsed[corev1.ResourceCPU]; got.Cmp(resource.MustParse("6")) != 0 {
+		t.Errorf("tenant-root subtreeUsed cpu = %s, want 6", got.String())
+	}
+}
+
+func TestGet_ForbiddenWithoutAccess(t *testing.T) {
+	r := newTestREST(t)
+	ctx := request.WithUser(context.Background(), &user.DefaultInfo{Name: "alice"})
+
+	if _, err := r.Get(ctx, "tenant-foo", &metav1.GetOptions{}); !apierrors.IsForbidden(err) {
+		t.Errorf("Get tenant-foo err = %v, want Forbidden", err)
+	}
+	if _, err := r.Get(ctx, "kube-system", &metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Get kube-system err = %v, want NotFound", err)
+	}
+	obj, err := r.Get(ctx, "tenant-foo-qux", &metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get tenant-foo-qux: %v", err)
+	}
+	if tq := obj.(*corev1alpha1.TenantQuota); tq.Status.PoolRoot != "tenant-foo" {
+		t.Errorf("poolRoot = %q, want tenant-foo", tq.Status.PoolRoot)
+	}
+}
+
+// nextEvent waits for the event of a TenantQuota, skipping the ones of
+// other nodes the same change touched.
+func nextEvent(t *testing.T, w watch.Interface, typ watch.EventType, name string) *corev1alpha1.TenantQuota {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				t.Fatalf("watch closed before %s %s", typ, name)
+			}
+			if tq, isTQ := ev.Object.(*corev1alpha1.TenantQuota); isTQ && ev.Type == typ && tq.Name == name {
+				return tq
+			}
+		case <-timeout:
+			t.Fatalf("no %s event for %s", typ, name)
+		}
+	}
+}
+
+func TestWatch_FollowsUpstreamChanges(t *testing.T) {
+	r := newTestREST(t)
+	ctx, cancel := context.WithCancel(request.WithUser(context.Background(), &user.DefaultInfo{Name: "alice"}))
+	defer cancel()
+
+	w, err := r.Watch(ctx, nil)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	defer w.Stop()
+	nextEvent(t, w, watch.Added, "tenant-foo-qux")
+
+	informers := r.informers.(*informertest.FakeInformers)
+	quotas, err := informers.FakeInformerFor(ctx, &corev1.ResourceQuota{})
+	if err != nil {
+		t.Fatalf("informer: %v", err)
+	}
+	rq := &corev1.ResourceQuota{}
+	if err := r.c.Get(ctx, client.ObjectKey{Namespace: "tenant-foo-qux", Name: "tenant-quota-allocated"}, rq); err != nil {
+		t.Fatalf("get quota: %v", err)
+	}
+	rq.Status.Used = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("3")}
+	if err := r.c.Update(ctx, rq); err != nil {
+		t.Fatalf("update quota: %v", err)
+	}
+	quotas.Update(rq, rq)
+	tq := nextEvent(t, w, watch.Modified, "tenant-foo-qux")
+	if got := tq.Status.Used[corev1.ResourceCPU]; got.Cmp(resource.MustParse("3")) != 0 {
+		t.Errorf("used cpu = %s, want 3", got.String())
+	}
+
+	releases, err := informers.FakeInformerFor(ctx, &helmv2.HelmRelease{})
+	if err != nil {
+		t.Fatalf("informer: %v", err)
+	}
+	hr := tenantRelease("tenant-foo", "tenant-qux")
+	if err := r.c.Delete(ctx, hr); err != nil {
+		t.Fatalf("delete release: %v", err)
+	}
+	releases.Delete(hr)
+	nextEvent(t, w, watch.Deleted, "tenant-foo-qux")
+}
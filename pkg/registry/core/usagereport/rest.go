@@ -0,0 +1,552 @@
+// SPDX-License-Identifier: Apache-2.0
+// UsageReport registry: a read-only, virtual resource that turns the
+// UsageRollups written by the metering controller into priced hourly and
+// daily reports. A report covers its namespace and every tenant below it, so
+// a parent tenant can bill for its whole subtree in one read.
+
+package usagereport
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metainternal "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	"github.com/cozystack/cozystack/internal/controller/metering"
+	corev1alpha1 "github.com/cozystack/cozystack/pkg/apis/core/v1alpha1"
+	"github.com/cozystack/cozystack/pkg/registry/core/tenantnamespace"
+)
+
+const (
+	prefix        = "tenant-"
+	singularName  = "usagereport"
+	hourlyPrefix  = "hourly-"
+	dailyPrefix   = "daily-"
+	monthLayout   = "2006-01"
+	priceListName = "default"
+)
+
+var (
+	_ rest.Lister               = &REST{}
+	_ rest.Getter               = &REST{}
+	_ rest.Watcher              = &REST{}
+	_ rest.TableConvertor       = &REST{}
+	_ rest.Scoper               = &REST{}
+	_ rest.SingularNameProvider = &REST{}
+)
+
+// REST implements the read-only UsageReport resource.
+type REST struct {
+	// c lists Namespaces from the informer cache.
+	c client.Client
+	// w reads UsageRollups and the PriceList uncached: rollups accumulate a
+	// year of history per tenant and have no business in an informer.
+	w      client.WithWatch
+	access *tenantnamespace.REST
+	gvr    schema.GroupVersionResource
+}
+
+func NewREST(c client.Client, w client.WithWatch) *REST {
+	return &REST{
+		c:      c,
+		w:      w,
+		access: tenantnamespace.NewREST(c, w),
+		gvr: schema.GroupVersionResource{
+			Group:    corev1alpha1.GroupName,
+			Version:  "v1alpha1",
+			Resource: "usagereports",
+		},
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Basic meta
+// -----------------------------------------------------------------------------
+
+func (*REST) NamespaceScoped() bool   { return true }
+func (*REST) New() runtime.Object     { return &corev1alpha1.UsageReport{} }
+func (*REST) NewList() runtime.Object { return &corev1alpha1.UsageReportList{} }
+func (*REST) Kind() string            { return "UsageReport" }
+func (r *REST) GroupVersionKind(_ schema.GroupVersion) schema.GroupVersionKind {
+	return r.gvr.GroupVersion().WithKind("UsageReport")
+}
+func (*REST) GetSingularName() string { return singularName }
+func (*REST) Destroy()                {}
+
+// -----------------------------------------------------------------------------
+// Lister / Getter
+// -----------------------------------------------------------------------------
+
+// List returns the reports available in the namespace, one hourly report
+// per day and one daily report per month metered anywhere in its subtree,
+// which is what Get reports on, without rows.
+func (r *REST) List(ctx context.Context, _ *metainternal.ListOptions) (runtime.Object, error) {
+	ns, err := r.authorize(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	subtree, err := r.subtree(ctx, ns)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]struct{}{}
+	for _, namespace := range subtree {
+		rollups := &metav1.PartialObjectMetadataList{}
+		rollups.SetGroupVersionKind(cozyv1alpha1.GroupVersion.WithKind("UsageRollupList"))
+		err := r.w.List(ctx, rollups, client.InNamespace(namespace))
+		if apimeta.IsNoMatchError(err) {
+			break
+		}
+		if err != nil {
+			return nil, apierrors.NewInternalError(fmt.Errorf("failed to list usage rollups: %w", err))
+		}
+		for i := range rollups.Items {
+			date, ok := rollupDate(rollups.Items[i].Name)
+			if !ok {
+				continue
+			}
+			names[hourlyPrefix+date] = struct{}{}
+			names[dailyPrefix+date[:len(monthLayout)]] = struct{}{}
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	out := &corev1alpha1.UsageReportList{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1alpha1.SchemeGroupVersion.String(),
+			Kind:       "UsageReportList",
+		},
+		ListMeta: metav1.ListMeta{ResourceVersion: "0"},
+	}
+	for _, name := range sorted {
+		p, _ := parsePeriod(name)
+		out.Items = append(out.Items, newReport(ns, name, p))
+	}
+	return out, nil
+}
+
+func (r *REST) Get(ctx context.Context, name string, _ *metav1.GetOptions) (runtime.Object, error) {
+	ns, err := r.authorize(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := parsePeriod(name)
+	if !ok {
+		return nil, apierrors.NewNotFound(r.gvr.GroupResource(), name)
+	}
+
+	subtree, err := r.subtree(ctx, ns)
+	if err != nil {
+		return nil, err
+	}
+	rollups, err := r.rollups(ctx, subtree, p)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := r.priceList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := newReport(ns, name, p)
+	Fill(&report.Status, rollups, prices)
+	return &report, nil
+}
+
+// authorize resolves the request namespace and checks the caller may read
+// it. Every authenticated user may reach this resource, so tenant scoping
+// is enforced here, the same way as for tenantquotas.
+func (r *REST) authorize(ctx context.Context, name string) (string, error) {
+	ns, ok := request.NamespaceFrom(ctx)
+	if !ok || ns == "" {
+		return "", apierrors.NewBadRequest("namespace required")
+	}
+	if !strings.HasPrefix(ns, prefix) {
+		return "", apierrors.NewNotFound(r.gvr.GroupResource(), name)
+	}
+	hasAccess, err := r.access.HasAccessToNamespace(ctx, ns)
+	if err != nil {
+		return "", err
+	}
+	if !hasAccess {
+		return "", apierrors.NewForbidden(r.gvr.GroupResource(), name, fmt.Errorf("access denied to namespace %s", ns))
+	}
+	return ns, nil
+}
+
+func (r *REST) priceList(ctx context.Context) (*cozyv1alpha1.PriceList, error) {
+	pl := &cozyv1alpha1.PriceList{}
+	err := r.w.Get(ctx, types.NamespacedName{Name: priceListName}, pl)
+	if apierrors.IsNotFound(err) || apimeta.IsNoMatchError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apierrors.NewInternalError(fmt.Errorf("failed to get price list: %w", err))
+	}
+	return pl, nil
+}
+
+// subtree returns root and the tenant namespaces below it; the tenant
+// hierarchy is encoded in namespace names.
+func (r *REST) subtree(ctx context.Context, root string) ([]string, error) {
+	list := &corev1.NamespaceList{}
+	if err := r.c.List(ctx, list); err != nil {
+		return nil, apierrors.NewInternalError(fmt.Errorf("failed to list namespaces: %w", err))
+	}
+	out := []string{root}
+	for i := range list.Items {
+		if strings.HasPrefix(list.Items[i].Name, root+"-") {
+			out = append(out, list.Items[i].Name)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// rollups reads the rollups covering p from each namespace in turn: one
+// Get per namespace for a day, one List per namespace for a month.
+func (r *REST) rollups(ctx context.Context, namespaces []string, p period) ([]cozyv1alpha1.UsageRollup, error) {
+	var out []cozyv1alpha1.UsageRollup
+	for _, ns := range namespaces {
+		if p.granularity == corev1alpha1.UsageReportHourly {
+			rollup := cozyv1alpha1.UsageRollup{}
+			key := types.NamespacedName{Namespace: ns, Name: metering.RollupPrefix + p.start.Format(metering.DateLayout)}
+			err := r.w.Get(ctx, key, &rollup)
+			switch {
+			case apimeta.IsNoMatchError(err):
+				return nil, nil
+			case apierrors.IsNotFound(err):
+				continue
+			case err != nil:
+				return nil, apierrors.NewInternalError(fmt.Errorf("failed to get usage rollup %s: %w", key, err))
+			}
+			out = append(out, rollup)
+			continue
+		}
+		list := &cozyv1alpha1.UsageRollupList{}
+		err := r.w.List(ctx, list, client.InNamespace(ns))
+		if apimeta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, apierrors.NewInternalError(fmt.Errorf("failed to list usage rollups: %w", err))
+		}
+		for i := range list.Items {
+			date, ok := rollupDate(list.Items[i].Name)
+			if !ok {
+				continue
+			}
+			if day, _ := time.Parse(metering.DateLayout, date); !day.Before(p.start) && day.Before(p.end) {
+				out = append(out, list.Items[i])
+			}
+		}
+	}
+	return out, nil
+}
+
+// rollupDate returns the day a UsageRollup covers, from its name.
+func rollupDate(name string) (string, bool) {
+	date, ok := strings.CutPrefix(name, metering.RollupPrefix)
+	if !ok {
+		return "", false
+	}
+	if _, err := time.Parse(metering.DateLayout, date); err != nil {
+		return "", false
+	}
+	return date, true
+}
+
+// -----------------------------------------------------------------------------
+// Report computation
+// -----------------------------------------------------------------------------
+
+type period struct {
+	granularity string
+	start, end  time.Time
+}
+
+// parsePeriod reads hourly-<YYYY-MM-DD> or daily-<YYYY-MM>.
+func parsePeriod(name string) (period, bool) {
+	if date, ok := strings.CutPrefix(name, hourlyPrefix); ok {
+		t, err := time.Parse(metering.DateLayout, date)
+		if err != nil {
+			return period{}, false
+		}
+		return period{corev1alpha1.UsageReportHourly, t, t.AddDate(0, 0, 1)}, true
+	}
+	if month, ok := strings.CutPrefix(name, dailyPrefix); ok {
+		t, err := time.Parse(monthLayout, month)
+		if err != nil {
+			return period{}, false
+		}
+		return period{corev1alpha1.UsageReportDaily, t, t.AddDate(0, 1, 0)}, true
+	}
+	return period{}, false
+}
+
+func newReport(ns, name string, p period) corev1alpha1.UsageReport {
+	return corev1alpha1.UsageReport{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1alpha1.SchemeGroupVersion.String(),
+			Kind:       "UsageReport",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       ns,
+			ResourceVersion: "0",
+		},
+		Status: corev1alpha1.UsageReportStatus{
+			Granularity: p.granularity,
+			Start:       metav1.NewTime(p.start),
+			End:         metav1.NewTime(p.end),
+		},
+	}
+}
+
+type rowKey struct {
+	start     time.Time
+	namespace string
+	kind      string
+	name      string
+	resource  string
+}
+
+// Fill computes the rows, costs and CSV of a report from the rollups of its
+// period. Daily reports sum the hours of each day.
+func Fill(st *corev1alpha1.UsageReportStatus, rollups []cozyv1alpha1.UsageRollup, prices *cozyv1alpha1.PriceList) {
+	sums := map[rowKey]resource.Quantity{}
+	for i := range rollups {
+		for _, h := range rollups[i].Status.Hours {
+			start := h.Start.UTC()
+			if start.Before(st.Start.Time) || !start.Before(st.End.Time) {
+				continue
+			}
+			if st.Granularity == corev1alpha1.UsageReportDaily {
+				start = start.Truncate(24 * time.Hour)
+			}
+			for _, app := range h.Applications {
+				for res, q := range app.Usage {
+					k := rowKey{start, rollups[i].Namespace, app.Kind, app.Name, res}
+					sum := sums[k]
+					sum.Add(q)
+					sums[k] = sum
+				}
+			}
+		}
+	}
+
+	keys := make([]rowKey, 0, len(sums))
+	for k, q := range sums {
+		if !q.IsZero() {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch {
+		case !a.start.Equal(b.start):
+			return a.start.Before(b.start)
+		case a.namespace != b.namespace:
+			return a.namespace < b.namespace
+		case a.kind != b.kind:
+			return a.kind < b.kind
+		case a.name != b.name:
+			return a.name < b.name
+		}
+		return a.resource < b.resource
+	})
+
+	if prices != nil {
+		st.Currency = prices.Spec.Currency
+	}
+	var total float64
+	priced := false
+	for _, k := range keys {
+		row := corev1alpha1.UsageReportRow{
+			Start:     metav1.NewTime(k.start),
+			Namespace: k.namespace,
+			Kind:      k.kind,
+			Name:      k.name,
+			Resource:  k.resource,
+			Quantity:  sums[k],
+		}
+		if price, ok := priceOf(prices, k.resource); ok {
+			cost := row.Quantity.AsApproximateFloat64() * price.PerHour.AsApproximateFloat64()
+			if price.Unit != nil && !price.Unit.IsZero() {
+				cost /= price.Unit.AsApproximateFloat64()
+			}
+			row.Cost = formatCost(cost)
+			total += cost
+			priced = true
+		}
+		st.Rows = append(st.Rows, row)
+	}
+	if priced {
+		st.TotalCost = formatCost(total)
+	}
+	st.CSV = renderCSV(st.Rows)
+}
+
+// priceOf finds the price of a resource: an exact entry wins, otherwise
+// the longest matching "*" pattern.
+func priceOf(prices *cozyv1alpha1.PriceList, res string) (*cozyv1alpha1.Price, bool) {
+	if prices == nil {
+		return nil, false
+	}
+	var best *cozyv1alpha1.Price
+	for i := range prices.Spec.Prices {
+		p := &prices.Spec.Prices[i]
+		if p.Resource == res {
+			return p, true
+		}
+		if suffix, ok := strings.CutPrefix(p.Resource, "*"); ok && strings.HasSuffix(res, suffix) &&
+			(best == nil || len(p.Resource) > len(best.Resource)) {
+			best = p
+		}
+	}
+	return best, best != nil
+}
+
+func formatCost(v float64) string {
+	return strconv.FormatFloat(v, 'f', 4, 64)
+}
+
+func renderCSV(rows []corev1alpha1.UsageReportRow) string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"start", "namespace", "kind", "name", "resource", "quantity", "cost"})
+	for _, row := range rows {
+		_ = w.Write([]string{
+			row.Start.UTC().Format(time.RFC3339),
+			row.Namespace,
+			row.Kind,
+			row.Name,
+			row.Resource,
+			strconv.FormatFloat(row.Quantity.AsApproximateFloat64(), 'f', -1, 64),
+			row.Cost,
+		})
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// -----------------------------------------------------------------------------
+// Watcher (one-shot): reports are computed on read, so emit the available
+// ones once and hold the stream open until the client goes away.
+// -----------------------------------------------------------------------------
+
+func (r *REST) Watch(ctx context.Context, opts *metainternal.ListOptions) (watch.Interface, error) {
+	events := make(chan watch.Event)
+	pw := watch.NewProxyWatcher(events)
+
+	go func() {
+		defer pw.Stop()
+		listObj, err := r.List(ctx, opts)
+		if err != nil {
+			klog.ErrorS(err, "usagereports: initial list for watch failed")
+		} else {
+			list := listObj.(*corev1alpha1.UsageReportList)
+			for i := range list.Items {
+				select {
+				case events <- watch.Event{Type: watch.Added, Object: &list.Items[i]}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+		<-ctx.Done()
+	}()
+
+	return pw, nil
+}
+
+// -----------------------------------------------------------------------------
+// TableConvertor
+// -----------------------------------------------------------------------------
+
+func (r *REST) ConvertToTable(_ context.Context, obj runtime.Object, _ runtime.Object) (*metav1.Table, error) {
+	row := func(o *corev1alpha1.UsageReport) metav1.TableRow {
+		total := o.Status.TotalCost
+		if total != "" && o.Status.Currency != "" {
+			total += " " + o.Status.Currency
+		}
+		return metav1.TableRow{
+			Cells: []interface{}{
+				o.Name,
+				o.Status.Granularity,
+				o.Status.Start.UTC().Format(time.RFC3339),
+				o.Status.End.UTC().Format(time.RFC3339),
+				total,
+			},
+			Object: runtime.RawExtension{Object: o},
+		}
+	}
+	tbl := &metav1.Table{
+		TypeMeta: metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "Table"},
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "NAME", Type: "string"},
+			{Name: "GRANULARITY", Type: "string"},
+			{Name: "START", Type: "string"},
+			{Name: "END", Type: "string"},
+			{Name: "TOTAL", Type: "string"},
+		},
+	}
+	switch v := obj.(type) {
+	case *corev1alpha1.UsageReportList:
+		for i := range v.Items {
+			tbl.Rows = append(tbl.Rows, row(&v.Items[i]))
+		}
+		tbl.ResourceVersion = v.ResourceVersion
+	case *corev1alpha1.UsageReport:
+		tbl.Rows = append(tbl.Rows, row(v))
+		tbl.ResourceVersion = v.ResourceVersion
+	default:
+		return nil, notAcceptable{r.gvr.GroupResource(), fmt.Sprintf("unexpected %T", obj)}
+	}
+	return tbl, nil
+}
+
+// -----------------------------------------------------------------------------
+// Helpers / boiler-plate
+// -----------------------------------------------------------------------------
+
+type notAcceptable struct {
+	resource schema.GroupResource
+	message  string
+}
+
+func (e notAcceptable) Error() string { return e.message }
+func (e notAcceptable) Status() metav1.Status {
+	return metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusNotAcceptable,
+		Reason:  metav1.StatusReason("NotAcceptable"),
+		Message: e.message,
+	}
+}
@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package usagereport
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cozyv1alpha1 "github.com/cozystack/cozystack/api/v1alpha1"
+	corev1alpha1 "github.com/cozystack/cozystack/pkg/apis/core/v1alpha1"
+)
+
+func rollup(ns, date string, hours ...cozyv1alpha1.UsageHour) *cozyv1alpha1.UsageRollup {
+	return &cozyv1alpha1.UsageRollup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "usage-" + date},
+		Status:     cozyv1alpha1.UsageRollupStatus{Date: date, Hours: hours},
+	}
+}
+
+func hour(at string, kind, name string, usage map[string]string) cozyv1alpha1.UsageHour {
+	t, _ := time.Parse(time.RFC3339, at)
+	u := map[string]resource.Quantity{}
+	for k, v := range usage {
+		u[k] = resource.MustParse(v)
+	}
+	return cozyv1alpha1.UsageHour{
+		Start:          metav1.NewTime(t),
+		SampledSeconds: 3600,
+		Applications:   []cozyv1alpha1.ApplicationUsage{{Kind: kind, Name: name, Usage: u}},
+	}
+}
+
+// newTestREST meters tenant-foo on 2026-03-01 and 2026-03-02, its child
+// tenant-foo-bar on 2026-02-28 and 2026-03-01, and an unrelated
+// tenant-foobar, and gives alice a RoleBinding in tenant-foo only.
+func newTestREST(t *testing.T) *REST {
+	t.Helper()
+	gib := resource.MustParse("1Gi")
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = rbacv1.AddToScheme(scheme)
+	_ = cozyv1alpha1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(
+			namespace("tenant-foo"), namespace("tenant-foo-bar"), namespace("tenant-foobar"),
+			rollup("tenant-foo", "2026-03-01",
+				hour("2026-03-01T10:00:00Z", "Postgres", "db", map[string]string{"cpu": "2", "memory": "4Gi"}),
+				hour("2026-03-01T11:00:00Z", "Postgres", "db", map[string]string{"cpu": "2"}),
+			),
+			rollup("tenant-foo", "2026-03-02",
+				hour("2026-03-02T00:00:00Z", "Postgres", "db", map[string]string{"cpu": "1"}),
+			),
+			rollup("tenant-foo-bar", "2026-02-28",
+				hour("2026-02-28T23:00:00Z", "VMInstance", "vm", map[string]string{"cpu": "1"}),
+			),
+			rollup("tenant-foo-bar", "2026-03-01",
+				hour("2026-03-01T10:00:00Z", "VMInstance", "vm", map[string]string{
+					"replicated.storageclass.storage.k8s.io/requests.storage": "10Gi",
+				}),
+			),
+			rollup("tenant-foobar", "2026-03-01",
+				hour("2026-03-01T10:00:00Z", "Redis", "cache", map[string]string{"cpu": "8"}),
+			),
+			&cozyv1alpha1.PriceList{
+				ObjectMeta: metav1.ObjectMeta{Name: "default"},
+				Spec: cozyv1alpha1.PriceListSpec{Currency: "EUR", Prices: []cozyv1alpha1.Price{
+					{Resource: "cpu", PerHour: resource.MustParse("0.02")},
+					{Resource: "*/requests.storage", PerHour: resource.MustParse("0.001"), Unit: &gib},
+					{Resource: "*.storageclass.storage.k8s.io/requests.storage", PerHour: resource.MustParse("0.002"), Unit: &gib},
+				}},
+			},
+			&rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-foo", Name: "alice"},
+				Subjects:   []rbacv1.Subject{{Kind: "User", Name: "alice"}},
+				RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "view"},
+			},
+		).Build()
+	return NewREST(c, c)
+}
+
+func namespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func aliceIn(ns string) context.Context {
+	ctx := request.WithUser(context.Background(), &user.DefaultInfo{Name: "alice"})
+	return request.WithNamespace(ctx, ns)
+}
+
+func TestGet_HourlyCoversSubtreeAndPrices(t *testing.T) {
+	r := newTestREST(t)
+	obj, err := r.Get(aliceIn("tenant-foo"), "hourly-2026-03-01", &metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	st := obj.(*corev1alpha1.UsageReport).Status
+	if st.Granularity != corev1alpha1.UsageReportHourly || st.Currency != "EUR" {
+		t.Errorf("granularity/currency = %q/%q, want Hourly/EUR", st.Granularity, st.Currency)
+	}
+	if len(st.Rows) != 4 {
+		t.Fatalf("rows = %+v, want 4 (no tenant-foobar)", st.Rows)
+	}
+	for _, row := range st.Rows {
+		if row.Namespace == "tenant-foobar" {
+			t.Errorf("report leaks sibling tenant-foobar: %+v", row)
+		}
+	}
+	// 10:00 rows sort by namespace, then resource.
+	if st.Rows[0].Resource != "cpu" || st.Rows[0].Cost != "0.0400" {
+		t.Errorf("row 0 = %+v, want 2 cpu-hours costing 0.0400", st.Rows[0])
+	}
+	if st.Rows[1].Resource != "memory" || st.Rows[1].Cost != "" {
+		t.Errorf("row 1 = %+v, want unpriced memory", st.Rows[1])
+	}
+	if st.Rows[2].Namespace != "tenant-foo-bar" || st.Rows[2].Cost != "0.0200" {
+		t.Errorf("row 2 = %+v, want 10Gi-hours at the longest pattern (0.0200)", st.Rows[2])
+	}
+	if st.TotalCost != "0.1000" {
+		t.Errorf("totalCost = %q, want 0.1000", st.TotalCost)
+	}
+	lines := strings.Split(strings.TrimSpace(st.CSV), "\n")
+	if len(lines) != 5 || lines[1] != "2026-03-01T10:00:00Z,tenant-foo,Postgres,db,cpu,2,0.0400" {
+		t.Errorf("csv = %q", st.CSV)
+	}
+}
+
+func TestGet_DailySumsHours(t *testing.T) {
+	r := newTestREST(t)
+	if _, err := r.Get(aliceIn("tenant-foo-bar"), "daily-2026-03", &metav1.GetOptions{}); !apierrors.IsForbidden(err) {
+		t.Fatalf("Get in tenant-foo-bar err = %v, want Forbidden", err)
+	}
+	obj, err := r.Get(aliceIn("tenant-foo"), "daily-2026-03", &metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	var cpu []string
+	for _, row := range obj.(*corev1alpha1.UsageReport).Status.Rows {
+		if row.Resource == "cpu" {
+			cpu = append(cpu, row.Start.UTC().Format("01-02")+"="+row.Quantity.String())
+		}
+	}
+	if strings.Join(cpu, ",") != "03-01=4,03-02=1" {
+		t.Errorf("daily cpu = %v, want 03-01=4,03-02=1", cpu)
+	}
+	if _, err := r.Get(aliceIn("tenant-foo"), "weekly-2026-10", &metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Get weekly err = %v, want NotFound", err)
+	}
+}
+
+func TestList_OffersMeteredPeriods(t *testing.T) {
+	r := newTestREST(t)
+	obj, err := r.List(aliceIn("tenant-foo"), nil)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var names []string
+	for _, item := range obj.(*corev1alpha1.UsageReportList).Items {
+		names = append(names, item.Name)
+	}
+	// The child's February usage is part of what Get reports for tenant-foo.
+	if got := strings.Join(names, ","); got != "daily-2026-02,daily-2026-03,hourly-2026-02-28,hourly-2026-03-01,hourly-2026-03-02" {
+		t.Errorf("items = %s", got)
+	}
+}
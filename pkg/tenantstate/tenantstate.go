@@ -0,0 +1,124 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tenantstate holds the tenant lifecycle markers shared by the
+// controller that acts on tenant namespaces and by the aggregated API, which
+// reads them to refuse new applications.
+package tenantstate
+
+import (
+	"strings"
+)
+
+// State is a declared tenant lifecycle state.
+type State string
+
+const (
+	Active    State = "active"
+	Suspended State = "suspended"
+	Archived  State = "archived"
+	Deleting  State = "deleting"
+)
+
+// Phase is what the controller has brought a namespace to.
+type Phase string
+
+const (
+	PhaseActive    Phase = "Active"
+	PhaseSuspended Phase = "Suspended"
+	PhaseArchiving Phase = "Archiving"
+	PhaseArchived  Phase = "Archived"
+	// PhasePendingDeletion is suspended with the deletion of the tenant, or
+	// of an ancestor, scheduled.
+	PhasePendingDeletion Phase = "PendingDeletion"
+)
+
+const (
+	// LifecycleLabel carries the declared state on a tenant namespace. It is
+	// rendered by the tenant chart.
+	LifecycleLabel = "namespace.cozystack.io/lifecycle"
+
+	// PhaseAnnotation, MessageAnnotation and DeletionAtAnnotation are
+	// written by the controller on each tenant namespace it acts on.
+	PhaseAnnotation      = "namespace.cozystack.io/lifecycle-phase"
+	MessageAnnotation    = "namespace.cozystack.io/lifecycle-message"
+	DeletionAtAnnotation = "namespace.cozystack.io/deletion-at"
+
+	// RootTenant is the namespace of the root tenant; every other tenant
+	// namespace is TenantPrefix followed by its path below the root.
+	RootTenant   = "tenant-root"
+	TenantPrefix = "tenant-"
+)
+
+// rank orders states from least to most restrictive.
+func (s State) rank() int {
+	switch s {
+	case Suspended:
+		return 1
+	case Archived:
+		return 2
+	case Deleting:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Declared reads the state declared in a namespace's labels. A missing or
+// unknown value is active.
+func Declared(labels map[string]string) State {
+	switch s := State(labels[LifecycleLabel]); s {
+	case Suspended, Archived, Deleting:
+		return s
+	default:
+		return Active
+	}
+}
+
+// Lineage returns a tenant namespace followed by its ancestors, nearest
+// first, ending with tenant-root: tenant-foo-bar yields tenant-foo-bar,
+// tenant-foo, tenant-root. A namespace outside the tenant tree yields nil.
+func Lineage(namespace string) []string {
+	if namespace == RootTenant {
+		return []string{RootTenant}
+	}
+	if !strings.HasPrefix(namespace, TenantPrefix) {
+		return nil
+	}
+	out := []string{}
+	for ns := namespace; ; {
+		out = append(out, ns)
+		i := strings.LastIndex(ns, "-")
+		if i <= len(TenantPrefix)-1 {
+			break
+		}
+		ns = ns[:i]
+	}
+	return append(out, RootTenant)
+}
+
+// Effective returns the state a namespace is held to and the namespace that
+// declared it: the most restrictive state along its lineage, the nearest on
+// a tie. labelsOf returns a namespace's labels, nil if it does not exist.
+func Effective(namespace string, labelsOf func(string) map[string]string) (State, string) {
+	state, from := Active, namespace
+	for _, ns := range Lineage(namespace) {
+		if s := Declared(labelsOf(ns)); s.rank() > state.rank() {
+			state, from = s, ns
+		}
+	}
+	return state, from
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2026 The Cozystack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenantstate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLineageAndEffective(t *testing.T) {
+	if got := strings.Join(Lineage("tenant-foo-bar"), ","); got != "tenant-foo-bar,tenant-foo,tenant-root" {
+		t.Errorf("Lineage = %s", got)
+	}
+	if Lineage("kube-system") != nil {
+		t.Errorf("Lineage of a non-tenant namespace must be nil")
+	}
+	labels := map[string]map[string]string{
+		"tenant-foo":     {LifecycleLabel: "suspended"},
+		"tenant-foo-bar": {LifecycleLabel: "archived"},
+	}
+	labelsOf := func(ns string) map[string]string { return labels[ns] }
+	if s, from := Effective("tenant-foo-bar-baz", labelsOf); s != Archived || from != "tenant-foo-bar" {
+		t.Errorf("Effective = %s from %s, want archived from tenant-foo-bar", s, from)
+	}
+	if s, _ := Effective("tenant-other", labelsOf); s != Active {
+		t.Errorf("Effective of an unrelated tenant = %s, want active", s)
+	}
+}
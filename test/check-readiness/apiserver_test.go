@@ -0,0 +1,303 @@
+package checkreadiness_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// apiResource is one row of an api-resources.txt fixture, which uses the
+// `kubectl api-resources --no-headers` layout:
+//
+//	NAME [SHORTNAMES] APIVERSION NAMESPACED KIND
+type apiResource struct {
+	name       string
+	group      string
+	version    string
+	namespaced bool
+	kind       string
+}
+
+// key is the resource as kubectl spells it: "resource.group".
+func (r apiResource) key() string {
+	if r.group == "" {
+		return r.name
+	}
+	return r.name + "." + r.group
+}
+
+func readAPIResources(t *testing.T, path string) []apiResource {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open api-resources fixture: %v", err)
+	}
+	defer f.Close()
+	var out []apiResource
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		n := len(fields)
+		r := apiResource{name: fields[0], namespaced: fields[n-2] == "true", kind: fields[n-1]}
+		r.version = fields[n-3]
+		if group, version, ok := strings.Cut(fields[n-3], "/"); ok {
+			r.group, r.version = group, version
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// fakeAPIServer serves discovery and LIST requests from a fixture directory
+// and records every request as the equivalent kubectl invocation, so tests
+// can assert scope propagation (-A / -n NS / -l SEL) in kubectl terms.
+//
+// Fixture files:
+//
+//	api-resources.txt      -> discovery (falls back to _base); an empty file
+//	                          makes discovery fail with 503
+//	<resource>[.group].yaml -> a YAML list of the objects LIST returns
+type fakeAPIServer struct {
+	*httptest.Server
+
+	t          *testing.T
+	fixtureDir string
+	// discovered is what discovery advertises; known is every resource the
+	// server can list, so lists work even when discovery is unavailable.
+	discovered []apiResource
+	known      []apiResource
+
+	mu  sync.Mutex
+	log []string
+}
+
+func newFakeAPIServer(t *testing.T, fixtureDir, fixtureBase string) *fakeAPIServer {
+	t.Helper()
+	s := &fakeAPIServer{t: t, fixtureDir: fixtureDir}
+	s.known = readAPIResources(t, filepath.Join(fixtureBase, "api-resources.txt"))
+	s.discovered = s.known
+	if _, err := os.Stat(filepath.Join(fixtureDir, "api-resources.txt")); err == nil {
+		s.discovered = readAPIResources(t, filepath.Join(fixtureDir, "api-resources.txt"))
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// callLog returns the recorded kubectl-equivalent invocations, one per line.
+func (s *fakeAPIServer) callLog() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Join(s.log, "\n")
+}
+
+func (s *fakeAPIServer) record(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log = append(s.log, line)
+}
+
+// kubeconfig writes a kubeconfig pointing at the server and returns its path.
+func (s *fakeAPIServer) kubeconfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	cfg := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: fake
+  cluster:
+    server: %s
+contexts:
+- name: fake
+  context:
+    cluster: fake
+    user: fake
+current-context: fake
+users:
+- name: fake
+  user:
+    token: fake
+`, s.URL)
+	if err := os.WriteFile(path, []byte(cfg), 0o600); err != nil {
+		t.Fatalf("write kubeconfig: %v", err)
+	}
+	return path
+}
+
+func (s *fakeAPIServer) serve(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case req.URL.Path == "/api" || req.URL.Path == "/apis":
+		s.record("api-resources")
+		if len(s.discovered) == 0 {
+			http.Error(w, "discovery unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if req.URL.Path == "/api" {
+			writeJSON(w, map[string]interface{}{"kind": "APIVersions", "versions": []string{"v1"}})
+			return
+		}
+		writeJSON(w, s.groupList())
+	case parts[0] == "api" && len(parts) == 2:
+		writeJSON(w, s.resourceList("", parts[1]))
+	case parts[0] == "apis" && len(parts) == 3:
+		writeJSON(w, s.resourceList(parts[1], parts[2]))
+	case parts[0] == "api" && len(parts) > 2:
+		s.serveList(w, req, "", parts[1], parts[2:])
+	case parts[0] == "apis" && len(parts) > 3:
+		s.serveList(w, req, parts[1], parts[2], parts[3:])
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (s *fakeAPIServer) groupList() map[string]interface{} {
+	versions := map[string][]string{}
+	for _, r := range s.discovered {
+		if r.group == "" {
+			continue
+		}
+		found := false
+		for _, v := range versions[r.group] {
+			found = found || v == r.version
+		}
+		if !found {
+			versions[r.group] = append(versions[r.group], r.version)
+		}
+	}
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var groups []interface{}
+	for _, name := range names {
+		var gvs []interface{}
+		for _, v := range versions[name] {
+			gvs = append(gvs, map[string]string{"groupVersion": name + "/" + v, "version": v})
+		}
+		groups = append(groups, map[string]interface{}{
+			"name": name, "versions": gvs, "preferredVersion": gvs[0],
+		})
+	}
+	return map[string]interface{}{"kind": "APIGroupList", "apiVersion": "v1", "groups": groups}
+}
+
+func (s *fakeAPIServer) resourceList(group, version string) map[string]interface{} {
+	gv := version
+	if group != "" {
+		gv = group + "/" + version
+	}
+	resources := []interface{}{}
+	for _, r := range s.discovered {
+		if r.group == group && r.version == version {
+			resources = append(resources, map[string]interface{}{
+				"name": r.name, "singularName": "", "namespaced": r.namespaced, "kind": r.kind,
+				"verbs": []string{"get", "list", "watch"},
+			})
+		}
+	}
+	return map[string]interface{}{"kind": "APIResourceList", "apiVersion": "v1", "groupVersion": gv, "resources": resources}
+}
+
+// serveList answers LIST on rest, which is [namespaces NS] RESOURCE.
+func (s *fakeAPIServer) serveList(w http.ResponseWriter, req *http.Request, group, version string, rest []string) {
+	namespace := ""
+	if len(rest) == 3 && rest[0] == "namespaces" {
+		namespace, rest = rest[1], rest[2:]
+	}
+	if len(rest) != 1 {
+		http.NotFound(w, req)
+		return
+	}
+	var res *apiResource
+	for i := range s.known {
+		if s.known[i].name == rest[0] && s.known[i].group == group {
+			res = &s.known[i]
+		}
+	}
+	if res == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	line := "get " + res.key()
+	switch {
+	case namespace != "":
+		line += " -n " + namespace
+	case res.namespaced:
+		line += " -A"
+	}
+	selector := req.URL.Query().Get("labelSelector")
+	if selector != "" {
+		line += " -l " + selector
+	}
+	s.record(line)
+
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	items := []interface{}{}
+	for _, obj := range s.fixtureObjects(res.key()) {
+		meta, _ := obj["metadata"].(map[string]interface{})
+		if namespace != "" && meta["namespace"] != namespace {
+			continue
+		}
+		objLabels := labels.Set{}
+		if l, ok := meta["labels"].(map[string]interface{}); ok {
+			for k, v := range l {
+				objLabels[k], _ = v.(string)
+			}
+		}
+		if !sel.Matches(objLabels) {
+			continue
+		}
+		apiVersion := version
+		if group != "" {
+			apiVersion = group + "/" + version
+		}
+		obj["apiVersion"], obj["kind"] = apiVersion, res.kind
+		items = append(items, obj)
+	}
+	writeJSON(w, map[string]interface{}{
+		"apiVersion": "v1", "kind": res.kind + "List",
+		"metadata": map[string]interface{}{}, "items": items,
+	})
+}
+
+func (s *fakeAPIServer) fixtureObjects(key string) []map[string]interface{} {
+	b, err := os.ReadFile(filepath.Join(s.fixtureDir, key+".yaml"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		s.t.Errorf("read fixture: %v", err)
+		return nil
+	}
+	var objs []map[string]interface{}
+	if err := yaml.Unmarshal(b, &objs); err != nil {
+		s.t.Errorf("parse fixture %s: %v", key, err)
+	}
+	return objs
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
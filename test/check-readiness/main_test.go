@@ -1,8 +1,8 @@
 // Package checkreadiness_test is a black-box test suite for the check-readiness
 // command (cmd/check-readiness).
 //
-// By default it builds ./cmd/check-readiness and runs that binary against a mock
-// kubectl that serves canned fixtures, comparing stdout/stderr/exit against
+// By default it builds ./cmd/check-readiness and runs that binary against a fake
+// apiserver that serves canned fixtures, comparing stdout/stderr/exit against
 // golden files. Set SCRIPT_UNDER_TEST to point at any other drop-in executable
 // (e.g. an alternative implementation) to validate it against the same suite.
 //
@@ -66,8 +66,8 @@ type testCase struct {
 	args     []string // arguments passed to the script
 	fixture  string   // scenario dir under testdata/fixtures (""=base only)
 	wantExit int      // expected process exit code
-	wantLog  []string // substrings that must appear in the kubectl call log
-	dontLog  []string // substrings that must NOT appear in the kubectl call log
+	wantLog  []string // substrings that must appear in the call log
+	dontLog  []string // substrings that must NOT appear in the call log
 }
 
 func cases() []testCase {
@@ -103,7 +103,7 @@ func cases() []testCase {
 		{name: "crd-not-installed-skipped", args: []string{"--core", "--no-color"}, fixture: "crd-missing", wantExit: 0},
 		{name: "mixed-multiple-kinds", args: []string{"--no-color"}, fixture: "mixed", wantExit: 1},
 
-		// === kubectl argument propagation (asserted via call log) ===
+		// === Scope propagation (asserted via the kubectl-equivalent call log) ===
 		{
 			name: "core-skips-extras", args: []string{"--core", "--no-color"}, fixture: "_base", wantExit: 0,
 			wantLog: []string{"get packages.cozystack.io", "get kustomizations.kustomize.toolkit.fluxcd.io"},
@@ -124,6 +124,31 @@ func cases() []testCase {
 			wantLog: []string{"get helmreleases.helm.toolkit.fluxcd.io -A -l app=demo", "get nodes -l app=demo"},
 		},
 
+		// === Permanent failures ===
+		{name: "failed-permanently", args: []string{"-v", "--no-color"}, fixture: "failed-permanently", wantExit: 3},
+		{
+			// --wait gives up on the first pass instead of running into the timeout.
+			name: "wait-failed-permanently", args: []string{"--wait", "--timeout", "1h", "--no-color"},
+			fixture: "failed-permanently", wantExit: 3,
+		},
+
+		// === Dependency view ===
+		{name: "deps-not-ready", args: []string{"--deps", "--no-color"}, fixture: "depends-on", wantExit: 1},
+		{
+			// Dependencies outside the namespace scope are not reported missing.
+			name: "deps-namespace-scoped", args: []string{"--deps", "-n", "tenant-root", "--no-color"},
+			fixture: "depends-on", wantExit: 1,
+		},
+
+		// === Machine-readable output ===
+		{name: "output-json", args: []string{"-o", "json"}, fixture: "depends-on", wantExit: 1},
+		{name: "output-json-all-ready", args: []string{"--core", "--output", "json"}, fixture: "all-ready", wantExit: 0},
+		{name: "output-junit", args: []string{"-o", "junit"}, fixture: "failed-permanently", wantExit: 3},
+		{name: "output-junit-crd-missing", args: []string{"--core", "-o", "junit"}, fixture: "crd-missing", wantExit: 0},
+		{name: "output-invalid", args: []string{"-o", "yaml"}, wantExit: 2},
+		{name: "output-missing-value", args: []string{"-o"}, wantExit: 2},
+		{name: "output-json-with-watch", args: []string{"-w", "-o", "json"}, wantExit: 2},
+
 		// === Wait / timeout ===
 		{name: "wait-success", args: []string{"--wait", "--no-color"}, fixture: "_base", wantExit: 0},
 		{
@@ -142,26 +167,19 @@ func cases() []testCase {
 
 func TestCheckReadiness(t *testing.T) {
 	repoRoot := repoRoot(t)
-	fakeBin := filepath.Join(repoRoot, "test", "check-readiness", "fakekubectl")
 	fixturesRoot := filepath.Join(repoRoot, "test", "check-readiness", "testdata", "fixtures")
 	goldenRoot := filepath.Join(repoRoot, "test", "check-readiness", "testdata", "golden")
 
-	// Ensure the mock is executable (git preserves the bit, but be defensive).
-	if err := os.Chmod(filepath.Join(fakeBin, "kubectl"), 0o755); err != nil {
-		t.Fatalf("chmod mock kubectl: %v", err)
-	}
-
 	for _, tc := range cases() {
 		t.Run(tc.name, func(t *testing.T) {
-			logFile := filepath.Join(t.TempDir(), "kubectl.log")
-
 			fixtureDir := fixturesRoot
 			if tc.fixture != "" {
 				fixtureDir = filepath.Join(fixturesRoot, tc.fixture)
 			}
 
 			cmd := scriptCommand(tc.args)
-			cmd.Env = testEnv(fakeBin, fixtureDir, filepath.Join(fixturesRoot, "_base"), logFile)
+			server := newFakeAPIServer(t, fixtureDir, filepath.Join(fixturesRoot, "_base"))
+			cmd.Env = testEnv(server.kubeconfig(t))
 
 			var stdout, stderr bytes.Buffer
 			cmd.Stdout = &stdout
@@ -176,7 +194,7 @@ func TestCheckReadiness(t *testing.T) {
 			checkGolden(t, goldenRoot, tc.name+".stdout", normalize(stdout.String()))
 			checkGolden(t, goldenRoot, tc.name+".stderr", normalize(stderr.String()))
 
-			assertLog(t, logFile, tc.wantLog, tc.dontLog)
+			assertLog(t, server.callLog(), tc.wantLog, tc.dontLog)
 		})
 	}
 }
@@ -197,28 +215,21 @@ func scriptCommand(args []string) *exec.Cmd {
 	return exec.Command(argv[0], argv[1:]...)
 }
 
-// testEnv returns a clean environment: fake kubectl first on PATH, fixture
-// pointers set, KUBECONFIG stripped so the script uses a bare `kubectl`.
-func testEnv(fakeBin, fixtureDir, fixtureBase, logFile string) []string {
+// testEnv returns a clean environment with KUBECONFIG pointing at the fake
+// apiserver and nothing that could lead the subject to a real cluster.
+func testEnv(kubeconfig string) []string {
 	var env []string
 	for _, kv := range os.Environ() {
 		switch {
-		case strings.HasPrefix(kv, "PATH="):
-			env = append(env, "PATH="+fakeBin+string(os.PathListSeparator)+kv[len("PATH="):])
 		case strings.HasPrefix(kv, "KUBECONFIG="),
-			strings.HasPrefix(kv, "FIXTURE_DIR="),
-			strings.HasPrefix(kv, "FIXTURE_BASE="),
-			strings.HasPrefix(kv, "KUBECTL_LOG="):
-			// drop — we set these ourselves
+			strings.HasPrefix(kv, "KUBERNETES_SERVICE_HOST="),
+			strings.HasPrefix(kv, "KUBERNETES_SERVICE_PORT="):
+			// drop — we set KUBECONFIG ourselves
 		default:
 			env = append(env, kv)
 		}
 	}
-	return append(env,
-		"FIXTURE_DIR="+fixtureDir,
-		"FIXTURE_BASE="+fixtureBase,
-		"KUBECTL_LOG="+logFile,
-	)
+	return append(env, "KUBECONFIG="+kubeconfig)
 }
 
 func exitCode(t *testing.T, err error) int {
@@ -262,24 +273,16 @@ func checkGolden(t *testing.T, dir, name, got string) {
 	}
 }
 
-func assertLog(t *testing.T, logFile string, want, dont []string) {
+func assertLog(t *testing.T, log string, want, dont []string) {
 	t.Helper()
-	if len(want) == 0 && len(dont) == 0 {
-		return
-	}
-	b, err := os.ReadFile(logFile)
-	if err != nil && !errors.Is(err, os.ErrNotExist) {
-		t.Fatalf("read kubectl log: %v", err)
-	}
-	log := string(b)
 	for _, sub := range want {
 		if !strings.Contains(log, sub) {
-			t.Errorf("kubectl log missing %q\nlog:\n%s", sub, log)
+			t.Errorf("call log missing %q\nlog:\n%s", sub, log)
 		}
 	}
 	for _, sub := range dont {
 		if strings.Contains(log, sub) {
-			t.Errorf("kubectl log unexpectedly contains %q\nlog:\n%s", sub, log)
+			t.Errorf("call log unexpectedly contains %q\nlog:\n%s", sub, log)
 		}
 	}
 }
@@ -297,16 +300,16 @@ func TestColorTTY(t *testing.T) {
 	}
 
 	root := repoRoot(t)
-	fakeBin := filepath.Join(root, "test", "check-readiness", "fakekubectl")
 	fixturesRoot := filepath.Join(root, "test", "check-readiness", "testdata", "fixtures")
 	fixtureDir := filepath.Join(fixturesRoot, "helmrelease-not-ready")
-	base := filepath.Join(fixturesRoot, "_base")
+	server := newFakeAPIServer(t, fixtureDir, filepath.Join(fixturesRoot, "_base"))
+	kubeconfig := server.kubeconfig(t)
 
 	runUnderPTY := func(args []string) string {
 		cmdline := strings.Join(subjectArgv(args), " ")
 		// script -q (quiet) -e (return child's exit) -c CMD <typescript-file>
 		cmd := exec.Command("script", "-qec", cmdline, "/dev/null")
-		cmd.Env = testEnv(fakeBin, fixtureDir, base, filepath.Join(t.TempDir(), "kubectl.log"))
+		cmd.Env = testEnv(kubeconfig)
 		out, _ := cmd.CombinedOutput() // non-zero exit expected (not-ready -> 1)
 		return string(out)
 	}